@@ -1,11 +1,17 @@
 package main
 
 import (
+	"context"
+	"errors"
 	"flag"
 	"log/slog"
 	"net/http"
 	"os"
+	"os/signal"
 	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
 	"time"
 
 	"github.com/go-chi/chi/v5"
@@ -55,6 +61,28 @@ func main() {
 		os.Exit(1)
 	}
 
+	// Restore data stores left unparseable, e.g. by a bad manual edit, from their latest valid backup
+	storeRecoveries, err := services.RecoverStores(fileService)
+	if err != nil {
+		logger.Error("failed to check data stores", slog.String("error", err.Error()))
+		os.Exit(1)
+	}
+	for _, recovery := range storeRecoveries {
+		if recovery.Recovered() {
+			logger.Error("data store was corrupt and has been restored from a backup; recent changes may be lost",
+				slog.String("file", recovery.File),
+				slog.String("backup", recovery.Backup),
+				slog.String("corrupt_copy", recovery.Corrupt),
+				slog.String("error", recovery.Error),
+			)
+		} else {
+			logger.Error("data store is corrupt and no valid backup was found; fix it by hand and restart",
+				slog.String("file", recovery.File),
+				slog.String("error", recovery.Error),
+			)
+		}
+	}
+
 	// Load admin configuration from file
 	var adminConfig models.AdminConfig
 	if err := fileService.ReadJSON("admin_config.json", &adminConfig); err != nil {
@@ -97,7 +125,10 @@ func main() {
 		os.Exit(1)
 	}
 
+	albumDefaultsService := services.NewAlbumDefaultsService(fileService)
 	albumService := services.NewAlbumService(fileService)
+	albumService.SetDefaultsService(albumDefaultsService)
+	albumService.SetLogger(logger)
 	configService := services.NewSiteConfigService(fileService)
 
 	imageService, err := services.NewImageService(uploadDir, configService, logger)
@@ -111,11 +142,392 @@ func main() {
 	// Configure persistence so password changes are saved to disk
 	authService.SetConfigPersistence(fileService, "admin_config.json")
 
+	// Initialize album auth service for password-protected albums (24 hour token TTL)
+	// Tokens are signed with ALBUM_AUTH_SECRET; without it a random secret is used per process
+	albumAuthService, err := services.NewAlbumAuthService(os.Getenv("ALBUM_AUTH_SECRET"), 24*time.Hour)
+	if err != nil {
+		logger.Error("failed to create album auth service", slog.String("error", err.Error()))
+		os.Exit(1)
+	}
+
+	// Key rotations made to revoke every token are kept with the data, so they survive restarts
+	if err := albumAuthService.SetKeyStore(fileService); err != nil {
+		logger.Error("failed to load album auth keys", slog.String("error", err.Error()))
+		os.Exit(1)
+	}
+
+	// Album access sessions (ALBUM_SESSIONS=stateless|memory|file); server-side sessions can be
+	// revoked, and are when an album's password changes
+	albumSessions := getEnv("ALBUM_SESSIONS", "stateless")
+	switch albumSessions {
+	case "stateless":
+	case "memory":
+		albumAuthService.SetSessionStore(services.NewMemorySessionStore())
+	case "file":
+		albumAuthService.SetSessionStore(services.NewFileSessionStore(fileService))
+	default:
+		logger.Error("unknown album session store", slog.String("album_sessions", albumSessions))
+		os.Exit(1)
+	}
+
+	// Email for magic album access links; unset SMTP_HOST disables access links
+	var mailer services.Mailer
+	if smtpHost := os.Getenv("SMTP_HOST"); smtpHost != "" {
+		smtpMailer, err := services.NewSMTPMailer(services.SMTPConfig{
+			Host:     smtpHost,
+			Port:     getEnv("SMTP_PORT", "587"),
+			Username: os.Getenv("SMTP_USERNAME"),
+			Password: os.Getenv("SMTP_PASSWORD"), // pragma: allowlist secret
+			From:     os.Getenv("SMTP_FROM"),
+		})
+		if err != nil {
+			logger.Error("failed to configure SMTP mailer", slog.String("error", err.Error()))
+			os.Exit(1)
+		}
+		mailer = smtpMailer
+	}
+
+	// Select the storage backend for photo files (STORAGE_BACKEND=local|s3)
+	// The S3 backend also enables direct-to-storage uploads via pre-signed URLs
+	var directUploadBackend services.DirectUploadBackend
+	storageBackend := getEnv("STORAGE_BACKEND", "local")
+	switch storageBackend {
+	case "local":
+	case "s3":
+		s3Storage, err := services.NewS3Storage(services.S3Config{
+			Endpoint:        os.Getenv("S3_ENDPOINT"),
+			Region:          getEnv("S3_REGION", "us-east-1"),
+			Bucket:          os.Getenv("S3_BUCKET"),
+			AccessKeyID:     os.Getenv("S3_ACCESS_KEY_ID"),
+			SecretAccessKey: os.Getenv("S3_SECRET_ACCESS_KEY"), // pragma: allowlist secret
+		})
+		if err != nil {
+			logger.Error("failed to configure S3 storage", slog.String("error", err.Error()))
+			os.Exit(1)
+		}
+		imageService.SetStorage(s3Storage)
+		directUploadBackend = s3Storage
+	default:
+		logger.Error("unknown storage backend", slog.String("storage_backend", storageBackend))
+		os.Exit(1)
+	}
+	logger.Info("storage backend configured", slog.String("storage_backend", storageBackend))
+
+	// albums.json is also copied to a second location, BACKUP_DIR or the private BACKUP_S3_BUCKET
+	// at S3_ENDPOINT, every BACKUP_INTERVAL_MINUTES and after changes, keeping BACKUP_KEEP snapshots
+	var backupService *services.StoreBackupService
+	var backupTarget services.Storage
+	backupDir, backupBucket := os.Getenv("BACKUP_DIR"), os.Getenv("BACKUP_S3_BUCKET")
+	switch {
+	case backupDir != "" && backupBucket != "":
+		logger.Error("set only one of BACKUP_DIR and BACKUP_S3_BUCKET")
+		os.Exit(1)
+	case backupDir != "":
+		localBackups, err := services.NewLocalStorage(backupDir)
+		if err != nil {
+			logger.Error("invalid BACKUP_DIR", slog.String("error", err.Error()))
+			os.Exit(1)
+		}
+		backupTarget = localBackups
+	case backupBucket != "":
+		s3Backups, err := services.NewS3Storage(services.S3Config{
+			Endpoint:        os.Getenv("S3_ENDPOINT"),
+			Region:          getEnv("S3_REGION", "us-east-1"),
+			Bucket:          backupBucket,
+			AccessKeyID:     os.Getenv("S3_ACCESS_KEY_ID"),
+			SecretAccessKey: os.Getenv("S3_SECRET_ACCESS_KEY"), // pragma: allowlist secret
+		})
+		if err != nil {
+			logger.Error("failed to configure S3 backups", slog.String("error", err.Error()))
+			os.Exit(1)
+		}
+		backupTarget = s3Backups
+	}
+	if backupTarget != nil {
+		backupKeep, err := strconv.Atoi(getEnv("BACKUP_KEEP", strconv.Itoa(services.DefaultStoreBackupKeep)))
+		if err != nil || backupKeep < 1 {
+			logger.Error("invalid BACKUP_KEEP", slog.String("value", os.Getenv("BACKUP_KEEP")))
+			os.Exit(1)
+		}
+		backupIntervalMinutes, err := strconv.Atoi(getEnv("BACKUP_INTERVAL_MINUTES", strconv.Itoa(int(services.DefaultStoreBackupInterval.Minutes()))))
+		if err != nil || backupIntervalMinutes < 0 {
+			logger.Error("invalid BACKUP_INTERVAL_MINUTES", slog.String("value", os.Getenv("BACKUP_INTERVAL_MINUTES")))
+			os.Exit(1)
+		}
+		backupOnWrite, err := strconv.ParseBool(getEnv("BACKUP_ON_WRITE", "true"))
+		if err != nil {
+			logger.Error("invalid BACKUP_ON_WRITE", slog.String("value", os.Getenv("BACKUP_ON_WRITE")))
+			os.Exit(1)
+		}
+
+		backupService = services.NewStoreBackupService(albumService, imageService.Storage(), backupTarget, backupKeep)
+		fileService.SetWriteHook(backupService.NotifyWrite)
+		backupService.Start(time.Duration(backupIntervalMinutes)*time.Minute, backupOnWrite, logger)
+		logger.Info("album backups configured",
+			slog.Int("keep", backupKeep),
+			slog.Int("interval_minutes", backupIntervalMinutes),
+			slog.Bool("on_write", backupOnWrite),
+		)
+	}
+
+	// Background jobs (derivative regeneration)
+	jobService := services.NewJobService()
+	regenerateService := services.NewRegenerateService(albumService, imageService, jobService, logger)
+	exifBackfillService := services.NewEXIFBackfillService(albumService, imageService, jobService, logger)
+	derivedBackfillService := services.NewDerivedBackfillService(albumService, imageService, jobService, logger)
+
+	// Folder imports are limited to IMPORT_ROOT; unset disables them
+	importService := services.NewImportService(albumService, imageService, os.Getenv("IMPORT_ROOT"))
+
+	// Files in one upload request are processed this many at a time
+	uploadConcurrency, err := strconv.Atoi(getEnv("UPLOAD_CONCURRENCY", strconv.Itoa(handlers.DefaultUploadConcurrency)))
+	if err != nil || uploadConcurrency < 1 {
+		logger.Error("invalid UPLOAD_CONCURRENCY", slog.String("value", os.Getenv("UPLOAD_CONCURRENCY")))
+		os.Exit(1)
+	}
+
+	// ZIP archives of photos uploaded in one request may be at most this many MB
+	maxBatchSizeMB, err := strconv.Atoi(getEnv("MAX_BATCH_SIZE", strconv.Itoa(handlers.DefaultMaxZIPUploadSize>>20)))
+	if err != nil || maxBatchSizeMB < 1 {
+		logger.Error("invalid MAX_BATCH_SIZE", slog.String("value", os.Getenv("MAX_BATCH_SIZE")))
+		os.Exit(1)
+	}
+
+	// Clients may upload at most this many photos, and this many MB, to each album accepting client uploads
+	clientUploadMaxPhotos, err := strconv.Atoi(getEnv("CLIENT_UPLOAD_MAX_PHOTOS", strconv.Itoa(handlers.DefaultClientUploadMaxPhotos)))
+	if err != nil || clientUploadMaxPhotos < 0 {
+		logger.Error("invalid CLIENT_UPLOAD_MAX_PHOTOS", slog.String("value", os.Getenv("CLIENT_UPLOAD_MAX_PHOTOS")))
+		os.Exit(1)
+	}
+	clientUploadMaxMB, err := strconv.Atoi(getEnv("CLIENT_UPLOAD_MAX_MB", strconv.Itoa(handlers.DefaultClientUploadMaxSize>>20)))
+	if err != nil || clientUploadMaxMB < 0 {
+		logger.Error("invalid CLIENT_UPLOAD_MAX_MB", slog.String("value", os.Getenv("CLIENT_UPLOAD_MAX_MB")))
+		os.Exit(1)
+	}
+
+	// JSON request bodies may be at most this many KB; STRICT_JSON also rejects unknown fields
+	maxJSONBodyKB, err := strconv.Atoi(getEnv("MAX_JSON_BODY_KB", strconv.Itoa(middleware.DefaultMaxJSONBodySize>>10)))
+	if err != nil || maxJSONBodyKB < 1 {
+		logger.Error("invalid MAX_JSON_BODY_KB", slog.String("value", os.Getenv("MAX_JSON_BODY_KB")))
+		os.Exit(1)
+	}
+	strictJSON, err := strconv.ParseBool(getEnv("STRICT_JSON", "false"))
+	if err != nil {
+		logger.Error("invalid STRICT_JSON", slog.String("value", os.Getenv("STRICT_JSON")))
+		os.Exit(1)
+	}
+
+	// Browsers and CDNs may cache photo files this many seconds without revalidating
+	imageCacheMaxAge, err := strconv.Atoi(getEnv("IMAGE_CACHE_MAX_AGE", strconv.Itoa(int(handlers.DefaultImageCacheMaxAge.Seconds()))))
+	if err != nil || imageCacheMaxAge < 0 {
+		logger.Error("invalid IMAGE_CACHE_MAX_AGE", slog.String("value", os.Getenv("IMAGE_CACHE_MAX_AGE")))
+		os.Exit(1)
+	}
+
+	// Each photo is read this many KiB at a time while building a ZIP download, bounding its memory use
+	zipBufferKB, err := strconv.Atoi(getEnv("ZIP_BUFFER_KB", strconv.Itoa(services.DefaultZIPBufferSize>>10)))
+	if err != nil || zipBufferKB < 4 {
+		logger.Error("invalid ZIP_BUFFER_KB", slog.String("value", os.Getenv("ZIP_BUFFER_KB")))
+		os.Exit(1)
+	}
+	imageService.SetZIPBufferSize(zipBufferKB << 10)
+
+	// Built album ZIPs are kept here and served again until the album changes; "off" disables the cache.
+	// Not under DATA_DIR, which the public site serves.
+	if zipCacheDir := getEnv("ZIP_CACHE_DIR", filepath.Join(os.TempDir(), "photoadmin-zip-cache")); zipCacheDir != "off" {
+		if err := imageService.SetZIPCacheDir(zipCacheDir); err != nil {
+			logger.Error("invalid ZIP_CACHE_DIR", slog.String("error", err.Error()))
+			os.Exit(1)
+		}
+	}
+	// Past this many MB of cached ZIPs, the least recently downloaded are removed ("0" keeps every ZIP)
+	zipCacheMB, err := strconv.Atoi(getEnv("ZIP_CACHE_MAX_MB", strconv.Itoa(services.DefaultZIPCacheSize>>20)))
+	if err != nil || zipCacheMB < 0 {
+		logger.Error("invalid ZIP_CACHE_MAX_MB", slog.String("value", os.Getenv("ZIP_CACHE_MAX_MB")))
+		os.Exit(1)
+	}
+	imageService.SetZIPCacheSize(int64(zipCacheMB) << 20)
+
+	// Album downloads without ?quality= are served at DEFAULT_DOWNLOAD_QUALITY
+	defaultDownloadQuality := getEnv("DEFAULT_DOWNLOAD_QUALITY", handlers.DefaultDownloadQuality)
+	if !handlers.IsDownloadQuality(defaultDownloadQuality) {
+		logger.Error("invalid DEFAULT_DOWNLOAD_QUALITY", slog.String("value", defaultDownloadQuality))
+		os.Exit(1)
+	}
+
+	// Requests per minute allowed to each client address using the public read endpoints without an API key
+	anonymousRateLimit, err := strconv.Atoi(getEnv("PUBLIC_RATE_LIMIT", strconv.Itoa(middleware.DefaultAnonymousRateLimit)))
+	if err != nil || anonymousRateLimit < 0 {
+		logger.Error("invalid PUBLIC_RATE_LIMIT", slog.String("value", os.Getenv("PUBLIC_RATE_LIMIT")))
+		os.Exit(1)
+	}
+	// Client addresses forwarded by TRUSTED_PROXIES are believed, so limits apply per visitor
+	// rather than to the reverse proxy as a whole ("off" trusts none)
+	trustedProxiesSpec := getEnv("TRUSTED_PROXIES", middleware.DefaultTrustedProxies)
+	if trustedProxiesSpec == "off" {
+		trustedProxiesSpec = ""
+	}
+	trustedProxies, err := middleware.ParseTrustedProxies(trustedProxiesSpec)
+	if err != nil {
+		logger.Error("invalid TRUSTED_PROXIES", slog.String("error", err.Error()))
+		os.Exit(1)
+	}
+	// At most MAX_CONCURRENT_DOWNLOADS album ZIPs are built and streamed at once (0 disables the limit)
+	maxConcurrentDownloads, err := strconv.Atoi(getEnv("MAX_CONCURRENT_DOWNLOADS", strconv.Itoa(middleware.DefaultMaxConcurrentDownloads)))
+	if err != nil || maxConcurrentDownloads < 0 {
+		logger.Error("invalid MAX_CONCURRENT_DOWNLOADS", slog.String("value", os.Getenv("MAX_CONCURRENT_DOWNLOADS")))
+		os.Exit(1)
+	}
+
+	// Uploads are scanned for malware by clamd when CLAMAV_ADDRESS is set
+	if clamavAddress := os.Getenv("CLAMAV_ADDRESS"); clamavAddress != "" {
+		scanner, err := services.NewClamAVScanner(clamavAddress, services.DefaultScanTimeout)
+		if err != nil {
+			logger.Error("invalid CLAMAV_ADDRESS", slog.String("error", err.Error()))
+			os.Exit(1)
+		}
+		imageService.SetScanner(scanner)
+	}
+
+	// Uploads are turned away with 507 while the upload volume has less than MIN_UPLOAD_SPACE_MB free (0 disables)
+	minUploadSpaceMB, err := strconv.Atoi(getEnv("MIN_UPLOAD_SPACE_MB", strconv.Itoa(services.DefaultMinUploadSpace>>20)))
+	if err != nil || minUploadSpaceMB < 0 {
+		logger.Error("invalid MIN_UPLOAD_SPACE_MB", slog.String("value", os.Getenv("MIN_UPLOAD_SPACE_MB")))
+		os.Exit(1)
+	}
+	imageService.SetMinUploadSpace(int64(minUploadSpaceMB) << 20)
+
+	// Square thumbnails are cropped around the photo's subject when THUMBNAIL_SUBJECT_CROP is set
+	subjectCrop, err := strconv.ParseBool(getEnv("THUMBNAIL_SUBJECT_CROP", "false"))
+	if err != nil {
+		logger.Error("invalid THUMBNAIL_SUBJECT_CROP", slog.String("value", os.Getenv("THUMBNAIL_SUBJECT_CROP")))
+		os.Exit(1)
+	}
+	if subjectCrop {
+		imageService.SetSubjectDetector(services.SaliencyDetector{})
+	}
+
+	// Padded thumbnails of one aspect ratio (THUMBNAIL_PADDING_ASPECT, e.g. 1:1) are stored
+	// alongside the regular ones, filled with THUMBNAIL_PADDING_BACKGROUND (#rrggbb or blur)
+	if aspect := os.Getenv("THUMBNAIL_PADDING_ASPECT"); aspect != "" {
+		padding, err := services.ParseThumbnailPadding(aspect, getEnv("THUMBNAIL_PADDING_BACKGROUND", "#ffffff"))
+		if err != nil {
+			logger.Error("invalid thumbnail padding", slog.String("error", err.Error()))
+			os.Exit(1)
+		}
+		imageService.SetThumbnailPadding(padding)
+	}
+
+	// Public album URLs with a differently cased slug or a trailing slash redirect to the canonical URL
+	canonicalSlugRedirects, err := strconv.ParseBool(getEnv("CANONICAL_SLUG_REDIRECTS", "true"))
+	if err != nil {
+		logger.Error("invalid CANONICAL_SLUG_REDIRECTS", slog.String("value", os.Getenv("CANONICAL_SLUG_REDIRECTS")))
+		os.Exit(1)
+	}
+	canonicalSlug := func(next http.Handler) http.Handler { return next }
+	if canonicalSlugRedirects {
+		canonicalSlug = middleware.CanonicalAlbumSlug(albumService)
+	}
+
+	// Files in the SIGNED_IMAGE_DIRS folders of /uploads (e.g. "originals,display") are only
+	// served through URLs the public album endpoints sign, valid for IMAGE_URL_TTL_MINUTES to
+	// twice that, plus IMAGE_URL_GRACE_MINUTES for pages loaded just before they expire.
+	// Files the web server serves from disk bypass the check; see the README.
+	var imageURLSigner *services.ImageURLSigner
+	if signedDirs := os.Getenv("SIGNED_IMAGE_DIRS"); signedDirs != "" {
+		ttlMinutes, err := strconv.Atoi(getEnv("IMAGE_URL_TTL_MINUTES", strconv.Itoa(int(services.DefaultImageURLTTL.Minutes()))))
+		if err != nil || ttlMinutes < 1 {
+			logger.Error("invalid IMAGE_URL_TTL_MINUTES", slog.String("value", os.Getenv("IMAGE_URL_TTL_MINUTES")))
+			os.Exit(1)
+		}
+		graceMinutes, err := strconv.Atoi(getEnv("IMAGE_URL_GRACE_MINUTES", strconv.Itoa(int(services.DefaultImageURLGrace.Minutes()))))
+		if err != nil || graceMinutes < 0 {
+			logger.Error("invalid IMAGE_URL_GRACE_MINUTES", slog.String("value", os.Getenv("IMAGE_URL_GRACE_MINUTES")))
+			os.Exit(1)
+		}
+		var dirs []string
+		for _, dir := range strings.Split(signedDirs, ",") {
+			if dir = strings.TrimSpace(dir); dir != "" {
+				dirs = append(dirs, dir)
+			}
+		}
+		imageURLSigner, err = services.NewImageURLSigner(os.Getenv("IMAGE_URL_SECRET"),
+			time.Duration(ttlMinutes)*time.Minute, time.Duration(graceMinutes)*time.Minute, dirs)
+		if err != nil {
+			logger.Error("invalid SIGNED_IMAGE_DIRS", slog.String("error", err.Error()))
+			os.Exit(1)
+		}
+	}
+
+	apiKeyService := services.NewAPIKeyService(fileService)
+	publicAPILimit := middleware.PublicAPIRateLimit(middleware.NewRateLimiter(), apiKeyService, anonymousRateLimit, logger)
+	// One limiter for every ZIP download route, so they share the bandwidth budget
+	downloadLimit := middleware.ConcurrencyLimit(middleware.NewConcurrencyLimiter(maxConcurrentDownloads), middleware.DownloadRetryAfter)
+	selectionLimit := middleware.RateLimit(middleware.NewRateLimiter(), middleware.DefaultSelectionRateLimit)
+	accessLinkLimit := middleware.RateLimit(middleware.NewRateLimiter(), middleware.DefaultAccessLinkRateLimit)
+	clientUploadLimit := middleware.RateLimit(middleware.NewRateLimiter(), middleware.DefaultClientUploadRateLimit)
+
 	// Initialize handlers
 	albumHandler := handlers.NewAlbumHandler(albumService, imageService, logger)
+	albumHandler.SetUploadConcurrency(uploadConcurrency)
+	albumHandler.SetMaxZIPUploadSize(int64(maxBatchSizeMB) << 20)
+	albumHandler.SetClientUploadLimits(clientUploadMaxPhotos, int64(clientUploadMaxMB)<<20)
+	albumHandler.SetDefaultDownloadQuality(defaultDownloadQuality)
+	albumHandler.SetAlbumAuthService(albumAuthService)
+	albumHandler.SetMailer(mailer, getEnv("PUBLIC_URL", "http://localhost:"+port))
+	albumHandler.SetRegenerateService(regenerateService)
+	albumHandler.SetSelectionService(services.NewSelectionService(fileService))
+	if imageURLSigner != nil {
+		albumHandler.SetImageURLSigner(imageURLSigner)
+	}
+
+	// Album views are counted per visitor session and saved every VIEW_FLUSH_SECONDS; 0 disables counting
+	viewFlushSeconds, err := strconv.Atoi(getEnv("VIEW_FLUSH_SECONDS", strconv.Itoa(int(services.DefaultViewFlushInterval.Seconds()))))
+	if err != nil || viewFlushSeconds < 0 {
+		logger.Error("invalid VIEW_FLUSH_SECONDS", slog.String("value", os.Getenv("VIEW_FLUSH_SECONDS")))
+		os.Exit(1)
+	}
+	var viewTracker *services.ViewTracker
+	if viewFlushSeconds > 0 {
+		viewTracker = services.NewViewTracker(fileService)
+		viewTracker.StartFlushing(time.Duration(viewFlushSeconds)*time.Second, logger)
+		albumHandler.SetViewTracker(viewTracker)
+	}
 	authHandler := handlers.NewAuthHandler(authService, logger)
 	configHandler := handlers.NewConfigHandler(configService, logger)
+	configHandler.SetRegenerateService(regenerateService)
+	albumDefaultsHandler := handlers.NewAlbumDefaultsHandler(albumDefaultsService, logger)
+	statsHandler := handlers.NewStatsHandler(albumService, logger)
+	healthHandler := handlers.NewHealthHandler(storeRecoveries)
 	storageHandler := handlers.NewStorageHandler(configService, uploadDir)
+	storageHandler.SetUsageService(services.NewStorageUsageService(albumService, imageService))
+	importHandler := handlers.NewImportHandler(importService, logger)
+	backupHandler := handlers.NewBackupHandler(backupService, logger)
+	jobHandler := handlers.NewJobHandler(jobService, regenerateService, exifBackfillService, derivedBackfillService, logger)
+	directUploadHandler := handlers.NewDirectUploadHandler(albumService, imageService, directUploadBackend, logger)
+	apiKeyHandler := handlers.NewAPIKeyHandler(apiKeyService, logger)
+
+	// Failed uploads are posted to UPLOAD_FAILURE_WEBHOOK_URL for alerting when it is set
+	if webhookURL := os.Getenv("UPLOAD_FAILURE_WEBHOOK_URL"); webhookURL != "" {
+		notifier, err := services.NewWebhookUploadFailureNotifier(webhookURL)
+		if err != nil {
+			logger.Error("invalid UPLOAD_FAILURE_WEBHOOK_URL", slog.String("error", err.Error()))
+			os.Exit(1)
+		}
+		failureHook := services.NewUploadFailureHook(notifier, services.DefaultUploadFailureTimeout, logger)
+		albumHandler.SetUploadFailureHook(failureHook)
+		directUploadHandler.SetUploadFailureHook(failureHook)
+	}
+
+	// Scheduled visibility changes are applied every SCHEDULE_INTERVAL_SECONDS; 0 disables them
+	scheduleIntervalSeconds, err := strconv.Atoi(getEnv("SCHEDULE_INTERVAL_SECONDS", strconv.Itoa(int(services.DefaultScheduleInterval.Seconds()))))
+	if err != nil || scheduleIntervalSeconds < 0 {
+		logger.Error("invalid SCHEDULE_INTERVAL_SECONDS", slog.String("value", os.Getenv("SCHEDULE_INTERVAL_SECONDS")))
+		os.Exit(1)
+	}
+	if scheduleIntervalSeconds > 0 {
+		albumService.StartScheduledChanges(time.Duration(scheduleIntervalSeconds)*time.Second, logger)
+	}
 
 	// Start session cleanup goroutine
 	authHandler.StartSessionCleanup()
@@ -125,9 +537,12 @@ func main() {
 
 	// Global middleware
 	r.Use(middleware.RequestID)
+	r.Use(middleware.RealIP(trustedProxies))
 	r.Use(middleware.Recoverer(logger))
 	r.Use(middleware.Logger(logger))
 	r.Use(middleware.SecurityHeaders)
+	r.Use(middleware.Compress)
+	r.Use(middleware.JSONBody(middleware.JSONBodyOptions{MaxBytes: int64(maxJSONBodyKB) << 10, Strict: strictJSON}))
 
 	// Strip trailing slashes to handle /api/albums and /api/albums/ consistently
 	r.Use(chimiddleware.StripSlashes)
@@ -135,9 +550,9 @@ func main() {
 	// CORS middleware (allow frontend in development)
 	r.Use(cors.Handler(cors.Options{
 		AllowedOrigins:   []string{"http://localhost:5173", "http://localhost:3000"},
-		AllowedMethods:   []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"},
-		AllowedHeaders:   []string{"Accept", "Authorization", "Content-Type", "X-Request-ID"},
-		ExposedHeaders:   []string{"X-Request-ID"},
+		AllowedMethods:   []string{"GET", "POST", "PUT", "PATCH", "DELETE", "OPTIONS"},
+		AllowedHeaders:   []string{"Accept", "Authorization", "Content-Type", "X-Request-ID", middleware.APIKeyHeader},
+		ExposedHeaders:   []string{"X-Request-ID", "X-Content-SHA256", "X-Print-Warning", "X-Conversion-Warning", "X-Print-DPI", "X-Print-Effective-DPI", "X-RateLimit-Limit", "X-RateLimit-Remaining", "X-RateLimit-Reset", "Retry-After", "Link"},
 		AllowCredentials: true,
 		MaxAge:           300,
 	}))
@@ -148,8 +563,77 @@ func main() {
 		_, _ = w.Write([]byte(`{"status":"ok"}`))
 	})
 
-	// Public album download endpoint (no auth required, respects allow_downloads flag)
-	r.Get("/api/albums/{slug}/download", albumHandler.DownloadAlbum)
+	// Readiness endpoint (public): fails while a data store is unreadable
+	r.Get("/api/readyz", healthHandler.Ready)
+
+	// Public album endpoints, mounted under /api for the default namespace and
+	// under /api/a/{namespace} for namespaced albums
+	mountPublicAlbumRoutes := func(r chi.Router, prefix string) {
+		// Restricted albums require an access cookie or token
+		r.Group(func(r chi.Router) {
+			r.Use(canonicalSlug)
+			r.Use(middleware.AlbumAccess(albumService, albumAuthService, logger))
+
+			// Album download (respects allow_downloads flag); ZIPs are limited to MAX_CONCURRENT_DOWNLOADS at once
+			r.With(downloadLimit).Get(prefix+"/albums/{slug}/download", albumHandler.DownloadAlbum)
+			r.Get(prefix+"/albums/{slug}/download/manifest", albumHandler.DownloadManifest)
+			r.With(downloadLimit).Get(prefix+"/albums/{slug}/export-html", albumHandler.ExportHTML)
+			r.Get(prefix+"/albums/{slug}/photos/{photoId}/download", albumHandler.DownloadPhoto)
+			r.Get(prefix+"/albums/{slug}/photos/{photoId}/print", albumHandler.PrintPhoto)
+
+			// Lightbox navigation
+			r.Get(prefix+"/albums/{slug}/photos/{photoId}/neighbors", albumHandler.GetPhotoNeighbors)
+
+			// The first screenful of thumbnails, for the gallery to load first
+			r.Get(prefix+"/albums/{slug}/preload", albumHandler.GetPreload)
+
+			// Photo permalinks
+			r.Get(prefix+"/p/{slug}/{photoSlug}", albumHandler.GetPhotoPermalink)
+
+			// Clients share back their picks of an album's photos as a selection link
+			r.With(selectionLimit).Post(prefix+"/albums/{slug}/selections", albumHandler.CreateSelection)
+
+			// Client contributions to albums whose upload_policy is clients; admins may always upload
+			r.With(clientUploadLimit, middleware.OptionalAuth(authService)).Post(prefix+"/albums/{slug}/photos/upload", albumHandler.UploadPhotos)
+		})
+
+		// Read-only album data for mirrors, rate limited per API key or per client address
+		r.Group(func(r chi.Router) {
+			r.Use(publicAPILimit)
+			r.Get(prefix+"/public/albums", albumHandler.GetPublicAlbums)
+			r.With(canonicalSlug).Get(prefix+"/public/albums/{slug}", albumHandler.GetPublicAlbum)
+		})
+
+		// Album names and photo counts for navigation; admins also see unlisted and restricted albums
+		r.With(middleware.OptionalAuth(authService)).Get(prefix+"/albums/summaries", albumHandler.GetSummaries)
+
+		// Several albums by ID or slug in one request, rate limited like the read-only album data;
+		// admins get restricted albums and admin-only fields too
+		r.With(publicAPILimit, middleware.OptionalAuth(authService)).Post(prefix+"/albums/batch", albumHandler.GetBatch)
+
+		// Several albums in one ZIP; each album's access is checked by the handler
+		r.With(downloadLimit).Post(prefix+"/download-multi", albumHandler.DownloadMultiple)
+
+		// The visitor session's recently viewed albums, for a "continue browsing" strip
+		r.Get(prefix+"/recently-viewed", albumHandler.GetRecentlyViewed)
+
+		// Magic access links for albums with a client access list
+		r.With(accessLinkLimit).Post(prefix+"/albums/{slug}/request-access", albumHandler.RequestAccessLink)
+		r.With(canonicalSlug).Get(prefix+"/albums/{slug}/access", albumHandler.OpenAccessLink)
+	}
+	mountPublicAlbumRoutes(r, "/api")
+	mountPublicAlbumRoutes(r, "/api/a/{namespace}")
+
+	// Public gear statistics (public albums only)
+	r.Get("/api/stats/gear", statsHandler.GetGearStats)
+
+	// Shared photo selections, opened by their token alone
+	r.Get("/api/selections/{token}", albumHandler.GetSelection)
+
+	// Public album password verification (issues an album access cookie), rate limited
+	// against guessing
+	r.With(middleware.RateLimit(middleware.NewRateLimiter(), middleware.DefaultPasswordCheckRateLimit)).
+		Post("/api/albums/verify-password", albumHandler.VerifyPassword)
 
 	// Data endpoints for Admin Frontend
 	r.Route("/api", func(r chi.Router) {
@@ -157,7 +641,28 @@ func main() {
 
 		// Album endpoints
 		r.Get("/albums", albumHandler.GetAll)
+		r.Get("/albums/diff", albumHandler.GetAlbumDiff)
 		r.Get("/albums/{id}", albumHandler.GetByID)
+		r.Get("/albums/{id}/incomplete", albumHandler.GetIncompletePhotos)
+		r.Get("/albums/{id}/duplicates", albumHandler.GetDuplicatePhotos)
+		r.Get("/albums/{id}/quality-flags", albumHandler.GetQualityFlags)
+		r.Get("/albums/{id}/history", albumHandler.GetHistory)
+		r.Get("/albums/{id}/date-histogram", albumHandler.GetDateHistogram)
+		r.Get("/albums/{id}/stats", albumHandler.GetStats)
+		r.Get("/albums/{id}/tags", albumHandler.GetTags)
+		r.Get("/albums/{id}/photos", albumHandler.GetPhotos)
+		r.Get("/albums/{id}/photos.geojson", albumHandler.GetPhotosGeoJSON)
+		r.Get("/albums/{id}/cover", albumHandler.GetCover)
+		r.Get("/albums/{id}/selections", albumHandler.ListSelections)
+
+		// Reorder the album index into a color gradient by cover hue
+		r.Post("/albums/reorder-by-color", albumHandler.ReorderByColor)
+
+		// Where a file is used, by content hash, across all albums
+		r.Get("/photos/by-hash/{hash}", albumHandler.GetPhotosByHash)
+
+		// File types and sizes accepted for upload
+		r.Get("/upload-config", albumHandler.GetUploadConfig)
 
 		// Site config
 		r.Get("/config", configHandler.Get)
@@ -182,30 +687,85 @@ func main() {
 			// Album management
 			r.Post("/albums", albumHandler.Create)
 			r.Put("/albums/{id}", albumHandler.Update)
+			r.Put("/albums/by-slug/{slug}", albumHandler.Upsert)
 			r.Delete("/albums/{id}", albumHandler.Delete)
+			r.Post("/albums/{id}/pin", albumHandler.Pin)
+			r.Post("/albums/{id}/unpin", albumHandler.Unpin)
 			r.Post("/albums/{id}/photos/upload", albumHandler.UploadPhotos)
+			r.Post("/albums/{id}/upload-zip", albumHandler.UploadZIP)
+			r.Post("/albums/{id}/upload-urls", directUploadHandler.IssueUploadURLs)
+			r.Post("/albums/{id}/upload-urls/finalize", directUploadHandler.FinalizeUploads)
 			r.Delete("/albums/{id}/photos", albumHandler.DeleteAllPhotos)
 			r.Delete("/albums/{id}/photos/{photoId}", albumHandler.DeletePhoto)
 			r.Post("/albums/{id}/set-cover", albumHandler.SetCoverPhoto)
 			r.Post("/albums/{id}/clear-cover", albumHandler.ClearCoverPhoto)
+			r.Post("/albums/{id}/covers", albumHandler.AddCoverPhoto)
+			r.Delete("/albums/{id}/covers/{photoId}", albumHandler.RemoveCoverPhoto)
+			r.Post("/albums/{id}/reorder-covers", albumHandler.ReorderCoverPhotos)
 			r.Post("/albums/{id}/reorder-photos", albumHandler.ReorderPhotos)
+			r.Post("/albums/{id}/photos/swap", albumHandler.SwapPhotos)
+			r.Patch("/albums/{id}/photos/positions", albumHandler.MovePhotos)
+			r.Post("/albums/{id}/photos/{photoId}/move-to", albumHandler.MovePhotoTo)
+			r.Post("/albums/{id}/auto-section", albumHandler.AutoSection)
+			r.Patch("/albums/{id}/theme", albumHandler.SetTheme)
+			r.Patch("/albums/{id}/download-settings", albumHandler.SetDownloadSettings)
+			r.Patch("/albums/{id}/schedule", albumHandler.SetSchedule)
+			r.Post("/albums/{id}/photos/{photoId}/regenerate", albumHandler.RegeneratePhoto)
 			r.Post("/albums/{id}/set-password", albumHandler.SetPassword)
-			r.Delete("/albums/{id}/password", albumHandler.RemovePassword) // Site configuration
+			r.Delete("/albums/{id}/password", albumHandler.RemovePassword)
+			r.With(middleware.RateLimit(middleware.NewRateLimiter(), middleware.DefaultPasswordCheckRateLimit)).
+				Post("/albums/{id}/verify-password", albumHandler.CheckPassword)
+			r.Post("/sessions/revoke-all", albumHandler.RevokeAllSessions)
+			r.Get("/integrity", albumHandler.CheckIntegrity)
+			r.Post("/import-folder", importHandler.ImportFolder)
+
+			// Site configuration
 			r.Put("/config", configHandler.Update)
 			r.Put("/config/main-portfolio-album", configHandler.SetMainPortfolioAlbum)
 
+			// Defaults applied to newly created albums
+			r.Get("/album-defaults", albumDefaultsHandler.Get)
+			r.Put("/album-defaults", albumDefaultsHandler.Update)
+
 			// Auth management
 			r.Post("/change-password", authHandler.ChangePassword)
 
 			// Storage management
+			r.Get("/storage", storageHandler.GetUsage)
 			r.Get("/storage/stats", storageHandler.GetStats)
+			r.Get("/overview", storageHandler.GetOverview)
+
+			// Album data backups to the second location
+			r.Get("/backups", backupHandler.List)
+			r.Post("/backup", backupHandler.Create)
+			r.Post("/restore", backupHandler.Restore)
+
+			// Background jobs
+			r.Post("/regenerate", jobHandler.Regenerate)
+			r.Post("/reprocess-exif", jobHandler.ReprocessEXIF)
+			r.Post("/backfill", jobHandler.Backfill)
+			r.Get("/jobs/{id}", jobHandler.Get)
+
+			// API keys for the public read endpoints
+			r.Get("/api-keys", apiKeyHandler.List)
+			r.Post("/api-keys", apiKeyHandler.Issue)
+			r.Delete("/api-keys/{id}", apiKeyHandler.Revoke)
 		})
 	})
 
-	// Serve static files (uploaded images)
-	workDir, _ := os.Getwd()
-	staticPath := filepath.Join(workDir, uploadDir)
-	r.Handle("/uploads/*", http.StripPrefix("/uploads/", http.FileServer(http.Dir(staticPath))))
+	// Serve uploaded images from whichever storage backend holds them
+	uploadsHandler := handlers.NewUploadsHandler(imageService, logger)
+	uploadsHandler.SetCacheMaxAge(time.Duration(imageCacheMaxAge) * time.Second)
+	uploadsHandler.SetAlbumService(albumService)
+	// Admins may fetch files that need a signed URL without one
+	uploadsAuth := func(next http.Handler) http.Handler { return next }
+	if imageURLSigner != nil {
+		uploadsHandler.SetURLSigner(imageURLSigner)
+		uploadsAuth = middleware.OptionalAuth(authService)
+	}
+	r.With(uploadsAuth).Handle("/uploads/*", http.StripPrefix("/uploads/", uploadsHandler))
+	// Album covers are part of the public read API, so mirrors fetching them are rate limited too
+	r.With(publicAPILimit, uploadsAuth).Handle("/uploads/covers/*", http.StripPrefix("/uploads/", uploadsHandler))
 
 	// Start server
 	addr := ":" + port
@@ -224,10 +784,32 @@ func main() {
 		IdleTimeout:       10 * time.Minute, // Keep-alive timeout
 	}
 
-	if err := server.ListenAndServe(); err != nil {
+	// On SIGINT or SIGTERM, finish the requests in flight, then save what is only in memory
+	stop := make(chan os.Signal, 1)
+	signal.Notify(stop, os.Interrupt, syscall.SIGTERM)
+	shutdown := make(chan struct{})
+	go func() {
+		<-stop
+		logger.Info("admin server shutting down")
+		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		defer cancel()
+		if err := server.Shutdown(ctx); err != nil {
+			logger.Error("server shutdown failed", slog.String("error", err.Error()))
+		}
+		close(shutdown)
+	}()
+
+	if err := server.ListenAndServe(); !errors.Is(err, http.ErrServerClosed) {
 		logger.Error("server failed", slog.String("error", err.Error()))
 		os.Exit(1)
 	}
+	<-shutdown
+
+	if viewTracker != nil {
+		if err := viewTracker.Flush(); err != nil {
+			logger.Error("failed to save album views", slog.String("error", err.Error()))
+		}
+	}
 }
 
 // getEnv gets an environment variable with a default value.
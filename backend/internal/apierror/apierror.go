@@ -0,0 +1,26 @@
+// Package apierror writes structured JSON error responses.
+package apierror
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+)
+
+// Response is the JSON body returned for API errors.
+type Response struct {
+	Error     string `json:"error"`
+	RequestID string `json:"request_id,omitempty"`
+}
+
+// Write sends a JSON error response with the given status code.
+// requestID may be empty when the request has none.
+func Write(w http.ResponseWriter, status int, message, requestID string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(Response{Error: message, RequestID: requestID}); err != nil {
+		// Log error but can't send another response
+		slog.Error("failed to encode JSON error response", slog.String("error", err.Error()))
+	}
+}
@@ -0,0 +1,68 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/njoubert/nielsshootsfilm/backend/internal/models"
+)
+
+// AddCoverPhoto adds {"photo_id": "..."} to the end of the album's cover carousel and responds
+// with the resolved covers, as for GetCover.
+func (h *AlbumHandler) AddCoverPhoto(w http.ResponseWriter, r *http.Request) {
+	albumID := chi.URLParam(r, "id")
+
+	var req struct {
+		PhotoID string `json:"photo_id"`
+	}
+	if !decodeJSON(w, r, &req) {
+		return
+	}
+	if req.PhotoID == "" {
+		http.Error(w, "photo_id is required", http.StatusBadRequest)
+		return
+	}
+
+	album, err := h.albumService.AddCoverPhoto(albumID, req.PhotoID)
+	h.respondCovers(w, album, err)
+}
+
+// RemoveCoverPhoto takes a photo out of the album's cover carousel, keeping it in the album,
+// and responds with the resolved covers.
+func (h *AlbumHandler) RemoveCoverPhoto(w http.ResponseWriter, r *http.Request) {
+	album, err := h.albumService.RemoveCoverPhoto(chi.URLParam(r, "id"), chi.URLParam(r, "photoId"))
+	h.respondCovers(w, album, err)
+}
+
+// ReorderCoverPhotos puts the album's covers in the order of {"photo_ids": [...]}, which must
+// list each cover once, and responds with the resolved covers.
+func (h *AlbumHandler) ReorderCoverPhotos(w http.ResponseWriter, r *http.Request) {
+	albumID := chi.URLParam(r, "id")
+
+	var req struct {
+		PhotoIDs []string `json:"photo_ids"`
+	}
+	if !decodeJSON(w, r, &req) {
+		return
+	}
+	if len(req.PhotoIDs) == 0 {
+		http.Error(w, "photo_ids array is required", http.StatusBadRequest)
+		return
+	}
+
+	album, err := h.albumService.ReorderCoverPhotos(albumID, req.PhotoIDs)
+	h.respondCovers(w, album, err)
+}
+
+// respondCovers finishes a change to an album's covers: it reports err, or re-renders the
+// clean cover, since the first cover may have changed, and responds with the resolved covers.
+func (h *AlbumHandler) respondCovers(w http.ResponseWriter, album *models.Album, err error) {
+	if err != nil {
+		h.respondChangeError(w, err, "failed to change cover photos")
+		return
+	}
+
+	h.refreshCover(album.ID)
+
+	respondJSON(w, http.StatusOK, h.resolvedCover(album))
+}
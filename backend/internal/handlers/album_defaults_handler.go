@@ -0,0 +1,56 @@
+package handlers
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+
+	"github.com/njoubert/nielsshootsfilm/backend/internal/models"
+	"github.com/njoubert/nielsshootsfilm/backend/internal/services"
+)
+
+// AlbumDefaultsHandler handles requests for the defaults applied to new albums.
+type AlbumDefaultsHandler struct {
+	defaultsService *services.AlbumDefaultsService
+	logger          *slog.Logger
+}
+
+// NewAlbumDefaultsHandler creates a new album defaults handler.
+func NewAlbumDefaultsHandler(defaultsService *services.AlbumDefaultsService, logger *slog.Logger) *AlbumDefaultsHandler {
+	return &AlbumDefaultsHandler{
+		defaultsService: defaultsService,
+		logger:          logger,
+	}
+}
+
+// Get returns the current album defaults.
+func (h *AlbumDefaultsHandler) Get(w http.ResponseWriter, r *http.Request) {
+	defaults, err := h.defaultsService.Get()
+	if err != nil {
+		h.logger.Error("failed to get album defaults", slog.String("error", err.Error()))
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	respondJSON(w, http.StatusOK, defaults)
+}
+
+// Update replaces the album defaults.
+func (h *AlbumDefaultsHandler) Update(w http.ResponseWriter, r *http.Request) {
+	var defaults models.AlbumDefaults
+	if !decodeJSON(w, r, &defaults) {
+		return
+	}
+
+	if err := h.defaultsService.Update(&defaults); err != nil {
+		if errors.Is(err, services.ErrValidation) {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		h.logger.Error("failed to update album defaults", slog.String("error", err.Error()))
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	respondJSON(w, http.StatusOK, defaults)
+}
@@ -0,0 +1,123 @@
+package handlers
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/njoubert/nielsshootsfilm/backend/internal/models"
+	"github.com/njoubert/nielsshootsfilm/backend/internal/services"
+)
+
+// DiffPhoto is a photo in an album comparison.
+type DiffPhoto struct {
+	ID               string `json:"id"`
+	FilenameOriginal string `json:"filename_original"`
+	URLThumbnail     string `json:"url_thumbnail"`
+	ContentHash      string `json:"content_hash,omitempty"`
+}
+
+// DiffSharedPhoto is a file found in both compared albums, with its photo in each.
+type DiffSharedPhoto struct {
+	ContentHash string    `json:"content_hash"`
+	A           DiffPhoto `json:"a"`
+	B           DiffPhoto `json:"b"`
+}
+
+// DiffAlbum identifies one side of an album comparison.
+type DiffAlbum struct {
+	ID    string `json:"id"`
+	Title string `json:"title"`
+}
+
+// AlbumDiff is the comparison of two albums' photo sets.
+type AlbumDiff struct {
+	A         DiffAlbum         `json:"a"`
+	B         DiffAlbum         `json:"b"`
+	OnlyA     []DiffPhoto       `json:"only_a"`
+	OnlyB     []DiffPhoto       `json:"only_b"`
+	Shared    []DiffSharedPhoto `json:"shared"`
+	UnhashedA []DiffPhoto       `json:"unhashed_a"` // Photos predating content hashes, not compared
+	UnhashedB []DiffPhoto       `json:"unhashed_b"`
+}
+
+// GetAlbumDiff compares the photos of albums ?a= and ?b= by content hash, listing the files
+// unique to each and those they share, e.g. to reconcile a proof album with its final cut.
+func (h *AlbumHandler) GetAlbumDiff(w http.ResponseWriter, r *http.Request) {
+	idA := r.URL.Query().Get("a")
+	idB := r.URL.Query().Get("b")
+	if idA == "" || idB == "" {
+		http.Error(w, "Both a and b album IDs are required", http.StatusBadRequest)
+		return
+	}
+
+	albumA, ok := h.albumByID(w, idA)
+	if !ok {
+		return
+	}
+	albumB, ok := h.albumByID(w, idB)
+	if !ok {
+		return
+	}
+
+	diff := services.DiffPhotos(albumA, albumB)
+	shared := make([]DiffSharedPhoto, 0, len(diff.Shared))
+	for _, pair := range diff.Shared {
+		shared = append(shared, DiffSharedPhoto{
+			ContentHash: pair.A.ContentHash,
+			A:           diffPhoto(pair.A),
+			B:           diffPhoto(pair.B),
+		})
+	}
+
+	respondJSON(w, http.StatusOK, AlbumDiff{
+		A:         DiffAlbum{ID: albumA.ID, Title: albumA.Title},
+		B:         DiffAlbum{ID: albumB.ID, Title: albumB.Title},
+		OnlyA:     diffPhotos(diff.OnlyA),
+		OnlyB:     diffPhotos(diff.OnlyB),
+		Shared:    shared,
+		UnhashedA: diffPhotos(diff.UnhashedA),
+		UnhashedB: diffPhotos(diff.UnhashedB),
+	})
+}
+
+func diffPhoto(photo models.Photo) DiffPhoto {
+	return DiffPhoto{
+		ID:               photo.ID,
+		FilenameOriginal: photo.FilenameOriginal,
+		URLThumbnail:     photo.URLThumbnail,
+		ContentHash:      photo.ContentHash,
+	}
+}
+
+func diffPhotos(photos []models.Photo) []DiffPhoto {
+	out := make([]DiffPhoto, 0, len(photos))
+	for _, photo := range photos {
+		out = append(out, diffPhoto(photo))
+	}
+	return out
+}
+
+// GetPhotosByHash lists the albums and photos referencing a file by its SHA-256 content hash,
+// e.g. to see where a shared file is still used before deleting one of its photos.
+func (h *AlbumHandler) GetPhotosByHash(w http.ResponseWriter, r *http.Request) {
+	hash := chi.URLParam(r, "hash")
+
+	refs, err := h.albumService.FindByContentHash(hash)
+	if err != nil {
+		if errors.Is(err, services.ErrInvalidContentHash) {
+			http.Error(w, "Invalid hash: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+		h.logger.Error("failed to find photos by hash", slog.String("error", err.Error()))
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	respondJSON(w, http.StatusOK, map[string]any{
+		"hash":       strings.ToLower(hash),
+		"references": refs,
+	})
+}
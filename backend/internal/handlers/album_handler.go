@@ -1,24 +1,69 @@
 package handlers
 
 import (
+	"archive/zip"
 	"encoding/json"
 	"errors"
+	"fmt"
+	"io"
 	"log/slog"
+	"mime/multipart"
 	"net/http"
+	"net/url"
+	"os"
+	"slices"
+	"strconv"
 	"strings"
+	"sync"
+	"time"
 
 	"github.com/go-chi/chi/v5"
 	"github.com/njoubert/nielsshootsfilm/backend/internal"
+	"github.com/njoubert/nielsshootsfilm/backend/internal/apierror"
+	"github.com/njoubert/nielsshootsfilm/backend/internal/middleware"
 	"github.com/njoubert/nielsshootsfilm/backend/internal/models"
 	"github.com/njoubert/nielsshootsfilm/backend/internal/services"
 	"golang.org/x/crypto/bcrypt"
 )
 
+// DefaultUploadConcurrency is how many files of one upload request are processed at once.
+const DefaultUploadConcurrency = 4
+
+// DefaultMaxZIPUploadSize is the largest ZIP archive of photos accepted in one upload.
+const DefaultMaxZIPUploadSize = 5000 << 20
+
+// DefaultClientUploadMaxPhotos is how many photos clients may upload to one album in all.
+const DefaultClientUploadMaxPhotos = 200
+
+// DefaultClientUploadMaxSize is how many bytes of photos clients may upload to one album in all.
+const DefaultClientUploadMaxSize = 2048 << 20
+
+// DefaultDownloadQuality is the quality of album downloads that do not ask for one.
+const DefaultDownloadQuality = "display"
+
+// IsDownloadQuality reports whether quality is a quality level photos can be downloaded at.
+func IsDownloadQuality(quality string) bool {
+	return slices.Contains(models.DownloadQualities, quality)
+}
+
 // AlbumHandler handles album-related HTTP requests.
 type AlbumHandler struct {
-	albumService *services.AlbumService
-	imageService *services.ImageService
-	logger       *slog.Logger
+	albumService      *services.AlbumService
+	imageService      *services.ImageService
+	albumAuthService  *services.AlbumAuthService
+	regenerateService *services.RegenerateService
+	mailer            services.Mailer
+	publicURL         string
+	uploadConcurrency int
+	maxZIPUploadSize  int64
+	clientMaxPhotos   int
+	clientMaxSize     int64
+	downloadQuality   string // Album download quality when ?quality= is omitted
+	failureHook       *services.UploadFailureHook
+	viewTracker       *services.ViewTracker
+	selectionService  *services.SelectionService
+	imageURLs         *services.ImageURLSigner
+	logger            *slog.Logger
 }
 
 // NewAlbumHandler creates a new album handler.
@@ -28,14 +73,93 @@ func NewAlbumHandler(
 	logger *slog.Logger,
 ) *AlbumHandler {
 	return &AlbumHandler{
-		albumService: albumService,
-		imageService: imageService,
-		logger:       logger,
+		albumService:      albumService,
+		imageService:      imageService,
+		uploadConcurrency: DefaultUploadConcurrency,
+		maxZIPUploadSize:  DefaultMaxZIPUploadSize,
+		clientMaxPhotos:   DefaultClientUploadMaxPhotos,
+		clientMaxSize:     DefaultClientUploadMaxSize,
+		downloadQuality:   DefaultDownloadQuality,
+		logger:            logger,
+	}
+}
+
+// SetUploadConcurrency sets how many files of one upload request are processed at once.
+// Values below 1 are treated as 1.
+func (h *AlbumHandler) SetUploadConcurrency(n int) {
+	h.uploadConcurrency = max(n, 1)
+}
+
+// SetMaxZIPUploadSize sets the largest ZIP archive of photos accepted in one upload, in bytes.
+// Values below 1 are treated as 1.
+func (h *AlbumHandler) SetMaxZIPUploadSize(size int64) {
+	h.maxZIPUploadSize = max(size, 1)
+}
+
+// SetClientUploadLimits sets how many photos, and how many bytes of them, clients may upload
+// to one album in all, for albums accepting client uploads. Admin uploads do not count.
+// Values below 0 are treated as 0.
+func (h *AlbumHandler) SetClientUploadLimits(maxPhotos int, maxSize int64) {
+	h.clientMaxPhotos = max(maxPhotos, 0)
+	h.clientMaxSize = max(maxSize, 0)
+}
+
+// SetDefaultDownloadQuality sets the quality album downloads use when the request does not
+// ask for one. Values that are not a download quality are ignored.
+func (h *AlbumHandler) SetDefaultDownloadQuality(quality string) {
+	if IsDownloadQuality(quality) {
+		h.downloadQuality = quality
 	}
 }
 
-// GetAll returns all albums.
+// SetAlbumAuthService configures the service used to grant access to password-protected albums.
+// Without it, password-protected albums cannot be accessed through public endpoints.
+func (h *AlbumHandler) SetAlbumAuthService(albumAuthService *services.AlbumAuthService) {
+	h.albumAuthService = albumAuthService
+}
+
+// SetRegenerateService configures the service used to re-render an album's thumbnails in the
+// background when its thumbnail fit changes. Without it, existing thumbnails keep their old fit.
+func (h *AlbumHandler) SetRegenerateService(regenerateService *services.RegenerateService) {
+	h.regenerateService = regenerateService
+}
+
+// SetMailer configures the mailer used to send magic access links for albums with a client
+// access list. publicURL is the site's external base URL, used to build the links.
+// Without a mailer, access links cannot be requested.
+func (h *AlbumHandler) SetMailer(mailer services.Mailer, publicURL string) {
+	h.mailer = mailer
+	h.publicURL = strings.TrimSuffix(publicURL, "/")
+}
+
+// SetUploadFailureHook reports uploads that fail to process or to be added to their album.
+// Without it, failures are only logged and returned to the client.
+func (h *AlbumHandler) SetUploadFailureHook(hook *services.UploadFailureHook) {
+	h.failureHook = hook
+}
+
+// SetViewTracker counts the visitor sessions that view each album through the public
+// album endpoint. Without it, views are not counted.
+func (h *AlbumHandler) SetViewTracker(viewTracker *services.ViewTracker) {
+	h.viewTracker = viewTracker
+}
+
+// SetImageURLSigner signs the photo URLs of albums as visitors see them, for an uploads
+// handler that only serves signed URLs. Without it, URLs are returned as stored.
+func (h *AlbumHandler) SetImageURLSigner(signer *services.ImageURLSigner) {
+	h.imageURLs = signer
+}
+
+// GetAll returns all albums. With ?as=visitor, admins see only the albums a visitor
+// would find listed: public ones that need no access token.
+// ?offset= and ?limit= (at most 200) select a page, which a Link header links to the next
+// and previous pages of; "total" counts every album listed.
 func (h *AlbumHandler) GetAll(w http.ResponseWriter, r *http.Request) {
+	offset, limit, ok := pageParams(w, r, 0, MaxListPageSize)
+	if !ok {
+		return
+	}
+
 	albums, err := h.albumService.GetAll()
 	if err != nil {
 		h.logger.Error("failed to get albums", slog.String("error", err.Error()))
@@ -43,12 +167,26 @@ func (h *AlbumHandler) GetAll(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if previewAsVisitor(r) {
+		listed := make([]models.Album, 0, len(albums))
+		for i := range albums {
+			if albums[i].Visibility == "public" && !albums[i].RequiresAccessToken() {
+				listed = append(listed, h.visitorAlbum(albums[i]))
+			}
+		}
+		albums = listed
+	}
+	services.SortPinnedFirst(albums)
+
+	setPageLinks(w, r, offset, limit, len(albums))
 	respondJSON(w, http.StatusOK, map[string]any{
-		"albums": albums,
+		"albums": page(albums, offset, limit),
+		"total":  len(albums),
 	})
 }
 
-// GetByID returns a single album by ID.
+// GetByID returns a single album by ID. With ?as=visitor, admins get the album as a visitor
+// would: restricted albums need an access token, and admin-only fields are left out.
 func (h *AlbumHandler) GetByID(w http.ResponseWriter, r *http.Request) {
 	id := chi.URLParam(r, "id")
 
@@ -63,50 +201,137 @@ func (h *AlbumHandler) GetByID(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	respondJSON(w, http.StatusOK, album)
+	if previewAsVisitor(r) {
+		if !h.hasAlbumAccess(r, album) {
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+		preview := h.visitorAlbum(*album)
+		album = &preview
+	}
+
+	respondJSON(w, http.StatusOK, sectionedAlbum{Album: album, GroupedPhotos: album.GroupedPhotos()})
 }
 
-// Create creates a new album.
-func (h *AlbumHandler) Create(w http.ResponseWriter, r *http.Request) {
-	var album models.Album
-	if err := json.NewDecoder(r.Body).Decode(&album); err != nil {
-		http.Error(w, "Invalid request body", http.StatusBadRequest)
+// sectionedAlbum is an album along with its photos grouped by section, for albums that have
+// sections. The flat photos list is still included.
+type sectionedAlbum struct {
+	*models.Album
+	GroupedPhotos []models.PhotoSection `json:"grouped_photos,omitempty"`
+}
+
+// AutoSection replaces the album's sections with ones derived from photo EXIF data, grouped
+// by ?by= (only "day", the default, is supported).
+func (h *AlbumHandler) AutoSection(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+
+	by := r.URL.Query().Get("by")
+	if by == "" {
+		by = services.SectionByDay
+	}
+	if by != services.SectionByDay {
+		http.Error(w, fmt.Sprintf("unsupported grouping %q (supported: %s)", by, services.SectionByDay), http.StatusBadRequest)
 		return
 	}
 
-	if err := h.albumService.Create(&album); err != nil {
-		h.logger.Error("failed to create album", slog.String("error", err.Error()))
-		http.Error(w, err.Error(), http.StatusBadRequest)
+	album, err := h.albumService.AutoSection(id, by)
+	if err != nil {
+		if err.Error() == "album not found" {
+			http.Error(w, "Album not found", http.StatusNotFound)
+			return
+		}
+		h.logger.Error("failed to section album", slog.String("error", err.Error()))
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
 		return
 	}
 
-	respondJSON(w, http.StatusCreated, album)
+	respondJSON(w, http.StatusOK, sectionedAlbum{Album: album, GroupedPhotos: album.GroupedPhotos()})
 }
 
-// Update updates an existing album.
-func (h *AlbumHandler) Update(w http.ResponseWriter, r *http.Request) {
+// GetDateHistogram counts the album's photos per EXIF capture ?bucket= (day, week, or month;
+// default day), with undated photos in an "unknown" bucket.
+func (h *AlbumHandler) GetDateHistogram(w http.ResponseWriter, r *http.Request) {
 	id := chi.URLParam(r, "id")
 
-	var updates models.Album
-	if err := json.NewDecoder(r.Body).Decode(&updates); err != nil {
-		http.Error(w, "Invalid request body", http.StatusBadRequest)
+	bucket := r.URL.Query().Get("bucket")
+	if bucket == "" {
+		bucket = services.BucketDay
+	}
+
+	album, err := h.albumService.GetByID(id)
+	if err != nil {
+		if err.Error() == "album not found" {
+			http.Error(w, "Album not found", http.StatusNotFound)
+			return
+		}
+		h.logger.Error("failed to get album", slog.String("error", err.Error()))
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
 		return
 	}
 
-	if err := h.albumService.Update(id, &updates); err != nil {
-		h.logger.Error("failed to update album", slog.String("error", err.Error()))
+	histogram, err := services.DateHistogram(album.Photos, bucket)
+	if err != nil {
 		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
 	}
 
-	respondJSON(w, http.StatusOK, updates)
+	respondJSON(w, http.StatusOK, map[string]any{
+		"bucket":  bucket,
+		"buckets": histogram,
+	})
 }
 
-// Delete deletes an album.
-func (h *AlbumHandler) Delete(w http.ResponseWriter, r *http.Request) {
+// GetPhotosGeoJSON returns the album's geotagged photos as a GeoJSON FeatureCollection of
+// points, for mapping tools. Photos without a GPS position are left out, and only albums
+// that share locations keep positions at all.
+func (h *AlbumHandler) GetPhotosGeoJSON(w http.ResponseWriter, r *http.Request) {
 	id := chi.URLParam(r, "id")
 
-	// Get album to delete photos
+	album, ok := h.albumByID(w, id)
+	if !ok {
+		return
+	}
+
+	w.Header().Set("Content-Type", services.GeoJSONContentType)
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(services.PhotosGeoJSON(album.Photos)); err != nil {
+		h.logger.Error("failed to encode GeoJSON response", slog.String("error", err.Error()))
+	}
+}
+
+// IncompletePhoto is a photo lacking some required metadata.
+type IncompletePhoto struct {
+	ID               string   `json:"id"`
+	FilenameOriginal string   `json:"filename_original"`
+	URLThumbnail     string   `json:"url_thumbnail"`
+	Missing          []string `json:"missing"`
+}
+
+// GetIncompletePhotos lists the album's photos that are missing any of the fields in
+// ?require= (comma-separated: title, alt, caption; default title,alt), in album order.
+func (h *AlbumHandler) GetIncompletePhotos(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+
+	required := []string{"title", "alt"}
+	if param := r.URL.Query().Get("require"); param != "" {
+		required = []string{}
+		for _, field := range strings.Split(param, ",") {
+			field = strings.TrimSpace(field)
+			if field == "" || slices.Contains(required, field) {
+				continue
+			}
+			if !slices.Contains(models.PhotoMetadataFields, field) {
+				http.Error(w, fmt.Sprintf("unknown field %q (supported: %s)", field, strings.Join(models.PhotoMetadataFields, ", ")), http.StatusBadRequest)
+				return
+			}
+			required = append(required, field)
+		}
+		if len(required) == 0 {
+			http.Error(w, "require must list at least one field", http.StatusBadRequest)
+			return
+		}
+	}
+
 	album, err := h.albumService.GetByID(id)
 	if err != nil {
 		if err.Error() == "album not found" {
@@ -118,184 +343,969 @@ func (h *AlbumHandler) Delete(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Delete all photos from filesystem
-	for _, photo := range album.Photos {
-		if err := h.imageService.DeletePhoto(&photo); err != nil {
-			h.logger.Warn("failed to delete photo file",
-				slog.String("photo_id", photo.ID),
-				slog.String("error", err.Error()),
-			)
+	incomplete := []IncompletePhoto{}
+	for i := range album.Photos {
+		photo := &album.Photos[i]
+		if missing := photo.MissingMetadata(required); len(missing) > 0 {
+			incomplete = append(incomplete, IncompletePhoto{
+				ID:               photo.ID,
+				FilenameOriginal: photo.FilenameOriginal,
+				URLThumbnail:     photo.URLThumbnail,
+				Missing:          missing,
+			})
 		}
 	}
 
-	// Delete album from JSON
-	if err := h.albumService.Delete(id); err != nil {
-		h.logger.Error("failed to delete album", slog.String("error", err.Error()))
-		http.Error(w, "Internal server error", http.StatusInternalServerError)
-		return
-	}
+	respondJSON(w, http.StatusOK, map[string]any{
+		"require": required,
+		"photos":  incomplete,
+		"total":   len(album.Photos),
+	})
+}
 
-	w.WriteHeader(http.StatusNoContent)
+// DuplicatePhoto is a photo in a cluster of near-duplicates.
+type DuplicatePhoto struct {
+	ID               string `json:"id"`
+	FilenameOriginal string `json:"filename_original"`
+	URLThumbnail     string `json:"url_thumbnail"`
+	PerceptualHash   string `json:"perceptual_hash"`
 }
 
-// UploadPhotos handles photo upload to an album.
-func (h *AlbumHandler) UploadPhotos(w http.ResponseWriter, r *http.Request) {
-	albumID := chi.URLParam(r, "id")
+// GetDuplicatePhotos lists clusters of near-identical photos in the album: photos whose
+// perceptual hashes differ by at most ?threshold= bits (0-64, default 10). Photos uploaded
+// before hashes were recorded have none and are not compared.
+func (h *AlbumHandler) GetDuplicatePhotos(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
 
-	// Verify album exists
-	if _, err := h.albumService.GetByID(albumID); err != nil {
+	threshold := services.DefaultDuplicateThreshold
+	if param := r.URL.Query().Get("threshold"); param != "" {
+		n, err := strconv.Atoi(param)
+		if err != nil || n < 0 || n > services.PerceptualHashBits {
+			http.Error(w, fmt.Sprintf("Invalid threshold parameter. Must be an integer from 0 to %d", services.PerceptualHashBits), http.StatusBadRequest)
+			return
+		}
+		threshold = n
+	}
+
+	album, err := h.albumService.GetByID(id)
+	if err != nil {
 		if err.Error() == "album not found" {
 			http.Error(w, "Album not found", http.StatusNotFound)
 			return
 		}
+		h.logger.Error("failed to get album", slog.String("error", err.Error()))
 		http.Error(w, "Internal server error", http.StatusInternalServerError)
 		return
 	}
 
-	// Parse multipart form
-	// Each request contains one file, but allow some overhead for form metadata
-	maxFormSize := int64(internal.MaxUploadFileSize + (10 * 1024 * 1024)) // Max file size + 10MB overhead
-	if err := r.ParseMultipartForm(maxFormSize); err != nil {
-		// Check if this is a timeout or connection error
-		errMsg := err.Error()
-		if strings.Contains(errMsg, "timeout") || strings.Contains(errMsg, "timed out") {
-			http.Error(w, "Upload timed out at the server. Try uploading smaller files or use a faster connection.", http.StatusRequestTimeout)
-			return
-		}
-		if strings.Contains(errMsg, "connection reset") || strings.Contains(errMsg, "broken pipe") {
-			http.Error(w, "Connection lost during upload. Please try again.", http.StatusBadRequest)
-			return
+	clusters := [][]DuplicatePhoto{}
+	for _, cluster := range services.DuplicateClusters(album.Photos, threshold) {
+		photos := make([]DuplicatePhoto, 0, len(cluster))
+		for _, photo := range cluster {
+			photos = append(photos, DuplicatePhoto{
+				ID:               photo.ID,
+				FilenameOriginal: photo.FilenameOriginal,
+				URLThumbnail:     photo.URLThumbnail,
+				PerceptualHash:   photo.PerceptualHash,
+			})
 		}
-		if errors.Is(err, http.ErrHandlerTimeout) {
-			http.Error(w, "Upload timed out at the server. Please try uploading smaller files or use a faster connection.", http.StatusRequestTimeout)
-			return
+		clusters = append(clusters, photos)
+	}
+
+	respondJSON(w, http.StatusOK, map[string]any{
+		"threshold": threshold,
+		"clusters":  clusters,
+	})
+}
+
+// QualityFlag is a photo flagged as badly exposed.
+type QualityFlag struct {
+	ID               string          `json:"id"`
+	FilenameOriginal string          `json:"filename_original"`
+	URLThumbnail     string          `json:"url_thumbnail"`
+	Flag             string          `json:"flag"` // underexposed, overexposed
+	Exposure         models.Exposure `json:"exposure"`
+}
+
+// GetQualityFlags lists the album's photos that are notably under- or overexposed, judged by
+// the brightness statistics recorded on upload. Thresholds can be set with ?dark=, ?bright=
+// (mean luminance, 0-1), and ?clipped= (fraction of clipped pixels). Photos uploaded before
+// statistics were recorded are counted as unmeasured.
+func (h *AlbumHandler) GetQualityFlags(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+
+	thresholds := services.DefaultExposureThresholds
+	for name, threshold := range map[string]*float64{
+		"dark":    &thresholds.Dark,
+		"bright":  &thresholds.Bright,
+		"clipped": &thresholds.Clipped,
+	} {
+		if param := r.URL.Query().Get(name); param != "" {
+			v, err := strconv.ParseFloat(param, 64)
+			if err != nil {
+				http.Error(w, fmt.Sprintf("Invalid %s parameter. Must be a number from 0 to 1", name), http.StatusBadRequest)
+				return
+			}
+			*threshold = v
 		}
-		// Generic parse error
-		http.Error(w, "Failed to parse form: "+err.Error(), http.StatusBadRequest)
+	}
+	if err := thresholds.Validate(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
 	}
 
-	files := r.MultipartForm.File["photos"]
-	if len(files) == 0 {
-		http.Error(w, "No files uploaded", http.StatusBadRequest)
+	album, err := h.albumService.GetByID(id)
+	if err != nil {
+		if err.Error() == "album not found" {
+			http.Error(w, "Album not found", http.StatusNotFound)
+			return
+		}
+		h.logger.Error("failed to get album", slog.String("error", err.Error()))
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
 		return
 	}
 
-	// Process each file
-	uploadedPhotos := []models.Photo{}
-	errors := []string{}
-
-	for _, fileHeader := range files {
-		photo, err := h.imageService.ProcessUpload(fileHeader)
-		if err != nil {
-			h.logger.Error("failed to process upload",
-				slog.String("filename", fileHeader.Filename),
-				slog.String("error", err.Error()),
-			)
-			errors = append(errors, fileHeader.Filename+": "+err.Error())
+	flagged := []QualityFlag{}
+	unmeasured := 0
+	for _, photo := range album.Photos {
+		if photo.Exposure == nil {
+			unmeasured++
 			continue
 		}
-
-		// Add photo to album
-		if err := h.albumService.AddPhoto(albumID, photo); err != nil {
-			h.logger.Error("failed to add photo to album",
-				slog.String("filename", fileHeader.Filename),
-				slog.String("error", err.Error()),
-			)
-			errors = append(errors, fileHeader.Filename+": "+err.Error())
-			continue
+		if flag := thresholds.Flag(photo.Exposure); flag != "" {
+			flagged = append(flagged, QualityFlag{
+				ID:               photo.ID,
+				FilenameOriginal: photo.FilenameOriginal,
+				URLThumbnail:     photo.URLThumbnail,
+				Flag:             flag,
+				Exposure:         *photo.Exposure,
+			})
 		}
-
-		uploadedPhotos = append(uploadedPhotos, *photo)
 	}
 
 	respondJSON(w, http.StatusOK, map[string]any{
-		"uploaded": uploadedPhotos,
-		"errors":   errors,
+		"thresholds": thresholds,
+		"photos":     flagged,
+		"unmeasured": unmeasured,
 	})
 }
 
-// DeletePhoto deletes a photo from an album.
-func (h *AlbumHandler) DeletePhoto(w http.ResponseWriter, r *http.Request) {
-	albumID := chi.URLParam(r, "id")
-	photoID := chi.URLParam(r, "photoId")
+// DefaultHistoryPageSize is how many history entries a page holds when no limit is given.
+const DefaultHistoryPageSize = 50
 
-	// Get album to find photo
-	album, err := h.albumService.GetByID(albumID)
-	if err != nil {
-		http.Error(w, "Album not found", http.StatusNotFound)
+// MaxHistoryPageSize is the largest page of history entries that may be requested.
+const MaxHistoryPageSize = 200
+
+// GetHistory returns a page of the album's change history, oldest first, selected by
+// ?offset= and ?limit= (default 50, at most 200). A Link header points at the next and
+// previous pages.
+func (h *AlbumHandler) GetHistory(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+
+	offset, limit, ok := pageParams(w, r, DefaultHistoryPageSize, MaxHistoryPageSize)
+	if !ok {
 		return
 	}
 
-	// Find photo
-	var photo *models.Photo
-	for i := range album.Photos {
-		if album.Photos[i].ID == photoID {
-			photo = &album.Photos[i]
-			break
+	entries, total, err := h.albumService.History(id, offset, limit)
+	if err != nil {
+		if err.Error() == "album not found" {
+			http.Error(w, "Album not found", http.StatusNotFound)
+			return
 		}
+		h.logger.Error("failed to get album history", slog.String("error", err.Error()))
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
 	}
 
-	if photo == nil {
-		http.Error(w, "Photo not found", http.StatusNotFound)
+	setPageLinks(w, r, offset, limit, total)
+	respondJSON(w, http.StatusOK, map[string]any{
+		"entries": entries,
+		"total":   total,
+		"offset":  offset,
+		"limit":   limit,
+	})
+}
+
+// Create creates a new album.
+func (h *AlbumHandler) Create(w http.ResponseWriter, r *http.Request) {
+	// Start from the configured defaults so fields omitted from the body inherit them
+	album, err := h.albumService.NewAlbum()
+	if err != nil {
+		h.logger.Error("failed to load album defaults", slog.String("error", err.Error()))
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
 		return
 	}
 
-	// Delete photo files
-	if err := h.imageService.DeletePhoto(photo); err != nil {
-		h.logger.Warn("failed to delete photo files",
-			slog.String("photo_id", photoID),
-			slog.String("error", err.Error()),
-		)
+	if !decodeJSON(w, r, album) {
+		return
 	}
 
-	// Delete photo from album
-	if err := h.albumService.DeletePhoto(albumID, photoID); err != nil {
-		h.logger.Error("failed to delete photo from album", slog.String("error", err.Error()))
-		http.Error(w, "Internal server error", http.StatusInternalServerError)
+	if err := h.albumService.Create(album); err != nil {
+		h.logger.Error("failed to create album", slog.String("error", err.Error()))
+		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
 	}
 
-	w.WriteHeader(http.StatusNoContent)
+	respondJSON(w, http.StatusCreated, album)
 }
 
-// DeleteAllPhotos deletes all photos from an album.
-func (h *AlbumHandler) DeleteAllPhotos(w http.ResponseWriter, r *http.Request) {
-	albumID := chi.URLParam(r, "id")
+// Update updates an existing album.
+func (h *AlbumHandler) Update(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
 
-	// Get album
-	album, err := h.albumService.GetByID(albumID)
-	if err != nil {
-		http.Error(w, "Album not found", http.StatusNotFound)
+	var updates models.Album
+	if !decodeJSON(w, r, &updates) {
 		return
 	}
 
-	// Delete all photo files
-	var deletionErrors []string
-	for i := range album.Photos {
-		if err := h.imageService.DeletePhoto(&album.Photos[i]); err != nil {
-			h.logger.Warn("failed to delete photo files",
-				slog.String("photo_id", album.Photos[i].ID),
-				slog.String("error", err.Error()),
-			)
-			deletionErrors = append(deletionErrors, album.Photos[i].ID)
-		}
+	// The cover URL is managed by the server; keep the current one so refreshCover can clean it up
+	existing, err := h.albumService.GetByID(id)
+	if err == nil {
+		updates.CoverURL = existing.CoverURL
 	}
 
-	// Delete all photos from album
-	if err := h.albumService.DeleteAllPhotos(albumID); err != nil {
-		h.logger.Error("failed to delete all photos from album", slog.String("error", err.Error()))
-		http.Error(w, "Internal server error", http.StatusInternalServerError)
+	if err := h.albumService.Update(id, &updates); err != nil {
+		h.logger.Error("failed to update album", slog.String("error", err.Error()))
+		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
 	}
 
-	// Return result
-	response := map[string]any{
-		"deleted": len(album.Photos) - len(deletionErrors),
-		"total":   len(album.Photos),
-	}
-	if len(deletionErrors) > 0 {
-		response["errors"] = deletionErrors
+	// Toggling the watermark settings may change the cover rendering
+	updates.CoverURL = h.refreshCover(id)
+	if existing != nil {
+		h.refreshThumbnails(existing, &updates)
+		h.refreshDisplays(existing, &updates)
+	}
+
+	respondJSON(w, http.StatusOK, updates)
+}
+
+// Upsert creates or updates the album with the {slug} URL parameter, in the namespace given by
+// ?namespace= (default namespace if empty), responding 201 when it was created and 200 when it was
+// updated. On update, fields omitted from the body keep their current values; in particular the
+// photos are only replaced when the body includes them. The URL decides the slug and namespace.
+func (h *AlbumHandler) Upsert(w http.ResponseWriter, r *http.Request) {
+	slug := chi.URLParam(r, "slug")
+	namespace := r.URL.Query().Get("namespace")
+
+	// Read before the store is locked; it is decoded once the album is known
+	var body json.RawMessage
+	if !decodeJSON(w, r, &body) {
+		return
+	}
+	// A new album starts from the configured defaults so fields omitted from the body inherit them
+	created, err := h.albumService.NewAlbum()
+	if err != nil {
+		h.logger.Error("failed to load album defaults", slog.String("error", err.Error()))
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	existing, album, err := h.albumService.Upsert(namespace, slug, func(existing *models.Album) (*models.Album, error) {
+		if existing == nil {
+			return created, decodeRawJSON(r, body, created)
+		}
+
+		// Decode over the stored album so omitted fields are kept
+		updates := upsertBase(existing)
+		if err := decodeRawJSON(r, body, &updates); err != nil {
+			return nil, err
+		}
+		restoreOmittedSlices(&updates, existing)
+		// The cover URL is managed by the server; keep the current one so refreshCover can clean it up
+		updates.CoverURL = existing.CoverURL
+		return &updates, nil
+	})
+	var invalidBody *invalidBodyError
+	switch {
+	case errors.As(err, &invalidBody):
+		http.Error(w, invalidBody.message, http.StatusBadRequest)
+		return
+	case err != nil:
+		h.logger.Error("failed to upsert album", slog.String("error", err.Error()))
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if existing == nil {
+		respondJSON(w, http.StatusCreated, album)
+		return
+	}
+
+	// Toggling the watermark settings may change the cover rendering
+	album.CoverURL = h.refreshCover(album.ID)
+	h.refreshThumbnails(existing, album)
+	h.refreshDisplays(existing, album)
+
+	respondJSON(w, http.StatusOK, album)
+}
+
+// upsertBase returns a copy of a stored album for a request body to be decoded over. Its slices
+// are cleared, since decoding into a non-empty slice would merge the sent elements into the
+// stored ones field by field, and its pointers are copied, so decoding does not write through
+// to the stored album.
+func upsertBase(existing *models.Album) models.Album {
+	base := *existing
+	base.CoverPhotoIDs = nil
+	base.AllowedEmails = nil
+	base.ScheduledChanges = nil
+	base.DownloadQualities = nil
+	base.FilmStocks = nil
+	base.Tags = nil
+	base.Sections = nil
+	base.Photos = nil
+	base.ExpirationDate = clonePointer(existing.ExpirationDate)
+	base.MinUploadEdgePx = clonePointer(existing.MinUploadEdgePx)
+	base.LastPhotoAddedAt = clonePointer(existing.LastPhotoAddedAt)
+	base.AlbumStartDate = clonePointer(existing.AlbumStartDate)
+	base.AlbumEndDate = clonePointer(existing.AlbumEndDate)
+	return base
+}
+
+// restoreOmittedSlices puts back the stored slices a body decoded over upsertBase left out.
+// An empty list sent in the body clears one.
+func restoreOmittedSlices(updates, existing *models.Album) {
+	if updates.CoverPhotoIDs == nil {
+		updates.CoverPhotoIDs = existing.CoverPhotoIDs
+	}
+	if updates.AllowedEmails == nil {
+		updates.AllowedEmails = existing.AllowedEmails
+	}
+	if updates.ScheduledChanges == nil {
+		updates.ScheduledChanges = existing.ScheduledChanges
+	}
+	if updates.DownloadQualities == nil {
+		updates.DownloadQualities = existing.DownloadQualities
+	}
+	if updates.FilmStocks == nil {
+		updates.FilmStocks = existing.FilmStocks
+	}
+	if updates.Tags == nil {
+		updates.Tags = existing.Tags
+	}
+	if updates.Sections == nil {
+		updates.Sections = existing.Sections
+	}
+	if updates.Photos == nil {
+		updates.Photos = existing.Photos
+	}
+}
+
+// clonePointer returns a pointer to a copy of *p, or nil for nil.
+func clonePointer[T any](p *T) *T {
+	if p == nil {
+		return nil
+	}
+	clone := *p
+	return &clone
+}
+
+// Delete deletes an album.
+func (h *AlbumHandler) Delete(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+
+	// Get album to delete photos
+	album, err := h.albumService.GetByID(id)
+	if err != nil {
+		if err.Error() == "album not found" {
+			http.Error(w, "Album not found", http.StatusNotFound)
+			return
+		}
+		h.logger.Error("failed to get album", slog.String("error", err.Error()))
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	// Delete all photos from filesystem, keeping files other albums' photos share
+	owning, err := h.albumService.PhotosOwningFiles(id, album.Photos)
+	if err != nil {
+		h.logger.Error("failed to check shared photo files", slog.String("error", err.Error()))
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+	for _, photo := range owning {
+		if err := h.imageService.DeletePhoto(&photo); err != nil {
+			h.logger.Warn("failed to delete photo file",
+				slog.String("photo_id", photo.ID),
+				slog.String("error", err.Error()),
+			)
+		}
+	}
+
+	if err := h.imageService.DeleteCover(album); err != nil {
+		h.logger.Warn("failed to delete album cover",
+			slog.String("album_id", id),
+			slog.String("error", err.Error()),
+		)
+	}
+	h.imageService.InvalidateAlbumZIPs(id)
+
+	// Delete album from JSON
+	if err := h.albumService.Delete(id); err != nil {
+		h.logger.Error("failed to delete album", slog.String("error", err.Error()))
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// UploadPhotos handles photo upload to an album, named by ID on the admin route and by slug
+// on the public one. On the public route, callers without an admin session get 403 unless
+// the album's upload policy accepts clients and they hold an access token for it.
+func (h *AlbumHandler) UploadPhotos(w http.ResponseWriter, r *http.Request) {
+	album, ok := h.uploadableAlbum(w, r)
+	if !ok {
+		return
+	}
+	albumID := album.ID
+	if !h.checkUploadSpace(w, albumID) {
+		return
+	}
+
+	// Client uploads are capped per album, by the body size before anything is read
+	clientUpload := chi.URLParam(r, "id") == "" && middleware.GetSession(r.Context()) == nil
+	clientPhotosLeft := 0
+	if clientUpload {
+		photosLeft, sizeLeft := h.clientUploadAllowance(album)
+		if photosLeft == 0 || sizeLeft == 0 {
+			http.Error(w, "This album has reached its limit for client uploads", http.StatusForbidden)
+			return
+		}
+		clientPhotosLeft = photosLeft
+		r.Body = http.MaxBytesReader(w, r.Body, sizeLeft)
+	}
+
+	// Parse multipart form
+	// Each request contains one file, but allow some overhead for form metadata
+	maxFormSize := int64(internal.MaxUploadFileSize + (10 * 1024 * 1024)) // Max file size + 10MB overhead
+	if err := r.ParseMultipartForm(maxFormSize); err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			http.Error(w, "Upload exceeds what clients may still upload to this album", http.StatusRequestEntityTooLarge)
+			return
+		}
+		// Check if this is a timeout or connection error
+		errMsg := err.Error()
+		if strings.Contains(errMsg, "timeout") || strings.Contains(errMsg, "timed out") {
+			http.Error(w, "Upload timed out at the server. Try uploading smaller files or use a faster connection.", http.StatusRequestTimeout)
+			return
+		}
+		if strings.Contains(errMsg, "connection reset") || strings.Contains(errMsg, "broken pipe") {
+			http.Error(w, "Connection lost during upload. Please try again.", http.StatusBadRequest)
+			return
+		}
+		if errors.Is(err, http.ErrHandlerTimeout) {
+			http.Error(w, "Upload timed out at the server. Please try uploading smaller files or use a faster connection.", http.StatusRequestTimeout)
+			return
+		}
+		// Generic parse error
+		http.Error(w, "Failed to parse form: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	files := r.MultipartForm.File["photos"]
+	if len(files) == 0 {
+		http.Error(w, "No files uploaded", http.StatusBadRequest)
+		return
+	}
+	if clientUpload && len(files) > clientPhotosLeft {
+		http.Error(w, fmt.Sprintf("Clients may upload at most %d more photos to this album", clientPhotosLeft), http.StatusRequestEntityTooLarge)
+		return
+	}
+
+	captureDates, err := parseCaptureDates(r.MultipartForm.Value["capture_date"], len(files))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	// The allowance read above may since have gone to other uploads, so claim it for good
+	if clientUpload && !h.reserveClientUploads(w, albumID, files) {
+		return
+	}
+
+	names := make([]string, len(files))
+	for i, fileHeader := range files {
+		names[i] = fileHeader.Filename
+	}
+	uploads := h.imageService.Uploads(album)
+	processed := h.processUploads(len(files), func(i int) processedUpload {
+		photo, err := uploads.ProcessUpload(files[i])
+		if err == nil && captureDates[i] != nil {
+			services.SetCaptureDate(photo, *captureDates[i])
+		}
+		if err == nil {
+			photo.ClientUploaded = clientUpload
+		}
+		return h.finishUpload(album, uploads, files[i].Filename, photo, err)
+	})
+	resp := newUploadResponse()
+	h.addUploads(albumID, names, processed, resp)
+
+	respondJSON(w, http.StatusOK, resp)
+}
+
+// checkUploadSpace turns an upload away with 507 while the storage volume is below the
+// configured free space, before the request body is read.
+func (h *AlbumHandler) checkUploadSpace(w http.ResponseWriter, albumID string) bool {
+	return checkStorageSpace(w, h.imageService, h.logger, albumID)
+}
+
+// checkStorageSpace turns a request that stores photos away with 507 while the storage volume
+// is below the configured free space. It reports whether the request may go ahead; if free
+// space cannot be read, the checks made as each file is stored decide.
+func checkStorageSpace(w http.ResponseWriter, imageService *services.ImageService, logger *slog.Logger, albumID string) bool {
+	err := imageService.CheckUploadSpace()
+	switch {
+	case err == nil:
+		return true
+	case errors.Is(err, services.ErrInsufficientStorage):
+		logger.Warn("upload rejected for low storage space",
+			slog.String("album_id", albumID),
+			slog.String("error", err.Error()),
+		)
+		http.Error(w, err.Error(), http.StatusInsufficientStorage)
+		return false
+	default:
+		logger.Warn("failed to check free storage space", slog.String("error", err.Error()))
+		return true
+	}
+}
+
+// parseCaptureDates parses the capture_date form values of an upload of count files: none,
+// one for every file, or one per file in file order, where an empty value leaves that file's
+// EXIF date. It returns the override for each file, or nil where there is none.
+func parseCaptureDates(values []string, count int) ([]*time.Time, error) {
+	dates := make([]*time.Time, count)
+	switch len(values) {
+	case 0:
+		return dates, nil
+	case 1, count:
+	default:
+		return nil, fmt.Errorf("expected 1 or %d capture_date values, got %d", count, len(values))
+	}
+
+	for i := range dates {
+		value := values[0]
+		if len(values) == count {
+			value = values[i]
+		}
+		if strings.TrimSpace(value) == "" {
+			continue
+		}
+		date, err := services.ParseCaptureDate(value)
+		if err != nil {
+			return nil, err
+		}
+		dates[i] = &date
+	}
+	return dates, nil
+}
+
+// uploadableAlbum loads the album to upload to and checks that the request may upload to it.
+// The admin route names the album by ID and is behind admin authentication; the public route
+// names it by slug and admits admins and, for albums accepting client uploads, clients with
+// an access token. On failure it writes the error response and returns false.
+func (h *AlbumHandler) uploadableAlbum(w http.ResponseWriter, r *http.Request) (*models.Album, bool) {
+	if albumID := chi.URLParam(r, "id"); albumID != "" {
+		album, err := h.albumService.GetByID(albumID)
+		if err != nil {
+			if err.Error() == "album not found" {
+				http.Error(w, "Album not found", http.StatusNotFound)
+				return nil, false
+			}
+			http.Error(w, "Internal server error", http.StatusInternalServerError)
+			return nil, false
+		}
+		return album, true
+	}
+
+	album, err := h.albumFromPath(r)
+	if err != nil {
+		if err.Error() == "album not found" {
+			h.respondAlbumNotFound(w, r)
+			return nil, false
+		}
+		h.logger.Error("failed to get album", slog.String("error", err.Error()))
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return nil, false
+	}
+
+	if !h.canUpload(r, album) {
+		h.logger.Warn("album upload denied",
+			slog.String("album_id", album.ID),
+			slog.String("upload_policy", album.UploadPolicy),
+		)
+		http.Error(w, "Uploads to this album are not permitted", http.StatusForbidden)
+		return nil, false
+	}
+
+	return album, true
+}
+
+// clientUploadAllowance returns how many more photos, and bytes of uploads, clients may add
+// to an album before reaching the client upload limits, counting everything clients have
+// already uploaded to it.
+func (h *AlbumHandler) clientUploadAllowance(album *models.Album) (int, int64) {
+	return max(h.clientMaxPhotos-album.ClientUploads, 0), max(h.clientMaxSize-album.ClientUploadBytes, 0)
+}
+
+// reserveClientUploads counts a client's files, by the bytes received, toward the album's
+// client upload limits. On failure, including when the upload would pass a limit, it writes
+// the error response and returns false.
+func (h *AlbumHandler) reserveClientUploads(w http.ResponseWriter, albumID string, files []*multipart.FileHeader) bool {
+	var size int64
+	for _, fileHeader := range files {
+		size += fileHeader.Size
+	}
+
+	err := h.albumService.ReserveClientUploads(albumID, len(files), size, h.clientMaxPhotos, h.clientMaxSize)
+	switch {
+	case err == nil:
+		return true
+	case errors.Is(err, services.ErrClientUploadLimit):
+		http.Error(w, "Upload exceeds what clients may still upload to this album", http.StatusRequestEntityTooLarge)
+	case err.Error() == "album not found":
+		http.Error(w, "Album not found", http.StatusNotFound)
+	default:
+		h.logger.Error("failed to reserve client uploads",
+			slog.String("album_id", albumID),
+			slog.String("error", err.Error()))
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+	}
+	return false
+}
+
+// canUpload reports whether the request may upload photos to an album: always with an admin
+// session, and otherwise only if the album accepts client uploads and the request carries an
+// access token for it.
+func (h *AlbumHandler) canUpload(r *http.Request, album *models.Album) bool {
+	if middleware.GetSession(r.Context()) != nil {
+		return true
+	}
+	if !album.AcceptsClientUploads() || h.albumAuthService == nil {
+		return false
+	}
+	return h.albumAuthService.HasToken(r, album)
+}
+
+// UploadZIP uploads the photos in a ZIP archive sent as the request body, adding them to the
+// album in archive order. Entries with unsafe paths and files that are not images are
+// reported as failed ahead of the photos, which may also fail to process.
+func (h *AlbumHandler) UploadZIP(w http.ResponseWriter, r *http.Request) {
+	albumID := chi.URLParam(r, "id")
+
+	// Verify album exists
+	album, err := h.albumService.GetByID(albumID)
+	if err != nil {
+		if err.Error() == "album not found" {
+			http.Error(w, "Album not found", http.StatusNotFound)
+			return
+		}
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+	if !h.checkUploadSpace(w, albumID) {
+		return
+	}
+
+	// Stage the archive on disk, since ZIP entries are read by offset
+	tmpFile, err := os.CreateTemp("", "upload-*.zip")
+	if err != nil {
+		h.logger.Error("failed to create temporary ZIP file", slog.String("error", err.Error()))
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+	defer func() {
+		_ = tmpFile.Close()
+		_ = os.Remove(tmpFile.Name())
+	}()
+
+	size, err := io.Copy(tmpFile, http.MaxBytesReader(w, r.Body, h.maxZIPUploadSize))
+	if err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			http.Error(w, fmt.Sprintf("ZIP archive exceeds the %dMB upload limit", h.maxZIPUploadSize>>20), http.StatusRequestEntityTooLarge)
+			return
+		}
+		http.Error(w, "Failed to read ZIP archive: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	// Unsafe entry names are reported per entry below rather than rejecting the whole archive
+	archive, err := zip.NewReader(tmpFile, size)
+	if err != nil && !errors.Is(err, zip.ErrInsecurePath) {
+		http.Error(w, "Invalid ZIP archive: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	entries := h.imageService.ZIPImageEntries(archive)
+	if len(entries) == 0 {
+		http.Error(w, "ZIP archive contains no photos", http.StatusBadRequest)
+		return
+	}
+
+	// Only the accepted entries are processed; rejected ones keep their place in the results
+	names := make([]string, len(entries))
+	processed := make([]processedUpload, len(entries))
+	accepted := []int{}
+	for i, entry := range entries {
+		names[i] = entry.Name
+		if entry.File == nil {
+			processed[i] = processedUpload{rejected: entry.Reason}
+			continue
+		}
+		accepted = append(accepted, i)
+	}
+	uploads := h.imageService.Uploads(album)
+	for i, upload := range h.processUploads(len(accepted), func(i int) processedUpload {
+		entry := entries[accepted[i]]
+		photo, err := uploads.ProcessZIPEntry(entry.File)
+		return h.finishUpload(album, uploads, entry.Name, photo, err)
+	}) {
+		processed[accepted[i]] = upload
+	}
+	resp := newUploadResponse()
+	h.addUploads(albumID, names, processed, resp)
+
+	respondJSON(w, http.StatusOK, resp)
+}
+
+// UploadConfigResponse tells clients which files uploads accept before they send them.
+type UploadConfigResponse struct {
+	services.UploadConfig
+	MaxZIPSizeBytes int64 `json:"max_zip_size_bytes"` // Largest archive accepted by the ZIP upload endpoint
+}
+
+// GetUploadConfig returns the file extensions, MIME types, and sizes uploads currently accept.
+func (h *AlbumHandler) GetUploadConfig(w http.ResponseWriter, r *http.Request) {
+	respondJSON(w, http.StatusOK, UploadConfigResponse{
+		UploadConfig:    h.imageService.UploadConfig(),
+		MaxZIPSizeBytes: h.maxZIPUploadSize,
+	})
+}
+
+// processedUpload is the outcome of processing one uploaded file. Files turned away before
+// processing, like unsupported ZIP entries, have only the reason they were rejected.
+type processedUpload struct {
+	photo    *models.Photo
+	err      error
+	rejected string
+}
+
+// processUploads runs process for files 0 to n-1 on a bounded pool of workers. Results keep
+// the order files were sent in.
+func (h *AlbumHandler) processUploads(n int, process func(i int) processedUpload) []processedUpload {
+	processed := make([]processedUpload, n)
+	indexes := make(chan int)
+	var wg sync.WaitGroup
+	for range min(h.uploadConcurrency, n) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range indexes {
+				processed[i] = process(i)
+			}
+		}()
+	}
+	for i := range n {
+		indexes <- i
+	}
+	close(indexes)
+	wg.Wait()
+	return processed
+}
+
+// addUploads adds processed uploads to the album in order, recording each file's outcome in resp.
+func (h *AlbumHandler) addUploads(albumID string, names []string, processed []processedUpload, resp *UploadResponse) {
+	// Add photos to the album one at a time, so albums.json writes stay serialized
+	added := 0
+	for i, name := range names {
+		photo, err := processed[i].photo, processed[i].err
+		if processed[i].rejected != "" {
+			resp.failed(name, processed[i].rejected)
+			continue
+		}
+		if err != nil {
+			h.failureHook.Notify(albumID, name, err)
+			resp.failed(name, err.Error())
+			continue
+		}
+
+		// Add photo to album
+		if err := h.albumService.AddPhoto(albumID, photo); err != nil {
+			h.logger.Error("failed to add photo to album",
+				slog.String("filename", name),
+				slog.String("error", err.Error()),
+			)
+			h.failureHook.Notify(albumID, name, err)
+			resp.failed(name, err.Error())
+			continue
+		}
+
+		resp.succeeded(name, *photo)
+		added++
+	}
+
+	// The first upload into an empty album becomes its cover
+	if added > 0 {
+		h.refreshCover(albumID)
+	}
+}
+
+// finishUpload takes a stored upload and its derivatives, or the error processing it, and
+// watermarks the display version if the album is watermarked. It does not touch the album.
+func (h *AlbumHandler) finishUpload(album *models.Album, uploads services.AlbumUploads, filename string, photo *models.Photo, err error) processedUpload {
+	if err != nil {
+		h.logger.Error("failed to process upload",
+			slog.String("filename", filename),
+			slog.String("error", err.Error()),
+		)
+		return processedUpload{err: err}
+	}
+
+	renderUploadVersions(h.imageService, h.logger, album, uploads, filename, photo)
+	return processedUpload{photo: photo}
+}
+
+// renderUploadVersions re-renders a new upload's display version and thumbnail in place
+// where the album's settings differ from the site's, e.g. a watermark or another thumbnail
+// fit. Failures are logged and leave the site's versions.
+func renderUploadVersions(imageService *services.ImageService, logger *slog.Logger, album *models.Album, uploads services.AlbumUploads, filename string, photo *models.Photo) {
+	// Repeat uploads share files already rendered for this album
+	if uploads.Reused(photo) {
+		return
+	}
+
+	// Re-render the gallery display version if the album is watermarked or sized differently.
+	// Lazily rendered versions are made this way when they are first requested.
+	if (album.WatermarkEnabled || imageService.DisplayMaxEdge(album) != imageService.DisplayMaxEdge(nil)) && !photo.DerivativesPending {
+		rendered, err := imageService.RenderDisplay(album, *photo)
+		if err != nil {
+			logger.Warn("failed to render display version",
+				slog.String("filename", filename),
+				slog.String("error", err.Error()),
+			)
+		} else {
+			*photo = rendered
+		}
+	}
+
+	// Re-crop the thumbnail if the album overrides the site's thumbnail fit
+	if imageService.ThumbnailFit(album) != imageService.ThumbnailFit(nil) && !photo.DerivativesPending {
+		rendered, err := imageService.RenderThumbnail(album, *photo)
+		if err != nil {
+			logger.Warn("failed to render thumbnail",
+				slog.String("filename", filename),
+				slog.String("error", err.Error()),
+			)
+		} else {
+			*photo = rendered
+		}
+	}
+}
+
+// DeletePhoto deletes a photo from an album.
+func (h *AlbumHandler) DeletePhoto(w http.ResponseWriter, r *http.Request) {
+	albumID := chi.URLParam(r, "id")
+	photoID := chi.URLParam(r, "photoId")
+
+	// Get album to find photo
+	album, err := h.albumService.GetByID(albumID)
+	if err != nil {
+		http.Error(w, "Album not found", http.StatusNotFound)
+		return
+	}
+
+	// Find photo
+	var photo *models.Photo
+	for i := range album.Photos {
+		if album.Photos[i].ID == photoID {
+			photo = &album.Photos[i]
+			break
+		}
+	}
+
+	if photo == nil {
+		http.Error(w, "Photo not found", http.StatusNotFound)
+		return
+	}
+
+	// Delete photo files, unless another photo shares them
+	owning, err := h.albumService.PhotosOwningFiles(albumID, []models.Photo{*photo})
+	if err != nil {
+		h.logger.Error("failed to check shared photo files", slog.String("error", err.Error()))
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+	for i := range owning {
+		if err := h.imageService.DeletePhoto(&owning[i]); err != nil {
+			h.logger.Warn("failed to delete photo files",
+				slog.String("photo_id", photoID),
+				slog.String("error", err.Error()),
+			)
+		}
+	}
+
+	// Delete photo from album
+	if err := h.albumService.DeletePhoto(albumID, photoID); err != nil {
+		h.logger.Error("failed to delete photo from album", slog.String("error", err.Error()))
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	h.refreshCover(albumID)
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// DeleteAllPhotos deletes all photos from an album.
+func (h *AlbumHandler) DeleteAllPhotos(w http.ResponseWriter, r *http.Request) {
+	albumID := chi.URLParam(r, "id")
+
+	// Get album
+	album, err := h.albumService.GetByID(albumID)
+	if err != nil {
+		http.Error(w, "Album not found", http.StatusNotFound)
+		return
+	}
+
+	// Delete all photo files, keeping files other albums' photos share
+	owning, err := h.albumService.PhotosOwningFiles(albumID, album.Photos)
+	if err != nil {
+		h.logger.Error("failed to check shared photo files", slog.String("error", err.Error()))
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+	var deletionErrors []string
+	for i := range owning {
+		if err := h.imageService.DeletePhoto(&owning[i]); err != nil {
+			h.logger.Warn("failed to delete photo files",
+				slog.String("photo_id", owning[i].ID),
+				slog.String("error", err.Error()),
+			)
+			deletionErrors = append(deletionErrors, owning[i].ID)
+		}
+	}
+
+	// Delete all photos from album
+	if err := h.albumService.DeleteAllPhotos(albumID); err != nil {
+		h.logger.Error("failed to delete all photos from album", slog.String("error", err.Error()))
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	h.refreshCover(albumID)
+
+	// Return result
+	response := map[string]any{
+		"deleted": len(album.Photos) - len(deletionErrors),
+		"total":   len(album.Photos),
+	}
+	if len(deletionErrors) > 0 {
+		response["errors"] = deletionErrors
 	}
 
 	w.Header().Set("Content-Type", "application/json")
@@ -305,146 +1315,1589 @@ func (h *AlbumHandler) DeleteAllPhotos(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
-// SetPassword sets a password for an album.
-func (h *AlbumHandler) SetPassword(w http.ResponseWriter, r *http.Request) {
-	albumID := chi.URLParam(r, "id")
+// SetPassword sets a password for an album.
+func (h *AlbumHandler) SetPassword(w http.ResponseWriter, r *http.Request) {
+	albumID := chi.URLParam(r, "id")
+
+	var req struct {
+		Password string `json:"password"`
+	}
+	if !decodeJSON(w, r, &req) {
+		return
+	}
+
+	// Get album
+	if _, err := h.albumService.GetByID(albumID); err != nil {
+		http.Error(w, "Album not found", http.StatusNotFound)
+		return
+	}
+
+	// Hash password
+	hash, err := bcrypt.GenerateFromPassword([]byte(req.Password), bcrypt.DefaultCost)
+	if err != nil {
+		h.logger.Error("failed to hash password", slog.String("error", err.Error()))
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	// Visitors who entered the old password must enter the new one
+	if !h.revokeAlbumSessions(w, albumID) {
+		return
+	}
+
+	// Update album
+	_, err = h.albumService.Modify(albumID, func(album *models.Album) error {
+		album.Visibility = "password_protected"
+		album.PasswordHash = string(hash)
+		return nil
+	})
+	if err != nil {
+		if err.Error() == "album not found" {
+			http.Error(w, "Album not found", http.StatusNotFound)
+			return
+		}
+		h.logger.Error("failed to update album", slog.String("error", err.Error()))
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+	h.revokeLateAlbumSessions(albumID)
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// CheckPassword reports whether a password matches a protected album's password, so an admin
+// can confirm what a client was given. Unlike VerifyPassword, no access token or cookie is issued.
+func (h *AlbumHandler) CheckPassword(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+
+	var req struct {
+		Password string `json:"password"`
+	}
+	if !decodeJSON(w, r, &req) {
+		return
+	}
+
+	album, err := h.albumService.GetByID(id)
+	if err != nil {
+		http.Error(w, "Album not found", http.StatusNotFound)
+		return
+	}
+
+	if album.PasswordHash == "" {
+		http.Error(w, "Album has no password", http.StatusConflict)
+		return
+	}
+
+	match := bcrypt.CompareHashAndPassword([]byte(album.PasswordHash), []byte(req.Password)) == nil
+
+	h.logger.Info("album password checked",
+		slog.String("album_id", album.ID),
+		slog.Bool("match", match),
+	)
+
+	respondJSON(w, http.StatusOK, map[string]bool{
+		"match": match,
+	})
+}
+
+// RemovePassword removes password protection from an album, along with any scheduled changes
+// back to password_protected, which would have no password left to protect it with.
+func (h *AlbumHandler) RemovePassword(w http.ResponseWriter, r *http.Request) {
+	albumID := chi.URLParam(r, "id")
+
+	if !h.revokeAlbumSessions(w, albumID) {
+		return
+	}
+
+	// Update album
+	_, err := h.albumService.Modify(albumID, func(album *models.Album) error {
+		album.Visibility = "public"
+		album.PasswordHash = ""
+		album.ScheduledChanges = slices.DeleteFunc(album.ScheduledChanges, func(change models.ScheduledChange) bool {
+			return change.Visibility == "password_protected"
+		})
+		return nil
+	})
+	if err != nil {
+		if err.Error() == "album not found" {
+			http.Error(w, "Album not found", http.StatusNotFound)
+			return
+		}
+		h.logger.Error("failed to update album", slog.String("error", err.Error()))
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+	h.revokeLateAlbumSessions(albumID)
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// revokeAlbumSessions ends the album access sessions opened for an album before its password
+// changes, so a failure leaves the password as it was. On failure it writes the error
+// response and returns false.
+func (h *AlbumHandler) revokeAlbumSessions(w http.ResponseWriter, albumID string) bool {
+	if err := h.endAlbumSessions(albumID); err != nil {
+		h.logger.Error("failed to revoke album sessions",
+			slog.String("album_id", albumID),
+			slog.String("error", err.Error()))
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return false
+	}
+	return true
+}
+
+// revokeLateAlbumSessions ends the sessions opened with an album's old password while the new
+// one was being saved. The change is saved by then, so a failure is logged rather than
+// reported.
+func (h *AlbumHandler) revokeLateAlbumSessions(albumID string) {
+	if err := h.endAlbumSessions(albumID); err != nil {
+		h.logger.Error("failed to revoke album sessions opened during a password change",
+			slog.String("album_id", albumID),
+			slog.String("error", err.Error()))
+	}
+}
+
+// endAlbumSessions revokes an album's access sessions, logging how many there were.
+func (h *AlbumHandler) endAlbumSessions(albumID string) error {
+	if h.albumAuthService == nil {
+		return nil
+	}
+	revoked, err := h.albumAuthService.RevokeSessions(albumID)
+	if err != nil {
+		return err
+	}
+	if revoked > 0 {
+		h.logger.Info("revoked album sessions",
+			slog.String("album_id", albumID),
+			slog.Int("sessions", revoked))
+	}
+	return nil
+}
+
+// RevokeAllSessions signs every visitor out of every restricted album, for incident response:
+// album access sessions are ended and the key access tokens are signed with is rotated, so
+// cookies and share links issued so far are rejected. Responds with the number of sessions
+// ended and when the key was rotated.
+func (h *AlbumHandler) RevokeAllSessions(w http.ResponseWriter, r *http.Request) {
+	if h.albumAuthService == nil {
+		http.Error(w, "Album authentication is not configured", http.StatusServiceUnavailable)
+		return
+	}
+
+	keys, revoked, err := h.albumAuthService.RevokeAll()
+	if err != nil {
+		h.logger.Error("failed to revoke all album sessions", slog.String("error", err.Error()))
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+	h.logger.Warn("revoked all album access",
+		slog.Int("sessions", revoked),
+		slog.Time("rotated_at", keys.RotatedAt))
+
+	respondJSON(w, http.StatusOK, map[string]any{
+		"revoked_sessions": revoked,
+		"rotated_at":       keys.RotatedAt,
+	})
+}
+
+// SetCoverPhoto sets the cover photo for an album.
+func (h *AlbumHandler) SetCoverPhoto(w http.ResponseWriter, r *http.Request) {
+	albumID := chi.URLParam(r, "id")
+
+	var req struct {
+		PhotoID string `json:"photo_id"`
+	}
+	if !decodeJSON(w, r, &req) {
+		return
+	}
+
+	if err := h.albumService.SetCoverPhoto(albumID, req.PhotoID); err != nil {
+		h.logger.Error("failed to set cover photo", slog.String("error", err.Error()))
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	h.refreshCover(albumID)
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// ClearCoverPhoto clears the album's chosen cover photo and responds with the resolved cover,
+// which is then the first photo.
+func (h *AlbumHandler) ClearCoverPhoto(w http.ResponseWriter, r *http.Request) {
+	albumID := chi.URLParam(r, "id")
+
+	if err := h.albumService.ClearCoverPhoto(albumID); err != nil {
+		h.logger.Error("failed to clear cover photo", slog.String("error", err.Error()))
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	h.refreshCover(albumID)
+
+	// Report the first photo that now stands in as the cover
+	album, err := h.albumService.GetByID(albumID)
+	if err != nil {
+		h.logger.Error("failed to get album", slog.String("error", err.Error()))
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+	respondJSON(w, http.StatusOK, h.resolvedCover(album))
+}
+
+// Cover sources in a ResolvedCover.
+const (
+	CoverSourceExplicit   = "explicit"    // The album's chosen cover photo
+	CoverSourceFirstPhoto = "first_photo" // No cover is chosen, so the first photo stands in
+	CoverSourceNone       = "none"        // The album has no photos
+)
+
+// ResolvedCover is the photo shown as an album's cover and why it was chosen. Photos lists
+// every cover of the album's carousel, starting with Photo.
+type ResolvedCover struct {
+	Photo  *models.Photo   `json:"photo"`
+	Photos []*models.Photo `json:"photos"`
+	Source string          `json:"source"`
+}
+
+// GetCover returns the photo shown as the album's cover and all of its carousel covers,
+// falling back to the first photo when none is chosen; photo is null for an empty album.
+func (h *AlbumHandler) GetCover(w http.ResponseWriter, r *http.Request) {
+	album, err := h.albumService.GetByID(chi.URLParam(r, "id"))
+	if err != nil {
+		if err.Error() == "album not found" {
+			http.Error(w, "Album not found", http.StatusNotFound)
+			return
+		}
+		h.logger.Error("failed to get album", slog.String("error", err.Error()))
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	respondJSON(w, http.StatusOK, h.resolvedCover(album))
+}
+
+// resolvedCover resolves the album's cover photos and reports where they came from.
+func (h *AlbumHandler) resolvedCover(album *models.Album) ResolvedCover {
+	covers := h.albumService.ResolveCovers(album)
+	switch {
+	case len(covers) == 0:
+		return ResolvedCover{Photos: covers, Source: CoverSourceNone}
+	case slices.Contains(album.CoverPhotoIDs, covers[0].ID):
+		return ResolvedCover{Photo: covers[0], Photos: covers, Source: CoverSourceExplicit}
+	default:
+		return ResolvedCover{Photo: covers[0], Photos: covers, Source: CoverSourceFirstPhoto}
+	}
+}
+
+// respondChangeError reports an error from changing an album: a missing album as a 404, a
+// change it cannot take as a 400 with the reason, and anything else as a logged 500.
+func (h *AlbumHandler) respondChangeError(w http.ResponseWriter, err error, msg string) {
+	switch {
+	case err.Error() == "album not found":
+		http.Error(w, "Album not found", http.StatusNotFound)
+	case errors.Is(err, services.ErrValidation):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	default:
+		h.logger.Error(msg, slog.String("error", err.Error()))
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+	}
+}
+
+// SetTheme sets the album's gallery accent color from {"accent_color": "#rrggbb"}; an empty
+// color clears it. Responds with the updated album.
+func (h *AlbumHandler) SetTheme(w http.ResponseWriter, r *http.Request) {
+	albumID := chi.URLParam(r, "id")
+
+	var req struct {
+		AccentColor *string `json:"accent_color"`
+	}
+	if !decodeJSON(w, r, &req) {
+		return
+	}
+	if req.AccentColor == nil {
+		http.Error(w, "accent_color is required", http.StatusBadRequest)
+		return
+	}
+
+	album, err := h.albumService.SetAccentColor(albumID, *req.AccentColor)
+	if err != nil {
+		h.respondChangeError(w, err, "failed to set album theme")
+		return
+	}
+
+	respondJSON(w, http.StatusOK, album)
+}
+
+// Pin features the album, listing it ahead of unpinned albums. Responds with the updated album.
+func (h *AlbumHandler) Pin(w http.ResponseWriter, r *http.Request) {
+	h.setPinned(w, r, true)
+}
+
+// Unpin stops featuring the album. Responds with the updated album.
+func (h *AlbumHandler) Unpin(w http.ResponseWriter, r *http.Request) {
+	h.setPinned(w, r, false)
+}
+
+// setPinned pins or unpins the album named in the path.
+func (h *AlbumHandler) setPinned(w http.ResponseWriter, r *http.Request, pinned bool) {
+	album, err := h.albumService.SetPinned(chi.URLParam(r, "id"), pinned)
+	if err != nil {
+		if err.Error() == "album not found" {
+			http.Error(w, "Album not found", http.StatusNotFound)
+			return
+		}
+		h.logger.Error("failed to pin album", slog.String("error", err.Error()))
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	respondJSON(w, http.StatusOK, album)
+}
+
+// SetDownloadSettings changes whether visitors may download the album and at which qualities,
+// from {"allow_downloads": true, "download_qualities": ["thumbnail", "display"]}. Either field
+// may be omitted to keep it, and an empty list allows every quality. Responds with the
+// updated album.
+func (h *AlbumHandler) SetDownloadSettings(w http.ResponseWriter, r *http.Request) {
+	albumID := chi.URLParam(r, "id")
+
+	var req struct {
+		AllowDownloads    *bool    `json:"allow_downloads"`
+		DownloadQualities []string `json:"download_qualities"`
+	}
+	if !decodeJSON(w, r, &req) {
+		return
+	}
+	if req.AllowDownloads == nil && req.DownloadQualities == nil {
+		http.Error(w, "allow_downloads or download_qualities is required", http.StatusBadRequest)
+		return
+	}
+
+	album, err := h.albumService.SetDownloadSettings(albumID, req.AllowDownloads, req.DownloadQualities)
+	if err != nil {
+		h.respondChangeError(w, err, "failed to set download settings")
+		return
+	}
+
+	respondJSON(w, http.StatusOK, album)
+}
+
+// SetSchedule replaces the album's scheduled visibility changes with {"scheduled_changes":
+// [{"at": "...", "visibility": "public"}, ...]}, each at a future time. An empty list clears
+// the schedule. Responds with the updated album.
+func (h *AlbumHandler) SetSchedule(w http.ResponseWriter, r *http.Request) {
+	albumID := chi.URLParam(r, "id")
+
+	var req struct {
+		ScheduledChanges []models.ScheduledChange `json:"scheduled_changes"`
+	}
+	if !decodeJSON(w, r, &req) {
+		return
+	}
+	if req.ScheduledChanges == nil {
+		http.Error(w, "scheduled_changes array is required", http.StatusBadRequest)
+		return
+	}
+
+	album, err := h.albumService.SetSchedule(albumID, req.ScheduledChanges, time.Now())
+	if err != nil {
+		h.respondChangeError(w, err, "failed to set album schedule")
+		return
+	}
+
+	respondJSON(w, http.StatusOK, album)
+}
+
+// AlbumColorPosition is an album's place in the order set by ReorderByColor.
+type AlbumColorPosition struct {
+	ID    string   `json:"id"`
+	Title string   `json:"title"`
+	Order int      `json:"order"`
+	Hue   *float64 `json:"hue"` // Dominant cover hue in degrees; null for albums without a colorful cover
+}
+
+// ReorderByColor sorts the album index into a color gradient by the dominant hue of each
+// album's cover, and saves the result as every album's explicit order. Albums without a
+// cover, with a colorless one, or whose cover cannot be read go last. It is a one-shot
+// reorder: later albums and covers are not kept in color order. Responds with the new order.
+func (h *AlbumHandler) ReorderByColor(w http.ResponseWriter, r *http.Request) {
+	albums, err := h.albumService.GetAll()
+	if err != nil {
+		h.logger.Error("failed to get albums", slog.String("error", err.Error()))
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	hues := make(map[string]float64, len(albums))
+	for i := range albums {
+		hue, ok, err := h.imageService.CoverHue(&albums[i])
+		if err != nil {
+			h.logger.Warn("failed to measure cover hue",
+				slog.String("album_id", albums[i].ID),
+				slog.String("error", err.Error()),
+			)
+			continue
+		}
+		if ok {
+			hues[albums[i].ID] = hue
+		}
+	}
+
+	services.SortByHue(albums, hues)
+	ids := make([]string, len(albums))
+	positions := make([]AlbumColorPosition, len(albums))
+	for i, album := range albums {
+		ids[i] = album.ID
+		positions[i] = AlbumColorPosition{ID: album.ID, Title: album.Title, Order: i + 1}
+		if hue, ok := hues[album.ID]; ok {
+			positions[i].Hue = &hue
+		}
+	}
+
+	if err := h.albumService.SetAlbumOrder(ids); err != nil {
+		h.logger.Error("failed to reorder albums", slog.String("error", err.Error()))
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	respondJSON(w, http.StatusOK, map[string]any{"albums": positions})
+}
+
+// ReorderPhotos reorders photos in an album.
+func (h *AlbumHandler) ReorderPhotos(w http.ResponseWriter, r *http.Request) {
+	albumID := chi.URLParam(r, "id")
+
+	var req struct {
+		PhotoIDs []string `json:"photo_ids"`
+	}
+	if !decodeJSON(w, r, &req) {
+		return
+	}
+
+	if len(req.PhotoIDs) == 0 {
+		http.Error(w, "photo_ids array is required", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.albumService.ReorderPhotos(albumID, req.PhotoIDs); err != nil {
+		h.logger.Error("failed to reorder photos", slog.String("error", err.Error()))
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	// Without an explicit cover, the first photo is the cover
+	h.refreshCover(albumID)
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// SwapPhotos exchanges the positions of two photos, given as {"a": photoID, "b": photoID}.
+func (h *AlbumHandler) SwapPhotos(w http.ResponseWriter, r *http.Request) {
+	albumID := chi.URLParam(r, "id")
+
+	var req struct {
+		A string `json:"a"`
+		B string `json:"b"`
+	}
+	if !decodeJSON(w, r, &req) {
+		return
+	}
+
+	if req.A == "" || req.B == "" {
+		http.Error(w, "a and b photo IDs are required", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.albumService.SwapPhotos(albumID, req.A, req.B); err != nil {
+		h.respondChangeError(w, err, "failed to swap photos")
+		return
+	}
+
+	// Without an explicit cover, the first photo is the cover
+	h.refreshCover(albumID)
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// MovePhotos moves only the listed photos to new 1-based positions, from a body like
+// [{"photo_id": "...", "position": 3}], keeping the other photos in their relative order.
+func (h *AlbumHandler) MovePhotos(w http.ResponseWriter, r *http.Request) {
+	albumID := chi.URLParam(r, "id")
+
+	var positions []services.PhotoPosition
+	if !decodeJSON(w, r, &positions) {
+		return
+	}
+
+	if len(positions) == 0 {
+		http.Error(w, "at least one photo position is required", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.albumService.MovePhotos(albumID, positions); err != nil {
+		h.respondChangeError(w, err, "failed to move photos")
+		return
+	}
+
+	// Without an explicit cover, the first photo is the cover
+	h.refreshCover(albumID)
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// MovePhotoTo moves one photo to the start or end of its album, from a body like
+// {"target": "top"} or {"target": "bottom"}.
+func (h *AlbumHandler) MovePhotoTo(w http.ResponseWriter, r *http.Request) {
+	albumID := chi.URLParam(r, "id")
+	photoID := chi.URLParam(r, "photoId")
+
+	var req struct {
+		Target string `json:"target"`
+	}
+	if !decodeJSON(w, r, &req) {
+		return
+	}
+
+	err := h.albumService.MovePhotoTo(albumID, photoID, req.Target)
+	if errors.Is(err, services.ErrPhotoNotFound) {
+		http.Error(w, "Photo not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		h.respondChangeError(w, err, "failed to move photo")
+		return
+	}
+
+	// Without an explicit cover, the first photo is the cover
+	h.refreshCover(albumID)
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// RegeneratePhoto rebuilds one photo's display and thumbnail versions from its stored original,
+// e.g. after the original was replaced or rotated, and records its new dimensions and file sizes.
+// Returns 409 if the original is missing, leaving the existing derivatives in place.
+func (h *AlbumHandler) RegeneratePhoto(w http.ResponseWriter, r *http.Request) {
+	albumID := chi.URLParam(r, "id")
+	photoID := chi.URLParam(r, "photoId")
+
+	album, err := h.albumService.GetByID(albumID)
+	if err != nil {
+		if err.Error() == "album not found" {
+			http.Error(w, "Album not found", http.StatusNotFound)
+			return
+		}
+		h.logger.Error("failed to get album", slog.String("error", err.Error()))
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	var photo *models.Photo
+	for i := range album.Photos {
+		if album.Photos[i].ID == photoID {
+			photo = &album.Photos[i]
+			break
+		}
+	}
+	if photo == nil {
+		http.Error(w, "Photo not found", http.StatusNotFound)
+		return
+	}
+
+	regenerated, err := h.imageService.RegenerateDerivatives(album, *photo)
+	if errors.Is(err, services.ErrObjectNotFound) {
+		http.Error(w, "Original file not found", http.StatusConflict)
+		return
+	}
+	if err != nil {
+		h.logger.Error("failed to regenerate photo",
+			slog.String("album_id", albumID),
+			slog.String("photo_id", photoID),
+			slog.String("error", err.Error()))
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	if err := h.albumService.UpdateRegeneratedPhoto(albumID, regenerated); err != nil {
+		h.logger.Error("failed to save regenerated photo", slog.String("error", err.Error()))
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	// A clean cover rendered from this photo is stale too; rendering without the current
+	// cover URL forces it to be redrawn in place
+	if cover := album.CoverPhoto(); cover != nil && cover.ID == photoID && album.CoverURL != "" {
+		stale := *album
+		stale.CoverURL = ""
+		if _, err := h.imageService.RenderCover(&stale); err != nil {
+			h.logger.Warn("failed to re-render album cover",
+				slog.String("album_id", albumID),
+				slog.String("error", err.Error()))
+		}
+	}
+
+	updated, err := h.albumService.GetByID(albumID)
+	if err != nil {
+		h.logger.Error("failed to get album", slog.String("error", err.Error()))
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+	for i := range updated.Photos {
+		if updated.Photos[i].ID == photoID {
+			respondJSON(w, http.StatusOK, updated.Photos[i])
+			return
+		}
+	}
+	http.Error(w, "Photo not found", http.StatusNotFound)
+}
+
+// DownloadAlbum streams a ZIP file containing album photos at the requested quality level.
+// With ?part=N only that part of a split download is streamed; see DownloadManifest.
+// The ZIP is built before sending so Content-Length and X-Content-SHA256 are set;
+// ?chunked=true streams it as it is built instead, for albums too large to stage.
+// Without ?quality= the configured default quality is used, or the highest quality the album
+// allows if it restricts downloads to others. ?sidecars=true adds a JSON
+// sidecar with each photo's metadata next to it.
+func (h *AlbumHandler) DownloadAlbum(w http.ResponseWriter, r *http.Request) {
+	quality, ok := h.albumDownloadQuality(w, r)
+	if !ok {
+		return
+	}
+
+	part := 0
+	if partParam := r.URL.Query().Get("part"); partParam != "" {
+		n, err := strconv.Atoi(partParam)
+		if err != nil || n < 1 {
+			http.Error(w, "Invalid part parameter. Must be a positive integer", http.StatusBadRequest)
+			return
+		}
+		part = n
+	}
+
+	opts := services.ZIPOptions{
+		Chunked:  r.URL.Query().Get("chunked") == "true",
+		Sidecars: r.URL.Query().Get("sidecars") == "true",
+	}
+
+	album, ok := h.downloadableAlbum(w, r)
+	if !ok {
+		return
+	}
+	if quality, ok = h.permittedDownloadQuality(w, album, quality, h.downloadQuality); !ok {
+		return
+	}
+
+	// Stream the ZIP file
+	var err error
+	if part > 0 {
+		err = h.imageService.StreamAlbumZIPPart(w, r, album, quality, part, opts)
+		if errors.Is(err, services.ErrZIPPartNotFound) {
+			http.Error(w, "ZIP part not found", http.StatusNotFound)
+			return
+		}
+	} else {
+		err = h.imageService.StreamAlbumZIP(w, r, album, quality, opts)
+	}
+	if err != nil {
+		h.logger.Error("failed to stream album ZIP",
+			slog.String("album", album.Slug),
+			slog.String("quality", quality),
+			slog.Int("part", part),
+			slog.String("error", err.Error()))
+		// Don't write error response as headers may already be sent
+		return
+	}
+}
+
+// ExportHTML streams a ZIP with a self-contained static gallery of the album's display
+// images, for delivering an album to clients who will view it offline. It is a display
+// quality download, so albums that do not allow those answer 403.
+func (h *AlbumHandler) ExportHTML(w http.ResponseWriter, r *http.Request) {
+	album, ok := h.downloadableAlbum(w, r)
+	if !ok {
+		return
+	}
+	if _, ok := h.permittedDownloadQuality(w, album, "display", ""); !ok {
+		return
+	}
+
+	if err := h.imageService.StreamAlbumHTML(w, album); err != nil {
+		h.logger.Error("failed to stream album HTML export",
+			slog.String("album", album.Slug),
+			slog.String("error", err.Error()))
+		// Don't write error response as headers may already be sent
+		return
+	}
+}
+
+// ZIPPartLink is a part of a split album download along with the URL to fetch it.
+type ZIPPartLink struct {
+	services.ZIPPart
+	URL string `json:"url"`
+}
+
+// albumDownloadQuality returns an album download's ?quality=, empty if it is omitted.
+// Invalid values are answered with 400 and ok is false.
+func (h *AlbumHandler) albumDownloadQuality(w http.ResponseWriter, r *http.Request) (string, bool) {
+	quality := r.URL.Query().Get("quality")
+	if quality != "" && !IsDownloadQuality(quality) {
+		http.Error(w, "Invalid quality parameter. Must be: thumbnail, display, or original", http.StatusBadRequest)
+		return "", false
+	}
+	return quality, true
+}
+
+// permittedDownloadQuality returns the quality to download the album at: the requested one,
+// or if none was requested, fallback or else the highest quality the album allows. A
+// requested quality the album does not allow is answered with 403 and ok is false.
+func (h *AlbumHandler) permittedDownloadQuality(w http.ResponseWriter, album *models.Album, requested, fallback string) (string, bool) {
+	if requested == "" {
+		return album.DownloadQuality(fallback), true
+	}
+	if !album.AllowsDownloadQuality(requested) {
+		http.Error(w, fmt.Sprintf("Downloads at %s quality are not enabled for this album", requested), http.StatusForbidden)
+		return "", false
+	}
+	return requested, true
+}
+
+// DownloadManifest lists the ZIP parts an album download is split into at the requested
+// quality level, or the configured default quality.
+func (h *AlbumHandler) DownloadManifest(w http.ResponseWriter, r *http.Request) {
+	quality, ok := h.albumDownloadQuality(w, r)
+	if !ok {
+		return
+	}
+
+	album, ok := h.downloadableAlbum(w, r)
+	if !ok {
+		return
+	}
+	if quality, ok = h.permittedDownloadQuality(w, album, quality, h.downloadQuality); !ok {
+		return
+	}
+
+	parts, err := h.imageService.PlanAlbumZIP(album, quality)
+	if err != nil {
+		h.logger.Error("failed to plan album ZIP", slog.String("error", err.Error()))
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	links := make([]ZIPPartLink, 0, len(parts))
+	for _, part := range parts {
+		query := url.Values{"quality": {quality}, "part": {strconv.Itoa(part.Part)}}
+		if token := r.URL.Query().Get("token"); token != "" {
+			query.Set("token", token)
+		}
+		if r.URL.Query().Get("sidecars") == "true" {
+			query.Set("sidecars", "true")
+		}
+		links = append(links, ZIPPartLink{
+			ZIPPart: part,
+			URL:     album.APIPath() + "/download?" + query.Encode(),
+		})
+	}
+
+	respondJSON(w, http.StatusOK, map[string]any{
+		"album":   album.Slug,
+		"quality": quality,
+		"parts":   links,
+	})
+}
+
+// MaxMultiDownloadAlbums is the most albums one multi-album download may request.
+const MaxMultiDownloadAlbums = 50
+
+// DownloadMultiple streams one ZIP holding several albums of the route's namespace, one folder
+// per album, at the requested quality level. Albums the request may not download (unknown,
+// restricted without an access token, or with downloads disabled) are skipped and listed
+// in the ZIP's manifest; if none can be downloaded the request is rejected.
+func (h *AlbumHandler) DownloadMultiple(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Slugs   []string `json:"slugs"`
+		Quality string   `json:"quality"`
+	}
+	if !decodeJSON(w, r, &req) {
+		return
+	}
+
+	// Validate quality parameter
+	if !IsDownloadQuality(req.Quality) {
+		http.Error(w, "Invalid quality parameter. Must be: thumbnail, display, or original", http.StatusBadRequest)
+		return
+	}
+	if len(req.Slugs) == 0 {
+		http.Error(w, "At least one album slug is required", http.StatusBadRequest)
+		return
+	}
+	if len(req.Slugs) > MaxMultiDownloadAlbums {
+		http.Error(w, fmt.Sprintf("At most %d albums can be downloaded at once", MaxMultiDownloadAlbums), http.StatusBadRequest)
+		return
+	}
+
+	namespace := chi.URLParam(r, "namespace")
+	var albums []*models.Album
+	var skipped []services.MultiAlbumSkipped
+	seen := make(map[string]bool, len(req.Slugs))
+	for _, slug := range req.Slugs {
+		if seen[slug] {
+			continue
+		}
+		seen[slug] = true
+
+		album, err := h.albumService.GetByNamespacedSlug(namespace, slug)
+		switch {
+		case err != nil && err.Error() == "album not found":
+			skipped = append(skipped, services.MultiAlbumSkipped{Slug: slug, Reason: "album not found"})
+		case err != nil:
+			h.logger.Error("failed to get album", slog.String("error", err.Error()))
+			http.Error(w, "Internal server error", http.StatusInternalServerError)
+			return
+		case !h.hasAlbumAccess(r, album):
+			skipped = append(skipped, services.MultiAlbumSkipped{Slug: slug, Reason: "access token required"})
+		case !album.AllowDownloads:
+			skipped = append(skipped, services.MultiAlbumSkipped{Slug: slug, Reason: "downloads are not enabled"})
+		case !album.AllowsDownloadQuality(req.Quality):
+			skipped = append(skipped, services.MultiAlbumSkipped{Slug: slug, Reason: req.Quality + " downloads are not enabled"})
+		default:
+			albums = append(albums, album)
+		}
+	}
+
+	if len(albums) == 0 {
+		http.Error(w, "None of the requested albums can be downloaded", http.StatusForbidden)
+		return
+	}
+
+	if err := h.imageService.StreamMultiAlbumZIP(w, albums, skipped, req.Quality); err != nil {
+		h.logger.Error("failed to stream multi-album ZIP",
+			slog.Int("albums", len(albums)),
+			slog.String("quality", req.Quality),
+			slog.String("error", err.Error()))
+		// Don't write error response as headers may already be sent
+		return
+	}
+}
+
+// GetSummaries lists the namespace's albums without their photos, for navigation. Visitors
+// get the public albums that need no access token; admins get every album.
+func (h *AlbumHandler) GetSummaries(w http.ResponseWriter, r *http.Request) {
+	namespace := chi.URLParam(r, "namespace")
+	admin := middleware.GetSession(r.Context()) != nil
+
+	albums, err := h.albumService.GetAll()
+	if err != nil {
+		h.logger.Error("failed to get albums", slog.String("error", err.Error()))
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+	services.SortPinnedFirst(albums)
+
+	summaries := make([]models.AlbumSummary, 0, len(albums))
+	for i := range albums {
+		if albums[i].Namespace != namespace {
+			continue
+		}
+		if !admin && (albums[i].Visibility != "public" || albums[i].RequiresAccessToken()) {
+			continue
+		}
+		summary := albums[i].Summary()
+		if !admin {
+			summary.CoverURL = h.signedURL(summary.CoverURL)
+		}
+		summaries = append(summaries, summary)
+	}
+
+	respondJSON(w, http.StatusOK, map[string]any{
+		"albums": summaries,
+	})
+}
+
+// GetPublicAlbums lists the namespace's public albums that need no access token, as visitors
+// see them, for third parties mirroring the portfolio.
+func (h *AlbumHandler) GetPublicAlbums(w http.ResponseWriter, r *http.Request) {
+	namespace := chi.URLParam(r, "namespace")
+
+	albums, err := h.albumService.GetAll()
+	if err != nil {
+		h.logger.Error("failed to get albums", slog.String("error", err.Error()))
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+	services.SortPinnedFirst(albums)
+
+	listed := make([]models.Album, 0, len(albums))
+	for i := range albums {
+		if albums[i].Namespace == namespace && albums[i].Visibility == "public" && !albums[i].RequiresAccessToken() {
+			listed = append(listed, h.visitorAlbum(albums[i]))
+		}
+	}
+
+	respondJSON(w, http.StatusOK, map[string]any{
+		"albums": listed,
+	})
+}
+
+// GetPublicAlbum returns the album named by its slug as visitors see it. Restricted albums
+// need an access token, as on the public site.
+func (h *AlbumHandler) GetPublicAlbum(w http.ResponseWriter, r *http.Request) {
+	album, err := h.albumFromPath(r)
+	if err != nil {
+		if err.Error() == "album not found" {
+			h.respondAlbumNotFound(w, r)
+			return
+		}
+		h.logger.Error("failed to get album", slog.String("error", err.Error()))
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	middleware.SetRobotsTag(w, album)
+	if !h.hasAlbumAccess(r, album) {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	h.recordView(w, r, album.ID)
+	respondJSON(w, http.StatusOK, h.visitorAlbum(*album))
+}
+
+// MaxBatchAlbums is the most albums one batch request may fetch.
+const MaxBatchAlbums = 100
+
+// GetBatch returns several albums of the route's namespace at once, named by either "ids" or
+// "slugs" in the request body. The response lists them in request order, with null for each
+// album that does not exist or the caller may not see. Visitors see albums as on the public
+// site: restricted ones need an access token and admin-only fields are left out. Admins get
+// every album in full.
+func (h *AlbumHandler) GetBatch(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		IDs   []string `json:"ids"`
+		Slugs []string `json:"slugs"`
+	}
+	if !decodeJSON(w, r, &req) {
+		return
+	}
+
+	if (len(req.IDs) == 0) == (len(req.Slugs) == 0) {
+		http.Error(w, "Exactly one of ids or slugs is required", http.StatusBadRequest)
+		return
+	}
+	if len(req.IDs)+len(req.Slugs) > MaxBatchAlbums {
+		http.Error(w, fmt.Sprintf("At most %d albums can be fetched at once", MaxBatchAlbums), http.StatusBadRequest)
+		return
+	}
+
+	// Load the albums once and index the namespace's, rather than reading the store per key
+	all, err := h.albumService.GetAll()
+	if err != nil {
+		h.logger.Error("failed to get albums", slog.String("error", err.Error()))
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+	namespace := chi.URLParam(r, "namespace")
+	keys := req.Slugs
+	if len(req.IDs) > 0 {
+		keys = req.IDs
+	}
+	index := make(map[string]*models.Album, len(all))
+	for i := range all {
+		if all[i].Namespace != namespace {
+			continue
+		}
+		key := all[i].Slug
+		if len(req.IDs) > 0 {
+			key = all[i].ID
+		}
+		index[key] = &all[i]
+	}
+
+	admin := middleware.GetSession(r.Context()) != nil
+	albums := make([]*models.Album, len(keys))
+	for i, key := range keys {
+		album, ok := index[key]
+		switch {
+		case !ok:
+			continue
+		case admin:
+			albums[i] = album
+		case h.hasAlbumAccess(r, album):
+			visible := h.visitorAlbum(*album)
+			albums[i] = &visible
+		}
+	}
+
+	respondJSON(w, http.StatusOK, map[string]any{
+		"albums": albums,
+	})
+}
+
+// albumFromPath loads the album named by the {slug} URL parameter, within the namespace
+// given by the {namespace} parameter on namespaced routes.
+func (h *AlbumHandler) albumFromPath(r *http.Request) (*models.Album, error) {
+	return h.albumService.GetByNamespacedSlug(chi.URLParam(r, "namespace"), chi.URLParam(r, "slug"))
+}
+
+// AlbumNotFoundResponse is the JSON body returned by public album routes for an unknown slug.
+type AlbumNotFoundResponse struct {
+	apierror.Response
+	Slug        string                     `json:"slug"`
+	Suggestions []services.AlbumSuggestion `json:"suggestions"`
+}
+
+// respondAlbumNotFound writes a JSON 404 for the {slug} URL parameter, suggesting the public
+// albums with the closest slugs so visitors following a stale link can find their way.
+func (h *AlbumHandler) respondAlbumNotFound(w http.ResponseWriter, r *http.Request) {
+	slug := chi.URLParam(r, "slug")
+
+	suggestions, err := h.albumService.SuggestPublicAlbums(chi.URLParam(r, "namespace"), slug)
+	if err != nil {
+		h.logger.Warn("failed to suggest albums", slog.String("error", err.Error()))
+		suggestions = []services.AlbumSuggestion{}
+	}
+
+	respondJSON(w, http.StatusNotFound, AlbumNotFoundResponse{
+		Response:    apierror.Response{Error: "Album not found", RequestID: middleware.GetRequestID(r.Context())},
+		Slug:        slug,
+		Suggestions: suggestions,
+	})
+}
+
+// downloadableAlbum loads the album named by the slug URL parameter and checks that the
+// request may download it. On failure it writes the error response and returns false.
+func (h *AlbumHandler) downloadableAlbum(w http.ResponseWriter, r *http.Request) (*models.Album, bool) {
+	album, err := h.albumFromPath(r)
+	if err != nil {
+		if err.Error() == "album not found" {
+			h.respondAlbumNotFound(w, r)
+			return nil, false
+		}
+		h.logger.Error("failed to get album", slog.String("error", err.Error()))
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return nil, false
+	}
+
+	// Password-protected albums require a valid access token
+	if !h.hasAlbumAccess(r, album) {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return nil, false
+	}
+
+	// Check if downloads are allowed for this album
+	if !album.AllowDownloads {
+		http.Error(w, "Downloads are not enabled for this album", http.StatusForbidden)
+		return nil, false
+	}
+
+	return album, true
+}
+
+// DownloadPhoto streams a single photo at the requested quality level (original by default).
+// Originals can be converted with ?format=jpeg, png, or tiff.
+func (h *AlbumHandler) DownloadPhoto(w http.ResponseWriter, r *http.Request) {
+	photoID := chi.URLParam(r, "photoId")
+	quality, ok := h.albumDownloadQuality(w, r)
+	if !ok {
+		return
+	}
+
+	// Originals may be converted to another format on the fly
+	format := r.URL.Query().Get("format")
+	if format != "" {
+		if !services.IsDownloadFormat(format) {
+			http.Error(w, "Invalid format parameter. Must be: jpeg, png, or tiff", http.StatusBadRequest)
+			return
+		}
+		if quality == "" {
+			quality = "original"
+		}
+		if quality != "original" {
+			http.Error(w, "The format parameter is only supported for original downloads", http.StatusBadRequest)
+			return
+		}
+	}
+
+	// Get album by slug
+	album, err := h.albumFromPath(r)
+	if err != nil {
+		if err.Error() == "album not found" {
+			h.respondAlbumNotFound(w, r)
+			return
+		}
+		h.logger.Error("failed to get album", slog.String("error", err.Error()))
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	// Password-protected albums require a valid access token
+	if !h.hasAlbumAccess(r, album) {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	// Check if downloads are allowed for this album
+	if !album.AllowDownloads {
+		http.Error(w, "Downloads are not enabled for this album", http.StatusForbidden)
+		return
+	}
+	if quality, ok = h.permittedDownloadQuality(w, album, quality, "original"); !ok {
+		return
+	}
+
+	var photo *models.Photo
+	for i := range album.Photos {
+		if album.Photos[i].ID == photoID {
+			photo = &album.Photos[i]
+			break
+		}
+	}
+	if photo == nil {
+		http.Error(w, "Photo not found", http.StatusNotFound)
+		return
+	}
+
+	if !photo.Downloadable {
+		http.Error(w, "Downloads are not enabled for this photo", http.StatusForbidden)
+		return
+	}
+
+	if format != "" {
+		h.downloadConvertedPhoto(w, album, photo, format)
+		return
+	}
+
+	if err := h.imageService.StreamPhoto(w, album, photo, quality); err != nil {
+		if errors.Is(err, services.ErrObjectNotFound) {
+			http.Error(w, "Photo file not found", http.StatusNotFound)
+			return
+		}
+		h.logger.Error("failed to stream photo",
+			slog.String("album", album.Slug),
+			slog.String("photo_id", photo.ID),
+			slog.String("quality", quality),
+			slog.String("error", err.Error()))
+		// Don't write error response as headers may already be sent
+		return
+	}
+}
+
+// downloadConvertedPhoto streams a photo's original converted to format. The conversion
+// finishes before anything is written, so failures get an error status rather than an
+// empty download.
+func (h *AlbumHandler) downloadConvertedPhoto(w http.ResponseWriter, album *models.Album, photo *models.Photo, format string) {
+	converted, err := h.imageService.ConvertOriginal(album, photo, format)
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrObjectNotFound):
+			http.Error(w, "Photo file not found", http.StatusNotFound)
+		case errors.Is(err, services.ErrUndecodableOriginal):
+			http.Error(w, "The original cannot be converted to "+format, http.StatusUnsupportedMediaType)
+		default:
+			h.logger.Error("failed to convert photo",
+				slog.String("album", album.Slug),
+				slog.String("photo_id", photo.ID),
+				slog.String("format", format),
+				slog.String("error", err.Error()))
+			http.Error(w, "Failed to convert photo", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	if err := services.WriteConvertedPhoto(w, converted); err != nil {
+		h.logger.Error("failed to stream photo",
+			slog.String("album", album.Slug),
+			slog.String("photo_id", photo.ID),
+			slog.String("format", format),
+			slog.String("error", err.Error()))
+	}
+}
+
+// PrintPhoto renders a photo's original for a standard print size (e.g. ?size=8x10) at 300 DPI and streams it as a JPEG.
+// Prints that need more pixels than the original has are still rendered, with an X-Print-Warning header.
+// Prints are original quality downloads, so albums that do not allow those answer 403.
+func (h *AlbumHandler) PrintPhoto(w http.ResponseWriter, r *http.Request) {
+	photoID := chi.URLParam(r, "photoId")
+	size := r.URL.Query().Get("size")
+	if size == "" {
+		http.Error(w, "size parameter is required (supported: "+strings.Join(services.PrintSizes(), ", ")+")", http.StatusBadRequest)
+		return
+	}
 
-	var req struct {
-		Password string `json:"password"`
+	album, ok := h.downloadableAlbum(w, r)
+	if !ok {
+		return
 	}
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		http.Error(w, "Invalid request body", http.StatusBadRequest)
+	if _, ok := h.permittedDownloadQuality(w, album, "original", ""); !ok {
 		return
 	}
 
-	// Get album
-	album, err := h.albumService.GetByID(albumID)
+	var photo *models.Photo
+	for i := range album.Photos {
+		if album.Photos[i].ID == photoID {
+			photo = &album.Photos[i]
+			break
+		}
+	}
+	if photo == nil {
+		http.Error(w, "Photo not found", http.StatusNotFound)
+		return
+	}
+
+	if !photo.Downloadable {
+		http.Error(w, "Downloads are not enabled for this photo", http.StatusForbidden)
+		return
+	}
+
+	export, err := h.imageService.RenderPrint(album, photo, size)
 	if err != nil {
-		http.Error(w, "Album not found", http.StatusNotFound)
+		switch {
+		case errors.Is(err, services.ErrUnsupportedPrintSize):
+			http.Error(w, err.Error(), http.StatusBadRequest)
+		case errors.Is(err, services.ErrObjectNotFound):
+			http.Error(w, "Photo file not found", http.StatusNotFound)
+		default:
+			h.logger.Error("failed to render print",
+				slog.String("album", album.Slug),
+				slog.String("photo_id", photo.ID),
+				slog.String("size", size),
+				slog.String("error", err.Error()))
+			http.Error(w, "Internal server error", http.StatusInternalServerError)
+		}
 		return
 	}
 
-	// Hash password
-	hash, err := bcrypt.GenerateFromPassword([]byte(req.Password), bcrypt.DefaultCost)
+	if export.Upscaled {
+		h.logger.Warn("print export upscaled",
+			slog.String("photo_id", photo.ID),
+			slog.String("size", size),
+			slog.Int("effective_dpi", export.EffectiveDPI))
+		w.Header().Set("X-Print-Warning", fmt.Sprintf("upscaled: the original provides %d DPI at %s", export.EffectiveDPI, size))
+	}
+
+	w.Header().Set("Content-Type", "image/jpeg")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", export.Filename))
+	w.Header().Set("Content-Length", strconv.Itoa(len(export.Data)))
+	w.Header().Set("X-Print-DPI", strconv.Itoa(services.PrintDPI))
+	w.Header().Set("X-Print-Effective-DPI", strconv.Itoa(export.EffectiveDPI))
+	w.WriteHeader(http.StatusOK)
+	_, _ = w.Write(export.Data)
+}
+
+// PhotoNeighbor is the subset of a photo the lightbox needs to show or preload it.
+type PhotoNeighbor struct {
+	ID           string `json:"id"`
+	Slug         string `json:"slug,omitempty"`
+	URLDisplay   string `json:"url_display"`
+	URLThumbnail string `json:"url_thumbnail"`
+	Width        int    `json:"width"`
+	Height       int    `json:"height"`
+}
+
+// GetPhotoNeighbors returns the photos before and after a photo in album order, with nulls at the ends.
+func (h *AlbumHandler) GetPhotoNeighbors(w http.ResponseWriter, r *http.Request) {
+	photoID := chi.URLParam(r, "photoId")
+
+	album, err := h.albumFromPath(r)
 	if err != nil {
-		h.logger.Error("failed to hash password", slog.String("error", err.Error()))
+		if err.Error() == "album not found" {
+			h.respondAlbumNotFound(w, r)
+			return
+		}
+		h.logger.Error("failed to get album", slog.String("error", err.Error()))
 		http.Error(w, "Internal server error", http.StatusInternalServerError)
 		return
 	}
 
-	// Update album
-	album.Visibility = "password_protected"
-	album.PasswordHash = string(hash)
+	// Password-protected albums require a valid access token
+	if !h.hasAlbumAccess(r, album) {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
 
-	if err := h.albumService.Update(albumID, album); err != nil {
-		h.logger.Error("failed to update album", slog.String("error", err.Error()))
-		http.Error(w, "Internal server error", http.StatusInternalServerError)
+	index := -1
+	for i := range album.Photos {
+		if album.Photos[i].ID == photoID {
+			index = i
+			break
+		}
+	}
+	if index == -1 {
+		http.Error(w, "Photo not found", http.StatusNotFound)
 		return
 	}
 
-	w.WriteHeader(http.StatusNoContent)
+	var previous, next *PhotoNeighbor
+	if index > 0 {
+		previous = h.photoNeighbor(&album.Photos[index-1])
+	}
+	if index < len(album.Photos)-1 {
+		next = h.photoNeighbor(&album.Photos[index+1])
+	}
+
+	respondJSON(w, http.StatusOK, map[string]any{
+		"photo_id": photoID,
+		"position": index + 1,
+		"total":    len(album.Photos),
+		"previous": previous,
+		"next":     next,
+	})
 }
 
-// RemovePassword removes password protection from an album.
-func (h *AlbumHandler) RemovePassword(w http.ResponseWriter, r *http.Request) {
-	albumID := chi.URLParam(r, "id")
+// photoNeighbor builds the lightbox summary of a photo, with its URLs signed for visitors.
+func (h *AlbumHandler) photoNeighbor(photo *models.Photo) *PhotoNeighbor {
+	return &PhotoNeighbor{
+		ID:           photo.ID,
+		Slug:         photo.Slug,
+		URLDisplay:   h.signedURL(photo.URLDisplay),
+		URLThumbnail: h.signedURL(photo.URLThumbnail),
+		Width:        photo.Width,
+		Height:       photo.Height,
+	}
+}
 
-	// Get album
-	album, err := h.albumService.GetByID(albumID)
+// DefaultPreloadCount is how many photos a preload manifest lists when no count is given,
+// about a screenful of thumbnails.
+const DefaultPreloadCount = 12
+
+// MaxPreloadCount is the most photos a preload manifest lists; larger counts are clamped.
+const MaxPreloadCount = 100
+
+// PreloadPhoto is what the gallery needs to fetch a thumbnail early and hold its place
+// with a placeholder until it arrives.
+type PreloadPhoto struct {
+	ID           string `json:"id"`
+	URLThumbnail string `json:"url_thumbnail"`
+	Blurhash     string `json:"blurhash,omitempty"` // Missing for photos uploaded before placeholders were computed
+}
+
+// GetPreload returns the thumbnails and placeholders of the first ?count= photos of an
+// album in album order (default 12), so the gallery can load its first screen first.
+// Counts outside 1 to 100 are clamped.
+func (h *AlbumHandler) GetPreload(w http.ResponseWriter, r *http.Request) {
+	count := DefaultPreloadCount
+	if param := r.URL.Query().Get("count"); param != "" {
+		n, err := strconv.Atoi(param)
+		if err != nil {
+			http.Error(w, "Invalid count parameter. Must be an integer", http.StatusBadRequest)
+			return
+		}
+		count = min(max(n, 1), MaxPreloadCount)
+	}
+
+	album, err := h.albumFromPath(r)
 	if err != nil {
-		http.Error(w, "Album not found", http.StatusNotFound)
+		if err.Error() == "album not found" {
+			h.respondAlbumNotFound(w, r)
+			return
+		}
+		h.logger.Error("failed to get album", slog.String("error", err.Error()))
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
 		return
 	}
 
-	// Update album
-	album.Visibility = "public"
-	album.PasswordHash = ""
-
-	if err := h.albumService.Update(albumID, album); err != nil {
-		h.logger.Error("failed to update album", slog.String("error", err.Error()))
-		http.Error(w, "Internal server error", http.StatusInternalServerError)
+	// Password-protected albums require a valid access token
+	if !h.hasAlbumAccess(r, album) {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
 		return
 	}
 
-	w.WriteHeader(http.StatusNoContent)
+	photos := album.Photos[:min(count, len(album.Photos))]
+	preload := make([]PreloadPhoto, len(photos))
+	for i := range photos {
+		preload[i] = PreloadPhoto{
+			ID:           photos[i].ID,
+			URLThumbnail: h.signedURL(photos[i].URLThumbnail),
+			Blurhash:     photos[i].Blurhash,
+		}
+	}
+
+	respondJSON(w, http.StatusOK, map[string]any{
+		"photos": preload,
+		"total":  len(album.Photos),
+	})
 }
 
-// SetCoverPhoto sets the cover photo for an album.
-func (h *AlbumHandler) SetCoverPhoto(w http.ResponseWriter, r *http.Request) {
-	albumID := chi.URLParam(r, "id")
+// PermalinkAlbum is the album context returned with a photo permalink.
+type PermalinkAlbum struct {
+	ID                string   `json:"id"`
+	Slug              string   `json:"slug"`
+	Title             string   `json:"title"`
+	Subtitle          string   `json:"subtitle,omitempty"`
+	AllowDownloads    bool     `json:"allow_downloads"`
+	DownloadQualities []string `json:"download_qualities,omitempty"`
+	TotalPhotos       int      `json:"total_photos"`
+}
 
-	var req struct {
-		PhotoID string `json:"photo_id"`
+// GetPhotoPermalink resolves a photo by its album slug and photo slug, returning the photo with its album context.
+func (h *AlbumHandler) GetPhotoPermalink(w http.ResponseWriter, r *http.Request) {
+	photoSlug := chi.URLParam(r, "photoSlug")
+
+	album, err := h.albumFromPath(r)
+	if err != nil {
+		if err.Error() == "album not found" {
+			h.respondAlbumNotFound(w, r)
+			return
+		}
+		h.logger.Error("failed to get album", slog.String("error", err.Error()))
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
 	}
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		http.Error(w, "Invalid request body", http.StatusBadRequest)
+
+	// Restricted albums require a valid access token
+	if !h.hasAlbumAccess(r, album) {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
 		return
 	}
 
-	if err := h.albumService.SetCoverPhoto(albumID, req.PhotoID); err != nil {
-		h.logger.Error("failed to set cover photo", slog.String("error", err.Error()))
-		http.Error(w, err.Error(), http.StatusBadRequest)
+	index := -1
+	for i := range album.Photos {
+		if album.Photos[i].Slug == photoSlug {
+			index = i
+			break
+		}
+	}
+	if index == -1 {
+		http.Error(w, "Photo not found", http.StatusNotFound)
 		return
 	}
 
-	w.WriteHeader(http.StatusNoContent)
+	var previous, next *PhotoNeighbor
+	if index > 0 {
+		previous = h.photoNeighbor(&album.Photos[index-1])
+	}
+	if index < len(album.Photos)-1 {
+		next = h.photoNeighbor(&album.Photos[index+1])
+	}
+
+	// As visitors see it, with URLs signed and GPS scrubbed as the album requires
+	photo := h.visitorAlbum(*album).Photos[index]
+
+	respondJSON(w, http.StatusOK, map[string]any{
+		"album": PermalinkAlbum{
+			ID:                album.ID,
+			Slug:              album.Slug,
+			Title:             album.Title,
+			Subtitle:          album.Subtitle,
+			AllowDownloads:    album.AllowDownloads,
+			DownloadQualities: album.DownloadQualities,
+			TotalPhotos:       len(album.Photos),
+		},
+		"photo":    photo,
+		"position": index + 1,
+		"previous": previous,
+		"next":     next,
+	})
 }
 
-// SetCoverPhoto sets the cover photo for an album.
-func (h *AlbumHandler) ClearCoverPhoto(w http.ResponseWriter, r *http.Request) {
-	albumID := chi.URLParam(r, "id")
+// VerifyPassword checks a visitor-supplied password for a protected album and issues an access token.
+func (h *AlbumHandler) VerifyPassword(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		AlbumID  string `json:"album_id"`
+		Password string `json:"password"`
+	}
+	if !decodeJSON(w, r, &req) {
+		return
+	}
 
-	if err := h.albumService.ClearCoverPhoto(albumID); err != nil {
-		h.logger.Error("failed to clear cover photo", slog.String("error", err.Error()))
-		http.Error(w, err.Error(), http.StatusBadRequest)
+	if h.albumAuthService == nil {
+		http.Error(w, "Album authentication is not configured", http.StatusServiceUnavailable)
 		return
 	}
 
-	w.WriteHeader(http.StatusNoContent)
-}
+	album, err := h.albumService.GetByID(req.AlbumID)
+	if err != nil {
+		http.Error(w, "Album not found", http.StatusNotFound)
+		return
+	}
 
-// ReorderPhotos reorders photos in an album.
-func (h *AlbumHandler) ReorderPhotos(w http.ResponseWriter, r *http.Request) {
-	albumID := chi.URLParam(r, "id")
+	token, err := h.albumAuthService.VerifyPassword(album, req.Password)
+	if err != nil {
+		h.logger.Warn("album password verification failed",
+			slog.String("album_id", album.ID),
+			slog.String("error", err.Error()),
+		)
+		http.Error(w, "Invalid password", http.StatusUnauthorized)
+		return
+	}
+
+	h.setAlbumAccessCookie(w, album.ID, token)
 
+	respondJSON(w, http.StatusOK, map[string]string{
+		"token": token,
+	})
+}
+
+// RequestAccessLink emails a magic access link to a client whose address is on the album's access list.
+// The response is the same whether or not the address is allowed, so the list cannot be probed.
+func (h *AlbumHandler) RequestAccessLink(w http.ResponseWriter, r *http.Request) {
 	var req struct {
-		PhotoIDs []string `json:"photo_ids"`
+		Email string `json:"email"`
 	}
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+	if !decodeJSON(w, r, &req) {
+		return
+	}
+	if req.Email == "" {
 		http.Error(w, "Invalid request body", http.StatusBadRequest)
 		return
 	}
 
-	if len(req.PhotoIDs) == 0 {
-		http.Error(w, "photo_ids array is required", http.StatusBadRequest)
+	if h.albumAuthService == nil || h.mailer == nil {
+		http.Error(w, "Email access links are not configured", http.StatusServiceUnavailable)
 		return
 	}
 
-	if err := h.albumService.ReorderPhotos(albumID, req.PhotoIDs); err != nil {
-		h.logger.Error("failed to reorder photos", slog.String("error", err.Error()))
-		http.Error(w, err.Error(), http.StatusBadRequest)
+	album, err := h.albumFromPath(r)
+	if err != nil {
+		if err.Error() == "album not found" {
+			h.respondAlbumNotFound(w, r)
+			return
+		}
+		h.logger.Error("failed to get album", slog.String("error", err.Error()))
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
 		return
 	}
 
-	w.WriteHeader(http.StatusNoContent)
-}
+	token, err := h.albumAuthService.RequestAccessLink(album, req.Email)
+	if err != nil {
+		h.logger.Warn("album access link refused",
+			slog.String("album_id", album.ID),
+			slog.String("error", err.Error()),
+		)
+	} else {
+		link := h.publicURL + album.APIPath() + "/access?" + url.Values{"token": {token}}.Encode()
+		body := "Open this link to view " + album.Title + ":\n\n" + link + "\n\n" +
+			"The link is personal to you, so please don't forward it. If you did not request it, you can ignore this email.\n"
+		// Sent in the background, so the response takes as long whether or not the address
+		// is on the list
+		albumID, to, subject := album.ID, req.Email, "Your link to "+album.Title
+		go func() {
+			if err := h.mailer.Send(to, subject, body); err != nil {
+				h.logger.Error("failed to send album access link",
+					slog.String("album_id", albumID),
+					slog.String("error", err.Error()),
+				)
+			}
+		}()
+	}
 
-// DownloadAlbum streams a ZIP file containing album photos at the requested quality level.
-func (h *AlbumHandler) DownloadAlbum(w http.ResponseWriter, r *http.Request) {
-	slug := chi.URLParam(r, "slug")
-	quality := r.URL.Query().Get("quality")
+	respondJSON(w, http.StatusAccepted, map[string]string{
+		"message": "If this address has access, a link has been sent to it",
+	})
+}
 
-	// Validate quality parameter
-	if quality != "thumbnail" && quality != "display" && quality != "original" {
-		http.Error(w, "Invalid quality parameter. Must be: thumbnail, display, or original", http.StatusBadRequest)
+// OpenAccessLink validates a magic access link, sets the album access cookie, and redirects to the album.
+func (h *AlbumHandler) OpenAccessLink(w http.ResponseWriter, r *http.Request) {
+	if h.albumAuthService == nil {
+		http.Error(w, "Album authentication is not configured", http.StatusServiceUnavailable)
 		return
 	}
 
-	// Get album by slug
-	album, err := h.albumService.GetBySlug(slug)
+	album, err := h.albumFromPath(r)
 	if err != nil {
 		if err.Error() == "album not found" {
-			http.Error(w, "Album not found", http.StatusNotFound)
+			h.respondAlbumNotFound(w, r)
 			return
 		}
 		h.logger.Error("failed to get album", slog.String("error", err.Error()))
@@ -452,21 +2905,182 @@ func (h *AlbumHandler) DownloadAlbum(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Check if downloads are allowed for this album
-	if !album.AllowDownloads {
-		http.Error(w, "Downloads are not enabled for this album", http.StatusForbidden)
+	token := r.URL.Query().Get("token")
+	if err := h.albumAuthService.Authorize(token, album); err != nil {
+		h.logger.Warn("invalid album access link",
+			slog.String("album_id", album.ID),
+			slog.String("error", err.Error()),
+		)
+		http.Error(w, "Invalid or expired access link", http.StatusUnauthorized)
 		return
 	}
 
-	// Stream the ZIP file
-	if err := h.imageService.StreamAlbumZIP(w, album, quality); err != nil {
-		h.logger.Error("failed to stream album ZIP",
-			slog.String("album", album.Slug),
-			slog.String("quality", quality),
-			slog.String("error", err.Error()))
-		// Don't write error response as headers may already be sent
+	h.setAlbumAccessCookie(w, album.ID, token)
+	http.Redirect(w, r, album.PublicPath(), http.StatusSeeOther)
+}
+
+// setAlbumAccessCookie stores an album access token in the album's access cookie.
+func (h *AlbumHandler) setAlbumAccessCookie(w http.ResponseWriter, albumID, token string) {
+	http.SetCookie(w, &http.Cookie{
+		Name:     services.AlbumAccessCookieName(albumID),
+		Value:    token,
+		Path:     "/",
+		MaxAge:   int(h.albumAuthService.TokenTTL().Seconds()),
+		HttpOnly: true,
+		Secure:   false, // Set to true in production with HTTPS
+		SameSite: http.SameSiteLaxMode,
+	})
+}
+
+// refreshCover re-renders an album's clean cover image after a change that may affect it.
+// Returns the album's current cover URL.
+func (h *AlbumHandler) refreshCover(albumID string) string {
+	return refreshAlbumCover(h.albumService, h.imageService, h.logger, albumID)
+}
+
+// refreshThumbnails starts re-rendering an album's thumbnails in the background if an update
+// changed its thumbnail fit.
+func (h *AlbumHandler) refreshThumbnails(before, after *models.Album) {
+	if h.regenerateService == nil || h.imageService.ThumbnailFit(before) == h.imageService.ThumbnailFit(after) {
+		return
+	}
+
+	job, err := h.regenerateService.StartThumbnails(after.ID)
+	if err != nil {
+		h.logger.Warn("failed to start thumbnail regeneration",
+			slog.String("album_id", after.ID),
+			slog.String("error", err.Error()),
+		)
+		return
+	}
+	h.logger.Info("thumbnail fit changed, regenerating thumbnails",
+		slog.String("album_id", after.ID),
+		slog.String("job_id", job.ID),
+	)
+}
+
+// refreshDisplays starts re-rendering an album's display versions in the background if an
+// update changed their size.
+func (h *AlbumHandler) refreshDisplays(before, after *models.Album) {
+	if h.regenerateService == nil || h.imageService.DisplayMaxEdge(before) == h.imageService.DisplayMaxEdge(after) {
+		return
+	}
+
+	job, err := h.regenerateService.StartDisplays(after.ID)
+	if err != nil {
+		h.logger.Warn("failed to start display regeneration",
+			slog.String("album_id", after.ID),
+			slog.String("error", err.Error()),
+		)
 		return
 	}
+	h.logger.Info("display size changed, regenerating display versions",
+		slog.String("album_id", after.ID),
+		slog.String("job_id", job.ID),
+	)
+}
+
+// hasAlbumAccess reports whether the request may view an album's contents.
+// Albums that are not restricted are always accessible. Restricted albums require a valid
+// access token, supplied either as the album's access cookie or as a ?token= share link.
+func (h *AlbumHandler) hasAlbumAccess(r *http.Request, album *models.Album) bool {
+	if !album.RequiresAccessToken() {
+		return true
+	}
+
+	if h.albumAuthService == nil {
+		return false
+	}
+
+	return h.albumAuthService.HasAccess(r, album)
+}
+
+// previewAsVisitor reports whether an admin asked, with ?as=visitor, to see a response as a
+// visitor would. The flag only counts with an admin session and only ever hides data, so it
+// cannot widen what anyone else sees.
+func previewAsVisitor(r *http.Request) bool {
+	return r.URL.Query().Get("as") == "visitor" && middleware.GetSession(r.Context()) != nil
+}
+
+// visitorAlbum returns a copy of the album without the fields only admins may see. Photo
+// positions are only shown for albums that share locations and do not scrub GPS data from
+// downloads. A photo's original file URL is only shown where the original may be downloaded
+// as stored: not for albums that withhold originals or scrub GPS data from them, nor for
+// photos that are not downloadable. Photo and cover URLs are signed if the handler has an
+// image URL signer.
+func (h *AlbumHandler) visitorAlbum(album models.Album) models.Album {
+	album.PasswordHash = ""
+	album.AllowedEmails = nil
+	album.ScheduledChanges = nil
+	hideLocations := !album.ShareLocations || album.ScrubGPSOnDownload
+	hideOriginals := !album.AllowDownloads || !album.AllowsDownloadQuality("original") || album.ScrubGPSOnDownload
+	now := time.Now()
+	photos := make([]models.Photo, len(album.Photos))
+	for i, photo := range album.Photos {
+		if hideLocations {
+			photo.EXIF = photo.EXIF.WithoutLocation()
+		}
+		if hideOriginals || !photo.Downloadable {
+			photo.URLOriginal = ""
+		}
+		if h.imageURLs != nil {
+			h.imageURLs.SignPhoto(&photo, now)
+		}
+		photos[i] = photo
+	}
+	album.Photos = photos
+	album.CoverURL = h.signedURL(album.CoverURL)
+	return album
+}
+
+// signedURL returns a photo file URL signed for visitors, or as it is without an image URL
+// signer.
+func (h *AlbumHandler) signedURL(rawURL string) string {
+	if h.imageURLs == nil || rawURL == "" {
+		return rawURL
+	}
+	return h.imageURLs.Sign(rawURL, time.Now())
+}
+
+// refreshAlbumCover renders an album's clean cover image and stores the new cover URL.
+// Failures are logged rather than returned, since the album change that triggered the
+// refresh has already been saved. Returns the album's current cover URL.
+func refreshAlbumCover(albumService *services.AlbumService, imageService *services.ImageService, logger *slog.Logger, albumID string) string {
+	album, err := albumService.GetByID(albumID)
+	if err != nil {
+		logger.Warn("failed to load album for cover refresh",
+			slog.String("album_id", albumID),
+			slog.String("error", err.Error()),
+		)
+		return ""
+	}
+
+	coverURL, err := imageService.RenderCover(album)
+	if err != nil {
+		logger.Warn("failed to render album cover",
+			slog.String("album_id", albumID),
+			slog.String("error", err.Error()),
+		)
+		return album.CoverURL
+	}
+
+	if coverURL == album.CoverURL {
+		return album.CoverURL
+	}
+
+	// Only the cover URL is saved, so edits made while the cover rendered are kept
+	_, err = albumService.Modify(albumID, func(stored *models.Album) error {
+		stored.CoverURL = coverURL
+		return nil
+	})
+	if err != nil {
+		logger.Warn("failed to save album cover",
+			slog.String("album_id", albumID),
+			slog.String("error", err.Error()),
+		)
+		return album.CoverURL
+	}
+	return coverURL
 }
 
 // respondJSON writes a JSON response.
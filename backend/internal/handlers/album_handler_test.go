@@ -0,0 +1,4140 @@
+package handlers
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"io"
+	"log/slog"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"path"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/davidbyttow/govips/v2/vips"
+	"github.com/go-chi/chi/v5"
+	"github.com/njoubert/nielsshootsfilm/backend/internal/middleware"
+	"github.com/njoubert/nielsshootsfilm/backend/internal/models"
+	"github.com/njoubert/nielsshootsfilm/backend/internal/services"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// setupAlbumHandler creates an album handler backed by temporary data and upload directories.
+func setupAlbumHandler(t *testing.T) (*AlbumHandler, *services.AlbumService, *services.FileService) {
+	t.Helper()
+
+	fileService, err := services.NewFileService(t.TempDir())
+	require.NoError(t, err)
+
+	albumService := services.NewAlbumService(fileService)
+	logger := slog.New(slog.NewTextHandler(&strings.Builder{}, nil))
+
+	imageService, err := services.NewImageService(t.TempDir(), nil, logger)
+	require.NoError(t, err)
+
+	return NewAlbumHandler(albumService, imageService, logger), albumService, fileService
+}
+
+func TestAlbumHandler_Create_AppliesDefaults(t *testing.T) {
+	handler, albumService, fileService := setupAlbumHandler(t)
+
+	defaultsService := services.NewAlbumDefaultsService(fileService)
+	require.NoError(t, defaultsService.Update(&models.AlbumDefaults{
+		Visibility:       "unlisted",
+		AllowDownloads:   true,
+		WatermarkEnabled: true,
+	}))
+	albumService.SetDefaultsService(defaultsService)
+
+	// Minimal body inherits the defaults
+	req := httptest.NewRequest("POST", "/api/admin/albums", strings.NewReader(`{"title":"Minimal"}`))
+	w := httptest.NewRecorder()
+	handler.Create(w, req)
+	require.Equal(t, http.StatusCreated, w.Code)
+
+	var created models.Album
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&created))
+	assert.Equal(t, "unlisted", created.Visibility)
+	assert.True(t, created.AllowDownloads)
+	assert.True(t, created.WatermarkEnabled)
+	assert.False(t, created.WatermarkCover)
+
+	// Explicit fields, including false booleans, override the defaults
+	body := `{"title":"Explicit","visibility":"public","allow_downloads":false,"watermark_enabled":false}`
+	req = httptest.NewRequest("POST", "/api/admin/albums", strings.NewReader(body))
+	w = httptest.NewRecorder()
+	handler.Create(w, req)
+	require.Equal(t, http.StatusCreated, w.Code)
+
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&created))
+	assert.Equal(t, "public", created.Visibility)
+	assert.False(t, created.AllowDownloads)
+	assert.False(t, created.WatermarkEnabled)
+}
+
+func TestAlbumHandler_JSONBodyLimits(t *testing.T) {
+	handler, albumService, _ := setupAlbumHandler(t)
+
+	album := &models.Album{Title: "Limits", Visibility: "public"}
+	require.NoError(t, albumService.Create(album))
+
+	send := func(opts middleware.JSONBodyOptions, action func(http.ResponseWriter, *http.Request), target, body string) *httptest.ResponseRecorder {
+		rctx := chi.NewRouteContext()
+		rctx.URLParams.Add("id", album.ID)
+		req := httptest.NewRequest("POST", target, strings.NewReader(body))
+		req = req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, rctx))
+		w := httptest.NewRecorder()
+		middleware.JSONBody(opts)(http.HandlerFunc(action)).ServeHTTP(w, req)
+		return w
+	}
+	lenient := middleware.JSONBodyOptions{MaxBytes: 1 << 10}
+	strict := middleware.JSONBodyOptions{MaxBytes: 1 << 10, Strict: true}
+
+	// Bodies over the limit are rejected before they are read in full
+	oversize := `{"title":"` + strings.Repeat("x", 2<<10) + `"}`
+	w := send(lenient, handler.Create, "/api/admin/albums", oversize)
+	assert.Equal(t, http.StatusRequestEntityTooLarge, w.Code)
+	assert.Contains(t, w.Body.String(), "1KB limit")
+	w = send(lenient, handler.SetPassword, "/api/admin/albums/"+album.ID+"/set-password", `{"password":"`+strings.Repeat("x", 2<<10)+`"}`)
+	assert.Equal(t, http.StatusRequestEntityTooLarge, w.Code)
+
+	// Without a configured limit the default applies
+	req := httptest.NewRequest("POST", "/api/admin/albums", strings.NewReader(`{"title":"`+strings.Repeat("x", middleware.DefaultMaxJSONBodySize)+`"}`))
+	w = httptest.NewRecorder()
+	handler.Create(w, req)
+	assert.Equal(t, http.StatusRequestEntityTooLarge, w.Code)
+
+	// Unknown fields are ignored unless decoding is strict
+	body := `{"title":"Extra","visibility":"public","colour":"red"}`
+	assert.Equal(t, http.StatusCreated, send(lenient, handler.Create, "/api/admin/albums", body).Code)
+	w = send(strict, handler.Create, "/api/admin/albums", body)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Contains(t, w.Body.String(), `unknown field "colour"`)
+
+	w = send(strict, handler.ReorderPhotos, "/api/admin/albums/"+album.ID+"/reorder-photos", `{"photo_ids":[],"reverse":true}`)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Contains(t, w.Body.String(), `unknown field "reverse"`)
+
+	// Strict decoding still accepts known fields
+	assert.Equal(t, http.StatusCreated, send(strict, handler.Create, "/api/admin/albums", `{"title":"Known","visibility":"public"}`).Code)
+}
+
+// createTestJPEG returns an encoded JPEG with a simple gradient.
+func createTestJPEG(t *testing.T, width, height int) []byte {
+	t.Helper()
+
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+	for x := 0; x < width; x++ {
+		for y := 0; y < height; y++ {
+			img.Set(x, y, color.RGBA{R: uint8(x % 256), G: uint8(y % 256), B: 120, A: 255})
+		}
+	}
+
+	var buf bytes.Buffer
+	require.NoError(t, jpeg.Encode(&buf, img, nil))
+	return buf.Bytes()
+}
+
+// createProtectedAlbum creates a downloadable password-protected album and returns it.
+func createProtectedAlbum(t *testing.T, albumService *services.AlbumService, password string) *models.Album {
+	t.Helper()
+
+	hash, err := services.HashPassword(password)
+	require.NoError(t, err)
+
+	album := &models.Album{
+		Title:          "Client Gallery",
+		Visibility:     "password_protected",
+		PasswordHash:   hash, // pragma: allowlist secret
+		AllowDownloads: true,
+	}
+	require.NoError(t, albumService.Create(album))
+	return album
+}
+
+// newSlugRequest creates a request with the chi slug URL parameter set.
+func newSlugRequest(method, target, slug string) *http.Request {
+	req := httptest.NewRequest(method, target, nil)
+	rctx := chi.NewRouteContext()
+	rctx.URLParams.Add("slug", slug)
+	return req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, rctx))
+}
+
+func TestAlbumHandler_Upsert(t *testing.T) {
+	handler, albumService, _ := setupAlbumHandler(t)
+
+	upsert := func(slug, query, body string) *httptest.ResponseRecorder {
+		req := newSlugRequest("PUT", "/api/admin/albums/by-slug/"+slug+query, slug)
+		req.Body = io.NopCloser(strings.NewReader(body))
+		w := httptest.NewRecorder()
+		handler.Upsert(w, req)
+		return w
+	}
+
+	// An unknown slug creates the album, keeping the slug from the URL
+	w := upsert("summer-trip", "", `{"title":"Summer Trip","slug":"ignored","visibility":"public"}`)
+	require.Equal(t, http.StatusCreated, w.Code)
+	var created models.Album
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &created))
+	assert.Equal(t, "summer-trip", created.Slug)
+	assert.NotEmpty(t, created.ID)
+
+	require.NoError(t, albumService.AddPhoto(created.ID, &models.Photo{FilenameOriginal: "a.jpg", Title: "Beach"}))
+	require.NoError(t, albumService.AddPhoto(created.ID, &models.Photo{FilenameOriginal: "b.jpg"}))
+
+	// The same slug updates it in place; omitted fields, photos included, are kept
+	w = upsert("summer-trip", "", `{"title":"Summer Trip 2024","allow_downloads":true}`)
+	require.Equal(t, http.StatusOK, w.Code)
+
+	album, err := albumService.GetBySlug("summer-trip")
+	require.NoError(t, err)
+	assert.Equal(t, created.ID, album.ID)
+	assert.Equal(t, "Summer Trip 2024", album.Title)
+	assert.Equal(t, "public", album.Visibility)
+	assert.True(t, album.AllowDownloads)
+	require.Len(t, album.Photos, 2)
+	assert.Equal(t, "Beach", album.Photos[0].Title)
+
+	// Photos in the body replace the stored ones rather than merging into them
+	w = upsert("summer-trip", "", `{"photos":[{"id":"`+album.Photos[1].ID+`","filename_original":"b.jpg","title":"Dunes"}]}`)
+	require.Equal(t, http.StatusOK, w.Code)
+	album, err = albumService.GetBySlug("summer-trip")
+	require.NoError(t, err)
+	require.Len(t, album.Photos, 1)
+	assert.Equal(t, "Dunes", album.Photos[0].Title)
+	assert.Empty(t, album.Photos[0].Caption)
+
+	// Sections in the body replace the stored ones too, keeping nothing of those they overlay
+	w = upsert("summer-trip", "", `{"sections":[{"id":"day-1","title":"Day 1","photo_ids":["`+album.Photos[0].ID+`"]}]}`)
+	require.Equal(t, http.StatusOK, w.Code)
+	w = upsert("summer-trip", "", `{"sections":[{"title":"Day 2"}]}`)
+	require.Equal(t, http.StatusOK, w.Code)
+	album, err = albumService.GetBySlug("summer-trip")
+	require.NoError(t, err)
+	require.Len(t, album.Sections, 1)
+	assert.Equal(t, "Day 2", album.Sections[0].Title)
+	assert.NotEqual(t, "day-1", album.Sections[0].ID)
+	assert.Empty(t, album.Sections[0].PhotoIDs)
+
+	albums, err := albumService.GetAll()
+	require.NoError(t, err)
+	assert.Len(t, albums, 1)
+
+	// Slugs are keyed per namespace, and validation still applies
+	w = upsert("summer-trip", "?namespace=jane", `{"title":"Jane's Summer","visibility":"public"}`)
+	require.Equal(t, http.StatusCreated, w.Code)
+	namespaced, err := albumService.GetByNamespacedSlug("jane", "summer-trip")
+	require.NoError(t, err)
+	assert.NotEqual(t, created.ID, namespaced.ID)
+
+	assert.Equal(t, http.StatusBadRequest, upsert("summer-trip", "", `{"visibility":"secret"}`).Code)
+	assert.Equal(t, http.StatusBadRequest, upsert("winter", "", `{"title":""}`).Code)
+	assert.Equal(t, http.StatusBadRequest, upsert("winter", "", `not json`).Code)
+}
+
+func TestAlbumHandler_DownloadAlbum_PasswordProtected(t *testing.T) {
+	handler, albumService, _ := setupAlbumHandler(t)
+
+	albumAuthService, err := services.NewAlbumAuthService("test-secret", time.Hour)
+	require.NoError(t, err)
+	handler.SetAlbumAuthService(albumAuthService)
+
+	album := createProtectedAlbum(t, albumService, "letmein")
+
+	// Without a cookie the download is rejected
+	req := newSlugRequest("GET", "/api/albums/"+album.Slug+"/download?quality=display", album.Slug)
+	w := httptest.NewRecorder()
+	handler.DownloadAlbum(w, req)
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+
+	// A forged cookie is rejected
+	req = newSlugRequest("GET", "/api/albums/"+album.Slug+"/download?quality=display", album.Slug)
+	req.AddCookie(&http.Cookie{Name: services.AlbumAccessCookieName(album.ID), Value: "forged.token"})
+	w = httptest.NewRecorder()
+	handler.DownloadAlbum(w, req)
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+
+	// Verifying the password issues a cookie that unlocks the download
+	body := `{"album_id":"` + album.ID + `","password":"letmein"}`
+	verifyReq := httptest.NewRequest("POST", "/api/albums/verify-password", strings.NewReader(body))
+	verifyW := httptest.NewRecorder()
+	handler.VerifyPassword(verifyW, verifyReq)
+	require.Equal(t, http.StatusOK, verifyW.Code)
+
+	cookies := verifyW.Result().Cookies()
+	require.Len(t, cookies, 1)
+	assert.Equal(t, services.AlbumAccessCookieName(album.ID), cookies[0].Name)
+
+	req = newSlugRequest("GET", "/api/albums/"+album.Slug+"/download?quality=display", album.Slug)
+	req.AddCookie(cookies[0])
+	w = httptest.NewRecorder()
+	handler.DownloadAlbum(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "application/zip", w.Header().Get("Content-Type"))
+}
+
+func TestAlbumHandler_VerifyPassword_WrongPassword(t *testing.T) {
+	handler, albumService, _ := setupAlbumHandler(t)
+
+	albumAuthService, err := services.NewAlbumAuthService("test-secret", time.Hour)
+	require.NoError(t, err)
+	handler.SetAlbumAuthService(albumAuthService)
+
+	album := createProtectedAlbum(t, albumService, "letmein")
+
+	body := `{"album_id":"` + album.ID + `","password":"wrong"}`
+	req := httptest.NewRequest("POST", "/api/albums/verify-password", strings.NewReader(body))
+	w := httptest.NewRecorder()
+	handler.VerifyPassword(w, req)
+
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+	assert.Empty(t, w.Result().Cookies())
+}
+
+func TestAlbumHandler_SetPassword_RevokesSessions(t *testing.T) {
+	handler, albumService, _ := setupAlbumHandler(t)
+
+	albumAuthService, err := services.NewAlbumAuthService("test-secret", time.Hour)
+	require.NoError(t, err)
+	albumAuthService.SetSessionStore(services.NewMemorySessionStore())
+	handler.SetAlbumAuthService(albumAuthService)
+
+	album := createProtectedAlbum(t, albumService, "letmein")
+	verify := func(password string) *http.Cookie {
+		body := `{"album_id":"` + album.ID + `","password":"` + password + `"}`
+		w := httptest.NewRecorder()
+		handler.VerifyPassword(w, httptest.NewRequest("POST", "/api/albums/verify-password", strings.NewReader(body)))
+		require.Equal(t, http.StatusOK, w.Code)
+		cookies := w.Result().Cookies()
+		require.Len(t, cookies, 1)
+		return cookies[0]
+	}
+	canDownload := func(cookie *http.Cookie) bool {
+		req := newSlugRequest("GET", "/api/albums/"+album.Slug+"/download?quality=display&chunked=true", album.Slug)
+		req.AddCookie(cookie)
+		w := httptest.NewRecorder()
+		handler.DownloadAlbum(w, req)
+		return w.Code == http.StatusOK
+	}
+	setPassword := func(password string) {
+		req := httptest.NewRequest("POST", "/api/admin/albums/"+album.ID+"/set-password", strings.NewReader(`{"password":"`+password+`"}`))
+		rctx := chi.NewRouteContext()
+		rctx.URLParams.Add("id", album.ID)
+		w := httptest.NewRecorder()
+		handler.SetPassword(w, req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, rctx)))
+		require.Equal(t, http.StatusNoContent, w.Code)
+	}
+
+	cookie := verify("letmein")
+	assert.True(t, canDownload(cookie))
+
+	// Changing the password signs out visitors who entered the old one
+	setPassword("new-secret")
+	assert.False(t, canDownload(cookie))
+	assert.True(t, canDownload(verify("new-secret")))
+}
+
+func TestAlbumHandler_RemovePassword_ClearsProtectedSchedule(t *testing.T) {
+	handler, albumService, _ := setupAlbumHandler(t)
+
+	album := createProtectedAlbum(t, albumService, "letmein")
+	now := time.Now().UTC()
+	_, err := albumService.SetSchedule(album.ID, []models.ScheduledChange{
+		{At: now.Add(time.Hour), Visibility: "unlisted"},
+		{At: now.Add(2 * time.Hour), Visibility: "password_protected"},
+	}, now)
+	require.NoError(t, err)
+
+	req := httptest.NewRequest("DELETE", "/api/admin/albums/"+album.ID+"/password", nil)
+	rctx := chi.NewRouteContext()
+	rctx.URLParams.Add("id", album.ID)
+	w := httptest.NewRecorder()
+	handler.RemovePassword(w, req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, rctx)))
+	require.Equal(t, http.StatusNoContent, w.Code)
+
+	// The change back to password_protected goes with the password; others are kept
+	stored, err := albumService.GetByID(album.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "public", stored.Visibility)
+	assert.Empty(t, stored.PasswordHash)
+	require.Len(t, stored.ScheduledChanges, 1)
+	assert.Equal(t, "unlisted", stored.ScheduledChanges[0].Visibility)
+}
+
+func TestAlbumHandler_RevokeAllSessions(t *testing.T) {
+	for _, mode := range []string{"stateless", "sessions"} {
+		t.Run(mode, func(t *testing.T) {
+			handler, albumService, _ := setupAlbumHandler(t)
+
+			albumAuthService, err := services.NewAlbumAuthService("test-secret", time.Hour)
+			require.NoError(t, err)
+			if mode == "sessions" {
+				albumAuthService.SetSessionStore(services.NewMemorySessionStore())
+			}
+			handler.SetAlbumAuthService(albumAuthService)
+
+			album := createProtectedAlbum(t, albumService, "letmein")
+			verify := func() *http.Cookie {
+				body := `{"album_id":"` + album.ID + `","password":"letmein"}`
+				w := httptest.NewRecorder()
+				handler.VerifyPassword(w, httptest.NewRequest("POST", "/api/albums/verify-password", strings.NewReader(body)))
+				require.Equal(t, http.StatusOK, w.Code)
+				cookies := w.Result().Cookies()
+				require.Len(t, cookies, 1)
+				return cookies[0]
+			}
+			canDownload := func(cookie *http.Cookie) bool {
+				req := newSlugRequest("GET", "/api/albums/"+album.Slug+"/download?quality=display&chunked=true", album.Slug)
+				req.AddCookie(cookie)
+				w := httptest.NewRecorder()
+				handler.DownloadAlbum(w, req)
+				return w.Code == http.StatusOK
+			}
+
+			cookie := verify()
+			require.True(t, canDownload(cookie))
+
+			w := httptest.NewRecorder()
+			handler.RevokeAllSessions(w, httptest.NewRequest("POST", "/api/admin/sessions/revoke-all", nil))
+			require.Equal(t, http.StatusOK, w.Code)
+			var resp struct {
+				RevokedSessions int       `json:"revoked_sessions"`
+				RotatedAt       time.Time `json:"rotated_at"`
+			}
+			require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+			assert.NotZero(t, resp.RotatedAt)
+			if mode == "sessions" {
+				assert.Equal(t, 1, resp.RevokedSessions)
+			}
+
+			// Cookies from before the revocation are rejected; entering the password again works
+			assert.False(t, canDownload(cookie))
+			assert.True(t, canDownload(verify()))
+		})
+	}
+
+	handler, _, _ := setupAlbumHandler(t)
+	w := httptest.NewRecorder()
+	handler.RevokeAllSessions(w, httptest.NewRequest("POST", "/api/admin/sessions/revoke-all", nil))
+	assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+}
+
+// newPhotoRequest creates a request with the chi slug and photoId URL parameters set.
+func newPhotoRequest(target, slug, photoID string) *http.Request {
+	req := httptest.NewRequest("GET", target, nil)
+	rctx := chi.NewRouteContext()
+	rctx.URLParams.Add("slug", slug)
+	rctx.URLParams.Add("photoId", photoID)
+	return req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, rctx))
+}
+
+func TestAlbumHandler_DownloadAlbum_DefaultQuality(t *testing.T) {
+	handler, albumService, _ := setupAlbumHandler(t)
+
+	album := &models.Album{Title: "Proofs", Visibility: "public", AllowDownloads: true}
+	require.NoError(t, albumService.Create(album))
+	photo, err := handler.imageService.ProcessBytes("proof.jpg", createTestJPEG(t, 640, 480))
+	require.NoError(t, err)
+	require.NoError(t, albumService.AddPhoto(album.ID, photo))
+
+	downloadedSize := func(query string) uint64 {
+		t.Helper()
+		w := httptest.NewRecorder()
+		handler.DownloadAlbum(w, newSlugRequest("GET", "/api/albums/"+album.Slug+"/download"+query, album.Slug))
+		require.Equal(t, http.StatusOK, w.Code)
+		zipReader, err := zip.NewReader(bytes.NewReader(w.Body.Bytes()), int64(w.Body.Len()))
+		require.NoError(t, err)
+		require.Len(t, zipReader.File, 1)
+		return zipReader.File[0].UncompressedSize64
+	}
+
+	// Without ?quality= the display versions are downloaded
+	assert.Equal(t, uint64(photo.FileSizeDisplay), downloadedSize(""))
+
+	// The default is configurable, and explicit qualities still win
+	handler.SetDefaultDownloadQuality("original")
+	assert.Equal(t, uint64(photo.FileSizeOriginal), downloadedSize(""))
+	assert.Equal(t, uint64(photo.FileSizeThumbnail), downloadedSize("?quality=thumbnail"))
+
+	// Invalid explicit values are still rejected, here and for the manifest
+	w := httptest.NewRecorder()
+	handler.DownloadAlbum(w, newSlugRequest("GET", "/api/albums/"+album.Slug+"/download?quality=huge", album.Slug))
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	w = httptest.NewRecorder()
+	handler.DownloadManifest(w, newSlugRequest("GET", "/api/albums/"+album.Slug+"/download/manifest?quality=huge", album.Slug))
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	w = httptest.NewRecorder()
+	handler.DownloadManifest(w, newSlugRequest("GET", "/api/albums/"+album.Slug+"/download/manifest", album.Slug))
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), "quality=original")
+}
+
+func TestAlbumHandler_DeletePhoto_SharedFiles(t *testing.T) {
+	handler, albumService, _ := setupAlbumHandler(t)
+
+	album := &models.Album{Title: "Contact Sheet", Visibility: "public"}
+	require.NoError(t, albumService.Create(album))
+	data := createTestJPEG(t, 64, 48)
+	first, err := handler.imageService.Uploads(album).ProcessBytes("frame.jpg", data)
+	require.NoError(t, err)
+	require.NoError(t, albumService.AddPhoto(album.ID, first))
+	album, err = albumService.GetByID(album.ID)
+	require.NoError(t, err)
+	repeat, err := handler.imageService.Uploads(album).ProcessBytes("frame-again.jpg", data)
+	require.NoError(t, err)
+	require.NoError(t, albumService.AddPhoto(album.ID, repeat))
+
+	deletePhoto := func(photoID string) {
+		req := httptest.NewRequest("DELETE", "/api/admin/albums/"+album.ID+"/photos/"+photoID, nil)
+		rctx := chi.NewRouteContext()
+		rctx.URLParams.Add("id", album.ID)
+		rctx.URLParams.Add("photoId", photoID)
+		w := httptest.NewRecorder()
+		handler.DeletePhoto(w, req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, rctx)))
+		require.Equal(t, http.StatusNoContent, w.Code)
+	}
+	originalKey := strings.TrimPrefix(first.URLOriginal, "/uploads/")
+
+	// The repeat upload's files outlive the first photo, and go with the last one
+	deletePhoto(first.ID)
+	_, err = handler.imageService.Storage().Get(originalKey)
+	require.NoError(t, err)
+
+	deletePhoto(repeat.ID)
+	_, err = handler.imageService.Storage().Get(originalKey)
+	assert.ErrorIs(t, err, services.ErrObjectNotFound)
+}
+
+func TestAlbumHandler_DownloadPhoto_Format(t *testing.T) {
+	handler, albumService, _ := setupAlbumHandler(t)
+
+	album := &models.Album{Title: "Scans", Visibility: "public", AllowDownloads: true}
+	require.NoError(t, albumService.Create(album))
+	photo, err := handler.imageService.ProcessBytes("frame.jpg", createTestJPEG(t, 64, 48))
+	require.NoError(t, err)
+	require.NoError(t, albumService.AddPhoto(album.ID, photo))
+
+	download := func(query string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		handler.DownloadPhoto(w, newPhotoRequest("/api/albums/"+album.Slug+"/photos/"+photo.ID+"/download"+query, album.Slug, photo.ID))
+		return w
+	}
+
+	w := download("?format=tiff")
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "image/tiff", w.Header().Get("Content-Type"))
+	assert.Equal(t, `attachment; filename="frame.tiff"`, w.Header().Get("Content-Disposition"))
+	img, err := vips.NewImageFromBuffer(w.Body.Bytes())
+	require.NoError(t, err)
+	assert.Equal(t, vips.ImageTypeTIFF, img.Format())
+
+	w = download("?quality=original&format=png")
+	require.Equal(t, http.StatusOK, w.Code)
+	img, err = vips.NewImageFromBuffer(w.Body.Bytes())
+	require.NoError(t, err)
+	assert.Equal(t, vips.ImageTypePNG, img.Format())
+
+	assert.Equal(t, http.StatusBadRequest, download("?format=bmp").Code)
+	assert.Equal(t, http.StatusBadRequest, download("?quality=display&format=png").Code)
+
+	// An original that cannot be decoded is an error, not an empty download
+	originalKey := strings.TrimPrefix(photo.URLOriginal, "/uploads/")
+	require.NoError(t, handler.imageService.Storage().Put(originalKey, strings.NewReader("not an image"), 12))
+	w = download("?format=png")
+	assert.Equal(t, http.StatusUnsupportedMediaType, w.Code)
+	assert.Empty(t, w.Header().Get("Content-Disposition"))
+}
+
+func TestAlbumHandler_PhotoDownloadable(t *testing.T) {
+	handler, albumService, _ := setupAlbumHandler(t)
+
+	album := &models.Album{Title: "Teasers", Visibility: "public", AllowDownloads: true}
+	require.NoError(t, albumService.Create(album))
+
+	open, err := handler.imageService.ProcessBytes("open.jpg", createTestJPEG(t, 64, 48))
+	require.NoError(t, err)
+	assert.True(t, open.Downloadable, "new photos are downloadable by default")
+	require.NoError(t, albumService.AddPhoto(album.ID, open))
+
+	teaser, err := handler.imageService.ProcessBytes("teaser.jpg", createTestJPEG(t, 64, 48))
+	require.NoError(t, err)
+	teaser.Downloadable = false
+	require.NoError(t, albumService.AddPhoto(album.ID, teaser))
+
+	// The ZIP only contains downloadable photos
+	w := httptest.NewRecorder()
+	handler.DownloadAlbum(w, newSlugRequest("GET", "/api/albums/"+album.Slug+"/download?quality=original", album.Slug))
+	require.Equal(t, http.StatusOK, w.Code)
+
+	zipReader, err := zip.NewReader(bytes.NewReader(w.Body.Bytes()), int64(w.Body.Len()))
+	require.NoError(t, err)
+	require.Len(t, zipReader.File, 1)
+	assert.Equal(t, "open.jpg", zipReader.File[0].Name)
+
+	// Single-photo download honours the same flag
+	target := "/api/albums/" + album.Slug + "/photos/"
+	w = httptest.NewRecorder()
+	handler.DownloadPhoto(w, newPhotoRequest(target+open.ID+"/download", album.Slug, open.ID))
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, `attachment; filename="open.jpg"`, w.Header().Get("Content-Disposition"))
+	assert.NotEmpty(t, w.Body.Bytes())
+
+	w = httptest.NewRecorder()
+	handler.DownloadPhoto(w, newPhotoRequest(target+teaser.ID+"/download", album.Slug, teaser.ID))
+	assert.Equal(t, http.StatusForbidden, w.Code)
+
+	w = httptest.NewRecorder()
+	handler.DownloadPhoto(w, newPhotoRequest(target+"missing/download", album.Slug, "missing"))
+	assert.Equal(t, http.StatusNotFound, w.Code)
+
+	// Album-level downloads still gate single photos
+	album.AllowDownloads = false
+	require.NoError(t, albumService.Update(album.ID, album))
+	w = httptest.NewRecorder()
+	handler.DownloadPhoto(w, newPhotoRequest(target+open.ID+"/download", album.Slug, open.ID))
+	assert.Equal(t, http.StatusForbidden, w.Code)
+}
+
+func TestAlbumHandler_PreviewAsVisitor(t *testing.T) {
+	handler, albumService, _ := setupAlbumHandler(t)
+
+	albumAuthService, err := services.NewAlbumAuthService("test-secret", time.Hour)
+	require.NoError(t, err)
+	handler.SetAlbumAuthService(albumAuthService)
+
+	public := &models.Album{Title: "Street", Visibility: "public"}
+	require.NoError(t, albumService.Create(public))
+	unlisted := &models.Album{Title: "Drafts", Visibility: "unlisted"}
+	require.NoError(t, albumService.Create(unlisted))
+	clients := &models.Album{Title: "Wedding", Visibility: "public", AllowedEmails: []string{"client@example.com"}}
+	require.NoError(t, albumService.Create(clients))
+	protected := createProtectedAlbum(t, albumService, "letmein")
+
+	adminHash, err := services.HashPassword("admin-pass")
+	require.NoError(t, err)
+	authService := services.NewAuthService("admin", adminHash, time.Hour)
+	sessionID, err := authService.Authenticate("admin", "admin-pass")
+	require.NoError(t, err)
+	adminCookie := &http.Cookie{Name: "photoadmin_session", Value: sessionID}
+
+	router := chi.NewRouter()
+	router.Route("/api", func(r chi.Router) {
+		r.Use(middleware.Auth(authService, handler.logger))
+		r.Get("/albums", handler.GetAll)
+		r.Get("/albums/{id}", handler.GetByID)
+	})
+
+	get := func(target string, cookies ...*http.Cookie) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("GET", target, nil)
+		for _, cookie := range cookies {
+			req.AddCookie(cookie)
+		}
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+	listTitles := func(w *httptest.ResponseRecorder) []string {
+		var body struct {
+			Albums []models.Album `json:"albums"`
+		}
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &body))
+		titles := make([]string, len(body.Albums))
+		for i, album := range body.Albums {
+			titles[i] = album.Title
+		}
+		return titles
+	}
+
+	// Admins normally see every album, including admin-only fields
+	w := get("/api/albums", adminCookie)
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.ElementsMatch(t, []string{"Street", "Drafts", "Wedding", "Client Gallery"}, listTitles(w))
+	w = get("/api/albums/"+protected.ID, adminCookie)
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), "password_hash")
+
+	// As a visitor, only public albums without an access list are listed
+	w = get("/api/albums?as=visitor", adminCookie)
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, []string{"Street"}, listTitles(w))
+
+	// Restricted albums need an access token, and admin-only fields are dropped
+	w = get("/api/albums/"+protected.ID+"?as=visitor", adminCookie)
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+	w = get("/api/albums/"+clients.ID+"?as=visitor", adminCookie)
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+
+	verifyReq := httptest.NewRequest("POST", "/api/albums/verify-password",
+		strings.NewReader(`{"album_id":"`+protected.ID+`","password":"letmein"}`))
+	verifyW := httptest.NewRecorder()
+	handler.VerifyPassword(verifyW, verifyReq)
+	require.Equal(t, http.StatusOK, verifyW.Code)
+	accessCookie := verifyW.Result().Cookies()[0]
+
+	w = get("/api/albums/"+protected.ID+"?as=visitor", adminCookie, accessCookie)
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.NotContains(t, w.Body.String(), "password_hash")
+	w = get("/api/albums/"+unlisted.ID+"?as=visitor", adminCookie)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	// Without an admin session the flag grants nothing
+	for _, target := range []string{"/api/albums?as=visitor", "/api/albums/" + public.ID + "?as=visitor"} {
+		assert.Equal(t, http.StatusUnauthorized, get(target).Code, target)
+		assert.Equal(t, http.StatusUnauthorized, get(target, accessCookie).Code, target)
+		assert.Equal(t, http.StatusUnauthorized, get(target, &http.Cookie{Name: "photoadmin_session", Value: "forged"}).Code, target)
+	}
+}
+
+func TestAlbumHandler_SwapPhotos(t *testing.T) {
+	handler, albumService, _ := setupAlbumHandler(t)
+
+	album := &models.Album{Title: "Sequence", Visibility: "public"}
+	require.NoError(t, albumService.Create(album))
+	first := &models.Photo{FilenameOriginal: "first.jpg"}
+	require.NoError(t, albumService.AddPhoto(album.ID, first))
+	second := &models.Photo{FilenameOriginal: "second.jpg"}
+	require.NoError(t, albumService.AddPhoto(album.ID, second))
+
+	swap := func(albumID, body string) int {
+		req := httptest.NewRequest("POST", "/api/admin/albums/"+albumID+"/photos/swap", strings.NewReader(body))
+		rctx := chi.NewRouteContext()
+		rctx.URLParams.Add("id", albumID)
+		req = req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, rctx))
+		w := httptest.NewRecorder()
+		handler.SwapPhotos(w, req)
+		return w.Code
+	}
+
+	assert.Equal(t, http.StatusNoContent, swap(album.ID, `{"a":"`+first.ID+`","b":"`+second.ID+`"}`))
+	stored, err := albumService.GetByID(album.ID)
+	require.NoError(t, err)
+	assert.Equal(t, second.ID, stored.Photos[0].ID)
+	assert.Equal(t, first.ID, stored.Photos[1].ID)
+
+	// An ID that is not in the album is rejected and nothing moves
+	assert.Equal(t, http.StatusBadRequest, swap(album.ID, `{"a":"`+first.ID+`","b":"not-a-photo"}`))
+	stored, err = albumService.GetByID(album.ID)
+	require.NoError(t, err)
+	assert.Equal(t, second.ID, stored.Photos[0].ID)
+
+	assert.Equal(t, http.StatusBadRequest, swap(album.ID, `{"a":"`+first.ID+`"}`))
+	assert.Equal(t, http.StatusNotFound, swap("missing", `{"a":"`+first.ID+`","b":"`+second.ID+`"}`))
+}
+
+func TestAlbumHandler_MovePhotos(t *testing.T) {
+	handler, albumService, _ := setupAlbumHandler(t)
+
+	album := &models.Album{Title: "Sequence", Visibility: "public"}
+	require.NoError(t, albumService.Create(album))
+	ids := []string{}
+	for _, name := range []string{"1.jpg", "2.jpg", "3.jpg", "4.jpg"} {
+		photo := &models.Photo{FilenameOriginal: name}
+		require.NoError(t, albumService.AddPhoto(album.ID, photo))
+		ids = append(ids, photo.ID)
+	}
+
+	move := func(albumID, body string) int {
+		req := httptest.NewRequest("PATCH", "/api/admin/albums/"+albumID+"/photos/positions", strings.NewReader(body))
+		rctx := chi.NewRouteContext()
+		rctx.URLParams.Add("id", albumID)
+		req = req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, rctx))
+		w := httptest.NewRecorder()
+		handler.MovePhotos(w, req)
+		return w.Code
+	}
+	names := func() []string {
+		stored, err := albumService.GetByID(album.ID)
+		require.NoError(t, err)
+		names := []string{}
+		for _, photo := range stored.Photos {
+			names = append(names, photo.FilenameOriginal)
+		}
+		return names
+	}
+
+	assert.Equal(t, http.StatusNoContent, move(album.ID, `[{"photo_id":"`+ids[3]+`","position":2}]`))
+	assert.Equal(t, []string{"1.jpg", "4.jpg", "2.jpg", "3.jpg"}, names())
+
+	// Duplicate positions are rejected and nothing moves
+	assert.Equal(t, http.StatusBadRequest, move(album.ID, `[{"photo_id":"`+ids[0]+`","position":3},{"photo_id":"`+ids[1]+`","position":3}]`))
+	assert.Equal(t, []string{"1.jpg", "4.jpg", "2.jpg", "3.jpg"}, names())
+
+	assert.Equal(t, http.StatusBadRequest, move(album.ID, `[]`))
+	assert.Equal(t, http.StatusBadRequest, move(album.ID, `{"photo_id":"`+ids[0]+`","position":1}`))
+	assert.Equal(t, http.StatusNotFound, move("missing", `[{"photo_id":"`+ids[0]+`","position":1}]`))
+}
+
+func TestAlbumHandler_MovePhotoTo(t *testing.T) {
+	handler, albumService, _ := setupAlbumHandler(t)
+
+	album := &models.Album{Title: "Sequence", Visibility: "public"}
+	require.NoError(t, albumService.Create(album))
+	ids := []string{}
+	for _, name := range []string{"1.jpg", "2.jpg", "3.jpg"} {
+		photo := &models.Photo{FilenameOriginal: name}
+		require.NoError(t, albumService.AddPhoto(album.ID, photo))
+		ids = append(ids, photo.ID)
+	}
+
+	moveTo := func(albumID, photoID, body string) int {
+		req := httptest.NewRequest("POST", "/api/admin/albums/"+albumID+"/photos/"+photoID+"/move-to", strings.NewReader(body))
+		rctx := chi.NewRouteContext()
+		rctx.URLParams.Add("id", albumID)
+		rctx.URLParams.Add("photoId", photoID)
+		req = req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, rctx))
+		w := httptest.NewRecorder()
+		handler.MovePhotoTo(w, req)
+		return w.Code
+	}
+	names := func() []string {
+		stored, err := albumService.GetByID(album.ID)
+		require.NoError(t, err)
+		names := []string{}
+		for _, photo := range stored.Photos {
+			names = append(names, photo.FilenameOriginal)
+		}
+		return names
+	}
+
+	assert.Equal(t, http.StatusNoContent, moveTo(album.ID, ids[2], `{"target":"top"}`))
+	assert.Equal(t, []string{"3.jpg", "1.jpg", "2.jpg"}, names())
+
+	assert.Equal(t, http.StatusNoContent, moveTo(album.ID, ids[2], `{"target":"bottom"}`))
+	assert.Equal(t, []string{"1.jpg", "2.jpg", "3.jpg"}, names())
+
+	assert.Equal(t, http.StatusBadRequest, moveTo(album.ID, ids[0], `{"target":"up"}`))
+	assert.Equal(t, http.StatusBadRequest, moveTo(album.ID, ids[0], `not json`))
+	assert.Equal(t, http.StatusNotFound, moveTo(album.ID, "fake-photo-id", `{"target":"top"}`))
+	assert.Equal(t, http.StatusNotFound, moveTo("missing", ids[0], `{"target":"top"}`))
+	assert.Equal(t, []string{"1.jpg", "2.jpg", "3.jpg"}, names())
+}
+
+func TestAlbumHandler_GetQualityFlags(t *testing.T) {
+	handler, albumService, _ := setupAlbumHandler(t)
+
+	album := &models.Album{Title: "Contact Sheet", Visibility: "public"}
+	require.NoError(t, albumService.Create(album))
+	for _, photo := range []*models.Photo{
+		{FilenameOriginal: "dark.jpg", Exposure: &models.Exposure{MeanLuminance: 0.08, ShadowsClipped: 0.4}},
+		{FilenameOriginal: "fine.jpg", Exposure: &models.Exposure{MeanLuminance: 0.45, HighlightsClipped: 0.02}},
+		{FilenameOriginal: "old.jpg"},
+		{FilenameOriginal: "blown.jpg", Exposure: &models.Exposure{MeanLuminance: 0.7, HighlightsClipped: 0.3}},
+	} {
+		require.NoError(t, albumService.AddPhoto(album.ID, photo))
+	}
+
+	get := func(albumID, query string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("GET", "/api/albums/"+albumID+"/quality-flags"+query, nil)
+		rctx := chi.NewRouteContext()
+		rctx.URLParams.Add("id", albumID)
+		req = req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, rctx))
+		w := httptest.NewRecorder()
+		handler.GetQualityFlags(w, req)
+		return w
+	}
+	type response struct {
+		Thresholds services.ExposureThresholds `json:"thresholds"`
+		Photos     []QualityFlag               `json:"photos"`
+		Unmeasured int                         `json:"unmeasured"`
+	}
+
+	w := get(album.ID, "")
+	require.Equal(t, http.StatusOK, w.Code)
+	var resp response
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&resp))
+	assert.Equal(t, services.DefaultExposureThresholds, resp.Thresholds)
+	assert.Equal(t, 1, resp.Unmeasured)
+	require.Len(t, resp.Photos, 2)
+	assert.Equal(t, "dark.jpg", resp.Photos[0].FilenameOriginal)
+	assert.Equal(t, services.ExposureUnder, resp.Photos[0].Flag)
+	assert.Equal(t, "blown.jpg", resp.Photos[1].FilenameOriginal)
+	assert.Equal(t, services.ExposureOver, resp.Photos[1].Flag)
+	assert.Equal(t, 0.3, resp.Photos[1].Exposure.HighlightsClipped)
+
+	// Stricter thresholds flag more, looser ones less
+	w = get(album.ID, "?dark=0.5")
+	require.Equal(t, http.StatusOK, w.Code)
+	resp = response{}
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&resp))
+	assert.Len(t, resp.Photos, 3)
+
+	w = get(album.ID, "?dark=0.05&clipped=0.5")
+	require.Equal(t, http.StatusOK, w.Code)
+	resp = response{}
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&resp))
+	assert.Empty(t, resp.Photos)
+
+	assert.Equal(t, http.StatusBadRequest, get(album.ID, "?dark=low").Code)
+	assert.Equal(t, http.StatusBadRequest, get(album.ID, "?clipped=2").Code)
+	assert.Equal(t, http.StatusBadRequest, get(album.ID, "?dark=0.9&bright=0.1").Code)
+	assert.Equal(t, http.StatusNotFound, get("missing", "").Code)
+}
+
+func TestAlbumHandler_GetIncompletePhotos(t *testing.T) {
+	handler, albumService, _ := setupAlbumHandler(t)
+
+	album := &models.Album{Title: "Pre-flight", Visibility: "public"}
+	require.NoError(t, albumService.Create(album))
+	for _, photo := range []models.Photo{
+		{FilenameOriginal: "done.jpg", Title: "Harbour", AltText: "Boats at dusk", Caption: "Cape Town"},
+		{FilenameOriginal: "untitled.jpg", AltText: "A lighthouse"},
+		{FilenameOriginal: "blank.jpg", Title: "  ", Caption: "Only a caption"},
+	} {
+		require.NoError(t, albumService.AddPhoto(album.ID, &photo))
+	}
+
+	complete := &models.Album{Title: "Ready", Visibility: "public"}
+	require.NoError(t, albumService.Create(complete))
+	require.NoError(t, albumService.AddPhoto(complete.ID, &models.Photo{FilenameOriginal: "a.jpg", Title: "A", AltText: "Alt"}))
+
+	type response struct {
+		Require []string          `json:"require"`
+		Photos  []IncompletePhoto `json:"photos"`
+		Total   int               `json:"total"`
+	}
+	get := func(albumID, query string) (int, response) {
+		req := httptest.NewRequest("GET", "/api/albums/"+albumID+"/incomplete"+query, nil)
+		rctx := chi.NewRouteContext()
+		rctx.URLParams.Add("id", albumID)
+		req = req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, rctx))
+
+		w := httptest.NewRecorder()
+		handler.GetIncompletePhotos(w, req)
+		var resp response
+		if w.Code == http.StatusOK {
+			require.NoError(t, json.NewDecoder(w.Body).Decode(&resp))
+		}
+		return w.Code, resp
+	}
+	missingByFile := func(resp response) map[string][]string {
+		out := map[string][]string{}
+		for _, photo := range resp.Photos {
+			out[photo.FilenameOriginal] = photo.Missing
+		}
+		return out
+	}
+
+	tests := []struct {
+		name    string
+		query   string
+		require []string
+		missing map[string][]string
+	}{
+		{"defaults to title and alt", "", []string{"title", "alt"}, map[string][]string{
+			"untitled.jpg": {"title"},
+			"blank.jpg":    {"title", "alt"},
+		}},
+		{"single field", "?require=alt", []string{"alt"}, map[string][]string{
+			"blank.jpg": {"alt"},
+		}},
+		{"all fields", "?require=caption,title,alt", []string{"caption", "title", "alt"}, map[string][]string{
+			"untitled.jpg": {"caption", "title"},
+			"blank.jpg":    {"title", "alt"},
+		}},
+		{"duplicates and spaces are tolerated", "?require=caption,%20caption", []string{"caption"}, map[string][]string{
+			"untitled.jpg": {"caption"},
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			code, resp := get(album.ID, tt.query)
+			require.Equal(t, http.StatusOK, code)
+			assert.Equal(t, tt.require, resp.Require)
+			assert.Equal(t, tt.missing, missingByFile(resp))
+			assert.Equal(t, 3, resp.Total)
+		})
+	}
+
+	// Photos are listed in album order
+	_, resp := get(album.ID, "")
+	require.Len(t, resp.Photos, 2)
+	assert.Equal(t, "untitled.jpg", resp.Photos[0].FilenameOriginal)
+	assert.Equal(t, "blank.jpg", resp.Photos[1].FilenameOriginal)
+
+	// A fully described album has nothing to report
+	code, resp := get(complete.ID, "?require=title,alt")
+	require.Equal(t, http.StatusOK, code)
+	assert.Empty(t, resp.Photos)
+	assert.NotNil(t, resp.Photos, "an empty list, not null")
+	assert.Equal(t, 1, resp.Total)
+
+	code, _ = get(album.ID, "?require=title,location")
+	assert.Equal(t, http.StatusBadRequest, code)
+	code, _ = get(album.ID, "?require=,")
+	assert.Equal(t, http.StatusBadRequest, code)
+	code, _ = get("missing", "")
+	assert.Equal(t, http.StatusNotFound, code)
+}
+
+func TestAlbumHandler_GetAlbumDiff(t *testing.T) {
+	handler, albumService, _ := setupAlbumHandler(t)
+
+	proof := &models.Album{Title: "Proofs", Visibility: "public"}
+	final := &models.Album{Title: "Final", Visibility: "public"}
+	require.NoError(t, albumService.Create(proof))
+	require.NoError(t, albumService.Create(final))
+	for _, photo := range []models.Photo{
+		{FilenameOriginal: "keeper.jpg", ContentHash: "aaaa"},
+		{FilenameOriginal: "outtake.jpg", ContentHash: "bbbb"},
+	} {
+		require.NoError(t, albumService.AddPhoto(proof.ID, &photo))
+	}
+	for _, photo := range []models.Photo{
+		{FilenameOriginal: "keeper-final.jpg", ContentHash: "aaaa"},
+		{FilenameOriginal: "retouched.jpg", ContentHash: "cccc"},
+		{FilenameOriginal: "legacy.jpg"},
+	} {
+		require.NoError(t, albumService.AddPhoto(final.ID, &photo))
+	}
+
+	get := func(query string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("GET", "/api/albums/diff"+query, nil)
+		w := httptest.NewRecorder()
+		handler.GetAlbumDiff(w, req)
+		return w
+	}
+
+	w := get("?a=" + proof.ID + "&b=" + final.ID)
+	require.Equal(t, http.StatusOK, w.Code)
+	var diff AlbumDiff
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &diff))
+	assert.Equal(t, "Proofs", diff.A.Title)
+	assert.Equal(t, "Final", diff.B.Title)
+	require.Len(t, diff.OnlyA, 1)
+	assert.Equal(t, "outtake.jpg", diff.OnlyA[0].FilenameOriginal)
+	require.Len(t, diff.OnlyB, 1)
+	assert.Equal(t, "retouched.jpg", diff.OnlyB[0].FilenameOriginal)
+	require.Len(t, diff.Shared, 1)
+	assert.Equal(t, "aaaa", diff.Shared[0].ContentHash)
+	assert.Equal(t, "keeper.jpg", diff.Shared[0].A.FilenameOriginal)
+	assert.Equal(t, "keeper-final.jpg", diff.Shared[0].B.FilenameOriginal)
+	assert.Empty(t, diff.UnhashedA)
+	require.Len(t, diff.UnhashedB, 1)
+	assert.Equal(t, "legacy.jpg", diff.UnhashedB[0].FilenameOriginal)
+
+	// An album compared with itself shares everything
+	w = get("?a=" + proof.ID + "&b=" + proof.ID)
+	require.Equal(t, http.StatusOK, w.Code)
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &diff))
+	assert.Empty(t, diff.OnlyA)
+	assert.Empty(t, diff.OnlyB)
+	assert.Len(t, diff.Shared, 2)
+
+	assert.Equal(t, http.StatusBadRequest, get("?a="+proof.ID).Code)
+	assert.Equal(t, http.StatusNotFound, get("?a="+proof.ID+"&b=missing").Code)
+}
+
+func TestAlbumHandler_Selections(t *testing.T) {
+	handler, albumService, fileService := setupAlbumHandler(t)
+	handler.SetSelectionService(services.NewSelectionService(fileService))
+	albumAuthService, err := services.NewAlbumAuthService("test-secret", time.Hour)
+	require.NoError(t, err)
+	handler.SetAlbumAuthService(albumAuthService)
+
+	album := &models.Album{Title: "Proofs", Visibility: "public", ScrubGPSOnDownload: true, ShareLocations: true}
+	require.NoError(t, albumService.Create(album))
+	lat, lon := 37.77, -122.42
+	for _, photo := range []models.Photo{
+		{FilenameOriginal: "first.jpg"},
+		{FilenameOriginal: "second.jpg", EXIF: &models.EXIF{Latitude: &lat, Longitude: &lon}},
+		{FilenameOriginal: "third.jpg"},
+	} {
+		require.NoError(t, albumService.AddPhoto(album.ID, &photo))
+	}
+	stored, err := albumService.GetByID(album.ID)
+	require.NoError(t, err)
+	photoIDs := []string{stored.Photos[2].ID, stored.Photos[1].ID}
+
+	create := func(slug, body string) *httptest.ResponseRecorder {
+		req := newSlugRequest("POST", "/api/albums/"+slug+"/selections", slug)
+		req.Body = io.NopCloser(strings.NewReader(body))
+		w := httptest.NewRecorder()
+		handler.CreateSelection(w, req)
+		return w
+	}
+	get := func(token string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("GET", "/api/selections/"+token, nil)
+		rctx := chi.NewRouteContext()
+		rctx.URLParams.Add("token", token)
+		req = req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, rctx))
+		w := httptest.NewRecorder()
+		handler.GetSelection(w, req)
+		return w
+	}
+
+	body, err := json.Marshal(map[string]any{"name": "Selects", "photo_ids": photoIDs})
+	require.NoError(t, err)
+	w := create(album.Slug, string(body))
+	require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+	var created CreatedSelection
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &created))
+	assert.Equal(t, "Selects", created.Name)
+	assert.Equal(t, "/api/selections/"+created.Token, created.URL)
+
+	// The token opens the selected photos, in album order, as visitors see them
+	w = get(created.Token)
+	require.Equal(t, http.StatusOK, w.Code)
+	var shared SharedSelection
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &shared))
+	assert.Equal(t, "Proofs", shared.Album.Title)
+	require.Len(t, shared.Photos, 2)
+	assert.Equal(t, "second.jpg", shared.Photos[0].FilenameOriginal)
+	assert.Equal(t, "third.jpg", shared.Photos[1].FilenameOriginal)
+	assert.Nil(t, shared.Photos[0].EXIF, "the album scrubs GPS data")
+
+	// Photos deleted since drop out
+	require.NoError(t, albumService.DeletePhoto(album.ID, stored.Photos[2].ID))
+	w = get(created.Token)
+	require.Equal(t, http.StatusOK, w.Code)
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &shared))
+	require.Len(t, shared.Photos, 1)
+	assert.Equal(t, "second.jpg", shared.Photos[0].FilenameOriginal)
+
+	assert.Equal(t, http.StatusNotFound, get("sel_unknown").Code)
+	assert.Equal(t, http.StatusBadRequest, create(album.Slug, `{"name": "Selects", "photo_ids": ["elsewhere"]}`).Code)
+	assert.Equal(t, http.StatusBadRequest, create(album.Slug, `{"name": "", "photo_ids": ["`+stored.Photos[0].ID+`"]}`).Code)
+	assert.Equal(t, http.StatusNotFound, create("missing", string(body)).Code)
+
+	// Restricted albums need an access token to select from
+	protected := createProtectedAlbum(t, albumService, "secret")
+	assert.Equal(t, http.StatusUnauthorized, create(protected.Slug, `{"name": "Selects", "photo_ids": []}`).Code)
+
+	// Admins list an album's selections
+	req := httptest.NewRequest("GET", "/api/albums/"+album.ID+"/selections", nil)
+	rctx := chi.NewRouteContext()
+	rctx.URLParams.Add("id", album.ID)
+	req = req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, rctx))
+	w = httptest.NewRecorder()
+	handler.ListSelections(w, req)
+	require.Equal(t, http.StatusOK, w.Code)
+	var listed struct {
+		Selections []models.Selection `json:"selections"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &listed))
+	require.Len(t, listed.Selections, 1)
+	assert.Equal(t, created.Token, listed.Selections[0].Token)
+}
+
+func TestAlbumHandler_GetPhotosByHash(t *testing.T) {
+	handler, albumService, _ := setupAlbumHandler(t)
+
+	data := createTestJPEG(t, 64, 48)
+	var hash string
+	for _, title := range []string{"Proofs", "Final", "Unrelated"} {
+		album := &models.Album{Title: title, Visibility: "public"}
+		require.NoError(t, albumService.Create(album))
+		upload := data
+		if title == "Unrelated" {
+			upload = createTestJPEG(t, 32, 32)
+		}
+		photo, err := handler.imageService.Uploads(album).ProcessBytes("frame.jpg", upload)
+		require.NoError(t, err)
+		require.NoError(t, albumService.AddPhoto(album.ID, photo))
+		if hash == "" {
+			hash = photo.ContentHash
+		}
+	}
+	require.Len(t, hash, 64)
+
+	get := func(hash string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("GET", "/api/photos/by-hash/"+hash, nil)
+		rctx := chi.NewRouteContext()
+		rctx.URLParams.Add("hash", hash)
+		req = req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, rctx))
+		w := httptest.NewRecorder()
+		handler.GetPhotosByHash(w, req)
+		return w
+	}
+
+	var body struct {
+		Hash       string                      `json:"hash"`
+		References []services.ContentReference `json:"references"`
+	}
+	w := get(strings.ToUpper(hash))
+	require.Equal(t, http.StatusOK, w.Code)
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &body))
+	assert.Equal(t, hash, body.Hash)
+	require.Len(t, body.References, 2)
+	assert.Equal(t, "Proofs", body.References[0].AlbumTitle)
+	assert.Equal(t, "Final", body.References[1].AlbumTitle)
+	assert.NotEqual(t, body.References[0].PhotoID, body.References[1].PhotoID)
+	assert.Equal(t, "frame.jpg", body.References[1].FilenameOriginal)
+
+	w = get(strings.Repeat("0", 64))
+	require.Equal(t, http.StatusOK, w.Code)
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &body))
+	assert.Empty(t, body.References)
+
+	assert.Equal(t, http.StatusBadRequest, get("not-a-hash").Code)
+}
+
+func TestAlbumHandler_GetDuplicatePhotos(t *testing.T) {
+	handler, albumService, _ := setupAlbumHandler(t)
+
+	album := &models.Album{Title: "Contact Sheet", Visibility: "public"}
+	require.NoError(t, albumService.Create(album))
+	for _, photo := range []models.Photo{
+		{FilenameOriginal: "a.jpg", PerceptualHash: "00000000000000ff"},
+		{FilenameOriginal: "b.jpg", PerceptualHash: "00000000000000fe"},
+		{FilenameOriginal: "c.jpg", PerceptualHash: "ffffffff00000000"},
+		{FilenameOriginal: "legacy.jpg"},
+	} {
+		require.NoError(t, albumService.AddPhoto(album.ID, &photo))
+	}
+
+	get := func(query string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("GET", "/api/albums/"+album.ID+"/duplicates"+query, nil)
+		rctx := chi.NewRouteContext()
+		rctx.URLParams.Add("id", album.ID)
+		req = req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, rctx))
+		w := httptest.NewRecorder()
+		handler.GetDuplicatePhotos(w, req)
+		return w
+	}
+
+	var body struct {
+		Threshold int                `json:"threshold"`
+		Clusters  [][]DuplicatePhoto `json:"clusters"`
+	}
+	w := get("")
+	require.Equal(t, http.StatusOK, w.Code)
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &body))
+	assert.Equal(t, services.DefaultDuplicateThreshold, body.Threshold)
+	require.Len(t, body.Clusters, 1)
+	require.Len(t, body.Clusters[0], 2)
+	assert.Equal(t, "a.jpg", body.Clusters[0][0].FilenameOriginal)
+	assert.Equal(t, "b.jpg", body.Clusters[0][1].FilenameOriginal)
+
+	w = get("?threshold=0")
+	require.Equal(t, http.StatusOK, w.Code)
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &body))
+	assert.Empty(t, body.Clusters)
+
+	assert.Equal(t, http.StatusBadRequest, get("?threshold=65").Code)
+	assert.Equal(t, http.StatusBadRequest, get("?threshold=close").Code)
+}
+
+func TestAlbumHandler_GetHistory(t *testing.T) {
+	handler, albumService, _ := setupAlbumHandler(t)
+
+	album := &models.Album{Title: "Roll 12", Visibility: "public"}
+	require.NoError(t, albumService.Create(album))
+	for _, name := range []string{"a.jpg", "b.jpg", "c.jpg"} {
+		require.NoError(t, albumService.AddPhoto(album.ID, &models.Photo{FilenameOriginal: name}))
+	}
+
+	get := func(id, query string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("GET", "/api/albums/"+id+"/history"+query, nil)
+		rctx := chi.NewRouteContext()
+		rctx.URLParams.Add("id", id)
+		req = req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, rctx))
+		w := httptest.NewRecorder()
+		handler.GetHistory(w, req)
+		return w
+	}
+
+	var body struct {
+		Entries []models.AlbumHistoryEntry `json:"entries"`
+		Total   int                        `json:"total"`
+		Offset  int                        `json:"offset"`
+		Limit   int                        `json:"limit"`
+	}
+	w := get(album.ID, "")
+	require.Equal(t, http.StatusOK, w.Code)
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &body))
+	assert.Equal(t, 4, body.Total)
+	assert.Equal(t, DefaultHistoryPageSize, body.Limit)
+	require.Len(t, body.Entries, 4)
+	assert.Equal(t, models.AlbumHistoryCreated, body.Entries[0].Type)
+
+	w = get(album.ID, "?offset=1&limit=2")
+	require.Equal(t, http.StatusOK, w.Code)
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &body))
+	assert.Equal(t, 4, body.Total)
+	assert.Equal(t, 1, body.Offset)
+	assert.Equal(t, 2, body.Limit)
+	require.Len(t, body.Entries, 2)
+	assert.Equal(t, models.AlbumHistoryPhotosAdded, body.Entries[0].Type)
+
+	assert.Equal(t, http.StatusNotFound, get("missing", "").Code)
+	assert.Equal(t, http.StatusBadRequest, get(album.ID, "?limit=0").Code)
+	assert.Equal(t, http.StatusBadRequest, get(album.ID, "?limit=201").Code)
+	assert.Equal(t, http.StatusBadRequest, get(album.ID, "?offset=-1").Code)
+
+	// The middle page links both ways, keeping other query parameters
+	w = get(album.ID, "?offset=1&limit=2&x=y")
+	assert.Equal(t, `</api/albums/`+album.ID+`/history?limit=2&offset=3&x=y>; rel="next", `+
+		`</api/albums/`+album.ID+`/history?limit=2&offset=0&x=y>; rel="prev"`, w.Header().Get("Link"))
+}
+
+func TestAlbumHandler_PageLinks(t *testing.T) {
+	handler, albumService, _ := setupAlbumHandler(t)
+
+	album := &models.Album{Title: "Roll 13", Visibility: "public"}
+	require.NoError(t, albumService.Create(album))
+	for i := range 5 {
+		require.NoError(t, albumService.AddPhoto(album.ID, &models.Photo{FilenameOriginal: fmt.Sprintf("%d.jpg", i)}))
+	}
+
+	getPhotos := func(query string) (*httptest.ResponseRecorder, AlbumPhotos) {
+		t.Helper()
+		req := httptest.NewRequest("GET", "/api/albums/"+album.ID+"/photos"+query, nil)
+		rctx := chi.NewRouteContext()
+		rctx.URLParams.Add("id", album.ID)
+		w := httptest.NewRecorder()
+		handler.GetPhotos(w, req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, rctx)))
+		require.Equal(t, http.StatusOK, w.Code)
+		var body AlbumPhotos
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &body))
+		return w, body
+	}
+	photosURL := func(offset int) string {
+		return fmt.Sprintf("</api/albums/%s/photos?limit=2&offset=%d>", album.ID, offset)
+	}
+
+	tests := []struct {
+		name   string
+		query  string
+		photos []string
+		link   string
+	}{
+		{"first page", "?limit=2", []string{"0.jpg", "1.jpg"}, photosURL(2) + `; rel="next"`},
+		{"middle page", "?offset=2&limit=2", []string{"2.jpg", "3.jpg"}, photosURL(4) + `; rel="next", ` + photosURL(0) + `; rel="prev"`},
+		{"last page", "?offset=4&limit=2", []string{"4.jpg"}, photosURL(2) + `; rel="prev"`},
+		{"past the end", "?offset=9&limit=2", []string{}, photosURL(3) + `; rel="prev"`},
+		{"unpaged", "", []string{"0.jpg", "1.jpg", "2.jpg", "3.jpg", "4.jpg"}, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w, body := getPhotos(tt.query)
+			assert.Equal(t, tt.link, w.Header().Get("Link"))
+			assert.Equal(t, 5, body.Total)
+			assert.Equal(t, 5, body.Matched)
+			names := []string{}
+			for _, photo := range body.Photos {
+				names = append(names, photo.FilenameOriginal)
+			}
+			assert.Equal(t, tt.photos, names)
+		})
+	}
+
+	// Album listings page the same way
+	other := &models.Album{Title: "Roll 14", Visibility: "public"}
+	require.NoError(t, albumService.Create(other))
+	w := httptest.NewRecorder()
+	handler.GetAll(w, httptest.NewRequest("GET", "/api/albums?limit=1", nil))
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, `</api/albums?limit=1&offset=1>; rel="next"`, w.Header().Get("Link"))
+	var listing struct {
+		Albums []models.Album `json:"albums"`
+		Total  int            `json:"total"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &listing))
+	assert.Len(t, listing.Albums, 1)
+	assert.Equal(t, 2, listing.Total)
+
+	w = httptest.NewRecorder()
+	handler.GetAll(w, httptest.NewRequest("GET", "/api/albums?limit=500", nil))
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+func TestAlbumHandler_UploadPhotos_Concurrent(t *testing.T) {
+	handler, albumService, _ := setupAlbumHandler(t)
+	handler.SetUploadConcurrency(3)
+
+	album := &models.Album{Title: "Batch", Visibility: "public"}
+	require.NoError(t, albumService.Create(album))
+
+	// Ten files of varying size, so workers finish out of order, with one unreadable file
+	var body bytes.Buffer
+	form := multipart.NewWriter(&body)
+	names := []string{}
+	for i := range 10 {
+		name := fmt.Sprintf("frame-%02d.jpg", i)
+		data := createTestJPEG(t, 64+(9-i)*40, 48)
+		if i == 4 {
+			data = []byte("not an image")
+		} else {
+			names = append(names, name)
+		}
+		part, err := form.CreateFormFile("photos", name)
+		require.NoError(t, err)
+		_, err = part.Write(data)
+		require.NoError(t, err)
+	}
+	require.NoError(t, form.Close())
+
+	req := httptest.NewRequest("POST", "/api/admin/albums/"+album.ID+"/photos/upload", &body)
+	req.Header.Set("Content-Type", form.FormDataContentType())
+	rctx := chi.NewRouteContext()
+	rctx.URLParams.Add("id", album.ID)
+	req = req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, rctx))
+
+	w := httptest.NewRecorder()
+	handler.UploadPhotos(w, req)
+	require.Equal(t, http.StatusOK, w.Code)
+
+	var resp struct {
+		Uploaded []models.Photo `json:"uploaded"`
+		Errors   []string       `json:"errors"`
+	}
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&resp))
+	require.Len(t, resp.Errors, 1)
+	assert.True(t, strings.HasPrefix(resp.Errors[0], "frame-04.jpg: "))
+
+	uploadedNames := []string{}
+	for _, photo := range resp.Uploaded {
+		uploadedNames = append(uploadedNames, photo.FilenameOriginal)
+	}
+	assert.Equal(t, names, uploadedNames)
+
+	// Every processed file landed in the album, in the order it was sent
+	album, err := albumService.GetByID(album.ID)
+	require.NoError(t, err)
+	albumNames := []string{}
+	for i, photo := range album.Photos {
+		albumNames = append(albumNames, photo.FilenameOriginal)
+		assert.Equal(t, i+1, photo.Order)
+	}
+	assert.Equal(t, names, albumNames)
+}
+
+func TestAlbumHandler_UploadPhotos_Results(t *testing.T) {
+	handler, albumService, _ := setupAlbumHandler(t)
+	handler.SetUploadConcurrency(2)
+
+	album := &models.Album{Title: "Mixed", Visibility: "public"}
+	require.NoError(t, albumService.Create(album))
+
+	// Successes and failures interleaved, with a repeated filename
+	files := []struct {
+		name string
+		data []byte
+	}{
+		{"frame-01.jpg", createTestJPEG(t, 320, 240)},
+		{"broken.jpg", []byte("not an image")},
+		{"frame-02.jpg", createTestJPEG(t, 240, 320)},
+		{"notes.txt", []byte("contact sheet notes")},
+		{"frame-01.jpg", createTestJPEG(t, 64, 48)},
+	}
+	var body bytes.Buffer
+	form := multipart.NewWriter(&body)
+	for _, file := range files {
+		part, err := form.CreateFormFile("photos", file.name)
+		require.NoError(t, err)
+		_, err = part.Write(file.data)
+		require.NoError(t, err)
+	}
+	require.NoError(t, form.Close())
+
+	req := httptest.NewRequest("POST", "/api/admin/albums/"+album.ID+"/photos/upload", &body)
+	req.Header.Set("Content-Type", form.FormDataContentType())
+	rctx := chi.NewRouteContext()
+	rctx.URLParams.Add("id", album.ID)
+	req = req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, rctx))
+
+	w := httptest.NewRecorder()
+	handler.UploadPhotos(w, req)
+	require.Equal(t, http.StatusOK, w.Code)
+
+	var resp UploadResponse
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&resp))
+	assert.Equal(t, UploadSummary{Total: 5, Uploaded: 3, Failed: 2}, resp.Summary)
+
+	// One result per file, in the order sent
+	require.Len(t, resp.Results, len(files))
+	wantStatus := []string{UploadStatusUploaded, UploadStatusFailed, UploadStatusUploaded, UploadStatusFailed, UploadStatusUploaded}
+	stored, err := albumService.GetByID(album.ID)
+	require.NoError(t, err)
+	require.Len(t, stored.Photos, 3)
+	uploaded := 0
+	for i, result := range resp.Results {
+		assert.Equal(t, files[i].name, result.Filename)
+		assert.Equal(t, wantStatus[i], result.Status, result.Filename)
+		if result.Status == UploadStatusFailed {
+			assert.Nil(t, result.Photo)
+			assert.NotEmpty(t, result.Error)
+			continue
+		}
+		require.NotNil(t, result.Photo)
+		assert.Empty(t, result.Error)
+		assert.Equal(t, stored.Photos[uploaded].ID, result.Photo.ID)
+		uploaded++
+	}
+
+	// Each result carries the photo processed from its own file, even with repeated names
+	assert.Equal(t, 320, resp.Results[0].Photo.Width)
+	assert.Equal(t, 64, resp.Results[4].Photo.Width)
+
+	// The flat lists carry the same outcomes
+	assert.Len(t, resp.Uploaded, 3)
+	require.Len(t, resp.Errors, 2)
+	assert.Equal(t, "broken.jpg: "+resp.Results[1].Error, resp.Errors[0])
+	assert.Equal(t, "notes.txt: "+resp.Results[3].Error, resp.Errors[1])
+}
+
+func TestAlbumHandler_UploadPhotos_MinUploadEdge(t *testing.T) {
+	handler, albumService, _ := setupAlbumHandler(t)
+
+	minEdge := 320
+	album := &models.Album{Title: "Fine Art", Visibility: "public", MinUploadEdgePx: &minEdge}
+	require.NoError(t, albumService.Create(album))
+
+	var body bytes.Buffer
+	form := multipart.NewWriter(&body)
+	for name, data := range map[string][]byte{
+		"small.jpg": createTestJPEG(t, 319, 200),
+		"exact.jpg": createTestJPEG(t, 200, 320),
+	} {
+		part, err := form.CreateFormFile("photos", name)
+		require.NoError(t, err)
+		_, err = part.Write(data)
+		require.NoError(t, err)
+	}
+	require.NoError(t, form.Close())
+
+	req := httptest.NewRequest("POST", "/api/admin/albums/"+album.ID+"/photos/upload", &body)
+	req.Header.Set("Content-Type", form.FormDataContentType())
+	rctx := chi.NewRouteContext()
+	rctx.URLParams.Add("id", album.ID)
+	req = req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, rctx))
+
+	w := httptest.NewRecorder()
+	handler.UploadPhotos(w, req)
+	require.Equal(t, http.StatusOK, w.Code)
+
+	// Only the image below the album's minimum is turned away, with a reason
+	var resp UploadResponse
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&resp))
+	assert.Equal(t, UploadSummary{Total: 2, Uploaded: 1, Failed: 1}, resp.Summary)
+	for _, result := range resp.Results {
+		switch result.Filename {
+		case "small.jpg":
+			assert.Equal(t, UploadStatusFailed, result.Status)
+			assert.Equal(t, "image resolution is too low: 319x200, but the longest edge must be at least 320 pixels", result.Error)
+		case "exact.jpg":
+			assert.Equal(t, UploadStatusUploaded, result.Status)
+		}
+	}
+
+	stored, err := albumService.GetByID(album.ID)
+	require.NoError(t, err)
+	require.Len(t, stored.Photos, 1)
+	assert.Equal(t, "exact.jpg", stored.Photos[0].FilenameOriginal)
+
+	// Negative minimums are invalid
+	minEdge = -1
+	assert.Error(t, albumService.Update(album.ID, album))
+}
+
+func TestAlbumHandler_UploadPhotos_UploadPolicy(t *testing.T) {
+	handler, albumService, _ := setupAlbumHandler(t)
+
+	albumAuthService, err := services.NewAlbumAuthService("test-secret", time.Hour)
+	require.NoError(t, err)
+	handler.SetAlbumAuthService(albumAuthService)
+
+	adminHash, err := services.HashPassword("admin-pass")
+	require.NoError(t, err)
+	authService := services.NewAuthService("admin", adminHash, time.Hour)
+	sessionID, err := authService.Authenticate("admin", "admin-pass")
+	require.NoError(t, err)
+	adminCookie := &http.Cookie{Name: "photoadmin_session", Value: sessionID}
+
+	adminOnly := createProtectedAlbum(t, albumService, "letmein")
+	collaborative := createProtectedAlbum(t, albumService, "letmein")
+	collaborative.UploadPolicy = models.UploadPolicyClients
+	require.NoError(t, albumService.Update(collaborative.ID, collaborative))
+
+	clientCookie := func(album *models.Album) *http.Cookie {
+		token, err := albumAuthService.VerifyPassword(album, "letmein")
+		require.NoError(t, err)
+		return &http.Cookie{Name: services.AlbumAccessCookieName(album.ID), Value: token}
+	}
+
+	router := chi.NewRouter()
+	router.With(middleware.Auth(authService, handler.logger)).Post("/api/admin/albums/{id}/photos/upload", handler.UploadPhotos)
+	router.With(middleware.OptionalAuth(authService)).Post("/api/albums/{slug}/photos/upload", handler.UploadPhotos)
+
+	upload := func(target, filename string, cookies ...*http.Cookie) *httptest.ResponseRecorder {
+		var body bytes.Buffer
+		form := multipart.NewWriter(&body)
+		part, err := form.CreateFormFile("photos", filename)
+		require.NoError(t, err)
+		_, err = part.Write(createTestJPEG(t, 64, 48))
+		require.NoError(t, err)
+		require.NoError(t, form.Close())
+
+		req := httptest.NewRequest("POST", target, &body)
+		req.Header.Set("Content-Type", form.FormDataContentType())
+		for _, cookie := range cookies {
+			req.AddCookie(cookie)
+		}
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+	photoNames := func(album *models.Album) []string {
+		stored, err := albumService.GetByID(album.ID)
+		require.NoError(t, err)
+		names := []string{}
+		for _, photo := range stored.Photos {
+			names = append(names, photo.FilenameOriginal)
+		}
+		return names
+	}
+
+	// The admin may upload to any album, on either route
+	assert.Equal(t, http.StatusOK, upload("/api/admin/albums/"+adminOnly.ID+"/photos/upload", "admin.jpg", adminCookie).Code)
+	assert.Equal(t, http.StatusOK, upload("/api/albums/"+adminOnly.Slug+"/photos/upload", "admin-public.jpg", adminCookie).Code)
+	assert.Equal(t, []string{"admin.jpg", "admin-public.jpg"}, photoNames(adminOnly))
+
+	// Clients with an access token may contribute to albums accepting client uploads
+	w := upload("/api/albums/"+collaborative.Slug+"/photos/upload", "guest.jpg", clientCookie(collaborative))
+	require.Equal(t, http.StatusOK, w.Code)
+	var resp UploadResponse
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&resp))
+	assert.Equal(t, UploadSummary{Total: 1, Uploaded: 1}, resp.Summary)
+	assert.Equal(t, []string{"guest.jpg"}, photoNames(collaborative))
+	stored, err := albumService.GetByID(adminOnly.ID)
+	require.NoError(t, err)
+	require.Len(t, stored.Photos, 2)
+	assert.False(t, stored.Photos[1].ClientUploaded)
+	stored, err = albumService.GetByID(collaborative.ID)
+	require.NoError(t, err)
+	require.Len(t, stored.Photos, 1)
+	assert.True(t, stored.Photos[0].ClientUploaded)
+
+	// But not to admin-only albums, and not without a valid token
+	assert.Equal(t, http.StatusForbidden, upload("/api/albums/"+adminOnly.Slug+"/photos/upload", "guest.jpg", clientCookie(adminOnly)).Code)
+	assert.Equal(t, http.StatusForbidden, upload("/api/albums/"+collaborative.Slug+"/photos/upload", "anonymous.jpg").Code)
+	forged := &http.Cookie{Name: services.AlbumAccessCookieName(collaborative.ID), Value: "forged.token"}
+	assert.Equal(t, http.StatusForbidden, upload("/api/albums/"+collaborative.Slug+"/photos/upload", "forged.jpg", forged).Code)
+	assert.Equal(t, []string{"admin.jpg", "admin-public.jpg"}, photoNames(adminOnly))
+	assert.Equal(t, []string{"guest.jpg"}, photoNames(collaborative))
+
+	// Unknown policies are invalid
+	collaborative.UploadPolicy = "everyone"
+	assert.ErrorContains(t, albumService.Update(collaborative.ID, collaborative), "album upload_policy must be admin or clients")
+}
+
+func TestAlbumHandler_UploadPhotos_ClientUploadLimits(t *testing.T) {
+	handler, albumService, _ := setupAlbumHandler(t)
+	handler.SetClientUploadLimits(3, 1<<20)
+
+	albumAuthService, err := services.NewAlbumAuthService("test-secret", time.Hour)
+	require.NoError(t, err)
+	handler.SetAlbumAuthService(albumAuthService)
+
+	album := createProtectedAlbum(t, albumService, "letmein")
+	album.UploadPolicy = models.UploadPolicyClients
+	require.NoError(t, albumService.Update(album.ID, album))
+	token, err := albumAuthService.VerifyPassword(album, "letmein")
+	require.NoError(t, err)
+
+	// Photos the admin uploaded do not count toward the limits
+	require.NoError(t, albumService.AddPhoto(album.ID, &models.Photo{ID: "admin", FilenameOriginal: "admin.jpg", FileSizeOriginal: 10 << 20}))
+	require.NoError(t, albumService.ReserveClientUploads(album.ID, 1, 100<<10, 3, 1<<20))
+
+	router := chi.NewRouter()
+	router.Post("/api/albums/{slug}/photos/upload", handler.UploadPhotos)
+	upload := func(files map[string][]byte) *httptest.ResponseRecorder {
+		var body bytes.Buffer
+		form := multipart.NewWriter(&body)
+		for name, data := range files {
+			part, err := form.CreateFormFile("photos", name)
+			require.NoError(t, err)
+			_, err = part.Write(data)
+			require.NoError(t, err)
+		}
+		require.NoError(t, form.Close())
+
+		req := httptest.NewRequest("POST", "/api/albums/"+album.Slug+"/photos/upload", &body)
+		req.Header.Set("Content-Type", form.FormDataContentType())
+		req.AddCookie(&http.Cookie{Name: services.AlbumAccessCookieName(album.ID), Value: token})
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+	counts := func() (int, int64) {
+		stored, err := albumService.GetByID(album.ID)
+		require.NoError(t, err)
+		return stored.ClientUploads, stored.ClientUploadBytes
+	}
+
+	// More photos than are left, or more bytes, are turned away without counting
+	small := createTestJPEG(t, 64, 48)
+	w := upload(map[string][]byte{"a.jpg": small, "b.jpg": small, "c.jpg": small})
+	assert.Equal(t, http.StatusRequestEntityTooLarge, w.Code)
+	assert.Contains(t, w.Body.String(), "at most 2 more photos")
+	assert.Equal(t, http.StatusRequestEntityTooLarge, upload(map[string][]byte{"big.jpg": make([]byte, 1<<20)}).Code)
+	photos, size := counts()
+	assert.Equal(t, 1, photos)
+	assert.Equal(t, int64(100<<10), size)
+
+	// An upload counts the bytes received, however the stored photo turns out
+	upload(map[string][]byte{"d.jpg": small})
+	photos, size = counts()
+	assert.Equal(t, 2, photos)
+	assert.Equal(t, int64(100<<10+len(small)), size)
+
+	// Admin edits, like removing photos, leave the counts alone
+	stored, err := albumService.GetByID(album.ID)
+	require.NoError(t, err)
+	stored.Photos = nil
+	stored.ClientUploads, stored.ClientUploadBytes = 0, 0
+	require.NoError(t, albumService.Update(album.ID, stored))
+	photos, _ = counts()
+	assert.Equal(t, 2, photos)
+
+	// An album that has reached a limit takes no more client uploads
+	require.NoError(t, albumService.ReserveClientUploads(album.ID, 1, 0, 3, 1<<20))
+	assert.Equal(t, http.StatusForbidden, upload(map[string][]byte{"e.jpg": small}).Code)
+}
+
+// createTestJPEGWithDate returns a test JPEG whose EXIF data records only a capture date, in
+// "2006:01:02 15:04:05" form.
+func createTestJPEGWithDate(t *testing.T, width, height int, dateTaken string) []byte {
+	t.Helper()
+
+	// Little-endian TIFF: IFD0 (EXIF pointer) at 8, EXIF IFD (date) at 26, date string at 44
+	const ifd0Offset, exifIFDOffset, dateOffset = 8, 26, 44
+	le := binary.LittleEndian
+
+	tiff := make([]byte, dateOffset)
+	copy(tiff, "II")
+	le.PutUint16(tiff[2:], 42)
+	le.PutUint32(tiff[4:], ifd0Offset)
+	writeEntry := func(at int, tag, typ uint16, count, value uint32) {
+		le.PutUint16(tiff[at:], 1)
+		le.PutUint16(tiff[at+2:], tag)
+		le.PutUint16(tiff[at+4:], typ)
+		le.PutUint32(tiff[at+6:], count)
+		le.PutUint32(tiff[at+10:], value)
+	}
+	writeEntry(ifd0Offset, 0x8769, 4, 1, exifIFDOffset)                        // EXIF IFD pointer
+	writeEntry(exifIFDOffset, 0x9003, 2, uint32(len(dateTaken)+1), dateOffset) // DateTimeOriginal
+	tiff = append(append(tiff, dateTaken...), 0)
+
+	payload := append([]byte("Exif\x00\x00"), tiff...)
+	segment := []byte{0xFF, 0xE1, 0, 0}
+	binary.BigEndian.PutUint16(segment[2:], uint16(len(payload)+2))
+	segment = append(segment, payload...)
+
+	// Insert the APP1 segment straight after the SOI marker
+	plain := createTestJPEG(t, width, height)
+	out := append([]byte{}, plain[:2]...)
+	out = append(out, segment...)
+	return append(out, plain[2:]...)
+}
+
+func TestAlbumHandler_UploadPhotos_CaptureDate(t *testing.T) {
+	handler, albumService, _ := setupAlbumHandler(t)
+
+	album := &models.Album{Title: "Scans", Visibility: "public"}
+	require.NoError(t, albumService.Create(album))
+
+	upload := func(files map[string][]byte, order []string, captureDates ...string) *httptest.ResponseRecorder {
+		var body bytes.Buffer
+		form := multipart.NewWriter(&body)
+		for _, name := range order {
+			part, err := form.CreateFormFile("photos", name)
+			require.NoError(t, err)
+			_, err = part.Write(files[name])
+			require.NoError(t, err)
+		}
+		for _, date := range captureDates {
+			require.NoError(t, form.WriteField("capture_date", date))
+		}
+		require.NoError(t, form.Close())
+
+		req := httptest.NewRequest("POST", "/api/admin/albums/"+album.ID+"/photos/upload", &body)
+		req.Header.Set("Content-Type", form.FormDataContentType())
+		rctx := chi.NewRouteContext()
+		rctx.URLParams.Add("id", album.ID)
+		req = req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, rctx))
+		w := httptest.NewRecorder()
+		handler.UploadPhotos(w, req)
+		return w
+	}
+	dateTaken := func(filename string) *time.Time {
+		stored, err := albumService.GetByID(album.ID)
+		require.NoError(t, err)
+		for _, photo := range stored.Photos {
+			if photo.FilenameOriginal == filename {
+				if photo.EXIF == nil {
+					return nil
+				}
+				return photo.EXIF.DateTaken
+			}
+		}
+		t.Fatalf("photo %s not found", filename)
+		return nil
+	}
+
+	files := map[string][]byte{
+		"dated.jpg":      createTestJPEGWithDate(t, 64, 48, "2021:05:01 10:30:00"),
+		"overridden.jpg": createTestJPEGWithDate(t, 64, 48, "2021:05:01 10:30:00"),
+		"scan.jpg":       createTestJPEG(t, 64, 48),
+	}
+
+	// One date per file: empty values fall back to EXIF
+	w := upload(files, []string{"dated.jpg", "overridden.jpg", "scan.jpg"}, "", "1987-07-14", "1987-07-15T18:20:00+02:00")
+	require.Equal(t, http.StatusOK, w.Code)
+
+	taken := dateTaken("dated.jpg")
+	require.NotNil(t, taken)
+	assert.Equal(t, "2021-05-01 10:30:00", taken.Format("2006-01-02 15:04:05"))
+
+	taken = dateTaken("overridden.jpg")
+	require.NotNil(t, taken)
+	assert.True(t, time.Date(1987, 7, 14, 0, 0, 0, 0, time.UTC).Equal(*taken))
+
+	taken = dateTaken("scan.jpg")
+	require.NotNil(t, taken, "photos without EXIF get the given date")
+	assert.True(t, time.Date(1987, 7, 15, 16, 20, 0, 0, time.UTC).Equal(*taken))
+
+	// A single date applies to every file
+	w = upload(map[string][]byte{"roll-a.jpg": files["dated.jpg"], "roll-b.jpg": files["scan.jpg"]}, []string{"roll-a.jpg", "roll-b.jpg"}, "1990-01-02")
+	require.Equal(t, http.StatusOK, w.Code)
+	for _, name := range []string{"roll-a.jpg", "roll-b.jpg"} {
+		taken = dateTaken(name)
+		require.NotNil(t, taken)
+		assert.Equal(t, "1990-01-02", taken.Format("2006-01-02"), name)
+	}
+
+	// Without the field, EXIF dates are kept and undated photos stay undated
+	w = upload(map[string][]byte{"plain.jpg": files["scan.jpg"]}, []string{"plain.jpg"})
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Nil(t, dateTaken("plain.jpg"))
+
+	// Malformed dates and mismatched counts reject the whole upload
+	w = upload(map[string][]byte{"bad.jpg": files["scan.jpg"]}, []string{"bad.jpg"}, "14/07/1987")
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Contains(t, w.Body.String(), `invalid capture date "14/07/1987"`)
+	w = upload(files, []string{"dated.jpg", "overridden.jpg", "scan.jpg"}, "1987-07-14", "1987-07-15")
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	stored, err := albumService.GetByID(album.ID)
+	require.NoError(t, err)
+	assert.Len(t, stored.Photos, 6)
+}
+
+// lowSpaceStorage is an in-memory storage reporting a fixed amount of free space.
+type lowSpaceStorage struct {
+	*services.MemoryStorage
+	available int64
+}
+
+func (s *lowSpaceStorage) AvailableBytes() (int64, error) {
+	return s.available, nil
+}
+
+func TestAlbumHandler_Upload_LowStorageSpace(t *testing.T) {
+	handler, albumService, _ := setupAlbumHandler(t)
+	storage := &lowSpaceStorage{MemoryStorage: services.NewMemoryStorage(), available: 10 << 20}
+	handler.imageService.SetStorage(storage)
+	handler.imageService.SetMinUploadSpace(100 << 20)
+
+	album := &models.Album{Title: "Full Disk", Visibility: "public"}
+	require.NoError(t, albumService.Create(album))
+
+	send := func(action func(http.ResponseWriter, *http.Request), target, contentType string, body []byte) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("POST", target, bytes.NewReader(body))
+		req.Header.Set("Content-Type", contentType)
+		rctx := chi.NewRouteContext()
+		rctx.URLParams.Add("id", album.ID)
+		req = req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, rctx))
+		w := httptest.NewRecorder()
+		action(w, req)
+		return w
+	}
+
+	var multipartBody bytes.Buffer
+	form := multipart.NewWriter(&multipartBody)
+	part, err := form.CreateFormFile("photos", "frame.jpg")
+	require.NoError(t, err)
+	_, err = part.Write(createTestJPEG(t, 64, 48))
+	require.NoError(t, err)
+	require.NoError(t, form.Close())
+
+	var zipBody bytes.Buffer
+	zw := zip.NewWriter(&zipBody)
+	entry, err := zw.Create("frame.jpg")
+	require.NoError(t, err)
+	_, err = entry.Write(createTestJPEG(t, 64, 48))
+	require.NoError(t, err)
+	require.NoError(t, zw.Close())
+
+	upload := func() *httptest.ResponseRecorder {
+		return send(handler.UploadPhotos, "/api/admin/albums/"+album.ID+"/photos/upload", form.FormDataContentType(), multipartBody.Bytes())
+	}
+	uploadZIP := func() *httptest.ResponseRecorder {
+		return send(handler.UploadZIP, "/api/admin/albums/"+album.ID+"/upload-zip", "application/zip", zipBody.Bytes())
+	}
+
+	// Below the threshold both upload paths are turned away untouched
+	w := upload()
+	assert.Equal(t, http.StatusInsufficientStorage, w.Code)
+	assert.Contains(t, w.Body.String(), "insufficient storage")
+	assert.Equal(t, http.StatusInsufficientStorage, uploadZIP().Code)
+	stored, err := albumService.GetByID(album.ID)
+	require.NoError(t, err)
+	assert.Empty(t, stored.Photos)
+
+	// Once space is freed uploads are accepted again
+	storage.available = 200 << 20
+	assert.Equal(t, http.StatusOK, upload().Code)
+	assert.Equal(t, http.StatusOK, uploadZIP().Code)
+	stored, err = albumService.GetByID(album.ID)
+	require.NoError(t, err)
+	assert.Len(t, stored.Photos, 2)
+}
+
+func TestAlbumHandler_UploadPhotos_FailureHook(t *testing.T) {
+	handler, albumService, _ := setupAlbumHandler(t)
+
+	failures := make(chan services.UploadFailure, 2)
+	handler.SetUploadFailureHook(services.NewUploadFailureHook(services.UploadFailureNotifierFunc(func(_ context.Context, failure services.UploadFailure) error {
+		failures <- failure
+		return nil
+	}), time.Second, handler.logger))
+
+	album := &models.Album{Title: "Monitored", Visibility: "public"}
+	require.NoError(t, albumService.Create(album))
+
+	var body bytes.Buffer
+	form := multipart.NewWriter(&body)
+	for _, file := range []struct {
+		name string
+		data []byte
+	}{
+		{"good.jpg", createTestJPEG(t, 64, 48)},
+		{"broken.jpg", []byte("not an image")},
+	} {
+		part, err := form.CreateFormFile("photos", file.name)
+		require.NoError(t, err)
+		_, err = part.Write(file.data)
+		require.NoError(t, err)
+	}
+	require.NoError(t, form.Close())
+
+	req := httptest.NewRequest("POST", "/api/admin/albums/"+album.ID+"/photos/upload", &body)
+	req.Header.Set("Content-Type", form.FormDataContentType())
+	rctx := chi.NewRouteContext()
+	rctx.URLParams.Add("id", album.ID)
+	req = req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, rctx))
+	w := httptest.NewRecorder()
+	handler.UploadPhotos(w, req)
+	require.Equal(t, http.StatusOK, w.Code)
+
+	// Only the failed file is reported, with the error the client sees
+	var resp UploadResponse
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&resp))
+	select {
+	case failure := <-failures:
+		assert.Equal(t, album.ID, failure.AlbumID)
+		assert.Equal(t, "broken.jpg", failure.Filename)
+		assert.Equal(t, resp.Results[1].Error, failure.Error)
+	case <-time.After(5 * time.Second):
+		t.Fatal("upload failure was not reported")
+	}
+	select {
+	case failure := <-failures:
+		t.Fatalf("unexpected failure report for %s", failure.Filename)
+	case <-time.After(50 * time.Millisecond):
+	}
+}
+
+func TestAlbumHandler_UploadZIP(t *testing.T) {
+	handler, albumService, _ := setupAlbumHandler(t)
+	handler.SetUploadConcurrency(2)
+
+	album := &models.Album{Title: "Zipped", Visibility: "public"}
+	require.NoError(t, albumService.Create(album))
+
+	// Photos out of name order, a folder, macOS metadata, a text file, a corrupt image,
+	// and entries trying to escape the archive
+	var archive bytes.Buffer
+	zw := zip.NewWriter(&archive)
+	for _, entry := range []struct {
+		name string
+		data []byte
+	}{
+		{"roll-2/", nil},
+		{"roll-2/frame-09.jpg", createTestJPEG(t, 320, 240)},
+		{"../../etc/cron.d/evil.jpg", createTestJPEG(t, 64, 48)},
+		{"frame-01.jpg", createTestJPEG(t, 240, 320)},
+		{"/abs/frame.jpg", createTestJPEG(t, 64, 48)},
+		{`roll-2\..\..\frame.jpg`, createTestJPEG(t, 64, 48)},
+		{"__MACOSX/roll-2/._frame-09.jpg", []byte("resource fork")},
+		{".DS_Store", []byte("finder")},
+		{"notes.txt", []byte("contact sheet notes")},
+		{"broken.jpg", []byte("not an image")},
+		{"roll-2/frame-10.jpeg", createTestJPEG(t, 200, 200)},
+	} {
+		part, err := zw.Create(entry.name)
+		require.NoError(t, err)
+		_, err = part.Write(entry.data)
+		require.NoError(t, err)
+	}
+	require.NoError(t, zw.Close())
+
+	uploadZIP := func(id string, body []byte) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("POST", "/api/admin/albums/"+id+"/upload-zip", bytes.NewReader(body))
+		req.Header.Set("Content-Type", "application/zip")
+		rctx := chi.NewRouteContext()
+		rctx.URLParams.Add("id", id)
+		req = req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, rctx))
+		w := httptest.NewRecorder()
+		handler.UploadZIP(w, req)
+		return w
+	}
+
+	w := uploadZIP(album.ID, archive.Bytes())
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+
+	var resp UploadResponse
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&resp))
+	assert.Equal(t, []string{
+		"../../etc/cron.d/evil.jpg: unsafe path",
+		"/abs/frame.jpg: unsafe path",
+		`roll-2\..\..\frame.jpg: unsafe path`,
+		"notes.txt: unsupported file type",
+	}, resp.Errors[:4])
+	require.Len(t, resp.Errors, 5)
+	assert.True(t, strings.HasPrefix(resp.Errors[4], "broken.jpg: "))
+	assert.Equal(t, UploadSummary{Total: 8, Uploaded: 3, Failed: 5}, resp.Summary)
+
+	// Results keep archive order, with rejected entries in their place
+	require.Len(t, resp.Results, 8)
+	statuses := []string{}
+	for _, result := range resp.Results {
+		statuses = append(statuses, result.Filename+" "+result.Status)
+	}
+	assert.Equal(t, []string{
+		"roll-2/frame-09.jpg uploaded",
+		"../../etc/cron.d/evil.jpg failed",
+		"frame-01.jpg uploaded",
+		"/abs/frame.jpg failed",
+		`roll-2\..\..\frame.jpg failed`,
+		"notes.txt failed",
+		"broken.jpg failed",
+		"roll-2/frame-10.jpeg uploaded",
+	}, statuses)
+	assert.Equal(t, "unsafe path", resp.Results[1].Error)
+	assert.Equal(t, "unsupported file type", resp.Results[5].Error)
+
+	// Photos are named after their file and added in archive order
+	album, err := albumService.GetByID(album.ID)
+	require.NoError(t, err)
+	albumNames := []string{}
+	for _, photo := range album.Photos {
+		albumNames = append(albumNames, photo.FilenameOriginal)
+	}
+	assert.Equal(t, []string{"frame-09.jpg", "frame-01.jpg", "frame-10.jpeg"}, albumNames)
+	assert.Len(t, resp.Uploaded, 3)
+
+	// Archives over the size limit are refused without adding anything
+	handler.SetMaxZIPUploadSize(int64(archive.Len() - 1))
+	w = uploadZIP(album.ID, archive.Bytes())
+	assert.Equal(t, http.StatusRequestEntityTooLarge, w.Code)
+	album, err = albumService.GetByID(album.ID)
+	require.NoError(t, err)
+	assert.Len(t, album.Photos, 3)
+	handler.SetMaxZIPUploadSize(DefaultMaxZIPUploadSize)
+
+	w = uploadZIP(album.ID, []byte("not a zip"))
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	w = uploadZIP("missing", archive.Bytes())
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
+
+func TestAlbumHandler_PrintPhoto(t *testing.T) {
+	handler, albumService, _ := setupAlbumHandler(t)
+
+	album := &models.Album{Title: "Prints", Visibility: "public", AllowDownloads: true}
+	require.NoError(t, albumService.Create(album))
+
+	photo, err := handler.imageService.ProcessBytes("harbour.jpg", createTestJPEG(t, 640, 480))
+	require.NoError(t, err)
+	require.NoError(t, albumService.AddPhoto(album.ID, photo))
+
+	teaser, err := handler.imageService.ProcessBytes("teaser.jpg", createTestJPEG(t, 640, 480))
+	require.NoError(t, err)
+	teaser.Downloadable = false
+	require.NoError(t, albumService.AddPhoto(album.ID, teaser))
+
+	target := "/api/albums/" + album.Slug + "/photos/"
+
+	// A 640px original is far short of 300 DPI at 5x7, so the print is upscaled with a warning
+	w := httptest.NewRecorder()
+	handler.PrintPhoto(w, newPhotoRequest(target+photo.ID+"/print?size=5x7", album.Slug, photo.ID))
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "image/jpeg", w.Header().Get("Content-Type"))
+	assert.Equal(t, `attachment; filename="harbour-5x7.jpg"`, w.Header().Get("Content-Disposition"))
+	assert.Equal(t, "300", w.Header().Get("X-Print-DPI"))
+	assert.Equal(t, "91", w.Header().Get("X-Print-Effective-DPI"))
+	assert.Contains(t, w.Header().Get("X-Print-Warning"), "upscaled")
+	assert.Equal(t, strconv.Itoa(w.Body.Len()), w.Header().Get("Content-Length"))
+
+	cfg, err := jpeg.DecodeConfig(bytes.NewReader(w.Body.Bytes()))
+	require.NoError(t, err)
+	assert.Equal(t, 2100, cfg.Width)
+	assert.Equal(t, 1500, cfg.Height)
+
+	// Missing and unknown sizes are rejected
+	w = httptest.NewRecorder()
+	handler.PrintPhoto(w, newPhotoRequest(target+photo.ID+"/print", album.Slug, photo.ID))
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	w = httptest.NewRecorder()
+	handler.PrintPhoto(w, newPhotoRequest(target+photo.ID+"/print?size=3x3", album.Slug, photo.ID))
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	w = httptest.NewRecorder()
+	handler.PrintPhoto(w, newPhotoRequest(target+"missing/print?size=5x7", album.Slug, "missing"))
+	assert.Equal(t, http.StatusNotFound, w.Code)
+
+	// Prints follow the same download rules as the original
+	w = httptest.NewRecorder()
+	handler.PrintPhoto(w, newPhotoRequest(target+teaser.ID+"/print?size=5x7", album.Slug, teaser.ID))
+	assert.Equal(t, http.StatusForbidden, w.Code)
+
+	album.AllowDownloads = false
+	require.NoError(t, albumService.Update(album.ID, album))
+	w = httptest.NewRecorder()
+	handler.PrintPhoto(w, newPhotoRequest(target+photo.ID+"/print?size=5x7", album.Slug, photo.ID))
+	assert.Equal(t, http.StatusForbidden, w.Code)
+}
+
+func TestAlbumHandler_GetPhotoNeighbors(t *testing.T) {
+	handler, albumService, _ := setupAlbumHandler(t)
+
+	album := &models.Album{Title: "Lightbox", Visibility: "public"}
+	require.NoError(t, albumService.Create(album))
+	for _, name := range []string{"one.jpg", "two.jpg", "three.jpg"} {
+		require.NoError(t, albumService.AddPhoto(album.ID, &models.Photo{
+			FilenameOriginal: name,
+			URLDisplay:       "/uploads/display/" + name,
+			URLThumbnail:     "/uploads/thumbnails/" + name,
+		}))
+	}
+	album, err := albumService.GetByID(album.ID)
+	require.NoError(t, err)
+	ids := []string{album.Photos[0].ID, album.Photos[1].ID, album.Photos[2].ID}
+
+	type neighborsResponse struct {
+		Position int            `json:"position"`
+		Total    int            `json:"total"`
+		Previous *PhotoNeighbor `json:"previous"`
+		Next     *PhotoNeighbor `json:"next"`
+	}
+	getNeighbors := func(photoID string) (int, neighborsResponse) {
+		w := httptest.NewRecorder()
+		handler.GetPhotoNeighbors(w, newPhotoRequest("/api/albums/"+album.Slug+"/photos/"+photoID+"/neighbors", album.Slug, photoID))
+		var resp neighborsResponse
+		if w.Code == http.StatusOK {
+			require.NoError(t, json.NewDecoder(w.Body).Decode(&resp))
+		}
+		return w.Code, resp
+	}
+
+	// First photo has no previous
+	code, resp := getNeighbors(ids[0])
+	require.Equal(t, http.StatusOK, code)
+	assert.Nil(t, resp.Previous)
+	require.NotNil(t, resp.Next)
+	assert.Equal(t, ids[1], resp.Next.ID)
+	assert.Equal(t, "/uploads/display/two.jpg", resp.Next.URLDisplay)
+	assert.Equal(t, 1, resp.Position)
+	assert.Equal(t, 3, resp.Total)
+
+	// Middle photo has both
+	code, resp = getNeighbors(ids[1])
+	require.Equal(t, http.StatusOK, code)
+	require.NotNil(t, resp.Previous)
+	require.NotNil(t, resp.Next)
+	assert.Equal(t, ids[0], resp.Previous.ID)
+	assert.Equal(t, ids[2], resp.Next.ID)
+
+	// Last photo has no next
+	code, resp = getNeighbors(ids[2])
+	require.Equal(t, http.StatusOK, code)
+	assert.Equal(t, ids[1], resp.Previous.ID)
+	assert.Nil(t, resp.Next)
+
+	// Reordering changes the neighbors
+	require.NoError(t, albumService.ReorderPhotos(album.ID, []string{ids[2], ids[0], ids[1]}))
+	_, resp = getNeighbors(ids[0])
+	assert.Equal(t, ids[2], resp.Previous.ID)
+	assert.Equal(t, ids[1], resp.Next.ID)
+
+	code, _ = getNeighbors("missing")
+	assert.Equal(t, http.StatusNotFound, code)
+}
+
+func TestAlbumHandler_GetPhotoNeighbors_PasswordProtected(t *testing.T) {
+	handler, albumService, _ := setupAlbumHandler(t)
+
+	albumAuthService, err := services.NewAlbumAuthService("test-secret", time.Hour)
+	require.NoError(t, err)
+	handler.SetAlbumAuthService(albumAuthService)
+
+	album := createProtectedAlbum(t, albumService, "letmein")
+	require.NoError(t, albumService.AddPhoto(album.ID, &models.Photo{FilenameOriginal: "a.jpg"}))
+	album, err = albumService.GetByID(album.ID)
+	require.NoError(t, err)
+	photoID := album.Photos[0].ID
+	target := "/api/albums/" + album.Slug + "/photos/" + photoID + "/neighbors"
+
+	w := httptest.NewRecorder()
+	handler.GetPhotoNeighbors(w, newPhotoRequest(target, album.Slug, photoID))
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+
+	w = httptest.NewRecorder()
+	handler.GetPhotoNeighbors(w, newPhotoRequest(target+"?token="+albumAuthService.IssueToken(album.ID), album.Slug, photoID))
+	assert.Equal(t, http.StatusOK, w.Code)
+}
+
+func TestAlbumHandler_GetPreload(t *testing.T) {
+	handler, albumService, _ := setupAlbumHandler(t)
+
+	album := &models.Album{Title: "Contact Sheet", Visibility: "public"}
+	require.NoError(t, albumService.Create(album))
+	for i := range 3 {
+		name := fmt.Sprintf("frame-%d.jpg", i)
+		require.NoError(t, albumService.AddPhoto(album.ID, &models.Photo{
+			FilenameOriginal: name,
+			URLThumbnail:     "/uploads/thumbnails/" + name,
+			Blurhash:         "LEHV6nWB2yk8pyo0adR*.7kCMdnj",
+		}))
+	}
+	album, err := albumService.GetByID(album.ID)
+	require.NoError(t, err)
+
+	type preloadResponse struct {
+		Photos []PreloadPhoto `json:"photos"`
+		Total  int            `json:"total"`
+	}
+	getPreload := func(query string) (int, preloadResponse) {
+		w := httptest.NewRecorder()
+		handler.GetPreload(w, newSlugRequest(http.MethodGet, "/api/albums/"+album.Slug+"/preload"+query, album.Slug))
+		var resp preloadResponse
+		if w.Code == http.StatusOK {
+			require.NoError(t, json.NewDecoder(w.Body).Decode(&resp))
+		}
+		return w.Code, resp
+	}
+
+	code, resp := getPreload("?count=2")
+	require.Equal(t, http.StatusOK, code)
+	require.Len(t, resp.Photos, 2)
+	assert.Equal(t, album.Photos[0].ID, resp.Photos[0].ID)
+	assert.Equal(t, "/uploads/thumbnails/frame-1.jpg", resp.Photos[1].URLThumbnail)
+	assert.Equal(t, "LEHV6nWB2yk8pyo0adR*.7kCMdnj", resp.Photos[1].Blurhash)
+	assert.Equal(t, 3, resp.Total)
+
+	// Counts are clamped to at least one photo, and to what the album has
+	for query, want := range map[string]int{"": 3, "?count=0": 1, "?count=-5": 1, "?count=5000": 3} {
+		code, resp := getPreload(query)
+		require.Equal(t, http.StatusOK, code, query)
+		assert.Len(t, resp.Photos, want, query)
+	}
+
+	code, _ = getPreload("?count=some")
+	assert.Equal(t, http.StatusBadRequest, code)
+}
+
+func TestAlbumHandler_GetPreload_PasswordProtected(t *testing.T) {
+	handler, albumService, _ := setupAlbumHandler(t)
+
+	albumAuthService, err := services.NewAlbumAuthService("test-secret", time.Hour)
+	require.NoError(t, err)
+	handler.SetAlbumAuthService(albumAuthService)
+
+	album := createProtectedAlbum(t, albumService, "letmein")
+	require.NoError(t, albumService.AddPhoto(album.ID, &models.Photo{FilenameOriginal: "a.jpg"}))
+	target := "/api/albums/" + album.Slug + "/preload"
+
+	w := httptest.NewRecorder()
+	handler.GetPreload(w, newSlugRequest(http.MethodGet, target, album.Slug))
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+
+	w = httptest.NewRecorder()
+	handler.GetPreload(w, newSlugRequest(http.MethodGet, target+"?token="+albumAuthService.IssueToken(album.ID), album.Slug))
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), `"total":1`)
+}
+
+func TestAlbumHandler_DownloadManifest(t *testing.T) {
+	handler, albumService, _ := setupAlbumHandler(t)
+
+	album := &models.Album{Title: "Contact Sheet", Visibility: "public", AllowDownloads: true}
+	require.NoError(t, albumService.Create(album))
+	for _, name := range []string{"one.jpg", "two.jpg"} {
+		photo, err := handler.imageService.ProcessBytes(name, createTestJPEG(t, 64, 48))
+		require.NoError(t, err)
+		require.NoError(t, albumService.AddPhoto(album.ID, photo))
+	}
+
+	// Without a configured part size the manifest has a single part
+	w := httptest.NewRecorder()
+	handler.DownloadManifest(w, newSlugRequest("GET", "/api/albums/"+album.Slug+"/download/manifest?quality=original", album.Slug))
+	require.Equal(t, http.StatusOK, w.Code)
+
+	var manifest struct {
+		Parts []ZIPPartLink `json:"parts"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &manifest))
+	require.Len(t, manifest.Parts, 1)
+	assert.Len(t, manifest.Parts[0].PhotoIDs, 2)
+	assert.Equal(t, "/api/albums/"+album.Slug+"/download?part=1&quality=original", manifest.Parts[0].URL)
+
+	// The listed part can be downloaded
+	w = httptest.NewRecorder()
+	handler.DownloadAlbum(w, newSlugRequest("GET", manifest.Parts[0].URL, album.Slug))
+	require.Equal(t, http.StatusOK, w.Code)
+	zipReader, err := zip.NewReader(bytes.NewReader(w.Body.Bytes()), int64(w.Body.Len()))
+	require.NoError(t, err)
+	assert.Len(t, zipReader.File, 2)
+
+	// Unknown and malformed parts are rejected
+	w = httptest.NewRecorder()
+	handler.DownloadAlbum(w, newSlugRequest("GET", "/api/albums/"+album.Slug+"/download?quality=original&part=2", album.Slug))
+	assert.Equal(t, http.StatusNotFound, w.Code)
+
+	w = httptest.NewRecorder()
+	handler.DownloadAlbum(w, newSlugRequest("GET", "/api/albums/"+album.Slug+"/download?quality=original&part=first", album.Slug))
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+func TestAlbumHandler_ExportHTML(t *testing.T) {
+	handler, albumService, _ := setupAlbumHandler(t)
+
+	albumAuthService, err := services.NewAlbumAuthService("test-secret", time.Hour)
+	require.NoError(t, err)
+	handler.SetAlbumAuthService(albumAuthService)
+
+	album := &models.Album{Title: "Contact Sheet", Visibility: "public", AllowDownloads: true}
+	require.NoError(t, albumService.Create(album))
+	for _, name := range []string{"one.jpg", "two.jpg"} {
+		photo, err := handler.imageService.ProcessBytes(name, createTestJPEG(t, 64, 48))
+		require.NoError(t, err)
+		require.NoError(t, albumService.AddPhoto(album.ID, photo))
+	}
+	album, err = albumService.GetByID(album.ID)
+	require.NoError(t, err)
+
+	w := httptest.NewRecorder()
+	handler.ExportHTML(w, newSlugRequest("GET", "/api/albums/"+album.Slug+"/export-html", album.Slug))
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "application/zip", w.Header().Get("Content-Type"))
+
+	zipReader, err := zip.NewReader(bytes.NewReader(w.Body.Bytes()), int64(w.Body.Len()))
+	require.NoError(t, err)
+	names := map[string]bool{}
+	var index string
+	for _, file := range zipReader.File {
+		names[file.Name] = true
+		if file.Name == services.HTMLExportIndex {
+			reader, err := file.Open()
+			require.NoError(t, err)
+			content, err := io.ReadAll(reader)
+			require.NoError(t, err)
+			_ = reader.Close()
+			index = string(content)
+		}
+	}
+
+	// Every photo's display image is bundled and shown on the page
+	require.Len(t, album.Photos, 2)
+	for _, photo := range album.Photos {
+		src := "images/" + path.Base(photo.URLDisplay)
+		assert.True(t, names[src], src)
+		assert.Contains(t, index, `src="`+src+`"`)
+	}
+
+	// Albums with downloads disabled cannot be exported
+	album.AllowDownloads = false
+	require.NoError(t, albumService.Update(album.ID, album))
+	w = httptest.NewRecorder()
+	handler.ExportHTML(w, newSlugRequest("GET", "/api/albums/"+album.Slug+"/export-html", album.Slug))
+	assert.Equal(t, http.StatusForbidden, w.Code)
+
+	// Protected albums need an access cookie
+	protected := createProtectedAlbum(t, albumService, "letmein")
+	w = httptest.NewRecorder()
+	handler.ExportHTML(w, newSlugRequest("GET", "/api/albums/"+protected.Slug+"/export-html", protected.Slug))
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+
+	// Unknown albums are not found
+	w = httptest.NewRecorder()
+	handler.ExportHTML(w, newSlugRequest("GET", "/api/albums/missing/export-html", "missing"))
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
+
+func TestAlbumHandler_GetPhotosByTag(t *testing.T) {
+	handler, albumService, _ := setupAlbumHandler(t)
+
+	album := &models.Album{Title: "Tagged", Visibility: "public"}
+	require.NoError(t, albumService.Create(album))
+	for name, tags := range map[string][]string{"1.jpg": {"Beach", "dog"}, "2.jpg": {"beach"}, "3.jpg": nil} {
+		require.NoError(t, albumService.AddPhoto(album.ID, &models.Photo{FilenameOriginal: name, Tags: tags}))
+	}
+
+	newRequest := func(target string) *http.Request {
+		req := httptest.NewRequest("GET", target, nil)
+		rctx := chi.NewRouteContext()
+		rctx.URLParams.Add("id", album.ID)
+		return req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, rctx))
+	}
+
+	w := httptest.NewRecorder()
+	handler.GetTags(w, newRequest("/api/albums/"+album.ID+"/tags"))
+	require.Equal(t, http.StatusOK, w.Code)
+	var tags AlbumTags
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &tags))
+	assert.Equal(t, []models.TagCount{{Tag: "beach", Photos: 2}, {Tag: "dog", Photos: 1}}, tags.Tags)
+
+	listed := func(target string) AlbumPhotos {
+		w := httptest.NewRecorder()
+		handler.GetPhotos(w, newRequest(target))
+		require.Equal(t, http.StatusOK, w.Code)
+		var photos AlbumPhotos
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &photos))
+		return photos
+	}
+
+	// The filter is normalized like the tags themselves
+	photos := listed("/api/albums/" + album.ID + "/photos?tag=%20BEACH")
+	assert.Equal(t, "beach", photos.Tag)
+	assert.Len(t, photos.Photos, 2)
+	assert.Equal(t, 3, photos.Total)
+	for _, photo := range photos.Photos {
+		assert.Contains(t, photo.Tags, "beach")
+	}
+
+	photos = listed("/api/albums/" + album.ID + "/photos?tag=dog")
+	require.Len(t, photos.Photos, 1)
+	assert.Equal(t, []string{"beach", "dog"}, photos.Photos[0].Tags)
+
+	assert.Empty(t, listed("/api/albums/"+album.ID+"/photos?tag=cat").Photos)
+	assert.Len(t, listed("/api/albums/"+album.ID+"/photos").Photos, 3)
+
+	w = httptest.NewRecorder()
+	req := httptest.NewRequest("GET", "/api/albums/missing/tags", nil)
+	rctx := chi.NewRouteContext()
+	rctx.URLParams.Add("id", "missing")
+	handler.GetTags(w, req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, rctx)))
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
+
+func TestAlbumHandler_GetPhotosGeoJSON(t *testing.T) {
+	handler, albumService, _ := setupAlbumHandler(t)
+
+	album := &models.Album{Title: "Road Trip", Visibility: "public", ShareLocations: true}
+	require.NoError(t, albumService.Create(album))
+	latitude, longitude := 36.2704, -121.8081
+	require.NoError(t, albumService.AddPhoto(album.ID, &models.Photo{FilenameOriginal: "big-sur.jpg", Title: "Big Sur", EXIF: &models.EXIF{Latitude: &latitude, Longitude: &longitude}}))
+	require.NoError(t, albumService.AddPhoto(album.ID, &models.Photo{FilenameOriginal: "scan.jpg", EXIF: &models.EXIF{Camera: "Pentax 67"}}))
+
+	req := httptest.NewRequest("GET", "/api/albums/"+album.ID+"/photos.geojson", nil)
+	rctx := chi.NewRouteContext()
+	rctx.URLParams.Add("id", album.ID)
+	w := httptest.NewRecorder()
+	handler.GetPhotosGeoJSON(w, req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, rctx)))
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "application/geo+json", w.Header().Get("Content-Type"))
+
+	var collection services.GeoJSONFeatureCollection
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &collection))
+	assert.Equal(t, "FeatureCollection", collection.Type)
+	require.Len(t, collection.Features, 1)
+	assert.Equal(t, [2]float64{longitude, latitude}, collection.Features[0].Geometry.Coordinates)
+	assert.Equal(t, "Big Sur", collection.Features[0].Properties.Title)
+
+	publicAlbum := func() models.Album {
+		w := httptest.NewRecorder()
+		handler.GetPublicAlbum(w, newSlugRequest("GET", "/api/public/albums/"+album.Slug, album.Slug))
+		require.Equal(t, http.StatusOK, w.Code)
+		var public models.Album
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &public))
+		return public
+	}
+
+	// Visitors see the positions an album shares
+	public := publicAlbum()
+	require.NotNil(t, public.Photos[0].EXIF)
+	assert.NotNil(t, public.Photos[0].EXIF.Latitude)
+
+	// but not in albums that scrub GPS data
+	stored, err := albumService.GetByID(album.ID)
+	require.NoError(t, err)
+	stored.ScrubGPSOnDownload = true
+	require.NoError(t, albumService.Update(stored.ID, stored))
+	public = publicAlbum()
+	assert.Nil(t, public.Photos[0].EXIF)
+
+	// Admins still do
+	stored, err = albumService.GetByID(album.ID)
+	require.NoError(t, err)
+	assert.NotNil(t, stored.Photos[0].EXIF.Latitude)
+
+	// Albums that stop sharing locations drop them from the store
+	stored.ShareLocations = false
+	require.NoError(t, albumService.Update(stored.ID, stored))
+	stored, err = albumService.GetByID(album.ID)
+	require.NoError(t, err)
+	assert.Nil(t, stored.Photos[0].EXIF)
+	w = httptest.NewRecorder()
+	handler.GetPhotosGeoJSON(w, req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, rctx)))
+	require.Equal(t, http.StatusOK, w.Code)
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &collection))
+	assert.Empty(t, collection.Features)
+}
+
+func TestAlbumHandler_GetPublicAlbum_HidesOriginals(t *testing.T) {
+	handler, albumService, _ := setupAlbumHandler(t)
+
+	album := &models.Album{Title: "Proofs", Visibility: "public", AllowDownloads: true}
+	require.NoError(t, albumService.Create(album))
+	require.NoError(t, albumService.AddPhoto(album.ID, &models.Photo{FilenameOriginal: "open.jpg", URLOriginal: "/uploads/originals/open.jpg", Downloadable: true}))
+	require.NoError(t, albumService.AddPhoto(album.ID, &models.Photo{FilenameOriginal: "teaser.jpg", URLOriginal: "/uploads/originals/teaser.jpg"}))
+
+	originals := func(change func(*models.Album)) []string {
+		stored, err := albumService.GetByID(album.ID)
+		require.NoError(t, err)
+		change(stored)
+		require.NoError(t, albumService.Update(stored.ID, stored))
+
+		w := httptest.NewRecorder()
+		handler.GetPublicAlbum(w, newSlugRequest("GET", "/api/public/albums/"+album.Slug, album.Slug))
+		require.Equal(t, http.StatusOK, w.Code)
+		var public models.Album
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &public))
+		return []string{public.Photos[0].URLOriginal, public.Photos[1].URLOriginal}
+	}
+
+	// Only downloadable photos link their originals
+	assert.Equal(t, []string{"/uploads/originals/open.jpg", ""}, originals(func(*models.Album) {}))
+
+	// Originals that could not be downloaded as stored are not linked at all
+	assert.Equal(t, []string{"", ""}, originals(func(a *models.Album) { a.DownloadQualities = []string{"display"} }))
+	assert.Equal(t, []string{"", ""}, originals(func(a *models.Album) { a.DownloadQualities, a.ScrubGPSOnDownload = nil, true }))
+	assert.Equal(t, []string{"", ""}, originals(func(a *models.Album) { a.ScrubGPSOnDownload, a.AllowDownloads = false, false }))
+
+	// Admins still see them
+	stored, err := albumService.GetByID(album.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "/uploads/originals/open.jpg", stored.Photos[0].URLOriginal)
+}
+
+func TestAlbumHandler_DownloadMultiple(t *testing.T) {
+	handler, albumService, _ := setupAlbumHandler(t)
+
+	albumAuthService, err := services.NewAlbumAuthService("test-secret", time.Hour)
+	require.NoError(t, err)
+	handler.SetAlbumAuthService(albumAuthService)
+
+	addPhoto := func(album *models.Album, filename string) {
+		photo, err := handler.imageService.ProcessBytes(filename, createTestJPEG(t, 64, 48))
+		require.NoError(t, err)
+		require.NoError(t, albumService.AddPhoto(album.ID, photo))
+	}
+
+	ceremony := &models.Album{Title: "Ceremony", Visibility: "public", AllowDownloads: true}
+	require.NoError(t, albumService.Create(ceremony))
+	addPhoto(ceremony, "vows.jpg")
+	addPhoto(ceremony, "rings.jpg")
+
+	party := &models.Album{Title: "Party", Visibility: "unlisted", AllowDownloads: true}
+	require.NoError(t, albumService.Create(party))
+	addPhoto(party, "dance.jpg")
+
+	proofs := &models.Album{Title: "Proofs", Visibility: "public", AllowDownloads: false}
+	require.NoError(t, albumService.Create(proofs))
+	addPhoto(proofs, "proof.jpg")
+
+	private := createProtectedAlbum(t, albumService, "letmein")
+	addPhoto(private, "portrait.jpg")
+
+	download := func(body string, cookies ...*http.Cookie) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("POST", "/api/download-multi", strings.NewReader(body))
+		for _, cookie := range cookies {
+			req.AddCookie(cookie)
+		}
+		w := httptest.NewRecorder()
+		handler.DownloadMultiple(w, req)
+		return w
+	}
+	readZIP := func(w *httptest.ResponseRecorder) ([]string, services.MultiAlbumManifest) {
+		zipReader, err := zip.NewReader(bytes.NewReader(w.Body.Bytes()), int64(w.Body.Len()))
+		require.NoError(t, err)
+		var names []string
+		var manifest services.MultiAlbumManifest
+		for _, file := range zipReader.File {
+			names = append(names, file.Name)
+			if file.Name == services.MultiAlbumManifestName {
+				rc, err := file.Open()
+				require.NoError(t, err)
+				require.NoError(t, json.NewDecoder(rc).Decode(&manifest))
+				require.NoError(t, rc.Close())
+			}
+		}
+		return names, manifest
+	}
+
+	// Allowed albums get a folder each; the rest are noted in the manifest
+	body := `{"slugs":["` + ceremony.Slug + `","` + party.Slug + `","` + proofs.Slug + `","` + private.Slug + `","nope","` + ceremony.Slug + `"],"quality":"display"}`
+	w := download(body)
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "application/zip", w.Header().Get("Content-Type"))
+	assert.Equal(t, `attachment; filename="albums-display.zip"`, w.Header().Get("Content-Disposition"))
+
+	names, manifest := readZIP(w)
+	assert.ElementsMatch(t, []string{
+		ceremony.Slug + "/vows.jpg",
+		ceremony.Slug + "/rings.jpg",
+		party.Slug + "/dance.jpg",
+		services.MultiAlbumManifestName,
+	}, names)
+	assert.Equal(t, "display", manifest.Quality)
+	assert.Equal(t, []services.MultiAlbumIncluded{
+		{Slug: ceremony.Slug, Title: "Ceremony", Photos: 2},
+		{Slug: party.Slug, Title: "Party", Photos: 1},
+	}, manifest.Albums)
+	assert.Equal(t, []services.MultiAlbumSkipped{
+		{Slug: proofs.Slug, Reason: "downloads are not enabled"},
+		{Slug: private.Slug, Reason: "access token required"},
+		{Slug: "nope", Reason: "album not found"},
+	}, manifest.Skipped)
+
+	// An access cookie unlocks the protected album
+	verifyReq := httptest.NewRequest("POST", "/api/albums/verify-password",
+		strings.NewReader(`{"album_id":"`+private.ID+`","password":"letmein"}`))
+	verifyW := httptest.NewRecorder()
+	handler.VerifyPassword(verifyW, verifyReq)
+	require.Equal(t, http.StatusOK, verifyW.Code)
+
+	w = download(`{"slugs":["`+ceremony.Slug+`","`+private.Slug+`"],"quality":"display"}`, verifyW.Result().Cookies()[0])
+	require.Equal(t, http.StatusOK, w.Code)
+	names, manifest = readZIP(w)
+	assert.Contains(t, names, private.Slug+"/portrait.jpg")
+	assert.Empty(t, manifest.Skipped)
+
+	// Nothing downloadable, or a bad request
+	assert.Equal(t, http.StatusForbidden, download(`{"slugs":["`+proofs.Slug+`","`+private.Slug+`"],"quality":"display"}`).Code)
+	assert.Equal(t, http.StatusBadRequest, download(`{"slugs":[],"quality":"display"}`).Code)
+	assert.Equal(t, http.StatusBadRequest, download(`{"slugs":["`+ceremony.Slug+`"],"quality":"huge"}`).Code)
+	assert.Equal(t, http.StatusBadRequest, download(`not json`).Code)
+}
+
+func TestAlbumHandler_UnknownSlugSuggestions(t *testing.T) {
+	handler, albumService, _ := setupAlbumHandler(t)
+
+	require.NoError(t, albumService.Create(&models.Album{Title: "Golden Hour", Visibility: "public", AllowDownloads: true}))
+	require.NoError(t, albumService.Create(&models.Album{Title: "Golden Gate", Visibility: "unlisted"}))
+
+	notFound := func(w *httptest.ResponseRecorder) AlbumNotFoundResponse {
+		require.Equal(t, http.StatusNotFound, w.Code)
+		assert.Equal(t, "application/json", w.Header().Get("Content-Type"))
+		var body AlbumNotFoundResponse
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &body))
+		assert.Equal(t, "Album not found", body.Error)
+		return body
+	}
+
+	// A near-miss slug suggests the public album, but never the unlisted one
+	w := httptest.NewRecorder()
+	handler.DownloadAlbum(w, newSlugRequest("GET", "/api/albums/golden-hours/download?quality=display", "golden-hours"))
+	body := notFound(w)
+	assert.Equal(t, "golden-hours", body.Slug)
+	assert.Equal(t, []services.AlbumSuggestion{{Slug: "golden-hour", Title: "Golden Hour", Path: "/albums/golden-hour"}}, body.Suggestions)
+
+	// Photo routes report unknown albums the same way; unrelated slugs get no suggestions
+	w = httptest.NewRecorder()
+	handler.GetPhotoNeighbors(w, newPhotoRequest("/api/albums/night-market/photos/x/neighbors", "night-market", "x"))
+	body = notFound(w)
+	assert.NotNil(t, body.Suggestions)
+	assert.Empty(t, body.Suggestions)
+}
+
+func TestAlbumHandler_NamespacedAlbum(t *testing.T) {
+	handler, albumService, _ := setupAlbumHandler(t)
+
+	album := &models.Album{Title: "Contact", Namespace: "alice", Visibility: "public", AllowDownloads: true}
+	require.NoError(t, albumService.Create(album))
+	photo, err := handler.imageService.ProcessBytes("one.jpg", createTestJPEG(t, 64, 48))
+	require.NoError(t, err)
+	require.NoError(t, albumService.AddPhoto(album.ID, photo))
+
+	namespacedRequest := func(target, namespace string) *http.Request {
+		req := newSlugRequest("GET", target, album.Slug)
+		if namespace != "" {
+			chi.RouteContext(req.Context()).URLParams.Add("namespace", namespace)
+		}
+		return req
+	}
+
+	// Links point at the namespaced routes
+	w := httptest.NewRecorder()
+	handler.DownloadManifest(w, namespacedRequest("/api/a/alice/albums/"+album.Slug+"/download/manifest?quality=original", "alice"))
+	require.Equal(t, http.StatusOK, w.Code)
+	var manifest struct {
+		Parts []ZIPPartLink `json:"parts"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &manifest))
+	require.Len(t, manifest.Parts, 1)
+	assert.Equal(t, "/api/a/alice/albums/contact/download?part=1&quality=original", manifest.Parts[0].URL)
+
+	// The album is not reachable from the default namespace or another one
+	for _, namespace := range []string{"", "bob"} {
+		w = httptest.NewRecorder()
+		handler.DownloadManifest(w, namespacedRequest("/download/manifest?quality=original", namespace))
+		assert.Equal(t, http.StatusNotFound, w.Code, "namespace %q", namespace)
+	}
+}
+
+// recordingMailer captures sent emails instead of delivering them.
+type recordingMailer struct {
+	mu   sync.Mutex
+	sent []sentEmail
+}
+
+type sentEmail struct {
+	to, subject, body string
+}
+
+func (m *recordingMailer) Send(to, subject, body string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.sent = append(m.sent, sentEmail{to: to, subject: subject, body: body})
+	return nil
+}
+
+// waitForSent waits for n emails to have been sent in the background and returns them.
+func (m *recordingMailer) waitForSent(t *testing.T, n int) []sentEmail {
+	t.Helper()
+	require.Eventually(t, func() bool {
+		m.mu.Lock()
+		defer m.mu.Unlock()
+		return len(m.sent) >= n
+	}, time.Second, 5*time.Millisecond)
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return slices.Clone(m.sent)
+}
+
+func TestAlbumHandler_AccessLinks(t *testing.T) {
+	handler, albumService, _ := setupAlbumHandler(t)
+
+	albumAuthService, err := services.NewAlbumAuthService("test-secret", time.Hour)
+	require.NoError(t, err)
+	handler.SetAlbumAuthService(albumAuthService)
+	mailer := &recordingMailer{}
+	handler.SetMailer(mailer, "https://photos.example.com/")
+
+	album := &models.Album{
+		Title:          "Smith Wedding",
+		Visibility:     "unlisted",
+		AllowDownloads: true,
+		AllowedEmails:  []string{"bride@example.com"},
+	}
+	require.NoError(t, albumService.Create(album))
+
+	requestLink := func(email string) int {
+		req := newSlugRequest("POST", "/api/albums/"+album.Slug+"/request-access", album.Slug)
+		req.Body = io.NopCloser(strings.NewReader(`{"email":"` + email + `"}`))
+		w := httptest.NewRecorder()
+		handler.RequestAccessLink(w, req)
+		return w.Code
+	}
+
+	// Addresses not on the list get the same response but no email
+	assert.Equal(t, http.StatusAccepted, requestLink("guest@example.com"))
+
+	// Listed addresses are emailed a link, in the background
+	assert.Equal(t, http.StatusAccepted, requestLink("Bride@Example.com"))
+	sent := mailer.waitForSent(t, 1)
+	require.Len(t, sent, 1)
+	assert.Equal(t, "Bride@Example.com", sent[0].to)
+	assert.Contains(t, sent[0].subject, "Smith Wedding")
+
+	prefix := "https://photos.example.com/api/albums/" + album.Slug + "/access?token="
+	start := strings.Index(sent[0].body, prefix)
+	require.GreaterOrEqual(t, start, 0, "email contains the access link")
+	link := strings.Fields(sent[0].body[start:])[0]
+	token, err := url.QueryUnescape(strings.TrimPrefix(link, prefix))
+	require.NoError(t, err)
+
+	// Without the link the album is locked
+	w := httptest.NewRecorder()
+	handler.DownloadAlbum(w, newSlugRequest("GET", "/api/albums/"+album.Slug+"/download?quality=display", album.Slug))
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+
+	// Opening the link sets the access cookie and redirects to the album
+	w = httptest.NewRecorder()
+	handler.OpenAccessLink(w, newSlugRequest("GET", "/api/albums/"+album.Slug+"/access?token="+url.QueryEscape(token), album.Slug))
+	require.Equal(t, http.StatusSeeOther, w.Code)
+	assert.Equal(t, "/albums/"+album.Slug, w.Header().Get("Location"))
+	cookies := w.Result().Cookies()
+	require.Len(t, cookies, 1)
+	assert.Equal(t, services.AlbumAccessCookieName(album.ID), cookies[0].Name)
+
+	req := newSlugRequest("GET", "/api/albums/"+album.Slug+"/download?quality=display", album.Slug)
+	req.AddCookie(cookies[0])
+	w = httptest.NewRecorder()
+	handler.DownloadAlbum(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	// Invalid links are rejected
+	w = httptest.NewRecorder()
+	handler.OpenAccessLink(w, newSlugRequest("GET", "/api/albums/"+album.Slug+"/access?token=forged.token", album.Slug))
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+}
+
+func TestAlbumHandler_RequestAccessLink_NotConfigured(t *testing.T) {
+	handler, albumService, _ := setupAlbumHandler(t)
+
+	album := &models.Album{Title: "Smith Wedding", Visibility: "unlisted", AllowedEmails: []string{"bride@example.com"}}
+	require.NoError(t, albumService.Create(album))
+
+	req := newSlugRequest("POST", "/api/albums/"+album.Slug+"/request-access", album.Slug)
+	req.Body = io.NopCloser(strings.NewReader(`{"email":"bride@example.com"}`))
+	w := httptest.NewRecorder()
+	handler.RequestAccessLink(w, req)
+	assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+}
+
+func TestAlbumHandler_GetPhotoPermalink(t *testing.T) {
+	handler, albumService, _ := setupAlbumHandler(t)
+
+	album := &models.Album{Title: "Coastline", Visibility: "public"}
+	require.NoError(t, albumService.Create(album))
+	for _, photo := range []*models.Photo{
+		{FilenameOriginal: "pier.jpg"},
+		{FilenameOriginal: "pier.png", Title: "Pier at Dawn"},
+		{FilenameOriginal: "pier.tif"},
+	} {
+		require.NoError(t, albumService.AddPhoto(album.ID, photo))
+	}
+
+	request := func(photoSlug string) *httptest.ResponseRecorder {
+		req := newSlugRequest("GET", "/api/p/"+album.Slug+"/"+photoSlug, album.Slug)
+		chi.RouteContext(req.Context()).URLParams.Add("photoSlug", photoSlug)
+		w := httptest.NewRecorder()
+		handler.GetPhotoPermalink(w, req)
+		return w
+	}
+
+	w := request("pier-at-dawn")
+	require.Equal(t, http.StatusOK, w.Code)
+
+	var resp struct {
+		Album    PermalinkAlbum `json:"album"`
+		Photo    models.Photo   `json:"photo"`
+		Position int            `json:"position"`
+		Previous *PhotoNeighbor `json:"previous"`
+		Next     *PhotoNeighbor `json:"next"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	assert.Equal(t, "Coastline", resp.Album.Title)
+	assert.Equal(t, 3, resp.Album.TotalPhotos)
+	assert.Equal(t, "Pier at Dawn", resp.Photo.Title)
+	assert.Equal(t, 2, resp.Position)
+	require.NotNil(t, resp.Previous)
+	assert.Equal(t, "pier", resp.Previous.Slug)
+	require.NotNil(t, resp.Next)
+	assert.Equal(t, "pier-2", resp.Next.Slug, "colliding filenames get distinct slugs")
+
+	// The colliding photo resolves on its own slug
+	w = request("pier-2")
+	require.Equal(t, http.StatusOK, w.Code)
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	assert.Equal(t, "pier.tif", resp.Photo.FilenameOriginal)
+
+	assert.Equal(t, http.StatusNotFound, request("missing").Code)
+}
+
+func TestAlbumHandler_GetPhotoPermalink_ScrubsGPS(t *testing.T) {
+	handler, albumService, _ := setupAlbumHandler(t)
+
+	album := &models.Album{Title: "Proofs", Visibility: "public", ScrubGPSOnDownload: true, ShareLocations: true}
+	require.NoError(t, albumService.Create(album))
+	lat, lon := 37.77, -122.42
+	photo := &models.Photo{FilenameOriginal: "home.jpg", EXIF: &models.EXIF{Camera: "Leica M6", Latitude: &lat, Longitude: &lon}}
+	require.NoError(t, albumService.AddPhoto(album.ID, photo))
+
+	req := newSlugRequest("GET", "/api/p/"+album.Slug+"/home", album.Slug)
+	chi.RouteContext(req.Context()).URLParams.Add("photoSlug", "home")
+	w := httptest.NewRecorder()
+	handler.GetPhotoPermalink(w, req)
+	require.Equal(t, http.StatusOK, w.Code)
+
+	var resp struct {
+		Photo models.Photo `json:"photo"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	require.NotNil(t, resp.Photo.EXIF)
+	assert.Equal(t, "Leica M6", resp.Photo.EXIF.Camera)
+	assert.Nil(t, resp.Photo.EXIF.Latitude)
+	assert.Nil(t, resp.Photo.EXIF.Longitude)
+
+	// The stored photo keeps its location
+	stored, err := albumService.GetByID(album.ID)
+	require.NoError(t, err)
+	assert.NotNil(t, stored.Photos[0].EXIF.Latitude)
+}
+
+func TestAlbumHandler_PublicAlbums(t *testing.T) {
+	handler, albumService, _ := setupAlbumHandler(t)
+
+	public := &models.Album{Title: "Street", Visibility: "public"}
+	require.NoError(t, albumService.Create(public))
+	require.NoError(t, albumService.Create(&models.Album{Title: "Hidden", Visibility: "unlisted"}))
+	require.NoError(t, albumService.Create(&models.Album{Title: "Elsewhere", Visibility: "public", Namespace: "guest"}))
+	protected := createProtectedAlbum(t, albumService, "secret")
+
+	w := httptest.NewRecorder()
+	handler.GetPublicAlbums(w, httptest.NewRequest("GET", "/api/public/albums", nil))
+	require.Equal(t, http.StatusOK, w.Code)
+	var list struct {
+		Albums []models.Album `json:"albums"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &list))
+	require.Len(t, list.Albums, 1)
+	assert.Equal(t, "street", list.Albums[0].Slug)
+
+	w = httptest.NewRecorder()
+	handler.GetPublicAlbum(w, newSlugRequest("GET", "/api/public/albums/hidden", "hidden"))
+	require.Equal(t, http.StatusOK, w.Code)
+	var album models.Album
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &album))
+	assert.Equal(t, "Hidden", album.Title)
+	assert.Empty(t, w.Header().Get("X-Robots-Tag"))
+
+	// Restricted albums need an access token, and their secrets are never returned
+	w = httptest.NewRecorder()
+	handler.GetPublicAlbum(w, newSlugRequest("GET", "/api/public/albums/"+protected.Slug, protected.Slug))
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+	assert.NotContains(t, w.Body.String(), protected.PasswordHash)
+	assert.Equal(t, "noindex", w.Header().Get("X-Robots-Tag"))
+
+	// Albums can opt out of search engines whatever their visibility
+	require.NoError(t, albumService.Create(&models.Album{Title: "Proofs", Visibility: "public", NoIndex: true}))
+	w = httptest.NewRecorder()
+	handler.GetPublicAlbum(w, newSlugRequest("GET", "/api/public/albums/proofs", "proofs"))
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "noindex", w.Header().Get("X-Robots-Tag"))
+
+	w = httptest.NewRecorder()
+	handler.GetPublicAlbum(w, newSlugRequest("GET", "/api/public/albums/stret", "stret"))
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	assert.Contains(t, w.Body.String(), `"slug":"street"`)
+}
+
+func TestAlbumHandler_RegeneratePhoto(t *testing.T) {
+	handler, albumService, _ := setupAlbumHandler(t)
+	storage := handler.imageService.Storage()
+
+	album := &models.Album{Title: "Rotations", Visibility: "public"}
+	require.NoError(t, albumService.Create(album))
+	photo, err := handler.imageService.ProcessBytes("frame.jpg", createTestJPEG(t, 64, 48))
+	require.NoError(t, err)
+	require.NoError(t, albumService.AddPhoto(album.ID, photo))
+	photo.Caption = "Kept"
+	require.NoError(t, albumService.UpdatePhoto(album.ID, photo.ID, photo))
+
+	// The original is replaced with a rotated copy and the display version is damaged
+	originalKey := strings.TrimPrefix(photo.URLOriginal, "/uploads/")
+	displayKey := strings.TrimPrefix(photo.URLDisplay, "/uploads/")
+	rotated := createTestJPEG(t, 48, 64)
+	require.NoError(t, storage.Put(originalKey, bytes.NewReader(rotated), int64(len(rotated))))
+	require.NoError(t, storage.Put(displayKey, strings.NewReader("broken"), 6))
+
+	regenerate := func(photoID string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("POST", "/api/admin/albums/"+album.ID+"/photos/"+photoID+"/regenerate", nil)
+		rctx := chi.NewRouteContext()
+		rctx.URLParams.Add("id", album.ID)
+		rctx.URLParams.Add("photoId", photoID)
+		req = req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, rctx))
+		w := httptest.NewRecorder()
+		handler.RegeneratePhoto(w, req)
+		return w
+	}
+
+	w := regenerate(photo.ID)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	var regenerated models.Photo
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &regenerated))
+	assert.Equal(t, 48, regenerated.Width)
+	assert.Equal(t, 64, regenerated.Height)
+	assert.Equal(t, photo.URLDisplay, regenerated.URLDisplay)
+	assert.Equal(t, "Kept", regenerated.Caption)
+
+	display, err := storage.Get(displayKey)
+	require.NoError(t, err)
+	assert.NotEqual(t, []byte("broken"), display)
+	assert.Equal(t, int64(len(display)), regenerated.FileSizeDisplay)
+
+	stored, err := albumService.GetByID(album.ID)
+	require.NoError(t, err)
+	assert.Equal(t, 48, stored.Photos[0].Width)
+	assert.Equal(t, 64, stored.Photos[0].Height)
+
+	// Without an original the derivatives are left alone
+	require.NoError(t, storage.Delete(originalKey))
+	assert.Equal(t, http.StatusConflict, regenerate(photo.ID).Code)
+	kept, err := storage.Get(displayKey)
+	require.NoError(t, err)
+	assert.Equal(t, display, kept)
+
+	assert.Equal(t, http.StatusNotFound, regenerate("missing").Code)
+}
+
+func TestAlbumHandler_CheckPassword(t *testing.T) {
+	handler, albumService, _ := setupAlbumHandler(t)
+
+	protected := createProtectedAlbum(t, albumService, "letmein")
+	open := &models.Album{Title: "Street", Visibility: "public"}
+	require.NoError(t, albumService.Create(open))
+
+	adminHash, err := services.HashPassword("admin-pass")
+	require.NoError(t, err)
+	authService := services.NewAuthService("admin", adminHash, time.Hour)
+	sessionID, err := authService.Authenticate("admin", "admin-pass")
+	require.NoError(t, err)
+	adminCookie := &http.Cookie{Name: "photoadmin_session", Value: sessionID}
+
+	router := chi.NewRouter()
+	router.Route("/api/admin", func(r chi.Router) {
+		r.Use(middleware.Auth(authService, handler.logger))
+		r.With(middleware.RateLimit(middleware.NewRateLimiter(), 4)).
+			Post("/albums/{id}/verify-password", handler.CheckPassword)
+	})
+
+	check := func(albumID, password string, cookies ...*http.Cookie) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("POST", "/api/admin/albums/"+albumID+"/verify-password",
+			strings.NewReader(`{"password":"`+password+`"}`))
+		for _, cookie := range cookies {
+			req.AddCookie(cookie)
+		}
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+	match := func(w *httptest.ResponseRecorder) bool {
+		var body struct {
+			Match bool `json:"match"`
+		}
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &body))
+		return body.Match
+	}
+
+	// Visitors cannot check passwords
+	w := check(protected.ID, "letmein")
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+
+	w = check(protected.ID, "letmein", adminCookie)
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.True(t, match(w))
+	assert.Empty(t, w.Result().Cookies(), "no access cookie is issued")
+
+	w = check(protected.ID, "wrong", adminCookie)
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.False(t, match(w))
+
+	assert.Equal(t, http.StatusConflict, check(open.ID, "letmein", adminCookie).Code)
+	assert.Equal(t, http.StatusNotFound, check("missing", "letmein", adminCookie).Code)
+
+	// Four checks a minute are allowed; rejected visitors are not counted
+	w = check(protected.ID, "letmein", adminCookie)
+	assert.Equal(t, http.StatusTooManyRequests, w.Code)
+	assert.NotEmpty(t, w.Header().Get("Retry-After"))
+}
+
+func TestAlbumHandler_AutoSection(t *testing.T) {
+	handler, albumService, _ := setupAlbumHandler(t)
+
+	album := &models.Album{Title: "Festival", Visibility: "public"}
+	require.NoError(t, albumService.Create(album))
+	friday := time.Date(2024, 8, 2, 20, 0, 0, 0, time.UTC)
+	saturday := time.Date(2024, 8, 3, 14, 0, 0, 0, time.UTC)
+	for _, photo := range []*models.Photo{
+		{FilenameOriginal: "sat.jpg", EXIF: &models.EXIF{DateTaken: &saturday}},
+		{FilenameOriginal: "fri.jpg", EXIF: &models.EXIF{DateTaken: &friday}},
+	} {
+		require.NoError(t, albumService.AddPhoto(album.ID, photo))
+	}
+
+	withID := func(req *http.Request, id string) *http.Request {
+		rctx := chi.NewRouteContext()
+		rctx.URLParams.Add("id", id)
+		return req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, rctx))
+	}
+	autoSection := func(id, query string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		handler.AutoSection(w, withID(httptest.NewRequest("POST", "/api/admin/albums/"+id+"/auto-section"+query, nil), id))
+		return w
+	}
+	type sectioned struct {
+		Photos        []models.Photo        `json:"photos"`
+		Sections      []models.AlbumSection `json:"sections"`
+		GroupedPhotos []models.PhotoSection `json:"grouped_photos"`
+	}
+
+	// Without sections there is no grouped structure
+	w := httptest.NewRecorder()
+	handler.GetByID(w, withID(httptest.NewRequest("GET", "/api/albums/"+album.ID, nil), album.ID))
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.NotContains(t, w.Body.String(), "grouped_photos")
+
+	w = autoSection(album.ID, "?by=day")
+	require.Equal(t, http.StatusOK, w.Code)
+	var body sectioned
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &body))
+	require.Len(t, body.Sections, 2)
+	assert.Equal(t, "Friday, August 2, 2024", body.Sections[0].Title)
+
+	// GetByID returns the grouped photos alongside the flat list
+	w = httptest.NewRecorder()
+	handler.GetByID(w, withID(httptest.NewRequest("GET", "/api/albums/"+album.ID, nil), album.ID))
+	require.Equal(t, http.StatusOK, w.Code)
+	body = sectioned{}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &body))
+	assert.Len(t, body.Photos, 2)
+	require.Len(t, body.GroupedPhotos, 2)
+	assert.Equal(t, "fri.jpg", body.GroupedPhotos[0].Photos[0].FilenameOriginal)
+	assert.Equal(t, "Saturday, August 3, 2024", body.GroupedPhotos[1].Title)
+	assert.Equal(t, "sat.jpg", body.GroupedPhotos[1].Photos[0].FilenameOriginal)
+
+	// by defaults to day; other groupings are rejected
+	assert.Equal(t, http.StatusOK, autoSection(album.ID, "").Code)
+	assert.Equal(t, http.StatusBadRequest, autoSection(album.ID, "?by=camera").Code)
+	assert.Equal(t, http.StatusNotFound, autoSection("missing", "?by=day").Code)
+}
+
+func TestAlbumHandler_ThumbnailFit(t *testing.T) {
+	handler, albumService, _ := setupAlbumHandler(t)
+	jobService := services.NewJobService()
+	handler.SetRegenerateService(services.NewRegenerateService(albumService, handler.imageService, jobService, handler.logger))
+
+	album := &models.Album{Title: "Contact Sheet", Visibility: "public", ThumbnailFit: models.ThumbnailFitCover}
+	require.NoError(t, albumService.Create(album))
+
+	withID := func(req *http.Request) *http.Request {
+		rctx := chi.NewRouteContext()
+		rctx.URLParams.Add("id", album.ID)
+		return req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, rctx))
+	}
+	thumbnailSize := func() (int, int) {
+		stored, err := albumService.GetByID(album.ID)
+		require.NoError(t, err)
+		data, err := handler.imageService.Storage().Get(strings.TrimPrefix(stored.Photos[0].URLThumbnail, "/uploads/"))
+		require.NoError(t, err)
+		img, err := vips.NewImageFromBuffer(data)
+		require.NoError(t, err)
+		defer img.Close()
+		return img.Width(), img.Height()
+	}
+
+	// Uploads follow the album's fit rather than the site's
+	var body bytes.Buffer
+	form := multipart.NewWriter(&body)
+	part, err := form.CreateFormFile("photos", "wide.jpg")
+	require.NoError(t, err)
+	_, err = part.Write(createTestJPEG(t, 160, 100))
+	require.NoError(t, err)
+	require.NoError(t, form.Close())
+	req := withID(httptest.NewRequest("POST", "/api/admin/albums/"+album.ID+"/photos/upload", &body))
+	req.Header.Set("Content-Type", form.FormDataContentType())
+	w := httptest.NewRecorder()
+	handler.UploadPhotos(w, req)
+	require.Equal(t, http.StatusOK, w.Code)
+
+	width, height := thumbnailSize()
+	assert.Equal(t, 100, width)
+	assert.Equal(t, 100, height)
+
+	// Changing the fit re-renders the album's thumbnails in the background
+	stored, err := albumService.GetByID(album.ID)
+	require.NoError(t, err)
+	stored.ThumbnailFit = models.ThumbnailFitContain
+	update, err := json.Marshal(stored)
+	require.NoError(t, err)
+	w = httptest.NewRecorder()
+	handler.Update(w, withID(httptest.NewRequest("PUT", "/api/admin/albums/"+album.ID, bytes.NewReader(update))))
+	require.Equal(t, http.StatusOK, w.Code)
+
+	require.Eventually(t, func() bool {
+		width, height := thumbnailSize()
+		return width == 160 && height == 100
+	}, 10*time.Second, 10*time.Millisecond)
+
+	// Invalid fits are rejected
+	stored.ThumbnailFit = "stretch"
+	update, err = json.Marshal(stored)
+	require.NoError(t, err)
+	w = httptest.NewRecorder()
+	handler.Update(w, withID(httptest.NewRequest("PUT", "/api/admin/albums/"+album.ID, bytes.NewReader(update))))
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+func TestAlbumHandler_DisplayMaxEdge(t *testing.T) {
+	handler, albumService, _ := setupAlbumHandler(t)
+	jobService := services.NewJobService()
+	handler.SetRegenerateService(services.NewRegenerateService(albumService, handler.imageService, jobService, handler.logger))
+
+	album := &models.Album{Title: "Small Screens", Visibility: "public", DisplayMaxEdge: 800}
+	require.NoError(t, albumService.Create(album))
+
+	withID := func(req *http.Request) *http.Request {
+		rctx := chi.NewRouteContext()
+		rctx.URLParams.Add("id", album.ID)
+		return req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, rctx))
+	}
+	displaySize := func() (int, int) {
+		stored, err := albumService.GetByID(album.ID)
+		require.NoError(t, err)
+		data, err := handler.imageService.Storage().Get(strings.TrimPrefix(stored.Photos[0].URLDisplay, "/uploads/"))
+		require.NoError(t, err)
+		img, err := vips.NewImageFromBuffer(data)
+		require.NoError(t, err)
+		defer img.Close()
+		return img.Width(), img.Height()
+	}
+
+	// Uploads are displayed at the album's size
+	var body bytes.Buffer
+	form := multipart.NewWriter(&body)
+	part, err := form.CreateFormFile("photos", "wide.jpg")
+	require.NoError(t, err)
+	_, err = part.Write(createTestJPEG(t, 1600, 1000))
+	require.NoError(t, err)
+	require.NoError(t, form.Close())
+	req := withID(httptest.NewRequest("POST", "/api/admin/albums/"+album.ID+"/photos/upload", &body))
+	req.Header.Set("Content-Type", form.FormDataContentType())
+	w := httptest.NewRecorder()
+	handler.UploadPhotos(w, req)
+	require.Equal(t, http.StatusOK, w.Code)
+
+	width, height := displaySize()
+	assert.Equal(t, 800, width)
+	assert.Equal(t, 500, height)
+
+	// Clearing the override re-renders the album's displays in the background
+	stored, err := albumService.GetByID(album.ID)
+	require.NoError(t, err)
+	stored.DisplayMaxEdge = 0
+	update, err := json.Marshal(stored)
+	require.NoError(t, err)
+	w = httptest.NewRecorder()
+	handler.Update(w, withID(httptest.NewRequest("PUT", "/api/admin/albums/"+album.ID, bytes.NewReader(update))))
+	require.Equal(t, http.StatusOK, w.Code)
+
+	require.Eventually(t, func() bool {
+		width, height := displaySize()
+		return width == 1600 && height == 1000
+	}, 10*time.Second, 10*time.Millisecond)
+
+	// Sizes outside the allowed range are rejected
+	stored.DisplayMaxEdge = 100
+	update, err = json.Marshal(stored)
+	require.NoError(t, err)
+	w = httptest.NewRecorder()
+	handler.Update(w, withID(httptest.NewRequest("PUT", "/api/admin/albums/"+album.ID, bytes.NewReader(update))))
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+func TestAlbumHandler_GetSummaries(t *testing.T) {
+	handler, albumService, _ := setupAlbumHandler(t)
+
+	public := &models.Album{Title: "Street", Visibility: "public"}
+	require.NoError(t, albumService.Create(public))
+	for _, name := range []string{"a.jpg", "b.jpg", "c.jpg"} {
+		require.NoError(t, albumService.AddPhoto(public.ID, &models.Photo{FilenameOriginal: name, URLThumbnail: "/uploads/thumbnails/" + name}))
+	}
+	stored, err := albumService.GetByID(public.ID)
+	require.NoError(t, err)
+	stored.CoverPhotoID = stored.Photos[1].ID
+	require.NoError(t, albumService.Update(public.ID, stored))
+
+	empty := &models.Album{Title: "Empty", Visibility: "public"}
+	require.NoError(t, albumService.Create(empty))
+	unlisted := &models.Album{Title: "Drafts", Visibility: "unlisted"}
+	require.NoError(t, albumService.Create(unlisted))
+	require.NoError(t, albumService.AddPhoto(unlisted.ID, &models.Photo{FilenameOriginal: "d.jpg"}))
+	clients := &models.Album{Title: "Wedding", Visibility: "public", AllowedEmails: []string{"client@example.com"}}
+	require.NoError(t, albumService.Create(clients))
+	protected := createProtectedAlbum(t, albumService, "letmein")
+	other := &models.Album{Title: "Elsewhere", Namespace: "jane", Visibility: "public"}
+	require.NoError(t, albumService.Create(other))
+
+	adminHash, err := services.HashPassword("admin-pass")
+	require.NoError(t, err)
+	authService := services.NewAuthService("admin", adminHash, time.Hour)
+	sessionID, err := authService.Authenticate("admin", "admin-pass")
+	require.NoError(t, err)
+
+	router := chi.NewRouter()
+	router.With(middleware.OptionalAuth(authService)).Get("/api/albums/summaries", handler.GetSummaries)
+	router.With(middleware.OptionalAuth(authService)).Get("/api/a/{namespace}/albums/summaries", handler.GetSummaries)
+
+	list := func(target string, cookies ...*http.Cookie) map[string]models.AlbumSummary {
+		req := httptest.NewRequest("GET", target, nil)
+		for _, cookie := range cookies {
+			req.AddCookie(cookie)
+		}
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		require.Equal(t, http.StatusOK, w.Code)
+		assert.NotContains(t, w.Body.String(), `"photos"`)
+
+		var body struct {
+			Albums []models.AlbumSummary `json:"albums"`
+		}
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &body))
+		byTitle := map[string]models.AlbumSummary{}
+		for _, summary := range body.Albums {
+			byTitle[summary.Title] = summary
+		}
+		return byTitle
+	}
+
+	// Visitors see public albums that need no access token
+	summaries := list("/api/albums/summaries")
+	assert.Len(t, summaries, 2)
+	assert.Contains(t, summaries, "Street")
+	assert.Contains(t, summaries, "Empty")
+
+	// Counts match the photo arrays, and the cover is the cover photo's thumbnail
+	albums, err := albumService.GetAll()
+	require.NoError(t, err)
+	for _, album := range albums {
+		if summary, ok := summaries[album.Title]; ok {
+			assert.Equal(t, len(album.Photos), summary.PhotoCount, album.Title)
+			assert.Equal(t, album.ID, summary.ID)
+			assert.Equal(t, album.Slug, summary.Slug)
+		}
+	}
+	assert.Equal(t, 3, summaries["Street"].PhotoCount)
+	assert.Equal(t, "/uploads/thumbnails/b.jpg", summaries["Street"].CoverURL)
+	assert.Empty(t, summaries["Empty"].CoverURL)
+
+	// Admins see every album in the namespace
+	summaries = list("/api/albums/summaries", &http.Cookie{Name: "photoadmin_session", Value: sessionID})
+	assert.Len(t, summaries, 5)
+	assert.Equal(t, 1, summaries["Drafts"].PhotoCount)
+	assert.Equal(t, "unlisted", summaries["Drafts"].Visibility)
+	assert.Equal(t, protected.ID, summaries["Client Gallery"].ID)
+
+	// An invalid session is treated as a visitor
+	assert.Len(t, list("/api/albums/summaries", &http.Cookie{Name: "photoadmin_session", Value: "bogus"}), 2)
+
+	// Namespaces are listed separately
+	summaries = list("/api/a/jane/albums/summaries")
+	assert.Len(t, summaries, 1)
+	assert.Contains(t, summaries, "Elsewhere")
+}
+
+func TestAlbumHandler_GetBatch(t *testing.T) {
+	handler, albumService, _ := setupAlbumHandler(t)
+
+	albumAuthService, err := services.NewAlbumAuthService("test-secret", time.Hour)
+	require.NoError(t, err)
+	handler.SetAlbumAuthService(albumAuthService)
+
+	street := &models.Album{Title: "Street", Visibility: "public"}
+	require.NoError(t, albumService.Create(street))
+	drafts := &models.Album{Title: "Drafts", Visibility: "unlisted"}
+	require.NoError(t, albumService.Create(drafts))
+	protected := createProtectedAlbum(t, albumService, "letmein")
+	other := &models.Album{Title: "Elsewhere", Namespace: "jane", Visibility: "public"}
+	require.NoError(t, albumService.Create(other))
+
+	adminHash, err := services.HashPassword("admin-pass")
+	require.NoError(t, err)
+	authService := services.NewAuthService("admin", adminHash, time.Hour)
+	sessionID, err := authService.Authenticate("admin", "admin-pass")
+	require.NoError(t, err)
+	adminCookie := &http.Cookie{Name: "photoadmin_session", Value: sessionID}
+
+	router := chi.NewRouter()
+	router.With(middleware.OptionalAuth(authService)).Post("/api/albums/batch", handler.GetBatch)
+	router.With(middleware.OptionalAuth(authService)).Post("/api/a/{namespace}/albums/batch", handler.GetBatch)
+
+	batch := func(target, body string, cookies ...*http.Cookie) []*models.Album {
+		req := httptest.NewRequest("POST", target, strings.NewReader(body))
+		for _, cookie := range cookies {
+			req.AddCookie(cookie)
+		}
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+		var response struct {
+			Albums []*models.Album `json:"albums"`
+		}
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+		return response.Albums
+	}
+	titles := func(albums []*models.Album) []string {
+		names := make([]string, len(albums))
+		for i, album := range albums {
+			if album != nil {
+				names[i] = album.Title
+			}
+		}
+		return names
+	}
+
+	// Albums come back in request order, with null for unknown ones and ones visitors may not see
+	slugs := `{"slugs": ["` + drafts.Slug + `", "missing", "` + protected.Slug + `", "` + street.Slug + `", "` + drafts.Slug + `"]}`
+	albums := batch("/api/albums/batch", slugs)
+	assert.Equal(t, []string{"Drafts", "", "", "Street", "Drafts"}, titles(albums))
+	assert.Nil(t, albums[1])
+	assert.Nil(t, albums[2])
+
+	ids := `{"ids": ["` + street.ID + `", "no-such-id", "` + other.ID + `", "` + drafts.ID + `"]}`
+	assert.Equal(t, []string{"Street", "", "", "Drafts"}, titles(batch("/api/albums/batch", ids)))
+
+	// Admins see restricted albums, with their admin-only fields
+	albums = batch("/api/albums/batch", slugs, adminCookie)
+	assert.Equal(t, []string{"Drafts", "", "Client Gallery", "Street", "Drafts"}, titles(albums))
+	assert.NotEmpty(t, albums[2].PasswordHash)
+
+	// Visitors with an access cookie see the album without its password hash
+	token, err := albumAuthService.VerifyPassword(protected, "letmein")
+	require.NoError(t, err)
+	albums = batch("/api/albums/batch", `{"ids": ["`+protected.ID+`"]}`, &http.Cookie{Name: services.AlbumAccessCookieName(protected.ID), Value: token})
+	require.NotNil(t, albums[0])
+	assert.Empty(t, albums[0].PasswordHash)
+
+	// Namespaced routes only resolve that namespace's albums
+	assert.Equal(t, []string{"Elsewhere", ""}, titles(batch("/api/a/jane/albums/batch", `{"ids": ["`+other.ID+`", "`+street.ID+`"]}`)))
+
+	// Exactly one of ids or slugs, and not too many
+	for _, body := range []string{`{}`, `{"ids": ["a"], "slugs": ["b"]}`, `{"ids": [` + strings.Repeat(`"x",`, MaxBatchAlbums) + `"x"]}`, `not json`} {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest("POST", "/api/albums/batch", strings.NewReader(body)))
+		assert.Equal(t, http.StatusBadRequest, w.Code, body)
+	}
+}
+
+func TestAlbumHandler_ReorderByColor(t *testing.T) {
+	handler, albumService, _ := setupAlbumHandler(t)
+
+	solidJPEG := func(c color.RGBA) []byte {
+		img := image.NewRGBA(image.Rect(0, 0, 64, 48))
+		for x := 0; x < 64; x++ {
+			for y := 0; y < 48; y++ {
+				img.Set(x, y, c)
+			}
+		}
+		var buf bytes.Buffer
+		require.NoError(t, jpeg.Encode(&buf, img, &jpeg.Options{Quality: 95}))
+		return buf.Bytes()
+	}
+
+	// Albums in stored order, with covers of known colors
+	covers := []struct {
+		title string
+		color *color.RGBA
+	}{
+		{"Monochrome", &color.RGBA{R: 120, G: 120, B: 120, A: 255}},
+		{"Ocean", &color.RGBA{R: 20, G: 80, B: 220, A: 255}},
+		{"Empty", nil},
+		{"Forest", &color.RGBA{R: 30, G: 160, B: 50, A: 255}},
+		{"Sunset", &color.RGBA{R: 230, G: 40, B: 20, A: 255}},
+		{"Meadow", &color.RGBA{R: 220, G: 200, B: 40, A: 255}},
+	}
+	for i, cover := range covers {
+		album := &models.Album{Title: cover.title, Visibility: "public", Order: i + 1}
+		require.NoError(t, albumService.Create(album))
+		if cover.color != nil {
+			photo, err := handler.imageService.ProcessBytes(cover.title+".jpg", solidJPEG(*cover.color))
+			require.NoError(t, err)
+			require.NoError(t, albumService.AddPhoto(album.ID, photo))
+		}
+	}
+
+	w := httptest.NewRecorder()
+	handler.ReorderByColor(w, httptest.NewRequest("POST", "/api/albums/reorder-by-color", nil))
+	require.Equal(t, http.StatusOK, w.Code)
+
+	// Red, yellow, green, blue, then the covers without a hue in their previous order
+	var resp struct {
+		Albums []AlbumColorPosition `json:"albums"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	want := []string{"Sunset", "Meadow", "Forest", "Ocean", "Monochrome", "Empty"}
+	require.Len(t, resp.Albums, len(want))
+	for i, position := range resp.Albums {
+		assert.Equal(t, want[i], position.Title)
+		assert.Equal(t, i+1, position.Order)
+	}
+	require.NotNil(t, resp.Albums[3].Hue)
+	assert.InDelta(t, 223, *resp.Albums[3].Hue, 5)
+	assert.Nil(t, resp.Albums[4].Hue)
+
+	// The order is saved as explicit positions
+	albums, err := albumService.GetAll()
+	require.NoError(t, err)
+	slices.SortFunc(albums, func(a, b models.Album) int { return a.Order - b.Order })
+	titles := make([]string, len(albums))
+	for i, album := range albums {
+		titles[i] = album.Title
+	}
+	assert.Equal(t, want, titles)
+}
+
+func TestAlbumHandler_Pin(t *testing.T) {
+	handler, albumService, _ := setupAlbumHandler(t)
+
+	var ids []string
+	for _, title := range []string{"First", "Second", "Third"} {
+		album := &models.Album{Title: title, Visibility: "public"}
+		require.NoError(t, albumService.Create(album))
+		ids = append(ids, album.ID)
+	}
+
+	pin := func(action func(http.ResponseWriter, *http.Request), id string) *httptest.ResponseRecorder {
+		rctx := chi.NewRouteContext()
+		rctx.URLParams.Add("id", id)
+		req := httptest.NewRequest("POST", "/api/admin/albums/"+id+"/pin", nil)
+		req = req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, rctx))
+		w := httptest.NewRecorder()
+		action(w, req)
+		return w
+	}
+	listed := func() []string {
+		w := httptest.NewRecorder()
+		handler.GetAll(w, httptest.NewRequest("GET", "/api/albums", nil))
+		require.Equal(t, http.StatusOK, w.Code)
+		var resp struct {
+			Albums []models.Album `json:"albums"`
+		}
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+		titles := make([]string, len(resp.Albums))
+		for i := range resp.Albums {
+			titles[i] = resp.Albums[i].Title
+		}
+		return titles
+	}
+
+	// Pinned albums are listed ahead of unpinned ones
+	w := pin(handler.Pin, ids[2])
+	require.Equal(t, http.StatusOK, w.Code)
+	var updated models.Album
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &updated))
+	assert.True(t, updated.Pinned)
+	assert.Equal(t, []string{"Third", "First", "Second"}, listed())
+
+	w = httptest.NewRecorder()
+	handler.GetSummaries(w, httptest.NewRequest("GET", "/api/albums/summaries", nil))
+	require.Equal(t, http.StatusOK, w.Code)
+	var summaries struct {
+		Albums []models.AlbumSummary `json:"albums"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &summaries))
+	require.Len(t, summaries.Albums, 3)
+	assert.Equal(t, ids[2], summaries.Albums[0].ID)
+	assert.True(t, summaries.Albums[0].Pinned)
+	assert.False(t, summaries.Albums[1].Pinned)
+
+	// Unpinning restores the stored order
+	require.Equal(t, http.StatusOK, pin(handler.Unpin, ids[2]).Code)
+	assert.Equal(t, []string{"First", "Second", "Third"}, listed())
+
+	assert.Equal(t, http.StatusNotFound, pin(handler.Pin, "missing").Code)
+	assert.Equal(t, http.StatusNotFound, pin(handler.Unpin, "missing").Code)
+}
+
+func TestAlbumHandler_ViewStats(t *testing.T) {
+	handler, albumService, fileService := setupAlbumHandler(t)
+
+	album := &models.Album{Title: "Street", Visibility: "public"}
+	require.NoError(t, albumService.Create(album))
+
+	stats := func(id string) *httptest.ResponseRecorder {
+		rctx := chi.NewRouteContext()
+		rctx.URLParams.Add("id", id)
+		req := httptest.NewRequest("GET", "/api/albums/"+id+"/stats", nil)
+		req = req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, rctx))
+		w := httptest.NewRecorder()
+		handler.GetStats(w, req)
+		return w
+	}
+	views := func() int64 {
+		w := stats(album.ID)
+		require.Equal(t, http.StatusOK, w.Code)
+		var resp AlbumStats
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+		assert.Equal(t, album.ID, resp.AlbumID)
+		return resp.Views
+	}
+	view := func(cookie *http.Cookie) *httptest.ResponseRecorder {
+		req := newSlugRequest("GET", "/api/public/albums/"+album.Slug, album.Slug)
+		if cookie != nil {
+			req.AddCookie(cookie)
+		}
+		w := httptest.NewRecorder()
+		handler.GetPublicAlbum(w, req)
+		require.Equal(t, http.StatusOK, w.Code)
+		return w
+	}
+
+	assert.Equal(t, http.StatusServiceUnavailable, stats(album.ID).Code)
+	handler.SetViewTracker(services.NewViewTracker(fileService))
+
+	// A first view starts a session; views within it count once
+	w := view(nil)
+	var session *http.Cookie
+	for _, cookie := range w.Result().Cookies() {
+		if cookie.Name == ViewerCookieName {
+			session = cookie
+		}
+	}
+	require.NotNil(t, session)
+	assert.Zero(t, session.MaxAge, "the session cookie should end with the browser session")
+	assert.Empty(t, view(session).Result().Cookies(), "an existing session should be kept")
+	view(session)
+	assert.Equal(t, int64(1), views())
+
+	// Each new session counts again
+	view(nil)
+	view(&http.Cookie{Name: ViewerCookieName, Value: "another-session"})
+	assert.Equal(t, int64(3), views())
+
+	assert.Equal(t, http.StatusNotFound, stats("missing").Code)
+}
+
+func TestAlbumHandler_GetRecentlyViewed(t *testing.T) {
+	handler, albumService, fileService := setupAlbumHandler(t)
+	handler.SetViewTracker(services.NewViewTracker(fileService))
+
+	var albums []*models.Album
+	for _, title := range []string{"Harbour", "Dunes", "Glacier"} {
+		album := &models.Album{Title: title, Visibility: "public"}
+		require.NoError(t, albumService.Create(album))
+		albums = append(albums, album)
+	}
+	session := &http.Cookie{Name: ViewerCookieName, Value: "visitor-1"}
+
+	view := func(album *models.Album) {
+		req := newSlugRequest("GET", "/api/public/albums/"+album.Slug, album.Slug)
+		req.AddCookie(session)
+		w := httptest.NewRecorder()
+		handler.GetPublicAlbum(w, req)
+		require.Equal(t, http.StatusOK, w.Code)
+	}
+	recent := func(cookie *http.Cookie) []string {
+		req := httptest.NewRequest("GET", "/api/recently-viewed", nil)
+		if cookie != nil {
+			req.AddCookie(cookie)
+		}
+		w := httptest.NewRecorder()
+		handler.GetRecentlyViewed(w, req)
+		require.Equal(t, http.StatusOK, w.Code)
+		var resp struct {
+			Albums []models.AlbumSummary `json:"albums"`
+		}
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+		require.NotNil(t, resp.Albums)
+		titles := make([]string, len(resp.Albums))
+		for i, summary := range resp.Albums {
+			titles[i] = summary.Title
+		}
+		return titles
+	}
+
+	// Most recent first, each album once
+	view(albums[0])
+	view(albums[1])
+	view(albums[2])
+	view(albums[0])
+	assert.Equal(t, []string{"Harbour", "Glacier", "Dunes"}, recent(session))
+
+	// Other sessions have their own history
+	assert.Empty(t, recent(nil))
+	assert.Empty(t, recent(&http.Cookie{Name: ViewerCookieName, Value: "visitor-2"}))
+
+	// Albums made private since drop out, as do deleted ones
+	hash, err := services.HashPassword("letmein")
+	require.NoError(t, err)
+	albums[2].Visibility = "password_protected"
+	albums[2].PasswordHash = hash // pragma: allowlist secret
+	require.NoError(t, albumService.Update(albums[2].ID, albums[2]))
+	require.NoError(t, albumService.Delete(albums[1].ID))
+	assert.Equal(t, []string{"Harbour"}, recent(session))
+}
+
+func TestAlbumHandler_SetTheme(t *testing.T) {
+	handler, albumService, _ := setupAlbumHandler(t)
+
+	album := &models.Album{Title: "Harbour", Visibility: "public"}
+	require.NoError(t, albumService.Create(album))
+
+	setTheme := func(id, body string) *httptest.ResponseRecorder {
+		rctx := chi.NewRouteContext()
+		rctx.URLParams.Add("id", id)
+		req := httptest.NewRequest("PATCH", "/api/admin/albums/"+id+"/theme", strings.NewReader(body))
+		req = req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, rctx))
+		w := httptest.NewRecorder()
+		handler.SetTheme(w, req)
+		return w
+	}
+
+	// Valid colors are stored lowercase and returned in the album
+	w := setTheme(album.ID, `{"accent_color": "#1E90FF"}`)
+	require.Equal(t, http.StatusOK, w.Code)
+	var updated models.Album
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &updated))
+	assert.Equal(t, "#1e90ff", updated.AccentColor)
+
+	stored, err := albumService.GetByID(album.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "#1e90ff", stored.AccentColor)
+
+	// Malformed colors are rejected and leave the stored color alone
+	for _, body := range []string{
+		`{"accent_color": "1e90ff"}`,
+		`{"accent_color": "#1e90f"}`,
+		`{"accent_color": "#zzzzzz"}`,
+		`{"accent_color": "blue"}`,
+		`{}`,
+		`not json`,
+	} {
+		w := setTheme(album.ID, body)
+		assert.Equal(t, http.StatusBadRequest, w.Code, body)
+	}
+	stored, err = albumService.GetByID(album.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "#1e90ff", stored.AccentColor)
+
+	// An empty color clears it
+	w = setTheme(album.ID, `{"accent_color": ""}`)
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.NotContains(t, w.Body.String(), "accent_color")
+
+	w = setTheme("missing", `{"accent_color": "#fff"}`)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
+
+func TestAlbumHandler_GetDateHistogram(t *testing.T) {
+	handler, albumService, _ := setupAlbumHandler(t)
+
+	album := &models.Album{Title: "Road Trip", Visibility: "public"}
+	require.NoError(t, albumService.Create(album))
+	first := time.Date(2024, 8, 2, 20, 0, 0, 0, time.UTC)
+	second := time.Date(2024, 9, 14, 9, 0, 0, 0, time.UTC)
+	for _, photo := range []*models.Photo{
+		{FilenameOriginal: "a.jpg", EXIF: &models.EXIF{DateTaken: &first}},
+		{FilenameOriginal: "b.jpg", EXIF: &models.EXIF{DateTaken: &second}},
+		{FilenameOriginal: "c.jpg", EXIF: &models.EXIF{DateTaken: &second}},
+		{FilenameOriginal: "scan.jpg"},
+	} {
+		require.NoError(t, albumService.AddPhoto(album.ID, photo))
+	}
+
+	histogram := func(id, query string) *httptest.ResponseRecorder {
+		rctx := chi.NewRouteContext()
+		rctx.URLParams.Add("id", id)
+		req := httptest.NewRequest("GET", "/api/albums/"+id+"/date-histogram"+query, nil)
+		req = req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, rctx))
+		w := httptest.NewRecorder()
+		handler.GetDateHistogram(w, req)
+		return w
+	}
+
+	w := histogram(album.ID, "?bucket=month")
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, `{"bucket":"month","buckets":[
+		{"bucket":"2024-08","count":1},
+		{"bucket":"2024-09","count":2},
+		{"bucket":"unknown","count":1}
+	]}`, w.Body.String())
+
+	// Days are the default bucket
+	w = histogram(album.ID, "")
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), `"bucket":"day"`)
+	assert.Contains(t, w.Body.String(), `{"bucket":"2024-09-14","count":2}`)
+
+	assert.Equal(t, http.StatusBadRequest, histogram(album.ID, "?bucket=hour").Code)
+	assert.Equal(t, http.StatusNotFound, histogram("missing", "").Code)
+}
+
+func TestAlbumHandler_GetUploadConfig(t *testing.T) {
+	fileService, err := services.NewFileService(t.TempDir())
+	require.NoError(t, err)
+	configService := services.NewSiteConfigService(fileService)
+	require.NoError(t, configService.Update(&models.SiteConfig{
+		Storage: models.StorageConfig{MaxImageSizeMB: 25, AllowedExtensions: []string{".png", ".webp"}},
+	}))
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	imageService, err := services.NewImageService(t.TempDir(), configService, logger)
+	require.NoError(t, err)
+	handler := NewAlbumHandler(services.NewAlbumService(fileService), imageService, logger)
+	handler.SetMaxZIPUploadSize(200 << 20)
+
+	w := httptest.NewRecorder()
+	handler.GetUploadConfig(w, httptest.NewRequest("GET", "/api/upload-config", nil))
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, `{
+		"extensions": [".png", ".webp"],
+		"mime_types": ["image/png", "image/webp"],
+		"max_file_size_bytes": 26214400,
+		"max_zip_size_bytes": 209715200
+	}`, w.Body.String())
+}
+
+func TestAlbumHandler_GetCover(t *testing.T) {
+	handler, albumService, _ := setupAlbumHandler(t)
+
+	album := &models.Album{Title: "Covers", Visibility: "public"}
+	require.NoError(t, albumService.Create(album))
+
+	withID := func(method, target, id string) *http.Request {
+		rctx := chi.NewRouteContext()
+		rctx.URLParams.Add("id", id)
+		req := httptest.NewRequest(method, target, nil)
+		return req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, rctx))
+	}
+	getCover := func(id string) (int, ResolvedCover) {
+		w := httptest.NewRecorder()
+		handler.GetCover(w, withID("GET", "/api/albums/"+id+"/cover", id))
+		var cover ResolvedCover
+		if w.Code == http.StatusOK {
+			require.NoError(t, json.Unmarshal(w.Body.Bytes(), &cover))
+		}
+		return w.Code, cover
+	}
+
+	code, cover := getCover(album.ID)
+	require.Equal(t, http.StatusOK, code)
+	assert.Nil(t, cover.Photo)
+	assert.Equal(t, CoverSourceNone, cover.Source)
+
+	first := &models.Photo{FilenameOriginal: "first.jpg"}
+	require.NoError(t, albumService.AddPhoto(album.ID, first))
+	second := &models.Photo{FilenameOriginal: "second.jpg"}
+	require.NoError(t, albumService.AddPhoto(album.ID, second))
+
+	_, cover = getCover(album.ID)
+	require.NotNil(t, cover.Photo)
+	assert.Equal(t, first.ID, cover.Photo.ID)
+	assert.Equal(t, CoverSourceFirstPhoto, cover.Source)
+
+	require.NoError(t, albumService.SetCoverPhoto(album.ID, second.ID))
+	_, cover = getCover(album.ID)
+	assert.Equal(t, second.ID, cover.Photo.ID)
+	assert.Equal(t, CoverSourceExplicit, cover.Source)
+
+	// Clearing the cover reports the first photo standing in
+	w := httptest.NewRecorder()
+	handler.ClearCoverPhoto(w, withID("POST", "/api/admin/albums/"+album.ID+"/clear-cover", album.ID))
+	require.Equal(t, http.StatusOK, w.Code)
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &cover))
+	assert.Equal(t, first.ID, cover.Photo.ID)
+	assert.Equal(t, CoverSourceFirstPhoto, cover.Source)
+
+	code, _ = getCover("missing")
+	assert.Equal(t, http.StatusNotFound, code)
+}
+
+func TestAlbumHandler_CoverCarousel(t *testing.T) {
+	handler, albumService, _ := setupAlbumHandler(t)
+
+	album := &models.Album{Title: "Carousel", Visibility: "public"}
+	require.NoError(t, albumService.Create(album))
+	var ids []string
+	for _, name := range []string{"a.jpg", "b.jpg", "c.jpg"} {
+		photo := &models.Photo{FilenameOriginal: name}
+		require.NoError(t, albumService.AddPhoto(album.ID, photo))
+		ids = append(ids, photo.ID)
+	}
+
+	send := func(method, target, body string, params map[string]string) (int, ResolvedCover) {
+		rctx := chi.NewRouteContext()
+		for key, value := range params {
+			rctx.URLParams.Add(key, value)
+		}
+		req := httptest.NewRequest(method, target, strings.NewReader(body))
+		req = req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, rctx))
+		w := httptest.NewRecorder()
+		switch {
+		case method == http.MethodDelete:
+			handler.RemoveCoverPhoto(w, req)
+		case strings.HasSuffix(target, "/reorder-covers"):
+			handler.ReorderCoverPhotos(w, req)
+		case strings.HasSuffix(target, "/covers"):
+			handler.AddCoverPhoto(w, req)
+		default:
+			handler.GetCover(w, req)
+		}
+		var cover ResolvedCover
+		if w.Code == http.StatusOK {
+			require.NoError(t, json.Unmarshal(w.Body.Bytes(), &cover))
+		}
+		return w.Code, cover
+	}
+	albumParams := map[string]string{"id": album.ID}
+	photoIDs := func(cover ResolvedCover) []string {
+		var got []string
+		for _, photo := range cover.Photos {
+			got = append(got, photo.ID)
+		}
+		return got
+	}
+
+	// Without covers the first photo stands in alone
+	code, cover := send(http.MethodGet, "/api/albums/"+album.ID+"/cover", "", albumParams)
+	require.Equal(t, http.StatusOK, code)
+	assert.Equal(t, []string{ids[0]}, photoIDs(cover))
+
+	for _, id := range []string{ids[2], ids[1]} {
+		code, cover = send(http.MethodPost, "/api/admin/albums/"+album.ID+"/covers", `{"photo_id":"`+id+`"}`, albumParams)
+		require.Equal(t, http.StatusOK, code)
+	}
+	assert.Equal(t, []string{ids[2], ids[1]}, photoIDs(cover))
+	assert.Equal(t, ids[2], cover.Photo.ID)
+	assert.Equal(t, CoverSourceExplicit, cover.Source)
+
+	// The covers endpoint returns all of them
+	_, cover = send(http.MethodGet, "/api/albums/"+album.ID+"/cover", "", albumParams)
+	assert.Equal(t, []string{ids[2], ids[1]}, photoIDs(cover))
+
+	code, cover = send(http.MethodPost, "/api/admin/albums/"+album.ID+"/reorder-covers", `{"photo_ids":["`+ids[1]+`","`+ids[2]+`"]}`, albumParams)
+	require.Equal(t, http.StatusOK, code)
+	assert.Equal(t, []string{ids[1], ids[2]}, photoIDs(cover))
+
+	code, cover = send(http.MethodDelete, "/api/admin/albums/"+album.ID+"/covers/"+ids[1], "", map[string]string{"id": album.ID, "photoId": ids[1]})
+	require.Equal(t, http.StatusOK, code)
+	assert.Equal(t, []string{ids[2]}, photoIDs(cover))
+
+	code, _ = send(http.MethodPost, "/api/admin/albums/"+album.ID+"/covers", `{"photo_id":"`+ids[2]+`"}`, albumParams)
+	assert.Equal(t, http.StatusBadRequest, code, "already a cover")
+	code, _ = send(http.MethodPost, "/api/admin/albums/missing/covers", `{"photo_id":"`+ids[0]+`"}`, map[string]string{"id": "missing"})
+	assert.Equal(t, http.StatusNotFound, code)
+}
+
+func TestAlbumHandler_DownloadQualities(t *testing.T) {
+	handler, albumService, _ := setupAlbumHandler(t)
+
+	album := &models.Album{Title: "Proofs", Visibility: "public", AllowDownloads: true}
+	require.NoError(t, albumService.Create(album))
+	photo, err := handler.imageService.ProcessBytes("proof.jpg", createTestJPEG(t, 640, 480))
+	require.NoError(t, err)
+	require.NoError(t, albumService.AddPhoto(album.ID, photo))
+
+	allowedSets := [][]string{
+		{},
+		{"thumbnail"},
+		{"display"},
+		{"original"},
+		{"thumbnail", "display"},
+		{"thumbnail", "original"},
+		{"display", "original"},
+		{"thumbnail", "display", "original"},
+	}
+	for _, allowed := range allowedSets {
+		_, err := albumService.SetDownloadSettings(album.ID, nil, allowed)
+		require.NoError(t, err)
+
+		for _, quality := range models.DownloadQualities {
+			want := http.StatusForbidden
+			if len(allowed) == 0 || slices.Contains(allowed, quality) {
+				want = http.StatusOK
+			}
+			t.Run(fmt.Sprintf("%v/%s", allowed, quality), func(t *testing.T) {
+				w := httptest.NewRecorder()
+				handler.DownloadAlbum(w, newSlugRequest("GET", "/api/albums/"+album.Slug+"/download?quality="+quality, album.Slug))
+				assert.Equal(t, want, w.Code, "album download")
+
+				w = httptest.NewRecorder()
+				handler.DownloadManifest(w, newSlugRequest("GET", "/api/albums/"+album.Slug+"/download/manifest?quality="+quality, album.Slug))
+				assert.Equal(t, want, w.Code, "download manifest")
+
+				w = httptest.NewRecorder()
+				handler.DownloadPhoto(w, newPhotoRequest("/api/albums/"+album.Slug+"/photos/"+photo.ID+"/download?quality="+quality, album.Slug, photo.ID))
+				assert.Equal(t, want, w.Code, "photo download")
+
+				// HTML exports are display downloads, and prints original ones
+				switch quality {
+				case "display":
+					w = httptest.NewRecorder()
+					handler.ExportHTML(w, newSlugRequest("GET", "/api/albums/"+album.Slug+"/export-html", album.Slug))
+					assert.Equal(t, want, w.Code, "HTML export")
+				case "original":
+					w = httptest.NewRecorder()
+					handler.PrintPhoto(w, newPhotoRequest("/api/albums/"+album.Slug+"/photos/"+photo.ID+"/print?size=5x7", album.Slug, photo.ID))
+					assert.Equal(t, want, w.Code, "print")
+				}
+			})
+		}
+	}
+
+	// Without ?quality= downloads fall back to the highest quality the album allows
+	_, err = albumService.SetDownloadSettings(album.ID, nil, []string{"thumbnail", "original"})
+	require.NoError(t, err)
+	w := httptest.NewRecorder()
+	handler.DownloadManifest(w, newSlugRequest("GET", "/api/albums/"+album.Slug+"/download/manifest", album.Slug))
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), `"quality":"original"`)
+
+	_, err = albumService.SetDownloadSettings(album.ID, nil, []string{"thumbnail"})
+	require.NoError(t, err)
+	w = httptest.NewRecorder()
+	handler.DownloadPhoto(w, newPhotoRequest("/api/albums/"+album.Slug+"/photos/"+photo.ID+"/download", album.Slug, photo.ID))
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, photo.FileSizeThumbnail, int64(w.Body.Len()))
+
+	// Conversions are original downloads
+	w = httptest.NewRecorder()
+	handler.DownloadPhoto(w, newPhotoRequest("/api/albums/"+album.Slug+"/photos/"+photo.ID+"/download?format=png", album.Slug, photo.ID))
+	assert.Equal(t, http.StatusForbidden, w.Code)
+}
+
+func TestAlbumHandler_SetDownloadSettings(t *testing.T) {
+	handler, albumService, fileService := setupAlbumHandler(t)
+
+	album := &models.Album{Title: "Proofs", Visibility: "public"}
+	require.NoError(t, albumService.Create(album))
+
+	patch := func(albumID, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("PATCH", "/api/admin/albums/"+albumID+"/download-settings", strings.NewReader(body))
+		rctx := chi.NewRouteContext()
+		rctx.URLParams.Add("id", albumID)
+		w := httptest.NewRecorder()
+		handler.SetDownloadSettings(w, req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, rctx)))
+		return w
+	}
+
+	// Qualities are stored lowest first, once each
+	w := patch(album.ID, `{"allow_downloads": true, "download_qualities": ["original", "display", "original"]}`)
+	require.Equal(t, http.StatusOK, w.Code)
+	var updated models.Album
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &updated))
+	assert.True(t, updated.AllowDownloads)
+	assert.Equal(t, []string{"display", "original"}, updated.DownloadQualities)
+
+	// Omitted fields are kept, and an empty list allows every quality
+	w = patch(album.ID, `{"allow_downloads": false}`)
+	require.Equal(t, http.StatusOK, w.Code)
+	stored, err := albumService.GetByID(album.ID)
+	require.NoError(t, err)
+	assert.False(t, stored.AllowDownloads)
+	assert.Equal(t, []string{"display", "original"}, stored.DownloadQualities)
+
+	w = patch(album.ID, `{"download_qualities": []}`)
+	require.Equal(t, http.StatusOK, w.Code)
+	stored, err = albumService.GetByID(album.ID)
+	require.NoError(t, err)
+	assert.Empty(t, stored.DownloadQualities)
+
+	assert.Equal(t, http.StatusBadRequest, patch(album.ID, `{}`).Code)
+	assert.Equal(t, http.StatusBadRequest, patch(album.ID, `{"download_qualities": ["raw"]}`).Code)
+	assert.Equal(t, http.StatusNotFound, patch("missing", `{"allow_downloads": true}`).Code)
+
+	// A store that cannot be read is the server's fault, not the request's
+	require.NoError(t, fileService.WriteJSON("albums.json", "corrupt"))
+	w = patch(album.ID, `{"allow_downloads": true}`)
+	assert.Equal(t, http.StatusInternalServerError, w.Code)
+	assert.Equal(t, "Internal server error\n", w.Body.String())
+}
+
+func TestAlbumHandler_CheckIntegrity(t *testing.T) {
+	handler, albumService, fileService := setupAlbumHandler(t)
+
+	// Two albums hand-edited to the same slug
+	require.NoError(t, fileService.WriteJSON("albums.json", &models.AlbumCollection{Albums: []models.Album{
+		{ID: "a1", Title: "Summer", Slug: "summer", Visibility: "public"},
+		{ID: "a2", Title: "Summer", Slug: "summer", Visibility: "public"},
+	}}))
+
+	check := func(query string) services.StoreIntegrity {
+		t.Helper()
+		w := httptest.NewRecorder()
+		handler.CheckIntegrity(w, httptest.NewRequest("GET", "/api/admin/integrity"+query, nil))
+		require.Equal(t, http.StatusOK, w.Code)
+		var report services.StoreIntegrity
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &report))
+		return report
+	}
+
+	report := check("")
+	require.Len(t, report.Issues, 1)
+	assert.Equal(t, services.IntegrityDuplicateSlug, report.Issues[0].Type)
+	assert.Equal(t, "a2", report.Issues[0].AlbumID)
+	assert.False(t, report.Issues[0].Fixed)
+
+	report = check("?fix=true")
+	assert.Equal(t, 1, report.Fixed)
+	assert.Empty(t, check("").Issues)
+	album, err := albumService.GetByID("a2")
+	require.NoError(t, err)
+	assert.Equal(t, "summer-1", album.Slug)
+}
+
+func TestAlbumHandler_SetSchedule(t *testing.T) {
+	handler, albumService, _ := setupAlbumHandler(t)
+
+	album := &models.Album{Title: "Client Gallery", Visibility: "unlisted"}
+	require.NoError(t, albumService.Create(album))
+
+	patch := func(albumID, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("PATCH", "/api/admin/albums/"+albumID+"/schedule", strings.NewReader(body))
+		rctx := chi.NewRouteContext()
+		rctx.URLParams.Add("id", albumID)
+		w := httptest.NewRecorder()
+		handler.SetSchedule(w, req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, rctx)))
+		return w
+	}
+
+	publish := time.Now().Add(24 * time.Hour).UTC().Format(time.RFC3339)
+	expire := time.Now().Add(14 * 24 * time.Hour).UTC().Format(time.RFC3339)
+	w := patch(album.ID, `{"scheduled_changes": [{"at": "`+expire+`", "visibility": "unlisted"}, {"at": "`+publish+`", "visibility": "public"}]}`)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	var updated models.Album
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &updated))
+	require.Len(t, updated.ScheduledChanges, 2)
+	assert.Equal(t, "public", updated.ScheduledChanges[0].Visibility)
+	assert.Equal(t, "unlisted", updated.Visibility)
+	assert.Empty(t, handler.visitorAlbum(updated).ScheduledChanges, "the schedule is for admins only")
+
+	// Once the time comes, the background ticker's pass makes the album public
+	transitions, err := albumService.ApplyScheduledChanges(time.Now().Add(25 * time.Hour))
+	require.NoError(t, err)
+	require.Len(t, transitions, 1)
+	stored, err := albumService.GetByID(album.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "public", stored.Visibility)
+
+	w = patch(album.ID, `{"scheduled_changes": []}`)
+	require.Equal(t, http.StatusOK, w.Code)
+	stored, err = albumService.GetByID(album.ID)
+	require.NoError(t, err)
+	assert.Empty(t, stored.ScheduledChanges)
+
+	past := time.Now().Add(-time.Hour).UTC().Format(time.RFC3339)
+	assert.Equal(t, http.StatusBadRequest, patch(album.ID, `{}`).Code)
+	assert.Equal(t, http.StatusBadRequest, patch(album.ID, `{"scheduled_changes": [{"at": "`+past+`", "visibility": "public"}]}`).Code)
+	assert.Equal(t, http.StatusBadRequest, patch(album.ID, `{"scheduled_changes": [{"at": "`+publish+`", "visibility": "secret"}]}`).Code)
+	assert.Equal(t, http.StatusNotFound, patch("missing", `{"scheduled_changes": []}`).Code)
+}
@@ -0,0 +1,25 @@
+package handlers
+
+import (
+	"log/slog"
+	"net/http"
+)
+
+// CheckIntegrity reports inconsistencies in the album data, such as duplicate slugs, missing
+// IDs, photos whose files are missing, and covers naming photos no longer in the album. With
+// ?fix=true missing and duplicate IDs and slugs are regenerated and saved.
+func (h *AlbumHandler) CheckIntegrity(w http.ResponseWriter, r *http.Request) {
+	fix := r.URL.Query().Get("fix") == "true"
+
+	report, err := h.albumService.CheckIntegrity(h.imageService.Storage(), fix)
+	if err != nil {
+		h.logger.Error("failed to check album integrity", slog.String("error", err.Error()))
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+	if report.Fixed > 0 {
+		h.logger.Info("fixed album integrity issues", slog.Int("fixed", report.Fixed))
+	}
+
+	respondJSON(w, http.StatusOK, report)
+}
@@ -0,0 +1,166 @@
+package handlers
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/njoubert/nielsshootsfilm/backend/internal/models"
+	"github.com/njoubert/nielsshootsfilm/backend/internal/services"
+)
+
+// SetSelectionService configures the store for the photo selections clients share back
+// from albums. Without it, selections cannot be made or opened.
+func (h *AlbumHandler) SetSelectionService(selectionService *services.SelectionService) {
+	h.selectionService = selectionService
+}
+
+// CreatedSelection is the response to making a selection: the selection and the API path
+// that opens it.
+type CreatedSelection struct {
+	models.Selection
+	URL string `json:"url"`
+}
+
+// SelectionAlbum identifies the album a shared selection was made from.
+type SelectionAlbum struct {
+	ID    string `json:"id"`
+	Slug  string `json:"slug"`
+	Title string `json:"title"`
+}
+
+// SharedSelection is a selection opened by its token, with the selected photos that are
+// still in the album.
+type SharedSelection struct {
+	Selection models.Selection `json:"selection"`
+	Album     SelectionAlbum   `json:"album"`
+	Photos    []models.Photo   `json:"photos"`
+}
+
+// CreateSelection saves a client's pick of the album's photos under a name and returns a
+// share token for it. Body: {"name": "Selects", "photo_ids": ["..."]}. Restricted albums
+// need an access token, as for viewing them.
+func (h *AlbumHandler) CreateSelection(w http.ResponseWriter, r *http.Request) {
+	if h.selectionService == nil {
+		http.Error(w, "Selections are not enabled", http.StatusServiceUnavailable)
+		return
+	}
+
+	var req struct {
+		Name     string   `json:"name"`
+		PhotoIDs []string `json:"photo_ids"`
+	}
+	if !decodeJSON(w, r, &req) {
+		return
+	}
+
+	album, err := h.albumFromPath(r)
+	if err != nil {
+		if err.Error() == "album not found" {
+			h.respondAlbumNotFound(w, r)
+			return
+		}
+		h.logger.Error("failed to get album", slog.String("error", err.Error()))
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+	if !h.hasAlbumAccess(r, album) {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	selection, err := h.selectionService.Create(album, req.Name, req.PhotoIDs)
+	if err != nil {
+		if errors.Is(err, services.ErrInvalidSelection) {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		h.logger.Error("failed to create selection", slog.String("error", err.Error()))
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	h.logger.Info("selection created",
+		slog.String("album_id", album.ID),
+		slog.String("selection_id", selection.ID),
+		slog.Int("photos", len(selection.PhotoIDs)),
+	)
+	respondJSON(w, http.StatusCreated, CreatedSelection{
+		Selection: *selection,
+		URL:       "/api/selections/" + selection.Token,
+	})
+}
+
+// GetSelection opens a shared selection by its token. The token is all that is needed, even
+// for restricted albums, and only reveals the selected photos. Photos deleted from the album
+// since are left out.
+func (h *AlbumHandler) GetSelection(w http.ResponseWriter, r *http.Request) {
+	if h.selectionService == nil {
+		http.Error(w, "Selection not found", http.StatusNotFound)
+		return
+	}
+
+	selection, err := h.selectionService.GetByToken(chi.URLParam(r, "token"))
+	if err != nil {
+		if errors.Is(err, services.ErrSelectionNotFound) {
+			http.Error(w, "Selection not found", http.StatusNotFound)
+			return
+		}
+		h.logger.Error("failed to get selection", slog.String("error", err.Error()))
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	album, err := h.albumService.GetByID(selection.AlbumID)
+	if err != nil {
+		if err.Error() == "album not found" {
+			http.Error(w, "Selection not found", http.StatusNotFound)
+			return
+		}
+		h.logger.Error("failed to get album", slog.String("error", err.Error()))
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	selected := make(map[string]bool, len(selection.PhotoIDs))
+	for _, id := range selection.PhotoIDs {
+		selected[id] = true
+	}
+	photos := []models.Photo{}
+	for _, photo := range h.visitorAlbum(*album).Photos {
+		if selected[photo.ID] {
+			photos = append(photos, photo)
+		}
+	}
+
+	respondJSON(w, http.StatusOK, SharedSelection{
+		Selection: *selection,
+		Album:     SelectionAlbum{ID: album.ID, Slug: album.Slug, Title: album.Title},
+		Photos:    photos,
+	})
+}
+
+// ListSelections returns the selections clients have made from an album, oldest first.
+func (h *AlbumHandler) ListSelections(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+
+	if _, ok := h.albumByID(w, id); !ok {
+		return
+	}
+
+	selections := []models.Selection{}
+	if h.selectionService != nil {
+		var err error
+		selections, err = h.selectionService.ListByAlbum(id)
+		if err != nil {
+			h.logger.Error("failed to list selections", slog.String("error", err.Error()))
+			http.Error(w, "Internal server error", http.StatusInternalServerError)
+			return
+		}
+	}
+
+	respondJSON(w, http.StatusOK, map[string]any{
+		"selections": selections,
+	})
+}
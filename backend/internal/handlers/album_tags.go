@@ -0,0 +1,91 @@
+package handlers
+
+import (
+	"log/slog"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/njoubert/nielsshootsfilm/backend/internal/models"
+)
+
+// AlbumTags is an album's tag index.
+type AlbumTags struct {
+	AlbumID string            `json:"album_id"`
+	Tags    []models.TagCount `json:"tags"`
+}
+
+// AlbumPhotos lists an album's photos, optionally only those with a tag.
+type AlbumPhotos struct {
+	AlbumID string         `json:"album_id"`
+	Tag     string         `json:"tag,omitempty"` // Normalized filter tag
+	Photos  []models.Photo `json:"photos"`
+	Total   int            `json:"total"`   // Photos in the album, whether or not they matched
+	Matched int            `json:"matched"` // Photos listed across all pages
+}
+
+// GetTags returns the distinct tags of an album's photos, sorted, with how many photos carry each.
+func (h *AlbumHandler) GetTags(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+
+	album, ok := h.albumByID(w, id)
+	if !ok {
+		return
+	}
+
+	respondJSON(w, http.StatusOK, AlbumTags{AlbumID: id, Tags: album.TagCounts()})
+}
+
+// GetPhotos lists an album's photos in album order. With ?tag= only photos carrying that tag
+// are listed; the tag is matched after the same normalization tags get on save. ?offset=
+// and ?limit= (at most 200) select a page of them, as for GetAll.
+func (h *AlbumHandler) GetPhotos(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+	tag := models.NormalizeTag(r.URL.Query().Get("tag"))
+	offset, limit, ok := pageParams(w, r, 0, MaxListPageSize)
+	if !ok {
+		return
+	}
+
+	album, ok := h.albumByID(w, id)
+	if !ok {
+		return
+	}
+
+	photos := album.Photos
+	if tag != "" {
+		photos = []models.Photo{}
+		for i := range album.Photos {
+			if album.Photos[i].HasTag(tag) {
+				photos = append(photos, album.Photos[i])
+			}
+		}
+	}
+	if photos == nil {
+		photos = []models.Photo{}
+	}
+
+	setPageLinks(w, r, offset, limit, len(photos))
+	respondJSON(w, http.StatusOK, AlbumPhotos{
+		AlbumID: id,
+		Tag:     tag,
+		Photos:  page(photos, offset, limit),
+		Total:   len(album.Photos),
+		Matched: len(photos),
+	})
+}
+
+// albumByID loads an album for an admin route. On failure it writes the error response and
+// returns false.
+func (h *AlbumHandler) albumByID(w http.ResponseWriter, id string) (*models.Album, bool) {
+	album, err := h.albumService.GetByID(id)
+	if err != nil {
+		if err.Error() == "album not found" {
+			http.Error(w, "Album not found", http.StatusNotFound)
+			return nil, false
+		}
+		h.logger.Error("failed to get album", slog.String("error", err.Error()))
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return nil, false
+	}
+	return album, true
+}
@@ -0,0 +1,112 @@
+package handlers
+
+import (
+	"log/slog"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/njoubert/nielsshootsfilm/backend/internal/models"
+)
+
+// ViewerCookieName is the cookie identifying a visitor's browser session, so repeat views
+// of an album from one session are counted once.
+const ViewerCookieName = "album_viewer"
+
+// recordView counts a view of an album by the request's visitor session, starting a session
+// if the visitor has none.
+func (h *AlbumHandler) recordView(w http.ResponseWriter, r *http.Request, albumID string) {
+	if h.viewTracker == nil {
+		return
+	}
+
+	session := ""
+	if cookie, err := r.Cookie(ViewerCookieName); err == nil && cookie.Value != "" {
+		session = cookie.Value
+	} else {
+		session = uuid.New().String()
+		// No MaxAge, so the cookie lasts for the browser session
+		http.SetCookie(w, &http.Cookie{
+			Name:     ViewerCookieName,
+			Value:    session,
+			Path:     "/",
+			HttpOnly: true,
+			Secure:   false, // Set to true in production with HTTPS
+			SameSite: http.SameSiteLaxMode,
+		})
+	}
+	h.viewTracker.Record(albumID, session)
+}
+
+// AlbumStats is an album's view statistics.
+type AlbumStats struct {
+	AlbumID string `json:"album_id"`
+	Views   int64  `json:"views"` // Visitor sessions that viewed the album
+}
+
+// GetStats returns how many visitor sessions have viewed the album.
+func (h *AlbumHandler) GetStats(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+
+	if h.viewTracker == nil {
+		http.Error(w, "View tracking is not enabled", http.StatusServiceUnavailable)
+		return
+	}
+
+	if _, err := h.albumService.GetByID(id); err != nil {
+		if err.Error() == "album not found" {
+			http.Error(w, "Album not found", http.StatusNotFound)
+			return
+		}
+		h.logger.Error("failed to get album", slog.String("error", err.Error()))
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	views, err := h.viewTracker.Views(id)
+	if err != nil {
+		h.logger.Error("failed to get album views", slog.String("error", err.Error()))
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	respondJSON(w, http.StatusOK, AlbumStats{AlbumID: id, Views: views})
+}
+
+// GetRecentlyViewed lists the namespace's albums the request's visitor session viewed most
+// recently, most recent first, for a "continue browsing" strip. Albums the visitor can no
+// longer open, because they were deleted or restricted since, are left out. Visitors without
+// a session, or with view tracking off, get an empty list.
+func (h *AlbumHandler) GetRecentlyViewed(w http.ResponseWriter, r *http.Request) {
+	summaries := []models.AlbumSummary{}
+
+	cookie, err := r.Cookie(ViewerCookieName)
+	if h.viewTracker == nil || err != nil || cookie.Value == "" {
+		respondJSON(w, http.StatusOK, map[string]any{"albums": summaries})
+		return
+	}
+
+	albums, err := h.albumService.GetAll()
+	if err != nil {
+		h.logger.Error("failed to get albums", slog.String("error", err.Error()))
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+	byID := make(map[string]*models.Album, len(albums))
+	for i := range albums {
+		byID[albums[i].ID] = &albums[i]
+	}
+
+	namespace := chi.URLParam(r, "namespace")
+	for _, id := range h.viewTracker.RecentlyViewed(cookie.Value) {
+		album, ok := byID[id]
+		if !ok || album.Namespace != namespace || !h.hasAlbumAccess(r, album) {
+			continue
+		}
+		summary := album.Summary()
+		summary.CoverURL = h.signedURL(summary.CoverURL)
+		summaries = append(summaries, summary)
+	}
+
+	respondJSON(w, http.StatusOK, map[string]any{"albums": summaries})
+}
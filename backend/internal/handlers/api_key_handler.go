@@ -0,0 +1,93 @@
+package handlers
+
+import (
+	"log/slog"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/njoubert/nielsshootsfilm/backend/internal/models"
+	"github.com/njoubert/nielsshootsfilm/backend/internal/services"
+)
+
+// APIKeyHandler handles the admin endpoints for public API keys.
+type APIKeyHandler struct {
+	apiKeyService *services.APIKeyService
+	logger        *slog.Logger
+}
+
+// NewAPIKeyHandler creates a new API key handler.
+func NewAPIKeyHandler(apiKeyService *services.APIKeyService, logger *slog.Logger) *APIKeyHandler {
+	return &APIKeyHandler{
+		apiKeyService: apiKeyService,
+		logger:        logger,
+	}
+}
+
+// IssuedAPIKey is the response to issuing an API key: the stored key and the key itself,
+// which is only ever shown here.
+type IssuedAPIKey struct {
+	models.APIKey
+	Key string `json:"key"`
+}
+
+// List returns every issued API key, including revoked ones.
+func (h *APIKeyHandler) List(w http.ResponseWriter, r *http.Request) {
+	keys, err := h.apiKeyService.List()
+	if err != nil {
+		h.logger.Error("failed to list API keys", slog.String("error", err.Error()))
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	respondJSON(w, http.StatusOK, map[string]any{
+		"keys": keys,
+	})
+}
+
+// Issue creates an API key. Body: {"name": "...", "rate_limit": 600}; rate_limit is in
+// requests per minute and may be omitted.
+func (h *APIKeyHandler) Issue(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Name      string `json:"name"`
+		RateLimit int    `json:"rate_limit"`
+	}
+	if !decodeJSON(w, r, &req) {
+		return
+	}
+	if req.RateLimit < 0 {
+		http.Error(w, "rate_limit must not be negative", http.StatusBadRequest)
+		return
+	}
+
+	key, secret, err := h.apiKeyService.Issue(req.Name, req.RateLimit)
+	if err != nil {
+		if err.Error() == "API key name is required" {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		h.logger.Error("failed to issue API key", slog.String("error", err.Error()))
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	h.logger.Info("API key issued", slog.String("key_id", key.ID), slog.String("name", key.Name))
+	respondJSON(w, http.StatusCreated, IssuedAPIKey{APIKey: *key, Key: secret})
+}
+
+// Revoke stops an API key from being accepted.
+func (h *APIKeyHandler) Revoke(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+
+	if err := h.apiKeyService.Revoke(id); err != nil {
+		if err.Error() == "API key not found" {
+			http.Error(w, "API key not found", http.StatusNotFound)
+			return
+		}
+		h.logger.Error("failed to revoke API key", slog.String("error", err.Error()))
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	h.logger.Info("API key revoked", slog.String("key_id", id))
+	w.WriteHeader(http.StatusNoContent)
+}
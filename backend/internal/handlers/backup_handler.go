@@ -0,0 +1,108 @@
+package handlers
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+
+	"github.com/njoubert/nielsshootsfilm/backend/internal/services"
+)
+
+// BackupHandler handles the admin endpoints for album data backups.
+type BackupHandler struct {
+	backupService *services.StoreBackupService
+	logger        *slog.Logger
+}
+
+// NewBackupHandler creates a new backup handler. A nil backup service means backups are not
+// configured, which the endpoints report with 503.
+func NewBackupHandler(backupService *services.StoreBackupService, logger *slog.Logger) *BackupHandler {
+	return &BackupHandler{
+		backupService: backupService,
+		logger:        logger,
+	}
+}
+
+// List returns the kept snapshots of the album data, newest first.
+func (h *BackupHandler) List(w http.ResponseWriter, r *http.Request) {
+	if h.backupService == nil {
+		http.Error(w, "Backups are not configured", http.StatusServiceUnavailable)
+		return
+	}
+
+	snapshots, err := h.backupService.List()
+	if err != nil {
+		h.logger.Error("failed to list backups", slog.String("error", err.Error()))
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	respondJSON(w, http.StatusOK, map[string]any{
+		"snapshots": snapshots,
+	})
+}
+
+// Create takes a snapshot of the album data now, even if it is unchanged since the last one.
+func (h *BackupHandler) Create(w http.ResponseWriter, r *http.Request) {
+	if h.backupService == nil {
+		http.Error(w, "Backups are not configured", http.StatusServiceUnavailable)
+		return
+	}
+
+	snapshot, err := h.backupService.Snapshot(services.SnapshotTriggerManual)
+	if err != nil {
+		if errors.Is(err, services.ErrNothingToBackUp) {
+			http.Error(w, "No album data to back up yet", http.StatusConflict)
+			return
+		}
+		h.logger.Error("failed to back up albums", slog.String("error", err.Error()))
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	h.logger.Info("albums backed up", slog.String("key", snapshot.Key))
+	respondJSON(w, http.StatusCreated, snapshot)
+}
+
+// RestoreRequest names the snapshot to restore, by its key in the backup list.
+type RestoreRequest struct {
+	Key string `json:"key"`
+}
+
+// Restore replaces the album data with a snapshot, after snapshotting the current data.
+func (h *BackupHandler) Restore(w http.ResponseWriter, r *http.Request) {
+	if h.backupService == nil {
+		http.Error(w, "Backups are not configured", http.StatusServiceUnavailable)
+		return
+	}
+
+	var req RestoreRequest
+	if !decodeJSON(w, r, &req) {
+		return
+	}
+	if req.Key == "" {
+		http.Error(w, "key is required", http.StatusBadRequest)
+		return
+	}
+
+	restore, err := h.backupService.Restore(req.Key)
+	switch {
+	case errors.Is(err, services.ErrSnapshotNotFound):
+		http.Error(w, "Backup not found", http.StatusNotFound)
+		return
+	case errors.Is(err, services.ErrInvalidSnapshot):
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	case err != nil:
+		h.logger.Error("failed to restore albums", slog.String("key", req.Key), slog.String("error", err.Error()))
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	attrs := []any{slog.String("key", req.Key)}
+	if restore.Safety != nil {
+		attrs = append(attrs, slog.String("safety_snapshot", restore.Safety.Key))
+	}
+	h.logger.Warn("albums restored from backup", attrs...)
+	respondJSON(w, http.StatusOK, restore)
+}
@@ -1,7 +1,7 @@
 package handlers
 
 import (
-	"encoding/json"
+	"fmt"
 	"log/slog"
 	"net/http"
 
@@ -11,8 +11,9 @@ import (
 
 // ConfigHandler handles site configuration requests.
 type ConfigHandler struct {
-	configService *services.SiteConfigService
-	logger        *slog.Logger
+	configService     *services.SiteConfigService
+	regenerateService *services.RegenerateService
+	logger            *slog.Logger
 }
 
 // NewConfigHandler creates a new config handler.
@@ -23,6 +24,12 @@ func NewConfigHandler(configService *services.SiteConfigService, logger *slog.Lo
 	}
 }
 
+// SetRegenerateService configures the service used to re-render thumbnails in the background
+// when the site's thumbnail fit changes. Without it, existing thumbnails keep their old fit.
+func (h *ConfigHandler) SetRegenerateService(regenerateService *services.RegenerateService) {
+	h.regenerateService = regenerateService
+}
+
 // Get returns the site configuration.
 func (h *ConfigHandler) Get(w http.ResponseWriter, r *http.Request) {
 	config, err := h.configService.Get()
@@ -38,8 +45,7 @@ func (h *ConfigHandler) Get(w http.ResponseWriter, r *http.Request) {
 // Update updates the site configuration.
 func (h *ConfigHandler) Update(w http.ResponseWriter, r *http.Request) {
 	var config models.SiteConfig
-	if err := json.NewDecoder(r.Body).Decode(&config); err != nil {
-		http.Error(w, "Invalid request body", http.StatusBadRequest)
+	if !decodeJSON(w, r, &config) {
 		return
 	}
 
@@ -54,12 +60,70 @@ func (h *ConfigHandler) Update(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if edge := config.Storage.MaxOriginalEdgePx; edge != 0 && edge < services.MinOriginalEdgeLimit {
+		http.Error(w, fmt.Sprintf("max_original_edge_px must be 0 (keep originals) or at least %d", services.MinOriginalEdgeLimit), http.StatusBadRequest)
+		return
+	}
+
+	if config.Storage.MinUploadEdgePx < 0 {
+		http.Error(w, "min_upload_edge_px must not be negative", http.StatusBadRequest)
+		return
+	}
+
+	if err := services.ValidateAllowedExtensions(config.Storage.AllowedExtensions); err != nil {
+		http.Error(w, "allowed_extensions: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if err := models.ValidateDownloadFilenameTemplate(config.Storage.DownloadFilename); err != nil {
+		http.Error(w, "download_filename: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	switch config.Storage.DerivativeMode {
+	case "", models.DerivativeModeEager, models.DerivativeModeLazy:
+	default:
+		http.Error(w, "derivative_mode must be eager or lazy", http.StatusBadRequest)
+		return
+	}
+
+	switch config.Storage.KeywordTags {
+	case "", models.KeywordTagsOn, models.KeywordTagsOff:
+	default:
+		http.Error(w, "keyword_tags must be on or off", http.StatusBadRequest)
+		return
+	}
+
+	switch config.Portfolio.ThumbnailFit {
+	case "", models.ThumbnailFitCover, models.ThumbnailFitContain:
+	default:
+		http.Error(w, "thumbnail_fit must be cover or contain", http.StatusBadRequest)
+		return
+	}
+
+	previous, err := h.configService.Get()
+	if err != nil {
+		h.logger.Error("failed to get config", slog.String("error", err.Error()))
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
 	if err := h.configService.Update(&config); err != nil {
 		h.logger.Error("failed to update config", slog.String("error", err.Error()))
 		http.Error(w, "Internal server error", http.StatusInternalServerError)
 		return
 	}
 
+	// Thumbnails of albums without their own fit follow the site's
+	if h.regenerateService != nil && thumbnailFit(previous.Portfolio.ThumbnailFit) != thumbnailFit(config.Portfolio.ThumbnailFit) {
+		job, err := h.regenerateService.StartThumbnails("")
+		if err != nil {
+			h.logger.Warn("failed to start thumbnail regeneration", slog.String("error", err.Error()))
+		} else {
+			h.logger.Info("thumbnail fit changed, regenerating thumbnails", slog.String("job_id", job.ID))
+		}
+	}
+
 	respondJSON(w, http.StatusOK, config)
 }
 
@@ -68,8 +132,7 @@ func (h *ConfigHandler) SetMainPortfolioAlbum(w http.ResponseWriter, r *http.Req
 	var req struct {
 		AlbumID string `json:"album_id"`
 	}
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		http.Error(w, "Invalid request body", http.StatusBadRequest)
+	if !decodeJSON(w, r, &req) {
 		return
 	}
 
@@ -81,3 +144,11 @@ func (h *ConfigHandler) SetMainPortfolioAlbum(w http.ResponseWriter, r *http.Req
 
 	w.WriteHeader(http.StatusNoContent)
 }
+
+// thumbnailFit returns the thumbnail fit a site setting stands for; unset means contain.
+func thumbnailFit(setting string) string {
+	if setting == "" {
+		return models.ThumbnailFitContain
+	}
+	return setting
+}
@@ -0,0 +1,256 @@
+package handlers
+
+import (
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/njoubert/nielsshootsfilm/backend/internal"
+	"github.com/njoubert/nielsshootsfilm/backend/internal/models"
+	"github.com/njoubert/nielsshootsfilm/backend/internal/services"
+)
+
+// uploadURLExpiry is how long a pre-signed upload URL remains valid.
+const uploadURLExpiry = 15 * time.Minute
+
+// maxUploadURLsPerRequest caps how many upload URLs can be issued in one request.
+const maxUploadURLsPerRequest = 100
+
+// DirectUploadHandler handles uploads that go straight from the client to object storage.
+// The client asks for pre-signed URLs, PUTs each file to storage, then calls finalize so
+// the server can ingest the objects and generate derivatives.
+type DirectUploadHandler struct {
+	albumService *services.AlbumService
+	imageService *services.ImageService
+	backend      services.DirectUploadBackend
+	failureHook  *services.UploadFailureHook
+	logger       *slog.Logger
+}
+
+// NewDirectUploadHandler creates a new direct upload handler.
+// A nil backend disables direct uploads; clients should use the multipart upload endpoint instead.
+func NewDirectUploadHandler(
+	albumService *services.AlbumService,
+	imageService *services.ImageService,
+	backend services.DirectUploadBackend,
+	logger *slog.Logger,
+) *DirectUploadHandler {
+	return &DirectUploadHandler{
+		albumService: albumService,
+		imageService: imageService,
+		backend:      backend,
+		logger:       logger,
+	}
+}
+
+// SetUploadFailureHook reports finalized uploads that fail to process or to be added to their
+// album. Without it, failures are only logged and returned to the client.
+func (h *DirectUploadHandler) SetUploadFailureHook(hook *services.UploadFailureHook) {
+	h.failureHook = hook
+}
+
+// UploadURL describes where and how a client should upload one file.
+type UploadURL struct {
+	Filename  string    `json:"filename"`
+	Key       string    `json:"key"`
+	URL       string    `json:"url"`
+	Method    string    `json:"method"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// IssueUploadURLs handles POST /api/admin/albums/{id}/upload-urls.
+func (h *DirectUploadHandler) IssueUploadURLs(w http.ResponseWriter, r *http.Request) {
+	albumID := chi.URLParam(r, "id")
+
+	if h.backend == nil {
+		http.Error(w, "Direct uploads require an S3-compatible storage backend; use the multipart upload endpoint", http.StatusNotImplemented)
+		return
+	}
+
+	var req struct {
+		Files []struct {
+			Filename string `json:"filename"`
+			Size     int64  `json:"size"`
+		} `json:"files"`
+	}
+	if !decodeJSON(w, r, &req) {
+		return
+	}
+
+	if len(req.Files) == 0 {
+		http.Error(w, "files array is required", http.StatusBadRequest)
+		return
+	}
+	if len(req.Files) > maxUploadURLsPerRequest {
+		http.Error(w, fmt.Sprintf("at most %d files can be requested at once", maxUploadURLsPerRequest), http.StatusBadRequest)
+		return
+	}
+
+	// Verify album exists
+	if _, err := h.albumService.GetByID(albumID); err != nil {
+		http.Error(w, "Album not found", http.StatusNotFound)
+		return
+	}
+	if !checkStorageSpace(w, h.imageService, h.logger, albumID) {
+		return
+	}
+
+	uploads := make([]UploadURL, 0, len(req.Files))
+	for _, file := range req.Files {
+		ext := strings.ToLower(filepath.Ext(file.Filename))
+		if !h.imageService.AllowsFilename(file.Filename) {
+			http.Error(w, fmt.Sprintf("%s: unsupported file type", file.Filename), http.StatusBadRequest)
+			return
+		}
+		if file.Size > internal.MaxUploadFileSize {
+			http.Error(w, fmt.Sprintf("%s: file exceeds maximum upload size", file.Filename), http.StatusBadRequest)
+			return
+		}
+
+		key := directUploadPrefix(albumID) + uuid.New().String() + ext
+		url, err := h.backend.PresignPut(key, uploadURLExpiry)
+		if err != nil {
+			h.logger.Error("failed to presign upload URL", slog.String("error", err.Error()))
+			http.Error(w, "Internal server error", http.StatusInternalServerError)
+			return
+		}
+
+		uploads = append(uploads, UploadURL{
+			Filename:  file.Filename,
+			Key:       key,
+			URL:       url,
+			Method:    http.MethodPut,
+			ExpiresAt: time.Now().UTC().Add(uploadURLExpiry),
+		})
+	}
+
+	respondJSON(w, http.StatusOK, map[string]any{
+		"uploads": uploads,
+	})
+}
+
+// FinalizeUploads handles POST /api/admin/albums/{id}/upload-urls/finalize.
+// Each uploaded object is fetched, processed like a multipart upload, added to the album, and removed from storage.
+func (h *DirectUploadHandler) FinalizeUploads(w http.ResponseWriter, r *http.Request) {
+	albumID := chi.URLParam(r, "id")
+
+	if h.backend == nil {
+		http.Error(w, "Direct uploads require an S3-compatible storage backend; use the multipart upload endpoint", http.StatusNotImplemented)
+		return
+	}
+
+	var req struct {
+		Uploads []struct {
+			Key      string `json:"key"`
+			Filename string `json:"filename"`
+		} `json:"uploads"`
+	}
+	if !decodeJSON(w, r, &req) {
+		return
+	}
+
+	if len(req.Uploads) == 0 {
+		http.Error(w, "uploads array is required", http.StatusBadRequest)
+		return
+	}
+
+	// Verify album exists
+	album, err := h.albumService.GetByID(albumID)
+	if err != nil {
+		http.Error(w, "Album not found", http.StatusNotFound)
+		return
+	}
+	if !checkStorageSpace(w, h.imageService, h.logger, albumID) {
+		return
+	}
+
+	resp := newUploadResponse()
+	uploads := h.imageService.Uploads(album)
+
+	for _, upload := range req.Uploads {
+		// Only objects issued for this album may be ingested
+		if !strings.HasPrefix(upload.Key, directUploadPrefix(albumID)) || strings.Contains(upload.Key, "..") {
+			resp.failed(upload.Filename, "invalid upload key")
+			continue
+		}
+
+		photo, err := h.ingest(uploads, upload.Key, upload.Filename)
+		if err != nil {
+			h.logger.Error("failed to finalize direct upload",
+				slog.String("key", upload.Key),
+				slog.String("filename", upload.Filename),
+				slog.String("error", err.Error()),
+			)
+			h.failureHook.Notify(albumID, upload.Filename, err)
+			resp.failed(upload.Filename, err.Error())
+			continue
+		}
+
+		renderUploadVersions(h.imageService, h.logger, album, uploads, upload.Filename, photo)
+
+		// Add photo to album
+		if err := h.albumService.AddPhoto(albumID, photo); err != nil {
+			h.logger.Error("failed to add photo to album",
+				slog.String("filename", upload.Filename),
+				slog.String("error", err.Error()),
+			)
+			h.failureHook.Notify(albumID, upload.Filename, err)
+			resp.failed(upload.Filename, err.Error())
+			continue
+		}
+
+		resp.succeeded(upload.Filename, *photo)
+	}
+
+	// The first upload into an empty album becomes its cover
+	if resp.Summary.Uploaded > 0 {
+		refreshAlbumCover(h.albumService, h.imageService, h.logger, albumID)
+	}
+
+	respondJSON(w, http.StatusOK, resp)
+}
+
+// ingest reads an uploaded object, processes it for the album, and deletes the staged object.
+func (h *DirectUploadHandler) ingest(uploads services.AlbumUploads, key, filename string) (*models.Photo, error) {
+	reader, err := h.backend.Stream(key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read uploaded object: %w", err)
+	}
+
+	// Read one byte past the limit so oversize objects are detected rather than truncated
+	data, err := io.ReadAll(io.LimitReader(reader, internal.MaxUploadFileSize+1))
+	_ = reader.Close()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read uploaded object: %w", err)
+	}
+
+	if filename == "" {
+		filename = filepath.Base(key)
+	}
+
+	photo, err := uploads.ProcessBytes(filename, data)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := h.backend.Delete(key); err != nil {
+		// The photo is safely stored locally; a leftover staged object only wastes space
+		h.logger.Warn("failed to delete staged upload",
+			slog.String("key", key),
+			slog.String("error", err.Error()),
+		)
+	}
+
+	return photo, nil
+}
+
+// directUploadPrefix returns the object key prefix for staged uploads to an album.
+func directUploadPrefix(albumID string) string {
+	return "incoming/" + albumID + "/"
+}
@@ -0,0 +1,165 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/njoubert/nielsshootsfilm/backend/internal/models"
+	"github.com/njoubert/nielsshootsfilm/backend/internal/services"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// memoryUploadBackend is an in-memory DirectUploadBackend for tests.
+type memoryUploadBackend struct {
+	*services.MemoryStorage
+}
+
+func (b *memoryUploadBackend) PresignPut(key string, expires time.Duration) (string, error) {
+	return fmt.Sprintf("https://storage.example.com/%s?expires=%d", key, int(expires.Seconds())), nil
+}
+
+// setupDirectUploadHandler creates a direct upload handler with an album to upload into.
+func setupDirectUploadHandler(t *testing.T, backend services.DirectUploadBackend) (*DirectUploadHandler, *services.AlbumService, *models.Album) {
+	t.Helper()
+
+	fileService, err := services.NewFileService(t.TempDir())
+	require.NoError(t, err)
+
+	albumService := services.NewAlbumService(fileService)
+	logger := slog.New(slog.NewTextHandler(&strings.Builder{}, nil))
+
+	imageService, err := services.NewImageService(t.TempDir(), nil, logger)
+	require.NoError(t, err)
+
+	album := &models.Album{Title: "Direct Uploads", Visibility: "public"}
+	require.NoError(t, albumService.Create(album))
+
+	return NewDirectUploadHandler(albumService, imageService, backend, logger), albumService, album
+}
+
+// newAlbumRequest creates a request with the chi album id URL parameter set.
+func newAlbumRequest(method, target, albumID, body string) *http.Request {
+	req := httptest.NewRequest(method, target, strings.NewReader(body))
+	rctx := chi.NewRouteContext()
+	rctx.URLParams.Add("id", albumID)
+	return req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, rctx))
+}
+
+func TestDirectUploadHandler_IssueUploadURLs(t *testing.T) {
+	handler, _, album := setupDirectUploadHandler(t, &memoryUploadBackend{services.NewMemoryStorage()})
+
+	body := `{"files":[{"filename":"roll1.jpg","size":1024},{"filename":"roll2.JPEG","size":2048}]}`
+	w := httptest.NewRecorder()
+	handler.IssueUploadURLs(w, newAlbumRequest("POST", "/api/admin/albums/"+album.ID+"/upload-urls", album.ID, body))
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+
+	var resp struct {
+		Uploads []UploadURL `json:"uploads"`
+	}
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&resp))
+	require.Len(t, resp.Uploads, 2)
+
+	for _, upload := range resp.Uploads {
+		assert.True(t, strings.HasPrefix(upload.Key, "incoming/"+album.ID+"/"))
+		assert.Contains(t, upload.URL, upload.Key)
+		assert.Equal(t, http.MethodPut, upload.Method)
+		assert.True(t, upload.ExpiresAt.After(time.Now()))
+	}
+	assert.Equal(t, "roll1.jpg", resp.Uploads[0].Filename)
+	assert.True(t, strings.HasSuffix(resp.Uploads[1].Key, ".jpeg"))
+	assert.NotEqual(t, resp.Uploads[0].Key, resp.Uploads[1].Key)
+
+	// Unsupported types are rejected before any URL is issued
+	w = httptest.NewRecorder()
+	handler.IssueUploadURLs(w, newAlbumRequest("POST", "/", album.ID, `{"files":[{"filename":"notes.txt","size":10}]}`))
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	// Oversize files are rejected
+	w = httptest.NewRecorder()
+	handler.IssueUploadURLs(w, newAlbumRequest("POST", "/", album.ID, `{"files":[{"filename":"big.jpg","size":999999999999}]}`))
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	// Unknown albums are rejected
+	w = httptest.NewRecorder()
+	handler.IssueUploadURLs(w, newAlbumRequest("POST", "/", "missing", `{"files":[{"filename":"a.jpg","size":10}]}`))
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
+
+func TestDirectUploadHandler_NotConfigured(t *testing.T) {
+	handler, _, album := setupDirectUploadHandler(t, nil)
+
+	w := httptest.NewRecorder()
+	handler.IssueUploadURLs(w, newAlbumRequest("POST", "/", album.ID, `{"files":[{"filename":"a.jpg","size":10}]}`))
+	assert.Equal(t, http.StatusNotImplemented, w.Code)
+
+	w = httptest.NewRecorder()
+	handler.FinalizeUploads(w, newAlbumRequest("POST", "/", album.ID, `{"uploads":[{"key":"k"}]}`))
+	assert.Equal(t, http.StatusNotImplemented, w.Code)
+}
+
+func TestDirectUploadHandler_LowStorageSpace(t *testing.T) {
+	backend := &memoryUploadBackend{services.NewMemoryStorage()}
+	handler, albumService, album := setupDirectUploadHandler(t, backend)
+	handler.imageService.SetStorage(&lowSpaceStorage{MemoryStorage: services.NewMemoryStorage(), available: 10 << 20})
+	handler.imageService.SetMinUploadSpace(100 << 20)
+
+	// Neither URLs are issued nor staged objects ingested while space is low
+	w := httptest.NewRecorder()
+	handler.IssueUploadURLs(w, newAlbumRequest("POST", "/", album.ID, `{"files":[{"filename":"a.jpg","size":10}]}`))
+	assert.Equal(t, http.StatusInsufficientStorage, w.Code)
+
+	key := "incoming/" + album.ID + "/upload.jpg"
+	require.NoError(t, backend.Put(key, strings.NewReader("jpeg"), 4))
+	w = httptest.NewRecorder()
+	handler.FinalizeUploads(w, newAlbumRequest("POST", "/", album.ID, fmt.Sprintf(`{"uploads":[{"key":%q,"filename":"a.jpg"}]}`, key)))
+	assert.Equal(t, http.StatusInsufficientStorage, w.Code)
+	assert.Equal(t, []string{key}, backend.Keys("incoming/"))
+	stored, err := albumService.GetByID(album.ID)
+	require.NoError(t, err)
+	assert.Empty(t, stored.Photos)
+}
+
+func TestDirectUploadHandler_FinalizeUploads(t *testing.T) {
+	jpegBytes := createTestJPEG(t, 64, 48)
+
+	backend := &memoryUploadBackend{services.NewMemoryStorage()}
+	handler, albumService, album := setupDirectUploadHandler(t, backend)
+
+	key := "incoming/" + album.ID + "/upload.jpg"
+	foreignKey := "incoming/other-album/upload.jpg"
+	require.NoError(t, backend.Put(key, bytes.NewReader(jpegBytes), int64(len(jpegBytes))))
+	require.NoError(t, backend.Put(foreignKey, bytes.NewReader(jpegBytes), int64(len(jpegBytes))))
+
+	body := fmt.Sprintf(`{"uploads":[{"key":%q,"filename":"roll1.jpg"},{"key":%q,"filename":"stolen.jpg"},{"key":%q,"filename":"missing.jpg"}]}`,
+		key, foreignKey, "incoming/"+album.ID+"/missing.jpg")
+	w := httptest.NewRecorder()
+	handler.FinalizeUploads(w, newAlbumRequest("POST", "/", album.ID, body))
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+
+	var resp struct {
+		Uploaded []models.Photo `json:"uploaded"`
+		Errors   []string       `json:"errors"`
+	}
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&resp))
+	require.Len(t, resp.Uploaded, 1)
+	assert.Equal(t, "roll1.jpg", resp.Uploaded[0].FilenameOriginal)
+	assert.Len(t, resp.Errors, 2)
+
+	// The staged object is removed once ingested; objects for other albums are untouched
+	assert.Equal(t, []string{foreignKey}, backend.Keys("incoming/"))
+
+	updated, err := albumService.GetByID(album.ID)
+	require.NoError(t, err)
+	require.Len(t, updated.Photos, 1)
+	assert.Equal(t, resp.Uploaded[0].ID, updated.Photos[0].ID)
+}
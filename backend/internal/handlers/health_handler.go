@@ -0,0 +1,41 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/njoubert/nielsshootsfilm/backend/internal/services"
+)
+
+// HealthHandler reports whether the service can serve its data.
+type HealthHandler struct {
+	recoveries []services.StoreRecovery
+}
+
+// NewHealthHandler creates a new health handler reporting the data store recoveries made at startup.
+func NewHealthHandler(recoveries []services.StoreRecovery) *HealthHandler {
+	if recoveries == nil {
+		recoveries = []services.StoreRecovery{}
+	}
+	return &HealthHandler{recoveries: recoveries}
+}
+
+// Ready responds 200 when every data store loaded, including any restored from a backup at
+// startup, and 503 when a store could not be parsed and had no valid backup. The startup
+// recoveries are listed either way.
+func (h *HealthHandler) Ready(w http.ResponseWriter, r *http.Request) {
+	status, code := "ready", http.StatusOK
+	if len(h.recoveries) > 0 {
+		status = "recovered"
+	}
+	for _, recovery := range h.recoveries {
+		if !recovery.Recovered() {
+			status, code = "unavailable", http.StatusServiceUnavailable
+			break
+		}
+	}
+
+	respondJSON(w, code, map[string]any{
+		"status":     status,
+		"recoveries": h.recoveries,
+	})
+}
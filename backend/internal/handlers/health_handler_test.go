@@ -0,0 +1,43 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/njoubert/nielsshootsfilm/backend/internal/services"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHealthHandler_Ready(t *testing.T) {
+	ready := func(recoveries []services.StoreRecovery) (int, string, []services.StoreRecovery) {
+		w := httptest.NewRecorder()
+		NewHealthHandler(recoveries).Ready(w, httptest.NewRequest("GET", "/api/readyz", nil))
+
+		var body struct {
+			Status     string                   `json:"status"`
+			Recoveries []services.StoreRecovery `json:"recoveries"`
+		}
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &body))
+		return w.Code, body.Status, body.Recoveries
+	}
+
+	code, status, recoveries := ready(nil)
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, "ready", status)
+	assert.Empty(t, recoveries)
+	assert.NotNil(t, recoveries)
+
+	restored := services.StoreRecovery{File: "albums.json", Error: "unexpected end of JSON input", Backup: "albums.json.20240501-120000.000000000.bak"}
+	code, status, recoveries = ready([]services.StoreRecovery{restored})
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, "recovered", status)
+	assert.Equal(t, []services.StoreRecovery{restored}, recoveries)
+
+	lost := services.StoreRecovery{File: "site_config.json", Error: "invalid character"}
+	code, status, _ = ready([]services.StoreRecovery{restored, lost})
+	assert.Equal(t, http.StatusServiceUnavailable, code)
+	assert.Equal(t, "unavailable", status)
+}
@@ -0,0 +1,67 @@
+package handlers
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+
+	"github.com/njoubert/nielsshootsfilm/backend/internal/services"
+)
+
+// ImportHandler handles importing existing photo folders from the server's filesystem.
+type ImportHandler struct {
+	importService *services.ImportService
+	logger        *slog.Logger
+}
+
+// NewImportHandler creates a new import handler.
+func NewImportHandler(importService *services.ImportService, logger *slog.Logger) *ImportHandler {
+	return &ImportHandler{
+		importService: importService,
+		logger:        logger,
+	}
+}
+
+// ImportFolder handles POST /api/admin/import-folder.
+// It creates an album from a server-side folder inside the configured import root.
+func (h *ImportHandler) ImportFolder(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Path  string `json:"path"`
+		Title string `json:"title"`
+	}
+	if !decodeJSON(w, r, &req) {
+		return
+	}
+
+	result, err := h.importService.ImportFolder(req.Path, req.Title)
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrImportDisabled):
+			http.Error(w, "Folder import is not configured; set IMPORT_ROOT", http.StatusNotImplemented)
+		case errors.Is(err, services.ErrInvalidImport):
+			http.Error(w, err.Error(), http.StatusBadRequest)
+		case errors.Is(err, services.ErrInsufficientStorage):
+			h.logger.Warn("folder import rejected for low storage space",
+				slog.String("path", req.Path),
+				slog.String("error", err.Error()),
+			)
+			http.Error(w, err.Error(), http.StatusInsufficientStorage)
+		default:
+			h.logger.Error("failed to import folder",
+				slog.String("path", req.Path),
+				slog.String("error", err.Error()),
+			)
+			http.Error(w, "Internal server error", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	h.logger.Info("folder imported",
+		slog.String("path", req.Path),
+		slog.String("album_id", result.Album.ID),
+		slog.Int("imported", result.Imported),
+		slog.Int("failed", result.Failed),
+	)
+
+	respondJSON(w, http.StatusCreated, result)
+}
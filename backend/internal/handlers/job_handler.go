@@ -0,0 +1,137 @@
+package handlers
+
+import (
+	"log/slog"
+	"net/http"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/njoubert/nielsshootsfilm/backend/internal/services"
+)
+
+// JobHandler handles requests for background job progress.
+type JobHandler struct {
+	jobService          *services.JobService
+	regenerateService   *services.RegenerateService
+	exifBackfillService *services.EXIFBackfillService
+	derivedBackfill     *services.DerivedBackfillService
+	logger              *slog.Logger
+}
+
+// NewJobHandler creates a new job handler.
+func NewJobHandler(
+	jobService *services.JobService,
+	regenerateService *services.RegenerateService,
+	exifBackfillService *services.EXIFBackfillService,
+	derivedBackfill *services.DerivedBackfillService,
+	logger *slog.Logger,
+) *JobHandler {
+	return &JobHandler{
+		jobService:          jobService,
+		regenerateService:   regenerateService,
+		exifBackfillService: exifBackfillService,
+		derivedBackfill:     derivedBackfill,
+		logger:              logger,
+	}
+}
+
+// Get handles GET /api/admin/jobs/{id}.
+func (h *JobHandler) Get(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+
+	job, err := h.jobService.Get(id)
+	if err != nil {
+		http.Error(w, "Job not found", http.StatusNotFound)
+		return
+	}
+
+	respondJSON(w, http.StatusOK, job)
+}
+
+// Regenerate handles POST /api/admin/regenerate?album_id=.
+// It deletes and rebuilds derivatives for one album, or all albums when album_id is omitted,
+// and returns a job whose progress can be polled.
+func (h *JobHandler) Regenerate(w http.ResponseWriter, r *http.Request) {
+	albumID := r.URL.Query().Get("album_id")
+
+	job, err := h.regenerateService.Start(albumID)
+	if err != nil {
+		if err.Error() == "album not found" {
+			http.Error(w, "Album not found", http.StatusNotFound)
+			return
+		}
+		h.logger.Error("failed to start regeneration", slog.String("error", err.Error()))
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	h.logger.Info("derivative regeneration started",
+		slog.String("job_id", job.ID),
+		slog.String("album_id", albumID),
+		slog.Int("photos", job.Total),
+	)
+
+	respondJSON(w, http.StatusAccepted, job)
+}
+
+// ReprocessEXIF handles POST /api/admin/reprocess-exif?album_id=.
+// It re-reads originals for one album, or all albums when album_id is omitted, and fills in
+// missing EXIF data, dimensions, and capture dates without regenerating derivatives.
+// Photos whose originals are missing are listed as skipped on the returned job.
+func (h *JobHandler) ReprocessEXIF(w http.ResponseWriter, r *http.Request) {
+	albumID := r.URL.Query().Get("album_id")
+
+	job, err := h.exifBackfillService.Start(albumID)
+	if err != nil {
+		if err.Error() == "album not found" {
+			http.Error(w, "Album not found", http.StatusNotFound)
+			return
+		}
+		h.logger.Error("failed to start EXIF reprocessing", slog.String("error", err.Error()))
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	h.logger.Info("EXIF reprocessing started",
+		slog.String("job_id", job.ID),
+		slog.String("album_id", albumID),
+		slog.Int("photos", job.Total),
+	)
+
+	respondJSON(w, http.StatusAccepted, job)
+}
+
+// Backfill handles POST /api/admin/backfill?fields=&album_id=.
+// It recomputes the derived fields listed in ?fields= (comma-separated; all of them if
+// omitted) from the originals of one album, or all albums when album_id is omitted. Other
+// fields are left alone. The returned job counts, per field, the photos whose value changed;
+// photos whose originals are missing are listed as skipped.
+func (h *JobHandler) Backfill(w http.ResponseWriter, r *http.Request) {
+	albumID := r.URL.Query().Get("album_id")
+
+	fields, err := services.ParseDerivedFields(r.URL.Query().Get("fields"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	job, err := h.derivedBackfill.Start(albumID, fields)
+	if err != nil {
+		if err.Error() == "album not found" {
+			http.Error(w, "Album not found", http.StatusNotFound)
+			return
+		}
+		h.logger.Error("failed to start backfill", slog.String("error", err.Error()))
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	h.logger.Info("derived field backfill started",
+		slog.String("job_id", job.ID),
+		slog.String("album_id", albumID),
+		slog.String("fields", strings.Join(fields, ",")),
+		slog.Int("photos", job.Total),
+	)
+
+	respondJSON(w, http.StatusAccepted, job)
+}
@@ -0,0 +1,69 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/njoubert/nielsshootsfilm/backend/internal/middleware"
+)
+
+// decodeJSON decodes a JSON request body into v, streaming it through the size limit and
+// strictness set by middleware.JSONBody. Bodies over the limit get 413, and malformed bodies,
+// or in strict mode bodies with unknown fields, get 400. On failure it writes the error
+// response and returns false.
+func decodeJSON(w http.ResponseWriter, r *http.Request, v any) bool {
+	opts := middleware.GetJSONBodyOptions(r.Context())
+	decoder := json.NewDecoder(http.MaxBytesReader(w, r.Body, opts.MaxBytes))
+	if opts.Strict {
+		decoder.DisallowUnknownFields()
+	}
+
+	if err := decoder.Decode(v); err != nil {
+		var tooLarge *http.MaxBytesError
+		switch {
+		case errors.As(err, &tooLarge):
+			http.Error(w, fmt.Sprintf("Request body exceeds the %dKB limit", opts.MaxBytes>>10), http.StatusRequestEntityTooLarge)
+		default:
+			http.Error(w, invalidBodyMessage(err), http.StatusBadRequest)
+		}
+		return false
+	}
+	return true
+}
+
+// invalidBodyMessage describes a body that failed to decode, naming the field in strict
+// mode's unknown field errors.
+func invalidBodyMessage(err error) string {
+	if strings.HasPrefix(err.Error(), "json: unknown field ") {
+		return "Invalid request body: " + strings.TrimPrefix(err.Error(), "json: ")
+	}
+	return "Invalid request body"
+}
+
+// invalidBodyError is a request body read with decodeJSON into a json.RawMessage that
+// decodeRawJSON could not decode, to be answered with 400 and its message.
+type invalidBodyError struct {
+	message string
+}
+
+func (e *invalidBodyError) Error() string {
+	return e.message
+}
+
+// decodeRawJSON decodes a request body read earlier with decodeJSON into a json.RawMessage,
+// with the strictness set by middleware.JSONBody, for handlers that only know what to decode
+// it into later. Failures are returned as an *invalidBodyError.
+func decodeRawJSON(r *http.Request, body json.RawMessage, v any) error {
+	decoder := json.NewDecoder(bytes.NewReader(body))
+	if middleware.GetJSONBodyOptions(r.Context()).Strict {
+		decoder.DisallowUnknownFields()
+	}
+	if err := decoder.Decode(v); err != nil {
+		return &invalidBodyError{message: invalidBodyMessage(err)}
+	}
+	return nil
+}
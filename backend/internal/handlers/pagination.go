@@ -0,0 +1,73 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// MaxListPageSize is the largest page of albums or photos that may be requested.
+const MaxListPageSize = 200
+
+// pageParams reads a page's ?offset= and ?limit=. An omitted limit is defaultLimit, where 0
+// means the rest of the list. Invalid values are answered with 400 and ok is false.
+func pageParams(w http.ResponseWriter, r *http.Request, defaultLimit, maxLimit int) (offset, limit int, ok bool) {
+	if param := r.URL.Query().Get("offset"); param != "" {
+		n, err := strconv.Atoi(param)
+		if err != nil || n < 0 {
+			http.Error(w, "Invalid offset parameter. Must be a non-negative integer", http.StatusBadRequest)
+			return 0, 0, false
+		}
+		offset = n
+	}
+
+	limit = defaultLimit
+	if param := r.URL.Query().Get("limit"); param != "" {
+		n, err := strconv.Atoi(param)
+		if err != nil || n < 1 || n > maxLimit {
+			http.Error(w, fmt.Sprintf("Invalid limit parameter. Must be an integer from 1 to %d", maxLimit), http.StatusBadRequest)
+			return 0, 0, false
+		}
+		limit = n
+	}
+	return offset, limit, true
+}
+
+// page returns the items of a list on the page at offset holding up to limit of them, or
+// the rest of the list if limit is 0.
+func page[T any](items []T, offset, limit int) []T {
+	start := min(offset, len(items))
+	end := len(items)
+	if limit > 0 {
+		end = min(start+limit, end)
+	}
+	return items[start:end]
+}
+
+// setPageLinks sets a Link header pointing at the next and previous pages of a list of total
+// items, as rel="next" and rel="prev" links to the request's own URL with ?offset= changed
+// and ?limit= set. A page holding the rest of the list (limit 0) has no links.
+func setPageLinks(w http.ResponseWriter, r *http.Request, offset, limit, total int) {
+	if limit <= 0 {
+		return
+	}
+
+	link := func(offset int, rel string) string {
+		query := r.URL.Query()
+		query.Set("offset", strconv.Itoa(offset))
+		query.Set("limit", strconv.Itoa(limit))
+		return fmt.Sprintf(`<%s?%s>; rel="%s"`, r.URL.Path, query.Encode(), rel)
+	}
+
+	var links []string
+	if offset+limit < total {
+		links = append(links, link(offset+limit, "next"))
+	}
+	if offset > 0 {
+		links = append(links, link(max(min(offset, total)-limit, 0), "prev"))
+	}
+	if len(links) > 0 {
+		w.Header().Set("Link", strings.Join(links, ", "))
+	}
+}
@@ -0,0 +1,35 @@
+package handlers
+
+import (
+	"log/slog"
+	"net/http"
+
+	"github.com/njoubert/nielsshootsfilm/backend/internal/services"
+)
+
+// StatsHandler handles public statistics requests.
+type StatsHandler struct {
+	albumService *services.AlbumService
+	logger       *slog.Logger
+}
+
+// NewStatsHandler creates a new stats handler.
+func NewStatsHandler(albumService *services.AlbumService, logger *slog.Logger) *StatsHandler {
+	return &StatsHandler{
+		albumService: albumService,
+		logger:       logger,
+	}
+}
+
+// GetGearStats handles GET /api/stats/gear.
+// It counts the cameras, lenses, and focal-length ranges recorded in the EXIF data of public albums.
+func (h *StatsHandler) GetGearStats(w http.ResponseWriter, r *http.Request) {
+	albums, err := h.albumService.GetAll()
+	if err != nil {
+		h.logger.Error("failed to get albums", slog.String("error", err.Error()))
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	respondJSON(w, http.StatusOK, services.ComputeGearStats(albums))
+}
@@ -6,6 +6,7 @@ import (
 	"net/http"
 	"os"
 	"path/filepath"
+	"strconv"
 	"syscall"
 
 	"github.com/njoubert/nielsshootsfilm/backend/internal/services"
@@ -14,6 +15,7 @@ import (
 // StorageHandler handles storage-related admin API endpoints.
 type StorageHandler struct {
 	configService *services.SiteConfigService
+	usageService  *services.StorageUsageService
 	uploadDir     string
 }
 
@@ -25,6 +27,11 @@ func NewStorageHandler(configService *services.SiteConfigService, uploadDir stri
 	}
 }
 
+// SetUsageService enables the per-album storage usage report.
+func (h *StorageHandler) SetUsageService(usageService *services.StorageUsageService) {
+	h.usageService = usageService
+}
+
 // StorageStats represents storage statistics.
 type StorageStats struct {
 	TotalBytes      int64           `json:"total_bytes"`
@@ -130,6 +137,54 @@ func (h *StorageHandler) GetStats(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
+// GetUsage handles GET /api/admin/storage.
+// It reports the bytes stored for each album and in total, broken down by quality level.
+func (h *StorageHandler) GetUsage(w http.ResponseWriter, r *http.Request) {
+	if h.usageService == nil {
+		http.Error(w, "Storage usage is not available", http.StatusServiceUnavailable)
+		return
+	}
+
+	report, err := h.usageService.Report()
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to calculate storage usage: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	respondJSON(w, http.StatusOK, report)
+}
+
+// MaxOverviewLargest is the most albums GetOverview will list as the largest.
+const MaxOverviewLargest = 50
+
+// GetOverview handles GET /api/admin/overview.
+// It reports total albums, photos, and stored bytes, album counts by visibility, and the
+// largest albums, as many as ?largest= asks for (default 5, at most 50).
+func (h *StorageHandler) GetOverview(w http.ResponseWriter, r *http.Request) {
+	if h.usageService == nil {
+		http.Error(w, "Overview is not available", http.StatusServiceUnavailable)
+		return
+	}
+
+	largest := services.DefaultOverviewLargest
+	if param := r.URL.Query().Get("largest"); param != "" {
+		n, err := strconv.Atoi(param)
+		if err != nil || n < 0 || n > MaxOverviewLargest {
+			http.Error(w, fmt.Sprintf("Invalid largest parameter. Must be an integer from 0 to %d", MaxOverviewLargest), http.StatusBadRequest)
+			return
+		}
+		largest = n
+	}
+
+	overview, err := h.usageService.Overview(largest)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to calculate overview: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	respondJSON(w, http.StatusOK, overview)
+}
+
 // calculateStorageBreakdown walks the upload directories and calculates total sizes.
 func (h *StorageHandler) calculateStorageBreakdown() (*StorageByType, error) {
 	breakdown := &StorageByType{}
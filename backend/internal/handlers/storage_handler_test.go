@@ -348,3 +348,83 @@ func TestStorageHandler_GetStats_DefaultMaxDiskUsage(t *testing.T) {
 	// Should default to 80% max usage = 20% reserved
 	assert.Equal(t, 20, stats.ReservedPercent, "should default to 20% reserved (80% max usage)")
 }
+
+func TestStorageHandler_GetUsage(t *testing.T) {
+	fileService, err := services.NewFileService(t.TempDir())
+	require.NoError(t, err)
+	albumService := services.NewAlbumService(fileService)
+	imageService, err := services.NewImageService(t.TempDir(), nil, nil)
+	require.NoError(t, err)
+	imageService.SetStorage(services.NewMemoryStorage())
+
+	handler := NewStorageHandler(services.NewSiteConfigService(fileService), t.TempDir())
+
+	// The report is unavailable until a usage service is configured
+	w := httptest.NewRecorder()
+	handler.GetUsage(w, httptest.NewRequest("GET", "/api/admin/storage", nil))
+	assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+
+	handler.SetUsageService(services.NewStorageUsageService(albumService, imageService))
+
+	album := &models.Album{Title: "Usage", Visibility: "public"}
+	require.NoError(t, albumService.Create(album))
+	photo, err := imageService.ProcessBytes("a.jpg", createTestJPEG(t, 64, 48))
+	require.NoError(t, err)
+	require.NoError(t, albumService.AddPhoto(album.ID, photo))
+
+	w = httptest.NewRecorder()
+	handler.GetUsage(w, httptest.NewRequest("GET", "/api/admin/storage", nil))
+	require.Equal(t, http.StatusOK, w.Code)
+
+	var report services.StorageUsageReport
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&report))
+	require.Len(t, report.Albums, 1)
+	assert.Equal(t, album.Slug, report.Albums[0].Slug)
+	assert.Equal(t, 1, report.Albums[0].Photos)
+	assert.Equal(t, photo.FileSizeOriginal, report.Albums[0].OriginalBytes)
+	assert.Equal(t, report.Albums[0].TotalBytes, report.Total.TotalBytes)
+}
+
+func TestStorageHandler_GetOverview(t *testing.T) {
+	fileService, err := services.NewFileService(t.TempDir())
+	require.NoError(t, err)
+	albumService := services.NewAlbumService(fileService)
+	imageService, err := services.NewImageService(t.TempDir(), nil, nil)
+	require.NoError(t, err)
+	imageService.SetStorage(services.NewMemoryStorage())
+
+	handler := NewStorageHandler(services.NewSiteConfigService(fileService), t.TempDir())
+
+	// The overview is unavailable until a usage service is configured
+	w := httptest.NewRecorder()
+	handler.GetOverview(w, httptest.NewRequest("GET", "/api/admin/overview", nil))
+	assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+
+	handler.SetUsageService(services.NewStorageUsageService(albumService, imageService))
+
+	for _, title := range []string{"First", "Second"} {
+		album := &models.Album{Title: title, Visibility: "public"}
+		require.NoError(t, albumService.Create(album))
+		photo, err := imageService.ProcessBytes("a.jpg", createTestJPEG(t, 64, 48))
+		require.NoError(t, err)
+		require.NoError(t, albumService.AddPhoto(album.ID, photo))
+	}
+
+	w = httptest.NewRecorder()
+	handler.GetOverview(w, httptest.NewRequest("GET", "/api/admin/overview?largest=1", nil))
+	require.Equal(t, http.StatusOK, w.Code)
+
+	var overview services.Overview
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&overview))
+	assert.Equal(t, 2, overview.Albums)
+	assert.Equal(t, 2, overview.Photos)
+	assert.Equal(t, map[string]int{"public": 2}, overview.ByVisibility)
+	require.Len(t, overview.Largest, 1)
+	assert.Positive(t, overview.TotalBytes)
+
+	for _, query := range []string{"?largest=-1", "?largest=51", "?largest=many"} {
+		w = httptest.NewRecorder()
+		handler.GetOverview(w, httptest.NewRequest("GET", "/api/admin/overview"+query, nil))
+		assert.Equal(t, http.StatusBadRequest, w.Code, query)
+	}
+}
@@ -0,0 +1,59 @@
+package handlers
+
+import "github.com/njoubert/nielsshootsfilm/backend/internal/models"
+
+// Upload result statuses.
+const (
+	UploadStatusUploaded = "uploaded"
+	UploadStatusFailed   = "failed"
+)
+
+// UploadResult is the outcome of one file in an upload batch.
+type UploadResult struct {
+	Filename string        `json:"filename"`
+	Status   string        `json:"status"`
+	Photo    *models.Photo `json:"photo,omitempty"` // Set when uploaded
+	Error    string        `json:"error,omitempty"` // Set when failed
+}
+
+// UploadSummary counts the outcomes of an upload batch.
+type UploadSummary struct {
+	Total    int `json:"total"`
+	Uploaded int `json:"uploaded"`
+	Failed   int `json:"failed"`
+}
+
+// UploadResponse reports an upload batch file by file, in the order the files were sent, so
+// clients can match each result to the file it came from. Uploaded and Errors hold the same
+// outcomes in the older flat form.
+type UploadResponse struct {
+	Results  []UploadResult `json:"results"`
+	Summary  UploadSummary  `json:"summary"`
+	Uploaded []models.Photo `json:"uploaded"`
+	Errors   []string       `json:"errors"` // "filename: reason"
+}
+
+// newUploadResponse returns an empty upload report.
+func newUploadResponse() *UploadResponse {
+	return &UploadResponse{
+		Results:  []UploadResult{},
+		Uploaded: []models.Photo{},
+		Errors:   []string{},
+	}
+}
+
+// succeeded records a file that was added to the album as photo.
+func (r *UploadResponse) succeeded(filename string, photo models.Photo) {
+	r.Results = append(r.Results, UploadResult{Filename: filename, Status: UploadStatusUploaded, Photo: &photo})
+	r.Uploaded = append(r.Uploaded, photo)
+	r.Summary.Total++
+	r.Summary.Uploaded++
+}
+
+// failed records a file that was not added to the album, and why.
+func (r *UploadResponse) failed(filename, reason string) {
+	r.Results = append(r.Results, UploadResult{Filename: filename, Status: UploadStatusFailed, Error: reason})
+	r.Errors = append(r.Errors, filename+": "+reason)
+	r.Summary.Total++
+	r.Summary.Failed++
+}
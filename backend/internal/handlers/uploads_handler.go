@@ -0,0 +1,192 @@
+package handlers
+
+import (
+	"errors"
+	"io"
+	"log/slog"
+	"mime"
+	"net/http"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/njoubert/nielsshootsfilm/backend/internal/middleware"
+	"github.com/njoubert/nielsshootsfilm/backend/internal/services"
+)
+
+// DefaultImageCacheMaxAge is how long browsers and CDNs may cache photo files without revalidating.
+const DefaultImageCacheMaxAge = 365 * 24 * time.Hour
+
+// uploadDirs lists the top-level storage prefixes that may be served publicly.
+var uploadDirs = map[string]bool{
+	"originals":  true,
+	"display":    true,
+	"thumbnails": true,
+	"covers":     true,
+}
+
+// UploadsHandler serves uploaded photo files from the image service's storage backend at /uploads/*.
+type UploadsHandler struct {
+	imageService *services.ImageService
+	albumService *services.AlbumService
+	urlSigner    *services.ImageURLSigner
+	cacheMaxAge  time.Duration
+	logger       *slog.Logger
+}
+
+// NewUploadsHandler creates a new uploads handler.
+func NewUploadsHandler(imageService *services.ImageService, logger *slog.Logger) *UploadsHandler {
+	return &UploadsHandler{
+		imageService: imageService,
+		cacheMaxAge:  DefaultImageCacheMaxAge,
+		logger:       logger,
+	}
+}
+
+// SetCacheMaxAge sets the max-age sent in Cache-Control for photo files.
+// Values below zero are treated as zero.
+func (h *UploadsHandler) SetCacheMaxAge(maxAge time.Duration) {
+	h.cacheMaxAge = max(maxAge, 0)
+}
+
+// SetAlbumService lets the handler render missing display and thumbnail versions on first
+// request, for photos uploaded in lazy derivative mode.
+func (h *UploadsHandler) SetAlbumService(albumService *services.AlbumService) {
+	h.albumService = albumService
+}
+
+// SetURLSigner makes the files the signer protects servable only through URLs it signed, so
+// other sites cannot embed them. Admins, with a session set by middleware.OptionalAuth, may
+// still fetch them unsigned.
+func (h *UploadsHandler) SetURLSigner(signer *services.ImageURLSigner) {
+	h.urlSigner = signer
+}
+
+// ServeHTTP streams the object named by the request path, e.g. "display/<id>_display.webp".
+// Responses carry a Cache-Control max-age and a content-hash ETag; a request whose
+// If-None-Match matches gets 304 Not Modified. Files protected by the URL signer need a valid
+// signature, else 403, and are cached no longer than it is accepted. Mount it behind
+// http.StripPrefix("/uploads/", ...).
+func (h *UploadsHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	key := strings.TrimPrefix(r.URL.Path, "/")
+	dir, _, _ := strings.Cut(key, "/")
+	if !uploadDirs[dir] {
+		http.NotFound(w, r)
+		return
+	}
+
+	cacheControl := "public, max-age=" + strconv.Itoa(int(h.cacheMaxAge.Seconds()))
+	if h.urlSigner != nil && h.urlSigner.Protects(key) {
+		if middleware.GetSession(r.Context()) != nil && r.URL.Query().Get("sig") == "" {
+			// Shared caches must not hand an admin's unsigned copy to anyone else
+			cacheControl = "private, max-age=" + strconv.Itoa(int(h.cacheMaxAge.Seconds()))
+		} else {
+			until, err := h.urlSigner.Verify(key, r.URL.Query(), time.Now())
+			if err != nil {
+				http.Error(w, "Forbidden: "+err.Error(), http.StatusForbidden)
+				return
+			}
+			maxAge := min(h.cacheMaxAge, time.Until(until))
+			cacheControl = "public, max-age=" + strconv.Itoa(int(max(maxAge, 0).Seconds()))
+		}
+	}
+
+	etag, err := h.imageService.ContentETag(key)
+	if errors.Is(err, services.ErrObjectNotFound) && h.generateDerivative(key) {
+		etag, err = h.imageService.ContentETag(key)
+	}
+	// A key that could escape the storage root names no file, as far as clients can tell
+	if errors.Is(err, services.ErrObjectNotFound) || errors.Is(err, services.ErrInvalidStorageKey) {
+		http.NotFound(w, r)
+		return
+	}
+	if err != nil {
+		h.logger.Error("failed to hash upload", slog.String("key", key), slog.String("error", err.Error()))
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Cache-Control", cacheControl)
+	w.Header().Set("ETag", etag)
+	if etagMatches(r.Header.Get("If-None-Match"), etag) {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+
+	reader, err := h.imageService.Storage().Stream(key)
+	if errors.Is(err, services.ErrObjectNotFound) {
+		http.NotFound(w, r)
+		return
+	}
+	if err != nil {
+		h.logger.Error("failed to stream upload", slog.String("key", key), slog.String("error", err.Error()))
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+	defer func() { _ = reader.Close() }()
+
+	if contentType := mime.TypeByExtension(path.Ext(key)); contentType != "" {
+		w.Header().Set("Content-Type", contentType)
+	}
+
+	// Local files can seek, so serve them with range support
+	if seeker, ok := reader.(io.ReadSeeker); ok {
+		http.ServeContent(w, r, key, time.Time{}, seeker)
+		return
+	}
+
+	if r.Method == http.MethodHead {
+		return
+	}
+	if _, err := io.Copy(w, reader); err != nil {
+		h.logger.Warn("failed to write upload response", slog.String("key", key), slog.String("error", err.Error()))
+	}
+}
+
+// generateDerivative renders the missing display or thumbnail version stored under key, if it
+// belongs to a photo, and records its size. Reports whether the version now exists.
+func (h *UploadsHandler) generateDerivative(key string) bool {
+	if h.albumService == nil {
+		return false
+	}
+
+	album, photo, quality, err := h.albumService.FindPhotoByDerivativeURL("/uploads/" + key)
+	if err != nil {
+		return false
+	}
+
+	size, err := h.imageService.GenerateDerivative(album, *photo, quality)
+	if err != nil {
+		h.logger.Warn("failed to generate derivative",
+			slog.String("key", key),
+			slog.String("photo_id", photo.ID),
+			slog.String("error", err.Error()))
+		return false
+	}
+
+	if err := h.albumService.RecordDerivativeSize(album.ID, photo.ID, quality, size); err != nil {
+		h.logger.Warn("failed to record derivative size",
+			slog.String("key", key),
+			slog.String("photo_id", photo.ID),
+			slog.String("error", err.Error()))
+	}
+	return true
+}
+
+// etagMatches reports whether an If-None-Match header value matches etag, comparing weakly as
+// RFC 9110 requires for If-None-Match.
+func etagMatches(ifNoneMatch, etag string) bool {
+	for _, candidate := range strings.Split(ifNoneMatch, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if candidate == "*" || strings.TrimPrefix(candidate, "W/") == etag {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,208 @@
+package handlers
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/njoubert/nielsshootsfilm/backend/internal/middleware"
+	"github.com/njoubert/nielsshootsfilm/backend/internal/models"
+	"github.com/njoubert/nielsshootsfilm/backend/internal/services"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestUploadsHandler_CacheHeaders(t *testing.T) {
+	albumHandler, albumService, _ := setupAlbumHandler(t)
+	imageService := albumHandler.imageService
+
+	album := &models.Album{Title: "Cached", Visibility: "public", AllowDownloads: true}
+	require.NoError(t, albumService.Create(album))
+	photo, err := imageService.ProcessBytes("photo.jpg", createTestJPEG(t, 64, 48))
+	require.NoError(t, err)
+	require.NoError(t, albumService.AddPhoto(album.ID, photo))
+
+	handler := NewUploadsHandler(imageService, albumHandler.logger)
+	handler.SetCacheMaxAge(time.Hour)
+	serve := func(target string, header http.Header) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("GET", target, nil)
+		for name, values := range header {
+			req.Header[name] = values
+		}
+		w := httptest.NewRecorder()
+		http.StripPrefix("/uploads/", handler).ServeHTTP(w, req)
+		return w
+	}
+
+	// Images are cacheable and carry a content-hash ETag
+	w := serve(photo.URLDisplay, nil)
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "public, max-age=3600", w.Header().Get("Cache-Control"))
+	assert.Equal(t, "image/webp", w.Header().Get("Content-Type"))
+	etag := w.Header().Get("ETag")
+	require.NotEmpty(t, etag)
+	assert.True(t, strings.HasPrefix(etag, `"`) && strings.HasSuffix(etag, `"`), etag)
+	assert.NotEmpty(t, w.Body.Bytes())
+
+	// The same file always has the same ETag
+	assert.Equal(t, etag, serve(photo.URLDisplay, nil).Header().Get("ETag"))
+
+	// A matching If-None-Match gets 304 with no body
+	for _, ifNoneMatch := range []string{etag, "W/" + etag, `"other", ` + etag, "*"} {
+		w = serve(photo.URLDisplay, http.Header{"If-None-Match": {ifNoneMatch}})
+		assert.Equal(t, http.StatusNotModified, w.Code, ifNoneMatch)
+		assert.Empty(t, w.Body.Bytes())
+		assert.Equal(t, etag, w.Header().Get("ETag"))
+	}
+	w = serve(photo.URLDisplay, http.Header{"If-None-Match": {`"stale"`}})
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	// Unknown files and directories are not served
+	assert.Equal(t, http.StatusNotFound, serve("/uploads/display/missing.webp", nil).Code)
+	assert.Equal(t, http.StatusNotFound, serve("/uploads/secrets/photo.jpg", nil).Code)
+
+	// ZIP downloads are built per request and never cached
+	w = httptest.NewRecorder()
+	albumHandler.DownloadAlbum(w, newSlugRequest("GET", "/api/albums/"+album.Slug+"/download?quality=display", album.Slug))
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "no-cache", w.Header().Get("Cache-Control"))
+	assert.Empty(t, w.Header().Get("ETag"))
+}
+
+func TestUploadsHandler_InvalidKeys(t *testing.T) {
+	albumHandler, _, _ := setupAlbumHandler(t)
+	var logs strings.Builder
+	handler := NewUploadsHandler(albumHandler.imageService, slog.New(slog.NewTextHandler(&logs, nil)))
+
+	for _, target := range []string{"/uploads/originals/../x", "/uploads/originals//x.jpg", "/uploads/display/./x.webp"} {
+		w := httptest.NewRecorder()
+		http.StripPrefix("/uploads/", handler).ServeHTTP(w, httptest.NewRequest("GET", target, nil))
+		assert.Equal(t, http.StatusNotFound, w.Code, target)
+	}
+	assert.Empty(t, logs.String())
+}
+
+func TestUploadsHandler_GeneratesPendingDerivatives(t *testing.T) {
+	albumHandler, albumService, _ := setupAlbumHandler(t)
+	imageService := albumHandler.imageService
+	storage := imageService.Storage()
+
+	album := &models.Album{Title: "Lazy", Visibility: "public"}
+	require.NoError(t, albumService.Create(album))
+	photo, err := imageService.ProcessBytes("photo.jpg", createTestJPEG(t, 64, 48))
+	require.NoError(t, err)
+
+	// Stand in for a lazy upload: only the original is stored
+	require.NoError(t, storage.Delete(strings.TrimPrefix(photo.URLDisplay, "/uploads/")))
+	require.NoError(t, storage.Delete(strings.TrimPrefix(photo.URLThumbnail, "/uploads/")))
+	photo.FileSizeDisplay, photo.FileSizeThumbnail, photo.DerivativesPending = 0, 0, true
+	require.NoError(t, albumService.AddPhoto(album.ID, photo))
+
+	handler := NewUploadsHandler(imageService, albumHandler.logger)
+	serve := func(target string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		http.StripPrefix("/uploads/", handler).ServeHTTP(w, httptest.NewRequest("GET", target, nil))
+		return w
+	}
+
+	// Without the album service there is nothing to render from
+	assert.Equal(t, http.StatusNotFound, serve(photo.URLDisplay).Code)
+
+	handler.SetAlbumService(albumService)
+	w := serve(photo.URLDisplay)
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.NotEmpty(t, w.Body.Bytes())
+
+	stored, err := albumService.GetByID(album.ID)
+	require.NoError(t, err)
+	assert.Equal(t, int64(w.Body.Len()), stored.Photos[0].FileSizeDisplay)
+	assert.True(t, stored.Photos[0].DerivativesPending, "thumbnail is still pending")
+
+	require.Equal(t, http.StatusOK, serve(photo.URLThumbnail).Code)
+	stored, err = albumService.GetByID(album.ID)
+	require.NoError(t, err)
+	assert.Positive(t, stored.Photos[0].FileSizeThumbnail)
+	assert.False(t, stored.Photos[0].DerivativesPending)
+
+	// Files that belong to no photo are still not found
+	assert.Equal(t, http.StatusNotFound, serve("/uploads/display/unknown_display.webp").Code)
+}
+
+func TestUploadsHandler_SignedURLs(t *testing.T) {
+	albumHandler, albumService, _ := setupAlbumHandler(t)
+	imageService := albumHandler.imageService
+
+	album := &models.Album{Title: "Signed", Visibility: "public"}
+	require.NoError(t, albumService.Create(album))
+	photo, err := imageService.ProcessBytes("photo.jpg", createTestJPEG(t, 64, 48))
+	require.NoError(t, err)
+	require.NoError(t, albumService.AddPhoto(album.ID, photo))
+
+	signer, err := services.NewImageURLSigner("test-secret", time.Hour, 15*time.Minute, []string{"originals", "display"})
+	require.NoError(t, err)
+	albumHandler.SetImageURLSigner(signer)
+
+	adminHash, err := services.HashPassword("admin-pass")
+	require.NoError(t, err)
+	authService := services.NewAuthService("admin", adminHash, time.Hour)
+	sessionID, err := authService.Authenticate("admin", "admin-pass")
+	require.NoError(t, err)
+
+	handler := NewUploadsHandler(imageService, albumHandler.logger)
+	handler.SetURLSigner(signer)
+	uploads := middleware.OptionalAuth(authService)(http.StripPrefix("/uploads/", handler))
+	serve := func(target string, cookies ...*http.Cookie) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("GET", target, nil)
+		for _, cookie := range cookies {
+			req.AddCookie(cookie)
+		}
+		w := httptest.NewRecorder()
+		uploads.ServeHTTP(w, req)
+		return w
+	}
+
+	// Visitors get signed URLs for the protected folders only
+	w := httptest.NewRecorder()
+	albumHandler.GetPublicAlbum(w, newSlugRequest("GET", "/api/public/albums/"+album.Slug, album.Slug))
+	require.Equal(t, http.StatusOK, w.Code)
+	var visible models.Album
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &visible))
+	require.Len(t, visible.Photos, 1)
+	signed := visible.Photos[0]
+	assert.True(t, strings.HasPrefix(signed.URLDisplay, photo.URLDisplay+"?"), signed.URLDisplay)
+	assert.True(t, strings.HasPrefix(signed.URLOriginal, photo.URLOriginal+"?"), signed.URLOriginal)
+	assert.Equal(t, photo.URLThumbnail, signed.URLThumbnail)
+
+	// Valid signatures are served, cached no longer than they are accepted
+	w = serve(signed.URLDisplay)
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.NotEmpty(t, w.Body.Bytes())
+	cacheControl := w.Header().Get("Cache-Control")
+	require.True(t, strings.HasPrefix(cacheControl, "public, max-age="), cacheControl)
+	maxAge, err := strconv.Atoi(strings.TrimPrefix(cacheControl, "public, max-age="))
+	require.NoError(t, err)
+	assert.Positive(t, maxAge)
+	assert.LessOrEqual(t, maxAge, int((2*time.Hour + 15*time.Minute).Seconds()))
+	assert.Equal(t, http.StatusOK, serve(signed.URLOriginal).Code)
+	assert.Equal(t, http.StatusOK, serve(signed.URLThumbnail).Code)
+
+	// Unsigned, expired, and tampered URLs are refused
+	assert.Equal(t, http.StatusForbidden, serve(photo.URLDisplay).Code)
+	expired := signer.Sign(photo.URLDisplay, time.Now().Add(-3*time.Hour))
+	assert.Equal(t, http.StatusForbidden, serve(expired).Code)
+	_, query, _ := strings.Cut(signed.URLDisplay, "?")
+	assert.Equal(t, http.StatusForbidden, serve(photo.URLOriginal+"?"+query).Code)
+	tampered := strings.Replace(signed.URLDisplay, "expires=", "expires=9", 1)
+	assert.Equal(t, http.StatusForbidden, serve(tampered).Code)
+
+	// Admins may fetch files unsigned, but shared caches may not keep those copies
+	w = serve(photo.URLDisplay, &http.Cookie{Name: "photoadmin_session", Value: sessionID})
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.True(t, strings.HasPrefix(w.Header().Get("Cache-Control"), "private, "), w.Header().Get("Cache-Control"))
+	assert.Equal(t, http.StatusForbidden, serve(photo.URLDisplay, &http.Cookie{Name: "photoadmin_session", Value: "forged"}).Code)
+}
@@ -0,0 +1,47 @@
+package middleware
+
+import (
+	"log/slog"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/njoubert/nielsshootsfilm/backend/internal/models"
+	"github.com/njoubert/nielsshootsfilm/backend/internal/services"
+)
+
+// SetRobotsTag asks search engines not to index a response about album, unless the album
+// is indexable.
+func SetRobotsTag(w http.ResponseWriter, album *models.Album) {
+	if !album.Indexable() {
+		w.Header().Set("X-Robots-Tag", "noindex")
+	}
+}
+
+// AlbumAccess middleware rejects requests for restricted albums that do not carry a valid
+// access token, and marks responses for albums that are not indexable with X-Robots-Tag. The album is looked up from the {slug} URL parameter, within the {namespace}
+// parameter on namespaced routes; unknown albums are passed through so the handler can report
+// them. It must be mounted with Group or With, since URL parameters are only available after routing.
+func AlbumAccess(albumService *services.AlbumService, albumAuthService *services.AlbumAuthService, logger *slog.Logger) func(next http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			album, err := albumService.GetByNamespacedSlug(chi.URLParam(r, "namespace"), chi.URLParam(r, "slug"))
+			if err != nil {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			SetRobotsTag(w, album)
+			if !albumAuthService.HasAccess(r, album) {
+				logger.Warn("album access denied",
+					slog.String("album_id", album.ID),
+					slog.String("path", r.URL.Path),
+					slog.String("request_id", GetRequestID(r.Context())),
+				)
+				http.Error(w, "Unauthorized", http.StatusUnauthorized)
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
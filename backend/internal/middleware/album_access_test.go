@@ -0,0 +1,74 @@
+package middleware
+
+import (
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/njoubert/nielsshootsfilm/backend/internal/models"
+	"github.com/njoubert/nielsshootsfilm/backend/internal/services"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAlbumAccess(t *testing.T) {
+	fileService, err := services.NewFileService(t.TempDir())
+	require.NoError(t, err)
+	albumService := services.NewAlbumService(fileService)
+	albumAuthService, err := services.NewAlbumAuthService("secret", time.Hour)
+	require.NoError(t, err)
+
+	restricted := &models.Album{Title: "Client", Visibility: "unlisted", AllowedEmails: []string{"client@example.com"}}
+	require.NoError(t, albumService.Create(restricted))
+	open := &models.Album{Title: "Open", Visibility: "public"}
+	require.NoError(t, albumService.Create(open))
+
+	// Mounted per route, as URL parameters are only available after routing
+	r := chi.NewRouter()
+	r.With(AlbumAccess(albumService, albumAuthService, slog.New(slog.NewTextHandler(io.Discard, nil)))).Get("/api/albums/{slug}/download", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+
+	serve := func(target string, cookie *http.Cookie) int {
+		req := httptest.NewRequest("GET", target, nil)
+		if cookie != nil {
+			req.AddCookie(cookie)
+		}
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w.Code
+	}
+	robotsTag := func(target string) string {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest("GET", target, nil))
+		return w.Header().Get("X-Robots-Tag")
+	}
+
+	// Restricted albums need a valid token
+	assert.Equal(t, http.StatusUnauthorized, serve("/api/albums/client/download", nil))
+	assert.Equal(t, http.StatusUnauthorized, serve("/api/albums/client/download?token=forged.token", nil))
+
+	token := albumAuthService.IssueEmailToken(restricted.ID, "client@example.com")
+	assert.Equal(t, http.StatusOK, serve("/api/albums/client/download?token="+token, nil))
+	assert.Equal(t, http.StatusOK, serve("/api/albums/client/download", &http.Cookie{Name: services.AlbumAccessCookieName(restricted.ID), Value: token}))
+
+	// A token for an address that is not listed is rejected
+	stranger := albumAuthService.IssueEmailToken(restricted.ID, "stranger@example.com")
+	assert.Equal(t, http.StatusUnauthorized, serve("/api/albums/client/download?token="+stranger, nil))
+
+	// Unrestricted and unknown albums pass through
+	assert.Equal(t, http.StatusOK, serve("/api/albums/open/download", nil))
+	assert.Equal(t, http.StatusOK, serve("/api/albums/missing/download", nil))
+
+	// Restricted albums and albums that opt out are never indexed
+	noIndex := &models.Album{Title: "Proofs", Visibility: "public", NoIndex: true}
+	require.NoError(t, albumService.Create(noIndex))
+	assert.Equal(t, "noindex", robotsTag("/api/albums/client/download"))
+	assert.Equal(t, "noindex", robotsTag("/api/albums/proofs/download"))
+	assert.Empty(t, robotsTag("/api/albums/open/download"))
+	assert.Empty(t, robotsTag("/api/albums/missing/download"))
+}
@@ -40,12 +40,31 @@ func Auth(authService *services.AuthService, logger *slog.Logger) func(next http
 			}
 
 			// Add session to context
-			ctx := context.WithValue(r.Context(), sessionKey, session)
-			next.ServeHTTP(w, r.WithContext(ctx))
+			next.ServeHTTP(w, r.WithContext(WithSession(r.Context(), session)))
 		})
 	}
 }
 
+// OptionalAuth middleware adds the admin session to the request context when the request
+// carries a valid session cookie, and otherwise lets the request through as a visitor.
+func OptionalAuth(authService *services.AuthService) func(next http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if cookie, err := r.Cookie("photoadmin_session"); err == nil {
+				if session, err := authService.ValidateSession(cookie.Value); err == nil {
+					r = r.WithContext(WithSession(r.Context(), session))
+				}
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// WithSession returns a copy of ctx carrying an authenticated admin session.
+func WithSession(ctx context.Context, session *services.Session) context.Context {
+	return context.WithValue(ctx, sessionKey, session)
+}
+
 // GetSession retrieves the session from context.
 func GetSession(ctx context.Context) *services.Session {
 	if session, ok := ctx.Value(sessionKey).(*services.Session); ok {
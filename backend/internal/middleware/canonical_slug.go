@@ -0,0 +1,63 @@
+package middleware
+
+import (
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/njoubert/nielsshootsfilm/backend/internal/services"
+)
+
+// CanonicalAlbumSlug redirects GET and HEAD requests for an album under a non-canonical URL,
+// such as /api/albums/Summer-Roll/ for the album summer-roll, to its canonical URL with
+// 301 Moved Permanently. The {slug} URL parameter is matched to an album ignoring case, and a
+// trailing slash is dropped; the query string is kept. Unknown albums and other methods pass
+// through. Like AlbumAccess, it must be mounted with Group or With, and only on routes that
+// take a {slug}, so routes addressing albums by ID are never affected.
+func CanonicalAlbumSlug(albumService *services.AlbumService) func(next http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			rctx := chi.RouteContext(r.Context())
+			if (r.Method != http.MethodGet && r.Method != http.MethodHead) || rctx == nil {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			slug := chi.URLParam(r, "slug")
+			canonical := slug
+			if album, err := albumService.GetByNamespacedSlugFold(chi.URLParam(r, "namespace"), slug); err == nil {
+				canonical = album.Slug
+			}
+			trailingSlash := len(r.URL.Path) > 1 && strings.HasSuffix(r.URL.Path, "/")
+			if canonical == slug && !trailingSlash {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			target := canonicalPath(rctx, canonical)
+			if r.URL.RawQuery != "" {
+				target += "?" + r.URL.RawQuery
+			}
+			http.Redirect(w, r, target, http.StatusMovedPermanently)
+		})
+	}
+}
+
+// canonicalPath rebuilds the request path from its route pattern, with the given slug in
+// place of the {slug} parameter and no trailing slash.
+func canonicalPath(rctx *chi.Context, slug string) string {
+	segments := strings.Split(rctx.RoutePattern(), "/")
+	for i, segment := range segments {
+		if !strings.HasPrefix(segment, "{") || !strings.HasSuffix(segment, "}") {
+			continue
+		}
+		name, _, _ := strings.Cut(strings.Trim(segment, "{}"), ":")
+		value := rctx.URLParam(name)
+		if name == "slug" {
+			value = slug
+		}
+		segments[i] = url.PathEscape(value)
+	}
+	return strings.TrimSuffix(strings.Join(segments, "/"), "/")
+}
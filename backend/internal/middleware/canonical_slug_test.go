@@ -0,0 +1,75 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	chimiddleware "github.com/go-chi/chi/v5/middleware"
+	"github.com/njoubert/nielsshootsfilm/backend/internal/models"
+	"github.com/njoubert/nielsshootsfilm/backend/internal/services"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCanonicalAlbumSlug(t *testing.T) {
+	fileService, err := services.NewFileService(t.TempDir())
+	require.NoError(t, err)
+	albumService := services.NewAlbumService(fileService)
+
+	album := &models.Album{Title: "Summer Roll", Visibility: "public"}
+	require.NoError(t, albumService.Create(album))
+	namespaced := &models.Album{Title: "Summer Roll", Namespace: "ana", Visibility: "public"}
+	require.NoError(t, albumService.Create(namespaced))
+
+	// Routed like the server: trailing slashes are stripped before routing, and only slug
+	// routes are wrapped
+	ok := func(w http.ResponseWriter, r *http.Request) { w.WriteHeader(http.StatusOK) }
+	r := chi.NewRouter()
+	r.Use(chimiddleware.StripSlashes)
+	canonical := CanonicalAlbumSlug(albumService)
+	r.With(canonical).Get("/api/public/albums/{slug}", ok)
+	r.With(canonical).Get("/api/albums/{slug}/photos/{photoId}/download", ok)
+	r.With(canonical).Get("/api/a/{namespace}/albums/{slug}/download", ok)
+	r.With(canonical).Post("/api/albums/{slug}/request-access", ok)
+	r.Get("/api/albums/{id}/duplicates", ok)
+
+	serve := func(method, target string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(method, target, nil))
+		return w
+	}
+
+	tests := []struct {
+		target   string
+		location string // Empty if served without a redirect
+	}{
+		{"/api/public/albums/summer-roll", ""},
+		{"/api/public/albums/Summer-Roll", "/api/public/albums/summer-roll"},
+		{"/api/public/albums/summer-roll/", "/api/public/albums/summer-roll"},
+		{"/api/public/albums/SUMMER-ROLL/", "/api/public/albums/summer-roll"},
+		{"/api/albums/Summer-Roll/photos/Photo-1/download?quality=display", "/api/albums/summer-roll/photos/Photo-1/download?quality=display"},
+		{"/api/a/ana/albums/Summer-Roll/download/", "/api/a/ana/albums/summer-roll/download"},
+
+		// Unknown albums are left for the handler to report
+		{"/api/public/albums/Winter-Roll", ""},
+
+		// Routes addressing albums by ID are untouched
+		{"/api/albums/" + album.ID + "/duplicates/", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.target, func(t *testing.T) {
+			w := serve("GET", tt.target)
+			if tt.location == "" {
+				assert.Equal(t, http.StatusOK, w.Code)
+				return
+			}
+			assert.Equal(t, http.StatusMovedPermanently, w.Code)
+			assert.Equal(t, tt.location, w.Header().Get("Location"))
+		})
+	}
+
+	// Only reads are redirected, since clients may replay other methods as GET
+	assert.Equal(t, http.StatusOK, serve("POST", "/api/albums/Summer-Roll/request-access").Code)
+}
@@ -0,0 +1,155 @@
+package middleware
+
+import (
+	"compress/flate"
+	"compress/gzip"
+	"io"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// compressor is the common interface of gzip and flate writers.
+type compressor interface {
+	io.WriteCloser
+	Flush() error
+	Reset(w io.Writer)
+}
+
+// compressorPools reuse writers per encoding, in order of preference.
+var compressorPools = []struct {
+	encoding string
+	pool     *sync.Pool
+}{
+	{"gzip", &sync.Pool{New: func() any { return gzip.NewWriter(io.Discard) }}},
+	{"deflate", &sync.Pool{New: func() any {
+		w, _ := flate.NewWriter(io.Discard, flate.DefaultCompression)
+		return w
+	}}},
+}
+
+// compressibleTypes are the media types worth compressing. Images and archives are already compressed.
+var compressibleTypes = map[string]bool{
+	"application/json":       true,
+	"application/javascript": true,
+	"application/xml":        true,
+	"image/svg+xml":          true,
+}
+
+// Compress gzip- or deflate-encodes text and JSON responses for clients that accept it.
+// Images, ZIPs, and responses that already set Content-Encoding pass through untouched,
+// and flushes reach the client so streamed downloads keep streaming.
+func Compress(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Add("Vary", "Accept-Encoding")
+
+		encoding, pool := negotiateEncoding(r.Header.Get("Accept-Encoding"))
+		if pool == nil || r.Method == http.MethodHead {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		cw := &compressResponseWriter{ResponseWriter: w, encoding: encoding, pool: pool}
+		defer cw.close()
+		next.ServeHTTP(cw, r)
+	})
+}
+
+// negotiateEncoding picks the preferred supported encoding the client accepts, ignoring q=0.
+func negotiateEncoding(acceptEncoding string) (string, *sync.Pool) {
+	accepted := map[string]bool{}
+	for _, part := range strings.Split(acceptEncoding, ",") {
+		name, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		if q, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			if value, err := strconv.ParseFloat(q, 64); err == nil && value == 0 {
+				continue
+			}
+		}
+		accepted[strings.ToLower(strings.TrimSpace(name))] = true
+	}
+
+	for _, candidate := range compressorPools {
+		if accepted[candidate.encoding] || accepted["*"] {
+			return candidate.encoding, candidate.pool
+		}
+	}
+	return "", nil
+}
+
+// isCompressible reports whether a Content-Type value is worth compressing.
+func isCompressible(contentType string) bool {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return false
+	}
+	return strings.HasPrefix(mediaType, "text/") || compressibleTypes[mediaType]
+}
+
+// compressResponseWriter decides on the first write whether to compress the body.
+type compressResponseWriter struct {
+	http.ResponseWriter
+	encoding    string
+	pool        *sync.Pool
+	writer      compressor // Set once the response is being compressed
+	wroteHeader bool
+}
+
+func (cw *compressResponseWriter) WriteHeader(code int) {
+	if cw.wroteHeader {
+		return
+	}
+	cw.wroteHeader = true
+
+	header := cw.Header()
+	if header.Get("Content-Encoding") == "" && code != http.StatusNoContent &&
+		code != http.StatusNotModified && isCompressible(header.Get("Content-Type")) {
+		header.Del("Content-Length")
+		header.Set("Content-Encoding", cw.encoding)
+		cw.writer = cw.pool.Get().(compressor)
+		cw.writer.Reset(cw.ResponseWriter)
+	}
+
+	cw.ResponseWriter.WriteHeader(code)
+}
+
+func (cw *compressResponseWriter) Write(p []byte) (int, error) {
+	if !cw.wroteHeader {
+		// Match net/http, which sniffs the type of bodies written without one
+		if cw.Header().Get("Content-Type") == "" {
+			cw.Header().Set("Content-Type", http.DetectContentType(p))
+		}
+		cw.WriteHeader(http.StatusOK)
+	}
+	if cw.writer != nil {
+		return cw.writer.Write(p)
+	}
+	return cw.ResponseWriter.Write(p)
+}
+
+// Flush sends any buffered compressed data, then flushes the underlying writer.
+func (cw *compressResponseWriter) Flush() {
+	if cw.writer != nil {
+		_ = cw.writer.Flush()
+	}
+	if flusher, ok := cw.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+// Unwrap lets http.ResponseController reach the underlying writer.
+func (cw *compressResponseWriter) Unwrap() http.ResponseWriter {
+	return cw.ResponseWriter
+}
+
+// close finishes the compressed stream and returns the writer to its pool.
+func (cw *compressResponseWriter) close() {
+	if cw.writer == nil {
+		return
+	}
+	_ = cw.writer.Close()
+	cw.writer.Reset(io.Discard)
+	cw.pool.Put(cw.writer)
+	cw.writer = nil
+}
@@ -0,0 +1,150 @@
+package middleware
+
+import (
+	"bytes"
+	"compress/flate"
+	"compress/gzip"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCompress_JSON(t *testing.T) {
+	body := `{"albums":[` + strings.Repeat(`{"title":"Harbour at dusk"},`, 200) + `{}]}`
+	handler := Compress(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Content-Length", "999")
+		_, _ = w.Write([]byte(body))
+	}))
+
+	// Compressed for clients that accept gzip
+	req := httptest.NewRequest("GET", "/api/albums", nil)
+	req.Header.Set("Accept-Encoding", "gzip, deflate, br")
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+
+	assert.Equal(t, "gzip", w.Header().Get("Content-Encoding"))
+	assert.Equal(t, "Accept-Encoding", w.Header().Get("Vary"))
+	assert.Empty(t, w.Header().Get("Content-Length"), "the length changes when compressed")
+	assert.Less(t, w.Body.Len(), len(body))
+
+	reader, err := gzip.NewReader(w.Body)
+	require.NoError(t, err)
+	decoded, err := io.ReadAll(reader)
+	require.NoError(t, err)
+	assert.Equal(t, body, string(decoded))
+
+	// Deflate when gzip is not accepted
+	req = httptest.NewRequest("GET", "/api/albums", nil)
+	req.Header.Set("Accept-Encoding", "gzip;q=0, deflate")
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+
+	assert.Equal(t, "deflate", w.Header().Get("Content-Encoding"))
+	decoded, err = io.ReadAll(flate.NewReader(w.Body))
+	require.NoError(t, err)
+	assert.Equal(t, body, string(decoded))
+
+	// Plain for clients that don't, but caches still learn the response varies
+	req = httptest.NewRequest("GET", "/api/albums", nil)
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+
+	assert.Empty(t, w.Header().Get("Content-Encoding"))
+	assert.Equal(t, "Accept-Encoding", w.Header().Get("Vary"))
+	assert.Equal(t, body, w.Body.String())
+}
+
+func TestCompress_SniffsUntypedText(t *testing.T) {
+	handler := Compress(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("plain text error message\n"))
+	}))
+
+	req := httptest.NewRequest("GET", "/", nil)
+	req.Header.Set("Accept-Encoding", "gzip")
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+
+	assert.Equal(t, "text/plain; charset=utf-8", w.Header().Get("Content-Type"))
+	assert.Equal(t, "gzip", w.Header().Get("Content-Encoding"))
+}
+
+func TestCompress_SkipsStreamedZIP(t *testing.T) {
+	chunks := [][]byte{[]byte("PK\x03\x04first-entry"), []byte("second-entry"), []byte("PK\x05\x06")}
+	flushes := 0
+	handler := Compress(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/zip")
+		w.Header().Set("Content-Disposition", `attachment; filename="album.zip"`)
+		w.WriteHeader(http.StatusOK)
+
+		flusher, ok := w.(http.Flusher)
+		require.True(t, ok, "streaming handlers must still be able to flush")
+		for _, chunk := range chunks {
+			_, _ = w.Write(chunk)
+			flusher.Flush()
+			flushes++
+		}
+	}))
+
+	req := httptest.NewRequest("GET", "/api/albums/a/download", nil)
+	req.Header.Set("Accept-Encoding", "gzip")
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+
+	assert.Empty(t, w.Header().Get("Content-Encoding"))
+	assert.True(t, w.Flushed)
+	assert.Equal(t, 3, flushes)
+	assert.Equal(t, bytes.Join(chunks, nil), w.Body.Bytes(), "ZIP bytes pass through unchanged")
+}
+
+func TestCompress_SkipsImagesAndEncodedResponses(t *testing.T) {
+	tests := []struct {
+		name   string
+		header map[string]string
+	}{
+		{"image", map[string]string{"Content-Type": "image/webp"}},
+		{"already encoded", map[string]string{"Content-Type": "application/json", "Content-Encoding": "br"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler := Compress(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				for k, v := range tt.header {
+					w.Header().Set(k, v)
+				}
+				_, _ = w.Write([]byte("payload"))
+			}))
+
+			req := httptest.NewRequest("GET", "/", nil)
+			req.Header.Set("Accept-Encoding", "gzip")
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, req)
+
+			assert.Equal(t, tt.header["Content-Encoding"], w.Header().Get("Content-Encoding"))
+			assert.Equal(t, "payload", w.Body.String())
+		})
+	}
+}
+
+func TestCompress_FlushesThroughMiddlewareChain(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	handler := Logger(logger)(Compress(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/zip")
+		_, _ = w.Write([]byte("PK"))
+		w.(http.Flusher).Flush()
+	})))
+
+	req := httptest.NewRequest("GET", "/api/albums/a/download", nil)
+	req.Header.Set("Accept-Encoding", "gzip")
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+
+	assert.True(t, w.Flushed, "flushes should reach the client through the logger")
+	assert.Equal(t, "PK", w.Body.String())
+}
@@ -0,0 +1,76 @@
+package middleware
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// DefaultMaxConcurrentDownloads is how many album ZIP downloads may be built and streamed at
+// once before further downloads are turned away.
+const DefaultMaxConcurrentDownloads = 4
+
+// DownloadRetryAfter is how long clients turned away by a full download limiter are asked to
+// wait before trying again.
+const DownloadRetryAfter = 30 * time.Second
+
+// ConcurrencyLimiter hands out a fixed number of slots to requests in progress.
+type ConcurrencyLimiter struct {
+	slots chan struct{}
+}
+
+// NewConcurrencyLimiter creates a limiter allowing limit requests at once. A limit below 1
+// gives a nil limiter, which allows any number.
+func NewConcurrencyLimiter(limit int) *ConcurrencyLimiter {
+	if limit < 1 {
+		return nil
+	}
+	return &ConcurrencyLimiter{slots: make(chan struct{}, limit)}
+}
+
+// TryAcquire takes a slot if one is free, without waiting, and reports whether it did.
+// Every successful call must be paired with Release.
+func (l *ConcurrencyLimiter) TryAcquire() bool {
+	if l == nil {
+		return true
+	}
+	select {
+	case l.slots <- struct{}{}:
+		return true
+	default:
+		return false
+	}
+}
+
+// Release frees a slot taken by TryAcquire.
+func (l *ConcurrencyLimiter) Release() {
+	if l != nil {
+		<-l.slots
+	}
+}
+
+// InUse returns how many slots are taken.
+func (l *ConcurrencyLimiter) InUse() int {
+	if l == nil {
+		return 0
+	}
+	return len(l.slots)
+}
+
+// ConcurrencyLimit middleware lets at most the limiter's number of requests through at once,
+// across all routes sharing the limiter. Requests over the limit are answered 503 with a
+// Retry-After rather than queued, so waiting clients hold no connection. The slot is freed
+// when the handler returns, including when the client disconnects mid-stream or it panics.
+func ConcurrencyLimit(limiter *ConcurrencyLimiter, retryAfter time.Duration) func(next http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if !limiter.TryAcquire() {
+				w.Header().Set("Retry-After", strconv.Itoa(max(int(retryAfter.Seconds()), 1)))
+				http.Error(w, "Too many downloads in progress, try again shortly", http.StatusServiceUnavailable)
+				return
+			}
+			defer limiter.Release()
+			next.ServeHTTP(w, r)
+		})
+	}
+}
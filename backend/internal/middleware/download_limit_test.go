@@ -0,0 +1,97 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestConcurrencyLimit(t *testing.T) {
+	limiter := NewConcurrencyLimiter(2)
+	started := make(chan struct{})
+	finish := make(chan struct{})
+	handler := ConcurrencyLimit(limiter, 30*time.Second)(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			started <- struct{}{}
+			// Streams until told to finish or the client goes away
+			select {
+			case <-finish:
+				w.WriteHeader(http.StatusOK)
+			case <-r.Context().Done():
+			}
+		}),
+	)
+
+	serve := func(ctx context.Context) <-chan *httptest.ResponseRecorder {
+		done := make(chan *httptest.ResponseRecorder, 1)
+		go func() {
+			req := httptest.NewRequest("GET", "/api/albums/x/download", nil).WithContext(ctx)
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, req)
+			done <- w
+		}()
+		return done
+	}
+
+	// Two downloads fill the limiter
+	first := serve(context.Background())
+	<-started
+	ctx, disconnect := context.WithCancel(context.Background())
+	second := serve(ctx)
+	<-started
+	assert.Equal(t, 2, limiter.InUse())
+
+	// A third is turned away without reaching the handler
+	w := <-serve(context.Background())
+	assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+	assert.Equal(t, "30", w.Header().Get("Retry-After"))
+
+	// Finishing a download frees its slot
+	finish <- struct{}{}
+	assert.Equal(t, http.StatusOK, (<-first).Code)
+	assert.Equal(t, 1, limiter.InUse())
+
+	// So does a client disconnecting mid-download
+	disconnect()
+	<-second
+	assert.Equal(t, 0, limiter.InUse())
+
+	third := serve(context.Background())
+	<-started
+	finish <- struct{}{}
+	assert.Equal(t, http.StatusOK, (<-third).Code)
+	assert.Equal(t, 0, limiter.InUse())
+}
+
+func TestConcurrencyLimit_ReleasesOnPanic(t *testing.T) {
+	limiter := NewConcurrencyLimiter(1)
+	handler := ConcurrencyLimit(limiter, time.Second)(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			panic("zip writer failed")
+		}),
+	)
+
+	require.Panics(t, func() {
+		handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/", nil))
+	})
+	assert.Equal(t, 0, limiter.InUse())
+}
+
+func TestConcurrencyLimit_Disabled(t *testing.T) {
+	limiter := NewConcurrencyLimiter(0)
+	assert.Nil(t, limiter)
+	handler := ConcurrencyLimit(limiter, time.Second)(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusOK)
+		}),
+	)
+
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest("GET", "/", nil))
+	assert.Equal(t, http.StatusOK, w.Code)
+}
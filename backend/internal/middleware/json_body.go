@@ -0,0 +1,36 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+)
+
+// DefaultMaxJSONBodySize is the largest JSON request body accepted when no limit is configured,
+// enough for albums with thousands of photos.
+const DefaultMaxJSONBodySize = 4 << 20 // 4MB
+
+const jsonBodyKey contextKey = "json_body"
+
+// JSONBodyOptions control how handlers decode JSON request bodies.
+type JSONBodyOptions struct {
+	MaxBytes int64 // Larger bodies are rejected with 413
+	Strict   bool  // Reject bodies with fields the endpoint does not know, rather than ignoring them
+}
+
+// JSONBody middleware sets the options handlers decode JSON request bodies with. Without it,
+// bodies are limited to DefaultMaxJSONBodySize and unknown fields are ignored.
+func JSONBody(opts JSONBodyOptions) func(next http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), jsonBodyKey, opts)))
+		})
+	}
+}
+
+// GetJSONBodyOptions retrieves the JSON body options from context, or the defaults.
+func GetJSONBodyOptions(ctx context.Context) JSONBodyOptions {
+	if opts, ok := ctx.Value(jsonBodyKey).(JSONBodyOptions); ok {
+		return opts
+	}
+	return JSONBodyOptions{MaxBytes: DefaultMaxJSONBodySize}
+}
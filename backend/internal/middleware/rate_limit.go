@@ -0,0 +1,168 @@
+package middleware
+
+import (
+	"errors"
+	"log/slog"
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/njoubert/nielsshootsfilm/backend/internal/services"
+)
+
+// APIKeyHeader is the request header carrying a public API key.
+const APIKeyHeader = "X-API-Key"
+
+// DefaultAnonymousRateLimit is the requests per minute allowed to each client address
+// calling the public read endpoints without an API key.
+const DefaultAnonymousRateLimit = 60
+
+// DefaultPasswordCheckRateLimit is the requests per minute allowed to each client address
+// checking album passwords, whether visitors entering them or the admin API.
+const DefaultPasswordCheckRateLimit = 10
+
+// DefaultSelectionRateLimit is the requests per minute allowed to each client address
+// saving photo selections from albums.
+const DefaultSelectionRateLimit = 10
+
+// DefaultAccessLinkRateLimit is the requests per minute allowed to each client address
+// requesting magic access links for albums, each of which may send an email.
+const DefaultAccessLinkRateLimit = 5
+
+// DefaultClientUploadRateLimit is the requests per minute allowed to each client address
+// uploading photos to albums that accept client uploads.
+const DefaultClientUploadRateLimit = 10
+
+// rateLimitWindow is the length of a rate limit window.
+const rateLimitWindow = time.Minute
+
+// RateLimiter counts requests per client in fixed one-minute windows.
+type RateLimiter struct {
+	mu        sync.Mutex
+	windows   map[string]*rateWindow
+	lastSweep time.Time
+	now       func() time.Time
+}
+
+type rateWindow struct {
+	start time.Time
+	count int
+}
+
+// NewRateLimiter creates a new rate limiter.
+func NewRateLimiter() *RateLimiter {
+	return &RateLimiter{
+		windows: make(map[string]*rateWindow),
+		now:     time.Now,
+	}
+}
+
+// Allow records a request from client and reports whether it is within limit requests for the
+// current window, along with how many requests remain and when the window resets.
+func (l *RateLimiter) Allow(client string, limit int) (ok bool, remaining int, reset time.Time) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.now()
+
+	// Forget clients whose windows have ended, at most once per window
+	if now.Sub(l.lastSweep) >= rateLimitWindow {
+		for key, window := range l.windows {
+			if now.Sub(window.start) >= rateLimitWindow {
+				delete(l.windows, key)
+			}
+		}
+		l.lastSweep = now
+	}
+
+	window, exists := l.windows[client]
+	if !exists || now.Sub(window.start) >= rateLimitWindow {
+		window = &rateWindow{start: now}
+		l.windows[client] = window
+	}
+	reset = window.start.Add(rateLimitWindow)
+
+	if window.count >= limit {
+		return false, 0, reset
+	}
+	window.count++
+	return true, limit - window.count, reset
+}
+
+// PublicAPIRateLimit middleware rate limits the public read endpoints. Requests carrying an
+// API key in the X-API-Key header are limited per key, at the key's own rate; an unknown or
+// revoked key is rejected. Requests without a key are limited per client address at
+// anonymousLimit requests per minute, or not at all if anonymousLimit is not positive.
+func PublicAPIRateLimit(limiter *RateLimiter, apiKeyService *services.APIKeyService, anonymousLimit int, logger *slog.Logger) func(next http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			client, limit := "", anonymousLimit
+			if secret := r.Header.Get(APIKeyHeader); secret != "" {
+				key, err := apiKeyService.Authenticate(secret)
+				if errors.Is(err, services.ErrInvalidAPIKey) {
+					logger.Warn("invalid API key",
+						slog.String("path", r.URL.Path),
+						slog.String("request_id", GetRequestID(r.Context())),
+					)
+					http.Error(w, "Invalid API key", http.StatusUnauthorized)
+					return
+				}
+				if err != nil {
+					logger.Error("failed to check API key", slog.String("error", err.Error()))
+					http.Error(w, "Internal server error", http.StatusInternalServerError)
+					return
+				}
+				client, limit = "key:"+key.ID, key.RateLimit
+			} else {
+				if anonymousLimit <= 0 {
+					next.ServeHTTP(w, r)
+					return
+				}
+				client = "addr:" + clientAddr(r)
+			}
+
+			if limiter.allowRequest(w, client, limit) {
+				next.ServeHTTP(w, r)
+			}
+		})
+	}
+}
+
+// RateLimit middleware limits each client address to limit requests per minute on the routes
+// it wraps. Each use should get its own limiter, so routes do not share a budget.
+func RateLimit(limiter *RateLimiter, limit int) func(next http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if limiter.allowRequest(w, "addr:"+clientAddr(r), limit) {
+				next.ServeHTTP(w, r)
+			}
+		})
+	}
+}
+
+// allowRequest counts a request from client, sets the X-RateLimit headers, and reports whether
+// the request may proceed. Otherwise it has already answered 429 with a Retry-After.
+func (l *RateLimiter) allowRequest(w http.ResponseWriter, client string, limit int) bool {
+	ok, remaining, reset := l.Allow(client, limit)
+	w.Header().Set("X-RateLimit-Limit", strconv.Itoa(limit))
+	w.Header().Set("X-RateLimit-Remaining", strconv.Itoa(remaining))
+	w.Header().Set("X-RateLimit-Reset", strconv.FormatInt(reset.Unix(), 10))
+	if !ok {
+		retryAfter := max(int(math.Ceil(reset.Sub(l.now()).Seconds())), 1)
+		w.Header().Set("Retry-After", strconv.Itoa(retryAfter))
+		http.Error(w, "Rate limit exceeded", http.StatusTooManyRequests)
+	}
+	return ok
+}
+
+// clientAddr returns the host part of the request's remote address.
+func clientAddr(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
@@ -0,0 +1,130 @@
+package middleware
+
+import (
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/njoubert/nielsshootsfilm/backend/internal/services"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRateLimiter_Allow(t *testing.T) {
+	limiter := NewRateLimiter()
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	limiter.now = func() time.Time { return now }
+
+	for i := range 3 {
+		ok, remaining, reset := limiter.Allow("a", 3)
+		assert.True(t, ok)
+		assert.Equal(t, 2-i, remaining)
+		assert.Equal(t, now.Add(time.Minute), reset)
+	}
+	ok, remaining, _ := limiter.Allow("a", 3)
+	assert.False(t, ok)
+	assert.Equal(t, 0, remaining)
+
+	// Other clients have their own windows
+	ok, _, _ = limiter.Allow("b", 3)
+	assert.True(t, ok)
+
+	// A new window starts once the minute is up
+	now = now.Add(time.Minute)
+	ok, remaining, _ = limiter.Allow("a", 3)
+	assert.True(t, ok)
+	assert.Equal(t, 2, remaining)
+}
+
+func TestPublicAPIRateLimit(t *testing.T) {
+	fileService, err := services.NewFileService(t.TempDir())
+	require.NoError(t, err)
+	apiKeyService := services.NewAPIKeyService(fileService)
+	mirror, mirrorSecret, err := apiKeyService.Issue("Mirror", 5)
+	require.NoError(t, err)
+	revoked, revokedSecret, err := apiKeyService.Issue("Former partner", 5)
+	require.NoError(t, err)
+	require.NoError(t, apiKeyService.Revoke(revoked.ID))
+
+	limiter := NewRateLimiter()
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	limiter.now = func() time.Time { return now }
+
+	handler := PublicAPIRateLimit(limiter, apiKeyService, 2, slog.New(slog.NewTextHandler(io.Discard, nil)))(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusOK)
+		}),
+	)
+	serve := func(remoteAddr, key string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("GET", "/api/public/albums", nil)
+		req.RemoteAddr = remoteAddr
+		if key != "" {
+			req.Header.Set(APIKeyHeader, key)
+		}
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, req)
+		return w
+	}
+
+	// Unknown and revoked keys are rejected rather than treated as anonymous
+	assert.Equal(t, http.StatusUnauthorized, serve("192.0.2.1:1000", "nsf_unknown").Code)
+	assert.Equal(t, http.StatusUnauthorized, serve("192.0.2.1:1000", revokedSecret).Code)
+
+	// Anonymous clients get the stricter limit, per address
+	assert.Equal(t, http.StatusOK, serve("192.0.2.1:1000", "").Code)
+	assert.Equal(t, http.StatusOK, serve("192.0.2.1:2000", "").Code)
+	w := serve("192.0.2.1:3000", "")
+	assert.Equal(t, http.StatusTooManyRequests, w.Code)
+	assert.Equal(t, "2", w.Header().Get("X-RateLimit-Limit"))
+	assert.Equal(t, "0", w.Header().Get("X-RateLimit-Remaining"))
+	assert.Equal(t, "60", w.Header().Get("Retry-After"))
+	assert.Equal(t, http.StatusOK, serve("198.51.100.7:1000", "").Code)
+
+	// The key has its own, larger allowance, whichever address it is used from
+	for i := range mirror.RateLimit {
+		w := serve("192.0.2.1:1000", mirrorSecret)
+		require.Equal(t, http.StatusOK, w.Code, "request %d", i+1)
+		assert.Equal(t, "5", w.Header().Get("X-RateLimit-Limit"))
+	}
+	assert.Equal(t, http.StatusTooManyRequests, serve("198.51.100.7:1000", mirrorSecret).Code)
+
+	// Allowances refill in the next window
+	now = now.Add(time.Minute)
+	assert.Equal(t, http.StatusOK, serve("192.0.2.1:1000", mirrorSecret).Code)
+	assert.Equal(t, http.StatusOK, serve("192.0.2.1:1000", "").Code)
+}
+
+func TestRateLimit(t *testing.T) {
+	limiter := NewRateLimiter()
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	limiter.now = func() time.Time { return now }
+
+	handler := RateLimit(limiter, 2)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	serve := func(remoteAddr string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("POST", "/", nil)
+		req.RemoteAddr = remoteAddr
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, req)
+		return w
+	}
+
+	w := serve("192.0.2.1:1234")
+	assert.Equal(t, http.StatusNoContent, w.Code)
+	assert.Equal(t, "2", w.Header().Get("X-RateLimit-Limit"))
+	assert.Equal(t, "1", w.Header().Get("X-RateLimit-Remaining"))
+
+	// The port does not matter; the address does
+	assert.Equal(t, http.StatusNoContent, serve("192.0.2.1:5678").Code)
+	w = serve("192.0.2.1:1234")
+	assert.Equal(t, http.StatusTooManyRequests, w.Code)
+	assert.Equal(t, "60", w.Header().Get("Retry-After"))
+	assert.Equal(t, http.StatusNoContent, serve("192.0.2.2:1234").Code)
+
+	now = now.Add(time.Minute)
+	assert.Equal(t, http.StatusNoContent, serve("192.0.2.1:1234").Code)
+}
@@ -0,0 +1,82 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseTrustedProxies(t *testing.T) {
+	proxies, err := ParseTrustedProxies(DefaultTrustedProxies + ", 10.0.0.0/8")
+	require.NoError(t, err)
+	require.Len(t, proxies, 3)
+	assert.Equal(t, "127.0.0.1/32", proxies[0].String())
+	assert.Equal(t, "::1/128", proxies[1].String())
+	assert.Equal(t, "10.0.0.0/8", proxies[2].String())
+
+	proxies, err = ParseTrustedProxies("")
+	require.NoError(t, err)
+	assert.Empty(t, proxies)
+
+	_, err = ParseTrustedProxies("localhost")
+	assert.Error(t, err)
+}
+
+func TestRealIP(t *testing.T) {
+	trusted, err := ParseTrustedProxies("127.0.0.1,10.0.0.0/8")
+	require.NoError(t, err)
+
+	var seen string
+	handler := RealIP(trusted)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		seen = clientAddr(r)
+	}))
+	request := func(remoteAddr string, headers map[string]string) string {
+		req := httptest.NewRequest("GET", "/api/public/albums", nil)
+		req.RemoteAddr = remoteAddr
+		for name, value := range headers {
+			req.Header.Set(name, value)
+		}
+		handler.ServeHTTP(httptest.NewRecorder(), req)
+		return seen
+	}
+
+	// The local proxy's X-Real-IP names the visitor
+	assert.Equal(t, "203.0.113.7", request("127.0.0.1:50000", map[string]string{"X-Real-IP": "203.0.113.7"}))
+
+	// Without it, the nearest untrusted X-Forwarded-For entry does; entries a client put
+	// ahead of it are ignored
+	assert.Equal(t, "203.0.113.7", request("127.0.0.1:50000", map[string]string{"X-Forwarded-For": "198.51.100.1, 203.0.113.7, 10.1.2.3"}))
+
+	// Visitors behind the proxy get their own address rather than the proxy's
+	assert.Equal(t, "127.0.0.1", request("127.0.0.1:50000", nil))
+
+	// Headers from untrusted peers are not believed
+	assert.Equal(t, "198.51.100.9", request("198.51.100.9:40000", map[string]string{"X-Real-IP": "203.0.113.7", "X-Forwarded-For": "203.0.113.7"}))
+
+	// Malformed headers leave the address alone
+	assert.Equal(t, "127.0.0.1", request("127.0.0.1:50000", map[string]string{"X-Forwarded-For": "not-an-ip"}))
+}
+
+func TestRealIP_SeparatesRateLimitBuckets(t *testing.T) {
+	trusted, err := ParseTrustedProxies(DefaultTrustedProxies)
+	require.NoError(t, err)
+	handler := RealIP(trusted)(RateLimit(NewRateLimiter(), 1)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})))
+	request := func(visitor string) int {
+		req := httptest.NewRequest("POST", "/api/albums/verify-password", nil)
+		req.RemoteAddr = "127.0.0.1:50000"
+		req.Header.Set("X-Real-IP", visitor)
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, req)
+		return w.Code
+	}
+
+	// One visitor using up their budget does not lock out another behind the same proxy
+	assert.Equal(t, http.StatusOK, request("203.0.113.7"))
+	assert.Equal(t, http.StatusTooManyRequests, request("203.0.113.7"))
+	assert.Equal(t, http.StatusOK, request("198.51.100.1"))
+}
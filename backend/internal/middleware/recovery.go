@@ -4,29 +4,78 @@ import (
 	"log/slog"
 	"net/http"
 	"runtime/debug"
+
+	"github.com/njoubert/nielsshootsfilm/backend/internal/apierror"
 )
 
-// Recoverer middleware recovers from panics and logs them.
+// Recoverer middleware recovers from panics, logs them with the request ID and stack trace,
+// and responds with a JSON 500 so the server keeps serving other requests.
+// It must be installed after RequestID so the request ID is available.
 func Recoverer(logger *slog.Logger) func(next http.Handler) http.Handler {
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			rw := &recoveryWriter{ResponseWriter: w}
+
 			defer func() {
-				if err := recover(); err != nil {
-					// Log the panic with stack trace
-					logger.Error("panic recovered",
-						slog.Any("error", err),
-						slog.String("stack", string(debug.Stack())),
-						slog.String("request_id", GetRequestID(r.Context())),
-						slog.String("method", r.Method),
-						slog.String("path", r.URL.Path),
-					)
-
-					// Return 500 Internal Server Error
-					http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+				err := recover()
+				if err == nil {
+					return
+				}
+
+				// http.ErrAbortHandler deliberately aborts the response; let net/http handle it
+				if err == http.ErrAbortHandler {
+					panic(err)
+				}
+
+				requestID := GetRequestID(r.Context())
+
+				// Log the panic with stack trace
+				logger.Error("panic recovered",
+					slog.Any("error", err),
+					slog.String("stack", string(debug.Stack())),
+					slog.String("request_id", requestID),
+					slog.String("method", r.Method),
+					slog.String("path", r.URL.Path),
+				)
+
+				// If the handler already started the response, the status can't be changed
+				if rw.wroteHeader {
+					return
 				}
+
+				apierror.Write(w, http.StatusInternalServerError, "Internal Server Error", requestID)
 			}()
 
-			next.ServeHTTP(w, r)
+			next.ServeHTTP(rw, r)
 		})
 	}
 }
+
+// recoveryWriter records whether the response has been started.
+type recoveryWriter struct {
+	http.ResponseWriter
+	wroteHeader bool
+}
+
+func (rw *recoveryWriter) WriteHeader(code int) {
+	rw.wroteHeader = true
+	rw.ResponseWriter.WriteHeader(code)
+}
+
+func (rw *recoveryWriter) Write(b []byte) (int, error) {
+	rw.wroteHeader = true
+	return rw.ResponseWriter.Write(b)
+}
+
+// Flush supports streaming responses such as ZIP downloads.
+func (rw *recoveryWriter) Flush() {
+	if flusher, ok := rw.ResponseWriter.(http.Flusher); ok {
+		rw.wroteHeader = true
+		flusher.Flush()
+	}
+}
+
+// Unwrap exposes the underlying writer to http.ResponseController.
+func (rw *recoveryWriter) Unwrap() http.ResponseWriter {
+	return rw.ResponseWriter
+}
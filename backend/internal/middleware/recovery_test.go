@@ -0,0 +1,84 @@
+package middleware
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/njoubert/nielsshootsfilm/backend/internal/apierror"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRecoverer_ReturnsJSON500(t *testing.T) {
+	var logs bytes.Buffer
+	logger := slog.New(slog.NewJSONHandler(&logs, nil))
+
+	panicking := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		panic("boom")
+	})
+	handler := RequestID(Recoverer(logger)(panicking))
+
+	req := httptest.NewRequest("GET", "/api/albums", nil)
+	req.Header.Set("X-Request-ID", "req-123")
+	w := httptest.NewRecorder()
+
+	require.NotPanics(t, func() { handler.ServeHTTP(w, req) })
+
+	assert.Equal(t, http.StatusInternalServerError, w.Code)
+	assert.Equal(t, "application/json", w.Header().Get("Content-Type"))
+	assert.Equal(t, "req-123", w.Header().Get("X-Request-ID"))
+
+	var body apierror.Response
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&body))
+	assert.Equal(t, "Internal Server Error", body.Error)
+	assert.Equal(t, "req-123", body.RequestID)
+
+	// The log entry carries the panic value, request ID, and a stack trace
+	var entry map[string]any
+	require.NoError(t, json.Unmarshal(logs.Bytes(), &entry))
+	assert.Equal(t, "panic recovered", entry["msg"])
+	assert.Equal(t, "boom", entry["error"])
+	assert.Equal(t, "req-123", entry["request_id"])
+	assert.Contains(t, entry["stack"], "recovery_test.go")
+
+	// The server keeps handling requests afterwards
+	ok := RequestID(Recoverer(logger)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	})))
+	w = httptest.NewRecorder()
+	ok.ServeHTTP(w, httptest.NewRequest("GET", "/api/healthz", nil))
+	assert.Equal(t, http.StatusNoContent, w.Code)
+}
+
+func TestRecoverer_ResponseAlreadyStarted(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(&bytes.Buffer{}, nil))
+
+	handler := Recoverer(logger)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+		_, _ = w.Write([]byte("partial"))
+		panic("mid-stream")
+	}))
+
+	w := httptest.NewRecorder()
+	require.NotPanics(t, func() { handler.ServeHTTP(w, httptest.NewRequest("GET", "/", nil)) })
+
+	// Nothing is appended to a response that was already in flight
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "partial", w.Body.String())
+}
+
+func TestRecoverer_AbortHandlerPropagates(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(&bytes.Buffer{}, nil))
+
+	handler := Recoverer(logger)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		panic(http.ErrAbortHandler)
+	}))
+
+	assert.PanicsWithValue(t, http.ErrAbortHandler, func() {
+		handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/", nil))
+	})
+}
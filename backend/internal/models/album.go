@@ -3,47 +3,145 @@ package models
 import (
 	"encoding/json"
 	"errors"
+	"fmt"
+	"net/mail"
+	"net/url"
+	"regexp"
+	"slices"
+	"strings"
 	"time"
 )
 
 // Album represents a photo album.
 type Album struct {
-	ID             string     `json:"id"`
-	Slug           string     `json:"slug"`
-	Title          string     `json:"title"`
-	Subtitle       string     `json:"subtitle,omitempty"`
-	Description    string     `json:"description,omitempty"`
-	CoverPhotoID   string     `json:"cover_photo_id,omitempty"`
-	Visibility     string     `json:"visibility"` // public, unlisted, password_protected
-	PasswordHash   string     `json:"password_hash,omitempty"`
-	ExpirationDate *time.Time `json:"expiration_date,omitempty"`
-	AllowDownloads bool       `json:"allow_downloads"`
-	Order          int        `json:"order"`
-	ThemeOverride  string     `json:"theme_override,omitempty"` // system, light, dark
-	CreatedAt      time.Time  `json:"created_at"`
-	UpdatedAt      time.Time  `json:"updated_at"`
-	AlbumStartDate *time.Time `json:"date_of_album_start,omitempty"`
-	AlbumEndDate   *time.Time `json:"date_of_album_end,omitempty"`
-	Photos         []Photo    `json:"photos"`
+	ID                 string            `json:"id"`
+	Slug               string            `json:"slug"`
+	Namespace          string            `json:"namespace,omitempty"` // Scopes the slug, e.g. per photographer; empty is the default namespace
+	Title              string            `json:"title"`
+	Subtitle           string            `json:"subtitle,omitempty"`
+	Description        string            `json:"description,omitempty"`
+	CoverPhotoID       string            `json:"cover_photo_id,omitempty"`  // The first of CoverPhotoIDs, kept for clients that know a single cover
+	CoverPhotoIDs      []string          `json:"cover_photo_ids,omitempty"` // Covers shown in turn on the index, in order
+	CoverURL           string            `json:"cover_url,omitempty"`       // Clean cover rendering when gallery displays are watermarked
+	Visibility         string            `json:"visibility"`                // public, unlisted, password_protected
+	PasswordHash       string            `json:"password_hash,omitempty"`
+	AllowedEmails      []string          `json:"allowed_emails,omitempty"` // Client addresses that may request a magic access link
+	ExpirationDate     *time.Time        `json:"expiration_date,omitempty"`
+	ScheduledChanges   []ScheduledChange `json:"scheduled_changes,omitempty"` // Visibility changes applied at set times, soonest first
+	AllowDownloads     bool              `json:"allow_downloads"`
+	DownloadQualities  []string          `json:"download_qualities,omitempty"` // Qualities visitors may download at when AllowDownloads is set; empty allows all
+	ScrubGPSOnDownload bool              `json:"scrub_gps_on_download"`        // Strip GPS tags from downloaded originals, keeping other EXIF
+	ShareLocations     bool              `json:"share_locations"`              // Keep photo GPS positions in the album data, which is public; off drops them on save
+	DownloadFilename   string            `json:"download_filename,omitempty"`  // Template naming downloaded photos, e.g. "{album}-{index}-{title}"; overrides the site's
+	WatermarkEnabled   bool              `json:"watermark_enabled"`
+	WatermarkCover     bool              `json:"watermark_cover"` // Stamp the cover too (default false keeps it clean)
+	NoIndex            bool              `json:"no_index"`        // Ask search engines not to index the album; restricted albums never are
+	Order              int               `json:"order"`
+	Pinned             bool              `json:"pinned"` // Featured: listed ahead of unpinned albums
+	Layout             string            `json:"layout,omitempty"`
+	ThemeOverride      string            `json:"theme_override,omitempty"`      // system, light, dark
+	ThumbnailFit       string            `json:"thumbnail_fit,omitempty"`       // Overrides the site's portfolio thumbnail_fit: cover, contain
+	AccentColor        string            `json:"accent_color,omitempty"`        // Gallery accent color as #rgb or #rrggbb, overriding the site theme's
+	DisplayMaxEdge     int               `json:"display_max_edge,omitempty"`    // Longest edge of display versions in pixels, overriding the default
+	MinUploadEdgePx    *int              `json:"min_upload_edge_px,omitempty"`  // Overrides the site's storage.min_upload_edge_px; 0 accepts any size
+	UploadPolicy       string            `json:"upload_policy,omitempty"`       // Who may upload photos: admin (default), clients
+	ClientUploads      int               `json:"client_uploads,omitempty"`      // Photos clients have uploaded, deleted ones included; counts toward the client upload limits
+	ClientUploadBytes  int64             `json:"client_upload_bytes,omitempty"` // Bytes of photos clients have uploaded, as received
+	CreatedAt          time.Time         `json:"created_at"`
+	UpdatedAt          time.Time         `json:"updated_at"`
+	LastPhotoAddedAt   *time.Time        `json:"last_photo_added_at,omitempty"` // When a photo was last added; unchanged by edits and deletes
+	AlbumStartDate     *time.Time        `json:"date_of_album_start,omitempty"`
+	AlbumEndDate       *time.Time        `json:"date_of_album_end,omitempty"`
+	FilmStocks         []string          `json:"film_stocks,omitempty"` // Distinct film stocks across photos, derived on save
+	Tags               []string          `json:"tags,omitempty"`        // Distinct photo tags, sorted, derived on save
+	Sections           []AlbumSection    `json:"sections,omitempty"`    // Optional grouping of photos, e.g. by capture day
+	Photos             []Photo           `json:"photos"`
 }
 
 // Photo represents a single photo in an album.
 type Photo struct {
-	ID                string    `json:"id"`
-	FilenameOriginal  string    `json:"filename_original"`
-	URLOriginal       string    `json:"url_original"`
-	URLDisplay        string    `json:"url_display"`
-	URLThumbnail      string    `json:"url_thumbnail"`
-	Caption           string    `json:"caption,omitempty"`
-	AltText           string    `json:"alt_text,omitempty"`
-	Order             int       `json:"order"`
-	Width             int       `json:"width"`
-	Height            int       `json:"height"`
-	FileSizeOriginal  int64     `json:"file_size_original"`
-	FileSizeDisplay   int64     `json:"file_size_display"`
-	FileSizeThumbnail int64     `json:"file_size_thumbnail"`
-	EXIF              *EXIF     `json:"exif,omitempty"`
-	UploadedAt        time.Time `json:"uploaded_at"`
+	ID                 string    `json:"id"`
+	Slug               string    `json:"slug,omitempty"` // Permalink slug, derived from the title or filename and unique within the album
+	FilenameOriginal   string    `json:"filename_original"`
+	MediaType          string    `json:"media_type,omitempty"`  // MIME type of the original, e.g. image/jpeg
+	IsAnimated         bool      `json:"is_animated,omitempty"` // Multi-frame GIF; display keeps the animation
+	URLOriginal        string    `json:"url_original"`
+	URLDisplay         string    `json:"url_display"`
+	URLThumbnail       string    `json:"url_thumbnail"`
+	URLThumbnailPadded string    `json:"url_thumbnail_padded,omitempty"` // Thumbnail padded to the configured aspect ratio, if padding is on
+	Title              string    `json:"title,omitempty"`
+	Caption            string    `json:"caption,omitempty"`
+	AltText            string    `json:"alt_text,omitempty"`
+	Order              int       `json:"order"`
+	Width              int       `json:"width"`
+	Height             int       `json:"height"`
+	FileSizeOriginal   int64     `json:"file_size_original"`
+	OriginalDownscaled bool      `json:"original_downscaled,omitempty"` // The stored original was shrunk to the configured max edge on upload
+	FileSizeDisplay    int64     `json:"file_size_display"`
+	FileSizeThumbnail  int64     `json:"file_size_thumbnail"`
+	DerivativesPending bool      `json:"derivatives_pending,omitempty"` // Uploaded in lazy mode; display and thumbnail are rendered on first request
+	EXIF               *EXIF     `json:"exif,omitempty"`
+	PerceptualHash     string    `json:"perceptual_hash,omitempty"` // 64-bit difference hash as hex, for near-duplicate detection
+	Blurhash           string    `json:"blurhash,omitempty"`        // Blurred placeholder to show while the thumbnail loads (https://blurha.sh)
+	Palette            []string  `json:"palette,omitempty"`         // The most common colors as #rrggbb, most common first
+	ContentHash        string    `json:"content_hash,omitempty"`    // SHA-256 of the uploaded file as hex; repeat uploads to an album share its stored files
+	Exposure           *Exposure `json:"exposure,omitempty"`        // Brightness statistics, for flagging badly exposed photos
+	FilmStock          string    `json:"film_stock,omitempty"`
+	FilmStockSource    string    `json:"film_stock_source,omitempty"` // exif, manual
+	Tags               []string  `json:"tags,omitempty"`              // Lowercase, trimmed, and unique, normalized on save
+	BuyURL             string    `json:"buy_url,omitempty"`           // Where to buy a print of the photo, an absolute http or https URL
+	Downloadable       bool      `json:"downloadable"`                // Included in ZIPs and single-photo downloads
+	UploadedAt         time.Time `json:"uploaded_at"`
+	ClientUploaded     bool      `json:"client_uploaded,omitempty"` // Uploaded by a client rather than the admin
+}
+
+// UnmarshalJSON decodes a photo, treating a missing downloadable field as true
+// so photos stored before the field existed stay downloadable.
+func (p *Photo) UnmarshalJSON(data []byte) error {
+	type photoAlias Photo
+	alias := photoAlias{Downloadable: true}
+	if err := json.Unmarshal(data, &alias); err != nil {
+		return err
+	}
+	*p = Photo(alias)
+	return nil
+}
+
+// PhotoMetadataFields are the descriptive photo fields that can be required before publishing,
+// in the order they are reported.
+var PhotoMetadataFields = []string{"title", "alt", "caption"}
+
+// metadataField returns the value of a named descriptive field.
+func (p *Photo) metadataField(name string) (string, bool) {
+	switch name {
+	case "title":
+		return p.Title, true
+	case "alt":
+		return p.AltText, true
+	case "caption":
+		return p.Caption, true
+	default:
+		return "", false
+	}
+}
+
+// MissingMetadata returns which of the named fields are empty or blank, in the order given.
+// Unknown field names are ignored.
+func (p *Photo) MissingMetadata(fields []string) []string {
+	missing := []string{}
+	for _, name := range fields {
+		if value, ok := p.metadataField(name); ok && strings.TrimSpace(value) == "" {
+			missing = append(missing, name)
+		}
+	}
+	return missing
+}
+
+// Exposure holds brightness statistics measured from a photo's pixels on upload.
+type Exposure struct {
+	MeanLuminance     float64 `json:"mean_luminance"`     // 0 (black) to 1 (white)
+	ShadowsClipped    float64 `json:"shadows_clipped"`    // Fraction of pixels crushed to black
+	HighlightsClipped float64 `json:"highlights_clipped"` // Fraction of pixels blown out to white
 }
 
 // EXIF represents photo metadata.
@@ -55,6 +153,48 @@ type EXIF struct {
 	ShutterSpeed string     `json:"shutter_speed,omitempty"`
 	FocalLength  string     `json:"focal_length,omitempty"`
 	DateTaken    *time.Time `json:"date_taken,omitempty"`
+	Description  string     `json:"description,omitempty"` // ImageDescription or UserComment text
+	Latitude     *float64   `json:"latitude,omitempty"`    // GPS position in decimal degrees, north positive
+	Longitude    *float64   `json:"longitude,omitempty"`   // East positive; set together with Latitude
+}
+
+// Location returns the photo's GPS position, and false if it has none or the recorded one
+// is out of range.
+func (e *EXIF) Location() (latitude, longitude float64, ok bool) {
+	if e == nil || e.Latitude == nil || e.Longitude == nil || !ValidCoordinates(*e.Latitude, *e.Longitude) {
+		return 0, 0, false
+	}
+	return *e.Latitude, *e.Longitude, true
+}
+
+// WithholdLocations drops every photo's GPS position unless the album shares locations.
+// Album data is served publicly, so positions are only kept for albums that opt in.
+func (a *Album) WithholdLocations() {
+	if a.ShareLocations {
+		return
+	}
+	for i := range a.Photos {
+		a.Photos[i].EXIF = a.Photos[i].EXIF.WithoutLocation()
+	}
+}
+
+// WithoutLocation returns the EXIF data without its GPS position, as a copy if it had one,
+// or nil if nothing else is left.
+func (e *EXIF) WithoutLocation() *EXIF {
+	if e == nil || (e.Latitude == nil && e.Longitude == nil) {
+		return e
+	}
+	withheld := *e
+	withheld.Latitude, withheld.Longitude = nil, nil
+	if withheld == (EXIF{}) {
+		return nil
+	}
+	return &withheld
+}
+
+// ValidCoordinates reports whether a latitude and longitude in decimal degrees are in range.
+func ValidCoordinates(latitude, longitude float64) bool {
+	return latitude >= -90 && latitude <= 90 && longitude >= -180 && longitude <= 180
 }
 
 // AlbumCollection represents the root albums.json structure.
@@ -70,14 +210,244 @@ func (a *Album) Validate() error {
 	if a.Slug == "" {
 		return errors.New("album slug is required")
 	}
+	if a.Namespace != "" && !namespacePattern.MatchString(a.Namespace) {
+		return errors.New("album namespace must be lowercase letters, digits, and inner hyphens")
+	}
 	if a.Visibility != "public" && a.Visibility != "unlisted" && a.Visibility != "password_protected" {
 		return errors.New("album visibility must be public, unlisted, or password_protected")
 	}
 	// Note: We don't validate password_hash here because it may be set via a separate API call
 	// after album creation. The set-password endpoint handles password setting.
+	for _, email := range a.AllowedEmails {
+		// Plain addresses only, since access links are matched against the address itself
+		if addr, err := mail.ParseAddress(email); err != nil || addr.Address != strings.TrimSpace(email) {
+			return fmt.Errorf("invalid allowed email %q", email)
+		}
+	}
+	if a.ThumbnailFit != "" && a.ThumbnailFit != ThumbnailFitCover && a.ThumbnailFit != ThumbnailFitContain {
+		return errors.New("album thumbnail_fit must be cover or contain")
+	}
+	if a.AccentColor != "" && !hexColorPattern.MatchString(a.AccentColor) {
+		return errors.New("album accent_color must be a hex color like #ff6b6b")
+	}
+	if a.DisplayMaxEdge != 0 && (a.DisplayMaxEdge < MinDisplayMaxEdge || a.DisplayMaxEdge > MaxDisplayMaxEdge) {
+		return fmt.Errorf("album display_max_edge must be between %d and %d pixels", MinDisplayMaxEdge, MaxDisplayMaxEdge)
+	}
+	if a.UploadPolicy != "" && a.UploadPolicy != UploadPolicyAdmin && a.UploadPolicy != UploadPolicyClients {
+		return errors.New("album upload_policy must be admin or clients")
+	}
+	for _, quality := range a.DownloadQualities {
+		if !slices.Contains(DownloadQualities, quality) {
+			return fmt.Errorf("album download quality %q must be thumbnail, display, or original", quality)
+		}
+	}
+	if err := ValidateDownloadFilenameTemplate(a.DownloadFilename); err != nil {
+		return err
+	}
+	if len(a.CoverPhotoIDs) > MaxCoverPhotos {
+		return fmt.Errorf("album may have at most %d cover photos", MaxCoverPhotos)
+	}
+	if a.MinUploadEdgePx != nil && *a.MinUploadEdgePx < 0 {
+		return errors.New("album min_upload_edge_px must not be negative")
+	}
+	if err := validateSchedule(a.ScheduledChanges); err != nil {
+		return err
+	}
+	for _, photo := range a.Photos {
+		if err := validatePhotoTags(photo.Tags); err != nil {
+			return err
+		}
+		if photo.BuyURL != "" && !isWebURL(photo.BuyURL) {
+			return fmt.Errorf("photo buy_url %q must be an absolute http or https URL", photo.BuyURL)
+		}
+		if photo.EXIF != nil && (photo.EXIF.Latitude != nil || photo.EXIF.Longitude != nil) {
+			if _, _, ok := photo.EXIF.Location(); !ok {
+				return errors.New("photo GPS position needs a latitude between -90 and 90 and a longitude between -180 and 180")
+			}
+		}
+	}
+	for _, section := range a.Sections {
+		if strings.TrimSpace(section.Title) == "" {
+			return errors.New("album section title is required")
+		}
+	}
 	return nil
 }
 
+// Bounds for Album.DisplayMaxEdge.
+const (
+	MinDisplayMaxEdge = 640
+	MaxDisplayMaxEdge = 8192
+)
+
+// DownloadQualities lists the quality levels photos can be downloaded at, lowest first.
+var DownloadQualities = []string{"thumbnail", "display", "original"}
+
+// AllowsDownloadQuality reports whether the album's photos may be downloaded at quality.
+// It does not check AllowDownloads.
+func (a *Album) AllowsDownloadQuality(quality string) bool {
+	return len(a.DownloadQualities) == 0 || slices.Contains(a.DownloadQualities, quality)
+}
+
+// DownloadQuality returns preferred if the album allows downloads at it, or else the highest
+// quality it allows. Downloads that do not ask for a quality use it.
+func (a *Album) DownloadQuality(preferred string) string {
+	if a.AllowsDownloadQuality(preferred) {
+		return preferred
+	}
+	for _, quality := range slices.Backward(DownloadQualities) {
+		if slices.Contains(a.DownloadQualities, quality) {
+			return quality
+		}
+	}
+	return preferred
+}
+
+// MaxCoverPhotos is the most cover photos an album's carousel may have.
+const MaxCoverPhotos = 10
+
+// isWebURL reports whether s is an absolute http or https URL with a host.
+func isWebURL(s string) bool {
+	parsed, err := url.Parse(s)
+	return err == nil && (parsed.Scheme == "http" || parsed.Scheme == "https") && parsed.Host != ""
+}
+
+// namespacePattern matches valid namespaces, which appear as a URL path segment.
+var namespacePattern = regexp.MustCompile(`^[a-z0-9]+(-[a-z0-9]+)*$`)
+
+// hexColorPattern matches CSS hex colors in short (#rgb) or full (#rrggbb) form.
+var hexColorPattern = regexp.MustCompile(`^#([0-9a-fA-F]{3}|[0-9a-fA-F]{6})$`)
+
+// PublicPath returns the album's page on the public site: /albums/{slug}, or
+// /a/{namespace}/{slug} for namespaced albums.
+func (a *Album) PublicPath() string {
+	if a.Namespace == "" {
+		return "/albums/" + a.Slug
+	}
+	return "/a/" + a.Namespace + "/" + a.Slug
+}
+
+// APIPath returns the base of the album's public API endpoints: /api/albums/{slug}, or
+// /api/a/{namespace}/albums/{slug} for namespaced albums.
+func (a *Album) APIPath() string {
+	if a.Namespace == "" {
+		return "/api/albums/" + a.Slug
+	}
+	return "/api/a/" + a.Namespace + "/albums/" + a.Slug
+}
+
+// RequiresAccessToken reports whether visitors need an access token to view the album,
+// either from its password or from an emailed access link.
+func (a *Album) RequiresAccessToken() bool {
+	return a.Visibility == "password_protected" || len(a.AllowedEmails) > 0
+}
+
+// Upload policies for Album.UploadPolicy.
+const (
+	UploadPolicyAdmin   = "admin"   // Only the admin uploads photos
+	UploadPolicyClients = "clients" // Clients holding an access token may contribute photos too
+)
+
+// AcceptsClientUploads reports whether clients with an access token may upload photos to the
+// album, besides the admin. Albums without an upload policy are admin-only.
+func (a *Album) AcceptsClientUploads() bool {
+	return a.UploadPolicy == UploadPolicyClients
+}
+
+// Indexable reports whether search engines may index the album's pages. Albums that opt out
+// with NoIndex and restricted albums are never indexed, whatever their visibility.
+func (a *Album) Indexable() bool {
+	return !a.NoIndex && !a.RequiresAccessToken()
+}
+
+// AllowsEmail reports whether an email address is on the album's client access list.
+// Addresses are compared case-insensitively.
+func (a *Album) AllowsEmail(email string) bool {
+	email = strings.TrimSpace(email)
+	for _, allowed := range a.AllowedEmails {
+		if strings.EqualFold(strings.TrimSpace(allowed), email) {
+			return true
+		}
+	}
+	return false
+}
+
+// DistinctFilmStocks returns the film stocks used in the album, in photo order without duplicates.
+func (a *Album) DistinctFilmStocks() []string {
+	seen := make(map[string]bool)
+	stocks := []string{}
+	for _, photo := range a.Photos {
+		if photo.FilmStock == "" || seen[photo.FilmStock] {
+			continue
+		}
+		seen[photo.FilmStock] = true
+		stocks = append(stocks, photo.FilmStock)
+	}
+	return stocks
+}
+
+// CoverPhoto returns the album's cover photo, falling back to the first photo, or nil if the album is empty.
+// With several covers, this is the first.
+func (a *Album) CoverPhoto() *Photo {
+	if covers := a.CoverPhotos(); len(covers) > 0 {
+		return covers[0]
+	}
+	return nil
+}
+
+// CoverPhotos returns the album's chosen covers that are still in the album, in cover order.
+// Without any, the first photo stands in alone; an empty album has none.
+func (a *Album) CoverPhotos() []*Photo {
+	ids := a.CoverPhotoIDs
+	if len(ids) == 0 && a.CoverPhotoID != "" {
+		ids = []string{a.CoverPhotoID}
+	}
+
+	covers := []*Photo{}
+	for _, id := range ids {
+		for i := range a.Photos {
+			if a.Photos[i].ID == id {
+				covers = append(covers, &a.Photos[i])
+				break
+			}
+		}
+	}
+	if len(covers) == 0 && len(a.Photos) > 0 {
+		covers = append(covers, &a.Photos[0])
+	}
+	return covers
+}
+
+// AlbumSummary is the part of an album shown in navigation: enough to link to it and show its
+// cover, without the photos.
+type AlbumSummary struct {
+	ID               string     `json:"id"`
+	Slug             string     `json:"slug"`
+	Title            string     `json:"title"`
+	PhotoCount       int        `json:"photo_count"`
+	CoverURL         string     `json:"cover_url,omitempty"` // Thumbnail of the cover photo, as on album cards
+	Visibility       string     `json:"visibility"`
+	Pinned           bool       `json:"pinned,omitempty"`
+	LastPhotoAddedAt *time.Time `json:"last_photo_added_at,omitempty"` // For marking recently updated albums
+}
+
+// Summary returns the album's navigation summary.
+func (a *Album) Summary() AlbumSummary {
+	summary := AlbumSummary{
+		ID:               a.ID,
+		Slug:             a.Slug,
+		Title:            a.Title,
+		PhotoCount:       len(a.Photos),
+		Visibility:       a.Visibility,
+		Pinned:           a.Pinned,
+		LastPhotoAddedAt: a.LastPhotoAddedAt,
+	}
+	if cover := a.CoverPhoto(); cover != nil {
+		summary.CoverURL = cover.URLThumbnail
+	}
+	return summary
+}
+
 // ToJSON converts album to JSON bytes.
 func (a *Album) ToJSON() ([]byte, error) {
 	return json.Marshal(a)
@@ -0,0 +1,24 @@
+package models
+
+import "errors"
+
+// AlbumDefaults holds the settings that newly created albums inherit.
+type AlbumDefaults struct {
+	Visibility       string `json:"visibility"` // public, unlisted, password_protected
+	Layout           string `json:"layout,omitempty"`
+	AllowDownloads   bool   `json:"allow_downloads"`
+	ThemeOverride    string `json:"theme_override,omitempty"` // system, light, dark
+	WatermarkEnabled bool   `json:"watermark_enabled"`
+	WatermarkCover   bool   `json:"watermark_cover"` // Stamp the cover too
+}
+
+// Validate checks that the defaults describe a valid album.
+func (d *AlbumDefaults) Validate() error {
+	if d.Visibility != "public" && d.Visibility != "unlisted" && d.Visibility != "password_protected" {
+		return errors.New("default visibility must be public, unlisted, or password_protected")
+	}
+	if d.ThemeOverride != "" && d.ThemeOverride != "system" && d.ThemeOverride != "light" && d.ThemeOverride != "dark" {
+		return errors.New("default theme_override must be system, light, or dark")
+	}
+	return nil
+}
@@ -0,0 +1,27 @@
+package models
+
+import "time"
+
+// Album history entry types.
+const (
+	AlbumHistoryCreated       = "created"
+	AlbumHistoryRenamed       = "renamed"
+	AlbumHistoryPhotosAdded   = "photos_added"
+	AlbumHistoryPhotosRemoved = "photos_removed"
+	AlbumHistoryReordered     = "reordered"
+)
+
+// AlbumHistoryEntry records one change to an album.
+type AlbumHistoryEntry struct {
+	AlbumID       string    `json:"album_id"`
+	Type          string    `json:"type"` // created, renamed, photos_added, photos_removed, reordered
+	At            time.Time `json:"at"`
+	Title         string    `json:"title,omitempty"`          // The album title after the change, for created and renamed
+	PreviousTitle string    `json:"previous_title,omitempty"` // The title before a rename
+	PhotoIDs      []string  `json:"photo_ids,omitempty"`      // Photos added or removed
+}
+
+// AlbumHistory represents the root album_history.json structure.
+type AlbumHistory struct {
+	Entries []AlbumHistoryEntry `json:"entries"`
+}
@@ -0,0 +1,33 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+// MaxScheduledChanges is the most visibility changes one album may have scheduled.
+const MaxScheduledChanges = 20
+
+// ScheduledChange is a visibility an album takes at a set time, such as going public on the
+// day of an event and being unlisted when its gallery expires.
+type ScheduledChange struct {
+	At         time.Time `json:"at"`
+	Visibility string    `json:"visibility"` // public, unlisted, password_protected
+}
+
+// validateSchedule checks an album's scheduled visibility changes.
+func validateSchedule(changes []ScheduledChange) error {
+	if len(changes) > MaxScheduledChanges {
+		return fmt.Errorf("album may have at most %d scheduled changes", MaxScheduledChanges)
+	}
+	for _, change := range changes {
+		if change.At.IsZero() {
+			return errors.New("scheduled change time is required")
+		}
+		if change.Visibility != "public" && change.Visibility != "unlisted" && change.Visibility != "password_protected" {
+			return errors.New("scheduled visibility must be public, unlisted, or password_protected")
+		}
+	}
+	return nil
+}
@@ -0,0 +1,53 @@
+package models
+
+// AlbumSection groups some of an album's photos under a title, such as one day of an event shoot.
+// Sections list photo IDs; the photos themselves stay in the album's flat Photos list.
+type AlbumSection struct {
+	ID       string   `json:"id"`
+	Title    string   `json:"title"`
+	PhotoIDs []string `json:"photo_ids"`
+}
+
+// PhotoSection is a section with its photos resolved, in album order.
+// Photos outside every section are grouped into a final section with no ID or title.
+type PhotoSection struct {
+	ID     string  `json:"id,omitempty"`
+	Title  string  `json:"title,omitempty"`
+	Photos []Photo `json:"photos"`
+}
+
+// GroupedPhotos returns the album's photos grouped by section, in section order, followed by
+// any photos in no section. Within a section photos keep album order. Albums without sections
+// get nil.
+func (a *Album) GroupedPhotos() []PhotoSection {
+	if len(a.Sections) == 0 {
+		return nil
+	}
+
+	sectionOf := make(map[string]int)
+	for i, section := range a.Sections {
+		for _, id := range section.PhotoIDs {
+			if _, taken := sectionOf[id]; !taken {
+				sectionOf[id] = i
+			}
+		}
+	}
+
+	grouped := make([]PhotoSection, len(a.Sections))
+	for i, section := range a.Sections {
+		grouped[i] = PhotoSection{ID: section.ID, Title: section.Title, Photos: []Photo{}}
+	}
+	unsectioned := []Photo{}
+	for _, photo := range a.Photos {
+		if i, ok := sectionOf[photo.ID]; ok {
+			grouped[i].Photos = append(grouped[i].Photos, photo)
+		} else {
+			unsectioned = append(unsectioned, photo)
+		}
+	}
+
+	if len(unsectioned) > 0 {
+		grouped = append(grouped, PhotoSection{Photos: unsectioned})
+	}
+	return grouped
+}
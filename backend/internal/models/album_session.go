@@ -0,0 +1,25 @@
+package models
+
+import "time"
+
+// AlbumSession is a server-side grant of access to a restricted album, opened by entering its
+// password or following an emailed access link. Only a hash of the session's token is stored.
+type AlbumSession struct {
+	TokenHash string    `json:"token_hash"`
+	AlbumID   string    `json:"album_id"`
+	Email     string    `json:"email,omitempty"` // Set for sessions opened through an access link
+	CreatedAt time.Time `json:"created_at"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// AlbumSessionCollection represents the root album_sessions.json structure.
+type AlbumSessionCollection struct {
+	Sessions []AlbumSession `json:"sessions"`
+}
+
+// AlbumAuthKeys records the latest rotation of the key album access tokens are signed with,
+// made to revoke every signed token at once. Stored as album_auth_keys.json.
+type AlbumAuthKeys struct {
+	Salt      string    `json:"salt"` // Mixed into the signing key; a new one invalidates every signed token
+	RotatedAt time.Time `json:"rotated_at"`
+}
@@ -0,0 +1,7 @@
+package models
+
+// AlbumViews represents the root album_views.json structure: how many visitor sessions have
+// viewed each album, by album ID.
+type AlbumViews struct {
+	Views map[string]int64 `json:"views"`
+}
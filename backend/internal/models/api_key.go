@@ -0,0 +1,20 @@
+package models
+
+import "time"
+
+// APIKey grants a third party rate-limited access to the public read endpoints.
+// Only a hash of the key is stored; the key itself is shown once, when it is issued.
+type APIKey struct {
+	ID        string     `json:"id"`
+	Name      string     `json:"name"`   // Who the key was issued to
+	Prefix    string     `json:"prefix"` // The key's first characters, to tell keys apart
+	KeyHash   string     `json:"key_hash"`
+	RateLimit int        `json:"rate_limit"` // Requests per minute
+	CreatedAt time.Time  `json:"created_at"`
+	RevokedAt *time.Time `json:"revoked_at,omitempty"`
+}
+
+// APIKeyCollection represents the root api_keys.json structure.
+type APIKeyCollection struct {
+	Keys []APIKey `json:"keys"`
+}
@@ -0,0 +1,52 @@
+package models
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+	"unicode/utf8"
+)
+
+// Placeholders of a download filename template.
+const (
+	FilenamePlaceholderAlbum    = "album"    // The album's slug
+	FilenamePlaceholderIndex    = "index"    // The photo's 1-based position in the album, zero-padded
+	FilenamePlaceholderTitle    = "title"    // The photo's title, empty if it has none
+	FilenamePlaceholderFilename = "filename" // The uploaded filename, without its extension
+	FilenamePlaceholderDate     = "date"     // The capture date as YYYY-MM-DD, empty if unknown
+)
+
+// DownloadFilenamePlaceholders lists every placeholder a download filename template may use.
+var DownloadFilenamePlaceholders = []string{
+	FilenamePlaceholderAlbum,
+	FilenamePlaceholderIndex,
+	FilenamePlaceholderTitle,
+	FilenamePlaceholderFilename,
+	FilenamePlaceholderDate,
+}
+
+// MaxDownloadFilenameTemplateLength is the longest download filename template, in characters.
+const MaxDownloadFilenameTemplateLength = 200
+
+// FilenamePlaceholderPattern matches a {placeholder} in a download filename template.
+var FilenamePlaceholderPattern = regexp.MustCompile(`\{([^{}]*)\}`)
+
+// ValidateDownloadFilenameTemplate checks that a download filename template, like
+// "{album}-{index}-{title}", only uses known placeholders. An empty template is valid and
+// keeps uploaded filenames.
+func ValidateDownloadFilenameTemplate(template string) error {
+	if utf8.RuneCountInString(template) > MaxDownloadFilenameTemplateLength {
+		return fmt.Errorf("download filename template is longer than %d characters", MaxDownloadFilenameTemplateLength)
+	}
+	for _, match := range FilenamePlaceholderPattern.FindAllStringSubmatch(template, -1) {
+		if !slices.Contains(DownloadFilenamePlaceholders, match[1]) {
+			return fmt.Errorf("unknown download filename placeholder {%s} (allowed: {%s})",
+				match[1], strings.Join(DownloadFilenamePlaceholders, "}, {"))
+		}
+	}
+	if rest := FilenamePlaceholderPattern.ReplaceAllString(template, ""); strings.ContainsAny(rest, "{}") {
+		return fmt.Errorf("download filename template %q has an unmatched brace", template)
+	}
+	return nil
+}
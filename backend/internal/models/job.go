@@ -0,0 +1,33 @@
+package models
+
+import "time"
+
+// Job status values.
+const (
+	JobStatusRunning   = "running"
+	JobStatusCompleted = "completed"
+	JobStatusFailed    = "failed"
+)
+
+// Job tracks the progress of a long-running background operation.
+type Job struct {
+	ID         string         `json:"id"`
+	Type       string         `json:"type"`
+	Status     string         `json:"status"` // running, completed, failed
+	Total      int            `json:"total"`
+	Processed  int            `json:"processed"`
+	Skipped    []JobItem      `json:"skipped"`
+	Errors     []JobItem      `json:"errors"`
+	Counts     map[string]int `json:"counts,omitempty"` // Per-category tallies, e.g. photos changed per backfilled field
+	Error      string         `json:"error,omitempty"`  // Set when the whole job failed
+	CreatedAt  time.Time      `json:"created_at"`
+	FinishedAt *time.Time     `json:"finished_at,omitempty"`
+}
+
+// JobItem identifies a photo a job skipped or failed on, and why.
+type JobItem struct {
+	AlbumID  string `json:"album_id"`
+	PhotoID  string `json:"photo_id"`
+	Filename string `json:"filename"`
+	Reason   string `json:"reason"`
+}
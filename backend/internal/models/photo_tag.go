@@ -0,0 +1,93 @@
+package models
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"unicode/utf8"
+)
+
+// Limits on the tags of one photo.
+const (
+	MaxPhotoTags      = 50
+	MaxPhotoTagLength = 64 // Characters, not bytes
+)
+
+// TagCount is a tag used in an album and how many of its photos carry it.
+type TagCount struct {
+	Tag    string `json:"tag"`
+	Photos int    `json:"photos"`
+}
+
+// NormalizeTag trims and lowercases a tag, so "Beach " and "beach" are the same tag.
+func NormalizeTag(tag string) string {
+	return strings.ToLower(strings.TrimSpace(tag))
+}
+
+// NormalizeTags normalizes each tag, dropping blank tags and duplicates while keeping the
+// order in which tags first appear. Returns nil if no tags are left.
+func NormalizeTags(tags []string) []string {
+	var normalized []string
+	for _, tag := range tags {
+		tag = NormalizeTag(tag)
+		if tag == "" || slices.Contains(normalized, tag) {
+			continue
+		}
+		normalized = append(normalized, tag)
+	}
+	return normalized
+}
+
+// validatePhotoTags checks a photo's tags against the per-photo limits.
+func validatePhotoTags(tags []string) error {
+	if len(tags) > MaxPhotoTags {
+		return fmt.Errorf("photos may have at most %d tags", MaxPhotoTags)
+	}
+	for _, tag := range tags {
+		if utf8.RuneCountInString(tag) > MaxPhotoTagLength {
+			return fmt.Errorf("photo tag %q is longer than %d characters", tag, MaxPhotoTagLength)
+		}
+	}
+	return nil
+}
+
+// HasTag reports whether the photo carries a tag, compared after normalization.
+func (p *Photo) HasTag(tag string) bool {
+	tag = NormalizeTag(tag)
+	for _, own := range p.Tags {
+		if NormalizeTag(own) == tag {
+			return true
+		}
+	}
+	return false
+}
+
+// TagCounts returns every tag used in the album's photos, sorted, with how many photos carry it.
+func (a *Album) TagCounts() []TagCount {
+	counts := make(map[string]int)
+	for _, photo := range a.Photos {
+		for _, tag := range NormalizeTags(photo.Tags) {
+			counts[tag]++
+		}
+	}
+
+	tags := make([]TagCount, 0, len(counts))
+	for tag, count := range counts {
+		tags = append(tags, TagCount{Tag: tag, Photos: count})
+	}
+	slices.SortFunc(tags, func(a, b TagCount) int { return strings.Compare(a.Tag, b.Tag) })
+	return tags
+}
+
+// DistinctTags returns the tags used in the album's photos, sorted without duplicates.
+func (a *Album) DistinctTags() []string {
+	counts := a.TagCounts()
+	if len(counts) == 0 {
+		return nil
+	}
+	tags := make([]string, len(counts))
+	for i, count := range counts {
+		tags[i] = count.Tag
+	}
+	return tags
+}
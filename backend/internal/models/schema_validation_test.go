@@ -70,6 +70,38 @@ func TestAlbumValidation(t *testing.T) {
 			wantErr: true,
 			errMsg:  "album visibility must be public, unlisted, or password_protected",
 		},
+		{
+			name: "valid allowed emails",
+			album: Album{
+				Title:         "Test Album",
+				Slug:          "test-album",
+				Visibility:    "unlisted",
+				AllowedEmails: []string{"client@example.com", "Partner@Example.com"},
+			},
+			wantErr: false,
+		},
+		{
+			name: "invalid allowed email",
+			album: Album{
+				Title:         "Test Album",
+				Slug:          "test-album",
+				Visibility:    "unlisted",
+				AllowedEmails: []string{"not-an-email"},
+			},
+			wantErr: true,
+			errMsg:  `invalid allowed email "not-an-email"`,
+		},
+		{
+			name: "allowed email with display name",
+			album: Album{
+				Title:         "Test Album",
+				Slug:          "test-album",
+				Visibility:    "unlisted",
+				AllowedEmails: []string{"Partner <partner@example.com>"},
+			},
+			wantErr: true,
+			errMsg:  `invalid allowed email "Partner <partner@example.com>"`,
+		},
 		{
 			name: "empty visibility",
 			album: Album{
@@ -80,6 +112,70 @@ func TestAlbumValidation(t *testing.T) {
 			wantErr: true,
 			errMsg:  "album visibility must be public, unlisted, or password_protected",
 		},
+		{
+			name: "untitled section",
+			album: Album{
+				Title:      "Test Album",
+				Slug:       "test-album",
+				Visibility: "public",
+				Sections:   []AlbumSection{{ID: "s1", Title: " "}},
+			},
+			wantErr: true,
+			errMsg:  "album section title is required",
+		},
+		{
+			name: "full hex accent color",
+			album: Album{
+				Title:       "Test Album",
+				Slug:        "test-album",
+				Visibility:  "public",
+				AccentColor: "#FF6b6b",
+			},
+			wantErr: false,
+		},
+		{
+			name: "short hex accent color",
+			album: Album{
+				Title:       "Test Album",
+				Slug:        "test-album",
+				Visibility:  "public",
+				AccentColor: "#f66",
+			},
+			wantErr: false,
+		},
+		{
+			name: "accent color without hash",
+			album: Album{
+				Title:       "Test Album",
+				Slug:        "test-album",
+				Visibility:  "public",
+				AccentColor: "ff6b6b",
+			},
+			wantErr: true,
+			errMsg:  "album accent_color must be a hex color like #ff6b6b",
+		},
+		{
+			name: "accent color with non-hex digits",
+			album: Album{
+				Title:       "Test Album",
+				Slug:        "test-album",
+				Visibility:  "public",
+				AccentColor: "#ff6b6g",
+			},
+			wantErr: true,
+			errMsg:  "album accent_color must be a hex color like #ff6b6b",
+		},
+		{
+			name: "named accent color",
+			album: Album{
+				Title:       "Test Album",
+				Slug:        "test-album",
+				Visibility:  "public",
+				AccentColor: "red",
+			},
+			wantErr: true,
+			errMsg:  "album accent_color must be a hex color like #ff6b6b",
+		},
 		// Note: We no longer validate password_hash during album validation
 		// because it can be set via a separate API call after creation
 	}
@@ -299,3 +395,35 @@ func TestPhotoJSONFields(t *testing.T) {
 		}
 	}
 }
+
+// TestPhotoDownloadableDefault tests that photos without a downloadable field stay downloadable.
+func TestPhotoDownloadableDefault(t *testing.T) {
+	var photo Photo
+	if err := json.Unmarshal([]byte(`{"id":"legacy"}`), &photo); err != nil {
+		t.Fatalf("failed to unmarshal photo: %v", err)
+	}
+	if !photo.Downloadable {
+		t.Error("expected missing downloadable field to default to true")
+	}
+
+	if err := json.Unmarshal([]byte(`{"id":"teaser","downloadable":false}`), &photo); err != nil {
+		t.Fatalf("failed to unmarshal photo: %v", err)
+	}
+	if photo.Downloadable {
+		t.Error("expected explicit downloadable false to be kept")
+	}
+}
+
+// TestValidateDownloadFilenameTemplate tests download filename template validation.
+func TestValidateDownloadFilenameTemplate(t *testing.T) {
+	for _, template := range []string{"", "{album}-{index}-{title}", "{date} {filename}", "prints"} {
+		if err := ValidateDownloadFilenameTemplate(template); err != nil {
+			t.Errorf("ValidateDownloadFilenameTemplate(%q) = %v, want nil", template, err)
+		}
+	}
+	for _, template := range []string{"{album}-{photographer}", "{album", "{index}}", "{}"} {
+		if err := ValidateDownloadFilenameTemplate(template); err == nil {
+			t.Errorf("ValidateDownloadFilenameTemplate(%q) = nil, want an error", template)
+		}
+	}
+}
@@ -0,0 +1,23 @@
+package models
+
+import "time"
+
+// MaxSelectionNameLength is the longest name a proof selection may have, in characters.
+const MaxSelectionNameLength = 100
+
+// Selection is a client's pick of photos from an album, such as the selects from a proof
+// gallery, shared back through a link carrying its token. The token is not a credential for
+// the album: it only shows the selected photos, so it is stored as is for admins to open.
+type Selection struct {
+	ID        string    `json:"id"`
+	Token     string    `json:"token"`
+	AlbumID   string    `json:"album_id"`
+	Name      string    `json:"name"`
+	PhotoIDs  []string  `json:"photo_ids"` // In album order
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// SelectionCollection represents the root selections.json structure.
+type SelectionCollection struct {
+	Selections []Selection `json:"selections"`
+}
@@ -58,15 +58,23 @@ type CustomLink struct {
 
 // BrandingConfig contains visual branding settings.
 type BrandingConfig struct {
-	LogoURL        string      `json:"logo_url,omitempty"`
-	FaviconURL     string      `json:"favicon_url,omitempty"`
-	PrimaryColor   string      `json:"primary_color"`
-	SecondaryColor string      `json:"secondary_color"`
-	AccentColor    string      `json:"accent_color"`
-	FontHeading    string      `json:"font_heading,omitempty"`
-	FontBody       string      `json:"font_body,omitempty"`
-	CustomCSSURL   string      `json:"custom_css_url,omitempty"`
-	Theme          ThemeConfig `json:"theme"`
+	LogoURL        string          `json:"logo_url,omitempty"`
+	FaviconURL     string          `json:"favicon_url,omitempty"`
+	PrimaryColor   string          `json:"primary_color"`
+	SecondaryColor string          `json:"secondary_color"`
+	AccentColor    string          `json:"accent_color"`
+	FontHeading    string          `json:"font_heading,omitempty"`
+	FontBody       string          `json:"font_body,omitempty"`
+	CustomCSSURL   string          `json:"custom_css_url,omitempty"`
+	Theme          ThemeConfig     `json:"theme"`
+	Watermark      WatermarkConfig `json:"watermark"`
+}
+
+// WatermarkConfig describes the image stamped onto display versions of photos in watermarked albums.
+type WatermarkConfig struct {
+	ImageKey     string  `json:"image_key,omitempty"`     // Storage key of a PNG with transparency, e.g. "watermarks/logo.png"
+	Opacity      float64 `json:"opacity,omitempty"`       // 0-1 (default 0.5)
+	ScalePercent int     `json:"scale_percent,omitempty"` // Watermark width as a percentage of the photo width (default 20)
 }
 
 // ThemeConfig contains light/dark theme settings.
@@ -92,8 +100,15 @@ type PortfolioConfig struct {
 	DefaultLayout  string `json:"default_photo_layout,omitempty"`
 	EnableLightbox bool   `json:"enable_lightbox"`
 	ShowPhotoCount bool   `json:"show_photo_count,omitempty"`
+	ThumbnailFit   string `json:"thumbnail_fit,omitempty"` // cover: centre-crop to square; contain (default): keep aspect ratio
 }
 
+// Thumbnail fits for PortfolioConfig.ThumbnailFit and Album.ThumbnailFit.
+const (
+	ThumbnailFitCover   = "cover"
+	ThumbnailFitContain = "contain"
+)
+
 // NavigationConfig controls nav menu visibility.
 type NavigationConfig struct {
 	ShowHome   bool `json:"show_home"`
@@ -102,10 +117,29 @@ type NavigationConfig struct {
 
 // StorageConfig contains storage and disk usage settings.
 type StorageConfig struct {
-	MaxDiskUsagePercent int `json:"max_disk_usage_percent"` // Maximum disk usage percentage (default 80)
-	MaxImageSizeMB      int `json:"max_image_size_mb"`      // Maximum individual image size in MB (default 50)
+	MaxDiskUsagePercent int      `json:"max_disk_usage_percent"`       // Maximum disk usage percentage (default 80)
+	MaxImageSizeMB      int      `json:"max_image_size_mb"`            // Maximum individual image size in MB (default 50)
+	MaxZIPPartSizeMB    int      `json:"max_zip_part_size_mb"`         // Split album downloads into ZIP parts of at most this size (0 = single ZIP)
+	MaxOriginalEdgePx   int      `json:"max_original_edge_px"`         // Downscale uploaded originals whose longest edge exceeds this (0 = keep true originals)
+	MinUploadEdgePx     int      `json:"min_upload_edge_px,omitempty"` // Reject uploads whose longest edge is shorter than this (0 = any size)
+	DerivativeMode      string   `json:"derivative_mode,omitempty"`    // eager (default): render display and thumbnail on upload; lazy: on first request
+	AllowedExtensions   []string `json:"allowed_extensions,omitempty"` // Image file extensions accepted for upload, e.g. ".jpg" (empty = every supported type)
+	DownloadFilename    string   `json:"download_filename,omitempty"`  // Template naming downloaded photos, e.g. "{album}-{index}-{title}" (empty = uploaded filenames)
+	KeywordTags         string   `json:"keyword_tags,omitempty"`       // on: tag uploads with their embedded IPTC/XMP keywords; off (default): leave them untagged
 }
 
+// Derivative modes for StorageConfig.DerivativeMode.
+const (
+	DerivativeModeEager = "eager"
+	DerivativeModeLazy  = "lazy"
+)
+
+// Keyword tagging settings for StorageConfig.KeywordTags.
+const (
+	KeywordTagsOn  = "on"
+	KeywordTagsOff = "off"
+)
+
 // Validate checks if the site config has required fields.
 func (sc *SiteConfig) Validate() error {
 	if sc.Site.Title == "" {
@@ -0,0 +1,18 @@
+package models
+
+import "time"
+
+// StoreSnapshot is a copy of albums.json kept in the backup location.
+type StoreSnapshot struct {
+	Key       string    `json:"key"` // Object key in the backup location
+	Size      int64     `json:"size"`
+	SHA256    string    `json:"sha256"`
+	Trigger   string    `json:"trigger"` // manual, scheduled, or write
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// StoreSnapshotIndex represents the index.json kept alongside the snapshots, oldest first.
+// Storage backends cannot list their objects, so the index is how snapshots are found.
+type StoreSnapshotIndex struct {
+	Snapshots []StoreSnapshot `json:"snapshots"`
+}
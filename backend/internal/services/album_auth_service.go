@@ -0,0 +1,336 @@
+package services
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/njoubert/nielsshootsfilm/backend/internal/models"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// ErrEmailNotAllowed is returned when an access link is requested for an address
+// that is not on the album's client access list.
+var ErrEmailNotAllowed = errors.New("email is not allowed to access this album")
+
+// albumAuthKeysFile records the latest signing key rotation made by RevokeAll.
+const albumAuthKeysFile = "album_auth_keys.json"
+
+// AlbumAccessCookiePrefix is the prefix of the per-album access cookie name.
+// The full cookie name is the prefix followed by the album ID.
+const AlbumAccessCookiePrefix = "album_access_"
+
+// AlbumAuthService issues and validates access tokens for restricted albums.
+// Tokens are stateless HMAC signatures over the album ID and expiry time, plus the
+// recipient's email address for tokens issued through emailed access links. With a session
+// store, visitors are given server-side session tokens instead, which can be revoked.
+type AlbumAuthService struct {
+	secret   []byte
+	tokenTTL time.Duration
+	sessions SessionStore
+	keyStore *FileService // Persists key rotations; nil keeps them in memory
+
+	mu  sync.RWMutex
+	key []byte // Signing key: the secret, or derived from it and the latest rotation's salt
+}
+
+// NewAlbumAuthService creates a new album auth service.
+// If secret is empty, a random secret is generated, so tokens do not survive restarts.
+func NewAlbumAuthService(secret string, tokenTTL time.Duration) (*AlbumAuthService, error) {
+	key := []byte(secret)
+	if len(key) == 0 {
+		key = make([]byte, 32)
+		if _, err := rand.Read(key); err != nil {
+			return nil, fmt.Errorf("failed to generate album auth secret: %w", err)
+		}
+	}
+
+	return &AlbumAuthService{
+		secret:   key,
+		tokenTTL: tokenTTL,
+		key:      key,
+	}, nil
+}
+
+// SetKeyStore saves the signing key rotations RevokeAll makes in album_auth_keys.json, so
+// revoked tokens stay revoked across restarts, and picks up the latest rotation saved there.
+func (s *AlbumAuthService) SetKeyStore(fileService *FileService) error {
+	s.keyStore = fileService
+	if !fileService.FileExists(albumAuthKeysFile) {
+		return nil
+	}
+	var keys models.AlbumAuthKeys
+	if err := fileService.ReadJSON(albumAuthKeysFile, &keys); err != nil {
+		return fmt.Errorf("failed to read album auth keys: %w", err)
+	}
+	s.mu.Lock()
+	s.key = s.deriveKey(keys.Salt)
+	s.mu.Unlock()
+	return nil
+}
+
+// SetSessionStore makes passwords and access links open revocable server-side sessions, and
+// only session tokens are accepted from then on. Without it, tokens are stateless and stay
+// valid until they expire.
+func (s *AlbumAuthService) SetSessionStore(store SessionStore) {
+	s.sessions = store
+}
+
+// AlbumAccessCookieName returns the name of the access cookie for an album.
+func AlbumAccessCookieName(albumID string) string {
+	return AlbumAccessCookiePrefix + albumID
+}
+
+// TokenTTL returns how long issued tokens remain valid.
+func (s *AlbumAuthService) TokenTTL() time.Duration {
+	return s.tokenTTL
+}
+
+// VerifyPassword checks a password against a protected album and issues an access token.
+func (s *AlbumAuthService) VerifyPassword(album *models.Album, password string) (string, error) { // pragma: allowlist secret
+	if album.Visibility != "password_protected" || album.PasswordHash == "" {
+		return "", errors.New("album is not password protected")
+	}
+
+	if err := bcrypt.CompareHashAndPassword([]byte(album.PasswordHash), []byte(password)); err != nil {
+		return "", errors.New("invalid password")
+	}
+
+	return s.issue(album.ID, "")
+}
+
+// IssueToken creates a signed access token for an album.
+func (s *AlbumAuthService) IssueToken(albumID string) string {
+	expiresAt := time.Now().Add(s.tokenTTL).Unix()
+	payload := albumID + "|" + strconv.FormatInt(expiresAt, 10)
+
+	return base64.RawURLEncoding.EncodeToString([]byte(payload)) + "." + s.sign(payload)
+}
+
+// IssueEmailToken creates a signed access token for an album bound to a client's email address.
+func (s *AlbumAuthService) IssueEmailToken(albumID, email string) string {
+	expiresAt := time.Now().Add(s.tokenTTL).Unix()
+	payload := albumID + "|" + strconv.FormatInt(expiresAt, 10) + "|" + strings.ToLower(strings.TrimSpace(email))
+
+	return base64.RawURLEncoding.EncodeToString([]byte(payload)) + "." + s.sign(payload)
+}
+
+// RequestAccessLink issues an email-bound access token for an album,
+// or returns ErrEmailNotAllowed if the address is not on the album's access list.
+func (s *AlbumAuthService) RequestAccessLink(album *models.Album, email string) (string, error) {
+	if !album.AllowsEmail(email) {
+		return "", ErrEmailNotAllowed
+	}
+	return s.issue(album.ID, email)
+}
+
+// RevokeSessions ends every session for an album, e.g. after its password changes, and
+// returns how many there were. Without a session store there is nothing to revoke.
+func (s *AlbumAuthService) RevokeSessions(albumID string) (int, error) {
+	if s.sessions == nil {
+		return 0, nil
+	}
+	return s.sessions.RevokeAlbum(albumID)
+}
+
+// RevokeAll ends every album access session and invalidates every signed token, so all
+// visitors must enter passwords or follow access links again. Signed tokens are revoked by
+// rotating the key they are signed with. It returns the rotation and how many sessions
+// were ended.
+func (s *AlbumAuthService) RevokeAll() (*models.AlbumAuthKeys, int, error) {
+	salt := make([]byte, 32)
+	if _, err := rand.Read(salt); err != nil {
+		return nil, 0, fmt.Errorf("failed to generate signing key salt: %w", err)
+	}
+	keys := &models.AlbumAuthKeys{
+		Salt:      base64.RawURLEncoding.EncodeToString(salt),
+		RotatedAt: time.Now().UTC(),
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.keyStore != nil {
+		if err := s.keyStore.WriteJSON(albumAuthKeysFile, keys); err != nil {
+			return nil, 0, fmt.Errorf("failed to write album auth keys: %w", err)
+		}
+	}
+	s.key = s.deriveKey(keys.Salt)
+
+	revoked := 0
+	if s.sessions != nil {
+		var err error
+		if revoked, err = s.sessions.RevokeAll(); err != nil {
+			return nil, 0, err
+		}
+	}
+	return keys, revoked, nil
+}
+
+// deriveKey returns the signing key for a rotation's salt: the secret itself before any
+// rotation, so tokens issued then stay valid.
+func (s *AlbumAuthService) deriveKey(salt string) []byte {
+	if salt == "" {
+		return s.secret
+	}
+	mac := hmac.New(sha256.New, s.secret)
+	mac.Write([]byte(salt))
+	return mac.Sum(nil)
+}
+
+// issue grants a visitor access to an album, optionally bound to their email address: a
+// session token if there is a session store, or a stateless signed token otherwise.
+func (s *AlbumAuthService) issue(albumID, email string) (string, error) {
+	if s.sessions == nil {
+		if email != "" {
+			return s.IssueEmailToken(albumID, email), nil
+		}
+		return s.IssueToken(albumID), nil
+	}
+
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("failed to generate session token: %w", err)
+	}
+	token := AlbumSessionTokenPrefix + base64.RawURLEncoding.EncodeToString(b)
+
+	now := time.Now().UTC()
+	err := s.sessions.Create(models.AlbumSession{
+		TokenHash: hashSessionToken(token),
+		AlbumID:   albumID,
+		Email:     strings.ToLower(strings.TrimSpace(email)),
+		CreatedAt: now,
+		ExpiresAt: now.Add(s.tokenTTL),
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to create album session: %w", err)
+	}
+	return token, nil
+}
+
+// ValidateToken checks that a token is authentic, unexpired, and grants access to the album.
+// It does not check email-bound tokens against the album's access list; use Authorize for that.
+func (s *AlbumAuthService) ValidateToken(token, albumID string) error {
+	_, err := s.checkToken(token, albumID)
+	return err
+}
+
+// Authorize checks that a token grants access to an album. Email-bound tokens are only
+// accepted while their address remains on the album's access list, so removing an address
+// revokes links already sent to it.
+func (s *AlbumAuthService) Authorize(token string, album *models.Album) error {
+	email, err := s.checkToken(token, album.ID)
+	if err != nil {
+		return err
+	}
+	if email != "" && !album.AllowsEmail(email) {
+		return ErrEmailNotAllowed
+	}
+	return nil
+}
+
+// HasAccess reports whether a request carries a token granting access to an album, either as
+// the album's access cookie or as a ?token= share link. Albums that do not require an access
+// token are always accessible.
+func (s *AlbumAuthService) HasAccess(r *http.Request, album *models.Album) bool {
+	return !album.RequiresAccessToken() || s.HasToken(r, album)
+}
+
+// HasToken reports whether a request carries a valid access token for an album, as the
+// album's access cookie or as a ?token= share link. Unlike HasAccess, it identifies a client
+// the album was shared with even when the album is open to everyone.
+func (s *AlbumAuthService) HasToken(r *http.Request, album *models.Album) bool {
+	if cookie, err := r.Cookie(AlbumAccessCookieName(album.ID)); err == nil {
+		if s.Authorize(cookie.Value, album) == nil {
+			return true
+		}
+	}
+
+	if token := r.URL.Query().Get("token"); token != "" {
+		if s.Authorize(token, album) == nil {
+			return true
+		}
+	}
+
+	return false
+}
+
+// checkToken checks that a token grants access to an album, as a session token if there is
+// a session store and a signed token otherwise, and returns the email address it is bound
+// to, if any.
+func (s *AlbumAuthService) checkToken(token, albumID string) (string, error) {
+	if s.sessions == nil {
+		return s.parseToken(token, albumID)
+	}
+
+	if !strings.HasPrefix(token, AlbumSessionTokenPrefix) {
+		return "", errors.New("malformed token")
+	}
+	session, err := s.sessions.Get(hashSessionToken(token))
+	if err != nil {
+		return "", err
+	}
+	if session.AlbumID != albumID {
+		return "", errors.New("token is for a different album")
+	}
+	return session.Email, nil
+}
+
+// parseToken verifies a token's signature, album, and expiry, and returns the email
+// address it is bound to, if any.
+func (s *AlbumAuthService) parseToken(token, albumID string) (string, error) {
+	encodedPayload, signature, ok := strings.Cut(token, ".")
+	if !ok {
+		return "", errors.New("malformed token")
+	}
+
+	payloadBytes, err := base64.RawURLEncoding.DecodeString(encodedPayload)
+	if err != nil {
+		return "", errors.New("malformed token")
+	}
+	payload := string(payloadBytes)
+
+	if !hmac.Equal([]byte(signature), []byte(s.sign(payload))) {
+		return "", errors.New("invalid token signature")
+	}
+
+	fields := strings.Split(payload, "|")
+	if len(fields) != 2 && len(fields) != 3 {
+		return "", errors.New("malformed token")
+	}
+
+	if fields[0] != albumID {
+		return "", errors.New("token is for a different album")
+	}
+
+	expiresAt, err := strconv.ParseInt(fields[1], 10, 64)
+	if err != nil {
+		return "", errors.New("malformed token")
+	}
+	if time.Now().Unix() > expiresAt {
+		return "", errors.New("token expired")
+	}
+
+	if len(fields) == 3 {
+		return fields[2], nil
+	}
+	return "", nil
+}
+
+// sign returns the base64-encoded HMAC-SHA256 signature of a payload.
+func (s *AlbumAuthService) sign(payload string) string {
+	s.mu.RLock()
+	key := s.key
+	s.mu.RUnlock()
+
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(payload))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
@@ -0,0 +1,104 @@
+package services
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/njoubert/nielsshootsfilm/backend/internal/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAlbumAuthService_TokenRoundTrip(t *testing.T) {
+	service, err := NewAlbumAuthService("secret", time.Hour)
+	require.NoError(t, err)
+
+	token := service.IssueToken("album-1")
+	assert.NoError(t, service.ValidateToken(token, "album-1"))
+
+	// Tokens are scoped to a single album
+	assert.Error(t, service.ValidateToken(token, "album-2"))
+
+	// Tokens signed with another secret are rejected
+	other, err := NewAlbumAuthService("other-secret", time.Hour)
+	require.NoError(t, err)
+	assert.Error(t, other.ValidateToken(token, "album-1"))
+
+	assert.Error(t, service.ValidateToken("garbage", "album-1"))
+}
+
+func TestAlbumAuthService_ExpiredToken(t *testing.T) {
+	service, err := NewAlbumAuthService("secret", -time.Minute)
+	require.NoError(t, err)
+
+	token := service.IssueToken("album-1")
+	err = service.ValidateToken(token, "album-1")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "expired")
+}
+
+func TestAlbumAuthService_VerifyPassword(t *testing.T) {
+	service, err := NewAlbumAuthService("", time.Hour)
+	require.NoError(t, err)
+
+	hash, err := HashPassword("letmein")
+	require.NoError(t, err)
+
+	album := &models.Album{ID: "album-1", Visibility: "password_protected", PasswordHash: hash}
+
+	token, err := service.VerifyPassword(album, "letmein")
+	require.NoError(t, err)
+	assert.NoError(t, service.ValidateToken(token, album.ID))
+
+	_, err = service.VerifyPassword(album, "wrong")
+	assert.Error(t, err)
+}
+
+func TestAlbumAuthService_AccessLinks(t *testing.T) {
+	service, err := NewAlbumAuthService("secret", time.Hour)
+	require.NoError(t, err)
+
+	album := &models.Album{ID: "album-1", Visibility: "unlisted", AllowedEmails: []string{"Client@Example.com"}}
+	assert.True(t, album.RequiresAccessToken())
+
+	// Only listed addresses get a link, matched case-insensitively
+	_, err = service.RequestAccessLink(album, "stranger@example.com")
+	assert.ErrorIs(t, err, ErrEmailNotAllowed)
+
+	token, err := service.RequestAccessLink(album, "client@example.com")
+	require.NoError(t, err)
+	assert.NoError(t, service.Authorize(token, album))
+
+	// Links are scoped to the album
+	other := &models.Album{ID: "album-2", AllowedEmails: album.AllowedEmails}
+	assert.Error(t, service.Authorize(token, other))
+
+	// Tampering with the bound address breaks the signature
+	forged := service.IssueEmailToken("album-1", "client@example.com")
+	forged = forged[:len(forged)-2] + "xx"
+	assert.Error(t, service.Authorize(forged, album))
+
+	// Removing an address from the list revokes links already sent to it
+	album.AllowedEmails = []string{"someone-else@example.com"}
+	assert.ErrorIs(t, service.Authorize(token, album), ErrEmailNotAllowed)
+}
+
+func TestAlbumAuthService_HasAccess(t *testing.T) {
+	service, err := NewAlbumAuthService("secret", time.Hour)
+	require.NoError(t, err)
+
+	album := &models.Album{ID: "album-1", Visibility: "public", AllowedEmails: []string{"client@example.com"}}
+	token := service.IssueEmailToken(album.ID, "client@example.com")
+
+	assert.False(t, service.HasAccess(httptest.NewRequest("GET", "/", nil), album))
+	assert.True(t, service.HasAccess(httptest.NewRequest("GET", "/?token="+token, nil), album))
+
+	req := httptest.NewRequest("GET", "/", nil)
+	req.AddCookie(&http.Cookie{Name: AlbumAccessCookieName(album.ID), Value: token})
+	assert.True(t, service.HasAccess(req, album))
+
+	// Albums without restrictions need no token
+	assert.True(t, service.HasAccess(httptest.NewRequest("GET", "/", nil), &models.Album{ID: "open", Visibility: "public"}))
+}
@@ -0,0 +1,133 @@
+package services
+
+import (
+	"fmt"
+	"slices"
+
+	"github.com/njoubert/nielsshootsfilm/backend/internal/models"
+)
+
+// syncCoverPhotos keeps an album's single cover field and its list of covers in step, with
+// the list as the source of truth. before is the album as stored, or nil for a new album.
+// Albums saved with only a single cover, and clients that only know that field and change it,
+// get a list of just that cover.
+func syncCoverPhotos(album, before *models.Album) {
+	if before != nil && album.CoverPhotoID != before.CoverPhotoID && slices.Equal(album.CoverPhotoIDs, before.CoverPhotoIDs) {
+		album.CoverPhotoIDs = nil
+	}
+	if len(album.CoverPhotoIDs) == 0 && album.CoverPhotoID != "" {
+		album.CoverPhotoIDs = []string{album.CoverPhotoID}
+	}
+
+	ids := make([]string, 0, len(album.CoverPhotoIDs))
+	for _, id := range album.CoverPhotoIDs {
+		if id != "" && !slices.Contains(ids, id) {
+			ids = append(ids, id)
+		}
+	}
+	album.CoverPhotoIDs = ids
+	if len(ids) == 0 {
+		album.CoverPhotoIDs = nil
+		album.CoverPhotoID = ""
+		return
+	}
+	album.CoverPhotoID = ids[0]
+}
+
+// ResolveCovers returns the photos an album's cover carousel shows: its chosen covers still in
+// the album, in order, else the first photo, else none for an empty album.
+func (s *AlbumService) ResolveCovers(album *models.Album) []*models.Photo {
+	return album.CoverPhotos()
+}
+
+// AddCoverPhoto appends a photo to the album's covers and returns the updated album.
+func (s *AlbumService) AddCoverPhoto(albumID, photoID string) (*models.Album, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	album, err := s.GetByID(albumID)
+	if err != nil {
+		return nil, err
+	}
+
+	if !slices.ContainsFunc(album.Photos, func(photo models.Photo) bool { return photo.ID == photoID }) {
+		return nil, fmt.Errorf("%w: %w", ErrValidation, ErrPhotoNotFound)
+	}
+	if slices.Contains(album.CoverPhotoIDs, photoID) {
+		return nil, fmt.Errorf("%w: photo is already a cover", ErrValidation)
+	}
+	if len(album.CoverPhotoIDs) >= models.MaxCoverPhotos {
+		return nil, fmt.Errorf("%w: album already has the most cover photos allowed (%d)", ErrValidation, models.MaxCoverPhotos)
+	}
+
+	album.CoverPhotoIDs = append(album.CoverPhotoIDs, photoID)
+	album.CoverPhotoID = album.CoverPhotoIDs[0]
+
+	if err := s.update(albumID, album); err != nil {
+		return nil, err
+	}
+	return album, nil
+}
+
+// RemoveCoverPhoto removes a photo from the album's covers, leaving the photo in the album,
+// and returns the updated album.
+func (s *AlbumService) RemoveCoverPhoto(albumID, photoID string) (*models.Album, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	album, err := s.GetByID(albumID)
+	if err != nil {
+		return nil, err
+	}
+
+	index := slices.Index(album.CoverPhotoIDs, photoID)
+	if index == -1 {
+		return nil, fmt.Errorf("%w: photo is not a cover", ErrValidation)
+	}
+
+	album.CoverPhotoIDs = slices.Delete(album.CoverPhotoIDs, index, index+1)
+	album.CoverPhotoID = ""
+	if len(album.CoverPhotoIDs) > 0 {
+		album.CoverPhotoID = album.CoverPhotoIDs[0]
+	}
+
+	if err := s.update(albumID, album); err != nil {
+		return nil, err
+	}
+	return album, nil
+}
+
+// ReorderCoverPhotos puts the album's covers in the given order, which must list each cover
+// exactly once, and returns the updated album.
+func (s *AlbumService) ReorderCoverPhotos(albumID string, photoIDs []string) (*models.Album, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	album, err := s.GetByID(albumID)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(album.CoverPhotoIDs) == 0 {
+		return nil, fmt.Errorf("%w: album has no cover photos to reorder", ErrValidation)
+	}
+	if len(photoIDs) != len(album.CoverPhotoIDs) {
+		return nil, fmt.Errorf("%w: photo ID count does not match cover count", ErrValidation)
+	}
+	for i, photoID := range photoIDs {
+		if !slices.Contains(album.CoverPhotoIDs, photoID) {
+			return nil, fmt.Errorf("%w: photo ID %s is not a cover", ErrValidation, photoID)
+		}
+		if slices.Contains(photoIDs[:i], photoID) {
+			return nil, fmt.Errorf("%w: photo ID %s is listed more than once", ErrValidation, photoID)
+		}
+	}
+
+	album.CoverPhotoIDs = slices.Clone(photoIDs)
+	album.CoverPhotoID = album.CoverPhotoIDs[0]
+
+	if err := s.update(albumID, album); err != nil {
+		return nil, err
+	}
+	return album, nil
+}
@@ -0,0 +1,163 @@
+package services
+
+import (
+	"testing"
+
+	"github.com/njoubert/nielsshootsfilm/backend/internal/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// createCoverAlbum creates an album with photos named after names, returning it as stored.
+func createCoverAlbum(t *testing.T, service *AlbumService, names ...string) *models.Album {
+	t.Helper()
+	album := &models.Album{Title: "Carousel", Visibility: "public"}
+	require.NoError(t, service.Create(album))
+	for _, name := range names {
+		require.NoError(t, service.AddPhoto(album.ID, &models.Photo{FilenameOriginal: name}))
+	}
+	stored, err := service.GetByID(album.ID)
+	require.NoError(t, err)
+	return stored
+}
+
+func coverIDs(covers []*models.Photo) []string {
+	ids := make([]string, len(covers))
+	for i, cover := range covers {
+		ids[i] = cover.ID
+	}
+	return ids
+}
+
+func TestAlbumService_CoverPhotos(t *testing.T) {
+	service, _ := setupAlbumService(t)
+	album := createCoverAlbum(t, service, "a.jpg", "b.jpg", "c.jpg")
+	a, b, c := album.Photos[0].ID, album.Photos[1].ID, album.Photos[2].ID
+
+	_, err := service.AddCoverPhoto(album.ID, c)
+	require.NoError(t, err)
+	updated, err := service.AddCoverPhoto(album.ID, a)
+	require.NoError(t, err)
+	assert.Equal(t, []string{c, a}, updated.CoverPhotoIDs)
+
+	// The first cover is the single cover older clients see
+	stored, err := service.GetByID(album.ID)
+	require.NoError(t, err)
+	assert.Equal(t, []string{c, a}, stored.CoverPhotoIDs)
+	assert.Equal(t, c, stored.CoverPhotoID)
+	assert.Equal(t, []string{c, a}, coverIDs(service.ResolveCovers(stored)))
+	assert.Equal(t, c, service.ResolveCover(stored).ID)
+
+	_, err = service.AddCoverPhoto(album.ID, a)
+	assert.EqualError(t, err, "validation failed: photo is already a cover")
+	assert.ErrorIs(t, err, ErrValidation)
+	_, err = service.AddCoverPhoto(album.ID, "missing")
+	assert.EqualError(t, err, "validation failed: photo not found in album")
+
+	updated, err = service.ReorderCoverPhotos(album.ID, []string{a, c})
+	require.NoError(t, err)
+	assert.Equal(t, []string{a, c}, updated.CoverPhotoIDs)
+	assert.Equal(t, a, updated.CoverPhotoID)
+	_, err = service.ReorderCoverPhotos(album.ID, []string{a, b})
+	assert.Error(t, err)
+	_, err = service.ReorderCoverPhotos(album.ID, []string{a, a})
+	assert.Error(t, err)
+
+	// Removing the first cover promotes the next
+	updated, err = service.RemoveCoverPhoto(album.ID, a)
+	require.NoError(t, err)
+	assert.Equal(t, []string{c}, updated.CoverPhotoIDs)
+	assert.Equal(t, c, updated.CoverPhotoID)
+	assert.Len(t, updated.Photos, 3, "the photo stays in the album")
+	_, err = service.RemoveCoverPhoto(album.ID, b)
+	assert.EqualError(t, err, "validation failed: photo is not a cover")
+
+	// Removing the last cover leaves the first photo standing in
+	updated, err = service.RemoveCoverPhoto(album.ID, c)
+	require.NoError(t, err)
+	assert.Empty(t, updated.CoverPhotoIDs)
+	assert.Empty(t, updated.CoverPhotoID)
+	assert.Equal(t, []string{a}, coverIDs(service.ResolveCovers(updated)))
+}
+
+func TestAlbumService_CoverPhotos_DeletedPhotos(t *testing.T) {
+	service, _ := setupAlbumService(t)
+	album := createCoverAlbum(t, service, "a.jpg", "b.jpg", "c.jpg")
+	a, b, c := album.Photos[0].ID, album.Photos[1].ID, album.Photos[2].ID
+	for _, id := range []string{b, c} {
+		_, err := service.AddCoverPhoto(album.ID, id)
+		require.NoError(t, err)
+	}
+
+	// Deleting a cover photo drops it from the covers and promotes the next
+	require.NoError(t, service.DeletePhoto(album.ID, b))
+	stored, err := service.GetByID(album.ID)
+	require.NoError(t, err)
+	assert.Equal(t, []string{c}, stored.CoverPhotoIDs)
+	assert.Equal(t, c, stored.CoverPhotoID)
+
+	// Deleting a photo that is not a cover leaves the covers alone
+	require.NoError(t, service.DeletePhoto(album.ID, a))
+	stored, err = service.GetByID(album.ID)
+	require.NoError(t, err)
+	assert.Equal(t, []string{c}, stored.CoverPhotoIDs)
+
+	require.NoError(t, service.DeleteAllPhotos(album.ID))
+	stored, err = service.GetByID(album.ID)
+	require.NoError(t, err)
+	assert.Empty(t, stored.CoverPhotoIDs)
+	assert.Empty(t, stored.CoverPhotoID)
+}
+
+func TestAlbumService_CoverPhotos_Limit(t *testing.T) {
+	service, _ := setupAlbumService(t)
+	names := make([]string, models.MaxCoverPhotos+1)
+	for i := range names {
+		names[i] = string(rune('a'+i)) + ".jpg"
+	}
+	album := createCoverAlbum(t, service, names...)
+
+	for _, photo := range album.Photos[:models.MaxCoverPhotos] {
+		_, err := service.AddCoverPhoto(album.ID, photo.ID)
+		require.NoError(t, err)
+	}
+	_, err := service.AddCoverPhoto(album.ID, album.Photos[models.MaxCoverPhotos].ID)
+	assert.ErrorContains(t, err, "most cover photos")
+}
+
+func TestAlbumService_CoverPhotos_SingleCoverMigration(t *testing.T) {
+	service, _ := setupAlbumService(t)
+	album := createCoverAlbum(t, service, "a.jpg", "b.jpg")
+	b := album.Photos[1].ID
+
+	// An album saved before cover carousels existed, with only the single cover field
+	album.CoverPhotoIDs = nil
+	album.CoverPhotoID = b
+	require.NoError(t, service.fileService.WriteJSON(albumsFile, &models.AlbumCollection{Albums: []models.Album{*album}}))
+
+	stored, err := service.GetByID(album.ID)
+	require.NoError(t, err)
+	assert.Equal(t, []string{b}, stored.CoverPhotoIDs)
+	assert.Equal(t, b, service.ResolveCover(stored).ID)
+
+	// Its single cover becomes the first of the carousel
+	updated, err := service.AddCoverPhoto(album.ID, album.Photos[0].ID)
+	require.NoError(t, err)
+	assert.Equal(t, []string{b, album.Photos[0].ID}, updated.CoverPhotoIDs)
+
+	// A client that only knows the single field replaces the carousel when it changes it
+	stored, err = service.GetByID(album.ID)
+	require.NoError(t, err)
+	stored.CoverPhotoID = album.Photos[0].ID
+	require.NoError(t, service.Update(album.ID, stored))
+	stored, err = service.GetByID(album.ID)
+	require.NoError(t, err)
+	assert.Equal(t, []string{album.Photos[0].ID}, stored.CoverPhotoIDs)
+
+	// Clearing it clears them all
+	stored.CoverPhotoID = ""
+	require.NoError(t, service.Update(album.ID, stored))
+	stored, err = service.GetByID(album.ID)
+	require.NoError(t, err)
+	assert.Empty(t, stored.CoverPhotoIDs)
+}
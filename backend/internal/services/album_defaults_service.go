@@ -0,0 +1,56 @@
+package services
+
+import (
+	"fmt"
+
+	"github.com/njoubert/nielsshootsfilm/backend/internal/models"
+)
+
+const albumDefaultsFile = "album_defaults.json"
+
+// AlbumDefaultsService manages the defaults applied to newly created albums.
+type AlbumDefaultsService struct {
+	fileService *FileService
+}
+
+// NewAlbumDefaultsService creates a new album defaults service.
+func NewAlbumDefaultsService(fileService *FileService) *AlbumDefaultsService {
+	return &AlbumDefaultsService{
+		fileService: fileService,
+	}
+}
+
+// Get returns the current album defaults.
+func (s *AlbumDefaultsService) Get() (*models.AlbumDefaults, error) {
+	if !s.fileService.FileExists(albumDefaultsFile) {
+		return s.getDefaultAlbumDefaults(), nil
+	}
+
+	var defaults models.AlbumDefaults
+	if err := s.fileService.ReadJSON(albumDefaultsFile, &defaults); err != nil {
+		return nil, fmt.Errorf("failed to read album defaults: %w", err)
+	}
+
+	return &defaults, nil
+}
+
+// Update validates and persists new album defaults.
+func (s *AlbumDefaultsService) Update(defaults *models.AlbumDefaults) error {
+	if err := defaults.Validate(); err != nil {
+		return fmt.Errorf("%w: %w", ErrValidation, err)
+	}
+
+	if err := s.fileService.WriteJSON(albumDefaultsFile, defaults); err != nil {
+		return fmt.Errorf("failed to write album defaults: %w", err)
+	}
+
+	return nil
+}
+
+// getDefaultAlbumDefaults returns the built-in defaults used before any are saved.
+func (s *AlbumDefaultsService) getDefaultAlbumDefaults() *models.AlbumDefaults {
+	return &models.AlbumDefaults{
+		Visibility:     "public",
+		AllowDownloads: false,
+	}
+}
@@ -0,0 +1,60 @@
+package services
+
+import "github.com/njoubert/nielsshootsfilm/backend/internal/models"
+
+// SharedPhoto pairs photos with the same content in two albums.
+type SharedPhoto struct {
+	A models.Photo
+	B models.Photo
+}
+
+// PhotoDiff compares the photo sets of two albums by content hash.
+type PhotoDiff struct {
+	OnlyA  []models.Photo
+	OnlyB  []models.Photo
+	Shared []SharedPhoto
+	// Photos uploaded before hashes were recorded cannot be matched, so they are set aside
+	UnhashedA []models.Photo
+	UnhashedB []models.Photo
+}
+
+// DiffPhotos compares two albums' photos by content, so a copy can be reconciled with the
+// album it came from. Each distinct file counts once: repeat uploads within an album are
+// represented by their first photo. Lists keep album order, and Shared follows album a.
+func DiffPhotos(a, b *models.Album) PhotoDiff {
+	diff := PhotoDiff{
+		OnlyA:  []models.Photo{},
+		OnlyB:  []models.Photo{},
+		Shared: []SharedPhoto{},
+	}
+	inA := photosByContent(a)
+	inB := photosByContent(b)
+
+	for _, photo := range a.Photos {
+		if photo.ContentHash == "" {
+			diff.UnhashedA = append(diff.UnhashedA, photo)
+			continue
+		}
+		if inA[photo.ContentHash].ID != photo.ID {
+			continue
+		}
+		if other, ok := inB[photo.ContentHash]; ok {
+			diff.Shared = append(diff.Shared, SharedPhoto{A: photo, B: other})
+		} else {
+			diff.OnlyA = append(diff.OnlyA, photo)
+		}
+	}
+	for _, photo := range b.Photos {
+		if photo.ContentHash == "" {
+			diff.UnhashedB = append(diff.UnhashedB, photo)
+			continue
+		}
+		if inB[photo.ContentHash].ID != photo.ID {
+			continue
+		}
+		if _, ok := inA[photo.ContentHash]; !ok {
+			diff.OnlyB = append(diff.OnlyB, photo)
+		}
+	}
+	return diff
+}
@@ -0,0 +1,97 @@
+package services
+
+import (
+	"testing"
+
+	"github.com/njoubert/nielsshootsfilm/backend/internal/models"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDiffPhotos(t *testing.T) {
+	photo := func(id, hash string) models.Photo {
+		return models.Photo{ID: id, ContentHash: hash}
+	}
+	ids := func(photos []models.Photo) []string {
+		out := []string{}
+		for _, p := range photos {
+			out = append(out, p.ID)
+		}
+		return out
+	}
+	pairs := func(shared []SharedPhoto) [][2]string {
+		out := [][2]string{}
+		for _, pair := range shared {
+			out = append(out, [2]string{pair.A.ID, pair.B.ID})
+		}
+		return out
+	}
+
+	tests := []struct {
+		name      string
+		a, b      []models.Photo
+		onlyA     []string
+		onlyB     []string
+		shared    [][2]string
+		unhashedA []string
+		unhashedB []string
+	}{
+		{
+			name:   "overlapping",
+			a:      []models.Photo{photo("a1", "h1"), photo("a2", "h2"), photo("a3", "h3")},
+			b:      []models.Photo{photo("b3", "h3"), photo("b4", "h4"), photo("b2", "h2")},
+			onlyA:  []string{"a1"},
+			onlyB:  []string{"b4"},
+			shared: [][2]string{{"a2", "b2"}, {"a3", "b3"}},
+		},
+		{
+			name:   "disjoint",
+			a:      []models.Photo{photo("a1", "h1"), photo("a2", "h2")},
+			b:      []models.Photo{photo("b3", "h3")},
+			onlyA:  []string{"a1", "a2"},
+			onlyB:  []string{"b3"},
+			shared: [][2]string{},
+		},
+		{
+			name:   "identical",
+			a:      []models.Photo{photo("a1", "h1"), photo("a2", "h2")},
+			b:      []models.Photo{photo("b1", "h1"), photo("b2", "h2")},
+			onlyA:  []string{},
+			onlyB:  []string{},
+			shared: [][2]string{{"a1", "b1"}, {"a2", "b2"}},
+		},
+		{
+			name:   "repeat uploads count once",
+			a:      []models.Photo{photo("a1", "h1"), photo("a1-again", "h1"), photo("a2", "h2"), photo("a2-again", "h2")},
+			b:      []models.Photo{photo("b1", "h1"), photo("b1-again", "h1")},
+			onlyA:  []string{"a2"},
+			onlyB:  []string{},
+			shared: [][2]string{{"a1", "b1"}},
+		},
+		{
+			name:      "photos without hashes are set aside",
+			a:         []models.Photo{photo("a1", "h1"), photo("legacy-a", "")},
+			b:         []models.Photo{photo("legacy-b", "")},
+			onlyA:     []string{"a1"},
+			onlyB:     []string{},
+			shared:    [][2]string{},
+			unhashedA: []string{"legacy-a"},
+			unhashedB: []string{"legacy-b"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			diff := DiffPhotos(&models.Album{Photos: tt.a}, &models.Album{Photos: tt.b})
+			assert.Equal(t, tt.onlyA, ids(diff.OnlyA))
+			assert.Equal(t, tt.onlyB, ids(diff.OnlyB))
+			assert.Equal(t, tt.shared, pairs(diff.Shared))
+			if tt.unhashedA == nil {
+				tt.unhashedA = []string{}
+			}
+			if tt.unhashedB == nil {
+				tt.unhashedB = []string{}
+			}
+			assert.Equal(t, tt.unhashedA, ids(diff.UnhashedA))
+			assert.Equal(t, tt.unhashedB, ids(diff.UnhashedB))
+		})
+	}
+}
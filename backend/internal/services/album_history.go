@@ -0,0 +1,196 @@
+package services
+
+import (
+	"fmt"
+	"log/slog"
+	"slices"
+	"time"
+
+	"github.com/njoubert/nielsshootsfilm/backend/internal/models"
+)
+
+const albumHistoryFile = "album_history.json"
+
+// History returns up to limit of an album's history entries, oldest first, skipping the first
+// offset, along with the total number of entries. A limit of zero or less returns them all.
+func (s *AlbumService) History(albumID string, offset, limit int) ([]models.AlbumHistoryEntry, int, error) {
+	if _, err := s.GetByID(albumID); err != nil {
+		return nil, 0, err
+	}
+
+	history, err := s.readHistory()
+	if err != nil {
+		return nil, 0, err
+	}
+
+	entries := []models.AlbumHistoryEntry{}
+	for _, entry := range history.Entries {
+		if entry.AlbumID == albumID {
+			entries = append(entries, entry)
+		}
+	}
+
+	total := len(entries)
+	start := min(max(offset, 0), total)
+	end := total
+	if limit > 0 {
+		end = min(start+limit, total)
+	}
+	return entries[start:end], total, nil
+}
+
+// readHistory loads the history of every album.
+func (s *AlbumService) readHistory() (*models.AlbumHistory, error) {
+	history := &models.AlbumHistory{Entries: []models.AlbumHistoryEntry{}}
+	if !s.fileService.FileExists(albumHistoryFile) {
+		return history, nil
+	}
+
+	if err := s.fileService.ReadJSON(albumHistoryFile, history); err != nil {
+		return nil, fmt.Errorf("failed to read album history: %w", err)
+	}
+	return history, nil
+}
+
+// MaxAlbumHistoryEntries is how many history entries are kept per album; older ones are dropped.
+const MaxAlbumHistoryEntries = 500
+
+// recordHistory appends the entries describing the change from before to after, dropping the
+// album's oldest entries beyond MaxAlbumHistoryEntries. A nil before records the album's
+// creation. The change itself is already saved by then, so failures are logged rather than
+// returned.
+func (s *AlbumService) recordHistory(before, after *models.Album) {
+	entries := albumHistoryEntries(before, after, after.UpdatedAt)
+	if len(entries) == 0 {
+		return
+	}
+
+	err := s.changeHistory(func(history *models.AlbumHistory) {
+		history.Entries = append(history.Entries, entries...)
+		history.Entries = capAlbumHistory(history.Entries, after.ID, MaxAlbumHistoryEntries)
+	})
+	if err != nil {
+		s.logger.Error("failed to record album history",
+			slog.String("album_id", after.ID),
+			slog.String("error", err.Error()))
+	}
+}
+
+// forgetHistory drops a deleted album's history entries. The album is already deleted by
+// then, so failures are logged rather than returned.
+func (s *AlbumService) forgetHistory(albumID string) {
+	if !s.fileService.FileExists(albumHistoryFile) {
+		return
+	}
+
+	err := s.changeHistory(func(history *models.AlbumHistory) {
+		history.Entries = slices.DeleteFunc(history.Entries, func(entry models.AlbumHistoryEntry) bool {
+			return entry.AlbumID == albumID
+		})
+	})
+	if err != nil {
+		s.logger.Error("failed to forget album history",
+			slog.String("album_id", albumID),
+			slog.String("error", err.Error()))
+	}
+}
+
+// changeHistory applies change to the stored history and saves it.
+func (s *AlbumService) changeHistory(change func(*models.AlbumHistory)) error {
+	history, err := s.readHistory()
+	if err != nil {
+		return err
+	}
+	change(history)
+
+	if err := s.fileService.WriteJSON(albumHistoryFile, history); err != nil {
+		return fmt.Errorf("failed to write album history: %w", err)
+	}
+	return nil
+}
+
+// capAlbumHistory drops an album's oldest entries so it has at most limit, keeping the
+// order of the rest and other albums' entries.
+func capAlbumHistory(entries []models.AlbumHistoryEntry, albumID string, limit int) []models.AlbumHistoryEntry {
+	excess := -limit
+	for _, entry := range entries {
+		if entry.AlbumID == albumID {
+			excess++
+		}
+	}
+	if excess <= 0 {
+		return entries
+	}
+	return slices.DeleteFunc(entries, func(entry models.AlbumHistoryEntry) bool {
+		if entry.AlbumID != albumID || excess == 0 {
+			return false
+		}
+		excess--
+		return true
+	})
+}
+
+// albumHistoryEntries describes the change from before to after as history entries. Edits
+// other than the title, the photos, and their order are not recorded.
+func albumHistoryEntries(before, after *models.Album, at time.Time) []models.AlbumHistoryEntry {
+	if before == nil {
+		return []models.AlbumHistoryEntry{{
+			AlbumID: after.ID, Type: models.AlbumHistoryCreated, At: at, Title: after.Title,
+		}}
+	}
+
+	entries := []models.AlbumHistoryEntry{}
+	if before.Title != after.Title {
+		entries = append(entries, models.AlbumHistoryEntry{
+			AlbumID: after.ID, Type: models.AlbumHistoryRenamed, At: at, Title: after.Title, PreviousTitle: before.Title,
+		})
+	}
+
+	beforeIDs := photoIDSet(before.Photos)
+	afterIDs := photoIDSet(after.Photos)
+
+	added := []string{}
+	kept := []string{}
+	for _, photo := range after.Photos {
+		if beforeIDs[photo.ID] {
+			kept = append(kept, photo.ID)
+		} else {
+			added = append(added, photo.ID)
+		}
+	}
+	removed := []string{}
+	keptBefore := []string{}
+	for _, photo := range before.Photos {
+		if afterIDs[photo.ID] {
+			keptBefore = append(keptBefore, photo.ID)
+		} else {
+			removed = append(removed, photo.ID)
+		}
+	}
+
+	if len(added) > 0 {
+		entries = append(entries, models.AlbumHistoryEntry{
+			AlbumID: after.ID, Type: models.AlbumHistoryPhotosAdded, At: at, PhotoIDs: added,
+		})
+	}
+	if len(removed) > 0 {
+		entries = append(entries, models.AlbumHistoryEntry{
+			AlbumID: after.ID, Type: models.AlbumHistoryPhotosRemoved, At: at, PhotoIDs: removed,
+		})
+	}
+	// Photos present before and after that now sit in a different relative order
+	if !slices.Equal(kept, keptBefore) {
+		entries = append(entries, models.AlbumHistoryEntry{
+			AlbumID: after.ID, Type: models.AlbumHistoryReordered, At: at,
+		})
+	}
+	return entries
+}
+
+func photoIDSet(photos []models.Photo) map[string]bool {
+	ids := make(map[string]bool, len(photos))
+	for _, photo := range photos {
+		ids[photo.ID] = true
+	}
+	return ids
+}
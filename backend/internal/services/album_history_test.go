@@ -0,0 +1,129 @@
+package services
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"github.com/njoubert/nielsshootsfilm/backend/internal/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAlbumService_History(t *testing.T) {
+	service, _ := setupAlbumService(t)
+
+	album := &models.Album{Title: "Draft", Visibility: "public"}
+	require.NoError(t, service.Create(album))
+	other := &models.Album{Title: "Other", Visibility: "public"}
+	require.NoError(t, service.Create(other))
+
+	ids := []string{}
+	for _, name := range []string{"1.jpg", "2.jpg", "3.jpg"} {
+		photo := &models.Photo{FilenameOriginal: name}
+		require.NoError(t, service.AddPhoto(album.ID, photo))
+		ids = append(ids, photo.ID)
+	}
+
+	stored, err := service.GetByID(album.ID)
+	require.NoError(t, err)
+	stored.Title = "Final"
+	stored.Description = "Not recorded"
+	require.NoError(t, service.Update(album.ID, stored))
+
+	require.NoError(t, service.ReorderPhotos(album.ID, []string{ids[2], ids[0], ids[1]}))
+	require.NoError(t, service.DeletePhoto(album.ID, ids[0]))
+	require.NoError(t, service.SetCoverPhoto(album.ID, ids[1])) // Not recorded
+
+	entries, total, err := service.History(album.ID, 0, 0)
+	require.NoError(t, err)
+	assert.Equal(t, 7, total)
+
+	types := []string{}
+	for _, entry := range entries {
+		assert.Equal(t, album.ID, entry.AlbumID)
+		assert.False(t, entry.At.IsZero())
+		types = append(types, entry.Type)
+	}
+	assert.Equal(t, []string{
+		models.AlbumHistoryCreated,
+		models.AlbumHistoryPhotosAdded,
+		models.AlbumHistoryPhotosAdded,
+		models.AlbumHistoryPhotosAdded,
+		models.AlbumHistoryRenamed,
+		models.AlbumHistoryReordered,
+		models.AlbumHistoryPhotosRemoved,
+	}, types)
+	assert.Equal(t, "Draft", entries[0].Title)
+	assert.Equal(t, []string{ids[1]}, entries[2].PhotoIDs)
+	assert.Equal(t, "Final", entries[4].Title)
+	assert.Equal(t, "Draft", entries[4].PreviousTitle)
+	assert.Equal(t, []string{ids[0]}, entries[6].PhotoIDs)
+	for i := 1; i < len(entries); i++ {
+		assert.False(t, entries[i].At.Before(entries[i-1].At), "entries are chronological")
+	}
+
+	// Pages slice the same sequence
+	page, total, err := service.History(album.ID, 4, 2)
+	require.NoError(t, err)
+	assert.Equal(t, 7, total)
+	assert.Equal(t, entries[4:6], page)
+
+	page, _, err = service.History(album.ID, 10, 2)
+	require.NoError(t, err)
+	assert.Empty(t, page)
+
+	// Other albums keep their own history
+	otherEntries, total, err := service.History(other.ID, 0, 0)
+	require.NoError(t, err)
+	assert.Equal(t, 1, total)
+	assert.Equal(t, models.AlbumHistoryCreated, otherEntries[0].Type)
+
+	// Deleting an album drops its history
+	require.NoError(t, service.Delete(album.ID))
+	_, _, err = service.History(album.ID, 0, 0)
+	assert.EqualError(t, err, "album not found")
+	history, err := service.readHistory()
+	require.NoError(t, err)
+	assert.Len(t, history.Entries, 1)
+}
+
+func TestAlbumService_HistoryFailureKeepsChange(t *testing.T) {
+	service, dataDir := setupAlbumService(t)
+
+	// An unreadable history file fails the history write, not the saved change
+	require.NoError(t, os.WriteFile(filepath.Join(dataDir, albumHistoryFile), []byte("{not json"), 0o600))
+
+	album := &models.Album{Title: "Launch", Visibility: "public"}
+	require.NoError(t, service.Create(album))
+	stored, err := service.GetByID(album.ID)
+	require.NoError(t, err)
+	stored.Title = "Launch Party"
+	require.NoError(t, service.Update(album.ID, stored))
+	require.NoError(t, service.Delete(album.ID))
+
+	albums, err := service.GetAll()
+	require.NoError(t, err)
+	assert.Empty(t, albums)
+}
+
+func TestCapAlbumHistory(t *testing.T) {
+	entries := []models.AlbumHistoryEntry{
+		{AlbumID: "a", Title: "a1"},
+		{AlbumID: "b", Title: "b1"},
+		{AlbumID: "a", Title: "a2"},
+		{AlbumID: "a", Title: "a3"},
+		{AlbumID: "b", Title: "b2"},
+	}
+
+	capped := capAlbumHistory(slices.Clone(entries), "a", 2)
+	assert.Equal(t, []models.AlbumHistoryEntry{
+		{AlbumID: "b", Title: "b1"},
+		{AlbumID: "a", Title: "a2"},
+		{AlbumID: "a", Title: "a3"},
+		{AlbumID: "b", Title: "b2"},
+	}, capped)
+
+	assert.Equal(t, entries, capAlbumHistory(slices.Clone(entries), "b", 2))
+}
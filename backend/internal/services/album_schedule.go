@@ -0,0 +1,151 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"slices"
+	"time"
+
+	"github.com/njoubert/nielsshootsfilm/backend/internal/models"
+)
+
+// DefaultScheduleInterval is how often albums are checked for scheduled changes that are due.
+const DefaultScheduleInterval = time.Minute
+
+// VisibilityTransition is a scheduled visibility change made to an album.
+type VisibilityTransition struct {
+	AlbumID     string    `json:"album_id"`
+	Slug        string    `json:"slug"`
+	From        string    `json:"from"`
+	To          string    `json:"to"`
+	ScheduledAt time.Time `json:"scheduled_at"`
+}
+
+// SetSchedule replaces an album's scheduled visibility changes, which must all be after now.
+// Changes to password_protected need the album to have a password already. An empty list
+// clears the schedule. Changes are stored soonest first.
+func (s *AlbumService) SetSchedule(albumID string, changes []models.ScheduledChange, now time.Time) (*models.Album, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	album, err := s.GetByID(albumID)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, change := range changes {
+		if !change.At.IsZero() && !change.At.After(now) {
+			return nil, fmt.Errorf("%w: scheduled change at %s is not in the future", ErrValidation, change.At.Format(time.RFC3339))
+		}
+		if change.Visibility == "password_protected" && album.PasswordHash == "" {
+			return nil, fmt.Errorf("%w: album has no password to protect it with", ErrValidation)
+		}
+	}
+	album.ScheduledChanges = changes
+
+	if err := s.update(albumID, album); err != nil {
+		return nil, err
+	}
+	return s.GetByID(albumID)
+}
+
+// sortSchedule orders an album's scheduled changes soonest first, keeping the given order
+// of changes scheduled for the same time.
+func sortSchedule(album *models.Album) {
+	slices.SortStableFunc(album.ScheduledChanges, func(a, b models.ScheduledChange) int {
+		return a.At.Compare(b.At)
+	})
+	if len(album.ScheduledChanges) == 0 {
+		album.ScheduledChanges = nil
+	}
+}
+
+// ApplyScheduledChanges makes every scheduled change due by now, soonest first, and removes
+// it from its album's schedule. An album with several changes due ends with the visibility
+// of the latest. Returns the transitions made, including those that left the visibility as
+// it was. A change to password_protected on an album whose password has since been removed
+// is dropped with a warning rather than leaving the album protected by no password. An album
+// that cannot be saved keeps its schedule, to be tried again next time.
+// Each album is re-read under the store lock and only its visibility and schedule change,
+// so edits saved since the albums were listed are kept.
+func (s *AlbumService) ApplyScheduledChanges(now time.Time) ([]VisibilityTransition, error) {
+	albums, err := s.GetAll()
+	if err != nil {
+		return nil, err
+	}
+
+	var transitions []VisibilityTransition
+	var errs []error
+	for _, listed := range albums {
+		if len(scheduleDue(&listed, now)) == 0 {
+			continue
+		}
+
+		var applied []VisibilityTransition
+		_, err := s.Modify(listed.ID, func(album *models.Album) error {
+			due := scheduleDue(album, now)
+			if len(due) == 0 {
+				return errAlbumUnchanged
+			}
+			for _, change := range due {
+				if change.Visibility == "password_protected" && album.PasswordHash == "" {
+					s.logger.Warn("scheduled change skipped: album has no password",
+						slog.String("album_id", album.ID),
+						slog.String("slug", album.Slug),
+						slog.Time("scheduled_at", change.At))
+					continue
+				}
+				applied = append(applied, VisibilityTransition{
+					AlbumID:     album.ID,
+					Slug:        album.Slug,
+					From:        album.Visibility,
+					To:          change.Visibility,
+					ScheduledAt: change.At,
+				})
+				album.Visibility = change.Visibility
+			}
+			album.ScheduledChanges = album.ScheduledChanges[len(due):]
+			return nil
+		})
+		if err != nil {
+			errs = append(errs, fmt.Errorf("album %s: %w", listed.ID, err))
+			continue
+		}
+		transitions = append(transitions, applied...)
+	}
+	return transitions, errors.Join(errs...)
+}
+
+// scheduleDue returns the leading changes of an album's schedule that are due by now.
+func scheduleDue(album *models.Album, now time.Time) []models.ScheduledChange {
+	due := 0
+	for due < len(album.ScheduledChanges) && !album.ScheduledChanges[due].At.After(now) {
+		due++
+	}
+	return album.ScheduledChanges[:due]
+}
+
+// StartScheduledChanges applies scheduled changes as they come due, checking every interval
+// in the background, and logs each transition.
+func (s *AlbumService) StartScheduledChanges(interval time.Duration, logger *slog.Logger) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for now := range ticker.C {
+			transitions, err := s.ApplyScheduledChanges(now)
+			for _, transition := range transitions {
+				logger.Info("scheduled visibility change applied",
+					slog.String("album_id", transition.AlbumID),
+					slog.String("slug", transition.Slug),
+					slog.String("from", transition.From),
+					slog.String("to", transition.To),
+					slog.Time("scheduled_at", transition.ScheduledAt))
+			}
+			if err != nil {
+				logger.Error("failed to apply scheduled changes", slog.String("error", err.Error()))
+			}
+		}
+	}()
+}
@@ -0,0 +1,188 @@
+package services
+
+import (
+	"bytes"
+	"log/slog"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/njoubert/nielsshootsfilm/backend/internal/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAlbumService_ScheduledChanges(t *testing.T) {
+	service, _ := setupAlbumService(t)
+	now := time.Now().UTC().Truncate(time.Second)
+
+	gallery := &models.Album{Title: "Smith Wedding", Visibility: "unlisted"}
+	require.NoError(t, service.Create(gallery))
+	other := &models.Album{Title: "Street", Visibility: "public"}
+	require.NoError(t, service.Create(other))
+
+	// Goes public the day after the event and expires a month later; stored soonest first
+	publish, expire := now.Add(24*time.Hour), now.Add(30*24*time.Hour)
+	scheduled, err := service.SetSchedule(gallery.ID, []models.ScheduledChange{
+		{At: expire, Visibility: "unlisted"},
+		{At: publish, Visibility: "public"},
+	}, now)
+	require.NoError(t, err)
+	require.Len(t, scheduled.ScheduledChanges, 2)
+	assert.True(t, scheduled.ScheduledChanges[0].At.Equal(publish))
+	assert.True(t, scheduled.ScheduledChanges[1].At.Equal(expire))
+
+	// Nothing is due yet
+	transitions, err := service.ApplyScheduledChanges(now.Add(time.Hour))
+	require.NoError(t, err)
+	assert.Empty(t, transitions)
+
+	// Past the first point, the album goes public and the change leaves the schedule
+	transitions, err = service.ApplyScheduledChanges(publish.Add(time.Minute))
+	require.NoError(t, err)
+	require.Len(t, transitions, 1)
+	assert.Equal(t, VisibilityTransition{AlbumID: gallery.ID, Slug: gallery.Slug, From: "unlisted", To: "public", ScheduledAt: publish}, transitions[0])
+	stored, err := service.GetByID(gallery.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "public", stored.Visibility)
+	require.Len(t, stored.ScheduledChanges, 1)
+
+	// Applying again at the same time changes nothing
+	transitions, err = service.ApplyScheduledChanges(publish.Add(time.Minute))
+	require.NoError(t, err)
+	assert.Empty(t, transitions)
+
+	// Past the second, it expires and the schedule is empty
+	transitions, err = service.ApplyScheduledChanges(expire)
+	require.NoError(t, err)
+	require.Len(t, transitions, 1)
+	assert.Equal(t, "public", transitions[0].From)
+	assert.Equal(t, "unlisted", transitions[0].To)
+	stored, err = service.GetByID(gallery.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "unlisted", stored.Visibility)
+	assert.Nil(t, stored.ScheduledChanges)
+
+	unchanged, err := service.GetByID(other.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "public", unchanged.Visibility)
+}
+
+func TestAlbumService_ScheduledChanges_SeveralDue(t *testing.T) {
+	service, _ := setupAlbumService(t)
+	now := time.Now().UTC()
+
+	album := &models.Album{Title: "Gala", Visibility: "unlisted", PasswordHash: "hash"}
+	require.NoError(t, service.Create(album))
+	_, err := service.SetSchedule(album.ID, []models.ScheduledChange{
+		{At: now.Add(time.Hour), Visibility: "public"},
+		{At: now.Add(2 * time.Hour), Visibility: "password_protected"},
+		{At: now.Add(3 * time.Hour), Visibility: "public"},
+	}, now)
+	require.NoError(t, err)
+
+	// A server that was down past two points catches up on both, in order
+	transitions, err := service.ApplyScheduledChanges(now.Add(150 * time.Minute))
+	require.NoError(t, err)
+	require.Len(t, transitions, 2)
+	assert.Equal(t, []string{"unlisted", "public"}, []string{transitions[0].From, transitions[1].From})
+	assert.Equal(t, []string{"public", "password_protected"}, []string{transitions[0].To, transitions[1].To})
+
+	stored, err := service.GetByID(album.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "password_protected", stored.Visibility)
+	require.Len(t, stored.ScheduledChanges, 1)
+	assert.Equal(t, "public", stored.ScheduledChanges[0].Visibility)
+}
+
+func TestAlbumService_ScheduledChanges_SkipsProtectionWithoutPassword(t *testing.T) {
+	service, _ := setupAlbumService(t)
+	var logs bytes.Buffer
+	service.SetLogger(slog.New(slog.NewTextHandler(&logs, nil)))
+	now := time.Now().UTC()
+
+	album := &models.Album{Title: "Gala", Visibility: "public", PasswordHash: "hash"}
+	require.NoError(t, service.Create(album))
+	_, err := service.SetSchedule(album.ID, []models.ScheduledChange{{At: now.Add(time.Hour), Visibility: "password_protected"}}, now)
+	require.NoError(t, err)
+
+	// The password is removed after the change was scheduled
+	_, err = service.Modify(album.ID, func(album *models.Album) error {
+		album.PasswordHash = ""
+		return nil
+	})
+	require.NoError(t, err)
+
+	// The change is dropped with a warning instead of protecting the album with no password
+	transitions, err := service.ApplyScheduledChanges(now.Add(2 * time.Hour))
+	require.NoError(t, err)
+	assert.Empty(t, transitions)
+	assert.Contains(t, logs.String(), "scheduled change skipped")
+
+	stored, err := service.GetByID(album.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "public", stored.Visibility)
+	assert.Empty(t, stored.ScheduledChanges)
+}
+
+func TestAlbumService_ScheduledChanges_KeepsConcurrentEdits(t *testing.T) {
+	service, _ := setupAlbumService(t)
+	now := time.Now().UTC()
+
+	var albumIDs []string
+	for range 5 {
+		album := &models.Album{Title: "Launch", Visibility: "unlisted"}
+		require.NoError(t, service.Create(album))
+		_, err := service.SetSchedule(album.ID, []models.ScheduledChange{{At: now.Add(time.Hour), Visibility: "public"}}, now)
+		require.NoError(t, err)
+		albumIDs = append(albumIDs, album.ID)
+	}
+
+	// Photos uploaded while the schedule is applied are kept
+	var wg sync.WaitGroup
+	for _, albumID := range albumIDs {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			assert.NoError(t, service.AddPhoto(albumID, &models.Photo{FilenameOriginal: "new.jpg"}))
+		}()
+	}
+	transitions, err := service.ApplyScheduledChanges(now.Add(2 * time.Hour))
+	wg.Wait()
+	require.NoError(t, err)
+	assert.Len(t, transitions, 5)
+
+	for _, albumID := range albumIDs {
+		stored, err := service.GetByID(albumID)
+		require.NoError(t, err)
+		assert.Equal(t, "public", stored.Visibility)
+		assert.Empty(t, stored.ScheduledChanges)
+		assert.Len(t, stored.Photos, 1)
+	}
+}
+
+func TestAlbumService_SetSchedule_Invalid(t *testing.T) {
+	service, _ := setupAlbumService(t)
+	now := time.Now().UTC()
+
+	album := &models.Album{Title: "Proofs", Visibility: "unlisted"}
+	require.NoError(t, service.Create(album))
+
+	_, err := service.SetSchedule(album.ID, []models.ScheduledChange{{At: now.Add(-time.Minute), Visibility: "public"}}, now)
+	assert.ErrorContains(t, err, "not in the future")
+	_, err = service.SetSchedule(album.ID, []models.ScheduledChange{{At: now.Add(time.Hour), Visibility: "hidden"}}, now)
+	assert.ErrorContains(t, err, "scheduled visibility must be")
+	_, err = service.SetSchedule(album.ID, []models.ScheduledChange{{Visibility: "public"}}, now)
+	assert.ErrorContains(t, err, "scheduled change time is required")
+	_, err = service.SetSchedule(album.ID, []models.ScheduledChange{{At: now.Add(time.Hour), Visibility: "password_protected"}}, now)
+	assert.ErrorContains(t, err, "album has no password")
+	_, err = service.SetSchedule("missing", nil, now)
+	assert.EqualError(t, err, "album not found")
+
+	// An empty list clears the schedule
+	_, err = service.SetSchedule(album.ID, []models.ScheduledChange{{At: now.Add(time.Hour), Visibility: "public"}}, now)
+	require.NoError(t, err)
+	cleared, err := service.SetSchedule(album.ID, []models.ScheduledChange{}, now)
+	require.NoError(t, err)
+	assert.Nil(t, cleared.ScheduledChanges)
+}
@@ -0,0 +1,85 @@
+package services
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/google/uuid"
+	"github.com/njoubert/nielsshootsfilm/backend/internal/models"
+)
+
+// SectionByDay groups photos into one section per capture day.
+const SectionByDay = "day"
+
+// AutoSection replaces an album's sections with ones derived from its photos' EXIF data.
+// With SectionByDay there is one section per capture day, in date order, titled with the date.
+// Photos without a capture date are left out of every section.
+func (s *AlbumService) AutoSection(id, by string) (*models.Album, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if by != SectionByDay {
+		return nil, fmt.Errorf("unsupported grouping %q (supported: %s)", by, SectionByDay)
+	}
+
+	album, err := s.GetByID(id)
+	if err != nil {
+		return nil, err
+	}
+
+	album.Sections = sectionsByDay(album.Photos)
+	if err := s.update(id, album); err != nil {
+		return nil, err
+	}
+	return album, nil
+}
+
+// sectionsByDay buckets photos by the calendar day they were taken, as recorded by the camera.
+func sectionsByDay(photos []models.Photo) []models.AlbumSection {
+	byDay := map[string]*models.AlbumSection{}
+	days := []string{}
+	for _, photo := range photos {
+		if photo.EXIF == nil || photo.EXIF.DateTaken == nil {
+			continue
+		}
+
+		taken := *photo.EXIF.DateTaken
+		day := taken.Format("2006-01-02")
+		section, ok := byDay[day]
+		if !ok {
+			section = &models.AlbumSection{Title: taken.Format("Monday, January 2, 2006")}
+			byDay[day] = section
+			days = append(days, day)
+		}
+		section.PhotoIDs = append(section.PhotoIDs, photo.ID)
+	}
+
+	sort.Strings(days)
+	sections := make([]models.AlbumSection, 0, len(days))
+	for _, day := range days {
+		sections = append(sections, *byDay[day])
+	}
+	return sections
+}
+
+// tidySections gives new sections an ID and drops photo IDs that are not in the album, or that
+// an earlier section already claimed, so deleted photos fall out of their sections.
+func tidySections(album *models.Album) {
+	inAlbum := photoIDSet(album.Photos)
+	claimed := make(map[string]bool)
+	for i := range album.Sections {
+		section := &album.Sections[i]
+		if section.ID == "" {
+			section.ID = uuid.New().String()
+		}
+
+		kept := []string{}
+		for _, id := range section.PhotoIDs {
+			if inAlbum[id] && !claimed[id] {
+				claimed[id] = true
+				kept = append(kept, id)
+			}
+		}
+		section.PhotoIDs = kept
+	}
+}
@@ -0,0 +1,104 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"github.com/njoubert/nielsshootsfilm/backend/internal/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAlbumService_AutoSection_ByDay(t *testing.T) {
+	service, _ := setupAlbumService(t)
+
+	taken := func(value string) *models.EXIF {
+		at, err := time.Parse(time.RFC3339, value)
+		require.NoError(t, err)
+		return &models.EXIF{DateTaken: &at}
+	}
+
+	album := &models.Album{Title: "Wedding Weekend", Visibility: "public"}
+	require.NoError(t, service.Create(album))
+	for _, photo := range []*models.Photo{
+		{FilenameOriginal: "ceremony.jpg", EXIF: taken("2024-06-01T15:00:00+02:00")},
+		{FilenameOriginal: "rehearsal.jpg", EXIF: taken("2024-05-31T18:30:00+02:00")},
+		{FilenameOriginal: "scan.jpg"}, // Film scan with no capture date
+		{FilenameOriginal: "party.jpg", EXIF: taken("2024-06-01T23:59:00+02:00")},
+		{FilenameOriginal: "brunch.jpg", EXIF: taken("2024-06-02T00:10:00+02:00")},
+		{FilenameOriginal: "no-date.jpg", EXIF: &models.EXIF{Camera: "Leica M6"}},
+	} {
+		require.NoError(t, service.AddPhoto(album.ID, photo))
+	}
+
+	sectioned, err := service.AutoSection(album.ID, SectionByDay)
+	require.NoError(t, err)
+
+	stored, err := service.GetByID(album.ID)
+	require.NoError(t, err)
+	assert.Equal(t, sectioned.Sections, stored.Sections)
+
+	// Days are bucketed by the camera's local date, in date order
+	filenames := func(photos []models.Photo) []string {
+		names := []string{}
+		for _, photo := range photos {
+			names = append(names, photo.FilenameOriginal)
+		}
+		return names
+	}
+	grouped := stored.GroupedPhotos()
+	require.Len(t, grouped, 4)
+	assert.Equal(t, "Friday, May 31, 2024", grouped[0].Title)
+	assert.Equal(t, []string{"rehearsal.jpg"}, filenames(grouped[0].Photos))
+	assert.Equal(t, "Saturday, June 1, 2024", grouped[1].Title)
+	assert.Equal(t, []string{"ceremony.jpg", "party.jpg"}, filenames(grouped[1].Photos))
+	assert.Equal(t, "Sunday, June 2, 2024", grouped[2].Title)
+	assert.Equal(t, []string{"brunch.jpg"}, filenames(grouped[2].Photos))
+	for _, section := range grouped[:3] {
+		assert.NotEmpty(t, section.ID)
+	}
+
+	// Undated photos stay out of every section
+	assert.Empty(t, grouped[3].ID)
+	assert.Equal(t, []string{"scan.jpg", "no-date.jpg"}, filenames(grouped[3].Photos))
+
+	// The flat list is unchanged
+	assert.Len(t, stored.Photos, 6)
+
+	_, err = service.AutoSection(album.ID, "week")
+	assert.Error(t, err)
+	_, err = service.AutoSection("missing", SectionByDay)
+	assert.EqualError(t, err, "album not found")
+}
+
+func TestAlbumService_Sections_DropDeletedPhotos(t *testing.T) {
+	service, _ := setupAlbumService(t)
+
+	album := &models.Album{Title: "Manual", Visibility: "public"}
+	require.NoError(t, service.Create(album))
+	first := &models.Photo{FilenameOriginal: "a.jpg"}
+	second := &models.Photo{FilenameOriginal: "b.jpg"}
+	require.NoError(t, service.AddPhoto(album.ID, first))
+	require.NoError(t, service.AddPhoto(album.ID, second))
+
+	stored, err := service.GetByID(album.ID)
+	require.NoError(t, err)
+	stored.Sections = []models.AlbumSection{
+		{Title: "Portraits", PhotoIDs: []string{first.ID, "unknown"}},
+		{Title: "Details", PhotoIDs: []string{second.ID, first.ID}},
+	}
+	require.NoError(t, service.Update(album.ID, stored))
+
+	stored, err = service.GetByID(album.ID)
+	require.NoError(t, err)
+	require.Len(t, stored.Sections, 2)
+	assert.NotEmpty(t, stored.Sections[0].ID)
+	assert.Equal(t, []string{first.ID}, stored.Sections[0].PhotoIDs)
+	assert.Equal(t, []string{second.ID}, stored.Sections[1].PhotoIDs, "a photo belongs to one section")
+
+	require.NoError(t, service.DeletePhoto(album.ID, first.ID))
+	stored, err = service.GetByID(album.ID)
+	require.NoError(t, err)
+	assert.Empty(t, stored.Sections[0].PhotoIDs)
+	assert.Equal(t, []string{second.ID}, stored.Sections[1].PhotoIDs)
+}
@@ -1,9 +1,15 @@
 package services
 
 import (
+	"cmp"
 	"errors"
 	"fmt"
+	"log/slog"
+	"path/filepath"
+	"regexp"
+	"slices"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/google/uuid"
@@ -12,18 +18,86 @@ import (
 
 const albumsFile = "albums.json"
 
+// ErrValidation is returned, wrapped with the reason, for album changes that cannot be made as
+// asked, such as an invalid field or a photo that is not in the album.
+var ErrValidation = errors.New("validation failed")
+
 // AlbumService handles album CRUD operations.
 type AlbumService struct {
-	fileService *FileService
+	mu              sync.Mutex // Held from read to write of each change to albums.json, so concurrent changes are not lost
+	fileService     *FileService
+	defaultsService *AlbumDefaultsService
+	logger          *slog.Logger
 }
 
 // NewAlbumService creates a new album service.
 func NewAlbumService(fileService *FileService) *AlbumService {
 	return &AlbumService{
 		fileService: fileService,
+		logger:      slog.Default(),
 	}
 }
 
+// SetLogger sets the logger for failures that do not fail the change being saved, such as
+// recording album history.
+func (s *AlbumService) SetLogger(logger *slog.Logger) {
+	s.logger = logger
+}
+
+// SetDefaultsService configures the album service to apply admin-configured defaults on create.
+func (s *AlbumService) SetDefaultsService(defaultsService *AlbumDefaultsService) {
+	s.defaultsService = defaultsService
+}
+
+// NewAlbum returns an album pre-populated with the configured defaults.
+// Decoding a request body into the result lets explicit fields override the defaults.
+func (s *AlbumService) NewAlbum() (*models.Album, error) {
+	album := &models.Album{}
+	if s.defaultsService == nil {
+		return album, nil
+	}
+
+	defaults, err := s.defaultsService.Get()
+	if err != nil {
+		return nil, err
+	}
+
+	album.Visibility = defaults.Visibility
+	album.Layout = defaults.Layout
+	album.AllowDownloads = defaults.AllowDownloads
+	album.ThemeOverride = defaults.ThemeOverride
+	album.WatermarkEnabled = defaults.WatermarkEnabled
+	album.WatermarkCover = defaults.WatermarkCover
+
+	return album, nil
+}
+
+// applyDefaults fills in unset fields of an album from the configured defaults. Booleans such
+// as AllowDownloads and WatermarkEnabled are only inherited through NewAlbum, since here an
+// unset one cannot be told from an explicit false.
+func (s *AlbumService) applyDefaults(album *models.Album) error {
+	if s.defaultsService == nil {
+		return nil
+	}
+
+	defaults, err := s.defaultsService.Get()
+	if err != nil {
+		return err
+	}
+
+	if album.Visibility == "" {
+		album.Visibility = defaults.Visibility
+	}
+	if album.Layout == "" {
+		album.Layout = defaults.Layout
+	}
+	if album.ThemeOverride == "" {
+		album.ThemeOverride = defaults.ThemeOverride
+	}
+
+	return nil
+}
+
 // GetAll returns all albums.
 func (s *AlbumService) GetAll() ([]models.Album, error) {
 	var collection models.AlbumCollection
@@ -37,6 +111,15 @@ func (s *AlbumService) GetAll() ([]models.Album, error) {
 		return nil, fmt.Errorf("failed to read albums: %w", err)
 	}
 
+	// Photos saved before permalinks existed get their slugs derived on read, albums
+	// saved before cover carousels existed get their single cover as the carousel, and
+	// positions saved before albums had to opt in to sharing them are dropped
+	for i := range collection.Albums {
+		assignPhotoSlugs(&collection.Albums[i])
+		syncCoverPhotos(&collection.Albums[i], nil)
+		collection.Albums[i].WithholdLocations()
+	}
+
 	return collection.Albums, nil
 }
 
@@ -56,17 +139,49 @@ func (s *AlbumService) GetByID(id string) (*models.Album, error) {
 	return nil, errors.New("album not found")
 }
 
-// GetBySlug returns an album by its slug.
+// GetBySlug returns an album by its slug in the default (empty) namespace.
 func (s *AlbumService) GetBySlug(slug string) (*models.Album, error) {
+	return s.GetByNamespacedSlug("", slug)
+}
+
+// GetByNamespacedSlug returns an album by its slug within a namespace.
+func (s *AlbumService) GetByNamespacedSlug(namespace, slug string) (*models.Album, error) {
+	albums, err := s.GetAll()
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range albums {
+		if albums[i].Namespace == namespace && albums[i].Slug == slug {
+			return &albums[i], nil
+		}
+	}
+
+	return nil, errors.New("album not found")
+}
+
+// GetByNamespacedSlugFold returns an album by its slug within a namespace, ignoring case.
+// An exact match wins over albums whose slugs only differ from it in case.
+func (s *AlbumService) GetByNamespacedSlugFold(namespace, slug string) (*models.Album, error) {
 	albums, err := s.GetAll()
 	if err != nil {
 		return nil, err
 	}
 
+	var folded *models.Album
 	for i := range albums {
+		if albums[i].Namespace != namespace {
+			continue
+		}
 		if albums[i].Slug == slug {
 			return &albums[i], nil
 		}
+		if folded == nil && strings.EqualFold(albums[i].Slug, slug) {
+			folded = &albums[i]
+		}
+	}
+	if folded != nil {
+		return folded, nil
 	}
 
 	return nil, errors.New("album not found")
@@ -74,29 +189,48 @@ func (s *AlbumService) GetBySlug(slug string) (*models.Album, error) {
 
 // Create creates a new album.
 func (s *AlbumService) Create(album *models.Album) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.create(album)
+}
+
+// create saves a new album. Callers must hold s.mu.
+func (s *AlbumService) create(album *models.Album) error {
 	// Set ID and timestamps
 	album.ID = uuid.New().String()
 	album.CreatedAt = time.Now().UTC()
 	album.UpdatedAt = time.Now().UTC()
 
+	// Fill in any unset fields from the configured defaults
+	if err := s.applyDefaults(album); err != nil {
+		return fmt.Errorf("failed to apply album defaults: %w", err)
+	}
+
 	// Get existing albums
 	albums, err := s.GetAll()
 	if err != nil {
 		return err
 	}
 
-	// Generate slug if not provided
+	// Generate slug if not provided; slugs only need to be unique within the album's namespace
+	neighbors := albumsInNamespace(albums, album.Namespace)
 	if album.Slug == "" {
 		baseSlug := generateSlug(album.Title)
-		album.Slug = generateUniqueSlug(baseSlug, albums)
+		album.Slug = generateUniqueSlug(baseSlug, neighbors)
 	} else {
 		// If slug is provided, ensure it's unique
-		album.Slug = generateUniqueSlug(album.Slug, albums)
+		album.Slug = generateUniqueSlug(album.Slug, neighbors)
 	}
+	tidySections(album)
+	normalizeTags(album)
+	syncCoverPhotos(album, nil)
+	sortSchedule(album)
+	album.WithholdLocations()
 
 	// Validate album
 	if err := album.Validate(); err != nil {
-		return fmt.Errorf("validation failed: %w", err)
+		return fmt.Errorf("%w: %w", ErrValidation, err)
 	}
 
 	// Add album to collection
@@ -107,36 +241,118 @@ func (s *AlbumService) Create(album *models.Album) error {
 		return fmt.Errorf("failed to write albums: %w", err)
 	}
 
+	s.recordHistory(nil, album)
 	return nil
 }
 
 // Update updates an existing album.
 func (s *AlbumService) Update(id string, updates *models.Album) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.update(id, updates)
+}
+
+// Upsert creates the album with slug in namespace, or updates it if there is one, holding the
+// store lock from the lookup to the write so concurrent upserts of one slug cannot both create
+// it. build returns the album to save, given the stored album or nil if there is none; the
+// slug and namespace are set on it. Returns the album as it was stored, nil if it was
+// created, and the saved album.
+func (s *AlbumService) Upsert(namespace, slug string, build func(existing *models.Album) (*models.Album, error)) (*models.Album, *models.Album, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	existing, err := s.GetByNamespacedSlug(namespace, slug)
+	if err != nil && err.Error() != "album not found" {
+		return nil, nil, err
+	}
+
+	album, err := build(existing)
+	if err != nil {
+		return nil, nil, err
+	}
+	album.Slug = slug
+	album.Namespace = namespace
+
+	if existing == nil {
+		if err := s.create(album); err != nil {
+			return nil, nil, err
+		}
+		return nil, album, nil
+	}
+	if err := s.update(existing.ID, album); err != nil {
+		return nil, nil, err
+	}
+	return existing, album, nil
+}
+
+// errAlbumUnchanged is returned by a Modify change that found nothing to change, so the album
+// is not written.
+var errAlbumUnchanged = errors.New("album unchanged")
+
+// Modify applies change to the album as stored and saves it, holding the store lock from the
+// read to the write, so changes saved meanwhile by others are kept. An error from change
+// leaves the album as it was. Returns the saved album.
+func (s *AlbumService) Modify(id string, change func(*models.Album) error) (*models.Album, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	album, err := s.GetByID(id)
+	if err != nil {
+		return nil, err
+	}
+	if err := change(album); errors.Is(err, errAlbumUnchanged) {
+		return album, nil
+	} else if err != nil {
+		return nil, err
+	}
+	if err := s.update(id, album); err != nil {
+		return nil, err
+	}
+	return album, nil
+}
+
+// update saves an existing album. Callers must hold s.mu.
+func (s *AlbumService) update(id string, updates *models.Album) error {
 	albums, err := s.GetAll()
 	if err != nil {
 		return err
 	}
 
+	var before models.Album
 	found := false
 	for i := range albums {
 		if albums[i].ID == id {
 			// Preserve ID and CreatedAt
 			updates.ID = albums[i].ID
 			updates.CreatedAt = albums[i].CreatedAt
+			// Client upload counts only change through ReserveClientUploads
+			updates.ClientUploads = albums[i].ClientUploads
+			updates.ClientUploadBytes = albums[i].ClientUploadBytes
 			updates.UpdatedAt = time.Now().UTC()
+			updates.FilmStocks = updates.DistinctFilmStocks()
+			normalizeTags(updates)
+			keepPhotoSlugs(updates, &albums[i])
+			trackPhotoAdds(updates, &albums[i])
+			assignPhotoSlugs(updates)
+			tidySections(updates)
+			syncCoverPhotos(updates, &albums[i])
+			sortSchedule(updates)
+			updates.WithholdLocations()
 
 			// Validate updates
 			if err := updates.Validate(); err != nil {
-				return fmt.Errorf("validation failed: %w", err)
+				return fmt.Errorf("%w: %w", ErrValidation, err)
 			}
 
-			// Check for duplicate slug (excluding current album)
+			// Check for duplicate slug in the same namespace (excluding current album)
 			for j := range albums {
-				if i != j && albums[j].Slug == updates.Slug {
+				if i != j && albums[j].Namespace == updates.Namespace && albums[j].Slug == updates.Slug {
 					return errors.New("album with this slug already exists")
 				}
 			}
 
+			before = albums[i]
 			albums[i] = *updates
 			found = true
 			break
@@ -152,11 +368,23 @@ func (s *AlbumService) Update(id string, updates *models.Album) error {
 		return fmt.Errorf("failed to write albums: %w", err)
 	}
 
+	s.recordHistory(&before, updates)
 	return nil
 }
 
+// normalizeTags normalizes every photo's tags and rebuilds the album's tag index from them.
+func normalizeTags(album *models.Album) {
+	for i := range album.Photos {
+		album.Photos[i].Tags = models.NormalizeTags(album.Photos[i].Tags)
+	}
+	album.Tags = album.DistinctTags()
+}
+
 // Delete deletes an album by ID.
 func (s *AlbumService) Delete(id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
 	albums, err := s.GetAll()
 	if err != nil {
 		return err
@@ -183,11 +411,15 @@ func (s *AlbumService) Delete(id string) error {
 		return fmt.Errorf("failed to write albums: %w", err)
 	}
 
+	s.forgetHistory(id)
 	return nil
 }
 
 // AddPhoto adds a photo to an album.
 func (s *AlbumService) AddPhoto(albumID string, photo *models.Photo) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
 	album, err := s.GetByID(albumID)
 	if err != nil {
 		return err
@@ -202,11 +434,63 @@ func (s *AlbumService) AddPhoto(albumID string, photo *models.Photo) error {
 
 	album.Photos = append(album.Photos, *photo)
 
-	return s.Update(albumID, album)
+	if err := s.update(albumID, album); err != nil {
+		return err
+	}
+
+	// Report the slug assigned on save
+	photo.Slug = album.Photos[len(album.Photos)-1].Slug
+	return nil
+}
+
+// ErrClientUploadLimit is returned when a client upload would take an album past its client
+// upload limits.
+var ErrClientUploadLimit = errors.New("client upload limit reached")
+
+// ReserveClientUploads counts an upload of photos files totalling size bytes toward an album's
+// client upload limits of maxPhotos photos and maxSize bytes, or returns ErrClientUploadLimit
+// if it would pass either. Checking and counting under the store lock keeps concurrent uploads
+// from each being granted the same allowance. The counts only grow: photos that fail to
+// process and photos deleted later still count.
+func (s *AlbumService) ReserveClientUploads(albumID string, photos int, size int64, maxPhotos int, maxSize int64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	albums, err := s.GetAll()
+	if err != nil {
+		return err
+	}
+
+	for i := range albums {
+		if albums[i].ID != albumID {
+			continue
+		}
+		album := &albums[i]
+		if album.ClientUploads+photos > maxPhotos || album.ClientUploadBytes+size > maxSize {
+			return ErrClientUploadLimit
+		}
+		album.ClientUploads += photos
+		album.ClientUploadBytes += size
+
+		collection := models.AlbumCollection{Albums: albums}
+		if err := s.fileService.WriteJSON(albumsFile, &collection); err != nil {
+			return fmt.Errorf("failed to write albums: %w", err)
+		}
+		return nil
+	}
+	return errors.New("album not found")
 }
 
 // UpdatePhoto updates a photo in an album.
 func (s *AlbumService) UpdatePhoto(albumID, photoID string, updates *models.Photo) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.updatePhoto(albumID, photoID, updates)
+}
+
+// updatePhoto updates a photo in an album. Callers must hold s.mu.
+func (s *AlbumService) updatePhoto(albumID, photoID string, updates *models.Photo) error {
 	album, err := s.GetByID(albumID)
 	if err != nil {
 		return err
@@ -219,6 +503,16 @@ func (s *AlbumService) UpdatePhoto(albumID, photoID string, updates *models.Phot
 			updates.ID = album.Photos[i].ID
 			updates.UploadedAt = album.Photos[i].UploadedAt
 
+			// A film stock that differs from the stored one was set by hand
+			switch {
+			case updates.FilmStock == "":
+				updates.FilmStockSource = ""
+			case updates.FilmStock != album.Photos[i].FilmStock:
+				updates.FilmStockSource = "manual"
+			case updates.FilmStockSource == "":
+				updates.FilmStockSource = album.Photos[i].FilmStockSource
+			}
+
 			album.Photos[i] = *updates
 			found = true
 			break
@@ -229,11 +523,145 @@ func (s *AlbumService) UpdatePhoto(albumID, photoID string, updates *models.Phot
 		return errors.New("photo not found")
 	}
 
-	return s.Update(albumID, album)
+	return s.update(albumID, album)
+}
+
+// UpdateRegeneratedPhoto stores the dimensions and file sizes of a photo whose derivatives were
+// rebuilt, without clobbering other edits made meanwhile.
+func (s *AlbumService) UpdateRegeneratedPhoto(albumID string, regenerated models.Photo) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	album, err := s.GetByID(albumID)
+	if err != nil {
+		return err
+	}
+
+	for _, photo := range album.Photos {
+		if photo.ID == regenerated.ID {
+			photo.Width, photo.Height = regenerated.Width, regenerated.Height
+			photo.FileSizeDisplay = regenerated.FileSizeDisplay
+			photo.FileSizeThumbnail = regenerated.FileSizeThumbnail
+			photo.DerivativesPending = regenerated.DerivativesPending
+			return s.updatePhoto(albumID, photo.ID, &photo)
+		}
+	}
+
+	return fmt.Errorf("photo %s no longer in album", regenerated.ID)
+}
+
+// PhotosOwningFiles returns the photos of an album, out of those about to be deleted, whose
+// stored files may be deleted with them: photos sharing their files with a photo that is
+// staying, in this album or another, are left out, and photos sharing files with each
+// other are returned once.
+func (s *AlbumService) PhotosOwningFiles(albumID string, photos []models.Photo) ([]models.Photo, error) {
+	albums, err := s.GetAll()
+	if err != nil {
+		return nil, err
+	}
+
+	deleting := make(map[string]bool, len(photos))
+	for _, photo := range photos {
+		deleting[photo.ID] = true
+	}
+	kept := make(map[string]bool)
+	for _, album := range albums {
+		for _, photo := range album.Photos {
+			if album.ID != albumID || !deleting[photo.ID] {
+				kept[photo.URLOriginal] = true
+			}
+		}
+	}
+
+	owning := make([]models.Photo, 0, len(photos))
+	for _, photo := range photos {
+		if kept[photo.URLOriginal] {
+			continue
+		}
+		kept[photo.URLOriginal] = true // Later photos sharing these files are covered by this one
+		owning = append(owning, photo)
+	}
+	return owning, nil
+}
+
+// derivativeURLPattern matches the display and thumbnail URLs processImage gives photos.
+var derivativeURLPattern = regexp.MustCompile(`^/uploads/(display/[0-9a-f-]{36}_display\.(webp|gif)|thumbnails/[0-9a-f-]{36}_thumbnail(_padded)?\.webp)$`)
+
+// FindPhotoByDerivativeURL returns the photo whose display or thumbnail URL is url, along with
+// its album, and which of the two url is. A padded thumbnail's URL counts as the thumbnail's,
+// since the two are rendered together. Only photos whose derivatives are still pending
+// are considered, and URLs not named like a derivative are turned away before the albums are
+// read, so requests for missing files cost little.
+func (s *AlbumService) FindPhotoByDerivativeURL(url string) (*models.Album, *models.Photo, string, error) {
+	if !derivativeURLPattern.MatchString(url) {
+		return nil, nil, "", errors.New("photo not found")
+	}
+
+	albums, err := s.GetAll()
+	if err != nil {
+		return nil, nil, "", err
+	}
+
+	for i := range albums {
+		for j := range albums[i].Photos {
+			if !albums[i].Photos[j].DerivativesPending {
+				continue
+			}
+			switch url {
+			case albums[i].Photos[j].URLDisplay:
+				return &albums[i], &albums[i].Photos[j], "display", nil
+			case albums[i].Photos[j].URLThumbnail, albums[i].Photos[j].URLThumbnailPadded:
+				return &albums[i], &albums[i].Photos[j], "thumbnail", nil
+			}
+		}
+	}
+
+	return nil, nil, "", errors.New("photo not found")
+}
+
+// RecordDerivativeSize stores the size of a photo's display or thumbnail version rendered on
+// first request. Once both are recorded the photo's derivatives are no longer pending.
+// Recording a size already on record changes nothing.
+func (s *AlbumService) RecordDerivativeSize(albumID, photoID, quality string, size int64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	album, err := s.GetByID(albumID)
+	if err != nil {
+		return err
+	}
+
+	for _, photo := range album.Photos {
+		if photo.ID != photoID {
+			continue
+		}
+
+		updated := photo
+		switch quality {
+		case "display":
+			updated.FileSizeDisplay = size
+		case "thumbnail":
+			updated.FileSizeThumbnail = size
+		default:
+			return fmt.Errorf("invalid derivative quality: %s", quality)
+		}
+		updated.DerivativesPending = updated.FileSizeDisplay == 0 || updated.FileSizeThumbnail == 0
+
+		if updated.FileSizeDisplay == photo.FileSizeDisplay && updated.FileSizeThumbnail == photo.FileSizeThumbnail &&
+			updated.DerivativesPending == photo.DerivativesPending {
+			return nil
+		}
+		return s.updatePhoto(albumID, photoID, &updated)
+	}
+
+	return errors.New("photo not found")
 }
 
 // DeletePhoto deletes a photo from an album.
 func (s *AlbumService) DeletePhoto(albumID, photoID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
 	album, err := s.GetByID(albumID)
 	if err != nil {
 		return err
@@ -257,24 +685,39 @@ func (s *AlbumService) DeletePhoto(albumID, photoID string) error {
 
 	album.Photos = newPhotos
 
-	return s.Update(albumID, album)
+	// A deleted photo stops being a cover, so the next one takes its place
+	album.CoverPhotoIDs = slices.DeleteFunc(album.CoverPhotoIDs, func(id string) bool { return id == photoID })
+	album.CoverPhotoID = ""
+	if len(album.CoverPhotoIDs) > 0 {
+		album.CoverPhotoID = album.CoverPhotoIDs[0]
+	}
+
+	return s.update(albumID, album)
 }
 
 // DeleteAllPhotos deletes all photos from an album.
 func (s *AlbumService) DeleteAllPhotos(albumID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
 	album, err := s.GetByID(albumID)
 	if err != nil {
 		return err
 	}
 
-	// Clear all photos
+	// Clear all photos, and with them the covers
 	album.Photos = []models.Photo{}
+	album.CoverPhotoIDs = nil
+	album.CoverPhotoID = ""
 
-	return s.Update(albumID, album)
+	return s.update(albumID, album)
 }
 
-// SetCoverPhoto sets the cover photo for an album.
+// SetCoverPhoto makes a photo the album's only cover.
 func (s *AlbumService) SetCoverPhoto(albumID, photoID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
 	album, err := s.GetByID(albumID)
 	if err != nil {
 		return err
@@ -294,24 +737,127 @@ func (s *AlbumService) SetCoverPhoto(albumID, photoID string) error {
 	}
 
 	album.CoverPhotoID = photoID
+	album.CoverPhotoIDs = []string{photoID}
+
+	return s.update(albumID, album)
+}
 
-	return s.Update(albumID, album)
+// ResolveCover returns the photo shown as an album's cover: the chosen cover photo if it is
+// still in the album, else the first photo, else nil for an empty album. UIs should show this
+// rather than reading cover_photo_id themselves.
+func (s *AlbumService) ResolveCover(album *models.Album) *models.Photo {
+	return album.CoverPhoto()
 }
 
-// ClearCoverPhoto clears the cover photo for an album.
+// ClearCoverPhoto clears every cover photo of an album, so the first photo is shown as its cover.
 func (s *AlbumService) ClearCoverPhoto(albumID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
 	album, err := s.GetByID(albumID)
 	if err != nil {
 		return err
 	}
 
 	album.CoverPhotoID = ""
+	album.CoverPhotoIDs = nil
 
-	return s.Update(albumID, album)
+	return s.update(albumID, album)
+}
+
+// SetAccentColor sets an album's gallery accent color, or clears it if color is empty.
+// Colors are stored lowercase; anything but #rgb or #rrggbb fails validation.
+func (s *AlbumService) SetAccentColor(albumID, color string) (*models.Album, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	album, err := s.GetByID(albumID)
+	if err != nil {
+		return nil, err
+	}
+
+	album.AccentColor = strings.ToLower(strings.TrimSpace(color))
+
+	if err := s.update(albumID, album); err != nil {
+		return nil, err
+	}
+	return s.GetByID(albumID)
+}
+
+// SetPinned pins an album, featuring it ahead of unpinned albums in listings, or unpins it.
+func (s *AlbumService) SetPinned(albumID string, pinned bool) (*models.Album, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	album, err := s.GetByID(albumID)
+	if err != nil {
+		return nil, err
+	}
+
+	album.Pinned = pinned
+
+	if err := s.update(albumID, album); err != nil {
+		return nil, err
+	}
+	return s.GetByID(albumID)
+}
+
+// SetDownloadSettings turns downloads of an album on or off and sets the qualities they may
+// be made at, listed in any order; an empty list allows every quality. A nil allowDownloads
+// or qualities leaves that setting as it is. Unknown qualities fail validation.
+func (s *AlbumService) SetDownloadSettings(albumID string, allowDownloads *bool, qualities []string) (*models.Album, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	album, err := s.GetByID(albumID)
+	if err != nil {
+		return nil, err
+	}
+
+	if allowDownloads != nil {
+		album.AllowDownloads = *allowDownloads
+	}
+	if qualities != nil {
+		// Stored lowest first and once each, unknown qualities last so validation names them
+		album.DownloadQualities = nil
+		for _, quality := range models.DownloadQualities {
+			if slices.Contains(qualities, quality) {
+				album.DownloadQualities = append(album.DownloadQualities, quality)
+			}
+		}
+		for _, quality := range qualities {
+			if !slices.Contains(models.DownloadQualities, quality) {
+				album.DownloadQualities = append(album.DownloadQualities, quality)
+			}
+		}
+	}
+
+	if err := s.update(albumID, album); err != nil {
+		return nil, err
+	}
+	return s.GetByID(albumID)
+}
+
+// SortPinnedFirst moves pinned albums ahead of unpinned ones, sorting each group by Order
+// and keeping the existing order among albums with the same Order.
+func SortPinnedFirst(albums []models.Album) {
+	slices.SortStableFunc(albums, func(a, b models.Album) int {
+		pinned := 0
+		switch {
+		case a.Pinned && !b.Pinned:
+			pinned = -1
+		case b.Pinned && !a.Pinned:
+			pinned = 1
+		}
+		return cmp.Or(pinned, cmp.Compare(a.Order, b.Order))
+	})
 }
 
 // ReorderPhotos reorders photos in an album based on the provided photo IDs.
 func (s *AlbumService) ReorderPhotos(albumID string, photoIDs []string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
 	album, err := s.GetByID(albumID)
 	if err != nil {
 		return err
@@ -341,13 +887,243 @@ func (s *AlbumService) ReorderPhotos(albumID string, photoIDs []string) error {
 
 	album.Photos = newPhotos
 
-	return s.Update(albumID, album)
+	return s.update(albumID, album)
+}
+
+// PhotoPosition moves a photo to a 1-based position in its album.
+type PhotoPosition struct {
+	PhotoID  string `json:"photo_id"`
+	Position int    `json:"position"`
+}
+
+// MovePhotos moves only the given photos to their new positions, saving the album once. The
+// other photos fill the remaining positions in their current relative order, and order numbers
+// are renumbered from 1. Positions must be distinct and within the album, and each photo may
+// be moved once.
+func (s *AlbumService) MovePhotos(albumID string, positions []PhotoPosition) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	album, err := s.GetByID(albumID)
+	if err != nil {
+		return err
+	}
+
+	inAlbum := photoIDSet(album.Photos)
+	moved := make(map[string]bool, len(positions))
+	slots := make([]*models.Photo, len(album.Photos))
+	for _, move := range positions {
+		if !inAlbum[move.PhotoID] {
+			return fmt.Errorf("%w: photo ID %s not found in album", ErrValidation, move.PhotoID)
+		}
+		if moved[move.PhotoID] {
+			return fmt.Errorf("%w: photo ID %s is moved more than once", ErrValidation, move.PhotoID)
+		}
+		if move.Position < 1 || move.Position > len(album.Photos) {
+			return fmt.Errorf("%w: position %d is out of range (1 to %d)", ErrValidation, move.Position, len(album.Photos))
+		}
+		if slots[move.Position-1] != nil {
+			return fmt.Errorf("%w: position %d is given more than once", ErrValidation, move.Position)
+		}
+		moved[move.PhotoID] = true
+		for i := range album.Photos {
+			if album.Photos[i].ID == move.PhotoID {
+				slots[move.Position-1] = &album.Photos[i]
+				break
+			}
+		}
+	}
+
+	// Everything else keeps its relative order in the free slots
+	next := 0
+	for i := range album.Photos {
+		if moved[album.Photos[i].ID] {
+			continue
+		}
+		for slots[next] != nil {
+			next++
+		}
+		slots[next] = &album.Photos[i]
+	}
+
+	newPhotos := make([]models.Photo, 0, len(slots))
+	for i, photo := range slots {
+		photo.Order = i + 1
+		newPhotos = append(newPhotos, *photo)
+	}
+	album.Photos = newPhotos
+
+	return s.update(albumID, album)
 }
 
-// generateSlug creates a URL-friendly slug from a title.
+// Targets for MovePhotoTo.
+const (
+	MoveToTop    = "top"
+	MoveToBottom = "bottom"
+)
+
+// ErrPhotoNotFound is returned when a photo is not in the album it is looked up in.
+var ErrPhotoNotFound = errors.New("photo not found in album")
+
+// MovePhotoTo moves one photo to the start (MoveToTop) or end (MoveToBottom) of its album,
+// saving the album once. The other photos keep their relative order.
+func (s *AlbumService) MovePhotoTo(albumID, photoID, target string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if target != MoveToTop && target != MoveToBottom {
+		return fmt.Errorf("%w: unsupported target %q (supported: %s, %s)", ErrValidation, target, MoveToTop, MoveToBottom)
+	}
+
+	album, err := s.GetByID(albumID)
+	if err != nil {
+		return err
+	}
+
+	i := slices.IndexFunc(album.Photos, func(photo models.Photo) bool { return photo.ID == photoID })
+	if i < 0 {
+		return ErrPhotoNotFound
+	}
+
+	photo := album.Photos[i]
+	album.Photos = slices.Delete(album.Photos, i, i+1)
+	if target == MoveToTop {
+		album.Photos = slices.Insert(album.Photos, 0, photo)
+	} else {
+		album.Photos = append(album.Photos, photo)
+	}
+	for i := range album.Photos {
+		album.Photos[i].Order = i + 1
+	}
+
+	return s.update(albumID, album)
+}
+
+// SwapPhotos exchanges the positions of two photos in an album, saving the album once.
+// Swapping a photo with itself changes nothing.
+func (s *AlbumService) SwapPhotos(albumID, photoA, photoB string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	album, err := s.GetByID(albumID)
+	if err != nil {
+		return err
+	}
+
+	indexOf := func(photoID string) (int, error) {
+		for i := range album.Photos {
+			if album.Photos[i].ID == photoID {
+				return i, nil
+			}
+		}
+		return 0, fmt.Errorf("%w: photo ID %s not found in album", ErrValidation, photoID)
+	}
+	i, err := indexOf(photoA)
+	if err != nil {
+		return err
+	}
+	j, err := indexOf(photoB)
+	if err != nil {
+		return err
+	}
+	if i == j {
+		return nil
+	}
+
+	// Each photo takes over the other's slot, including its order number
+	album.Photos[i], album.Photos[j] = album.Photos[j], album.Photos[i]
+	album.Photos[i].Order, album.Photos[j].Order = album.Photos[j].Order, album.Photos[i].Order
+
+	return s.update(albumID, album)
+}
+
+// keepPhotoSlugs carries stored photo slugs over to an updated album. Slugs are derived,
+// so an update cannot set them directly; a photo's slug is only re-derived when its title changes.
+func keepPhotoSlugs(updated, stored *models.Album) {
+	storedPhotos := make(map[string]*models.Photo, len(stored.Photos))
+	for i := range stored.Photos {
+		storedPhotos[stored.Photos[i].ID] = &stored.Photos[i]
+	}
+
+	for i := range updated.Photos {
+		photo := &updated.Photos[i]
+		if previous, ok := storedPhotos[photo.ID]; ok {
+			photo.Slug = previous.Slug
+			if photo.Title != previous.Title {
+				photo.Slug = ""
+			}
+		}
+	}
+}
+
+// trackPhotoAdds carries the stored LastPhotoAddedAt over to an updated album, moving it to
+// the update time if the update adds photos. Edits, reorders, and deletes leave it as it was.
+func trackPhotoAdds(updated, stored *models.Album) {
+	updated.LastPhotoAddedAt = stored.LastPhotoAddedAt
+
+	storedPhotos := make(map[string]bool, len(stored.Photos))
+	for i := range stored.Photos {
+		storedPhotos[stored.Photos[i].ID] = true
+	}
+	for i := range updated.Photos {
+		if !storedPhotos[updated.Photos[i].ID] {
+			addedAt := updated.UpdatedAt
+			updated.LastPhotoAddedAt = &addedAt
+			return
+		}
+	}
+}
+
+// assignPhotoSlugs gives every photo in an album a permalink slug that is unique within the album.
+// Existing slugs are kept; photos without one, or whose slug duplicates an earlier photo's,
+// get a new slug derived from their title or filename.
+func assignPhotoSlugs(album *models.Album) {
+	taken := make(map[string]bool, len(album.Photos))
+	needsSlug := []int{}
+	for i := range album.Photos {
+		slug := album.Photos[i].Slug
+		if slug == "" || taken[slug] {
+			needsSlug = append(needsSlug, i)
+			continue
+		}
+		taken[slug] = true
+	}
+
+	for _, i := range needsSlug {
+		photo := &album.Photos[i]
+		base := slugify(photo.Title)
+		if base == "" {
+			base = slugify(strings.TrimSuffix(photo.FilenameOriginal, filepath.Ext(photo.FilenameOriginal)))
+		}
+		if base == "" {
+			base = photo.ID
+		}
+
+		slug := base
+		for n := 2; taken[slug]; n++ {
+			slug = fmt.Sprintf("%s-%d", base, n)
+		}
+		photo.Slug = slug
+		taken[slug] = true
+	}
+}
+
+// generateSlug creates a URL-friendly slug from a title, falling back to a UUID.
 func generateSlug(title string) string {
+	slug := slugify(title)
+
+	// If slug is empty, use a UUID
+	if slug == "" {
+		slug = uuid.New().String()
+	}
+
+	return slug
+}
+
+// slugify converts text to a URL-friendly slug, which may be empty.
+func slugify(text string) string {
 	// Convert to lowercase
-	slug := strings.ToLower(title)
+	slug := strings.ToLower(text)
 
 	// Replace spaces with hyphens
 	slug = strings.ReplaceAll(slug, " ", "-")
@@ -367,14 +1143,18 @@ func generateSlug(title string) string {
 	}
 
 	// Trim hyphens from ends
-	slug = strings.Trim(slug, "-")
+	return strings.Trim(slug, "-")
+}
 
-	// If slug is empty, use a UUID
-	if slug == "" {
-		slug = uuid.New().String()
+// albumsInNamespace returns the albums in a namespace.
+func albumsInNamespace(albums []models.Album, namespace string) []models.Album {
+	scoped := make([]models.Album, 0, len(albums))
+	for _, album := range albums {
+		if album.Namespace == namespace {
+			scoped = append(scoped, album)
+		}
 	}
-
-	return slug
+	return scoped
 }
 
 // generateUniqueSlug ensures a slug is unique by appending a number if needed.
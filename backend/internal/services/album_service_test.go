@@ -1,6 +1,10 @@
 package services
 
 import (
+	"fmt"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 
@@ -62,6 +66,56 @@ func TestAlbumService_Create_GeneratesUniqueSlug(t *testing.T) {
 	assert.Contains(t, album2.Slug, "test-album-")
 }
 
+func TestAlbumService_Namespaces(t *testing.T) {
+	service, _ := setupAlbumService(t)
+
+	unscoped := &models.Album{Title: "Wedding", Visibility: "public"}
+	require.NoError(t, service.Create(unscoped))
+	alice := &models.Album{Title: "Wedding", Namespace: "alice", Visibility: "public"}
+	require.NoError(t, service.Create(alice))
+	bob := &models.Album{Title: "Wedding", Namespace: "bob", Visibility: "public"}
+	require.NoError(t, service.Create(bob))
+
+	// The same slug can be reused across namespaces
+	assert.Equal(t, "wedding", unscoped.Slug)
+	assert.Equal(t, "wedding", alice.Slug)
+	assert.Equal(t, "wedding", bob.Slug)
+
+	// Within a namespace, new albums get a unique slug
+	again := &models.Album{Title: "Wedding", Namespace: "alice", Visibility: "public"}
+	require.NoError(t, service.Create(again))
+	assert.Equal(t, "wedding-1", again.Slug)
+
+	// Lookups are scoped; GetBySlug only sees the default namespace
+	found, err := service.GetBySlug("wedding")
+	require.NoError(t, err)
+	assert.Equal(t, unscoped.ID, found.ID)
+	found, err = service.GetByNamespacedSlug("alice", "wedding")
+	require.NoError(t, err)
+	assert.Equal(t, alice.ID, found.ID)
+	_, err = service.GetByNamespacedSlug("carol", "wedding")
+	assert.EqualError(t, err, "album not found")
+	_, err = service.GetBySlug("wedding-1")
+	assert.EqualError(t, err, "album not found")
+
+	// Renaming into a slug taken in the same namespace is rejected
+	again.Slug = "wedding"
+	assert.EqualError(t, service.Update(again.ID, again), "album with this slug already exists")
+
+	// Moving an album to a namespace where its slug is free is allowed, but not where it is taken
+	again.Slug = "wedding-1"
+	again.Namespace = "bob"
+	require.NoError(t, service.Update(again.ID, again))
+	bob.Namespace = ""
+	assert.EqualError(t, service.Update(bob.ID, bob), "album with this slug already exists")
+
+	// Namespaces must be usable as a URL path segment
+	for _, namespace := range []string{"Alice", "a/b", "-alice", "alice-", "al ice"} {
+		album := &models.Album{Title: "Bad", Namespace: namespace, Visibility: "public"}
+		assert.Error(t, service.Create(album), "namespace %q should be rejected", namespace)
+	}
+}
+
 func TestAlbumService_GetByID(t *testing.T) {
 	service, _ := setupAlbumService(t)
 
@@ -218,6 +272,39 @@ func TestAlbumService_UpdatePhoto(t *testing.T) {
 	assert.Equal(t, "New alt text", updatedPhoto.AltText)
 }
 
+func TestAlbumService_UpdatePhoto_BuyURL(t *testing.T) {
+	service, _ := setupAlbumService(t)
+
+	album := &models.Album{Title: "Prints", Visibility: "public"}
+	require.NoError(t, service.Create(album))
+	require.NoError(t, service.AddPhoto(album.ID, &models.Photo{FilenameOriginal: "dunes.jpg"}))
+	stored, err := service.GetByID(album.ID)
+	require.NoError(t, err)
+	photo := stored.Photos[0]
+
+	buyURL := func() string {
+		stored, err := service.GetByID(album.ID)
+		require.NoError(t, err)
+		return stored.Photos[0].BuyURL
+	}
+
+	photo.BuyURL = "https://shop.example.com/prints/dunes"
+	require.NoError(t, service.UpdatePhoto(album.ID, photo.ID, &photo))
+	assert.Equal(t, "https://shop.example.com/prints/dunes", buyURL())
+
+	for _, malformed := range []string{"shop.example.com/prints", "/prints/dunes", "ftp://shop.example.com/dunes", "javascript:alert(1)", "https://"} {
+		update := photo
+		update.BuyURL = malformed
+		err := service.UpdatePhoto(album.ID, photo.ID, &update)
+		assert.ErrorContains(t, err, "buy_url", malformed)
+	}
+	assert.Equal(t, "https://shop.example.com/prints/dunes", buyURL(), "rejected links leave the stored one")
+
+	photo.BuyURL = ""
+	require.NoError(t, service.UpdatePhoto(album.ID, photo.ID, &photo))
+	assert.Empty(t, buyURL())
+}
+
 func TestAlbumService_DeletePhoto(t *testing.T) {
 	service, _ := setupAlbumService(t)
 
@@ -251,6 +338,55 @@ func TestAlbumService_DeletePhoto(t *testing.T) {
 	assert.Len(t, result.Photos, 0)
 }
 
+func TestAlbumService_LastPhotoAddedAt(t *testing.T) {
+	service, _ := setupAlbumService(t)
+
+	album := &models.Album{Title: "Test Album", Visibility: "public"}
+	require.NoError(t, service.Create(album))
+	stored, err := service.GetByID(album.ID)
+	require.NoError(t, err)
+	assert.Nil(t, stored.LastPhotoAddedAt, "albums without added photos have no timestamp")
+
+	addPhoto := func(name string) *models.Album {
+		t.Helper()
+		require.NoError(t, service.AddPhoto(album.ID, &models.Photo{
+			FilenameOriginal: name,
+			URLOriginal:      "/uploads/originals/" + name,
+		}))
+		updated, err := service.GetByID(album.ID)
+		require.NoError(t, err)
+		return updated
+	}
+
+	// Adding a photo sets it, and the summary reports it
+	first := addPhoto("first.jpg")
+	require.NotNil(t, first.LastPhotoAddedAt)
+	assert.Equal(t, first.UpdatedAt, *first.LastPhotoAddedAt)
+	assert.Equal(t, first.LastPhotoAddedAt, first.Summary().LastPhotoAddedAt)
+
+	// Each addition moves it forward
+	time.Sleep(2 * time.Millisecond)
+	second := addPhoto("second.jpg")
+	require.NotNil(t, second.LastPhotoAddedAt)
+	assert.True(t, second.LastPhotoAddedAt.After(*first.LastPhotoAddedAt))
+
+	// Deletes and edits leave it alone, and updates cannot set it
+	time.Sleep(2 * time.Millisecond)
+	require.NoError(t, service.DeletePhoto(album.ID, second.Photos[1].ID))
+	edited, err := service.GetByID(album.ID)
+	require.NoError(t, err)
+	edited.Title = "Renamed"
+	edited.LastPhotoAddedAt = nil
+	require.NoError(t, service.Update(album.ID, edited))
+	require.NoError(t, service.DeleteAllPhotos(album.ID))
+
+	stored, err = service.GetByID(album.ID)
+	require.NoError(t, err)
+	assert.True(t, stored.UpdatedAt.After(*second.LastPhotoAddedAt))
+	require.NotNil(t, stored.LastPhotoAddedAt)
+	assert.Equal(t, *second.LastPhotoAddedAt, *stored.LastPhotoAddedAt)
+}
+
 func TestAlbumService_SetCoverPhoto(t *testing.T) {
 	service, _ := setupAlbumService(t)
 
@@ -294,13 +430,50 @@ func TestAlbumService_SetCoverPhoto(t *testing.T) {
 	require.NoError(t, err)
 	assert.Equal(t, photo2ID, result.CoverPhotoID)
 
-	// Clear cover photo
+	assert.Equal(t, photo2ID, service.ResolveCover(result).ID)
+
+	// Clear cover photo; the first photo stands in
 	err = service.ClearCoverPhoto(album.ID)
 	require.NoError(t, err)
+	result, err = service.GetByID(album.ID)
+	require.NoError(t, err)
 	assert.Equal(t, "", result.CoverPhotoID)
+	assert.Equal(t, updated.Photos[0].ID, service.ResolveCover(result).ID)
 
 }
 
+func TestAlbumService_ResolveCover(t *testing.T) {
+	service, _ := setupAlbumService(t)
+
+	album := &models.Album{Title: "Covers", Visibility: "public"}
+	require.NoError(t, service.Create(album))
+
+	// Empty albums have no cover
+	stored, err := service.GetByID(album.ID)
+	require.NoError(t, err)
+	assert.Nil(t, service.ResolveCover(stored))
+
+	first := &models.Photo{FilenameOriginal: "first.jpg"}
+	require.NoError(t, service.AddPhoto(album.ID, first))
+	second := &models.Photo{FilenameOriginal: "second.jpg"}
+	require.NoError(t, service.AddPhoto(album.ID, second))
+
+	// Without a chosen cover the first photo stands in
+	stored, err = service.GetByID(album.ID)
+	require.NoError(t, err)
+	assert.Equal(t, first.ID, service.ResolveCover(stored).ID)
+
+	// An explicit cover wins
+	require.NoError(t, service.SetCoverPhoto(album.ID, second.ID))
+	stored, err = service.GetByID(album.ID)
+	require.NoError(t, err)
+	assert.Equal(t, second.ID, service.ResolveCover(stored).ID)
+
+	// A cover photo that is no longer in the album falls back to the first photo
+	stored.CoverPhotoIDs = []string{"deleted-photo"}
+	assert.Equal(t, first.ID, service.ResolveCover(stored).ID)
+}
+
 func TestAlbumService_Validation(t *testing.T) {
 	service, _ := setupAlbumService(t)
 
@@ -452,6 +625,179 @@ func TestAlbumService_ReorderPhotos(t *testing.T) {
 	assert.Equal(t, 3, reordered.Photos[2].Order)
 }
 
+func TestAlbumService_GetByNamespacedSlugFold(t *testing.T) {
+	service, _ := setupAlbumService(t)
+
+	lower := &models.Album{Title: "Summer Roll", Visibility: "public"}
+	require.NoError(t, service.Create(lower))
+	namespaced := &models.Album{Title: "Summer Roll", Namespace: "ana", Visibility: "public"}
+	require.NoError(t, service.Create(namespaced))
+
+	album, err := service.GetByNamespacedSlugFold("", "Summer-ROLL")
+	require.NoError(t, err)
+	assert.Equal(t, lower.ID, album.ID)
+
+	album, err = service.GetByNamespacedSlugFold("ana", "SUMMER-ROLL")
+	require.NoError(t, err)
+	assert.Equal(t, namespaced.ID, album.ID)
+
+	// An exact match wins over one that only matches ignoring case
+	lower.Slug = "Summer-Roll-2"
+	exact := &models.Album{Title: "summer roll 2", Visibility: "public"}
+	require.NoError(t, service.Create(exact))
+	require.NoError(t, service.Update(lower.ID, lower))
+	album, err = service.GetByNamespacedSlugFold("", "summer-roll-2")
+	require.NoError(t, err)
+	assert.Equal(t, exact.ID, album.ID)
+	album, err = service.GetByNamespacedSlugFold("", "Summer-Roll-2")
+	require.NoError(t, err)
+	assert.Equal(t, lower.ID, album.ID)
+
+	_, err = service.GetByNamespacedSlugFold("bo", "summer-roll")
+	assert.EqualError(t, err, "album not found")
+}
+
+func TestAlbumService_MovePhotos(t *testing.T) {
+	service, _ := setupAlbumService(t)
+
+	album := &models.Album{Title: "Test Album", Visibility: "public"}
+	require.NoError(t, service.Create(album))
+	ids := map[string]string{}
+	for _, name := range []string{"a", "b", "c", "d", "e", "f"} {
+		photo := &models.Photo{FilenameOriginal: name}
+		require.NoError(t, service.AddPhoto(album.ID, photo))
+		ids[name] = photo.ID
+	}
+
+	order := func() []string {
+		stored, err := service.GetByID(album.ID)
+		require.NoError(t, err)
+		names := []string{}
+		for i, photo := range stored.Photos {
+			names = append(names, photo.FilenameOriginal)
+			assert.Equal(t, i+1, photo.Order)
+		}
+		return names
+	}
+
+	// Moving one photo to the front shifts the photos before it down
+	require.NoError(t, service.MovePhotos(album.ID, []PhotoPosition{{PhotoID: ids["e"], Position: 1}}))
+	assert.Equal(t, []string{"e", "a", "b", "c", "d", "f"}, order())
+
+	// Several sparse moves at once; unmoved photos keep their relative order around them
+	require.NoError(t, service.MovePhotos(album.ID, []PhotoPosition{
+		{PhotoID: ids["a"], Position: 6},
+		{PhotoID: ids["f"], Position: 2},
+		{PhotoID: ids["c"], Position: 3},
+	}))
+	assert.Equal(t, []string{"e", "f", "c", "b", "d", "a"}, order())
+
+	// A photo moved to where it already is changes nothing
+	require.NoError(t, service.MovePhotos(album.ID, []PhotoPosition{{PhotoID: ids["b"], Position: 4}}))
+	assert.Equal(t, []string{"e", "f", "c", "b", "d", "a"}, order())
+
+	// Invalid moves are rejected without changing anything
+	for _, tt := range []struct {
+		positions []PhotoPosition
+		err       string
+	}{
+		{[]PhotoPosition{{PhotoID: ids["a"], Position: 1}, {PhotoID: ids["b"], Position: 1}}, "position 1 is given more than once"},
+		{[]PhotoPosition{{PhotoID: ids["a"], Position: 1}, {PhotoID: ids["a"], Position: 2}}, "is moved more than once"},
+		{[]PhotoPosition{{PhotoID: ids["a"], Position: 0}}, "position 0 is out of range (1 to 6)"},
+		{[]PhotoPosition{{PhotoID: ids["a"], Position: 7}}, "position 7 is out of range (1 to 6)"},
+		{[]PhotoPosition{{PhotoID: "fake-photo-id", Position: 1}}, "not found in album"},
+	} {
+		assert.ErrorContains(t, service.MovePhotos(album.ID, tt.positions), tt.err)
+	}
+	assert.Equal(t, []string{"e", "f", "c", "b", "d", "a"}, order())
+
+	assert.EqualError(t, service.MovePhotos("missing", nil), "album not found")
+}
+
+func TestAlbumService_MovePhotoTo(t *testing.T) {
+	service, _ := setupAlbumService(t)
+
+	album := &models.Album{Title: "Test Album", Visibility: "public"}
+	require.NoError(t, service.Create(album))
+	ids := map[string]string{}
+	for _, name := range []string{"a", "b", "c", "d"} {
+		photo := &models.Photo{FilenameOriginal: name}
+		require.NoError(t, service.AddPhoto(album.ID, photo))
+		ids[name] = photo.ID
+	}
+
+	order := func() []string {
+		stored, err := service.GetByID(album.ID)
+		require.NoError(t, err)
+		names := []string{}
+		for i, photo := range stored.Photos {
+			names = append(names, photo.FilenameOriginal)
+			assert.Equal(t, i+1, photo.Order)
+		}
+		return names
+	}
+
+	require.NoError(t, service.MovePhotoTo(album.ID, ids["c"], MoveToTop))
+	assert.Equal(t, []string{"c", "a", "b", "d"}, order())
+
+	require.NoError(t, service.MovePhotoTo(album.ID, ids["a"], MoveToBottom))
+	assert.Equal(t, []string{"c", "b", "d", "a"}, order())
+
+	// Moving a photo to where it already is changes nothing
+	require.NoError(t, service.MovePhotoTo(album.ID, ids["c"], MoveToTop))
+	require.NoError(t, service.MovePhotoTo(album.ID, ids["a"], MoveToBottom))
+	assert.Equal(t, []string{"c", "b", "d", "a"}, order())
+
+	assert.ErrorIs(t, service.MovePhotoTo(album.ID, "fake-photo-id", MoveToTop), ErrPhotoNotFound)
+	assert.EqualError(t, service.MovePhotoTo(album.ID, ids["b"], "middle"), `validation failed: unsupported target "middle" (supported: top, bottom)`)
+	assert.EqualError(t, service.MovePhotoTo("missing", ids["b"], MoveToTop), "album not found")
+	assert.Equal(t, []string{"c", "b", "d", "a"}, order())
+}
+
+func TestAlbumService_SwapPhotos(t *testing.T) {
+	service, _ := setupAlbumService(t)
+
+	album := &models.Album{Title: "Test Album", Visibility: "public"}
+	require.NoError(t, service.Create(album))
+	ids := []string{}
+	for _, name := range []string{"1.jpg", "2.jpg", "3.jpg"} {
+		photo := &models.Photo{FilenameOriginal: name}
+		require.NoError(t, service.AddPhoto(album.ID, photo))
+		ids = append(ids, photo.ID)
+	}
+
+	order := func() ([]string, []int) {
+		stored, err := service.GetByID(album.ID)
+		require.NoError(t, err)
+		names, orders := []string{}, []int{}
+		for _, photo := range stored.Photos {
+			names = append(names, photo.FilenameOriginal)
+			orders = append(orders, photo.Order)
+		}
+		return names, orders
+	}
+	_, originalOrders := order()
+
+	// The first and last photos trade places; order numbers stay with the positions
+	require.NoError(t, service.SwapPhotos(album.ID, ids[0], ids[2]))
+	names, orders := order()
+	assert.Equal(t, []string{"3.jpg", "2.jpg", "1.jpg"}, names)
+	assert.Equal(t, originalOrders, orders)
+
+	// Swapping a photo with itself is a no-op
+	require.NoError(t, service.SwapPhotos(album.ID, ids[1], ids[1]))
+	names, _ = order()
+	assert.Equal(t, []string{"3.jpg", "2.jpg", "1.jpg"}, names)
+
+	// Unknown photo IDs are rejected without changing anything
+	err := service.SwapPhotos(album.ID, ids[0], "fake-photo-id")
+	assert.ErrorContains(t, err, "not found in album")
+	names, _ = order()
+	assert.Equal(t, []string{"3.jpg", "2.jpg", "1.jpg"}, names)
+
+	assert.EqualError(t, service.SwapPhotos("missing-album", ids[0], ids[1]), "album not found")
+}
+
 func TestAlbumService_ReorderPhotos_InvalidCount(t *testing.T) {
 	service, _ := setupAlbumService(t)
 
@@ -497,3 +843,443 @@ func TestAlbumService_ReorderPhotos_InvalidPhotoID(t *testing.T) {
 	assert.Error(t, err)
 	assert.Contains(t, err.Error(), "not found in album")
 }
+
+func TestAlbumService_Create_InheritsDefaults(t *testing.T) {
+	service, tmpDir := setupAlbumService(t)
+
+	fileService, err := NewFileService(tmpDir)
+	require.NoError(t, err)
+	defaultsService := NewAlbumDefaultsService(fileService)
+	require.NoError(t, defaultsService.Update(&models.AlbumDefaults{
+		Visibility:       "unlisted",
+		Layout:           "masonry",
+		AllowDownloads:   true,
+		ThemeOverride:    "dark",
+		WatermarkEnabled: true,
+		WatermarkCover:   true,
+	}))
+	service.SetDefaultsService(defaultsService)
+
+	// A minimal album picks up every default
+	album, err := service.NewAlbum()
+	require.NoError(t, err)
+	album.Title = "Minimal Album"
+	require.NoError(t, service.Create(album))
+
+	created, err := service.GetByID(album.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "unlisted", created.Visibility)
+	assert.Equal(t, "masonry", created.Layout)
+	assert.True(t, created.AllowDownloads)
+	assert.Equal(t, "dark", created.ThemeOverride)
+	assert.True(t, created.WatermarkEnabled)
+	assert.True(t, created.WatermarkCover)
+}
+
+func TestAlbumService_Create_ExplicitFieldsOverrideDefaults(t *testing.T) {
+	service, tmpDir := setupAlbumService(t)
+
+	fileService, err := NewFileService(tmpDir)
+	require.NoError(t, err)
+	defaultsService := NewAlbumDefaultsService(fileService)
+	require.NoError(t, defaultsService.Update(&models.AlbumDefaults{
+		Visibility:     "unlisted",
+		Layout:         "masonry",
+		AllowDownloads: true,
+	}))
+	service.SetDefaultsService(defaultsService)
+
+	album := &models.Album{Title: "Explicit Album", Visibility: "public", Layout: "grid"}
+	require.NoError(t, service.Create(album))
+
+	created, err := service.GetByID(album.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "public", created.Visibility)
+	assert.Equal(t, "grid", created.Layout)
+}
+
+func TestAlbumDefaultsService_Validation(t *testing.T) {
+	fileService, err := NewFileService(t.TempDir())
+	require.NoError(t, err)
+	defaultsService := NewAlbumDefaultsService(fileService)
+
+	// Built-in defaults are returned before anything is saved
+	defaults, err := defaultsService.Get()
+	require.NoError(t, err)
+	assert.Equal(t, "public", defaults.Visibility)
+
+	err = defaultsService.Update(&models.AlbumDefaults{Visibility: "secret"})
+	assert.Error(t, err)
+}
+
+func TestAlbumService_FilmStock(t *testing.T) {
+	service, _ := setupAlbumService(t)
+
+	album := &models.Album{Title: "Film Album", Visibility: "public"}
+	require.NoError(t, service.Create(album))
+
+	// One photo with an EXIF-derived stock, one without any stock
+	exifPhoto := &models.Photo{FilenameOriginal: "1.jpg", FilmStock: "Kodak Portra 400", FilmStockSource: "exif"}
+	plainPhoto := &models.Photo{FilenameOriginal: "2.jpg"}
+	require.NoError(t, service.AddPhoto(album.ID, exifPhoto))
+	require.NoError(t, service.AddPhoto(album.ID, plainPhoto))
+
+	updated, err := service.GetByID(album.ID)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"Kodak Portra 400"}, updated.FilmStocks)
+	assert.Equal(t, "exif", updated.Photos[0].FilmStockSource)
+	assert.Empty(t, updated.Photos[1].FilmStock)
+
+	// Manually setting a stock marks it as a manual override and updates the summary
+	manual := updated.Photos[1]
+	manual.FilmStock = "Ilford HP5 Plus"
+	require.NoError(t, service.UpdatePhoto(album.ID, manual.ID, &manual))
+
+	updated, err = service.GetByID(album.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "manual", updated.Photos[1].FilmStockSource)
+	assert.Equal(t, []string{"Kodak Portra 400", "Ilford HP5 Plus"}, updated.FilmStocks)
+
+	// Saving the EXIF photo unchanged keeps its source
+	unchanged := updated.Photos[0]
+	unchanged.FilmStockSource = ""
+	require.NoError(t, service.UpdatePhoto(album.ID, unchanged.ID, &unchanged))
+
+	updated, err = service.GetByID(album.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "exif", updated.Photos[0].FilmStockSource)
+}
+
+func TestAlbumService_PhotoSlugs(t *testing.T) {
+	service, _ := setupAlbumService(t)
+
+	album := &models.Album{Title: "Slugs", Visibility: "public"}
+	require.NoError(t, service.Create(album))
+
+	first := &models.Photo{FilenameOriginal: "Roll1-01.jpg"}
+	require.NoError(t, service.AddPhoto(album.ID, first))
+	assert.Equal(t, "roll1-01", first.Slug, "slug derives from the filename")
+
+	// Colliding names get a numeric suffix
+	second := &models.Photo{FilenameOriginal: "roll1 01.png"}
+	require.NoError(t, service.AddPhoto(album.ID, second))
+	assert.Equal(t, "roll1-01-2", second.Slug)
+
+	// Titles take precedence over filenames
+	titled := &models.Photo{FilenameOriginal: "DSC_0042.jpg", Title: "Golden Hour"}
+	require.NoError(t, service.AddPhoto(album.ID, titled))
+	assert.Equal(t, "golden-hour", titled.Slug)
+
+	// Changing a title re-derives the slug, avoiding existing ones
+	renamed := *second
+	renamed.Title = "Golden Hour"
+	require.NoError(t, service.UpdatePhoto(album.ID, second.ID, &renamed))
+
+	// Other edits keep the slug, and clients cannot set it directly
+	captioned := *first
+	captioned.Caption = "Pier at dawn"
+	captioned.Slug = "hijacked"
+	require.NoError(t, service.UpdatePhoto(album.ID, first.ID, &captioned))
+
+	stored, err := service.GetByID(album.ID)
+	require.NoError(t, err)
+	slugs := []string{}
+	for _, photo := range stored.Photos {
+		slugs = append(slugs, photo.Slug)
+	}
+	assert.Equal(t, []string{"roll1-01", "golden-hour-2", "golden-hour"}, slugs)
+}
+
+func TestAlbumService_PhotoSlugs_DerivedForExistingPhotos(t *testing.T) {
+	service, _ := setupAlbumService(t)
+
+	// Albums saved before photo slugs existed
+	collection := models.AlbumCollection{Albums: []models.Album{{
+		ID:         "album-1",
+		Slug:       "legacy",
+		Title:      "Legacy",
+		Visibility: "public",
+		Photos: []models.Photo{
+			{ID: "p1", FilenameOriginal: "scan.tif"},
+			{ID: "p2", FilenameOriginal: "scan.jpg"},
+			{ID: "p3", FilenameOriginal: "???.jpg"},
+		},
+	}}}
+	require.NoError(t, service.fileService.WriteJSON(albumsFile, &collection))
+
+	album, err := service.GetBySlug("legacy")
+	require.NoError(t, err)
+	assert.Equal(t, "scan", album.Photos[0].Slug)
+	assert.Equal(t, "scan-2", album.Photos[1].Slug)
+	assert.Equal(t, "p3", album.Photos[2].Slug, "falls back to the photo ID")
+}
+
+func TestAlbumService_RecordDerivativeSize(t *testing.T) {
+	service, _ := setupAlbumService(t)
+
+	album := &models.Album{Title: "Lazy", Visibility: "public"}
+	require.NoError(t, service.Create(album))
+	photo := &models.Photo{
+		URLDisplay:         "/uploads/display/0b7c8f52-3f1e-4c55-9d0a-6f3e2b1a9c44_display.webp",
+		URLThumbnail:       "/uploads/thumbnails/0b7c8f52-3f1e-4c55-9d0a-6f3e2b1a9c44_thumbnail.webp",
+		URLThumbnailPadded: "/uploads/thumbnails/0b7c8f52-3f1e-4c55-9d0a-6f3e2b1a9c44_thumbnail_padded.webp",
+		DerivativesPending: true,
+	}
+	require.NoError(t, service.AddPhoto(album.ID, photo))
+	rendered := &models.Photo{
+		URLDisplay:   "/uploads/display/5d2e9a01-7b6c-4f3d-8e21-c4a9b0f7d312_display.webp",
+		URLThumbnail: "/uploads/thumbnails/5d2e9a01-7b6c-4f3d-8e21-c4a9b0f7d312_thumbnail.webp",
+	}
+	require.NoError(t, service.AddPhoto(album.ID, rendered))
+
+	found, foundPhoto, quality, err := service.FindPhotoByDerivativeURL(photo.URLThumbnail)
+	require.NoError(t, err)
+	assert.Equal(t, album.ID, found.ID)
+	assert.Equal(t, photo.ID, foundPhoto.ID)
+	assert.Equal(t, "thumbnail", quality)
+	_, _, quality, err = service.FindPhotoByDerivativeURL(photo.URLThumbnailPadded)
+	require.NoError(t, err)
+	assert.Equal(t, "thumbnail", quality, "padded thumbnails are rendered with the thumbnail")
+	_, _, _, err = service.FindPhotoByDerivativeURL("/uploads/display/other.webp")
+	assert.Error(t, err, "not named like a derivative")
+	_, _, _, err = service.FindPhotoByDerivativeURL("/uploads/display/1f0e4d2c-8a7b-4c6d-9e5f-a1b2c3d4e5f6_display.webp")
+	assert.Error(t, err, "no such photo")
+	_, _, _, err = service.FindPhotoByDerivativeURL(rendered.URLDisplay)
+	assert.Error(t, err, "derivatives already rendered")
+
+	require.NoError(t, service.RecordDerivativeSize(album.ID, photo.ID, "display", 1234))
+	stored, err := service.GetByID(album.ID)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1234), stored.Photos[0].FileSizeDisplay)
+	assert.True(t, stored.Photos[0].DerivativesPending)
+
+	require.NoError(t, service.RecordDerivativeSize(album.ID, photo.ID, "thumbnail", 56))
+	stored, err = service.GetByID(album.ID)
+	require.NoError(t, err)
+	assert.Equal(t, int64(56), stored.Photos[0].FileSizeThumbnail)
+	assert.False(t, stored.Photos[0].DerivativesPending)
+
+	// Recording the same size again leaves the album untouched
+	updatedAt := stored.UpdatedAt
+	require.NoError(t, service.RecordDerivativeSize(album.ID, photo.ID, "thumbnail", 56))
+	stored, err = service.GetByID(album.ID)
+	require.NoError(t, err)
+	assert.Equal(t, updatedAt, stored.UpdatedAt)
+
+	assert.Error(t, service.RecordDerivativeSize(album.ID, photo.ID, "original", 1))
+	assert.Error(t, service.RecordDerivativeSize(album.ID, "missing", "display", 1))
+}
+
+func TestAlbumService_RecordDerivativeSize_Concurrent(t *testing.T) {
+	service, _ := setupAlbumService(t)
+
+	album := &models.Album{Title: "Lazy", Visibility: "public"}
+	require.NoError(t, service.Create(album))
+	var photoIDs []string
+	for i := range 5 {
+		photo := &models.Photo{FilenameOriginal: fmt.Sprintf("%d.jpg", i), DerivativesPending: true}
+		require.NoError(t, service.AddPhoto(album.ID, photo))
+		photoIDs = append(photoIDs, photo.ID)
+	}
+
+	// Display and thumbnail requests for every photo arrive together, along with a new upload
+	var wg sync.WaitGroup
+	for _, photoID := range photoIDs {
+		for _, quality := range []string{"display", "thumbnail"} {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				assert.NoError(t, service.RecordDerivativeSize(album.ID, photoID, quality, 100))
+			}()
+		}
+	}
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		assert.NoError(t, service.AddPhoto(album.ID, &models.Photo{FilenameOriginal: "new.jpg"}))
+	}()
+	wg.Wait()
+
+	stored, err := service.GetByID(album.ID)
+	require.NoError(t, err)
+	require.Len(t, stored.Photos, 6)
+	for _, photo := range stored.Photos[:5] {
+		assert.Equal(t, int64(100), photo.FileSizeDisplay, photo.FilenameOriginal)
+		assert.Equal(t, int64(100), photo.FileSizeThumbnail, photo.FilenameOriginal)
+		assert.False(t, photo.DerivativesPending, photo.FilenameOriginal)
+	}
+}
+
+func TestAlbumService_PhotoTags(t *testing.T) {
+	service, _ := setupAlbumService(t)
+
+	album := &models.Album{Title: "Tagged", Visibility: "public"}
+	require.NoError(t, service.Create(album))
+	require.NoError(t, service.AddPhoto(album.ID, &models.Photo{FilenameOriginal: "1.jpg", Tags: []string{" Beach", "sunset ", "beach", "", "BEACH"}}))
+	require.NoError(t, service.AddPhoto(album.ID, &models.Photo{FilenameOriginal: "2.jpg", Tags: []string{"Dog", "beach"}}))
+	require.NoError(t, service.AddPhoto(album.ID, &models.Photo{FilenameOriginal: "3.jpg"}))
+
+	// Tags are trimmed, lowercased, and deduplicated in first-seen order; the album indexes them sorted
+	updated, err := service.GetByID(album.ID)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"beach", "sunset"}, updated.Photos[0].Tags)
+	assert.Equal(t, []string{"dog", "beach"}, updated.Photos[1].Tags)
+	assert.Nil(t, updated.Photos[2].Tags)
+	assert.Equal(t, []string{"beach", "dog", "sunset"}, updated.Tags)
+	assert.Equal(t, []models.TagCount{{Tag: "beach", Photos: 2}, {Tag: "dog", Photos: 1}, {Tag: "sunset", Photos: 1}}, updated.TagCounts())
+
+	// Editing a photo's tags updates the index
+	photo := updated.Photos[1]
+	photo.Tags = []string{"Cat"}
+	require.NoError(t, service.UpdatePhoto(album.ID, photo.ID, &photo))
+	updated, err = service.GetByID(album.ID)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"beach", "cat", "sunset"}, updated.Tags)
+
+	// Too many or too long tags are rejected
+	photo = updated.Photos[2]
+	photo.Tags = []string{strings.Repeat("x", models.MaxPhotoTagLength+1)}
+	err = service.UpdatePhoto(album.ID, photo.ID, &photo)
+	assert.ErrorContains(t, err, "longer than 64 characters")
+
+	photo.Tags = make([]string, models.MaxPhotoTags+1)
+	for i := range photo.Tags {
+		photo.Tags[i] = fmt.Sprintf("tag-%d", i)
+	}
+	err = service.UpdatePhoto(album.ID, photo.ID, &photo)
+	assert.ErrorContains(t, err, "at most 50 tags")
+
+	// Duplicates collapse before the limit is checked
+	photo.Tags = make([]string, models.MaxPhotoTags+1)
+	for i := range photo.Tags {
+		photo.Tags[i] = "Same"
+	}
+	require.NoError(t, service.UpdatePhoto(album.ID, photo.ID, &photo))
+}
+
+func TestAlbumService_Pinned(t *testing.T) {
+	service, _ := setupAlbumService(t)
+
+	titles := func(albums []models.Album) []string {
+		names := make([]string, len(albums))
+		for i := range albums {
+			names[i] = albums[i].Title
+		}
+		return names
+	}
+
+	ids := map[string]string{}
+	for _, title := range []string{"Alps", "Beach", "City", "Desert"} {
+		album := &models.Album{Title: title, Visibility: "public"}
+		require.NoError(t, service.Create(album))
+		ids[title] = album.ID
+	}
+
+	pinned, err := service.SetPinned(ids["City"], true)
+	require.NoError(t, err)
+	assert.True(t, pinned.Pinned)
+	_, err = service.SetPinned(ids["Alps"], true)
+	require.NoError(t, err)
+
+	// Pinned albums sort first, each group keeping the stored order
+	albums, err := service.GetAll()
+	require.NoError(t, err)
+	assert.Equal(t, []string{"Alps", "Beach", "City", "Desert"}, titles(albums), "pinning should not reorder the stored albums")
+	SortPinnedFirst(albums)
+	assert.Equal(t, []string{"Alps", "City", "Beach", "Desert"}, titles(albums))
+
+	// Unpinning returns an album to its place among the rest
+	unpinned, err := service.SetPinned(ids["Alps"], false)
+	require.NoError(t, err)
+	assert.False(t, unpinned.Pinned)
+	albums, err = service.GetAll()
+	require.NoError(t, err)
+	SortPinnedFirst(albums)
+	assert.Equal(t, []string{"City", "Alps", "Beach", "Desert"}, titles(albums))
+
+	// Within each group, albums sort by their order
+	albums = []models.Album{
+		{Title: "Desert", Order: 2},
+		{Title: "City", Order: 3, Pinned: true},
+		{Title: "Beach", Order: 1},
+		{Title: "Alps", Order: 1, Pinned: true},
+	}
+	SortPinnedFirst(albums)
+	assert.Equal(t, []string{"Alps", "City", "Beach", "Desert"}, titles(albums))
+
+	_, err = service.SetPinned("missing", true)
+	assert.EqualError(t, err, "album not found")
+}
+
+func TestAlbumService_ReserveClientUploads(t *testing.T) {
+	service, _ := setupAlbumService(t)
+
+	album := &models.Album{Title: "Wedding", Visibility: "unlisted"}
+	require.NoError(t, service.Create(album))
+
+	// Concurrent uploads cannot each claim the same allowance
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	reserved := 0
+	for range 10 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			err := service.ReserveClientUploads(album.ID, 1, 100, 3, 1000)
+			if err == nil {
+				mu.Lock()
+				reserved++
+				mu.Unlock()
+				return
+			}
+			assert.ErrorIs(t, err, ErrClientUploadLimit)
+		}()
+	}
+	wg.Wait()
+	assert.Equal(t, 3, reserved)
+
+	stored, err := service.GetByID(album.ID)
+	require.NoError(t, err)
+	assert.Equal(t, 3, stored.ClientUploads)
+	assert.Equal(t, int64(300), stored.ClientUploadBytes)
+
+	// The byte limit counts too
+	require.NoError(t, service.ReserveClientUploads(album.ID, 0, 700, 3, 1000))
+	assert.ErrorIs(t, service.ReserveClientUploads(album.ID, 0, 1, 3, 1000), ErrClientUploadLimit)
+
+	assert.EqualError(t, service.ReserveClientUploads("missing", 1, 1, 3, 1000), "album not found")
+}
+
+func TestAlbumService_Upsert_Concurrent(t *testing.T) {
+	service, _ := setupAlbumService(t)
+
+	// Concurrent upserts of one slug create it once and update it after
+	var wg sync.WaitGroup
+	var created atomic.Int32
+	for i := range 8 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			existing, album, err := service.Upsert("", "summer", func(existing *models.Album) (*models.Album, error) {
+				if existing == nil {
+					return &models.Album{Title: "Summer", Visibility: "public"}, nil
+				}
+				updated := *existing
+				updated.Description = fmt.Sprintf("upsert %d", i)
+				return &updated, nil
+			})
+			assert.NoError(t, err)
+			assert.Equal(t, "summer", album.Slug)
+			if existing == nil {
+				created.Add(1)
+			}
+		}()
+	}
+	wg.Wait()
+
+	assert.Equal(t, int32(1), created.Load())
+	albums, err := service.GetAll()
+	require.NoError(t, err)
+	require.Len(t, albums, 1)
+	assert.Equal(t, "summer", albums[0].Slug)
+}
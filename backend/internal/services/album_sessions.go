@@ -0,0 +1,219 @@
+package services
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/njoubert/nielsshootsfilm/backend/internal/models"
+)
+
+const albumSessionsFile = "album_sessions.json"
+
+// AlbumSessionTokenPrefix starts every album session token, telling them apart from
+// stateless signed tokens.
+const AlbumSessionTokenPrefix = "as_"
+
+// ErrSessionNotFound is returned for album session tokens that are unknown, revoked, or expired.
+var ErrSessionNotFound = errors.New("album session not found")
+
+// SessionStore keeps server-side album access sessions, so they can be revoked before they
+// expire. Sessions are looked up by a hash of their token; stores never see the tokens.
+type SessionStore interface {
+	// Create stores a new session.
+	Create(session models.AlbumSession) error
+	// Get returns the unexpired session with a token hash, or ErrSessionNotFound.
+	Get(tokenHash string) (*models.AlbumSession, error)
+	// RevokeAlbum deletes every session for an album and returns how many there were.
+	RevokeAlbum(albumID string) (int, error)
+	// RevokeAll deletes every session and returns how many there were.
+	RevokeAll() (int, error)
+}
+
+// hashSessionToken returns the stored form of an album session token. Tokens are long and
+// random, so a plain SHA-256 is enough to keep a leaked store from revealing them.
+func hashSessionToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// MemorySessionStore keeps album sessions in memory, so they end when the server restarts.
+type MemorySessionStore struct {
+	mu       sync.Mutex
+	sessions map[string]models.AlbumSession // By token hash
+}
+
+// NewMemorySessionStore creates an empty in-memory session store.
+func NewMemorySessionStore() *MemorySessionStore {
+	return &MemorySessionStore{sessions: make(map[string]models.AlbumSession)}
+}
+
+// Create stores a new session, dropping any that have expired.
+func (s *MemorySessionStore) Create(session models.AlbumSession) error {
+	now := time.Now()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for hash, existing := range s.sessions {
+		if !now.Before(existing.ExpiresAt) {
+			delete(s.sessions, hash)
+		}
+	}
+	s.sessions[session.TokenHash] = session
+	return nil
+}
+
+// Get returns the unexpired session with a token hash, or ErrSessionNotFound.
+func (s *MemorySessionStore) Get(tokenHash string) (*models.AlbumSession, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	session, ok := s.sessions[tokenHash]
+	if !ok || !time.Now().Before(session.ExpiresAt) {
+		return nil, ErrSessionNotFound
+	}
+	return &session, nil
+}
+
+// RevokeAlbum deletes every session for an album and returns how many there were.
+func (s *MemorySessionStore) RevokeAlbum(albumID string) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	revoked := 0
+	for hash, session := range s.sessions {
+		if session.AlbumID == albumID {
+			delete(s.sessions, hash)
+			revoked++
+		}
+	}
+	return revoked, nil
+}
+
+// RevokeAll deletes every session and returns how many there were.
+func (s *MemorySessionStore) RevokeAll() (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	revoked := len(s.sessions)
+	clear(s.sessions)
+	return revoked, nil
+}
+
+// FileSessionStore keeps album sessions in album_sessions.json, so they survive restarts.
+// The file is read once and its sessions cached in memory, so looking one up, which every
+// request to a restricted album does, never waits on the disk. Changes are written through.
+type FileSessionStore struct {
+	fileService *FileService
+	mu          sync.Mutex            // Serializes read-modify-write cycles of the file
+	sessions    []models.AlbumSession // The file's sessions, once loaded
+	loaded      bool
+}
+
+// NewFileSessionStore creates a session store backed by album_sessions.json.
+func NewFileSessionStore(fileService *FileService) *FileSessionStore {
+	return &FileSessionStore{fileService: fileService}
+}
+
+// Create stores a new session, dropping any that have expired.
+func (s *FileSessionStore) Create(session models.AlbumSession) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	sessions, err := s.read()
+	if err != nil {
+		return err
+	}
+	now := time.Now()
+	kept := make([]models.AlbumSession, 0, len(sessions)+1)
+	for _, existing := range sessions {
+		if now.Before(existing.ExpiresAt) {
+			kept = append(kept, existing)
+		}
+	}
+	return s.write(append(kept, session))
+}
+
+// Get returns the unexpired session with a token hash, or ErrSessionNotFound.
+func (s *FileSessionStore) Get(tokenHash string) (*models.AlbumSession, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	sessions, err := s.read()
+	if err != nil {
+		return nil, err
+	}
+	for _, session := range sessions {
+		if session.TokenHash == tokenHash && time.Now().Before(session.ExpiresAt) {
+			return &session, nil
+		}
+	}
+	return nil, ErrSessionNotFound
+}
+
+// RevokeAlbum deletes every session for an album and returns how many there were.
+func (s *FileSessionStore) RevokeAlbum(albumID string) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	sessions, err := s.read()
+	if err != nil {
+		return 0, err
+	}
+	kept := make([]models.AlbumSession, 0, len(sessions))
+	for _, session := range sessions {
+		if session.AlbumID != albumID {
+			kept = append(kept, session)
+		}
+	}
+	revoked := len(sessions) - len(kept)
+	if revoked == 0 {
+		return 0, nil
+	}
+	return revoked, s.write(kept)
+}
+
+// RevokeAll deletes every session and returns how many there were.
+func (s *FileSessionStore) RevokeAll() (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	sessions, err := s.read()
+	if err != nil {
+		return 0, err
+	}
+	if len(sessions) == 0 {
+		return 0, nil
+	}
+	return len(sessions), s.write(nil)
+}
+
+// read returns every stored session, including expired ones, reading the file on first use.
+// Callers must hold mu and must not modify the result.
+func (s *FileSessionStore) read() ([]models.AlbumSession, error) {
+	if s.loaded {
+		return s.sessions, nil
+	}
+	if s.fileService.FileExists(albumSessionsFile) {
+		var collection models.AlbumSessionCollection
+		if err := s.fileService.ReadJSON(albumSessionsFile, &collection); err != nil {
+			return nil, fmt.Errorf("failed to read album sessions: %w", err)
+		}
+		s.sessions = collection.Sessions
+	}
+	s.loaded = true
+	return s.sessions, nil
+}
+
+// write replaces the stored sessions, and the cached ones once the file is written. Callers
+// must hold mu.
+func (s *FileSessionStore) write(sessions []models.AlbumSession) error {
+	if sessions == nil {
+		sessions = []models.AlbumSession{}
+	}
+	if err := s.fileService.WriteJSON(albumSessionsFile, &models.AlbumSessionCollection{Sessions: sessions}); err != nil {
+		return fmt.Errorf("failed to write album sessions: %w", err)
+	}
+	s.sessions = sessions
+	return nil
+}
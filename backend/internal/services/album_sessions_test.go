@@ -0,0 +1,208 @@
+package services
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/njoubert/nielsshootsfilm/backend/internal/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSessionStores(t *testing.T) {
+	stores := map[string]func(t *testing.T) SessionStore{
+		"memory": func(t *testing.T) SessionStore { return NewMemorySessionStore() },
+		"file": func(t *testing.T) SessionStore {
+			fileService, err := NewFileService(t.TempDir())
+			require.NoError(t, err)
+			return NewFileSessionStore(fileService)
+		},
+	}
+
+	for name, newStore := range stores {
+		t.Run(name, func(t *testing.T) {
+			store := newStore(t)
+			now := time.Now().UTC()
+			session := func(hash, albumID string, ttl time.Duration) models.AlbumSession {
+				return models.AlbumSession{TokenHash: hash, AlbumID: albumID, CreatedAt: now, ExpiresAt: now.Add(ttl)}
+			}
+
+			require.NoError(t, store.Create(session("a1", "album-a", time.Hour)))
+			require.NoError(t, store.Create(session("a2", "album-a", time.Hour)))
+			require.NoError(t, store.Create(session("b1", "album-b", time.Hour)))
+			require.NoError(t, store.Create(session("old", "album-b", -time.Minute)))
+
+			found, err := store.Get("a1")
+			require.NoError(t, err)
+			assert.Equal(t, "album-a", found.AlbumID)
+
+			// Unknown and expired sessions are not found
+			_, err = store.Get("missing")
+			assert.ErrorIs(t, err, ErrSessionNotFound)
+			_, err = store.Get("old")
+			assert.ErrorIs(t, err, ErrSessionNotFound)
+
+			// Revoking an album ends all of its sessions and no others
+			revoked, err := store.RevokeAlbum("album-a")
+			require.NoError(t, err)
+			assert.Equal(t, 2, revoked)
+			for _, hash := range []string{"a1", "a2"} {
+				_, err = store.Get(hash)
+				assert.ErrorIs(t, err, ErrSessionNotFound)
+			}
+			_, err = store.Get("b1")
+			assert.NoError(t, err)
+
+			revoked, err = store.RevokeAlbum("album-a")
+			require.NoError(t, err)
+			assert.Zero(t, revoked)
+
+			// Revoking everything ends the rest
+			revoked, err = store.RevokeAll()
+			require.NoError(t, err)
+			assert.Equal(t, 2, revoked)
+			_, err = store.Get("b1")
+			assert.ErrorIs(t, err, ErrSessionNotFound)
+		})
+	}
+}
+
+func TestFileSessionStore_Persists(t *testing.T) {
+	fileService, err := NewFileService(t.TempDir())
+	require.NoError(t, err)
+	service, err := NewAlbumAuthService("secret", time.Hour)
+	require.NoError(t, err)
+	service.SetSessionStore(NewFileSessionStore(fileService))
+
+	hash, err := HashPassword("letmein")
+	require.NoError(t, err)
+	album := &models.Album{ID: "album-1", Visibility: "password_protected", PasswordHash: hash}
+	token, err := service.VerifyPassword(album, "letmein")
+	require.NoError(t, err)
+
+	// Sessions survive a restart, and only token hashes are stored
+	restarted, err := NewAlbumAuthService("secret", time.Hour)
+	require.NoError(t, err)
+	restarted.SetSessionStore(NewFileSessionStore(fileService))
+	assert.NoError(t, restarted.Authorize(token, album))
+
+	var collection models.AlbumSessionCollection
+	require.NoError(t, fileService.ReadJSON(albumSessionsFile, &collection))
+	require.Len(t, collection.Sessions, 1)
+	assert.NotEqual(t, token, collection.Sessions[0].TokenHash)
+}
+
+func TestFileSessionStore_CachesSessions(t *testing.T) {
+	dataDir := t.TempDir()
+	fileService, err := NewFileService(dataDir)
+	require.NoError(t, err)
+	store := NewFileSessionStore(fileService)
+
+	now := time.Now().UTC()
+	require.NoError(t, store.Create(models.AlbumSession{TokenHash: "a1", AlbumID: "album-a", CreatedAt: now, ExpiresAt: now.Add(time.Hour)}))
+
+	// Lookups are answered from memory once the file has been read
+	require.NoError(t, os.Remove(filepath.Join(dataDir, albumSessionsFile)))
+	found, err := store.Get("a1")
+	require.NoError(t, err)
+	assert.Equal(t, "album-a", found.AlbumID)
+
+	// Changes are written through, and a fresh store reads them back
+	revoked, err := store.RevokeAlbum("album-a")
+	require.NoError(t, err)
+	assert.Equal(t, 1, revoked)
+	_, err = NewFileSessionStore(fileService).Get("a1")
+	assert.ErrorIs(t, err, ErrSessionNotFound)
+}
+
+func TestAlbumAuthService_Sessions(t *testing.T) {
+	service, err := NewAlbumAuthService("secret", time.Hour)
+	require.NoError(t, err)
+	service.SetSessionStore(NewMemorySessionStore())
+
+	hash, err := HashPassword("letmein")
+	require.NoError(t, err)
+	album := &models.Album{ID: "album-1", Visibility: "password_protected", PasswordHash: hash}
+	other := &models.Album{ID: "album-2", Visibility: "public", AllowedEmails: []string{"client@example.com"}}
+
+	// Passwords and access links open sessions, scoped to their album
+	token, err := service.VerifyPassword(album, "letmein")
+	require.NoError(t, err)
+	assert.Contains(t, token, AlbumSessionTokenPrefix)
+	assert.NoError(t, service.Authorize(token, album))
+	assert.Error(t, service.Authorize(token, other))
+
+	second, err := service.VerifyPassword(album, "letmein")
+	require.NoError(t, err)
+	assert.NotEqual(t, token, second)
+
+	linkToken, err := service.RequestAccessLink(other, "Client@Example.com")
+	require.NoError(t, err)
+	assert.NoError(t, service.Authorize(linkToken, other))
+
+	// Sessions bound to an address end when it leaves the access list
+	listed := *other
+	listed.AllowedEmails = []string{"someone@example.com"}
+	assert.ErrorIs(t, service.Authorize(linkToken, &listed), ErrEmailNotAllowed)
+
+	// Stateless tokens are no longer accepted
+	assert.Error(t, service.Authorize(service.IssueToken(album.ID), album))
+
+	// A password change revokes every session for the album, and only that album
+	revoked, err := service.RevokeSessions(album.ID)
+	require.NoError(t, err)
+	assert.Equal(t, 2, revoked)
+	assert.ErrorIs(t, service.Authorize(token, album), ErrSessionNotFound)
+	assert.ErrorIs(t, service.Authorize(second, album), ErrSessionNotFound)
+	assert.NoError(t, service.Authorize(linkToken, other))
+
+	// Without a session store there is nothing to revoke
+	stateless, err := NewAlbumAuthService("secret", time.Hour)
+	require.NoError(t, err)
+	revoked, err = stateless.RevokeSessions(album.ID)
+	require.NoError(t, err)
+	assert.Zero(t, revoked)
+}
+
+func TestAlbumAuthService_RevokeAll(t *testing.T) {
+	fileService, err := NewFileService(t.TempDir())
+	require.NoError(t, err)
+	newService := func() *AlbumAuthService {
+		service, err := NewAlbumAuthService("secret", time.Hour)
+		require.NoError(t, err)
+		require.NoError(t, service.SetKeyStore(fileService))
+		return service
+	}
+	album := &models.Album{ID: "album-1", Visibility: "public", AllowedEmails: []string{"client@example.com"}}
+
+	service := newService()
+	token := service.IssueToken(album.ID)
+	linkToken := service.IssueEmailToken(album.ID, "client@example.com")
+	require.NoError(t, service.Authorize(token, album))
+
+	keys, revoked, err := service.RevokeAll()
+	require.NoError(t, err)
+	assert.NotEmpty(t, keys.Salt)
+	assert.Zero(t, revoked, "stateless tokens have no sessions to count")
+
+	// Signed tokens issued before are rejected, and new ones accepted
+	assert.Error(t, service.Authorize(token, album))
+	assert.Error(t, service.Authorize(linkToken, album))
+	fresh := service.IssueToken(album.ID)
+	assert.NoError(t, service.Authorize(fresh, album))
+
+	// The rotation survives a restart with the same secret
+	restarted := newService()
+	assert.Error(t, restarted.Authorize(token, album))
+	assert.NoError(t, restarted.Authorize(fresh, album))
+
+	// Without a key store the rotation is kept in memory, until the next restart
+	unpersisted, err := NewAlbumAuthService("secret", time.Hour)
+	require.NoError(t, err)
+	old := unpersisted.IssueToken(album.ID)
+	_, _, err = unpersisted.RevokeAll()
+	require.NoError(t, err)
+	assert.Error(t, unpersisted.Authorize(old, album))
+}
@@ -0,0 +1,81 @@
+package services
+
+import "sort"
+
+// MaxAlbumSuggestions is how many albums are suggested for an unknown slug.
+const MaxAlbumSuggestions = 3
+
+// AlbumSuggestion is a public album whose slug is close to one that was not found.
+type AlbumSuggestion struct {
+	Slug  string `json:"slug"`
+	Title string `json:"title"`
+	Path  string `json:"path"` // Public page path, e.g. /albums/{slug}
+}
+
+// SuggestPublicAlbums returns up to MaxAlbumSuggestions public albums in the namespace whose slugs
+// are closest to slug by edit distance, closest first. Albums further than a third of the longer
+// slug's length (at least 2 edits) are not suggested, so unrelated slugs get no suggestions.
+// Unlisted and restricted albums are never suggested, since that would reveal them.
+func (s *AlbumService) SuggestPublicAlbums(namespace, slug string) ([]AlbumSuggestion, error) {
+	albums, err := s.GetAll()
+	if err != nil {
+		return nil, err
+	}
+
+	type candidate struct {
+		suggestion AlbumSuggestion
+		distance   int
+	}
+	candidates := []candidate{}
+	for i := range albums {
+		album := &albums[i]
+		if album.Namespace != namespace || album.Visibility != "public" || album.RequiresAccessToken() {
+			continue
+		}
+
+		distance := levenshtein(slug, album.Slug)
+		if distance > max(2, max(len(slug), len(album.Slug))/3) {
+			continue
+		}
+		candidates = append(candidates, candidate{
+			suggestion: AlbumSuggestion{Slug: album.Slug, Title: album.Title, Path: album.PublicPath()},
+			distance:   distance,
+		})
+	}
+
+	sort.SliceStable(candidates, func(i, j int) bool {
+		if candidates[i].distance != candidates[j].distance {
+			return candidates[i].distance < candidates[j].distance
+		}
+		return candidates[i].suggestion.Slug < candidates[j].suggestion.Slug
+	})
+
+	suggestions := make([]AlbumSuggestion, 0, min(len(candidates), MaxAlbumSuggestions))
+	for _, c := range candidates[:min(len(candidates), MaxAlbumSuggestions)] {
+		suggestions = append(suggestions, c.suggestion)
+	}
+	return suggestions, nil
+}
+
+// levenshtein returns the number of single-byte insertions, deletions, and substitutions
+// needed to turn a into b. Slugs are ASCII, so bytes are characters.
+func levenshtein(a, b string) int {
+	previous := make([]int, len(b)+1)
+	current := make([]int, len(b)+1)
+	for j := range previous {
+		previous[j] = j
+	}
+
+	for i := 1; i <= len(a); i++ {
+		current[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			current[j] = min(previous[j]+1, current[j-1]+1, previous[j-1]+cost)
+		}
+		previous, current = current, previous
+	}
+	return previous[len(b)]
+}
@@ -0,0 +1,65 @@
+package services
+
+import (
+	"testing"
+
+	"github.com/njoubert/nielsshootsfilm/backend/internal/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLevenshtein(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want int
+	}{
+		{"", "", 0},
+		{"", "abc", 3},
+		{"summer-trip", "summer-trip", 0},
+		{"sumer-trip", "summer-trip", 1},
+		{"kitten", "sitting", 3},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, levenshtein(tt.a, tt.b), "%q -> %q", tt.a, tt.b)
+		assert.Equal(t, tt.want, levenshtein(tt.b, tt.a), "%q -> %q", tt.b, tt.a)
+	}
+}
+
+func TestAlbumService_SuggestPublicAlbums(t *testing.T) {
+	svc, _ := setupAlbumService(t)
+
+	for _, album := range []*models.Album{
+		{Title: "Summer Trip", Slug: "summer-trip", Visibility: "public"},
+		{Title: "Summer Trips", Slug: "summer-trips", Visibility: "public"},
+		{Title: "Summer Strip", Slug: "summer-strip", Visibility: "public"},
+		{Title: "Summer Tripod", Slug: "summer-tripod", Visibility: "public"},
+		{Title: "Summer Drip", Slug: "summer-drip", Visibility: "unlisted"},
+		{Title: "Summer Grip", Slug: "summer-grip", Visibility: "password_protected", PasswordHash: "x"}, // pragma: allowlist secret
+		{Title: "Summer Tri", Slug: "summer-tri", Visibility: "public", AllowedEmails: []string{"client@example.com"}},
+		{Title: "Jane's Summer Trip", Slug: "summer-trip", Namespace: "jane", Visibility: "public"},
+	} {
+		require.NoError(t, svc.Create(album))
+	}
+
+	// Near misses suggest the closest public albums of the namespace, closest first
+	suggestions, err := svc.SuggestPublicAlbums("", "sumer-trip")
+	require.NoError(t, err)
+	slugs := []string{}
+	for _, s := range suggestions {
+		slugs = append(slugs, s.Slug)
+	}
+	assert.Equal(t, []string{"summer-trip", "summer-strip", "summer-trips"}, slugs)
+	assert.Equal(t, "Summer Trip", suggestions[0].Title)
+	assert.Equal(t, "/albums/summer-trip", suggestions[0].Path)
+
+	suggestions, err = svc.SuggestPublicAlbums("jane", "summer-trp")
+	require.NoError(t, err)
+	require.Len(t, suggestions, 1)
+	assert.Equal(t, "/a/jane/summer-trip", suggestions[0].Path)
+
+	// Wildly different slugs get no suggestions
+	suggestions, err = svc.SuggestPublicAlbums("", "winter-in-kyoto")
+	require.NoError(t, err)
+	assert.Empty(t, suggestions)
+	assert.NotNil(t, suggestions)
+}
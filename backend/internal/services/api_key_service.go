@@ -0,0 +1,146 @@
+package services
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/njoubert/nielsshootsfilm/backend/internal/models"
+)
+
+const apiKeysFile = "api_keys.json"
+
+// APIKeyPrefix starts every issued API key, so keys are easy to recognise.
+const APIKeyPrefix = "nsf_"
+
+// DefaultAPIKeyRateLimit is the requests per minute allowed for a key issued without a limit.
+const DefaultAPIKeyRateLimit = 600
+
+// ErrInvalidAPIKey is returned when an API key is unknown or revoked.
+var ErrInvalidAPIKey = errors.New("invalid API key")
+
+// APIKeyService issues, revokes, and checks the API keys for the public read endpoints.
+type APIKeyService struct {
+	fileService *FileService
+}
+
+// NewAPIKeyService creates a new API key service.
+func NewAPIKeyService(fileService *FileService) *APIKeyService {
+	return &APIKeyService{
+		fileService: fileService,
+	}
+}
+
+// List returns every issued API key, including revoked ones, in the order they were issued.
+func (s *APIKeyService) List() ([]models.APIKey, error) {
+	if !s.fileService.FileExists(apiKeysFile) {
+		return []models.APIKey{}, nil
+	}
+
+	var collection models.APIKeyCollection
+	if err := s.fileService.ReadJSON(apiKeysFile, &collection); err != nil {
+		return nil, fmt.Errorf("failed to read API keys: %w", err)
+	}
+	if collection.Keys == nil {
+		collection.Keys = []models.APIKey{}
+	}
+	return collection.Keys, nil
+}
+
+// Issue creates an API key for name, allowing rateLimit requests per minute (or
+// DefaultAPIKeyRateLimit if it is not positive). It returns the stored key and the key
+// itself, which is not kept and cannot be retrieved later.
+func (s *APIKeyService) Issue(name string, rateLimit int) (*models.APIKey, string, error) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return nil, "", errors.New("API key name is required")
+	}
+	if rateLimit <= 0 {
+		rateLimit = DefaultAPIKeyRateLimit
+	}
+
+	b := make([]byte, 24)
+	if _, err := rand.Read(b); err != nil {
+		return nil, "", fmt.Errorf("failed to generate API key: %w", err)
+	}
+	secret := APIKeyPrefix + base64.RawURLEncoding.EncodeToString(b)
+
+	keys, err := s.List()
+	if err != nil {
+		return nil, "", err
+	}
+
+	key := models.APIKey{
+		ID:        uuid.New().String(),
+		Name:      name,
+		Prefix:    secret[:len(APIKeyPrefix)+6],
+		KeyHash:   hashAPIKey(secret),
+		RateLimit: rateLimit,
+		CreatedAt: time.Now().UTC(),
+	}
+	keys = append(keys, key)
+
+	if err := s.fileService.WriteJSON(apiKeysFile, &models.APIKeyCollection{Keys: keys}); err != nil {
+		return nil, "", fmt.Errorf("failed to write API keys: %w", err)
+	}
+	return &key, secret, nil
+}
+
+// Revoke stops an API key from being accepted. Revoking a revoked key changes nothing.
+func (s *APIKeyService) Revoke(id string) error {
+	keys, err := s.List()
+	if err != nil {
+		return err
+	}
+
+	for i := range keys {
+		if keys[i].ID != id {
+			continue
+		}
+		if keys[i].RevokedAt != nil {
+			return nil
+		}
+		now := time.Now().UTC()
+		keys[i].RevokedAt = &now
+
+		if err := s.fileService.WriteJSON(apiKeysFile, &models.APIKeyCollection{Keys: keys}); err != nil {
+			return fmt.Errorf("failed to write API keys: %w", err)
+		}
+		return nil
+	}
+
+	return errors.New("API key not found")
+}
+
+// Authenticate returns the active API key matching secret, or ErrInvalidAPIKey.
+func (s *APIKeyService) Authenticate(secret string) (*models.APIKey, error) {
+	if !strings.HasPrefix(secret, APIKeyPrefix) {
+		return nil, ErrInvalidAPIKey
+	}
+
+	keys, err := s.List()
+	if err != nil {
+		return nil, err
+	}
+
+	hash := hashAPIKey(secret)
+	for i := range keys {
+		if keys[i].KeyHash == hash && keys[i].RevokedAt == nil {
+			return &keys[i], nil
+		}
+	}
+	return nil, ErrInvalidAPIKey
+}
+
+// hashAPIKey returns the stored form of an API key. Keys are long and random, so a plain
+// SHA-256 is enough to keep a leaked api_keys.json from revealing them.
+func hashAPIKey(secret string) string {
+	sum := sha256.Sum256([]byte(secret))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,74 @@
+package services
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAPIKeyService_IssueAuthenticateRevoke(t *testing.T) {
+	tmpDir := t.TempDir()
+	fileService, err := NewFileService(tmpDir)
+	require.NoError(t, err)
+	service := NewAPIKeyService(fileService)
+
+	keys, err := service.List()
+	require.NoError(t, err)
+	assert.Empty(t, keys)
+
+	_, _, err = service.Issue("  ", 0)
+	assert.EqualError(t, err, "API key name is required")
+
+	mirror, mirrorSecret, err := service.Issue("Archive mirror", 0)
+	require.NoError(t, err)
+	assert.True(t, strings.HasPrefix(mirrorSecret, APIKeyPrefix))
+	assert.Equal(t, DefaultAPIKeyRateLimit, mirror.RateLimit)
+	assert.True(t, strings.HasPrefix(mirrorSecret, mirror.Prefix))
+	assert.NotContains(t, mirror.KeyHash, mirrorSecret)
+
+	partner, partnerSecret, err := service.Issue("Partner", 30)
+	require.NoError(t, err)
+	assert.Equal(t, 30, partner.RateLimit)
+	assert.NotEqual(t, mirrorSecret, partnerSecret)
+
+	// Only the hashes are stored
+	stored, err := os.ReadFile(filepath.Join(tmpDir, apiKeysFile))
+	require.NoError(t, err)
+	assert.NotContains(t, string(stored), mirrorSecret)
+	assert.NotContains(t, string(stored), partnerSecret)
+
+	key, err := service.Authenticate(mirrorSecret)
+	require.NoError(t, err)
+	assert.Equal(t, mirror.ID, key.ID)
+
+	for _, secret := range []string{"", "nsf_unknown", "not-a-key", mirrorSecret + "x"} {
+		_, err := service.Authenticate(secret)
+		assert.ErrorIs(t, err, ErrInvalidAPIKey, secret)
+	}
+
+	// A revoked key is rejected but still listed; other keys keep working
+	require.NoError(t, service.Revoke(mirror.ID))
+	_, err = service.Authenticate(mirrorSecret)
+	assert.ErrorIs(t, err, ErrInvalidAPIKey)
+	_, err = service.Authenticate(partnerSecret)
+	assert.NoError(t, err)
+
+	keys, err = service.List()
+	require.NoError(t, err)
+	require.Len(t, keys, 2)
+	require.NotNil(t, keys[0].RevokedAt)
+	revokedAt := *keys[0].RevokedAt
+	assert.Nil(t, keys[1].RevokedAt)
+
+	// Revoking again keeps the original revocation time
+	require.NoError(t, service.Revoke(mirror.ID))
+	keys, err = service.List()
+	require.NoError(t, err)
+	assert.Equal(t, revokedAt, *keys[0].RevokedAt)
+
+	assert.EqualError(t, service.Revoke("missing"), "API key not found")
+}
@@ -0,0 +1,161 @@
+package services
+
+import (
+	"fmt"
+	"math"
+	"strings"
+
+	"github.com/davidbyttow/govips/v2/vips"
+)
+
+// blurhashSampleEdge is the longest edge images are shrunk to before computing a blurhash;
+// the hash only keeps a handful of low frequencies, so more pixels add nothing.
+const blurhashSampleEdge = 32
+
+// blurhashLongComponents and blurhashShortComponents are the numbers of cosine components
+// along an image's long and short edges. Four by three is what the blurhash authors suggest.
+const (
+	blurhashLongComponents  = 4
+	blurhashShortComponents = 3
+)
+
+// blurhashDigits is the base 83 alphabet blurhashes are written in.
+const blurhashDigits = "0123456789ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz#$%*+,-.:;=?@[]^_{|}~"
+
+// blurhash encodes an image as a blurhash (https://blurha.sh), a short string that clients
+// decode into a blurred placeholder while the real image loads.
+func blurhash(imageBytes []byte) (string, error) {
+	img, err := vips.NewThumbnailWithSizeFromBuffer(imageBytes, blurhashSampleEdge, blurhashSampleEdge, vips.InterestingNone, vips.SizeDown)
+	if err != nil {
+		return "", fmt.Errorf("failed to shrink image: %w", err)
+	}
+	defer img.Close()
+
+	if err := img.ToColorSpace(vips.InterpretationSRGB); err != nil {
+		return "", fmt.Errorf("failed to convert to sRGB: %w", err)
+	}
+	if img.BandFormat() != vips.BandFormatUchar {
+		if err := img.Cast(vips.BandFormatUchar); err != nil {
+			return "", fmt.Errorf("failed to convert to 8 bits: %w", err)
+		}
+	}
+
+	pixels, err := img.ToBytes()
+	if err != nil {
+		return "", fmt.Errorf("failed to read pixels: %w", err)
+	}
+	width, height, bands := img.Width(), img.Height(), img.Bands()
+	if width == 0 || height == 0 || len(pixels) < width*height*bands {
+		return "", fmt.Errorf("unexpected pixel data size %d for %dx%d image", len(pixels), width, height)
+	}
+
+	xComponents, yComponents := blurhashLongComponents, blurhashShortComponents
+	if height > width {
+		xComponents, yComponents = yComponents, xComponents
+	}
+	return encodeBlurhash(pixels, width, height, bands, xComponents, yComponents), nil
+}
+
+// encodeBlurhash encodes 8-bit sRGB pixels, with bands values per pixel, as a blurhash of
+// xComponents by yComponents cosine components. Images with fewer than three bands are
+// treated as grayscale, and bands after the third, like alpha, are ignored.
+func encodeBlurhash(pixels []byte, width, height, bands, xComponents, yComponents int) string {
+	// Linearize each pixel once rather than once per component
+	linear := make([][3]float64, width*height)
+	for i := range linear {
+		pixel := pixels[i*bands : (i+1)*bands]
+		if bands < 3 {
+			gray := srgbToLinear(pixel[0])
+			linear[i] = [3]float64{gray, gray, gray}
+		} else {
+			linear[i] = [3]float64{srgbToLinear(pixel[0]), srgbToLinear(pixel[1]), srgbToLinear(pixel[2])}
+		}
+	}
+
+	factors := make([][3]float64, 0, xComponents*yComponents)
+	for j := range yComponents {
+		for i := range xComponents {
+			normalization := 2.0
+			if i == 0 && j == 0 {
+				normalization = 1
+			}
+			var factor [3]float64
+			for y := range height {
+				yBasis := math.Cos(math.Pi * float64(j) * float64(y) / float64(height))
+				for x := range width {
+					basis := normalization * yBasis * math.Cos(math.Pi*float64(i)*float64(x)/float64(width))
+					for c, value := range linear[y*width+x] {
+						factor[c] += basis * value
+					}
+				}
+			}
+			for c := range factor {
+				factor[c] /= float64(width * height)
+			}
+			factors = append(factors, factor)
+		}
+	}
+
+	var hash strings.Builder
+	hash.WriteString(base83((xComponents-1)+(yComponents-1)*9, 1))
+
+	dc, ac := factors[0], factors[1:]
+	maxValue := 1.0
+	if len(ac) > 0 {
+		actualMax := 0.0
+		for _, factor := range ac {
+			for _, value := range factor {
+				actualMax = math.Max(actualMax, math.Abs(value))
+			}
+		}
+		quantisedMax := int(math.Max(0, math.Min(82, math.Floor(actualMax*166-0.5))))
+		maxValue = float64(quantisedMax+1) / 166
+		hash.WriteString(base83(quantisedMax, 1))
+	} else {
+		hash.WriteString(base83(0, 1))
+	}
+
+	hash.WriteString(base83(linearToSRGB(dc[0])<<16|linearToSRGB(dc[1])<<8|linearToSRGB(dc[2]), 4))
+	for _, factor := range ac {
+		value := 0
+		for _, component := range factor {
+			quantised := math.Floor(signedPow(component/maxValue, 0.5)*9 + 9.5)
+			value = value*19 + int(math.Max(0, math.Min(18, quantised)))
+		}
+		hash.WriteString(base83(value, 2))
+	}
+	return hash.String()
+}
+
+// base83 writes value as length base 83 digits, most significant first.
+func base83(value, length int) string {
+	digits := make([]byte, length)
+	for i := length - 1; i >= 0; i-- {
+		digits[i] = blurhashDigits[value%83]
+		value /= 83
+	}
+	return string(digits)
+}
+
+// srgbToLinear converts an 8-bit sRGB channel to linear light between 0 and 1.
+func srgbToLinear(value byte) float64 {
+	v := float64(value) / 255
+	if v <= 0.04045 {
+		return v / 12.92
+	}
+	return math.Pow((v+0.055)/1.055, 2.4)
+}
+
+// linearToSRGB converts linear light to an 8-bit sRGB channel, clamping out-of-range values.
+func linearToSRGB(value float64) int {
+	v := math.Max(0, math.Min(1, value))
+	if v <= 0.0031308 {
+		return int(v*12.92*255 + 0.5)
+	}
+	return int((1.055*math.Pow(v, 1/2.4)-0.055)*255 + 0.5)
+}
+
+// signedPow raises the magnitude of value to exp, keeping its sign.
+func signedPow(value, exp float64) float64 {
+	return math.Copysign(math.Pow(math.Abs(value), exp), value)
+}
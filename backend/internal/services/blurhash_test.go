@@ -0,0 +1,51 @@
+package services
+
+import (
+	"image"
+	"image/color"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEncodeBlurhash_SolidColor(t *testing.T) {
+	pixels := make([]byte, 0, 8*6*3)
+	for range 8 * 6 {
+		pixels = append(pixels, 255, 0, 0)
+	}
+
+	// Size flag "L" for 4x3 components, then the DC term, which is the average color
+	hash := encodeBlurhash(pixels, 8, 6, 3, 4, 3)
+	require.Len(t, hash, 4+2+2*(4*3-1))
+	assert.Equal(t, "L", hash[:1])
+	assert.Equal(t, "TI:j", hash[2:6], "pure red")
+
+	// Grayscale pixels are spread over all three channels
+	white := make([]byte, 8*6)
+	for i := range white {
+		white[i] = 255
+	}
+	assert.Equal(t, "TSUA", encodeBlurhash(white, 8, 6, 1, 4, 3)[2:6], "pure white")
+}
+
+func TestBlurhash(t *testing.T) {
+	landscape := image.NewRGBA(image.Rect(0, 0, 120, 80))
+	for y := range 80 {
+		for x := range 120 {
+			landscape.Set(x, y, color.RGBA{R: uint8(x * 2), G: 60, B: uint8(y * 3), A: 255})
+		}
+	}
+	hash, err := blurhash(encodeJPEG(t, landscape, 90))
+	require.NoError(t, err)
+	require.Len(t, hash, 4+2+2*(4*3-1))
+	assert.Equal(t, "L", hash[:1], "4x3 components")
+
+	portrait := image.NewRGBA(image.Rect(0, 0, 80, 120))
+	hash, err = blurhash(encodeJPEG(t, portrait, 90))
+	require.NoError(t, err)
+	assert.Equal(t, "T", hash[:1], "3x4 components")
+
+	_, err = blurhash([]byte("not an image"))
+	assert.Error(t, err)
+}
@@ -0,0 +1,34 @@
+package services
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/njoubert/nielsshootsfilm/backend/internal/models"
+)
+
+// captureDateLayouts are the formats accepted for capture dates given on upload. Dates and
+// times without a zone are taken as UTC.
+var captureDateLayouts = []string{time.RFC3339, "2006-01-02T15:04:05", "2006-01-02"}
+
+// ParseCaptureDate parses a capture date given on upload, as YYYY-MM-DD, YYYY-MM-DDTHH:MM:SS,
+// or RFC 3339 with a zone.
+func ParseCaptureDate(value string) (time.Time, error) {
+	value = strings.TrimSpace(value)
+	for _, layout := range captureDateLayouts {
+		if date, err := time.Parse(layout, value); err == nil {
+			return date, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("invalid capture date %q: use YYYY-MM-DD, YYYY-MM-DDTHH:MM:SS, or RFC 3339", value)
+}
+
+// SetCaptureDate sets a photo's capture date, which date sections and histograms group by,
+// over any date read from its EXIF data. Other EXIF fields are kept.
+func SetCaptureDate(photo *models.Photo, date time.Time) {
+	if photo.EXIF == nil {
+		photo.EXIF = &models.EXIF{}
+	}
+	photo.EXIF.DateTaken = &date
+}
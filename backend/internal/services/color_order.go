@@ -0,0 +1,187 @@
+package services
+
+import (
+	"cmp"
+	"errors"
+	"fmt"
+	"math"
+	"slices"
+
+	"github.com/davidbyttow/govips/v2/vips"
+	"github.com/njoubert/nielsshootsfilm/backend/internal/models"
+)
+
+// hueSampleEdge is the longest edge covers are shrunk to before measuring their hue.
+const hueSampleEdge = 64
+
+// hueBins is how many slices of the color wheel pixels are tallied into when finding a
+// cover's dominant hue.
+const hueBins = 36
+
+// Pixels count towards the dominant hue only if they are at least this saturated and this
+// bright, so grays, whites, and shadows do not pull covers towards an arbitrary hue.
+const (
+	minHueSaturation = 0.2
+	minHueValue      = 0.15
+)
+
+// minColorfulFraction is the share of a cover's pixels that must be colorful for it to have
+// a dominant hue at all.
+const minColorfulFraction = 0.05
+
+// dominantHue returns the hue, in degrees from 0 (red) through 120 (green) and 240 (blue), of
+// the most prominent color in an image, weighting pixels by saturation. ok is false for
+// images with too little color to have a meaningful hue, like black-and-white photos.
+func dominantHue(imageBytes []byte) (hue float64, ok bool, err error) {
+	img, err := vips.NewThumbnailWithSizeFromBuffer(imageBytes, hueSampleEdge, hueSampleEdge, vips.InterestingNone, vips.SizeDown)
+	if err != nil {
+		return 0, false, fmt.Errorf("failed to shrink image: %w", err)
+	}
+	defer img.Close()
+
+	if err := img.ToColorSpace(vips.InterpretationSRGB); err != nil {
+		return 0, false, fmt.Errorf("failed to convert to sRGB: %w", err)
+	}
+	if img.BandFormat() != vips.BandFormatUchar {
+		if err := img.Cast(vips.BandFormatUchar); err != nil {
+			return 0, false, fmt.Errorf("failed to convert to 8 bits: %w", err)
+		}
+	}
+
+	pixels, err := img.ToBytes()
+	if err != nil {
+		return 0, false, fmt.Errorf("failed to read pixels: %w", err)
+	}
+	bands := img.Bands()
+	count := img.Width() * img.Height()
+	if count == 0 || len(pixels) < count*bands {
+		return 0, false, fmt.Errorf("unexpected pixel data size %d for %dx%d image", len(pixels), img.Width(), img.Height())
+	}
+	if bands < 3 {
+		return 0, false, nil
+	}
+
+	var weights, hueSums [hueBins]float64
+	colorful := 0
+	for i := range count {
+		pixel := pixels[i*bands : (i+1)*bands]
+		h, s, v := hsv(pixel[0], pixel[1], pixel[2])
+		if s < minHueSaturation || v < minHueValue {
+			continue
+		}
+		bin := int(h/360*hueBins) % hueBins
+		weights[bin] += s
+		hueSums[bin] += h * s
+		colorful++
+	}
+	if float64(colorful) < minColorfulFraction*float64(count) {
+		return 0, false, nil
+	}
+
+	best := 0
+	for bin := range weights {
+		if weights[bin] > weights[best] {
+			best = bin
+		}
+	}
+	return hueSums[best] / weights[best], true, nil
+}
+
+// hsv converts an 8-bit RGB color to hue in degrees and saturation and value between 0 and 1.
+func hsv(r, g, b uint8) (h, s, v float64) {
+	rf, gf, bf := float64(r)/255, float64(g)/255, float64(b)/255
+	maxC := math.Max(rf, math.Max(gf, bf))
+	minC := math.Min(rf, math.Min(gf, bf))
+	delta := maxC - minC
+
+	v = maxC
+	if maxC == 0 || delta == 0 {
+		return 0, 0, v
+	}
+	s = delta / maxC
+
+	switch maxC {
+	case rf:
+		h = math.Mod((gf-bf)/delta, 6)
+	case gf:
+		h = (bf-rf)/delta + 2
+	default:
+		h = (rf-gf)/delta + 4
+	}
+	h *= 60
+	if h < 0 {
+		h += 360
+	}
+	return h, s, v
+}
+
+// CoverHue returns the dominant hue of an album's cover photo, measured on its original.
+// ok is false if the album has no cover or the cover has too little color.
+func (s *ImageService) CoverHue(album *models.Album) (hue float64, ok bool, err error) {
+	cover := album.CoverPhoto()
+	if cover == nil {
+		return 0, false, nil
+	}
+
+	original, err := s.storage.Get(storageKeyFromURL(cover.URLOriginal, "originals"))
+	if err != nil {
+		return 0, false, fmt.Errorf("failed to read cover original: %w", err)
+	}
+
+	// Acquire semaphore to limit concurrent VIPS operations
+	s.processSem <- struct{}{}
+	defer func() { <-s.processSem }()
+
+	return dominantHue(original)
+}
+
+// SortByHue orders albums into a color gradient by the hues of their covers, keyed by album
+// ID. Albums without a hue follow, in their current order, which also breaks ties.
+func SortByHue(albums []models.Album, hues map[string]float64) {
+	slices.SortStableFunc(albums, func(a, b models.Album) int {
+		hueA, okA := hues[a.ID]
+		hueB, okB := hues[b.ID]
+		switch {
+		case okA && okB:
+			return cmp.Or(cmp.Compare(hueA, hueB), a.Order-b.Order)
+		case okA:
+			return -1
+		case okB:
+			return 1
+		}
+		return a.Order - b.Order
+	})
+}
+
+// SetAlbumOrder gives every album an explicit position, numbering them from 1 in the order
+// of albumIDs, which must list each album exactly once.
+func (s *AlbumService) SetAlbumOrder(albumIDs []string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	albums, err := s.GetAll()
+	if err != nil {
+		return err
+	}
+	if len(albumIDs) != len(albums) {
+		return errors.New("album ID count does not match album count")
+	}
+
+	positions := make(map[string]int, len(albumIDs))
+	for i, id := range albumIDs {
+		positions[id] = i + 1
+	}
+	for i := range albums {
+		position, ok := positions[albums[i].ID]
+		if !ok {
+			return fmt.Errorf("album %s is missing from the order", albums[i].ID)
+		}
+		albums[i].Order = position
+	}
+
+	collection := models.AlbumCollection{Albums: albums}
+	if err := s.fileService.WriteJSON(albumsFile, &collection); err != nil {
+		return fmt.Errorf("failed to write albums: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,100 @@
+package services
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"testing"
+
+	"github.com/njoubert/nielsshootsfilm/backend/internal/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// createTwoColorJPEG returns a JPEG filled with background, with a centred square of
+// foreground covering about a quarter of it.
+func createTwoColorJPEG(t *testing.T, background, foreground color.RGBA) []byte {
+	t.Helper()
+
+	img := image.NewRGBA(image.Rect(0, 0, 96, 96))
+	for x := 0; x < 96; x++ {
+		for y := 0; y < 96; y++ {
+			c := background
+			if x >= 24 && x < 72 && y >= 24 && y < 72 {
+				c = foreground
+			}
+			img.Set(x, y, c)
+		}
+	}
+
+	var buf bytes.Buffer
+	require.NoError(t, jpeg.Encode(&buf, img, &jpeg.Options{Quality: 95}))
+	return buf.Bytes()
+}
+
+func TestDominantHue(t *testing.T) {
+	red := color.RGBA{R: 220, G: 30, B: 30, A: 255}
+	orange := color.RGBA{R: 230, G: 140, B: 20, A: 255}
+	blue := color.RGBA{R: 30, G: 60, B: 220, A: 255}
+	gray := color.RGBA{R: 128, G: 128, B: 128, A: 255}
+
+	tests := []struct {
+		name    string
+		data    []byte
+		wantHue float64
+		wantOK  bool
+	}{
+		{"solid red", createTwoColorJPEG(t, red, red), 0, true},
+		{"solid blue", createTwoColorJPEG(t, blue, blue), 231, true},
+		{"orange on gray", createTwoColorJPEG(t, gray, orange), 36, true},
+		{"blue outweighs red", createTwoColorJPEG(t, blue, red), 231, true},
+		{"gray", createTwoColorJPEG(t, gray, gray), 0, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			hue, ok, err := dominantHue(tt.data)
+			require.NoError(t, err)
+			assert.Equal(t, tt.wantOK, ok)
+			if tt.wantOK {
+				assert.InDelta(t, tt.wantHue, hue, 5)
+			}
+		})
+	}
+}
+
+func TestSortByHue(t *testing.T) {
+	albums := []models.Album{
+		{ID: "gray", Order: 1},
+		{ID: "blue", Order: 2},
+		{ID: "none", Order: 0},
+		{ID: "red", Order: 4},
+		{ID: "green", Order: 3},
+		{ID: "also-green", Order: 5},
+	}
+	SortByHue(albums, map[string]float64{"blue": 231, "red": 0, "green": 120, "also-green": 120})
+
+	ids := make([]string, len(albums))
+	for i, album := range albums {
+		ids[i] = album.ID
+	}
+	assert.Equal(t, []string{"red", "green", "also-green", "blue", "none", "gray"}, ids)
+}
+
+func TestAlbumService_SetAlbumOrder(t *testing.T) {
+	service, _ := setupAlbumService(t)
+	first := &models.Album{Title: "First", Visibility: "public"}
+	require.NoError(t, service.Create(first))
+	second := &models.Album{Title: "Second", Visibility: "public"}
+	require.NoError(t, service.Create(second))
+
+	require.NoError(t, service.SetAlbumOrder([]string{second.ID, first.ID}))
+	albums, err := service.GetAll()
+	require.NoError(t, err)
+	assert.Equal(t, 2, albums[0].Order)
+	assert.Equal(t, 1, albums[1].Order)
+
+	// Every album must be placed exactly once
+	assert.Error(t, service.SetAlbumOrder([]string{first.ID}))
+	assert.Error(t, service.SetAlbumOrder([]string{first.ID, first.ID}))
+}
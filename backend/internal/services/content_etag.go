@@ -0,0 +1,69 @@
+package services
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"sync"
+)
+
+// contentETags caches the content-hash ETags of stored objects. Objects are only rewritten
+// through ImageService.putBytes and removed through ImageService.deleteObject, which both
+// forget the key, so cached tags stay accurate and deleted objects leave none behind.
+type contentETags struct {
+	mu         sync.Mutex
+	tags       map[string]string
+	generation uint64 // Bumped by every forget, so a hash racing a rewrite is not cached
+}
+
+// forget drops a key's cached ETag.
+func (c *contentETags) forget(key string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.tags, key)
+	c.generation++
+}
+
+// reset drops every cached ETag, e.g. when the storage backend changes.
+func (c *contentETags) reset() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.tags = nil
+	c.generation++
+}
+
+// ContentETag returns a strong ETag for a stored object, derived from the SHA-256 of its
+// content. The object is read once and its tag cached until the object is rewritten.
+// Returns an error wrapping ErrObjectNotFound if the object does not exist.
+func (s *ImageService) ContentETag(key string) (string, error) {
+	s.etags.mu.Lock()
+	etag, ok := s.etags.tags[key]
+	generation := s.etags.generation
+	s.etags.mu.Unlock()
+	if ok {
+		return etag, nil
+	}
+
+	reader, err := s.storage.Stream(key)
+	if err != nil {
+		return "", err
+	}
+	defer func() { _ = reader.Close() }()
+
+	hash := sha256.New()
+	if _, err := io.Copy(hash, reader); err != nil {
+		return "", fmt.Errorf("failed to hash %s: %w", key, err)
+	}
+	etag = `"` + hex.EncodeToString(hash.Sum(nil)[:16]) + `"`
+
+	s.etags.mu.Lock()
+	if s.etags.generation == generation {
+		if s.etags.tags == nil {
+			s.etags.tags = make(map[string]string)
+		}
+		s.etags.tags[key] = etag
+	}
+	s.etags.mu.Unlock()
+	return etag, nil
+}
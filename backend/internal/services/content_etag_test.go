@@ -0,0 +1,44 @@
+package services
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestImageService_ContentETag(t *testing.T) {
+	imageService, err := NewImageService(t.TempDir(), nil, nil)
+	require.NoError(t, err)
+	imageService.SetStorage(NewMemoryStorage())
+
+	require.NoError(t, imageService.putBytes("display/a.webp", []byte("first")))
+	require.NoError(t, imageService.putBytes("display/b.webp", []byte("second")))
+
+	etag, err := imageService.ContentETag("display/a.webp")
+	require.NoError(t, err)
+	assert.Regexp(t, `^"[0-9a-f]{32}"$`, etag)
+
+	again, err := imageService.ContentETag("display/a.webp")
+	require.NoError(t, err)
+	assert.Equal(t, etag, again)
+
+	other, err := imageService.ContentETag("display/b.webp")
+	require.NoError(t, err)
+	assert.NotEqual(t, etag, other)
+
+	// Rewriting an object in place, as regeneration does, changes its ETag
+	require.NoError(t, imageService.putBytes("display/a.webp", []byte("rewritten")))
+	rewritten, err := imageService.ContentETag("display/a.webp")
+	require.NoError(t, err)
+	assert.NotEqual(t, etag, rewritten)
+
+	_, err = imageService.ContentETag("display/missing.webp")
+	assert.ErrorIs(t, err, ErrObjectNotFound)
+
+	// Deleting an object drops its cached ETag
+	require.NoError(t, imageService.deleteObject("display/a.webp"))
+	assert.NotContains(t, imageService.etags.tags, "display/a.webp")
+	_, err = imageService.ContentETag("display/a.webp")
+	assert.ErrorIs(t, err, ErrObjectNotFound)
+}
@@ -0,0 +1,56 @@
+package services
+
+import (
+	"errors"
+	"regexp"
+	"strings"
+)
+
+// ErrInvalidContentHash is returned for content hashes that are not hex SHA-256 digests.
+var ErrInvalidContentHash = errors.New("content hash must be 64 hexadecimal characters")
+
+// contentHashPattern matches a hex SHA-256 digest, as recorded in a photo's ContentHash.
+var contentHashPattern = regexp.MustCompile(`^[0-9a-f]{64}$`)
+
+// ContentReference is a photo holding a given file, with the album it is in.
+type ContentReference struct {
+	AlbumID          string `json:"album_id"`
+	AlbumSlug        string `json:"album_slug"`
+	AlbumTitle       string `json:"album_title"`
+	PhotoID          string `json:"photo_id"`
+	FilenameOriginal string `json:"filename_original"`
+	URLOriginal      string `json:"url_original"` // Photos sharing a stored file have the same URL
+}
+
+// FindByContentHash lists every photo, across all albums, whose uploaded file had the given
+// SHA-256 content hash, in the order albums and their photos are stored. The hash is matched
+// case-insensitively. Photos uploaded before hashes were recorded are never found.
+func (s *AlbumService) FindByContentHash(hash string) ([]ContentReference, error) {
+	hash = strings.ToLower(hash)
+	if !contentHashPattern.MatchString(hash) {
+		return nil, ErrInvalidContentHash
+	}
+
+	albums, err := s.GetAll()
+	if err != nil {
+		return nil, err
+	}
+
+	refs := []ContentReference{}
+	for _, album := range albums {
+		for _, photo := range album.Photos {
+			if photo.ContentHash != hash {
+				continue
+			}
+			refs = append(refs, ContentReference{
+				AlbumID:          album.ID,
+				AlbumSlug:        album.Slug,
+				AlbumTitle:       album.Title,
+				PhotoID:          photo.ID,
+				FilenameOriginal: photo.FilenameOriginal,
+				URLOriginal:      photo.URLOriginal,
+			})
+		}
+	}
+	return refs, nil
+}
@@ -0,0 +1,69 @@
+package services
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/njoubert/nielsshootsfilm/backend/internal/models"
+)
+
+// Date histogram bucket sizes.
+const (
+	BucketDay   = "day"
+	BucketWeek  = "week"
+	BucketMonth = "month"
+)
+
+// UnknownDateBucket counts the photos without a capture date in a date histogram.
+const UnknownDateBucket = "unknown"
+
+// DateBucket is the number of photos taken in one time bucket.
+type DateBucket struct {
+	Bucket string `json:"bucket"` // 2006-01-02 for days, 2006-W01 (ISO week) for weeks, 2006-01 for months, or "unknown"
+	Count  int    `json:"count"`
+}
+
+// DateHistogram counts photos per capture day, ISO week, or month, as recorded by the camera.
+// Buckets are in date order and empty ones are left out; photos without a capture date are
+// counted in a final UnknownDateBucket, present only if there are any.
+func DateHistogram(photos []models.Photo, bucket string) ([]DateBucket, error) {
+	var label func(photo *models.Photo) string
+	switch bucket {
+	case BucketDay:
+		label = func(photo *models.Photo) string { return photo.EXIF.DateTaken.Format("2006-01-02") }
+	case BucketWeek:
+		label = func(photo *models.Photo) string {
+			year, week := photo.EXIF.DateTaken.ISOWeek()
+			return fmt.Sprintf("%04d-W%02d", year, week)
+		}
+	case BucketMonth:
+		label = func(photo *models.Photo) string { return photo.EXIF.DateTaken.Format("2006-01") }
+	default:
+		return nil, fmt.Errorf("unsupported bucket %q (supported: %s, %s, %s)", bucket, BucketDay, BucketWeek, BucketMonth)
+	}
+
+	counts := map[string]int{}
+	unknown := 0
+	for i := range photos {
+		if photos[i].EXIF == nil || photos[i].EXIF.DateTaken == nil {
+			unknown++
+			continue
+		}
+		counts[label(&photos[i])]++
+	}
+
+	labels := make([]string, 0, len(counts))
+	for l := range counts {
+		labels = append(labels, l)
+	}
+	sort.Strings(labels)
+
+	histogram := make([]DateBucket, 0, len(labels)+1)
+	for _, l := range labels {
+		histogram = append(histogram, DateBucket{Bucket: l, Count: counts[l]})
+	}
+	if unknown > 0 {
+		histogram = append(histogram, DateBucket{Bucket: UnknownDateBucket, Count: unknown})
+	}
+	return histogram, nil
+}
@@ -0,0 +1,70 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"github.com/njoubert/nielsshootsfilm/backend/internal/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDateHistogram(t *testing.T) {
+	takenAt := func(year int, month time.Month, day, hour int) models.Photo {
+		taken := time.Date(year, month, day, hour, 0, 0, 0, time.UTC)
+		return models.Photo{EXIF: &models.EXIF{DateTaken: &taken}}
+	}
+	photos := []models.Photo{
+		takenAt(2024, time.August, 3, 14), // Saturday, ISO week 31
+		takenAt(2024, time.July, 31, 9),   // Wednesday, week 31
+		takenAt(2024, time.August, 3, 23), // Same day, late
+		{EXIF: &models.EXIF{Camera: "Leica M6"}},
+		takenAt(2024, time.August, 5, 8), // Monday, week 32
+		{},
+		takenAt(2024, time.December, 30, 12), // ISO week 1 of 2025
+	}
+
+	tests := []struct {
+		bucket string
+		want   []DateBucket
+	}{
+		{BucketDay, []DateBucket{
+			{Bucket: "2024-07-31", Count: 1},
+			{Bucket: "2024-08-03", Count: 2},
+			{Bucket: "2024-08-05", Count: 1},
+			{Bucket: "2024-12-30", Count: 1},
+			{Bucket: UnknownDateBucket, Count: 2},
+		}},
+		{BucketWeek, []DateBucket{
+			{Bucket: "2024-W31", Count: 3},
+			{Bucket: "2024-W32", Count: 1},
+			{Bucket: "2025-W01", Count: 1},
+			{Bucket: UnknownDateBucket, Count: 2},
+		}},
+		{BucketMonth, []DateBucket{
+			{Bucket: "2024-07", Count: 1},
+			{Bucket: "2024-08", Count: 3},
+			{Bucket: "2024-12", Count: 1},
+			{Bucket: UnknownDateBucket, Count: 2},
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.bucket, func(t *testing.T) {
+			histogram, err := DateHistogram(photos, tt.bucket)
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, histogram)
+		})
+	}
+
+	// Fully dated albums have no unknown bucket; empty albums have no buckets at all
+	histogram, err := DateHistogram(photos[:3], BucketMonth)
+	require.NoError(t, err)
+	assert.Equal(t, []DateBucket{{Bucket: "2024-07", Count: 1}, {Bucket: "2024-08", Count: 2}}, histogram)
+
+	histogram, err = DateHistogram(nil, BucketDay)
+	require.NoError(t, err)
+	assert.Empty(t, histogram)
+
+	_, err = DateHistogram(photos, "year")
+	assert.EqualError(t, err, `unsupported bucket "year" (supported: day, week, month)`)
+}
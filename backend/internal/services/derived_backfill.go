@@ -0,0 +1,282 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"maps"
+	"slices"
+	"strings"
+
+	"github.com/davidbyttow/govips/v2/vips"
+	"github.com/njoubert/nielsshootsfilm/backend/internal/models"
+)
+
+// DerivedBackfillJobType identifies derived field backfill jobs.
+const DerivedBackfillJobType = "backfill_derived"
+
+// Derived fields: photo fields computed from the original on upload.
+const (
+	DerivedFieldDimensions     = "dimensions"      // Width and height
+	DerivedFieldPerceptualHash = "perceptual_hash" // For near-duplicate detection
+	DerivedFieldExposure       = "exposure"        // Brightness statistics
+	DerivedFieldBlurhash       = "blurhash"        // Placeholder shown while thumbnails load
+	DerivedFieldPalette        = "palette"         // Most common colors
+)
+
+// DerivedFields lists every derived field that can be backfilled, in the order counts are reported.
+var DerivedFields = []string{DerivedFieldDimensions, DerivedFieldPerceptualHash, DerivedFieldExposure, DerivedFieldBlurhash, DerivedFieldPalette}
+
+// ParseDerivedFields parses a comma-separated list of derived fields, dropping duplicates.
+// An empty list means every field.
+func ParseDerivedFields(list string) ([]string, error) {
+	if strings.TrimSpace(list) == "" {
+		return slices.Clone(DerivedFields), nil
+	}
+	var fields []string
+	for _, field := range strings.Split(list, ",") {
+		field = strings.TrimSpace(field)
+		if !slices.Contains(DerivedFields, field) {
+			return nil, fmt.Errorf("unknown derived field %q (allowed: %s)", field, strings.Join(DerivedFields, ", "))
+		}
+		if !slices.Contains(fields, field) {
+			fields = append(fields, field)
+		}
+	}
+	return fields, nil
+}
+
+// DerivedBackfillService recomputes derived fields of existing photos from their stored
+// originals in the background, so photos uploaded before a field existed get it too.
+// Only the requested fields are touched, and derivatives are left alone.
+type DerivedBackfillService struct {
+	albumService *AlbumService
+	imageService *ImageService
+	jobService   *JobService
+	logger       *slog.Logger
+}
+
+// NewDerivedBackfillService creates a new derived field backfill service.
+func NewDerivedBackfillService(albumService *AlbumService, imageService *ImageService, jobService *JobService, logger *slog.Logger) *DerivedBackfillService {
+	return &DerivedBackfillService{
+		albumService: albumService,
+		imageService: imageService,
+		jobService:   jobService,
+		logger:       logger,
+	}
+}
+
+// derivedValues are the derived fields computed for one photo; only requested ones are set.
+type derivedValues struct {
+	width, height  int
+	perceptualHash string
+	exposure       *models.Exposure
+	blurhash       string
+	palette        []string
+}
+
+// Start begins recomputing fields for every photo of one album, or every album if albumID
+// is empty, and returns the job tracking its progress. The job's counts give, per field, how
+// many photos it changed.
+func (s *DerivedBackfillService) Start(albumID string, fields []string) (models.Job, error) {
+	var albums []models.Album
+	if albumID != "" {
+		album, err := s.albumService.GetByID(albumID)
+		if err != nil {
+			return models.Job{}, err
+		}
+		albums = []models.Album{*album}
+	} else {
+		all, err := s.albumService.GetAll()
+		if err != nil {
+			return models.Job{}, err
+		}
+		albums = all
+	}
+
+	total := 0
+	for _, album := range albums {
+		total += len(album.Photos)
+	}
+
+	// Every requested field is reported, even if no photo changes
+	counts := make(map[string]int, len(fields))
+	for _, field := range fields {
+		counts[field] = 0
+	}
+
+	job := s.jobService.Create(DerivedBackfillJobType, total)
+	s.jobService.Update(job.ID, func(job *models.Job) {
+		job.Counts = counts
+	})
+	job.Counts = maps.Clone(counts)
+	go s.run(job.ID, albums, fields)
+	return job, nil
+}
+
+// run computes each album's fields, then saves the album once with the new values.
+func (s *DerivedBackfillService) run(jobID string, albums []models.Album, fields []string) {
+	for _, album := range albums {
+		computed := make(map[string]derivedValues, len(album.Photos))
+
+		for i := range album.Photos {
+			photo := &album.Photos[i]
+			values, err := s.imageService.computeDerivedFields(photo, fields)
+			if err == nil {
+				computed[photo.ID] = values
+			}
+
+			item := models.JobItem{AlbumID: album.ID, PhotoID: photo.ID, Filename: photo.FilenameOriginal}
+			s.jobService.Update(jobID, func(job *models.Job) {
+				job.Processed++
+				switch {
+				case errors.Is(err, ErrObjectNotFound):
+					item.Reason = "original not found"
+					job.Skipped = append(job.Skipped, item)
+				case err != nil:
+					item.Reason = err.Error()
+					job.Errors = append(job.Errors, item)
+				}
+			})
+
+			if err != nil && s.logger != nil {
+				s.logger.Warn("failed to compute derived fields",
+					slog.String("job_id", jobID),
+					slog.String("album_id", album.ID),
+					slog.String("photo_id", photo.ID),
+					slog.String("error", err.Error()),
+				)
+			}
+		}
+
+		changed, err := s.saveAlbum(album.ID, computed, fields)
+		if err != nil {
+			if s.logger != nil {
+				s.logger.Error("failed to save backfilled fields",
+					slog.String("job_id", jobID),
+					slog.String("album_id", album.ID),
+					slog.String("error", err.Error()),
+				)
+			}
+			continue
+		}
+		s.jobService.Update(jobID, func(job *models.Job) {
+			for field, photos := range changed {
+				job.Counts[field] += photos
+			}
+		})
+	}
+
+	s.jobService.Finish(jobID, nil)
+}
+
+// saveAlbum applies computed values to the album as currently stored, so edits made while
+// the job ran are kept, and returns how many photos changed per field. The album is only
+// written if something changed.
+func (s *DerivedBackfillService) saveAlbum(albumID string, computed map[string]derivedValues, fields []string) (map[string]int, error) {
+	if len(computed) == 0 {
+		return nil, nil
+	}
+
+	var changed map[string]int
+	_, err := s.albumService.Modify(albumID, func(album *models.Album) error {
+		changed = make(map[string]int)
+		for i := range album.Photos {
+			if values, ok := computed[album.Photos[i].ID]; ok {
+				for _, field := range applyDerivedFields(&album.Photos[i], values, fields) {
+					changed[field]++
+				}
+			}
+		}
+		if len(changed) == 0 {
+			return errAlbumUnchanged
+		}
+		return nil
+	})
+	if err != nil || len(changed) == 0 {
+		return nil, err
+	}
+	return changed, nil
+}
+
+// applyDerivedFields sets the requested fields of a photo to the computed values and
+// returns the fields whose values changed.
+func applyDerivedFields(photo *models.Photo, values derivedValues, fields []string) []string {
+	var changed []string
+	for _, field := range fields {
+		switch field {
+		case DerivedFieldDimensions:
+			if photo.Width == values.width && photo.Height == values.height {
+				continue
+			}
+			photo.Width, photo.Height = values.width, values.height
+		case DerivedFieldPerceptualHash:
+			if photo.PerceptualHash == values.perceptualHash {
+				continue
+			}
+			photo.PerceptualHash = values.perceptualHash
+		case DerivedFieldExposure:
+			if photo.Exposure != nil && *photo.Exposure == *values.exposure {
+				continue
+			}
+			photo.Exposure = values.exposure
+		case DerivedFieldBlurhash:
+			if photo.Blurhash == values.blurhash {
+				continue
+			}
+			photo.Blurhash = values.blurhash
+		case DerivedFieldPalette:
+			if slices.Equal(photo.Palette, values.palette) {
+				continue
+			}
+			photo.Palette = values.palette
+		default:
+			continue
+		}
+		changed = append(changed, field)
+	}
+	return changed
+}
+
+// computeDerivedFields computes the requested derived fields of a photo from its original.
+// Returns an error wrapping ErrObjectNotFound if the original is missing.
+func (s *ImageService) computeDerivedFields(photo *models.Photo, fields []string) (derivedValues, error) {
+	original, err := s.storage.Get(storageKeyFromURL(photo.URLOriginal, "originals"))
+	if err != nil {
+		return derivedValues{}, fmt.Errorf("failed to read original: %w", err)
+	}
+
+	// Acquire semaphore to limit concurrent VIPS operations
+	s.processSem <- struct{}{}
+	defer func() { <-s.processSem }()
+
+	var values derivedValues
+	for _, field := range fields {
+		switch field {
+		case DerivedFieldDimensions:
+			img, err := vips.NewImageFromBuffer(original)
+			if err != nil {
+				return derivedValues{}, fmt.Errorf("failed to decode original: %w", err)
+			}
+			values.width, values.height = img.Width(), img.Height()
+			img.Close()
+		case DerivedFieldPerceptualHash:
+			if values.perceptualHash, err = perceptualHash(original); err != nil {
+				return derivedValues{}, err
+			}
+		case DerivedFieldExposure:
+			if values.exposure, err = measureExposure(original); err != nil {
+				return derivedValues{}, err
+			}
+		case DerivedFieldBlurhash:
+			if values.blurhash, err = blurhash(original); err != nil {
+				return derivedValues{}, err
+			}
+		case DerivedFieldPalette:
+			if values.palette, err = extractPalette(original); err != nil {
+				return derivedValues{}, err
+			}
+		}
+	}
+	return values, nil
+}
@@ -0,0 +1,115 @@
+package services
+
+import (
+	"testing"
+
+	"github.com/njoubert/nielsshootsfilm/backend/internal/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseDerivedFields(t *testing.T) {
+	fields, err := ParseDerivedFields("")
+	require.NoError(t, err)
+	assert.Equal(t, DerivedFields, fields)
+
+	fields, err = ParseDerivedFields("exposure, dimensions,exposure")
+	require.NoError(t, err)
+	assert.Equal(t, []string{DerivedFieldExposure, DerivedFieldDimensions}, fields)
+
+	fields, err = ParseDerivedFields("palette,blurhash,dimensions")
+	require.NoError(t, err)
+	assert.Equal(t, []string{DerivedFieldPalette, DerivedFieldBlurhash, DerivedFieldDimensions}, fields)
+
+	_, err = ParseDerivedFields("dimensions,histogram")
+	assert.ErrorContains(t, err, `unknown derived field "histogram"`)
+}
+
+func TestDerivedBackfillService_Start(t *testing.T) {
+	albumService, _ := setupAlbumService(t)
+	imageService, err := NewImageService(t.TempDir(), nil, nil)
+	require.NoError(t, err)
+	storage := NewMemoryStorage()
+	imageService.SetStorage(storage)
+
+	album := &models.Album{Title: "Back Catalogue", Visibility: "public"}
+	require.NoError(t, albumService.Create(album))
+
+	// Uploaded before any derived fields were recorded
+	old, err := imageService.ProcessBytes("old.jpg", createTestJPEG(t, 320, 200))
+	require.NoError(t, err)
+	wantHash, wantExposure, wantPalette := old.PerceptualHash, *old.Exposure, old.Palette
+	require.NotEmpty(t, wantHash)
+	require.NotEmpty(t, wantPalette)
+	old.Width, old.Height, old.PerceptualHash, old.Exposure, old.Palette = 0, 0, "", nil, nil
+	require.NoError(t, albumService.AddPhoto(album.ID, old))
+
+	// Already up to date
+	current, err := imageService.ProcessBytes("current.jpg", createTestJPEG(t, 200, 320))
+	require.NoError(t, err)
+	require.NoError(t, albumService.AddPhoto(album.ID, current))
+
+	// Lost original
+	orphan, err := imageService.ProcessBytes("orphan.jpg", createTestJPEG(t, 100, 100))
+	require.NoError(t, err)
+	orphan.Width, orphan.Height = 0, 0
+	require.NoError(t, albumService.AddPhoto(album.ID, orphan))
+	require.NoError(t, storage.Delete(storageKeyFromURL(orphan.URLOriginal, "originals")))
+
+	jobService := NewJobService()
+	backfillService := NewDerivedBackfillService(albumService, imageService, jobService, nil)
+
+	// Only the requested field is recomputed
+	job, err := backfillService.Start(album.ID, []string{DerivedFieldDimensions})
+	require.NoError(t, err)
+	assert.Equal(t, DerivedBackfillJobType, job.Type)
+	assert.Equal(t, 3, job.Total)
+
+	finished := waitForJob(t, jobService, job.ID)
+	assert.Equal(t, models.JobStatusCompleted, finished.Status)
+	assert.Equal(t, map[string]int{DerivedFieldDimensions: 1}, finished.Counts)
+	assert.Empty(t, finished.Errors)
+	require.Len(t, finished.Skipped, 1)
+	assert.Equal(t, orphan.ID, finished.Skipped[0].PhotoID)
+
+	album, err = albumService.GetByID(album.ID)
+	require.NoError(t, err)
+	got := album.Photos[0]
+	assert.Equal(t, 320, got.Width)
+	assert.Equal(t, 200, got.Height)
+	assert.Empty(t, got.PerceptualHash)
+	assert.Nil(t, got.Exposure)
+	assert.Empty(t, got.Palette)
+	assert.Zero(t, album.Photos[2].Width, "photos without originals are left alone")
+
+	// The remaining fields, counted separately
+	job, err = backfillService.Start(album.ID, []string{DerivedFieldPerceptualHash, DerivedFieldExposure, DerivedFieldDimensions})
+	require.NoError(t, err)
+	finished = waitForJob(t, jobService, job.ID)
+	assert.Equal(t, map[string]int{DerivedFieldPerceptualHash: 1, DerivedFieldExposure: 1, DerivedFieldDimensions: 0}, finished.Counts)
+
+	album, err = albumService.GetByID(album.ID)
+	require.NoError(t, err)
+	got = album.Photos[0]
+	assert.Equal(t, wantHash, got.PerceptualHash)
+	require.NotNil(t, got.Exposure)
+	assert.Equal(t, wantExposure, *got.Exposure)
+	assert.Empty(t, got.Palette)
+
+	job, err = backfillService.Start(album.ID, []string{DerivedFieldPalette})
+	require.NoError(t, err)
+	finished = waitForJob(t, jobService, job.ID)
+	assert.Equal(t, map[string]int{DerivedFieldPalette: 1}, finished.Counts)
+	album, err = albumService.GetByID(album.ID)
+	require.NoError(t, err)
+	assert.Equal(t, wantPalette, album.Photos[0].Palette)
+}
+
+func TestDerivedBackfillService_Start_AlbumNotFound(t *testing.T) {
+	albumService, _ := setupAlbumService(t)
+	imageService, err := NewImageService(t.TempDir(), nil, nil)
+	require.NoError(t, err)
+
+	_, err = NewDerivedBackfillService(albumService, imageService, NewJobService(), nil).Start("missing", DerivedFields)
+	assert.EqualError(t, err, "album not found")
+}
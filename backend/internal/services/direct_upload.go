@@ -0,0 +1,13 @@
+package services
+
+import (
+	"time"
+)
+
+// DirectUploadBackend is a storage backend that clients can upload to directly
+// using pre-signed URLs, bypassing the app server for the file transfer.
+type DirectUploadBackend interface {
+	Storage
+	// PresignPut returns a URL that accepts a single PUT of the object body.
+	PresignPut(key string, expires time.Duration) (string, error)
+}
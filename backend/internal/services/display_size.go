@@ -0,0 +1,12 @@
+package services
+
+import "github.com/njoubert/nielsshootsfilm/backend/internal/models"
+
+// DisplayMaxEdge returns the longest edge, in pixels, of display versions of an album's
+// photos: the album's own override, else the default. A nil album gets the default.
+func (s *ImageService) DisplayMaxEdge(album *models.Album) int {
+	if album != nil && album.DisplayMaxEdge > 0 {
+		return album.DisplayMaxEdge
+	}
+	return displayMaxSize
+}
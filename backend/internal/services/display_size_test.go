@@ -0,0 +1,50 @@
+package services
+
+import (
+	"io"
+	"log/slog"
+	"strings"
+	"testing"
+
+	"github.com/davidbyttow/govips/v2/vips"
+	"github.com/njoubert/nielsshootsfilm/backend/internal/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// displaySize decodes a photo's stored display version and returns its dimensions.
+func displaySize(t *testing.T, imageService *ImageService, photo models.Photo) (int, int) {
+	t.Helper()
+
+	data, err := imageService.Storage().Get(strings.TrimPrefix(photo.URLDisplay, "/uploads/"))
+	require.NoError(t, err)
+	img, err := vips.NewImageFromBuffer(data)
+	require.NoError(t, err)
+	defer img.Close()
+	return img.Width(), img.Height()
+}
+
+func TestImageService_DisplayMaxEdge(t *testing.T) {
+	imageService, err := NewImageService(t.TempDir(), nil, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	require.NoError(t, err)
+	imageService.SetStorage(NewMemoryStorage())
+
+	// Photos smaller than the default are never enlarged
+	assert.Equal(t, 3840, imageService.DisplayMaxEdge(nil))
+	assert.Equal(t, 3840, imageService.DisplayMaxEdge(&models.Album{}))
+	photo, err := imageService.ProcessBytes("wide.jpg", createTestJPEG(t, 1600, 1000))
+	require.NoError(t, err)
+	width, height := displaySize(t, imageService, *photo)
+	assert.Equal(t, 1600, width)
+	assert.Equal(t, 1000, height)
+
+	// An album's override shrinks the long edge, keeping the aspect ratio
+	album := &models.Album{DisplayMaxEdge: 800}
+	assert.Equal(t, 800, imageService.DisplayMaxEdge(album))
+	rendered, err := imageService.RenderDisplay(album, *photo)
+	require.NoError(t, err)
+	width, height = displaySize(t, imageService, rendered)
+	assert.Equal(t, 800, width)
+	assert.Equal(t, 500, height)
+	assert.Less(t, rendered.FileSizeDisplay, photo.FileSizeDisplay)
+}
@@ -0,0 +1,159 @@
+package services
+
+import (
+	"fmt"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/njoubert/nielsshootsfilm/backend/internal/models"
+)
+
+// maxDownloadFilenameStem is the longest rendered download filename, in characters, before
+// its extension.
+const maxDownloadFilenameStem = 180
+
+// filenameSeparators are the characters that join placeholders in a template. Rendered names
+// have runs of one collapsed and are trimmed of them at the ends.
+const filenameSeparators = "-_. "
+
+// downloadFilenameTemplate returns the template naming an album's downloaded photos: the
+// album's own, else the site's, else empty to keep uploaded filenames.
+func (s *ImageService) downloadFilenameTemplate(album *models.Album) string {
+	if album.DownloadFilename != "" || s.configService == nil {
+		return album.DownloadFilename
+	}
+	config, err := s.configService.Get()
+	if err != nil {
+		return ""
+	}
+	return config.Storage.DownloadFilename
+}
+
+// downloadFilename names a photo's download by filling in a template's placeholders, then
+// adds ext, the extension of the file sent. Characters unsafe in filenames are replaced,
+// separators left doubled or dangling by an empty placeholder, like a missing {title}, are
+// tidied away, and a template that renders to nothing falls back to the uploaded filename.
+// An empty template keeps the uploaded filename.
+func downloadFilename(template string, album *models.Album, photo *models.Photo, ext string) string {
+	stem := strings.TrimSuffix(photo.FilenameOriginal, filepath.Ext(photo.FilenameOriginal))
+	if template == "" {
+		return stem + ext
+	}
+
+	// Split the template into literals and placeholders, filling the placeholders in
+	type part struct {
+		text        string
+		placeholder bool
+	}
+	var parts []part
+	last := 0
+	for _, match := range models.FilenamePlaceholderPattern.FindAllStringSubmatchIndex(template, -1) {
+		parts = append(parts,
+			part{text: template[last:match[0]]},
+			part{text: placeholderValue(template[match[2]:match[3]], album, photo, stem), placeholder: true})
+		last = match[1]
+	}
+	parts = append(parts, part{text: template[last:]})
+
+	// An empty placeholder takes the separator after it with it, or else the one before
+	for i := range parts {
+		if !parts[i].placeholder || parts[i].text != "" {
+			continue
+		}
+		if i+1 < len(parts) && parts[i+1].text != "" && isSeparators(parts[i+1].text) {
+			parts[i+1].text = ""
+		} else if i > 0 && isSeparators(parts[i-1].text) {
+			parts[i-1].text = ""
+		}
+	}
+	var rendered strings.Builder
+	for _, part := range parts {
+		rendered.WriteString(part.text)
+	}
+
+	if safe := sanitizeFilename(rendered.String()); safe != "" {
+		return safe + ext
+	}
+	if safe := sanitizeFilename(stem); safe != "" {
+		return safe + ext
+	}
+	return photo.ID + ext
+}
+
+// placeholderValue returns what a download filename placeholder stands for; stem is the
+// uploaded filename without its extension.
+func placeholderValue(name string, album *models.Album, photo *models.Photo, stem string) string {
+	switch name {
+	case models.FilenamePlaceholderAlbum:
+		return album.Slug
+	case models.FilenamePlaceholderIndex:
+		return photoIndex(album, photo)
+	case models.FilenamePlaceholderTitle:
+		return photo.Title
+	case models.FilenamePlaceholderFilename:
+		return stem
+	case models.FilenamePlaceholderDate:
+		if photo.EXIF != nil && photo.EXIF.DateTaken != nil {
+			return photo.EXIF.DateTaken.Format("2006-01-02")
+		}
+	}
+	return ""
+}
+
+// isSeparators reports whether text is made only of filename separators.
+func isSeparators(text string) bool {
+	return strings.Trim(text, filenameSeparators) == ""
+}
+
+// photoIndex returns a photo's 1-based position in its album, zero-padded to the width of the
+// album's photo count so names sort in album order, or empty if the photo is not in it.
+func photoIndex(album *models.Album, photo *models.Photo) string {
+	for i := range album.Photos {
+		if album.Photos[i].ID == photo.ID {
+			width := len(strconv.Itoa(len(album.Photos)))
+			return fmt.Sprintf("%0*d", width, i+1)
+		}
+	}
+	return ""
+}
+
+// sanitizeFilename makes a rendered name safe as a filename on common systems: path
+// separators, characters Windows reserves, and control characters become hyphens, runs of
+// a separator collapse to one, and separators are trimmed from the ends. Long names are cut.
+func sanitizeFilename(name string) string {
+	var b strings.Builder
+	var previous rune
+	for _, r := range name {
+		if unicode.IsControl(r) || strings.ContainsRune(`/\:*?"<>|`, r) {
+			r = '-'
+		}
+		if unicode.IsSpace(r) {
+			r = ' '
+		}
+		if r == previous && strings.ContainsRune(filenameSeparators, r) {
+			continue
+		}
+		b.WriteRune(r)
+		previous = r
+	}
+
+	safe := strings.Trim(b.String(), filenameSeparators)
+	if runes := []rune(safe); len(runes) > maxDownloadFilenameStem {
+		safe = strings.TrimRight(string(runes[:maxDownloadFilenameStem]), filenameSeparators)
+	}
+	return safe
+}
+
+// uniqueFilename returns name, or if taken already, name numbered before its extension like
+// "name-2.jpg", and marks the result taken.
+func uniqueFilename(taken map[string]bool, name string) string {
+	unique := name
+	ext := filepath.Ext(name)
+	for n := 2; taken[unique]; n++ {
+		unique = fmt.Sprintf("%s-%d%s", strings.TrimSuffix(name, ext), n, ext)
+	}
+	taken[unique] = true
+	return unique
+}
@@ -0,0 +1,96 @@
+package services
+
+import (
+	"archive/zip"
+	"bytes"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/njoubert/nielsshootsfilm/backend/internal/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDownloadFilename(t *testing.T) {
+	taken := time.Date(2024, 3, 9, 17, 30, 0, 0, time.UTC)
+	photos := make([]models.Photo, 12)
+	for i := range photos {
+		photos[i] = models.Photo{ID: string(rune('a' + i)), FilenameOriginal: "IMG_0001.JPG"}
+	}
+	photos[2].Title = "Golden Hour"
+	photos[2].EXIF = &models.EXIF{DateTaken: &taken}
+	photos[3].Title = `Pier: "North/South" <2>`
+	album := &models.Album{Slug: "coastline", Photos: photos}
+
+	tests := []struct {
+		name     string
+		template string
+		photo    int
+		want     string
+	}{
+		{"no template keeps the uploaded name", "", 0, "IMG_0001.jpg"},
+		{"with title", "{album}-{index}-{title}", 2, "coastline-03-Golden Hour.jpg"},
+		{"without title", "{album}-{index}-{title}", 0, "coastline-01.jpg"},
+		{"without title in the middle", "{album} - {title} - {index}", 0, "coastline - 01.jpg"},
+		{"without title first", "{title}_{index}", 0, "01.jpg"},
+		{"nothing left falls back to the uploaded name", "{title}", 0, "IMG_0001.jpg"},
+		{"unsafe characters", "{title}", 3, "Pier- -North-South- -2.jpg"},
+		{"date and filename", "{date}_{filename}", 2, "2024-03-09_IMG_0001.jpg"},
+		{"unknown date", "{date}_{filename}", 0, "IMG_0001.jpg"},
+		{"literal text", "{album} print {index}", 11, "coastline print 12.jpg"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ext := ".jpg"
+			assert.Equal(t, tt.want, downloadFilename(tt.template, album, &album.Photos[tt.photo], ext))
+		})
+	}
+}
+
+func TestUniqueFilename(t *testing.T) {
+	taken := map[string]bool{}
+	assert.Equal(t, "roll.jpg", uniqueFilename(taken, "roll.jpg"))
+	assert.Equal(t, "roll-2.jpg", uniqueFilename(taken, "roll.jpg"))
+	assert.Equal(t, "roll-3.jpg", uniqueFilename(taken, "roll.jpg"))
+}
+
+func TestImageService_DownloadFilenameTemplate(t *testing.T) {
+	imageService, err := NewImageService(t.TempDir(), nil, nil)
+	require.NoError(t, err)
+	imageService.SetStorage(NewMemoryStorage())
+
+	titled, err := imageService.ProcessBytes("one.jpg", createTestJPEG(t, 64, 48))
+	require.NoError(t, err)
+	titled.ID = "titled"
+	titled.Title = "Dunes"
+	untitled, err := imageService.ProcessBytes("two.jpg", createTestJPEG(t, 48, 64))
+	require.NoError(t, err)
+	untitled.ID = "untitled"
+
+	album := &models.Album{ID: "album-1", Slug: "sahara", DownloadFilename: "{album}-{index}-{title}", Photos: []models.Photo{*titled, *untitled}}
+
+	w := httptest.NewRecorder()
+	require.NoError(t, imageService.StreamPhoto(w, album, &album.Photos[0], "original"))
+	assert.Equal(t, `attachment; filename="sahara-1-Dunes.jpg"`, w.Header().Get("Content-Disposition"))
+
+	w = httptest.NewRecorder()
+	require.NoError(t, imageService.StreamAlbumZIP(w, httptest.NewRequest("GET", "/download", nil), album, "original", ZIPOptions{}))
+	zipReader, err := zip.NewReader(bytes.NewReader(w.Body.Bytes()), int64(w.Body.Len()))
+	require.NoError(t, err)
+	var names []string
+	for _, file := range zipReader.File {
+		names = append(names, file.Name)
+	}
+	assert.Equal(t, []string{"sahara-1-Dunes.jpg", "sahara-2.jpg"}, names)
+
+	// Templates naming photos alike get numbered names in ZIPs
+	album.DownloadFilename = "{album}"
+	w = httptest.NewRecorder()
+	require.NoError(t, imageService.StreamAlbumZIP(w, httptest.NewRequest("GET", "/download", nil), album, "original", ZIPOptions{}))
+	zipReader, err = zip.NewReader(bytes.NewReader(w.Body.Bytes()), int64(w.Body.Len()))
+	require.NoError(t, err)
+	require.Len(t, zipReader.File, 2)
+	assert.Equal(t, "sahara.jpg", zipReader.File[0].Name)
+	assert.Equal(t, "sahara-2.jpg", zipReader.File[1].Name)
+}
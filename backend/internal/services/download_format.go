@@ -0,0 +1,138 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/davidbyttow/govips/v2/vips"
+	"github.com/njoubert/nielsshootsfilm/backend/internal/models"
+)
+
+// ErrUnsupportedDownloadFormat is returned when an original download asks for a format it
+// cannot be converted to.
+var ErrUnsupportedDownloadFormat = errors.New("unsupported download format")
+
+// ErrUndecodableOriginal is returned when an original cannot be decoded for conversion.
+var ErrUndecodableOriginal = errors.New("original cannot be decoded")
+
+// convertedJPEGQuality is the JPEG quality originals are converted at, high enough that
+// converting a lossless original loses little.
+const convertedJPEGQuality = 95
+
+// downloadFormats maps the formats originals can be downloaded in to their image type,
+// content type, and file extension.
+var downloadFormats = map[string]struct {
+	imageType   vips.ImageType
+	contentType string
+	extension   string
+}{
+	"jpeg": {vips.ImageTypeJPEG, "image/jpeg", ".jpg"},
+	"png":  {vips.ImageTypePNG, "image/png", ".png"},
+	"tiff": {vips.ImageTypeTIFF, "image/tiff", ".tiff"},
+}
+
+// lossyFormats are the original formats whose pixels are already compressed lossily.
+var lossyFormats = map[vips.ImageType]bool{
+	vips.ImageTypeJPEG: true,
+	vips.ImageTypeWEBP: true,
+	vips.ImageTypeHEIF: true,
+	vips.ImageTypeAVIF: true,
+}
+
+// IsDownloadFormat reports whether originals can be downloaded converted to format.
+func IsDownloadFormat(format string) bool {
+	_, ok := downloadFormats[format]
+	return ok
+}
+
+// ConvertedPhoto is an original converted to another format for download.
+type ConvertedPhoto struct {
+	Data        []byte
+	ContentType string
+	Filename    string
+	// Warning is set when the conversion loses quality: re-encoding a lossy original, such as
+	// a JPEG or WebP, in lossy JPEG compounds the compression artifacts
+	Warning string
+}
+
+// ConvertOriginal renders a photo's original in another format: jpeg, png, or tiff. PNG and
+// TIFF are lossless, so converting to them keeps every pixel at the cost of larger files;
+// converting to JPEG is lossy. An original already in the requested format is returned as
+// is. Metadata is kept, except GPS tags when the album scrubs them from downloads.
+func (s *ImageService) ConvertOriginal(album *models.Album, photo *models.Photo, format string) (*ConvertedPhoto, error) {
+	target, ok := downloadFormats[format]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s (supported: jpeg, png, tiff)", ErrUnsupportedDownloadFormat, format)
+	}
+
+	reader, _, err := s.openDownload(album, photo, "original")
+	if err != nil {
+		return nil, err
+	}
+	original, err := io.ReadAll(reader)
+	_ = reader.Close()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read original: %w", err)
+	}
+
+	converted := &ConvertedPhoto{
+		ContentType: target.contentType,
+		Filename:    downloadFilename(s.downloadFilenameTemplate(album), album, photo, target.extension),
+	}
+
+	// Acquire semaphore to limit concurrent VIPS operations
+	s.processSem <- struct{}{}
+	defer func() { <-s.processSem }()
+
+	img, err := vips.NewImageFromBuffer(original)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrUndecodableOriginal, err)
+	}
+	defer img.Close()
+
+	source := img.Format()
+	if source == target.imageType {
+		converted.Data = original
+		return converted, nil
+	}
+	if lossyFormats[source] && target.imageType == vips.ImageTypeJPEG {
+		converted.Warning = "Lossy original re-encoded as JPEG; some quality is lost"
+	}
+
+	switch target.imageType {
+	case vips.ImageTypePNG:
+		ep := vips.NewPngExportParams()
+		ep.StripMetadata = false
+		converted.Data, _, err = img.ExportPng(ep)
+	case vips.ImageTypeTIFF:
+		ep := vips.NewTiffExportParams()
+		ep.StripMetadata = false
+		converted.Data, _, err = img.ExportTiff(ep)
+	default:
+		ep := vips.NewJpegExportParams()
+		ep.Quality = convertedJPEGQuality
+		ep.StripMetadata = false
+		converted.Data, _, err = img.ExportJpeg(ep)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to export %s: %w", format, err)
+	}
+	return converted, nil
+}
+
+// WriteConvertedPhoto writes an original converted by ConvertOriginal as an attachment,
+// with an X-Conversion-Warning header if the conversion loses quality. Converting first
+// lets callers report conversion errors before any of the response is written.
+func WriteConvertedPhoto(w http.ResponseWriter, converted *ConvertedPhoto) error {
+	w.Header().Set("Content-Type", converted.ContentType)
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", converted.Filename))
+	if converted.Warning != "" {
+		w.Header().Set("X-Conversion-Warning", converted.Warning)
+	}
+	if _, err := w.Write(converted.Data); err != nil {
+		return fmt.Errorf("failed to write photo: %w", err)
+	}
+	return nil
+}
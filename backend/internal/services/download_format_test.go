@@ -0,0 +1,88 @@
+package services
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/png"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/davidbyttow/govips/v2/vips"
+	"github.com/njoubert/nielsshootsfilm/backend/internal/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestImageService_ConvertOriginal(t *testing.T) {
+	imageService, err := NewImageService(t.TempDir(), nil, nil)
+	require.NoError(t, err)
+	imageService.SetStorage(NewMemoryStorage())
+
+	upload := createTestJPEG(t, 96, 64)
+	photo, err := imageService.ProcessBytes("frame-7.jpg", upload)
+	require.NoError(t, err)
+	album := &models.Album{Slug: "scans", AllowDownloads: true, Photos: []models.Photo{*photo}}
+
+	for _, tc := range []struct {
+		format      string
+		imageType   vips.ImageType
+		contentType string
+		filename    string
+	}{
+		{"png", vips.ImageTypePNG, "image/png", "frame-7.png"},
+		{"tiff", vips.ImageTypeTIFF, "image/tiff", "frame-7.tiff"},
+	} {
+		converted, err := imageService.ConvertOriginal(album, photo, tc.format)
+		require.NoError(t, err, tc.format)
+		assert.Equal(t, tc.contentType, converted.ContentType)
+		assert.Equal(t, tc.filename, converted.Filename)
+		assert.Empty(t, converted.Warning, "converting to a lossless format loses nothing")
+
+		img, err := vips.NewImageFromBuffer(converted.Data)
+		require.NoError(t, err, tc.format)
+		assert.Equal(t, tc.imageType, img.Format())
+		assert.Equal(t, 96, img.Width())
+		assert.Equal(t, 64, img.Height())
+	}
+
+	// An original already in the format is sent unchanged rather than re-encoded
+	converted, err := imageService.ConvertOriginal(album, photo, "jpeg")
+	require.NoError(t, err)
+	assert.Equal(t, upload, converted.Data)
+	assert.Equal(t, "frame-7.jpg", converted.Filename)
+
+	_, err = imageService.ConvertOriginal(album, photo, "bmp")
+	assert.ErrorIs(t, err, ErrUnsupportedDownloadFormat)
+}
+
+func TestWriteConvertedPhoto_LosslessToJPEG(t *testing.T) {
+	imageService, err := NewImageService(t.TempDir(), nil, nil)
+	require.NoError(t, err)
+	imageService.SetStorage(NewMemoryStorage())
+
+	src := image.NewRGBA(image.Rect(0, 0, 80, 60))
+	for y := range 60 {
+		for x := range 80 {
+			src.Set(x, y, color.RGBA{uint8(x * 3), uint8(y * 4), 120, 255})
+		}
+	}
+	var buf bytes.Buffer
+	require.NoError(t, png.Encode(&buf, src))
+	photo, err := imageService.ProcessBytes("scan.png", buf.Bytes())
+	require.NoError(t, err)
+	album := &models.Album{Slug: "scans", AllowDownloads: true, Photos: []models.Photo{*photo}}
+
+	converted, err := imageService.ConvertOriginal(album, photo, "jpeg")
+	require.NoError(t, err)
+	w := httptest.NewRecorder()
+	require.NoError(t, WriteConvertedPhoto(w, converted))
+	assert.Equal(t, "image/jpeg", w.Header().Get("Content-Type"))
+	assert.Equal(t, `attachment; filename="scan.jpg"`, w.Header().Get("Content-Disposition"))
+	assert.Empty(t, w.Header().Get("X-Conversion-Warning"))
+
+	img, err := vips.NewImageFromBuffer(w.Body.Bytes())
+	require.NoError(t, err)
+	assert.Equal(t, vips.ImageTypeJPEG, img.Format())
+	assert.Equal(t, 80, img.Width())
+}
@@ -0,0 +1,198 @@
+package services
+
+import (
+	"errors"
+	"log/slog"
+
+	"github.com/njoubert/nielsshootsfilm/backend/internal/models"
+)
+
+// EXIFBackfillJobType identifies EXIF reprocessing jobs.
+const EXIFBackfillJobType = "reprocess_exif"
+
+// EXIFBackfillService re-reads stored originals in the background and fills in EXIF data,
+// dimensions, and capture dates missing from existing photos. Derivatives are left alone.
+type EXIFBackfillService struct {
+	albumService *AlbumService
+	imageService *ImageService
+	jobService   *JobService
+	logger       *slog.Logger
+}
+
+// NewEXIFBackfillService creates a new EXIF backfill service.
+func NewEXIFBackfillService(albumService *AlbumService, imageService *ImageService, jobService *JobService, logger *slog.Logger) *EXIFBackfillService {
+	return &EXIFBackfillService{
+		albumService: albumService,
+		imageService: imageService,
+		jobService:   jobService,
+		logger:       logger,
+	}
+}
+
+// originalMetadata is what was read from one photo's original.
+type originalMetadata struct {
+	width, height int
+	exif          *models.EXIF
+}
+
+// Start begins reprocessing EXIF data for one album, or every album if albumID is empty,
+// and returns the job tracking its progress.
+func (s *EXIFBackfillService) Start(albumID string) (models.Job, error) {
+	var albums []models.Album
+	if albumID != "" {
+		album, err := s.albumService.GetByID(albumID)
+		if err != nil {
+			return models.Job{}, err
+		}
+		albums = []models.Album{*album}
+	} else {
+		all, err := s.albumService.GetAll()
+		if err != nil {
+			return models.Job{}, err
+		}
+		albums = all
+	}
+
+	total := 0
+	for _, album := range albums {
+		total += len(album.Photos)
+	}
+
+	job := s.jobService.Create(EXIFBackfillJobType, total)
+	go s.run(job.ID, albums)
+	return job, nil
+}
+
+// run reads each album's originals, then saves the album once with the backfilled fields.
+func (s *EXIFBackfillService) run(jobID string, albums []models.Album) {
+	for _, album := range albums {
+		read := make(map[string]originalMetadata, len(album.Photos))
+
+		for i := range album.Photos {
+			photo := &album.Photos[i]
+			width, height, exifData, err := s.imageService.ReadOriginalMetadata(photo)
+			if err == nil {
+				read[photo.ID] = originalMetadata{width: width, height: height, exif: exifData}
+			}
+
+			item := models.JobItem{AlbumID: album.ID, PhotoID: photo.ID, Filename: photo.FilenameOriginal}
+			s.jobService.Update(jobID, func(job *models.Job) {
+				job.Processed++
+				switch {
+				case errors.Is(err, ErrObjectNotFound):
+					item.Reason = "original not found"
+					job.Skipped = append(job.Skipped, item)
+				case err != nil:
+					item.Reason = err.Error()
+					job.Errors = append(job.Errors, item)
+				}
+			})
+
+			if err != nil && s.logger != nil {
+				s.logger.Warn("failed to read original metadata",
+					slog.String("job_id", jobID),
+					slog.String("album_id", album.ID),
+					slog.String("photo_id", photo.ID),
+					slog.String("error", err.Error()),
+				)
+			}
+		}
+
+		if err := s.saveAlbum(album.ID, read); err != nil && s.logger != nil {
+			s.logger.Error("failed to save backfilled metadata",
+				slog.String("job_id", jobID),
+				slog.String("album_id", album.ID),
+				slog.String("error", err.Error()),
+			)
+		}
+	}
+
+	s.jobService.Finish(jobID, nil)
+}
+
+// saveAlbum applies read metadata to the album as currently stored, so edits made
+// while the job ran are kept. The album is only written if something changed.
+func (s *EXIFBackfillService) saveAlbum(albumID string, read map[string]originalMetadata) error {
+	if len(read) == 0 {
+		return nil
+	}
+
+	_, err := s.albumService.Modify(albumID, func(album *models.Album) error {
+		changed := false
+		for i := range album.Photos {
+			if metadata, ok := read[album.Photos[i].ID]; ok {
+				// Positions the album would drop on save are not news
+				if !album.ShareLocations {
+					metadata.exif = metadata.exif.WithoutLocation()
+				}
+				if backfillPhotoMetadata(&album.Photos[i], metadata) {
+					changed = true
+				}
+			}
+		}
+		if !changed {
+			return errAlbumUnchanged
+		}
+		return nil
+	})
+	return err
+}
+
+// backfillPhotoMetadata fills fields the photo is missing from metadata read from its original.
+// Fields that are already set are never overwritten. Reports whether anything changed.
+func backfillPhotoMetadata(photo *models.Photo, metadata originalMetadata) bool {
+	changed := false
+
+	if photo.Width == 0 && photo.Height == 0 && metadata.width > 0 {
+		photo.Width = metadata.width
+		photo.Height = metadata.height
+		changed = true
+	}
+
+	if metadata.exif == nil {
+		return changed
+	}
+
+	if photo.EXIF == nil {
+		photo.EXIF = &models.EXIF{}
+	}
+	dst, src := photo.EXIF, metadata.exif
+	fillString := func(field *string, value string) {
+		if *field == "" && value != "" {
+			*field = value
+			changed = true
+		}
+	}
+	fillString(&dst.Camera, src.Camera)
+	fillString(&dst.Lens, src.Lens)
+	fillString(&dst.Aperture, src.Aperture)
+	fillString(&dst.ShutterSpeed, src.ShutterSpeed)
+	fillString(&dst.FocalLength, src.FocalLength)
+	fillString(&dst.Description, src.Description)
+	if dst.ISO == 0 && src.ISO != 0 {
+		dst.ISO = src.ISO
+		changed = true
+	}
+	if dst.DateTaken == nil && src.DateTaken != nil {
+		dst.DateTaken = src.DateTaken
+		changed = true
+	}
+	if dst.Latitude == nil && dst.Longitude == nil && src.Latitude != nil && src.Longitude != nil {
+		dst.Latitude, dst.Longitude = src.Latitude, src.Longitude
+		changed = true
+	}
+	if *dst == (models.EXIF{}) {
+		photo.EXIF = nil
+	}
+
+	// Film stocks recorded in EXIF text are picked up too, unless one was already set
+	if photo.FilmStock == "" {
+		if stock := DetectFilmStock(photo.EXIF); stock != "" {
+			photo.FilmStock = stock
+			photo.FilmStockSource = "exif"
+			changed = true
+		}
+	}
+
+	return changed
+}
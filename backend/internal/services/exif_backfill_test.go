@@ -0,0 +1,162 @@
+package services
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+	"log/slog"
+	"testing"
+
+	"github.com/njoubert/nielsshootsfilm/backend/internal/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// createTestJPEGWithEXIF returns a JPEG carrying an EXIF block with a camera make and model,
+// an ISO rating, and a capture date in "2006:01:02 15:04:05" form.
+func createTestJPEGWithEXIF(t *testing.T, width, height int, cameraMake, cameraModel, dateTaken string, iso uint16) []byte {
+	t.Helper()
+
+	// Little-endian TIFF: IFD0 (make, model, EXIF pointer) at 8, EXIF IFD (date, ISO) at 50, strings from 80
+	const ifd0Offset, exifIFDOffset, dataOffset = 8, 50, 80
+	le := binary.LittleEndian
+
+	var data bytes.Buffer
+	addString := func(s string) (offset, count uint32) {
+		offset = uint32(dataOffset + data.Len())
+		data.WriteString(s)
+		data.WriteByte(0)
+		return offset, uint32(len(s) + 1)
+	}
+	makeOffset, makeCount := addString(cameraMake)
+	modelOffset, modelCount := addString(cameraModel)
+	dateOffset, dateCount := addString(dateTaken)
+
+	tiff := make([]byte, dataOffset)
+	copy(tiff, "II")
+	le.PutUint16(tiff[2:], 42)
+	le.PutUint32(tiff[4:], ifd0Offset)
+
+	writeIFD := func(at int, entries [][4]uint32) {
+		le.PutUint16(tiff[at:], uint16(len(entries)))
+		for i, e := range entries {
+			entry := tiff[at+2+i*12:]
+			le.PutUint16(entry[0:], uint16(e[0]))
+			le.PutUint16(entry[2:], uint16(e[1]))
+			le.PutUint32(entry[4:], e[2])
+			le.PutUint32(entry[8:], e[3])
+		}
+	}
+	// Entries are {tag, type, count, value-or-offset}; type 2 is ASCII, 3 SHORT, 4 LONG
+	writeIFD(ifd0Offset, [][4]uint32{
+		{0x010F, 2, makeCount, makeOffset},
+		{0x0110, 2, modelCount, modelOffset},
+		{0x8769, 4, 1, exifIFDOffset},
+	})
+	writeIFD(exifIFDOffset, [][4]uint32{
+		{0x8827, 3, 1, uint32(iso)},
+		{0x9003, 2, dateCount, dateOffset},
+	})
+	tiff = append(tiff, data.Bytes()...)
+
+	payload := append([]byte("Exif\x00\x00"), tiff...)
+	segment := []byte{0xFF, 0xE1, 0, 0}
+	binary.BigEndian.PutUint16(segment[2:], uint16(len(payload)+2))
+	segment = append(segment, payload...)
+
+	// Insert the APP1 segment straight after the SOI marker
+	plain := createTestJPEG(t, width, height)
+	out := append([]byte{}, plain[:2]...)
+	out = append(out, segment...)
+	return append(out, plain[2:]...)
+}
+
+func TestEXIFBackfillService_Start(t *testing.T) {
+	albumService, _ := setupAlbumService(t)
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+
+	imageService, err := NewImageService(t.TempDir(), nil, logger)
+	require.NoError(t, err)
+	storage := NewMemoryStorage()
+	imageService.SetStorage(storage)
+
+	album := &models.Album{Title: "Back Catalogue", Visibility: "public"}
+	require.NoError(t, albumService.Create(album))
+
+	// Uploaded before EXIF extraction existed: no EXIF and no dimensions on record
+	bare, err := imageService.ProcessBytes("bare.jpg", createTestJPEGWithEXIF(t, 320, 200, "Nikon", "FM2 Kodak Portra 400", "2019:07:14 18:30:05", 400))
+	require.NoError(t, err)
+	require.NotNil(t, bare.EXIF, "fixture should carry EXIF")
+	bare.EXIF = nil
+	bare.Width, bare.Height = 0, 0
+	bare.FilmStock, bare.FilmStockSource = "", ""
+	require.NoError(t, albumService.AddPhoto(album.ID, bare))
+
+	// Partially described: the hand-entered camera is kept, the missing date is filled
+	edited, err := imageService.ProcessBytes("edited.jpg", createTestJPEGWithEXIF(t, 200, 320, "Canon", "AE-1 Program", "2020:01:02 03:04:05", 200))
+	require.NoError(t, err)
+	edited.EXIF = &models.EXIF{Camera: "Canon AE-1 (borrowed)"}
+	require.NoError(t, albumService.AddPhoto(album.ID, edited))
+
+	// Lost original
+	orphan, err := imageService.ProcessBytes("orphan.jpg", createTestJPEG(t, 100, 100))
+	require.NoError(t, err)
+	require.NoError(t, albumService.AddPhoto(album.ID, orphan))
+	require.NoError(t, storage.Delete(storageKeyFromURL(orphan.URLOriginal, "originals")))
+
+	displayBefore, err := storage.Get(storageKeyFromURL(bare.URLDisplay, "display"))
+	require.NoError(t, err)
+
+	jobService := NewJobService()
+	backfillService := NewEXIFBackfillService(albumService, imageService, jobService, logger)
+
+	job, err := backfillService.Start(album.ID)
+	require.NoError(t, err)
+	assert.Equal(t, EXIFBackfillJobType, job.Type)
+	assert.Equal(t, 3, job.Total)
+
+	finished := waitForJob(t, jobService, job.ID)
+	assert.Equal(t, models.JobStatusCompleted, finished.Status)
+	assert.Equal(t, 3, finished.Processed)
+	assert.Empty(t, finished.Errors)
+	require.Len(t, finished.Skipped, 1)
+	assert.Equal(t, orphan.ID, finished.Skipped[0].PhotoID)
+	assert.Equal(t, "original not found", finished.Skipped[0].Reason)
+
+	album, err = albumService.GetByID(album.ID)
+	require.NoError(t, err)
+	require.Len(t, album.Photos, 3)
+
+	got := album.Photos[0]
+	require.NotNil(t, got.EXIF)
+	assert.Equal(t, "Nikon FM2 Kodak Portra 400", got.EXIF.Camera)
+	assert.Equal(t, 400, got.EXIF.ISO)
+	require.NotNil(t, got.EXIF.DateTaken)
+	assert.Equal(t, "2019:07:14 18:30:05", got.EXIF.DateTaken.Format("2006:01:02 15:04:05"))
+	assert.Equal(t, 320, got.Width)
+	assert.Equal(t, 200, got.Height)
+	assert.Equal(t, "Kodak Portra 400", got.FilmStock)
+	assert.Equal(t, "exif", got.FilmStockSource)
+
+	got = album.Photos[1]
+	assert.Equal(t, "Canon AE-1 (borrowed)", got.EXIF.Camera)
+	assert.Equal(t, 200, got.EXIF.ISO)
+	require.NotNil(t, got.EXIF.DateTaken)
+	assert.Equal(t, 2020, got.EXIF.DateTaken.Year())
+
+	assert.Nil(t, album.Photos[2].EXIF)
+
+	// Derivatives are not regenerated
+	displayAfter, err := storage.Get(storageKeyFromURL(bare.URLDisplay, "display"))
+	require.NoError(t, err)
+	assert.Equal(t, displayBefore, displayAfter)
+}
+
+func TestEXIFBackfillService_Start_AlbumNotFound(t *testing.T) {
+	albumService, _ := setupAlbumService(t)
+	imageService, err := NewImageService(t.TempDir(), nil, nil)
+	require.NoError(t, err)
+
+	_, err = NewEXIFBackfillService(albumService, imageService, NewJobService(), nil).Start("missing")
+	assert.EqualError(t, err, "album not found")
+}
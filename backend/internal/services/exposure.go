@@ -0,0 +1,116 @@
+package services
+
+import (
+	"fmt"
+
+	"github.com/davidbyttow/govips/v2/vips"
+	"github.com/njoubert/nielsshootsfilm/backend/internal/models"
+)
+
+// Exposure flags.
+const (
+	ExposureUnder = "underexposed"
+	ExposureOver  = "overexposed"
+)
+
+// exposureSampleEdge is the longest edge photos are shrunk to before measuring exposure.
+const exposureSampleEdge = 256
+
+// Luminance levels, out of 255, at or beyond which a pixel counts as clipped.
+const (
+	shadowClipLevel    = 4
+	highlightClipLevel = 251
+)
+
+// ExposureThresholds decide which photos are flagged as badly exposed.
+type ExposureThresholds struct {
+	Dark    float64 `json:"dark"`    // Underexposed below this mean luminance (0-1)
+	Bright  float64 `json:"bright"`  // Overexposed above this mean luminance (0-1)
+	Clipped float64 `json:"clipped"` // Also flagged when more than this fraction of pixels is clipped
+}
+
+// DefaultExposureThresholds are used for anything the caller does not set.
+var DefaultExposureThresholds = ExposureThresholds{Dark: 0.2, Bright: 0.8, Clipped: 0.1}
+
+// Validate checks that the thresholds are fractions and the dark one is below the bright one.
+func (t ExposureThresholds) Validate() error {
+	for _, v := range []float64{t.Dark, t.Bright, t.Clipped} {
+		if v < 0 || v > 1 {
+			return fmt.Errorf("exposure thresholds must be between 0 and 1")
+		}
+	}
+	if t.Dark >= t.Bright {
+		return fmt.Errorf("dark threshold must be below bright threshold")
+	}
+	return nil
+}
+
+// Flag returns ExposureUnder or ExposureOver for a badly exposed photo, or "" if it is fine
+// or was never measured. A photo is judged by its mean luminance first, then by whichever
+// end of the histogram clips more.
+func (t ExposureThresholds) Flag(exposure *models.Exposure) string {
+	switch {
+	case exposure == nil:
+		return ""
+	case exposure.MeanLuminance < t.Dark:
+		return ExposureUnder
+	case exposure.MeanLuminance > t.Bright:
+		return ExposureOver
+	case exposure.ShadowsClipped > t.Clipped && exposure.ShadowsClipped >= exposure.HighlightsClipped:
+		return ExposureUnder
+	case exposure.HighlightsClipped > t.Clipped:
+		return ExposureOver
+	}
+	return ""
+}
+
+// measureExposure computes the mean luminance of an image and the fractions of its pixels
+// clipped to black or white, from a copy shrunk to exposureSampleEdge.
+func measureExposure(imageBytes []byte) (*models.Exposure, error) {
+	img, err := vips.NewThumbnailWithSizeFromBuffer(imageBytes, exposureSampleEdge, exposureSampleEdge, vips.InterestingNone, vips.SizeDown)
+	if err != nil {
+		return nil, fmt.Errorf("failed to shrink image: %w", err)
+	}
+	defer img.Close()
+
+	if err := img.ToColorSpace(vips.InterpretationSRGB); err != nil {
+		return nil, fmt.Errorf("failed to convert to sRGB: %w", err)
+	}
+	if img.BandFormat() != vips.BandFormatUchar {
+		if err := img.Cast(vips.BandFormatUchar); err != nil {
+			return nil, fmt.Errorf("failed to convert to 8 bits: %w", err)
+		}
+	}
+
+	pixels, err := img.ToBytes()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read pixels: %w", err)
+	}
+	bands := img.Bands()
+	count := img.Width() * img.Height()
+	if count == 0 || len(pixels) < count*bands {
+		return nil, fmt.Errorf("unexpected pixel data size %d for %dx%d image", len(pixels), img.Width(), img.Height())
+	}
+
+	var sum float64
+	var shadows, highlights int
+	for i := range count {
+		pixel := pixels[i*bands : (i+1)*bands]
+		luminance := float64(pixel[0])
+		if bands >= 3 {
+			luminance = 0.299*float64(pixel[0]) + 0.587*float64(pixel[1]) + 0.114*float64(pixel[2])
+		}
+		sum += luminance
+		if luminance <= shadowClipLevel {
+			shadows++
+		} else if luminance >= highlightClipLevel {
+			highlights++
+		}
+	}
+
+	return &models.Exposure{
+		MeanLuminance:     sum / float64(count) / 255,
+		ShadowsClipped:    float64(shadows) / float64(count),
+		HighlightsClipped: float64(highlights) / float64(count),
+	}, nil
+}
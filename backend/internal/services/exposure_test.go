@@ -0,0 +1,82 @@
+package services
+
+import (
+	"image"
+	"image/color"
+	"image/draw"
+	"testing"
+
+	"github.com/njoubert/nielsshootsfilm/backend/internal/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestProcessBytes_MeasuresExposure(t *testing.T) {
+	imageService, err := NewImageService(t.TempDir(), nil, nil)
+	require.NoError(t, err)
+	imageService.SetStorage(NewMemoryStorage())
+
+	measure := func(filename string, brighten int) *models.Exposure {
+		photo, err := imageService.ProcessBytes(filename, encodeJPEG(t, createSceneImage(160, 120, brighten, false), 90))
+		require.NoError(t, err)
+		require.NotNil(t, photo.Exposure)
+		return photo.Exposure
+	}
+
+	// Pushed far enough down, most of the scene is crushed to black
+	dark := measure("dark.jpg", -200)
+	assert.Less(t, dark.MeanLuminance, 0.1)
+	assert.Greater(t, dark.ShadowsClipped, 0.5)
+	assert.Zero(t, dark.HighlightsClipped)
+	assert.Equal(t, ExposureUnder, DefaultExposureThresholds.Flag(dark))
+
+	// Pushed up, it is nearly white; the halved blue channel keeps it just short of clipping
+	bright := measure("bright.jpg", 200)
+	assert.Greater(t, bright.MeanLuminance, 0.85)
+	assert.Zero(t, bright.ShadowsClipped)
+	assert.Equal(t, ExposureOver, DefaultExposureThresholds.Flag(bright))
+
+	balanced := measure("balanced.jpg", 0)
+	assert.InDelta(t, 0.45, balanced.MeanLuminance, 0.1)
+	assert.Less(t, balanced.ShadowsClipped, 0.05)
+	assert.Zero(t, balanced.HighlightsClipped)
+	assert.Empty(t, DefaultExposureThresholds.Flag(balanced))
+
+	// A blown-out frame clips its highlights
+	white := image.NewRGBA(image.Rect(0, 0, 160, 120))
+	draw.Draw(white, white.Bounds(), image.NewUniform(color.White), image.Point{}, draw.Src)
+	photo, err := imageService.ProcessBytes("white.jpg", encodeJPEG(t, white, 90))
+	require.NoError(t, err)
+	require.NotNil(t, photo.Exposure)
+	assert.InDelta(t, 1.0, photo.Exposure.MeanLuminance, 0.01)
+	assert.InDelta(t, 1.0, photo.Exposure.HighlightsClipped, 0.01)
+}
+
+func TestExposureThresholds_Flag(t *testing.T) {
+	thresholds := ExposureThresholds{Dark: 0.25, Bright: 0.75, Clipped: 0.05}
+
+	tests := []struct {
+		name     string
+		exposure *models.Exposure
+		want     string
+	}{
+		{"unmeasured", nil, ""},
+		{"balanced", &models.Exposure{MeanLuminance: 0.5}, ""},
+		{"dark", &models.Exposure{MeanLuminance: 0.2}, ExposureUnder},
+		{"bright", &models.Exposure{MeanLuminance: 0.8}, ExposureOver},
+		{"crushed shadows", &models.Exposure{MeanLuminance: 0.4, ShadowsClipped: 0.1}, ExposureUnder},
+		{"blown highlights", &models.Exposure{MeanLuminance: 0.6, HighlightsClipped: 0.1}, ExposureOver},
+		{"clipped at both ends", &models.Exposure{MeanLuminance: 0.5, ShadowsClipped: 0.08, HighlightsClipped: 0.2}, ExposureOver},
+		{"slight clipping", &models.Exposure{MeanLuminance: 0.5, ShadowsClipped: 0.04, HighlightsClipped: 0.04}, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, thresholds.Flag(tt.exposure))
+		})
+	}
+
+	require.NoError(t, DefaultExposureThresholds.Validate())
+	assert.Error(t, ExposureThresholds{Dark: 0.8, Bright: 0.2, Clipped: 0.1}.Validate())
+	assert.Error(t, ExposureThresholds{Dark: 0.2, Bright: 1.5, Clipped: 0.1}.Validate())
+	assert.Error(t, ExposureThresholds{Dark: 0.2, Bright: 0.8, Clipped: -0.1}.Validate())
+}
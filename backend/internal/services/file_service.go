@@ -16,6 +16,7 @@ type FileService struct {
 	backupDir  string
 	fileLocks  map[string]*sync.RWMutex
 	locksGuard sync.Mutex
+	onWrite    func(filename string)
 }
 
 // NewFileService creates a new file service.
@@ -55,6 +56,26 @@ func (fs *FileService) getFileLock(filename string) *sync.RWMutex {
 	return lock
 }
 
+// SetWriteHook registers a function called with a file's name after each successful
+// WriteJSON, while the file is still locked, so it must not block. Set it before use.
+func (fs *FileService) SetWriteHook(hook func(filename string)) {
+	fs.onWrite = hook
+}
+
+// ReadFile reads a file's raw contents.
+func (fs *FileService) ReadFile(filename string) ([]byte, error) {
+	lock := fs.getFileLock(filename)
+	lock.RLock()
+	defer lock.RUnlock()
+
+	// #nosec G304 - File path is from controlled data directory
+	data, err := os.ReadFile(filepath.Join(fs.dataDir, filename))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read file %s: %w", filename, err)
+	}
+	return data, nil
+}
+
 // ReadJSON reads and unmarshals JSON from a file.
 func (fs *FileService) ReadJSON(filename string, v any) error {
 	lock := fs.getFileLock(filename)
@@ -78,6 +99,17 @@ func (fs *FileService) ReadJSON(filename string, v any) error {
 
 // WriteJSON marshals and writes JSON to a file atomically with backup.
 func (fs *FileService) WriteJSON(filename string, v any) error {
+	return fs.writeJSON(filename, v, true)
+}
+
+// WriteJSONWithoutBackup marshals and writes JSON to a file atomically, without keeping a
+// backup of the previous version. It suits files rewritten often whose history is not worth
+// keeping, such as counters.
+func (fs *FileService) WriteJSONWithoutBackup(filename string, v any) error {
+	return fs.writeJSON(filename, v, false)
+}
+
+func (fs *FileService) writeJSON(filename string, v any, backup bool) error {
 	lock := fs.getFileLock(filename)
 	lock.Lock()
 	defer lock.Unlock()
@@ -91,7 +123,7 @@ func (fs *FileService) WriteJSON(filename string, v any) error {
 	}
 
 	// Create backup if file exists
-	if _, err := os.Stat(filePath); err == nil {
+	if _, err := os.Stat(filePath); err == nil && backup {
 		if err := fs.createBackup(filename); err != nil {
 			return fmt.Errorf("failed to create backup: %w", err)
 		}
@@ -111,6 +143,9 @@ func (fs *FileService) WriteJSON(filename string, v any) error {
 		return fmt.Errorf("failed to rename temporary file: %w", err)
 	}
 
+	if fs.onWrite != nil {
+		fs.onWrite(filename)
+	}
 	return nil
 }
 
@@ -124,7 +159,8 @@ func (fs *FileService) createBackup(filename string) error {
 		return err
 	}
 
-	timestamp := time.Now().Format("20060102-150405")
+	// Nanoseconds keep every write's backup, even several in one second, and still sort by name
+	timestamp := time.Now().Format("20060102-150405.000000000")
 	backupName := fmt.Sprintf("%s.%s.bak", filename, timestamp)
 	backupPath := filepath.Join(fs.backupDir, backupName)
 
@@ -0,0 +1,134 @@
+package services
+
+import (
+	"strings"
+
+	"github.com/njoubert/nielsshootsfilm/backend/internal/models"
+)
+
+// knownFilmStocks lists recognised film stocks by canonical display name.
+// Entries are matched in order, so more specific names must come before shorter ones.
+var knownFilmStocks = []string{
+	"Kodak Portra 160",
+	"Kodak Portra 400",
+	"Kodak Portra 800",
+	"Kodak Ektar 100",
+	"Kodak Gold 200",
+	"Kodak Ultramax 400",
+	"Kodak ColorPlus 200",
+	"Kodak Ektachrome E100",
+	"Kodak Tri-X 400",
+	"Kodak T-Max 100",
+	"Kodak T-Max 400",
+	"Kodak T-Max P3200",
+	"Fujifilm Pro 400H",
+	"Fujifilm Superia X-TRA 400",
+	"Fujifilm Superia 400",
+	"Fujifilm C200",
+	"Fujifilm Velvia 50",
+	"Fujifilm Velvia 100",
+	"Fujifilm Provia 100F",
+	"Fujifilm Acros 100",
+	"Ilford HP5 Plus",
+	"Ilford FP4 Plus",
+	"Ilford Delta 100",
+	"Ilford Delta 400",
+	"Ilford Delta 3200",
+	"Ilford XP2 Super",
+	"Ilford Pan F Plus",
+	"CineStill 800T",
+	"CineStill 400D",
+	"CineStill 50D",
+	"Lomography Color Negative 400",
+	"Lomography Color Negative 800",
+}
+
+// filmStockAliases maps common shorthand (already normalized) to canonical film stock names.
+// Entries are matched in order after the canonical names fail to match.
+var filmStockAliases = []struct {
+	alias string
+	stock string
+}{
+	{"tmax3200", "Kodak T-Max P3200"},
+	{"tmax400", "Kodak T-Max 400"},
+	{"tmax100", "Kodak T-Max 100"},
+	{"trix", "Kodak Tri-X 400"},
+	{"ultramax", "Kodak Ultramax 400"},
+	{"ektachrome", "Kodak Ektachrome E100"},
+	{"acros", "Fujifilm Acros 100"},
+	{"hp5", "Ilford HP5 Plus"},
+	{"fp4", "Ilford FP4 Plus"},
+	{"xp2", "Ilford XP2 Super"},
+}
+
+// normalizeFilmStockText lowercases text and strips everything except letters and digits,
+// so "Portra-400", "portra 400", and "PORTRA400" all compare equal.
+func normalizeFilmStockText(text string) string {
+	return strings.Join(filmStockWords(text), "")
+}
+
+// filmStockWords splits text into lowercase runs of letters and digits.
+func filmStockWords(text string) []string {
+	return strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return (r < 'a' || r > 'z') && (r < '0' || r > '9')
+	})
+}
+
+// containsFilmStockName reports whether consecutive words join up to exactly name, which must
+// be normalized. Names therefore only match whole words: "portra 400" and "portra400" match
+// "portra400", but "portra4000" and "eos 400d" do not match "portra400" and "cinestill400d".
+func containsFilmStockName(words []string, name string) bool {
+	for i := range words {
+		joined := ""
+		for j := i; j < len(words) && strings.HasPrefix(name, joined+words[j]); j++ {
+			joined += words[j]
+			if joined == name {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// DetectFilmStock guesses the film stock from EXIF text fields.
+// Scanners and lab software often record the stock in the description or user comment,
+// and some film cameras with data backs record it in the make/model. Returns "" when unknown.
+// Names match on word boundaries. Stocks are named without their manufacturer only in the
+// description, since camera models such as the Canon EOS 400D would otherwise read as film.
+func DetectFilmStock(exifData *models.EXIF) string {
+	if exifData == nil {
+		return ""
+	}
+
+	for _, field := range []struct {
+		text      string
+		shortName bool
+	}{
+		{exifData.Description, true},
+		{exifData.Camera, false},
+	} {
+		words := filmStockWords(field.text)
+		if len(words) == 0 {
+			continue
+		}
+
+		for _, stock := range knownFilmStocks {
+			// Match on the full name first, then without the manufacturer prefix
+			if containsFilmStockName(words, normalizeFilmStockText(stock)) {
+				return stock
+			}
+			_, name, _ := strings.Cut(stock, " ")
+			if field.shortName && containsFilmStockName(words, normalizeFilmStockText(name)) {
+				return stock
+			}
+		}
+
+		for _, a := range filmStockAliases {
+			if containsFilmStockName(words, a.alias) {
+				return a.stock
+			}
+		}
+	}
+
+	return ""
+}
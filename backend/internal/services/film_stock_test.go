@@ -0,0 +1,78 @@
+package services
+
+import (
+	"testing"
+
+	"github.com/njoubert/nielsshootsfilm/backend/internal/models"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDetectFilmStock(t *testing.T) {
+	tests := []struct {
+		name string
+		exif *models.EXIF
+		want string
+	}{
+		{
+			name: "nil EXIF",
+			exif: nil,
+			want: "",
+		},
+		{
+			name: "no film stock fields",
+			exif: &models.EXIF{Camera: "NORITSU KOKI EZ Controller"},
+			want: "",
+		},
+		{
+			name: "full name in description",
+			exif: &models.EXIF{Description: "Kodak Portra 400 @ box speed"},
+			want: "Kodak Portra 400",
+		},
+		{
+			name: "name without manufacturer and odd spacing",
+			exif: &models.EXIF{Description: "shot on portra-800, pushed one stop"},
+			want: "Kodak Portra 800",
+		},
+		{
+			name: "shorthand alias",
+			exif: &models.EXIF{Description: "Tri-X"},
+			want: "Kodak Tri-X 400",
+		},
+		{
+			name: "recorded in camera make/model",
+			exif: &models.EXIF{Camera: "Leica M6 HP5"},
+			want: "Ilford HP5 Plus",
+		},
+		{
+			name: "full name in camera make/model",
+			exif: &models.EXIF{Camera: "Nikon F100 Kodak Portra 160"},
+			want: "Kodak Portra 160",
+		},
+		{
+			name: "digital camera models are not film stocks",
+			exif: &models.EXIF{Camera: "Canon EOS 400D DIGITAL"},
+			want: "",
+		},
+		{
+			name: "digital camera model with a stock-like name",
+			exif: &models.EXIF{Camera: "Canon EOS 50D"},
+			want: "",
+		},
+		{
+			name: "names only match whole words",
+			exif: &models.EXIF{Description: "frame 4000D, portra4000"},
+			want: "",
+		},
+		{
+			name: "short name in description",
+			exif: &models.EXIF{Description: "roll of 400D under sodium lights"},
+			want: "CineStill 400D",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, DetectFilmStock(tt.exif))
+		})
+	}
+}
@@ -0,0 +1,114 @@
+package services
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/njoubert/nielsshootsfilm/backend/internal/models"
+)
+
+// GearCount is how many photos were taken with one camera, lens, or focal-length range.
+type GearCount struct {
+	Name  string `json:"name"`
+	Count int    `json:"count"`
+}
+
+// GearStats summarizes the EXIF gear data of publicly visible photos.
+type GearStats struct {
+	Photos       int         `json:"photos"` // Photos counted, whether or not they have EXIF data
+	Cameras      []GearCount `json:"cameras"`
+	Lenses       []GearCount `json:"lenses"`
+	FocalLengths []GearCount `json:"focal_lengths"` // In focal-length order, not by count
+}
+
+// focalLengthBuckets are the upper bounds (exclusive, in mm) and labels of the focal-length ranges.
+var focalLengthBuckets = []struct {
+	below int
+	label string
+}{
+	{24, "under 24mm"},
+	{35, "24-34mm"},
+	{50, "35-49mm"},
+	{85, "50-84mm"},
+	{135, "85-134mm"},
+	{300, "135-299mm"},
+	{0, "300mm+"},
+}
+
+// ComputeGearStats counts cameras, lenses, and focal-length ranges across the photos of public
+// albums. Unlisted, password-protected, and access-list albums are left out.
+func ComputeGearStats(albums []models.Album) GearStats {
+	cameras := map[string]int{}
+	lenses := map[string]int{}
+	focal := make([]int, len(focalLengthBuckets))
+	stats := GearStats{}
+
+	for i := range albums {
+		album := &albums[i]
+		if album.Visibility != "public" || album.RequiresAccessToken() {
+			continue
+		}
+
+		for j := range album.Photos {
+			stats.Photos++
+			exif := album.Photos[j].EXIF
+			if exif == nil {
+				continue
+			}
+			if camera := strings.TrimSpace(exif.Camera); camera != "" {
+				cameras[camera]++
+			}
+			if lens := strings.TrimSpace(exif.Lens); lens != "" {
+				lenses[lens]++
+			}
+			if mm, ok := parseFocalLength(exif.FocalLength); ok {
+				focal[focalLengthBucket(mm)]++
+			}
+		}
+	}
+
+	stats.Cameras = sortedGearCounts(cameras)
+	stats.Lenses = sortedGearCounts(lenses)
+	stats.FocalLengths = []GearCount{}
+	for i, count := range focal {
+		if count > 0 {
+			stats.FocalLengths = append(stats.FocalLengths, GearCount{Name: focalLengthBuckets[i].label, Count: count})
+		}
+	}
+	return stats
+}
+
+// parseFocalLength reads a stored focal length such as "50mm".
+func parseFocalLength(value string) (int, bool) {
+	mm, err := strconv.Atoi(strings.TrimSuffix(strings.TrimSpace(value), "mm"))
+	if err != nil || mm <= 0 {
+		return 0, false
+	}
+	return mm, true
+}
+
+// focalLengthBucket returns the index of the range containing mm.
+func focalLengthBucket(mm int) int {
+	for i, bucket := range focalLengthBuckets {
+		if bucket.below == 0 || mm < bucket.below {
+			return i
+		}
+	}
+	return len(focalLengthBuckets) - 1
+}
+
+// sortedGearCounts orders counts from most to least used, then by name.
+func sortedGearCounts(counts map[string]int) []GearCount {
+	sorted := make([]GearCount, 0, len(counts))
+	for name, count := range counts {
+		sorted = append(sorted, GearCount{Name: name, Count: count})
+	}
+	sort.Slice(sorted, func(i, j int) bool {
+		if sorted[i].Count != sorted[j].Count {
+			return sorted[i].Count > sorted[j].Count
+		}
+		return sorted[i].Name < sorted[j].Name
+	})
+	return sorted
+}
@@ -0,0 +1,72 @@
+package services
+
+import (
+	"testing"
+
+	"github.com/njoubert/nielsshootsfilm/backend/internal/models"
+	"github.com/stretchr/testify/assert"
+)
+
+func gearPhoto(camera, lens, focalLength string) models.Photo {
+	return models.Photo{EXIF: &models.EXIF{Camera: camera, Lens: lens, FocalLength: focalLength}}
+}
+
+func TestComputeGearStats(t *testing.T) {
+	albums := []models.Album{
+		{
+			Visibility: "public",
+			Photos: []models.Photo{
+				gearPhoto("Leica M6", "Summicron 35mm f/2", "35mm"),
+				gearPhoto("Leica M6", "Summicron 35mm f/2", "35mm"),
+				gearPhoto("Leica M6", "Summilux 50mm f/1.4", "50mm"),
+				gearPhoto("Pentax 67", "", "105mm"),
+				{}, // Scans without EXIF still count as photos
+			},
+		},
+		{
+			Visibility: "public",
+			Photos: []models.Photo{
+				gearPhoto("Pentax 67", "SMC 45mm", "45mm"),
+				gearPhoto("Nikon F3", "Nikkor 20mm", "20mm"),
+				gearPhoto("Nikon F3", "Nikkor 400mm", "400mm"),
+				gearPhoto("  ", "", "n/a"),
+			},
+		},
+		// Private albums are excluded
+		{Visibility: "unlisted", Photos: []models.Photo{gearPhoto("Hasselblad 500CM", "Planar 80mm", "80mm")}},
+		{Visibility: "password_protected", Photos: []models.Photo{gearPhoto("Hasselblad 500CM", "", "80mm")}},
+		{Visibility: "public", AllowedEmails: []string{"client@example.com"}, Photos: []models.Photo{gearPhoto("Hasselblad 500CM", "", "80mm")}},
+	}
+
+	stats := ComputeGearStats(albums)
+
+	assert.Equal(t, 9, stats.Photos)
+	assert.Equal(t, []GearCount{
+		{"Leica M6", 3},
+		{"Nikon F3", 2},
+		{"Pentax 67", 2},
+	}, stats.Cameras)
+	assert.Equal(t, []GearCount{
+		{"Summicron 35mm f/2", 2},
+		{"Nikkor 20mm", 1},
+		{"Nikkor 400mm", 1},
+		{"SMC 45mm", 1},
+		{"Summilux 50mm f/1.4", 1},
+	}, stats.Lenses)
+	assert.Equal(t, []GearCount{
+		{"under 24mm", 1},
+		{"35-49mm", 3},
+		{"50-84mm", 1},
+		{"85-134mm", 1},
+		{"300mm+", 1},
+	}, stats.FocalLengths)
+}
+
+func TestComputeGearStats_Empty(t *testing.T) {
+	stats := ComputeGearStats(nil)
+
+	assert.Zero(t, stats.Photos)
+	assert.NotNil(t, stats.Cameras)
+	assert.NotNil(t, stats.Lenses)
+	assert.NotNil(t, stats.FocalLengths)
+}
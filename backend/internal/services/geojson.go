@@ -0,0 +1,54 @@
+package services
+
+import "github.com/njoubert/nielsshootsfilm/backend/internal/models"
+
+// GeoJSONContentType is the media type of GeoJSON documents (RFC 7946).
+const GeoJSONContentType = "application/geo+json"
+
+// GeoJSONFeatureCollection is a GeoJSON FeatureCollection of photo positions.
+type GeoJSONFeatureCollection struct {
+	Type     string           `json:"type"` // Always "FeatureCollection"
+	Features []GeoJSONFeature `json:"features"`
+}
+
+// GeoJSONFeature is one geotagged photo as a GeoJSON Feature.
+type GeoJSONFeature struct {
+	Type       string                 `json:"type"` // Always "Feature"
+	Geometry   GeoJSONPoint           `json:"geometry"`
+	Properties GeoJSONPhotoProperties `json:"properties"`
+}
+
+// GeoJSONPoint is a GeoJSON Point geometry.
+type GeoJSONPoint struct {
+	Type        string     `json:"type"`        // Always "Point"
+	Coordinates [2]float64 `json:"coordinates"` // Longitude, then latitude, as GeoJSON orders them
+}
+
+// GeoJSONPhotoProperties identify the photo a feature marks.
+type GeoJSONPhotoProperties struct {
+	ID           string `json:"id"`
+	Title        string `json:"title"`
+	ThumbnailURL string `json:"thumbnail_url"`
+}
+
+// PhotosGeoJSON returns the geotagged photos as a FeatureCollection of points, in photo order.
+// Photos without a GPS position, or with one out of range, are left out.
+func PhotosGeoJSON(photos []models.Photo) GeoJSONFeatureCollection {
+	collection := GeoJSONFeatureCollection{Type: "FeatureCollection", Features: []GeoJSONFeature{}}
+	for _, photo := range photos {
+		latitude, longitude, ok := photo.EXIF.Location()
+		if !ok {
+			continue
+		}
+		collection.Features = append(collection.Features, GeoJSONFeature{
+			Type:     "Feature",
+			Geometry: GeoJSONPoint{Type: "Point", Coordinates: [2]float64{longitude, latitude}},
+			Properties: GeoJSONPhotoProperties{
+				ID:           photo.ID,
+				Title:        photo.Title,
+				ThumbnailURL: photo.URLThumbnail,
+			},
+		})
+	}
+	return collection
+}
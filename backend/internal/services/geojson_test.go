@@ -0,0 +1,101 @@
+package services
+
+import (
+	"encoding/json"
+	"math"
+	"testing"
+
+	"github.com/njoubert/nielsshootsfilm/backend/internal/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestImageService_ProcessBytes_GPSPosition(t *testing.T) {
+	imageService, err := NewImageService(t.TempDir(), nil, nil)
+	require.NoError(t, err)
+	imageService.SetStorage(NewMemoryStorage())
+
+	photo, err := imageService.ProcessBytes("leica.jpg", createGPSJPEG(t))
+	require.NoError(t, err)
+	latitude, longitude, ok := photo.EXIF.Location()
+	require.True(t, ok)
+	assert.InDelta(t, 37.775, latitude, 1e-9)
+	assert.InDelta(t, -122.42, longitude, 1e-9)
+
+	// Photos without GPS tags have no position
+	plain, err := imageService.ProcessBytes("plain.jpg", createTestJPEG(t, 64, 48))
+	require.NoError(t, err)
+	_, _, ok = plain.EXIF.Location()
+	assert.False(t, ok)
+}
+
+func TestPhotosGeoJSON(t *testing.T) {
+	position := func(latitude, longitude float64) *models.EXIF {
+		return &models.EXIF{Latitude: &latitude, Longitude: &longitude}
+	}
+	latitudeOnly := 12.5
+	photos := []models.Photo{
+		{ID: "sf", Title: "Golden Gate", URLThumbnail: "/uploads/thumbnails/sf_thumb.webp", EXIF: position(37.8199, -122.4783)},
+		{ID: "no-exif"},
+		{ID: "no-gps", EXIF: &models.EXIF{Camera: "Leica M6"}},
+		{ID: "half", EXIF: &models.EXIF{Latitude: &latitudeOnly}},
+		{ID: "out-of-range", EXIF: position(91, 10)},
+		{ID: "nan", EXIF: position(math.NaN(), 10)},
+		{ID: "sydney", URLThumbnail: "/uploads/thumbnails/sydney_thumb.webp", EXIF: position(-33.8568, 151.2153)},
+	}
+
+	encoded, err := json.Marshal(PhotosGeoJSON(photos))
+	require.NoError(t, err)
+
+	// Decode generically to check the document's shape rather than our own types
+	var document map[string]any
+	require.NoError(t, json.Unmarshal(encoded, &document))
+	assert.Equal(t, "FeatureCollection", document["type"])
+	features, ok := document["features"].([]any)
+	require.True(t, ok)
+	require.Len(t, features, 2, "only photos with a valid position are included")
+
+	first := features[0].(map[string]any)
+	assert.Equal(t, "Feature", first["type"])
+	assert.Equal(t, map[string]any{"type": "Point", "coordinates": []any{-122.4783, 37.8199}}, first["geometry"])
+	assert.Equal(t, map[string]any{"id": "sf", "title": "Golden Gate", "thumbnail_url": "/uploads/thumbnails/sf_thumb.webp"}, first["properties"])
+	assert.Equal(t, "sydney", features[1].(map[string]any)["properties"].(map[string]any)["id"])
+
+	// Albums without geotagged photos give an empty collection, not null
+	encoded, err = json.Marshal(PhotosGeoJSON(photos[1:3]))
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"type": "FeatureCollection", "features": []}`, string(encoded))
+}
+
+func TestAlbumValidation_GPSPosition(t *testing.T) {
+	service, _ := setupAlbumService(t)
+	album := &models.Album{Title: "Travels", Visibility: "public", ShareLocations: true}
+	require.NoError(t, service.Create(album))
+
+	latitude, longitude := 48.8584, 2.2945
+	require.NoError(t, service.AddPhoto(album.ID, &models.Photo{FilenameOriginal: "paris.jpg", EXIF: &models.EXIF{Latitude: &latitude, Longitude: &longitude}}))
+
+	outOfRange := 200.0
+	err := service.AddPhoto(album.ID, &models.Photo{FilenameOriginal: "nowhere.jpg", EXIF: &models.EXIF{Latitude: &latitude, Longitude: &outOfRange}})
+	assert.ErrorContains(t, err, "longitude between -180 and 180")
+
+	err = service.AddPhoto(album.ID, &models.Photo{FilenameOriginal: "half.jpg", EXIF: &models.EXIF{Latitude: &latitude}})
+	assert.Error(t, err)
+}
+
+func TestAlbumService_WithholdsLocations(t *testing.T) {
+	service, _ := setupAlbumService(t)
+	album := &models.Album{Title: "Home", Visibility: "public"}
+	require.NoError(t, service.Create(album))
+
+	// Albums that do not share locations never store positions
+	latitude, longitude := 48.8584, 2.2945
+	require.NoError(t, service.AddPhoto(album.ID, &models.Photo{FilenameOriginal: "paris.jpg", EXIF: &models.EXIF{Camera: "Leica M6", Latitude: &latitude, Longitude: &longitude}}))
+
+	stored, err := service.GetByID(album.ID)
+	require.NoError(t, err)
+	require.NotNil(t, stored.Photos[0].EXIF)
+	assert.Equal(t, "Leica M6", stored.Photos[0].EXIF.Camera)
+	assert.Nil(t, stored.Photos[0].EXIF.Latitude)
+	assert.Nil(t, stored.Photos[0].EXIF.Longitude)
+}
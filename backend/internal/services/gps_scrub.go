@@ -0,0 +1,274 @@
+package services
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"strings"
+)
+
+// gpsInfoTag is the IFD0 tag pointing to the GPS IFD.
+const gpsInfoTag = 0x8825
+
+// xmpGPSPrefix starts the name of every XMP GPS property, e.g. exif:GPSLatitude.
+var xmpGPSPrefix = []byte("exif:GPS")
+
+// exifHeader prefixes the TIFF structure holding EXIF data in JPEG APP1 segments and HEIF Exif items.
+var exifHeader = []byte("Exif\x00\x00")
+
+// tiffTypeSizes is the size in bytes of one value of each TIFF field type.
+var tiffTypeSizes = map[uint16]int{
+	1: 1, 2: 1, 3: 2, 4: 4, 5: 8, 6: 1, 7: 1, 8: 2, 9: 4, 10: 8, 11: 4, 12: 8,
+}
+
+// openScrubbedOriginal reads an original image file and returns it with the GPS data removed
+// from its EXIF. The file keeps its exact length, so recorded file sizes stay accurate.
+func openScrubbedOriginal(r io.Reader) (io.ReadCloser, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read original: %w", err)
+	}
+	scrubGPS(data)
+	return io.NopCloser(bytes.NewReader(data)), nil
+}
+
+// scrubGPS removes the GPS data from the EXIF and XMP metadata embedded in an image file, in
+// place, keeping every other tag. JPEG APP1 segments, PNG eXIf chunks, WebP EXIF chunks, and
+// TIFF files are handled by their container structure; in other files (e.g. HEIC)
+// Exif-prefixed TIFF blocks are found by scanning. XMP exif:GPS properties are blanked
+// wherever they appear. Returns whether any GPS data was removed.
+func scrubGPS(data []byte) bool {
+	scrubbed := false
+	switch {
+	case bytes.HasPrefix(data, []byte{0xFF, 0xD8}):
+		scrubbed = scrubJPEGGPS(data)
+	case bytes.HasPrefix(data, []byte("\x89PNG\r\n\x1a\n")):
+		// XMP in PNG sits in iTXt chunks, whose CRCs are updated along with the EXIF's
+		return scrubPNGGPS(data)
+	case len(data) >= 12 && string(data[0:4]) == "RIFF" && string(data[8:12]) == "WEBP":
+		scrubbed = scrubWebPGPS(data)
+	case bytes.HasPrefix(data, []byte("II*\x00")) || bytes.HasPrefix(data, []byte("MM\x00*")):
+		// A TIFF original is the EXIF structure itself
+		scrubbed = scrubTIFFGPS(data)
+	default:
+		for offset := 0; ; {
+			i := bytes.Index(data[offset:], exifHeader)
+			if i < 0 {
+				break
+			}
+			start := offset + i + len(exifHeader)
+			if scrubTIFFGPS(data[start:]) {
+				scrubbed = true
+			}
+			offset = start
+		}
+	}
+
+	if scrubXMPGPS(data) {
+		scrubbed = true
+	}
+	return scrubbed
+}
+
+// scrubJPEGGPS scrubs the Exif APP1 segments of a JPEG, stopping at the image data.
+func scrubJPEGGPS(data []byte) bool {
+	scrubbed := false
+	for offset := 2; offset+4 <= len(data) && data[offset] == 0xFF; {
+		marker := data[offset+1]
+		if marker == 0xDA || marker == 0xD9 { // Start of scan, end of image
+			break
+		}
+		length := int(binary.BigEndian.Uint16(data[offset+2:]))
+		end := offset + 2 + length
+		if length < 2 || end > len(data) {
+			break
+		}
+		segment := data[offset+4 : end]
+		if marker == 0xE1 && bytes.HasPrefix(segment, exifHeader) && scrubTIFFGPS(segment[len(exifHeader):]) {
+			scrubbed = true
+		}
+		offset = end
+	}
+	return scrubbed
+}
+
+// scrubPNGGPS scrubs a PNG's eXIf chunk and uncompressed XMP iTXt chunks, updating the
+// chunks' CRCs.
+func scrubPNGGPS(data []byte) bool {
+	scrubbed := false
+	for offset := 8; offset+12 <= len(data); {
+		length := int(binary.BigEndian.Uint32(data[offset:]))
+		end := offset + 12 + length
+		if end > len(data) {
+			break
+		}
+		chunkType, chunk := string(data[offset+4:offset+8]), data[offset+8:offset+8+length]
+		if (chunkType == "eXIf" && scrubTIFFGPS(chunk)) || (chunkType == "iTXt" && scrubXMPGPS(chunk)) {
+			binary.BigEndian.PutUint32(data[end-4:], crc32.ChecksumIEEE(data[offset+4:offset+8+length]))
+			scrubbed = true
+		}
+		offset = end
+	}
+	return scrubbed
+}
+
+// scrubWebPGPS scrubs a WebP's EXIF chunk, which may or may not carry the Exif prefix.
+func scrubWebPGPS(data []byte) bool {
+	scrubbed := false
+	for offset := 12; offset+8 <= len(data); {
+		length := int(binary.LittleEndian.Uint32(data[offset+4:]))
+		end := offset + 8 + length
+		if end > len(data) {
+			break
+		}
+		if string(data[offset:offset+4]) == "EXIF" {
+			chunk := bytes.TrimPrefix(data[offset+8:end], exifHeader)
+			if scrubTIFFGPS(chunk) {
+				scrubbed = true
+			}
+		}
+		offset = end + length%2 // Chunks are padded to an even length
+	}
+	return scrubbed
+}
+
+// scrubTIFFGPS removes the GPS IFD pointer from a TIFF structure's first IFD and zeroes the GPS
+// IFD and its values, in place. The structure keeps its length and every other offset, so the
+// remaining tags are untouched. Returns false if the structure has no GPS IFD or is malformed.
+func scrubTIFFGPS(tiff []byte) bool {
+	if len(tiff) < 8 {
+		return false
+	}
+	var order binary.ByteOrder
+	switch string(tiff[0:2]) {
+	case "II":
+		order = binary.LittleEndian
+	case "MM":
+		order = binary.BigEndian
+	default:
+		return false
+	}
+	if order.Uint16(tiff[2:]) != 42 {
+		return false
+	}
+
+	ifd0 := int(order.Uint32(tiff[4:]))
+	if ifd0 < 8 || ifd0+2 > len(tiff) {
+		return false
+	}
+	count := int(order.Uint16(tiff[ifd0:]))
+	entries := ifd0 + 2
+	if entries+12*count+4 > len(tiff) {
+		return false
+	}
+
+	gpsEntry := -1
+	for i := range count {
+		if order.Uint16(tiff[entries+12*i:]) == gpsInfoTag {
+			gpsEntry = i
+			break
+		}
+	}
+	if gpsEntry < 0 {
+		return false
+	}
+
+	// Zero the GPS IFD and the values it points to
+	gpsIFD := int(order.Uint32(tiff[entries+12*gpsEntry+8:]))
+	if gpsIFD >= 8 && gpsIFD+2 <= len(tiff) {
+		gpsCount := int(order.Uint16(tiff[gpsIFD:]))
+		gpsEnd := min(gpsIFD+2+12*gpsCount+4, len(tiff))
+		for i := range gpsCount {
+			entry := gpsIFD + 2 + 12*i
+			if entry+12 > len(tiff) {
+				break
+			}
+			size := tiffTypeSizes[order.Uint16(tiff[entry+2:])] * int(order.Uint32(tiff[entry+4:]))
+			if size > 4 {
+				valueOffset := int(order.Uint32(tiff[entry+8:]))
+				if valueOffset >= 8 && size <= len(tiff)-valueOffset {
+					clear(tiff[valueOffset : valueOffset+size])
+				}
+			}
+		}
+		clear(tiff[gpsIFD:gpsEnd])
+	}
+
+	// Drop the pointer: later entries and the next-IFD offset move up one slot
+	last := entries + 12*count
+	copy(tiff[entries+12*gpsEntry:], tiff[entries+12*(gpsEntry+1):last+4])
+	clear(tiff[last-8 : last+4])
+	order.PutUint16(tiff[ifd0:], uint16(count-1))
+	return true
+}
+
+// scrubXMPGPS blanks the exif:GPS properties of the XMP packets in data, in place: both
+// attributes (exif:GPSLatitude="...") and elements (<exif:GPSLatitude>...</exif:GPSLatitude>)
+// are overwritten with spaces, which keeps the packet well-formed and its length unchanged.
+// Returns whether any property was blanked.
+func scrubXMPGPS(data []byte) bool {
+	scrubbed := false
+	for offset := 0; ; {
+		i := bytes.Index(data[offset:], xmpGPSPrefix)
+		if i < 0 {
+			return scrubbed
+		}
+		start := offset + i
+		offset = start + len(xmpGPSPrefix)
+
+		begin, end := xmpPropertySpan(data, start)
+		if end < 0 {
+			continue
+		}
+		for j := begin; j < end; j++ {
+			data[j] = ' '
+		}
+		scrubbed = true
+		offset = end
+	}
+}
+
+// xmpPropertySpan returns the bounds of the XMP attribute or element whose name starts at
+// start, or an end of -1 if it is neither or is not terminated.
+func xmpPropertySpan(data []byte, start int) (begin, end int) {
+	nameEnd := start
+	for nameEnd < len(data) && strings.IndexByte(" \t\r\n=/>", data[nameEnd]) < 0 {
+		nameEnd++
+	}
+	if start == 0 || nameEnd == len(data) {
+		return 0, -1
+	}
+	name := data[start:nameEnd]
+
+	switch data[start-1] {
+	case '<':
+		// An element: self-closing, or running to its closing tag
+		tagEnd := bytes.IndexByte(data[nameEnd:], '>')
+		if tagEnd < 0 {
+			return 0, -1
+		}
+		tagEnd += nameEnd
+		if data[tagEnd-1] == '/' {
+			return start - 1, tagEnd + 1
+		}
+		closing := append(append([]byte("</"), name...), '>')
+		closeAt := bytes.Index(data[tagEnd:], closing)
+		if closeAt < 0 {
+			return 0, -1
+		}
+		return start - 1, tagEnd + closeAt + len(closing)
+	case ' ', '\t', '\r', '\n':
+		// An attribute: name="value" or name='value'
+		if nameEnd+2 > len(data) || data[nameEnd] != '=' || (data[nameEnd+1] != '"' && data[nameEnd+1] != '\'') {
+			return 0, -1
+		}
+		valueEnd := bytes.IndexByte(data[nameEnd+2:], data[nameEnd+1])
+		if valueEnd < 0 {
+			return 0, -1
+		}
+		return start, nameEnd + 2 + valueEnd + 1
+	}
+	return 0, -1
+}
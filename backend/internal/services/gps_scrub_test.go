@@ -0,0 +1,259 @@
+package services
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/binary"
+	"encoding/xml"
+	"hash/crc32"
+	"image"
+	"image/png"
+	"io"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/njoubert/nielsshootsfilm/backend/internal/models"
+	"github.com/rwcarlsen/goexif/exif"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// createGPSTIFF builds a little-endian EXIF TIFF structure with a camera make and model, an
+// ISO in the EXIF IFD, and a GPS IFD holding a position of 37°46'30" N, 122°25'12" W.
+func createGPSTIFF() []byte {
+	// IFD0 at 8, EXIF IFD at 62, GPS IFD at 80, values from 134
+	const ifd0Offset, exifIFDOffset, gpsIFDOffset, dataOffset = 8, 62, 80, 134
+	le := binary.LittleEndian
+
+	var data bytes.Buffer
+	addBytes := func(b []byte) uint32 {
+		offset := uint32(dataOffset + data.Len())
+		data.Write(b)
+		return offset
+	}
+	makeOffset := addBytes([]byte("Leica\x00"))
+	modelOffset := addBytes([]byte("M6 TTL\x00"))
+	latitude := make([]byte, 24)
+	for i, v := range []uint32{37, 1, 46, 1, 30, 1} {
+		le.PutUint32(latitude[4*i:], v)
+	}
+	latitudeOffset := addBytes(latitude)
+	longitude := make([]byte, 24)
+	for i, v := range []uint32{122, 1, 25, 1, 12, 1} {
+		le.PutUint32(longitude[4*i:], v)
+	}
+	longitudeOffset := addBytes(longitude)
+
+	tiff := make([]byte, dataOffset)
+	copy(tiff, "II")
+	le.PutUint16(tiff[2:], 42)
+	le.PutUint32(tiff[4:], ifd0Offset)
+	writeIFD := func(at int, entries [][4]uint32) {
+		le.PutUint16(tiff[at:], uint16(len(entries)))
+		for i, e := range entries {
+			entry := tiff[at+2+i*12:]
+			le.PutUint16(entry[0:], uint16(e[0]))
+			le.PutUint16(entry[2:], uint16(e[1]))
+			le.PutUint32(entry[4:], e[2])
+			le.PutUint32(entry[8:], e[3])
+		}
+	}
+	// Entries are {tag, type, count, value-or-offset}; type 2 is ASCII, 3 SHORT, 4 LONG, 5 RATIONAL
+	writeIFD(ifd0Offset, [][4]uint32{
+		{0x010F, 2, 6, makeOffset},
+		{0x0110, 2, 7, modelOffset},
+		{0x8769, 4, 1, exifIFDOffset},
+		{gpsInfoTag, 4, 1, gpsIFDOffset},
+	})
+	writeIFD(exifIFDOffset, [][4]uint32{
+		{0x8827, 3, 1, 400},
+	})
+	writeIFD(gpsIFDOffset, [][4]uint32{
+		{0x0001, 2, 2, uint32('N')},
+		{0x0002, 5, 3, latitudeOffset},
+		{0x0003, 2, 2, uint32('W')},
+		{0x0004, 5, 3, longitudeOffset},
+	})
+	return append(tiff, data.Bytes()...)
+}
+
+// createGPSJPEG returns a JPEG carrying the createGPSTIFF EXIF in an APP1 segment.
+func createGPSJPEG(t *testing.T) []byte {
+	t.Helper()
+
+	payload := append([]byte("Exif\x00\x00"), createGPSTIFF()...)
+	segment := []byte{0xFF, 0xE1, 0, 0}
+	binary.BigEndian.PutUint16(segment[2:], uint16(len(payload)+2))
+	segment = append(segment, payload...)
+
+	plain := createTestJPEG(t, 64, 48)
+	out := append([]byte{}, plain[:2]...)
+	out = append(out, segment...)
+	return append(out, plain[2:]...)
+}
+
+// assertGPSScrubbed checks that a JPEG's EXIF keeps the camera tags but has no GPS data.
+func assertGPSScrubbed(t *testing.T, data []byte) {
+	t.Helper()
+
+	x, err := exif.Decode(bytes.NewReader(data))
+	require.NoError(t, err)
+	cameraMake, err := x.Get(exif.Make)
+	require.NoError(t, err)
+	value, _ := cameraMake.StringVal()
+	assert.Equal(t, "Leica", value)
+	cameraModel, err := x.Get(exif.Model)
+	require.NoError(t, err)
+	value, _ = cameraModel.StringVal()
+	assert.Equal(t, "M6 TTL", value)
+	iso, err := x.Get(exif.ISOSpeedRatings)
+	require.NoError(t, err)
+	isoValue, _ := iso.Int(0)
+	assert.Equal(t, 400, isoValue)
+
+	_, err = x.Get(exif.GPSLatitude)
+	assert.Error(t, err, "GPS latitude should be gone")
+	_, _, err = x.LatLong()
+	assert.Error(t, err)
+
+	// The coordinates are not left behind as unreferenced bytes either
+	latitude := make([]byte, 24)
+	for i, v := range []uint32{37, 1, 46, 1, 30, 1} {
+		binary.LittleEndian.PutUint32(latitude[4*i:], v)
+	}
+	assert.False(t, bytes.Contains(data, latitude))
+}
+
+func TestScrubGPS_JPEG(t *testing.T) {
+	original := createGPSJPEG(t)
+	x, err := exif.Decode(bytes.NewReader(original))
+	require.NoError(t, err)
+	_, err = x.Get(exif.GPSLatitude)
+	require.NoError(t, err, "fixture should carry GPS")
+
+	data := bytes.Clone(original)
+	require.True(t, scrubGPS(data))
+	assert.Len(t, data, len(original))
+	assertGPSScrubbed(t, data)
+
+	// The image data is untouched
+	_, format, err := image.Decode(bytes.NewReader(data))
+	require.NoError(t, err)
+	assert.Equal(t, "jpeg", format)
+
+	// Scrubbing again, or scrubbing a file without GPS, changes nothing
+	again := bytes.Clone(data)
+	assert.False(t, scrubGPS(again))
+	assert.Equal(t, data, again)
+	plain := createTestJPEG(t, 64, 48)
+	assert.False(t, scrubGPS(bytes.Clone(plain)))
+}
+
+func TestScrubGPS_PNG(t *testing.T) {
+	var encoded bytes.Buffer
+	require.NoError(t, png.Encode(&encoded, image.NewGray(image.Rect(0, 0, 8, 8))))
+	plain := encoded.Bytes()
+
+	// Insert an eXIf chunk straight after IHDR (8-byte signature, 25-byte IHDR chunk)
+	tiff := createGPSTIFF()
+	chunk := binary.BigEndian.AppendUint32(nil, uint32(len(tiff)))
+	chunk = append(chunk, "eXIf"...)
+	chunk = append(chunk, tiff...)
+	chunk = binary.BigEndian.AppendUint32(chunk, crc32.ChecksumIEEE(chunk[4:]))
+	data := append(append(append([]byte{}, plain[:33]...), chunk...), plain[33:]...)
+
+	require.True(t, scrubGPS(data))
+	_, err := png.Decode(bytes.NewReader(data))
+	require.NoError(t, err, "chunk CRC must be updated")
+
+	scrubbed := data[33+8 : 33+8+len(tiff)]
+	assert.Equal(t, uint16(3), binary.LittleEndian.Uint16(scrubbed[8:]), "IFD0 loses the GPS pointer")
+	assert.False(t, scrubTIFFGPS(scrubbed))
+}
+
+func TestScrubGPS_TIFF(t *testing.T) {
+	// A TIFF original has no Exif header: the file is the TIFF structure
+	data := createGPSTIFF()
+	x, err := exif.Decode(bytes.NewReader(data))
+	require.NoError(t, err)
+	_, err = x.Get(exif.GPSLatitude)
+	require.NoError(t, err, "fixture should carry GPS")
+
+	length := len(data)
+	require.True(t, scrubGPS(data))
+	assert.Len(t, data, length)
+	assertGPSScrubbed(t, data)
+}
+
+func TestScrubGPS_XMP(t *testing.T) {
+	const xmp = `<x:xmpmeta xmlns:x="adobe:ns:meta/"><rdf:RDF xmlns:rdf="http://www.w3.org/1999/02/22-rdf-syntax-ns#">` +
+		`<rdf:Description xmlns:exif="http://ns.adobe.com/exif/1.0/" xmlns:tiff="http://ns.adobe.com/tiff/1.0/" ` +
+		`tiff:Make="Leica" exif:GPSLatitude="37,46.5N" exif:GPSLongitude='122,25.2W'>` +
+		`<exif:GPSAltitude>52/1</exif:GPSAltitude><exif:GPSVersionID/><tiff:Model>M6 TTL</tiff:Model>` +
+		`</rdf:Description></rdf:RDF></x:xmpmeta>`
+	payload := append([]byte("http://ns.adobe.com/xap/1.0/\x00"), xmp...)
+	segment := []byte{0xFF, 0xE1, 0, 0}
+	binary.BigEndian.PutUint16(segment[2:], uint16(len(payload)+2))
+	segment = append(segment, payload...)
+	plain := createTestJPEG(t, 64, 48)
+	data := append(append(append([]byte{}, plain[:2]...), segment...), plain[2:]...)
+
+	length := len(data)
+	require.True(t, scrubGPS(data))
+	assert.Len(t, data, length)
+	assert.NotContains(t, string(data), "exif:GPS")
+	assert.NotContains(t, string(data), "46.5N")
+	assert.Contains(t, string(data), `tiff:Make="Leica"`)
+	assert.Contains(t, string(data), `<tiff:Model>M6 TTL</tiff:Model>`)
+
+	// The packet is still well-formed XML
+	start := bytes.Index(data, []byte("<x:xmpmeta"))
+	end := bytes.Index(data, []byte("</x:xmpmeta>")) + len("</x:xmpmeta>")
+	require.NoError(t, xml.Unmarshal(data[start:end], new(struct{})))
+	_, _, err := image.Decode(bytes.NewReader(data))
+	require.NoError(t, err)
+
+	assert.False(t, scrubGPS(data))
+}
+
+func TestImageService_Downloads_ScrubGPSOnDownload(t *testing.T) {
+	imageService, err := NewImageService(t.TempDir(), nil, nil)
+	require.NoError(t, err)
+	storage := NewMemoryStorage()
+	imageService.SetStorage(storage)
+
+	upload := createGPSJPEG(t)
+	photo, err := imageService.ProcessBytes("leica.jpg", upload)
+	require.NoError(t, err)
+	photo.ID = "leica"
+	photo.Downloadable = true
+	album := &models.Album{Slug: "street", AllowDownloads: true, ScrubGPSOnDownload: true, Photos: []models.Photo{*photo}}
+
+	// Single download
+	w := httptest.NewRecorder()
+	require.NoError(t, imageService.StreamPhoto(w, album, photo, "original"))
+	assertGPSScrubbed(t, w.Body.Bytes())
+
+	// ZIP download
+	w = httptest.NewRecorder()
+	require.NoError(t, imageService.StreamAlbumZIP(w, httptest.NewRequest("GET", "/download", nil), album, "original", ZIPOptions{Chunked: true}))
+	archive, err := zip.NewReader(bytes.NewReader(w.Body.Bytes()), int64(w.Body.Len()))
+	require.NoError(t, err)
+	require.Len(t, archive.File, 1)
+	entry, err := archive.File[0].Open()
+	require.NoError(t, err)
+	zipped, err := io.ReadAll(entry)
+	require.NoError(t, err)
+	assertGPSScrubbed(t, zipped)
+
+	// The stored original keeps its GPS data
+	stored, err := storage.Get(storageKeyFromURL(photo.URLOriginal, "originals"))
+	require.NoError(t, err)
+	assert.Equal(t, upload, stored)
+
+	// Without the flag originals download as stored
+	album.ScrubGPSOnDownload = false
+	w = httptest.NewRecorder()
+	require.NoError(t, imageService.StreamPhoto(w, album, photo, "original"))
+	assert.Equal(t, upload, w.Body.Bytes())
+}
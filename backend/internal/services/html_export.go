@@ -0,0 +1,233 @@
+package services
+
+import (
+	"archive/zip"
+	"errors"
+	"fmt"
+	"html/template"
+	"log/slog"
+	"net/http"
+	"path"
+	"time"
+
+	"github.com/njoubert/nielsshootsfilm/backend/internal/models"
+)
+
+// Names of the files in a static HTML album export, besides the images.
+const (
+	HTMLExportIndex  = "index.html"
+	htmlExportStyle  = "style.css"
+	htmlExportScript = "gallery.js"
+	htmlExportImages = "images"
+)
+
+// htmlExportPhoto is a photo as listed in an exported gallery page.
+type htmlExportPhoto struct {
+	Src     string
+	Alt     string
+	Title   string
+	Caption string
+	Width   int
+	Height  int
+}
+
+// htmlExportPage is the data the exported gallery page is rendered from.
+type htmlExportPage struct {
+	Title       string
+	Subtitle    string
+	Description string
+	Dates       string
+	Photos      []htmlExportPhoto
+	Exported    string
+}
+
+var htmlExportTemplate = template.Must(template.New(HTMLExportIndex).Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<meta name="viewport" content="width=device-width, initial-scale=1">
+<title>{{.Title}}</title>
+<link rel="stylesheet" href="style.css">
+</head>
+<body>
+<header>
+<h1>{{.Title}}</h1>
+{{- if .Subtitle}}
+<p class="subtitle">{{.Subtitle}}</p>
+{{- end}}
+{{- if .Dates}}
+<p class="dates">{{.Dates}}</p>
+{{- end}}
+{{- if .Description}}
+<p class="description">{{.Description}}</p>
+{{- end}}
+</header>
+<main class="grid">
+{{- range .Photos}}
+<figure>
+<a href="{{.Src}}"><img src="{{.Src}}" alt="{{.Alt}}"{{if .Width}} width="{{.Width}}" height="{{.Height}}"{{end}} loading="lazy"></a>
+{{- if or .Title .Caption}}
+<figcaption>{{if .Title}}<strong>{{.Title}}</strong> {{end}}{{.Caption}}</figcaption>
+{{- end}}
+</figure>
+{{- end}}
+</main>
+<footer>Exported {{.Exported}}</footer>
+<script src="gallery.js"></script>
+</body>
+</html>
+`))
+
+const htmlExportCSS = `body{margin:0;font-family:system-ui,sans-serif;background:#111;color:#eee}
+header,footer{max-width:72rem;margin:0 auto;padding:1.5rem}
+h1{margin:0 0 .5rem;font-weight:400}
+.subtitle,.dates{margin:0 0 .5rem;color:#aaa}
+.description{white-space:pre-line}
+.grid{max-width:72rem;margin:0 auto;padding:0 1.5rem;display:grid;gap:1rem;grid-template-columns:repeat(auto-fill,minmax(16rem,1fr))}
+figure{margin:0}
+img{display:block;width:100%;height:auto}
+figcaption{padding:.5rem 0;font-size:.9rem;color:#ccc}
+footer{font-size:.8rem;color:#777}
+.lightbox{position:fixed;inset:0;display:flex;align-items:center;justify-content:center;background:rgba(0,0,0,.92);cursor:zoom-out}
+.lightbox img{max-width:95vw;max-height:95vh;width:auto}
+`
+
+const htmlExportJS = `(function () {
+  var links = Array.prototype.slice.call(document.querySelectorAll('.grid a'));
+  var box = null, current = 0;
+  function show(i) {
+    current = (i + links.length) % links.length;
+    if (!box) {
+      box = document.createElement('div');
+      box.className = 'lightbox';
+      box.appendChild(document.createElement('img'));
+      box.addEventListener('click', close);
+      document.body.appendChild(box);
+    }
+    var img = links[current].querySelector('img');
+    box.firstChild.src = links[current].getAttribute('href');
+    box.firstChild.alt = img.alt;
+  }
+  function close() {
+    if (box) { box.remove(); box = null; }
+  }
+  links.forEach(function (link, i) {
+    link.addEventListener('click', function (e) { e.preventDefault(); show(i); });
+  });
+  document.addEventListener('keydown', function (e) {
+    if (!box) return;
+    if (e.key === 'Escape') close();
+    if (e.key === 'ArrowRight') show(current + 1);
+    if (e.key === 'ArrowLeft') show(current - 1);
+  });
+})();
+`
+
+// StreamAlbumHTML streams a ZIP holding a self-contained static gallery of an album, to be
+// opened locally without the site: an index.html page with the album's details and its
+// downloadable photos at display quality under images/, plus the page's CSS and script.
+// Photos whose files cannot be opened are logged and left off the page. The ZIP is streamed
+// as it is built, with the page written last so it only lists the images that made it in.
+func (s *ImageService) StreamAlbumHTML(w http.ResponseWriter, album *models.Album) error {
+	w.Header().Set("Content-Type", "application/zip")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", album.Slug+"-gallery.zip"))
+
+	zipWriter := zip.NewWriter(w)
+	flusher, _ := w.(http.Flusher)
+	page := htmlExportPage{
+		Title:       album.Title,
+		Subtitle:    album.Subtitle,
+		Description: album.Description,
+		Dates:       albumDateRange(album),
+		Photos:      []htmlExportPhoto{},
+		Exported:    time.Now().UTC().Format("January 2, 2006"),
+	}
+
+	buf := make([]byte, s.zipBufferSize)
+	for _, photo := range album.Photos {
+		// Photos marked as not downloadable never leave the site
+		if !photo.Downloadable {
+			continue
+		}
+
+		sourceFile, key, err := s.openDownload(album, &photo, "display")
+		if err != nil {
+			if errors.Is(err, ErrObjectNotFound) {
+				s.logger.Warn("photo file not found, skipping",
+					slog.String("album", album.Slug),
+					slog.String("photo_id", photo.ID),
+					slog.String("key", key))
+			} else {
+				s.logger.Error("failed to open photo file",
+					slog.String("photo_id", photo.ID),
+					slog.String("key", key),
+					slog.String("error", err.Error()))
+			}
+			continue
+		}
+
+		src := path.Join(htmlExportImages, path.Base(key))
+		entry, err := zipWriter.CreateHeader(&zip.FileHeader{Name: src, Method: zip.Store})
+		if err != nil {
+			_ = sourceFile.Close()
+			return fmt.Errorf("failed to create ZIP entry for %s: %w", src, err)
+		}
+		err = copyToZIP(zipWriter, entry, sourceFile, buf, flusher)
+		_ = sourceFile.Close()
+		if err != nil {
+			return fmt.Errorf("failed to write photo to ZIP: %w", err)
+		}
+
+		alt := photo.AltText
+		if alt == "" {
+			alt = photo.Title
+		}
+		page.Photos = append(page.Photos, htmlExportPhoto{
+			Src:     src,
+			Alt:     alt,
+			Title:   photo.Title,
+			Caption: photo.Caption,
+			Width:   photo.Width,
+			Height:  photo.Height,
+		})
+	}
+
+	for _, asset := range []struct{ name, content string }{
+		{htmlExportStyle, htmlExportCSS},
+		{htmlExportScript, htmlExportJS},
+	} {
+		entry, err := zipWriter.Create(asset.name)
+		if err != nil {
+			return fmt.Errorf("failed to create ZIP entry for %s: %w", asset.name, err)
+		}
+		if _, err := entry.Write([]byte(asset.content)); err != nil {
+			return fmt.Errorf("failed to write %s to ZIP: %w", asset.name, err)
+		}
+	}
+
+	entry, err := zipWriter.Create(HTMLExportIndex)
+	if err != nil {
+		return fmt.Errorf("failed to create ZIP entry for %s: %w", HTMLExportIndex, err)
+	}
+	if err := htmlExportTemplate.Execute(entry, page); err != nil {
+		return fmt.Errorf("failed to render %s: %w", HTMLExportIndex, err)
+	}
+
+	if err := zipWriter.Close(); err != nil {
+		return fmt.Errorf("failed to finish ZIP: %w", err)
+	}
+	return nil
+}
+
+// albumDateRange formats an album's start and end dates for display, or returns "" if it has none.
+func albumDateRange(album *models.Album) string {
+	const layout = "January 2, 2006"
+	switch {
+	case album.AlbumStartDate == nil:
+		return ""
+	case album.AlbumEndDate == nil || album.AlbumEndDate.Equal(*album.AlbumStartDate):
+		return album.AlbumStartDate.Format(layout)
+	}
+	return album.AlbumStartDate.Format(layout) + " – " + album.AlbumEndDate.Format(layout)
+}
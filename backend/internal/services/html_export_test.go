@@ -0,0 +1,79 @@
+package services
+
+import (
+	"archive/zip"
+	"bytes"
+	"io"
+	"log/slog"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/njoubert/nielsshootsfilm/backend/internal/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestImageService_StreamAlbumHTML(t *testing.T) {
+	imageService, err := NewImageService(t.TempDir(), nil, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	require.NoError(t, err)
+	storage := NewMemoryStorage()
+	imageService.SetStorage(storage)
+
+	start := time.Date(2024, time.June, 1, 0, 0, 0, 0, time.UTC)
+	end := time.Date(2024, time.June, 3, 0, 0, 0, 0, time.UTC)
+	album := &models.Album{
+		Slug:           "coast",
+		Title:          "Coast <Road>",
+		Description:    "Three days north",
+		AlbumStartDate: &start,
+		AlbumEndDate:   &end,
+		Photos: []models.Photo{
+			{ID: "a", URLDisplay: "/uploads/display/a_display.webp", AltText: "Cliffs at dusk", Caption: "Big Sur", Downloadable: true},
+			{ID: "b", URLDisplay: "/uploads/display/b_display.webp", Title: "Pier", Downloadable: true},
+			{ID: "c", URLDisplay: "/uploads/display/c_display.webp", Downloadable: false},
+			{ID: "d", URLDisplay: "/uploads/display/d_display.webp", Downloadable: true}, // File is missing
+		},
+	}
+	for _, id := range []string{"a", "b", "c"} {
+		require.NoError(t, storage.Put("display/"+id+"_display.webp", strings.NewReader("image "+id), 0))
+	}
+
+	w := httptest.NewRecorder()
+	require.NoError(t, imageService.StreamAlbumHTML(w, album))
+	assert.Equal(t, "application/zip", w.Header().Get("Content-Type"))
+	assert.Contains(t, w.Header().Get("Content-Disposition"), `filename="coast-gallery.zip"`)
+
+	zipReader, err := zip.NewReader(bytes.NewReader(w.Body.Bytes()), int64(w.Body.Len()))
+	require.NoError(t, err)
+	files := map[string]string{}
+	for _, file := range zipReader.File {
+		reader, err := file.Open()
+		require.NoError(t, err)
+		content, err := io.ReadAll(reader)
+		require.NoError(t, err)
+		_ = reader.Close()
+		files[file.Name] = string(content)
+	}
+
+	// Downloadable photos with files are bundled; the rest are left out
+	assert.Equal(t, "image a", files["images/a_display.webp"])
+	assert.Equal(t, "image b", files["images/b_display.webp"])
+	assert.NotContains(t, files, "images/c_display.webp")
+	assert.NotContains(t, files, "images/d_display.webp")
+	assert.Contains(t, files, "style.css")
+	assert.Contains(t, files, "gallery.js")
+
+	// The page references every bundled image by relative path, with the album's details escaped
+	index := files[HTMLExportIndex]
+	assert.Contains(t, index, `<img src="images/a_display.webp" alt="Cliffs at dusk"`)
+	assert.Contains(t, index, `<img src="images/b_display.webp" alt="Pier"`)
+	assert.NotContains(t, index, "c_display.webp")
+	assert.NotContains(t, index, "d_display.webp")
+	assert.Contains(t, index, "<title>Coast &lt;Road&gt;</title>")
+	assert.Contains(t, index, "June 1, 2024 – June 3, 2024")
+	assert.Contains(t, index, "Big Sur")
+	assert.Contains(t, index, `href="style.css"`)
+	assert.Contains(t, index, `src="gallery.js"`)
+}
@@ -2,15 +2,21 @@ package services
 
 import (
 	"archive/zip"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
 	"errors"
 	"fmt"
 	"io"
 	"log/slog"
+	"mime"
 	"mime/multipart"
 	"net/http"
 	"os"
+	"path"
 	"path/filepath"
 	"runtime"
+	"strconv"
 	"strings"
 	"syscall"
 
@@ -19,6 +25,7 @@ import (
 	"github.com/njoubert/nielsshootsfilm/backend/internal"
 	"github.com/njoubert/nielsshootsfilm/backend/internal/models"
 	"github.com/rwcarlsen/goexif/exif"
+	"golang.org/x/sync/singleflight"
 )
 
 // Image processing constants.
@@ -29,8 +36,19 @@ const (
 	thumbnailQuality     = 80                // Quality for thumbnail (JPEG/WebP)
 	minFreeSpace         = 500 * 1024 * 1024 // Minimum 500 MB free space required
 	maxConcurrentVIPSOps = 4                 // Max concurrent VIPS operations (prevents CPU thrashing)
+	originalQuality      = 95                // Quality for re-encoded originals (JPEG/WebP/TIFF)
 )
 
+// DefaultZIPBufferSize is how much of each photo is read at a time while building a ZIP.
+const DefaultZIPBufferSize = 1 << 20
+
+// minZIPBufferSize is the smallest allowed ZIP copy buffer.
+const minZIPBufferSize = 4 << 10
+
+// MinOriginalEdgeLimit is the smallest allowed max_original_edge_px, so downscaled originals
+// still cover the display version.
+const MinOriginalEdgeLimit = displayMaxSize
+
 // VIPS configuration constants.
 const (
 	vipsMaxCacheMem   = 500 * 1024 * 1024 // 500 MB cache
@@ -38,22 +56,24 @@ const (
 	vipsMaxCacheSize  = 100               // Max 100 operations in cache
 )
 
-var allowedMimeTypes = map[string]bool{
-	"image/jpeg": true,
-	"image/png":  true,
-	"image/webp": true,
-	"image/gif":  true,
-	"image/tiff": true,
-	"image/heic": true,
-	"image/heif": true,
-}
-
 // ImageService handles image upload and processing.
 type ImageService struct {
-	uploadDir     string
-	configService *SiteConfigService
-	processSem    chan struct{} // Semaphore to limit concurrent VIPS operations
-	logger        *slog.Logger
+	uploadDir        string
+	storage          Storage
+	configService    *SiteConfigService
+	processSem       chan struct{} // Semaphore to limit concurrent VIPS operations
+	etags            contentETags
+	derivatives      singleflight.Group // Coalesces concurrent on-demand renderings of one derivative
+	zipBufferSize    int                // Bytes of a photo held in memory at once while building a ZIP
+	zipCacheDir      string             // Where built album ZIPs are kept; empty disables the cache
+	zipCacheSize     int64              // Bytes of ZIPs the cache keeps before evicting the least recently used; 0 is unlimited
+	zipBuilds        singleflight.Group
+	scanner          Scanner             // Checks uploads for malware before they are stored; nil skips scanning
+	subjectDetector  SubjectDetector     // Places square thumbnail crops; nil centre-crops
+	thumbnailPadding *ThumbnailPadding   // Also store thumbnails padded to one aspect ratio; nil skips them
+	minUploadSpace   int64               // Uploads are turned away below this much free storage space; 0 never
+	onEncode         func(dstKey string) // Called for each derivative encoded, so tests can count them
+	logger           *slog.Logger
 }
 
 // NewImageService creates a new image service.
@@ -103,14 +123,60 @@ func NewImageService(uploadDir string, configService *SiteConfigService, logger
 		}
 	}
 
+	storage, err := NewLocalStorage(uploadDir)
+	if err != nil {
+		return nil, err
+	}
+
 	return &ImageService{
-		uploadDir:     uploadDir,
-		configService: configService,
-		processSem:    make(chan struct{}, maxConcurrentVIPSOps), // Limit concurrent VIPS operations
-		logger:        logger,
+		uploadDir:      uploadDir,
+		storage:        storage,
+		configService:  configService,
+		processSem:     make(chan struct{}, maxConcurrentVIPSOps), // Limit concurrent VIPS operations
+		zipBufferSize:  DefaultZIPBufferSize,
+		zipCacheSize:   DefaultZIPCacheSize,
+		minUploadSpace: DefaultMinUploadSpace,
+		logger:         logger,
 	}, nil
 }
 
+// SetZIPBufferSize sets how many bytes of a photo are read at a time while building a ZIP,
+// which bounds the memory each download uses. Values below 4 KiB are treated as 4 KiB.
+func (s *ImageService) SetZIPBufferSize(size int) {
+	s.zipBufferSize = max(size, minZIPBufferSize)
+}
+
+// SetStorage replaces the default local-filesystem storage, e.g. with an S3-compatible backend.
+func (s *ImageService) SetStorage(storage Storage) {
+	s.storage = storage
+	s.etags.reset()
+}
+
+// SetScanner has every upload checked for malware before anything is stored. Files the
+// scanner flags, or cannot scan, are rejected. A nil scanner turns scanning off.
+func (s *ImageService) SetScanner(scanner Scanner) {
+	s.scanner = scanner
+}
+
+// SetSubjectDetector has square thumbnails cropped around the subject the detector finds
+// rather than the image's centre. Photos it finds no subject in are still centre-cropped.
+// A nil detector turns this off.
+func (s *ImageService) SetSubjectDetector(detector SubjectDetector) {
+	s.subjectDetector = detector
+}
+
+// Storage returns the backend photo files are stored in.
+func (s *ImageService) Storage() Storage {
+	return s.storage
+}
+
+// usesLocalDisk reports whether photo files are stored on the local upload directory,
+// in which case disk space limits apply.
+func (s *ImageService) usesLocalDisk() bool {
+	_, ok := s.storage.(*LocalStorage)
+	return ok
+}
+
 // checkDiskSpace checks if there is enough free disk space for an upload
 // It enforces both the configured max_disk_usage_percent and always reserves 5% of disk.
 func (s *ImageService) checkDiskSpace(estimatedSize int64) error {
@@ -171,67 +237,152 @@ func (s *ImageService) checkDiskSpace(estimatedSize int64) error {
 	return nil
 }
 
-// ProcessUpload processes an uploaded image file using libvips.
+// ProcessUpload processes an uploaded image file using libvips, with the site's upload settings.
 func (s *ImageService) ProcessUpload(fileHeader *multipart.FileHeader) (*models.Photo, error) {
+	return s.Uploads(nil).ProcessUpload(fileHeader)
+}
+
+// ProcessBytes processes an image that has already been read into memory, with the site's
+// upload settings.
+func (s *ImageService) ProcessBytes(filename string, fileBytes []byte) (*models.Photo, error) {
+	return s.Uploads(nil).ProcessBytes(filename, fileBytes)
+}
+
+// ProcessFile processes an image file read from the server's filesystem, with the site's
+// upload settings.
+func (s *ImageService) ProcessFile(path string) (*models.Photo, error) {
+	return s.Uploads(nil).ProcessFile(path)
+}
+
+// ProcessUpload processes an uploaded image file using libvips.
+func (u AlbumUploads) ProcessUpload(fileHeader *multipart.FileHeader) (*models.Photo, error) {
+	s := u.service
+
 	// Acquire semaphore to limit concurrent VIPS operations
 	s.processSem <- struct{}{}
 	defer func() { <-s.processSem }()
 
-	// Validate file size against configured max (default 50MB)
-	maxSizeMB := 50
-	if s.configService != nil {
-		config, err := s.configService.Get()
-		if err == nil && config.Storage.MaxImageSizeMB > 0 {
-			maxSizeMB = config.Storage.MaxImageSizeMB
-		}
+	// Reject oversize files before reading them
+	if err := s.validateUploadSize(fileHeader.Size); err != nil {
+		return nil, err
+	}
+
+	// Open uploaded file
+	file, err := fileHeader.Open()
+	if err != nil {
+		return nil, fmt.Errorf("failed to open uploaded file: %w", err)
+	}
+	defer func() { _ = file.Close() }()
+
+	// Read entire file into memory for vips processing
+	fileBytes, err := io.ReadAll(file)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read file: %w", err)
 	}
+
+	return u.process(fileHeader.Filename, fileBytes)
+}
+
+// ProcessBytes processes an image that has already been read into memory,
+// such as one fetched from object storage after a direct upload.
+func (u AlbumUploads) ProcessBytes(filename string, fileBytes []byte) (*models.Photo, error) {
+	s := u.service
+
+	// Acquire semaphore to limit concurrent VIPS operations
+	s.processSem <- struct{}{}
+	defer func() { <-s.processSem }()
+
+	if err := s.validateUploadSize(int64(len(fileBytes))); err != nil {
+		return nil, err
+	}
+
+	return u.process(filename, fileBytes)
+}
+
+// ProcessFile processes an image file read from the server's filesystem, such as during a folder import.
+func (u AlbumUploads) ProcessFile(path string) (*models.Photo, error) {
+	s := u.service
+
+	// Acquire semaphore to limit concurrent VIPS operations
+	s.processSem <- struct{}{}
+	defer func() { <-s.processSem }()
+
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to stat file: %w", err)
+	}
+	if err := s.validateUploadSize(info.Size()); err != nil {
+		return nil, err
+	}
+
+	// #nosec G304 -- callers restrict path to an allowed import root
+	fileBytes, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read file: %w", err)
+	}
+
+	return u.process(filepath.Base(path), fileBytes)
+}
+
+// validateUploadSize checks a file size against the configured and absolute upload limits.
+func (s *ImageService) validateUploadSize(size int64) error {
+	// Validate file size against configured max (default 50MB)
+	maxSizeMB := s.maxImageSizeMB()
 	maxSizeBytes := int64(maxSizeMB) * 1024 * 1024
-	if fileHeader.Size > maxSizeBytes {
-		return nil, fmt.Errorf("file size %s exceeds maximum allowed %s (%dMB)", formatBytes(fileHeader.Size), formatBytes(maxSizeBytes), maxSizeMB)
+	if size > maxSizeBytes {
+		return fmt.Errorf("file size %s exceeds maximum allowed %s (%dMB)", formatBytes(size), formatBytes(maxSizeBytes), maxSizeMB)
 	}
 
 	// Also check hard limit for safety
-	if fileHeader.Size > internal.MaxUploadFileSize {
-		return nil, fmt.Errorf("file size %s exceeds absolute maximum %s", formatBytes(fileHeader.Size), formatBytes(internal.MaxUploadFileSize))
+	if size > internal.MaxUploadFileSize {
+		return fmt.Errorf("file size %s exceeds absolute maximum %s", formatBytes(size), formatBytes(internal.MaxUploadFileSize))
 	}
 
+	return nil
+}
+
+// processImage validates, stores, and generates derivatives for an image held in memory.
+// Images whose longest edge is shorter than minEdge are rejected; 0 accepts any size.
+// Callers must hold the processing semaphore.
+func (s *ImageService) processImage(filename string, fileBytes []byte, minEdge int) (*models.Photo, error) {
 	// Check disk space before processing
-	if err := s.checkDiskSpace(fileHeader.Size); err != nil {
-		return nil, err
+	if s.usesLocalDisk() {
+		if err := s.checkDiskSpace(int64(len(fileBytes))); err != nil {
+			return nil, err
+		}
 	}
 
-	// Open uploaded file
-	file, err := fileHeader.Open()
-	if err != nil {
-		return nil, fmt.Errorf("failed to open uploaded file: %w", err)
+	if len(fileBytes) == 0 {
+		return nil, errors.New("failed to read file header: file is empty")
 	}
-	defer func() { _ = file.Close() }()
 
-	// Detect content type
-	buffer := make([]byte, 512)
-	if _, err := file.Read(buffer); err != nil {
-		return nil, fmt.Errorf("failed to read file header: %w", err)
+	// Reject malware before anything else looks at the file
+	if s.scanner != nil {
+		if err := s.scanner.Scan(bytes.NewReader(fileBytes)); err != nil {
+			if errors.Is(err, ErrInfected) {
+				if s.logger != nil {
+					s.logger.Warn("rejected infected upload", slog.String("filename", filename), slog.String("error", err.Error()))
+				}
+				return nil, err
+			}
+			return nil, fmt.Errorf("failed to scan file for malware: %w", err)
+		}
 	}
 
-	contentType := detectContentType(buffer, fileHeader.Filename)
-	if !allowedMimeTypes[contentType] {
+	// Detect content type
+	contentType := detectContentType(fileBytes[:min(len(fileBytes), 512)], filename)
+	if !s.allowsMimeType(contentType) {
 		return nil, fmt.Errorf("unsupported file type: %s", contentType)
 	}
 
-	// Reset file pointer
-	if _, err := file.Seek(0, 0); err != nil {
-		return nil, fmt.Errorf("failed to reset file pointer: %w", err)
+	// Fully decode the image so truncated or corrupt files are rejected before anything is stored
+	if err := verifyImageIntegrity(fileBytes); err != nil {
+		return nil, err
 	}
 
 	// Generate UUID for this photo
 	photoID := uuid.New().String()
 
-	// Read entire file into memory for vips processing
-	fileBytes, err := io.ReadAll(file)
-	if err != nil {
-		return nil, fmt.Errorf("failed to read file: %w", err)
-	}
-
 	// Load image with vips to get dimensions
 	img, err := vips.NewImageFromBuffer(fileBytes)
 	if err != nil {
@@ -241,6 +392,29 @@ func (s *ImageService) ProcessUpload(fileHeader *multipart.FileHeader) (*models.
 
 	width := img.Width()
 	height := img.Height()
+	if max(width, height) < minEdge {
+		return nil, fmt.Errorf("%w: %dx%d, but the longest edge must be at least %d pixels", ErrImageTooSmall, width, height, minEdge)
+	}
+
+	// CMYK originals are kept as uploaded; their derivatives are converted to sRGB
+	if img.Interpretation() == vips.InterpretationCMYK && s.logger != nil {
+		s.logger.Info("converting CMYK upload to sRGB derivatives", slog.String("filename", filename))
+	}
+
+	// Multi-frame GIFs keep their animation for display; only the thumbnail is a still
+	animated := contentType == "image/gif" && img.Pages() > 1
+
+	// Shrink oversize originals when a maximum edge is configured; GIFs are always kept as uploaded
+	originalBytes := fileBytes
+	downscaled := false
+	if maxEdge := s.maxOriginalEdge(); maxEdge > 0 && max(width, height) > maxEdge && contentType != "image/gif" {
+		originalBytes, err = downscaleOriginal(img, contentType, maxEdge)
+		if err != nil {
+			return nil, fmt.Errorf("failed to downscale original: %w", err)
+		}
+		width, height = img.Width(), img.Height()
+		downscaled = true
+	}
 
 	// Determine original format from content type
 	originalExt := ""
@@ -261,80 +435,414 @@ func (s *ImageService) ProcessUpload(fileHeader *multipart.FileHeader) (*models.
 
 	// Save original
 	originalFilename := photoID + originalExt
-	originalPath := filepath.Join(s.uploadDir, "originals", originalFilename)
+	originalKey := "originals/" + originalFilename
 
-	if err := os.WriteFile(originalPath, fileBytes, 0600); err != nil {
+	if err := s.putBytes(originalKey, originalBytes); err != nil {
 		return nil, fmt.Errorf("failed to save original: %w", err)
 	}
 
-	originalSize := int64(len(fileBytes))
+	originalSize := int64(len(originalBytes))
 
-	// Generate display version (WebP)
+	// Display version: WebP, or the animated GIF itself
 	displayFilename := photoID + "_display.webp"
-	displayPath := filepath.Join(s.uploadDir, "display", displayFilename)
-
-	displaySize, err := s.generateResizedVersion(fileBytes, displayPath, displayMaxSize, displayQuality)
-	if err != nil {
-		// Clean up original
-		_ = os.Remove(originalPath)
-		return nil, fmt.Errorf("failed to generate display version: %w", err)
+	if animated {
+		displayFilename = photoID + "_display.gif"
 	}
+	displayKey := "display/" + displayFilename
 
-	// Generate thumbnail (WebP)
+	// Thumbnail: WebP, first frame only for animated GIFs
 	thumbnailFilename := photoID + "_thumbnail.webp"
-	thumbnailPath := filepath.Join(s.uploadDir, "thumbnails", thumbnailFilename)
+	thumbnailKey := "thumbnails/" + thumbnailFilename
+
+	// In lazy mode the derivatives are rendered on first request instead; see GenerateDerivative
+	lazy := s.derivativeMode() == models.DerivativeModeLazy
+	var displaySize, thumbnailSize int64
+	var paddedURL string
+	if lazy && s.thumbnailPadding != nil {
+		paddedURL = "/uploads/" + paddedThumbnailKey(thumbnailKey)
+	}
+	if !lazy {
+		displaySize, err = s.generateDisplayVersion(originalBytes, displayKey, animated, false, s.DisplayMaxEdge(nil))
+		if err != nil {
+			// Clean up original
+			_ = s.deleteObject(originalKey)
+			return nil, fmt.Errorf("failed to generate display version: %w", err)
+		}
 
-	thumbnailSize, err := s.generateResizedVersion(fileBytes, thumbnailPath, thumbnailMaxSize, thumbnailQuality)
-	if err != nil {
-		// Clean up original and display
-		_ = os.Remove(originalPath)
-		_ = os.Remove(displayPath)
-		return nil, fmt.Errorf("failed to generate thumbnail: %w", err)
+		thumbnailSize, err = s.generateThumbnail(originalBytes, thumbnailKey, s.ThumbnailFit(nil))
+		if err != nil {
+			// Clean up original and display
+			_ = s.deleteObject(originalKey)
+			_ = s.deleteObject(displayKey)
+			return nil, fmt.Errorf("failed to generate thumbnail: %w", err)
+		}
+
+		paddedURL, err = s.renderPaddedThumbnail(originalBytes, thumbnailKey)
+		if err != nil {
+			_ = s.deleteObject(originalKey)
+			_ = s.deleteObject(displayKey)
+			_ = s.deleteObject(thumbnailKey)
+			return nil, err
+		}
 	}
 
-	// Extract EXIF data (using original file bytes)
+	// Extract EXIF data (using the uploaded bytes, which still carry it if the original was re-encoded)
 	exifData, err := s.extractEXIFFromBytes(fileBytes)
 	if err != nil {
 		// EXIF extraction is not critical, just log and continue
 		exifData = nil
 	}
 
+	// Hash the stored original for near-duplicate detection; like EXIF, this is not critical
+	phash, err := perceptualHash(originalBytes)
+	if err != nil {
+		phash = ""
+	}
+
+	// Encode a placeholder for galleries to show while thumbnails load, also not critical
+	placeholder, err := blurhash(originalBytes)
+	if err != nil {
+		placeholder = ""
+	}
+
+	// Measure brightness for exposure flags, also not critical
+	exposure, err := measureExposure(originalBytes)
+	if err != nil {
+		exposure = nil
+	}
+
+	// Take the photo's main colors, also not critical
+	palette, err := extractPalette(originalBytes)
+	if err != nil {
+		palette = nil
+	}
+
 	// Final disk space check after upload completes
 	totalSize := originalSize + displaySize + thumbnailSize
-	if err := s.checkDiskSpace(totalSize); err != nil {
-		// Clean up all files
-		_ = os.Remove(originalPath)
-		_ = os.Remove(displayPath)
-		_ = os.Remove(thumbnailPath)
-		return nil, fmt.Errorf("insufficient disk space after upload: %w", err)
+	if s.usesLocalDisk() {
+		if err := s.checkDiskSpace(totalSize); err != nil {
+			// Clean up all files
+			_ = s.deleteObject(originalKey)
+			_ = s.deleteObject(displayKey)
+			_ = s.deleteObject(thumbnailKey)
+			if paddedURL != "" {
+				_ = s.deleteObject(paddedThumbnailKey(thumbnailKey))
+			}
+			return nil, fmt.Errorf("insufficient disk space after upload: %w", err)
+		}
 	}
 
 	// Create photo object
 	photo := &models.Photo{
-		FilenameOriginal:  fileHeader.Filename,
-		URLOriginal:       "/uploads/originals/" + originalFilename,
-		URLDisplay:        "/uploads/display/" + displayFilename,
-		URLThumbnail:      "/uploads/thumbnails/" + thumbnailFilename,
-		Width:             width,
-		Height:            height,
-		FileSizeOriginal:  originalSize,
-		FileSizeDisplay:   displaySize,
-		FileSizeThumbnail: thumbnailSize,
-		EXIF:              exifData,
+		FilenameOriginal:   filename,
+		MediaType:          contentType,
+		IsAnimated:         animated,
+		URLOriginal:        "/uploads/originals/" + originalFilename,
+		URLDisplay:         "/uploads/display/" + displayFilename,
+		URLThumbnail:       "/uploads/thumbnails/" + thumbnailFilename,
+		URLThumbnailPadded: paddedURL,
+		Width:              width,
+		Height:             height,
+		FileSizeOriginal:   originalSize,
+		OriginalDownscaled: downscaled,
+		FileSizeDisplay:    displaySize,
+		FileSizeThumbnail:  thumbnailSize,
+		DerivativesPending: lazy,
+		EXIF:               exifData,
+		PerceptualHash:     phash,
+		Blurhash:           placeholder,
+		Palette:            palette,
+		ContentHash:        contentHash(fileBytes),
+		Exposure:           exposure,
+		Downloadable:       true,
+	}
+
+	if stock := DetectFilmStock(exifData); stock != "" {
+		photo.FilmStock = stock
+		photo.FilmStockSource = "exif"
+	}
+
+	if s.keywordTagging() {
+		photo.Tags = keywordTags(photo.Tags, embeddedKeywords(fileBytes))
 	}
 
 	return photo, nil
 }
 
-// generateResizedVersion generates a resized WebP version of an image using libvips.
-func (s *ImageService) generateResizedVersion(imageBytes []byte, dstPath string, maxSize int, quality int) (int64, error) {
-	// Load image with vips
+// maxOriginalEdge returns the configured maximum longest edge for stored originals, or 0 if unlimited.
+func (s *ImageService) maxOriginalEdge() int {
+	if s.configService == nil {
+		return 0
+	}
+	config, err := s.configService.Get()
+	if err != nil {
+		return 0
+	}
+	return config.Storage.MaxOriginalEdgePx
+}
+
+// derivativeMode returns the configured derivative mode, eager unless lazy is configured.
+func (s *ImageService) derivativeMode() string {
+	if s.configService == nil {
+		return models.DerivativeModeEager
+	}
+	config, err := s.configService.Get()
+	if err != nil || config.Storage.DerivativeMode != models.DerivativeModeLazy {
+		return models.DerivativeModeEager
+	}
+	return models.DerivativeModeLazy
+}
+
+// keywordTagging reports whether uploads are tagged with their embedded keywords, which is
+// only when turned on.
+func (s *ImageService) keywordTagging() bool {
+	if s.configService == nil {
+		return false
+	}
+	config, err := s.configService.Get()
+	return err == nil && config.Storage.KeywordTags == models.KeywordTagsOn
+}
+
+// GenerateDerivative makes sure a photo's display or thumbnail version exists, rendering it
+// from the stored original if it is missing, and returns its size. A thumbnail comes with
+// its padded version, if the photo has one. Concurrent calls for the same version share a
+// single rendering. Returns an error wrapping ErrObjectNotFound if the original is missing.
+func (s *ImageService) GenerateDerivative(album *models.Album, photo models.Photo, quality string) (int64, error) {
+	if quality != "display" && quality != "thumbnail" {
+		return 0, fmt.Errorf("invalid derivative quality: %s", quality)
+	}
+	key, _ := photoStorageKey(&photo, quality)
+
+	size, err, _ := s.derivatives.Do(key, func() (any, error) {
+		// A call that finished just before this one may already have rendered it
+		size, err := s.storage.Size(key)
+		rendered := err == nil
+		paddedKey := ""
+		if quality == "thumbnail" && s.thumbnailPadding != nil && photo.URLThumbnailPadded != "" {
+			key := storageKeyFromURL(photo.URLThumbnailPadded, "thumbnails")
+			if _, err := s.storage.Size(key); err != nil {
+				paddedKey = key
+			}
+		}
+		if rendered && paddedKey == "" {
+			return size, nil
+		}
+
+		original, err := s.storage.Get(storageKeyFromURL(photo.URLOriginal, "originals"))
+		if err != nil {
+			return int64(0), fmt.Errorf("failed to read original: %w", err)
+		}
+
+		// Acquire semaphore to limit concurrent VIPS operations
+		s.processSem <- struct{}{}
+		defer func() { <-s.processSem }()
+
+		if quality == "display" {
+			return s.generateDisplayVersion(original, key, photo.IsAnimated, album.WatermarkEnabled, s.DisplayMaxEdge(album))
+		}
+		if !rendered {
+			if size, err = s.generateThumbnail(original, key, s.ThumbnailFit(album)); err != nil {
+				return int64(0), err
+			}
+		}
+		if paddedKey != "" {
+			if _, err := s.generatePaddedThumbnail(original, paddedKey, s.thumbnailPadding); err != nil {
+				return int64(0), fmt.Errorf("failed to generate padded thumbnail: %w", err)
+			}
+		}
+		return size, nil
+	})
+	return size.(int64), err
+}
+
+// downscaleOriginal shrinks img so its longest edge is maxEdge and re-encodes it in its
+// original format, keeping its metadata.
+func downscaleOriginal(img *vips.ImageRef, contentType string, maxEdge int) ([]byte, error) {
+	scale := float64(maxEdge) / float64(max(img.Width(), img.Height()))
+	if err := img.Resize(scale, vips.KernelLanczos3); err != nil {
+		return nil, fmt.Errorf("failed to resize image: %w", err)
+	}
+
+	var data []byte
+	var err error
+	switch contentType {
+	case "image/png":
+		data, _, err = img.ExportPng(vips.NewPngExportParams())
+	case "image/webp":
+		ep := vips.NewWebpExportParams()
+		ep.Quality = originalQuality
+		data, _, err = img.ExportWebp(ep)
+	case "image/tiff":
+		ep := vips.NewTiffExportParams()
+		ep.Quality = originalQuality
+		data, _, err = img.ExportTiff(ep)
+	default:
+		ep := vips.NewJpegExportParams()
+		ep.Quality = originalQuality
+		data, _, err = img.ExportJpeg(ep)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to export image: %w", err)
+	}
+	return data, nil
+}
+
+// ErrCorruptImage is returned when an uploaded image cannot be fully decoded, such as a truncated file.
+var ErrCorruptImage = errors.New("image file is corrupt or truncated")
+
+// verifyImageIntegrity decodes every pixel of an image, failing on any decoder error.
+// libvips loads lazily and by default fills in missing data, so a partially transferred
+// file would otherwise produce a broken derivative instead of an error.
+func verifyImageIntegrity(fileBytes []byte) error {
+	params := vips.NewImportParams()
+	params.FailOnError.Set(true)
+
+	img, err := vips.LoadImageFromBuffer(fileBytes, params)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrCorruptImage, err)
+	}
+	defer img.Close()
+
+	// Averaging the pixels forces the whole image to be decoded
+	if _, err := img.Average(); err != nil {
+		return fmt.Errorf("%w: %v", ErrCorruptImage, err)
+	}
+	return nil
+}
+
+// RegenerateDerivatives deletes a photo's display and thumbnail versions and rebuilds them
+// from the stored original using the current settings and the album's watermark, display size, and thumbnail fit.
+// URLs are unchanged; dimensions and file sizes are updated on the returned copy, since the
+// original may have been replaced or rotated.
+// Returns an error wrapping ErrObjectNotFound if the original is missing.
+func (s *ImageService) RegenerateDerivatives(album *models.Album, photo models.Photo) (models.Photo, error) {
+	// Acquire semaphore to limit concurrent VIPS operations
+	s.processSem <- struct{}{}
+	defer func() { <-s.processSem }()
+
+	original, err := s.storage.Get(storageKeyFromURL(photo.URLOriginal, "originals"))
+	if err != nil {
+		return photo, fmt.Errorf("failed to read original: %w", err)
+	}
+
+	img, err := vips.NewImageFromBuffer(original)
+	if err != nil {
+		return photo, fmt.Errorf("failed to decode original: %w", err)
+	}
+	width, height := img.Width(), img.Height()
+	img.Close()
+
+	displayKey := storageKeyFromURL(photo.URLDisplay, "display")
+	thumbnailKey := storageKeyFromURL(photo.URLThumbnail, "thumbnails")
+
+	// Clear existing derivatives so a failed rebuild never leaves stale files behind
+	if err := s.deleteObject(displayKey); err != nil {
+		return photo, fmt.Errorf("failed to delete display version: %w", err)
+	}
+	if err := s.deleteObject(thumbnailKey); err != nil {
+		return photo, fmt.Errorf("failed to delete thumbnail: %w", err)
+	}
+
+	displaySize, err := s.generateDisplayVersion(original, displayKey, photo.IsAnimated, album.WatermarkEnabled, s.DisplayMaxEdge(album))
+	if err != nil {
+		return photo, fmt.Errorf("failed to generate display version: %w", err)
+	}
+
+	thumbnailSize, err := s.generateThumbnail(original, thumbnailKey, s.ThumbnailFit(album))
+	if err != nil {
+		return photo, fmt.Errorf("failed to generate thumbnail: %w", err)
+	}
+
+	paddedURL, err := s.renderPaddedThumbnail(original, thumbnailKey)
+	if err != nil {
+		return photo, err
+	}
+	if paddedURL != "" {
+		photo.URLThumbnailPadded = paddedURL
+	}
+
+	photo.Width, photo.Height = width, height
+	photo.FileSizeDisplay = displaySize
+	photo.FileSizeThumbnail = thumbnailSize
+	photo.DerivativesPending = false
+	return photo, nil
+}
+
+// ReadOriginalMetadata reads a photo's dimensions and EXIF data from its stored original
+// without touching its derivatives. The EXIF data is nil if the original has none.
+// Returns an error wrapping ErrObjectNotFound if the original is missing.
+func (s *ImageService) ReadOriginalMetadata(photo *models.Photo) (width, height int, exifData *models.EXIF, err error) {
+	original, err := s.storage.Get(storageKeyFromURL(photo.URLOriginal, "originals"))
+	if err != nil {
+		return 0, 0, nil, fmt.Errorf("failed to read original: %w", err)
+	}
+
+	// Acquire semaphore to limit concurrent VIPS operations
+	s.processSem <- struct{}{}
+	defer func() { <-s.processSem }()
+
+	img, err := vips.NewImageFromBuffer(original)
+	if err != nil {
+		return 0, 0, nil, fmt.Errorf("failed to decode original: %w", err)
+	}
+	defer img.Close()
+
+	// EXIF is optional; originals without it still report their dimensions
+	exifData, err = s.extractEXIFFromBytes(original)
+	if err != nil {
+		exifData = nil
+	}
+
+	return img.Width(), img.Height(), exifData, nil
+}
+
+// putBytes writes an in-memory object to storage.
+func (s *ImageService) putBytes(key string, data []byte) error {
+	// Derivatives are rewritten in place when regenerated, so a cached ETag may be stale
+	s.etags.forget(key)
+	return s.storage.Put(key, bytes.NewReader(data), int64(len(data)))
+}
+
+// deleteObject removes an object from storage along with its cached ETag, so a file stored
+// again under the same key is not served with the old tag.
+func (s *ImageService) deleteObject(key string) error {
+	s.etags.forget(key)
+	return s.storage.Delete(key)
+}
+
+// generateDisplayVersion stores the display version of an image under dstKey, fitted within maxEdge.
+// Animated GIFs are stored unchanged, since converting them to WebP would keep only the first frame;
+// for the same reason they are never watermarked.
+func (s *ImageService) generateDisplayVersion(imageBytes []byte, dstKey string, animated, watermark bool, maxEdge int) (int64, error) {
+	if animated {
+		if err := s.putBytes(dstKey, imageBytes); err != nil {
+			return 0, fmt.Errorf("failed to write file: %w", err)
+		}
+		return int64(len(imageBytes)), nil
+	}
+	return s.generateResizedVersion(imageBytes, dstKey, maxEdge, displayQuality, watermark)
+}
+
+// generateResizedVersion generates a resized WebP version of an image using libvips and stores it under dstKey,
+// optionally stamped with the configured watermark.
+func (s *ImageService) generateResizedVersion(imageBytes []byte, dstKey string, maxSize int, quality int, watermark bool) (int64, error) {
+	// Load image with vips (multi-frame formats load only their first frame)
 	img, err := vips.NewImageFromBuffer(imageBytes)
 	if err != nil {
 		return 0, fmt.Errorf("failed to load image: %w", err)
 	}
 	defer img.Close()
 
+	if err := convertToSRGB(img); err != nil {
+		return 0, err
+	}
+
+	return s.storeResized(img, dstKey, maxSize, quality, watermark)
+}
+
+// storeResized shrinks a loaded sRGB image to fit within maxSize, optionally stamps the
+// watermark, and stores it under dstKey as WebP.
+func (s *ImageService) storeResized(img *vips.ImageRef, dstKey string, maxSize int, quality int, watermark bool) (int64, error) {
 	// Calculate scaling to fit within maxSize
 	width := img.Width()
 	height := img.Height()
@@ -355,6 +863,12 @@ func (s *ImageService) generateResizedVersion(imageBytes []byte, dstPath string,
 		}
 	}
 
+	if watermark {
+		if err := s.applyWatermark(img); err != nil {
+			return 0, err
+		}
+	}
+
 	// Export as WebP
 	ep := vips.NewWebpExportParams()
 	ep.Quality = quality
@@ -365,15 +879,39 @@ func (s *ImageService) generateResizedVersion(imageBytes []byte, dstPath string,
 	if err != nil {
 		return 0, fmt.Errorf("failed to export webp: %w", err)
 	}
+	if s.onEncode != nil {
+		s.onEncode(dstKey)
+	}
 
-	// Write to file
-	if err := os.WriteFile(dstPath, imageData, 0600); err != nil {
+	// Write to storage
+	if err := s.putBytes(dstKey, imageData); err != nil {
 		return 0, fmt.Errorf("failed to write file: %w", err)
 	}
 
 	return int64(len(imageData)), nil
 }
 
+// convertToSRGB converts an image's pixels to sRGB, the colour space browsers assume for
+// untagged images. CMYK JPEGs (common from Photoshop) otherwise show inverted or washed-out
+// colours, and wide-gamut profiles such as Display P3 look dull once their profile is stripped.
+// An embedded profile is applied first; derivatives are then exported without it.
+func convertToSRGB(img *vips.ImageRef) error {
+	if img.HasICCProfile() {
+		if err := img.TransformICCProfile(vips.SRGBIEC6196621ICCProfilePath); err != nil {
+			return fmt.Errorf("failed to apply ICC profile: %w", err)
+		}
+	}
+
+	switch img.Interpretation() {
+	case vips.InterpretationSRGB, vips.InterpretationBW:
+		return nil
+	}
+	if err := img.ToColorSpace(vips.InterpretationSRGB); err != nil {
+		return fmt.Errorf("failed to convert to sRGB: %w", err)
+	}
+	return nil
+}
+
 // extractEXIFFromBytes extracts EXIF data from image bytes.
 func (s *ImageService) extractEXIFFromBytes(imageBytes []byte) (*models.EXIF, error) {
 	return s.extractEXIF(strings.NewReader(string(imageBytes)))
@@ -451,6 +989,25 @@ func (s *ImageService) extractEXIF(r io.Reader) (*models.EXIF, error) {
 		exifData.DateTaken = &dateTime
 	}
 
+	// Free-text description, where scanners and labs often record the film stock
+	if desc, err := x.Get(exif.ImageDescription); err == nil {
+		if descStr, err := desc.StringVal(); err == nil {
+			exifData.Description = strings.TrimSpace(descStr)
+		}
+	}
+	if exifData.Description == "" {
+		if comment, err := x.Get(exif.UserComment); err == nil && len(comment.Val) > 8 {
+			// UserComment starts with an 8-byte character code identifier (e.g. "ASCII\x00\x00\x00")
+			exifData.Description = strings.TrimSpace(strings.Trim(string(comment.Val[8:]), "\x00"))
+		}
+	}
+
+	// GPS position, kept only if it is in range
+	if latitude, longitude, err := x.LatLong(); err == nil && models.ValidCoordinates(latitude, longitude) {
+		exifData.Latitude = &latitude
+		exifData.Longitude = &longitude
+	}
+
 	return exifData, nil
 }
 
@@ -458,29 +1015,28 @@ func (s *ImageService) extractEXIF(r io.Reader) (*models.EXIF, error) {
 func (s *ImageService) DeletePhoto(photo *models.Photo) error {
 	errors := []error{}
 
-	// Extract filename from URL
-	originalFilename := filepath.Base(photo.URLOriginal)
-	displayFilename := filepath.Base(photo.URLDisplay)
-	thumbnailFilename := filepath.Base(photo.URLThumbnail)
-
 	// Delete original
-	originalPath := filepath.Join(s.uploadDir, "originals", originalFilename)
-	if err := os.Remove(originalPath); err != nil && !os.IsNotExist(err) {
+	if err := s.deleteObject(storageKeyFromURL(photo.URLOriginal, "originals")); err != nil {
 		errors = append(errors, fmt.Errorf("failed to delete original: %w", err))
 	}
 
 	// Delete display version
-	displayPath := filepath.Join(s.uploadDir, "display", displayFilename)
-	if err := os.Remove(displayPath); err != nil && !os.IsNotExist(err) {
+	if err := s.deleteObject(storageKeyFromURL(photo.URLDisplay, "display")); err != nil {
 		errors = append(errors, fmt.Errorf("failed to delete display version: %w", err))
 	}
 
 	// Delete thumbnail
-	thumbnailPath := filepath.Join(s.uploadDir, "thumbnails", thumbnailFilename)
-	if err := os.Remove(thumbnailPath); err != nil && !os.IsNotExist(err) {
+	if err := s.deleteObject(storageKeyFromURL(photo.URLThumbnail, "thumbnails")); err != nil {
 		errors = append(errors, fmt.Errorf("failed to delete thumbnail: %w", err))
 	}
 
+	// Delete padded thumbnail, if one was made
+	if photo.URLThumbnailPadded != "" {
+		if err := s.deleteObject(storageKeyFromURL(photo.URLThumbnailPadded, "thumbnails")); err != nil {
+			errors = append(errors, fmt.Errorf("failed to delete padded thumbnail: %w", err))
+		}
+	}
+
 	if len(errors) > 0 {
 		return fmt.Errorf("errors deleting photo: %v", errors)
 	}
@@ -544,97 +1100,359 @@ func formatBytes(bytes int64) string {
 	return fmt.Sprintf("%.1f %cB", float64(bytes)/float64(div), "KMGTPE"[exp])
 }
 
-// StreamAlbumZIP creates and streams a ZIP file containing all photos from an album at the specified quality level.
-func (s *ImageService) StreamAlbumZIP(w http.ResponseWriter, album *models.Album, quality string) error {
-	// Determine subdirectory based on quality
-	var subdir string
+// photoStorageKey returns the storage key of a photo's file at the given quality level.
+func photoStorageKey(photo *models.Photo, quality string) (string, error) {
+	// We extract the filename from the URL since that's the source of truth
 	switch quality {
 	case "thumbnail":
-		subdir = "thumbnails"
+		return storageKeyFromURL(photo.URLThumbnail, "thumbnails"), nil
 	case "display":
-		subdir = "display"
+		return storageKeyFromURL(photo.URLDisplay, "display"), nil
 	case "original":
-		subdir = "originals"
+		return storageKeyFromURL(photo.URLOriginal, "originals"), nil
+	default:
+		return "", fmt.Errorf("invalid quality: %s", quality)
+	}
+}
+
+// openDownload opens a photo file of an album at the specified quality level for download.
+// If the album scrubs GPS data, an original is returned with its GPS tags removed; the stored
+// file is not changed. Derivatives never carry EXIF, so they are returned as stored, after
+// being rendered if the photo was uploaded in lazy mode and they have not been requested yet.
+func (s *ImageService) openDownload(album *models.Album, photo *models.Photo, quality string) (io.ReadCloser, string, error) {
+	key, err := photoStorageKey(photo, quality)
+	if err != nil {
+		return nil, "", err
+	}
+
+	reader, err := s.storage.Stream(key)
+	if errors.Is(err, ErrObjectNotFound) && photo.DerivativesPending && quality != "original" {
+		if _, err := s.GenerateDerivative(album, *photo, quality); err != nil {
+			return nil, key, err
+		}
+		reader, err = s.storage.Stream(key)
+	}
+	if err != nil {
+		return nil, key, err
+	}
+	if !album.ScrubGPSOnDownload || quality != "original" {
+		return reader, key, nil
+	}
+
+	defer func() { _ = reader.Close() }()
+	scrubbed, err := openScrubbedOriginal(reader)
+	return scrubbed, key, err
+}
+
+// StreamPhoto streams a single photo file of an album at the specified quality level as an
+// attachment. If the album scrubs GPS data, GPS tags are removed from an original on the fly.
+// Returns an error wrapping ErrObjectNotFound, before writing anything, if the file is missing.
+func (s *ImageService) StreamPhoto(w http.ResponseWriter, album *models.Album, photo *models.Photo, quality string) error {
+	reader, key, err := s.openDownload(album, photo, quality)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = reader.Close() }()
+
+	// Originals keep the uploaded extension; derivatives keep their own
+	ext := filepath.Ext(photo.FilenameOriginal)
+	if quality != "original" {
+		ext = filepath.Ext(key)
+	}
+	filename := downloadFilename(s.downloadFilenameTemplate(album), album, photo, ext)
+
+	contentType := mime.TypeByExtension(filepath.Ext(key))
+	if contentType == "" {
+		contentType = "application/octet-stream"
+	}
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+
+	if _, err := io.Copy(w, reader); err != nil {
+		return fmt.Errorf("failed to write photo: %w", err)
+	}
+	return nil
+}
+
+// ErrZIPPartNotFound is returned when a requested ZIP part does not exist.
+var ErrZIPPartNotFound = errors.New("ZIP part not found")
+
+// ZIPPart describes one part of a split album download.
+type ZIPPart struct {
+	Part     int      `json:"part"` // 1-based part number
+	Filename string   `json:"filename"`
+	Size     int64    `json:"size"` // Total size of the photos in the part, before ZIP overhead
+	PhotoIDs []string `json:"photo_ids"`
+}
+
+// zipPartMaxBytes returns the configured maximum ZIP part size, or 0 if album ZIPs are not split.
+func (s *ImageService) zipPartMaxBytes() int64 {
+	if s.configService == nil {
+		return 0
+	}
+	config, err := s.configService.Get()
+	if err != nil || config.Storage.MaxZIPPartSizeMB <= 0 {
+		return 0
+	}
+	return int64(config.Storage.MaxZIPPartSizeMB) * 1024 * 1024
+}
+
+// PlanAlbumZIP splits an album's downloadable photos into sequential ZIP parts of at most the
+// configured size. Photos are assigned in album order using their recorded file sizes, so the
+// same album always produces the same parts. A photo larger than the limit gets a part of its own.
+// Without a configured limit the whole album is a single part.
+func (s *ImageService) PlanAlbumZIP(album *models.Album, quality string) ([]ZIPPart, error) {
+	if _, err := photoStorageKey(&models.Photo{}, quality); err != nil {
+		return nil, err
+	}
+
+	maxBytes := s.zipPartMaxBytes()
+	parts := []ZIPPart{}
+	var current *ZIPPart
+	for _, photo := range album.Photos {
+		// Photos marked as not downloadable never appear in ZIPs
+		if !photo.Downloadable {
+			continue
+		}
+
+		size := photoFileSize(&photo, quality)
+		if current == nil || (maxBytes > 0 && len(current.PhotoIDs) > 0 && current.Size+size > maxBytes) {
+			parts = append(parts, ZIPPart{Part: len(parts) + 1, PhotoIDs: []string{}})
+			current = &parts[len(parts)-1]
+		}
+		current.Size += size
+		current.PhotoIDs = append(current.PhotoIDs, photo.ID)
+	}
+
+	for i := range parts {
+		if len(parts) == 1 {
+			parts[i].Filename = fmt.Sprintf("%s-%s.zip", album.Slug, quality)
+		} else {
+			parts[i].Filename = fmt.Sprintf("%s-%s-part%d.zip", album.Slug, quality, parts[i].Part)
+		}
+	}
+	return parts, nil
+}
+
+// photoFileSize returns the recorded file size of a photo at the given quality level.
+func photoFileSize(photo *models.Photo, quality string) int64 {
+	switch quality {
+	case "thumbnail":
+		return photo.FileSizeThumbnail
+	case "display":
+		return photo.FileSizeDisplay
 	default:
-		return fmt.Errorf("invalid quality: %s", quality)
+		return photo.FileSizeOriginal
+	}
+}
+
+// StreamAlbumZIP creates and streams a ZIP file containing all photos from an album at the specified quality level.
+// By default the ZIP is built in a temporary file first so the response carries Content-Length and
+// X-Content-SHA256 headers; with chunked set it is streamed as it is built, which suits very large albums.
+// With a ZIP cache, built ZIPs are kept and served again with range support until the album changes.
+func (s *ImageService) StreamAlbumZIP(w http.ResponseWriter, r *http.Request, album *models.Album, quality string, opts ZIPOptions) error {
+	// Validate quality before any headers are written
+	if _, err := photoStorageKey(&models.Photo{}, quality); err != nil {
+		return err
 	}
 
-	// Set response headers
 	filename := fmt.Sprintf("%s-%s.zip", album.Slug, quality)
+	return s.serveAlbumZIP(w, r, album, quality, filename, album.Photos, opts)
+}
+
+// StreamAlbumZIPPart streams one part of a split album download, as planned by PlanAlbumZIP.
+// Returns ErrZIPPartNotFound, before any headers are written, if the part does not exist.
+func (s *ImageService) StreamAlbumZIPPart(w http.ResponseWriter, r *http.Request, album *models.Album, quality string, part int, opts ZIPOptions) error {
+	parts, err := s.PlanAlbumZIP(album, quality)
+	if err != nil {
+		return err
+	}
+	if part < 1 || part > len(parts) {
+		return fmt.Errorf("%w: part %d of %d", ErrZIPPartNotFound, part, len(parts))
+	}
+
+	included := make(map[string]bool, len(parts[part-1].PhotoIDs))
+	for _, id := range parts[part-1].PhotoIDs {
+		included[id] = true
+	}
+	photos := make([]models.Photo, 0, len(included))
+	for _, photo := range album.Photos {
+		if included[photo.ID] {
+			photos = append(photos, photo)
+		}
+	}
+
+	return s.serveAlbumZIP(w, r, album, quality, parts[part-1].Filename, photos, opts)
+}
+
+// serveAlbumZIP writes a ZIP of the given album photos as a file download, either streamed
+// directly (chunked) or built in a file first so its length and checksum are known. Built
+// files are served from the ZIP cache if there is one, or from a temporary file.
+func (s *ImageService) serveAlbumZIP(w http.ResponseWriter, r *http.Request, album *models.Album, quality, filename string, photos []models.Photo, opts ZIPOptions) error {
+	// Downloads are assembled from the album's current photos, so clients must revalidate
+	w.Header().Set("Cache-Control", "no-cache")
+	if opts.Chunked {
+		w.Header().Set("Content-Type", "application/zip")
+		w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+		return s.writeAlbumZIP(w, album, quality, photos, opts.Sidecars)
+	}
+	if s.zipCacheDir != "" {
+		return s.serveCachedAlbumZIP(w, r, album, quality, filename, photos, opts.Sidecars)
+	}
+
+	tmpFile, err := os.CreateTemp("", "album-*.zip")
+	if err != nil {
+		return fmt.Errorf("failed to create temporary ZIP file: %w", err)
+	}
+	defer func() {
+		_ = tmpFile.Close()
+		if err := os.Remove(tmpFile.Name()); err != nil {
+			s.logger.Warn("failed to remove temporary ZIP file",
+				slog.String("path", tmpFile.Name()),
+				slog.String("error", err.Error()))
+		}
+	}()
+
+	// Hash while writing so the file only has to be read once more, to send it
+	hash := sha256.New()
+	if err := s.writeAlbumZIP(io.MultiWriter(tmpFile, hash), album, quality, photos, opts.Sidecars); err != nil {
+		return err
+	}
+
+	size, err := tmpFile.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return fmt.Errorf("failed to size temporary ZIP file: %w", err)
+	}
+	if _, err := tmpFile.Seek(0, io.SeekStart); err != nil {
+		return fmt.Errorf("failed to rewind temporary ZIP file: %w", err)
+	}
+
 	w.Header().Set("Content-Type", "application/zip")
 	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+	w.Header().Set("Content-Length", strconv.FormatInt(size, 10))
+	w.Header().Set("X-Content-SHA256", hex.EncodeToString(hash.Sum(nil)))
+
+	if _, err := io.Copy(w, tmpFile); err != nil {
+		return fmt.Errorf("failed to send ZIP file: %w", err)
+	}
+	return nil
+}
 
-	// Create ZIP writer that writes directly to response
+// writeAlbumZIP writes a ZIP file containing the given album photos at the specified quality level.
+// A ZIP streamed to a client is flushed as it is written.
+func (s *ImageService) writeAlbumZIP(w io.Writer, album *models.Album, quality string, photos []models.Photo, sidecars bool) error {
+	// Create ZIP writer that writes directly to the destination
 	zipWriter := zip.NewWriter(w)
-	defer func() {
-		if err := zipWriter.Close(); err != nil {
-			s.logger.Error("failed to close ZIP writer", slog.String("error", err.Error()))
+	flusher, _ := w.(http.Flusher)
+
+	if _, err := s.addPhotosToZIP(zipWriter, flusher, album, quality, photos, "", sidecars); err != nil {
+		return err
+	}
+
+	if err := zipWriter.Close(); err != nil {
+		return fmt.Errorf("failed to finish ZIP: %w", err)
+	}
+	return nil
+}
+
+// copyToZIP copies src into a ZIP entry a buffer at a time, flushing the ZIP writer and then
+// flusher after each buffer if flusher is not nil, so the data leaves the server as it is read.
+func copyToZIP(zipWriter *zip.Writer, entry io.Writer, src io.Reader, buf []byte, flusher http.Flusher) error {
+	for {
+		n, readErr := io.ReadFull(src, buf)
+		if n > 0 {
+			if _, err := entry.Write(buf[:n]); err != nil {
+				return err
+			}
+			if flusher != nil {
+				if err := zipWriter.Flush(); err != nil {
+					return err
+				}
+				flusher.Flush()
+			}
 		}
-	}()
+		if readErr == io.EOF || readErr == io.ErrUnexpectedEOF {
+			return nil
+		}
+		if readErr != nil {
+			return readErr
+		}
+	}
+}
 
-	// Add each photo to the ZIP
+// addPhotosToZIP adds the given album photos at the specified quality level to a ZIP, inside
+// dir if it is not empty. Originals have their GPS tags removed if the album asks for it.
+// Photos whose files cannot be opened are logged and skipped. Files are copied through one
+// buffer of the configured ZIP buffer size; if flusher is not nil, the ZIP is flushed to it
+// after every buffer. With sidecars, each photo is followed by its JSON sidecar.
+// Returns the number of photos added.
+func (s *ImageService) addPhotosToZIP(zipWriter *zip.Writer, flusher http.Flusher, album *models.Album, quality string, photos []models.Photo, dir string, sidecars bool) (int, error) {
+	added := 0
 	skippedCount := 0
-	for _, photo := range album.Photos {
-		// Determine the actual filename based on quality
-		// We extract the filename from the URL since that's the source of truth
-		var photoFilename string
-		switch quality {
-		case "thumbnail":
-			photoFilename = filepath.Base(photo.URLThumbnail)
-		case "display":
-			photoFilename = filepath.Base(photo.URLDisplay)
-		case "original":
-			photoFilename = filepath.Base(photo.URLOriginal)
-		}
-
-		// Construct full file path
-		photoPath := filepath.Join(s.uploadDir, subdir, photoFilename)
-
-		// Check if file exists
-		if _, err := os.Stat(photoPath); os.IsNotExist(err) {
+	buf := make([]byte, s.zipBufferSize)
+	template := s.downloadFilenameTemplate(album)
+	names := make(map[string]bool, len(photos))
+	for _, photo := range photos {
+		// Photos marked as not downloadable never appear in ZIPs
+		if !photo.Downloadable {
+			continue
+		}
+
+		// Open source file
+		sourceFile, photoKey, err := s.openDownload(album, &photo, quality)
+		if errors.Is(err, ErrObjectNotFound) {
 			s.logger.Warn("photo file not found, skipping",
 				slog.String("album", album.Slug),
 				slog.String("photo_id", photo.ID),
-				slog.String("path", photoPath))
+				slog.String("key", photoKey))
 			skippedCount++
 			continue
 		}
-
-		// Open source file
-		// #nosec G304 -- photoPath is constructed from validated album data and filepath.Base() extracts only the filename
-		sourceFile, err := os.Open(photoPath)
 		if err != nil {
 			s.logger.Error("failed to open photo file",
 				slog.String("photo_id", photo.ID),
-				slog.String("path", photoPath),
+				slog.String("key", photoKey),
 				slog.String("error", err.Error()))
 			skippedCount++
 			continue
 		}
 
-		// Create entry in ZIP with original filename using Store method (no compression)
+		// Templates can name several photos alike, e.g. without {index}, so those names are numbered
+		name := downloadFilename(template, album, &photo, filepath.Ext(photo.FilenameOriginal))
+		if template != "" {
+			name = uniqueFilename(names, name)
+		}
+
+		// Create entry in ZIP with the download filename using Store method (no compression)
 		// Photos are already compressed, so we don't want to waste CPU trying to compress them further
 		header := &zip.FileHeader{
-			Name:   photo.FilenameOriginal,
+			Name:   path.Join(dir, name),
 			Method: zip.Store, // No compression
 		}
 		zipEntry, err := zipWriter.CreateHeader(header)
 		if err != nil {
 			_ = sourceFile.Close()
-			return fmt.Errorf("failed to create ZIP entry for %s: %w", photo.FilenameOriginal, err)
+			return added, fmt.Errorf("failed to create ZIP entry for %s: %w", photo.FilenameOriginal, err)
 		}
 
 		// Copy file contents to ZIP (streaming, no buffering entire file)
-		if _, err := io.Copy(zipEntry, sourceFile); err != nil {
+		if err := copyToZIP(zipWriter, zipEntry, sourceFile, buf, flusher); err != nil {
 			_ = sourceFile.Close()
-			return fmt.Errorf("failed to write photo to ZIP: %w", err)
+			return added, fmt.Errorf("failed to write photo to ZIP: %w", err)
 		}
+		added++
 
 		if err := sourceFile.Close(); err != nil {
 			s.logger.Warn("failed to close source file",
 				slog.String("photo_id", photo.ID),
 				slog.String("error", err.Error()))
 		}
+
+		if sidecars {
+			if err := addSidecarToZIP(zipWriter, album, &photo, header.Name); err != nil {
+				return added, err
+			}
+		}
 	}
 
 	if skippedCount > 0 {
@@ -642,8 +1460,8 @@ func (s *ImageService) StreamAlbumZIP(w http.ResponseWriter, album *models.Album
 			slog.String("album", album.Slug),
 			slog.String("quality", quality),
 			slog.Int("skipped", skippedCount),
-			slog.Int("total", len(album.Photos)))
+			slog.Int("total", len(photos)))
 	}
 
-	return nil
+	return added, nil
 }
@@ -1,12 +1,31 @@
 package services
 
 import (
+	"archive/zip"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"image"
+	"image/color"
+	"image/gif"
+	"image/jpeg"
+	"image/png"
+	"io"
+	"log/slog"
+	"mime/multipart"
+	"net/http/httptest"
 	"os"
 	"path/filepath"
+	"runtime"
+	"strconv"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"syscall"
 	"testing"
 
+	"github.com/davidbyttow/govips/v2/vips"
 	"github.com/njoubert/nielsshootsfilm/backend/internal/models"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -410,3 +429,638 @@ func TestImageService_CheckDiskSpace_ErrorMessage(t *testing.T) {
 		)
 	}
 }
+
+// createTestAnimatedGIF returns an encoded GIF with the given number of solid-color frames.
+func createTestAnimatedGIF(t *testing.T, frames int) []byte {
+	t.Helper()
+
+	palette := color.Palette{color.Black, color.White, color.RGBA{R: 255, A: 255}}
+	anim := &gif.GIF{}
+	for i := 0; i < frames; i++ {
+		frame := image.NewPaletted(image.Rect(0, 0, 40, 30), palette)
+		for x := 0; x < 40; x++ {
+			for y := 0; y < 30; y++ {
+				frame.SetColorIndex(x, y, uint8(i%len(palette)))
+			}
+		}
+		anim.Image = append(anim.Image, frame)
+		anim.Delay = append(anim.Delay, 10)
+	}
+
+	var buf bytes.Buffer
+	require.NoError(t, gif.EncodeAll(&buf, anim))
+	return buf.Bytes()
+}
+
+func TestImageService_AnimatedGIF(t *testing.T) {
+	imageService, err := NewImageService(t.TempDir(), nil, nil)
+	require.NoError(t, err)
+	storage := NewMemoryStorage()
+	imageService.SetStorage(storage)
+
+	original := createTestAnimatedGIF(t, 3)
+	photo, err := imageService.ProcessBytes("loop.gif", original)
+	require.NoError(t, err)
+
+	assert.Equal(t, "image/gif", photo.MediaType)
+	assert.True(t, photo.IsAnimated)
+	assert.Equal(t, 40, photo.Width)
+	assert.Equal(t, 30, photo.Height)
+	assert.True(t, strings.HasSuffix(photo.URLDisplay, ".gif"), "display keeps the GIF format")
+
+	// Original and display both keep every frame
+	for _, url := range []string{photo.URLOriginal, photo.URLDisplay} {
+		data, err := storage.Get(strings.TrimPrefix(url, "/uploads/"))
+		require.NoError(t, err)
+		decoded, err := gif.DecodeAll(bytes.NewReader(data))
+		require.NoError(t, err, url)
+		assert.Len(t, decoded.Image, 3, url)
+	}
+
+	// The thumbnail is a single still frame
+	thumbnail, err := storage.Get(strings.TrimPrefix(photo.URLThumbnail, "/uploads/"))
+	require.NoError(t, err)
+	thumbImg, err := vips.NewImageFromBuffer(thumbnail)
+	require.NoError(t, err)
+	defer thumbImg.Close()
+	assert.Equal(t, 1, thumbImg.Pages())
+	assert.Equal(t, 30, thumbImg.Height(), "thumbnail height is one frame, not the whole strip")
+
+	// Regeneration preserves the animation
+	regenerated, err := imageService.RegenerateDerivatives(&models.Album{}, *photo)
+	require.NoError(t, err)
+	display, err := storage.Get(strings.TrimPrefix(regenerated.URLDisplay, "/uploads/"))
+	require.NoError(t, err)
+	assert.Equal(t, original, display)
+
+	// Single-frame GIFs are treated like any other still image
+	still, err := imageService.ProcessBytes("still.gif", createTestAnimatedGIF(t, 1))
+	require.NoError(t, err)
+	assert.False(t, still.IsAnimated)
+	assert.True(t, strings.HasSuffix(still.URLDisplay, ".webp"))
+}
+
+func TestImageService_PlanAlbumZIP_SplitsIntoParts(t *testing.T) {
+	fileService, err := NewFileService(t.TempDir())
+	require.NoError(t, err)
+	configService := NewSiteConfigService(fileService)
+	require.NoError(t, configService.Update(&models.SiteConfig{
+		Storage: models.StorageConfig{MaxZIPPartSizeMB: 1},
+	}))
+
+	imageService, err := NewImageService(t.TempDir(), configService, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	require.NoError(t, err)
+	storage := NewMemoryStorage()
+	imageService.SetStorage(storage)
+
+	// Recorded sizes drive the split; the stored objects only need to exist
+	const kb = 1024
+	sizes := []int64{400 * kb, 400 * kb, 300 * kb, 1500 * kb, 200 * kb, 100 * kb, 500 * kb}
+	album := &models.Album{Slug: "big-roll"}
+	for i, size := range sizes {
+		filename := fmt.Sprintf("frame-%02d.jpg", i+1)
+		photo := models.Photo{
+			ID:               fmt.Sprintf("p%d", i+1),
+			FilenameOriginal: filename,
+			URLOriginal:      "/uploads/originals/" + filename,
+			FileSizeOriginal: size,
+			Downloadable:     i != 5, // p6 is excluded from downloads
+		}
+		require.NoError(t, storage.Put("originals/"+filename, strings.NewReader(filename), int64(len(filename))))
+		album.Photos = append(album.Photos, photo)
+	}
+
+	parts, err := imageService.PlanAlbumZIP(album, "original")
+	require.NoError(t, err)
+
+	// Photos fill parts in album order; the oversize photo gets a part of its own
+	require.Len(t, parts, 4)
+	assert.Equal(t, []string{"p1", "p2"}, parts[0].PhotoIDs)
+	assert.Equal(t, []string{"p3"}, parts[1].PhotoIDs)
+	assert.Equal(t, []string{"p4"}, parts[2].PhotoIDs)
+	assert.Equal(t, []string{"p5", "p7"}, parts[3].PhotoIDs)
+	assert.Equal(t, int64(800*kb), parts[0].Size)
+	assert.Equal(t, "big-roll-original-part2.zip", parts[1].Filename)
+
+	// Every downloadable photo is covered exactly once, and parts stay within the limit
+	seen := map[string]int{}
+	for i, part := range parts {
+		assert.Equal(t, i+1, part.Part)
+		if len(part.PhotoIDs) > 1 {
+			assert.LessOrEqual(t, part.Size, int64(1024*kb))
+		}
+		for _, id := range part.PhotoIDs {
+			seen[id]++
+		}
+	}
+	assert.Equal(t, map[string]int{"p1": 1, "p2": 1, "p3": 1, "p4": 1, "p5": 1, "p7": 1}, seen)
+
+	// Planning is deterministic
+	again, err := imageService.PlanAlbumZIP(album, "original")
+	require.NoError(t, err)
+	assert.Equal(t, parts, again)
+
+	// Each part streams only its own photos
+	w := httptest.NewRecorder()
+	require.NoError(t, imageService.StreamAlbumZIPPart(w, httptest.NewRequest("GET", "/download", nil), album, "original", 4, ZIPOptions{}))
+	assert.Contains(t, w.Header().Get("Content-Disposition"), "big-roll-original-part4.zip")
+	zipReader, err := zip.NewReader(bytes.NewReader(w.Body.Bytes()), int64(w.Body.Len()))
+	require.NoError(t, err)
+	require.Len(t, zipReader.File, 2)
+	assert.Equal(t, "frame-05.jpg", zipReader.File[0].Name)
+	assert.Equal(t, "frame-07.jpg", zipReader.File[1].Name)
+
+	// Parts past the end do not exist
+	err = imageService.StreamAlbumZIPPart(httptest.NewRecorder(), httptest.NewRequest("GET", "/download", nil), album, "original", 5, ZIPOptions{})
+	assert.ErrorIs(t, err, ErrZIPPartNotFound)
+}
+
+func TestImageService_PlanAlbumZIP_Unsplit(t *testing.T) {
+	imageService, err := NewImageService(t.TempDir(), nil, nil)
+	require.NoError(t, err)
+
+	album := &models.Album{Slug: "small-roll", Photos: []models.Photo{
+		{ID: "p1", FileSizeDisplay: 10, Downloadable: true},
+		{ID: "p2", FileSizeDisplay: 20, Downloadable: true},
+	}}
+
+	// Without a configured limit the whole album is one part
+	parts, err := imageService.PlanAlbumZIP(album, "display")
+	require.NoError(t, err)
+	require.Len(t, parts, 1)
+	assert.Equal(t, []string{"p1", "p2"}, parts[0].PhotoIDs)
+	assert.Equal(t, int64(30), parts[0].Size)
+	assert.Equal(t, "small-roll-display.zip", parts[0].Filename)
+
+	_, err = imageService.PlanAlbumZIP(album, "huge")
+	assert.Error(t, err)
+}
+
+// newMultipartFileHeader returns a multipart file header for an in-memory upload.
+func newMultipartFileHeader(t *testing.T, filename string, data []byte) *multipart.FileHeader {
+	t.Helper()
+
+	var body bytes.Buffer
+	writer := multipart.NewWriter(&body)
+	part, err := writer.CreateFormFile("photos", filename)
+	require.NoError(t, err)
+	_, err = part.Write(data)
+	require.NoError(t, err)
+	require.NoError(t, writer.Close())
+
+	form, err := multipart.NewReader(&body, writer.Boundary()).ReadForm(int64(body.Len()) + 1024)
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = form.RemoveAll() })
+	return form.File["photos"][0]
+}
+
+func TestImageService_ProcessUpload_RejectsTruncatedImage(t *testing.T) {
+	uploadDir := t.TempDir()
+	imageService, err := NewImageService(uploadDir, nil, nil)
+	require.NoError(t, err)
+
+	// A partially transferred JPEG: the header is intact but the scan data is cut off
+	complete := createTestJPEG(t, 640, 480)
+	truncated := complete[:len(complete)/2]
+
+	_, err = imageService.ProcessUpload(newMultipartFileHeader(t, "cut-off.jpg", truncated))
+	require.Error(t, err)
+	assert.ErrorIs(t, err, ErrCorruptImage)
+	assert.Contains(t, err.Error(), "corrupt or truncated")
+
+	// Nothing is left behind in the upload directory
+	var leftovers []string
+	require.NoError(t, filepath.WalkDir(uploadDir, func(path string, d os.DirEntry, err error) error {
+		if err == nil && !d.IsDir() {
+			leftovers = append(leftovers, path)
+		}
+		return err
+	}))
+	assert.Empty(t, leftovers)
+
+	// The complete file is accepted
+	photo, err := imageService.ProcessUpload(newMultipartFileHeader(t, "whole.jpg", complete))
+	require.NoError(t, err)
+	assert.Equal(t, 640, photo.Width)
+}
+
+func TestImageService_ProcessBytes_RejectsTruncatedImage(t *testing.T) {
+	imageService, err := NewImageService(t.TempDir(), nil, nil)
+	require.NoError(t, err)
+	storage := NewMemoryStorage()
+	imageService.SetStorage(storage)
+
+	var buf bytes.Buffer
+	require.NoError(t, png.Encode(&buf, image.NewRGBA(image.Rect(0, 0, 200, 200))))
+	truncated := buf.Bytes()[:buf.Len()-40]
+
+	_, err = imageService.ProcessBytes("cut-off.png", truncated)
+	assert.ErrorIs(t, err, ErrCorruptImage)
+	assert.Empty(t, storage.Keys(""))
+}
+
+func TestImageService_StreamAlbumZIP_LengthAndChecksum(t *testing.T) {
+	// Point temporary files at a directory we can inspect for leftovers
+	tmpDir := t.TempDir()
+	t.Setenv("TMPDIR", tmpDir)
+
+	imageService, err := NewImageService(t.TempDir(), nil, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	require.NoError(t, err)
+	storage := NewMemoryStorage()
+	imageService.SetStorage(storage)
+
+	first, err := imageService.ProcessBytes("frame-01.jpg", createTestJPEG(t, 320, 240))
+	require.NoError(t, err)
+	second, err := imageService.ProcessBytes("frame-02.jpg", createTestJPEG(t, 240, 320))
+	require.NoError(t, err)
+	album := &models.Album{Slug: "contact-sheet", Photos: []models.Photo{*first, *second}}
+
+	w := httptest.NewRecorder()
+	require.NoError(t, imageService.StreamAlbumZIP(w, httptest.NewRequest("GET", "/download", nil), album, "original", ZIPOptions{}))
+
+	// The headers describe exactly the bytes sent
+	body := w.Body.Bytes()
+	assert.Equal(t, strconv.Itoa(len(body)), w.Header().Get("Content-Length"))
+	sum := sha256.Sum256(body)
+	assert.Equal(t, hex.EncodeToString(sum[:]), w.Header().Get("X-Content-SHA256"))
+
+	zipReader, err := zip.NewReader(bytes.NewReader(body), int64(len(body)))
+	require.NoError(t, err)
+	assert.Len(t, zipReader.File, 2)
+
+	// The staged ZIP is removed once sent
+	entries, err := os.ReadDir(tmpDir)
+	require.NoError(t, err)
+	assert.Empty(t, entries)
+
+	// Chunked streaming sends the same archive without precomputed headers
+	w = httptest.NewRecorder()
+	require.NoError(t, imageService.StreamAlbumZIP(w, httptest.NewRequest("GET", "/download", nil), album, "original", ZIPOptions{Chunked: true}))
+	assert.Empty(t, w.Header().Get("Content-Length"))
+	assert.Empty(t, w.Header().Get("X-Content-SHA256"))
+	zipReader, err = zip.NewReader(bytes.NewReader(w.Body.Bytes()), int64(w.Body.Len()))
+	require.NoError(t, err)
+	assert.Len(t, zipReader.File, 2)
+}
+
+// largeObjectStorage streams every object as size bytes generated on the fly, so tests can
+// download huge photos without holding them in memory.
+type largeObjectStorage struct {
+	Storage
+	size int64
+}
+
+func (l *largeObjectStorage) Stream(key string) (io.ReadCloser, error) {
+	return io.NopCloser(io.LimitReader(zeroReader{}, l.size)), nil
+}
+
+type zeroReader struct{}
+
+func (zeroReader) Read(p []byte) (int, error) {
+	clear(p)
+	return len(p), nil
+}
+
+// countingFlushWriter discards a streamed response, counting bytes written and flushes.
+type countingFlushWriter struct {
+	*httptest.ResponseRecorder
+	written int64
+	flushes int
+}
+
+func (c *countingFlushWriter) Write(p []byte) (int, error) {
+	c.written += int64(len(p))
+	return len(p), nil
+}
+
+func (c *countingFlushWriter) Flush() {
+	c.flushes++
+}
+
+func TestImageService_StreamAlbumZIP_BoundedMemory(t *testing.T) {
+	const photoSize = 64 << 20
+	const bufferSize = 256 << 10
+
+	imageService, err := NewImageService(t.TempDir(), nil, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	require.NoError(t, err)
+	imageService.SetStorage(&largeObjectStorage{Storage: NewMemoryStorage(), size: photoSize})
+	imageService.SetZIPBufferSize(bufferSize)
+
+	album := &models.Album{Slug: "huge"}
+	for i := range 4 {
+		album.Photos = append(album.Photos, models.Photo{
+			ID:               fmt.Sprintf("photo-%d", i),
+			FilenameOriginal: fmt.Sprintf("frame-%d.jpg", i),
+			URLDisplay:       fmt.Sprintf("/uploads/display/photo-%d_display.webp", i),
+			Downloadable:     true,
+		})
+	}
+
+	w := &countingFlushWriter{ResponseRecorder: httptest.NewRecorder()}
+	var before, after runtime.MemStats
+	runtime.GC()
+	runtime.ReadMemStats(&before)
+	require.NoError(t, imageService.StreamAlbumZIP(w, httptest.NewRequest("GET", "/download", nil), album, "display", ZIPOptions{Chunked: true}))
+	runtime.ReadMemStats(&after)
+
+	// Every photo went out, flushed a buffer at a time, without ever being held whole
+	assert.Greater(t, w.written, int64(4*photoSize))
+	assert.GreaterOrEqual(t, w.flushes, 4*photoSize/bufferSize)
+	assert.Less(t, after.TotalAlloc-before.TotalAlloc, uint64(photoSize/4))
+
+	// Buffers too small to be useful are raised to the minimum
+	imageService.SetZIPBufferSize(1)
+	assert.Equal(t, minZIPBufferSize, imageService.zipBufferSize)
+}
+
+func TestImageService_ProcessBytes_MaxOriginalEdge(t *testing.T) {
+	configService := createTestConfigService(t, 90)
+	config, err := configService.Get()
+	require.NoError(t, err)
+	config.Storage.MaxOriginalEdgePx = MinOriginalEdgeLimit
+	require.NoError(t, configService.Update(config))
+
+	imageService, err := NewImageService(t.TempDir(), configService, nil)
+	require.NoError(t, err)
+	storage := NewMemoryStorage()
+	imageService.SetStorage(storage)
+
+	storedOriginal := func(photo *models.Photo) image.Config {
+		data, err := storage.Get(storageKeyFromURL(photo.URLOriginal, "originals"))
+		require.NoError(t, err)
+		assert.Equal(t, photo.FileSizeOriginal, int64(len(data)))
+		cfg, err := jpeg.DecodeConfig(bytes.NewReader(data))
+		require.NoError(t, err)
+		return cfg
+	}
+
+	// An oversize scan is shrunk to the configured longest edge, keeping its aspect ratio and EXIF
+	upload := createTestJPEGWithEXIF(t, 4800, 1600, "Epson", "Perfection V850", "2021:05:06 07:08:09", 100)
+	photo, err := imageService.ProcessBytes("scan.jpg", upload)
+	require.NoError(t, err)
+	assert.True(t, photo.OriginalDownscaled)
+	assert.Equal(t, 3840, photo.Width)
+	assert.Equal(t, 1280, photo.Height)
+	require.NotNil(t, photo.EXIF)
+	assert.Equal(t, "Epson Perfection V850", photo.EXIF.Camera)
+
+	cfg := storedOriginal(photo)
+	assert.Equal(t, 3840, cfg.Width)
+	assert.Equal(t, 1280, cfg.Height)
+
+	// Uploads within the limit are stored byte for byte
+	normal := createTestJPEG(t, 1200, 800)
+	photo, err = imageService.ProcessBytes("normal.jpg", normal)
+	require.NoError(t, err)
+	assert.False(t, photo.OriginalDownscaled)
+	assert.Equal(t, 1200, photo.Width)
+	stored, err := storage.Get(storageKeyFromURL(photo.URLOriginal, "originals"))
+	require.NoError(t, err)
+	assert.Equal(t, normal, stored)
+}
+
+func TestImageService_ProcessBytes_KeepsTrueOriginalsByDefault(t *testing.T) {
+	imageService, err := NewImageService(t.TempDir(), createTestConfigService(t, 90), nil)
+	require.NoError(t, err)
+	storage := NewMemoryStorage()
+	imageService.SetStorage(storage)
+
+	upload := createTestJPEG(t, 4800, 1600)
+	photo, err := imageService.ProcessBytes("scan.jpg", upload)
+	require.NoError(t, err)
+	assert.False(t, photo.OriginalDownscaled)
+	assert.Equal(t, 4800, photo.Width)
+
+	stored, err := storage.Get(storageKeyFromURL(photo.URLOriginal, "originals"))
+	require.NoError(t, err)
+	assert.Equal(t, upload, stored)
+}
+
+// createCMYKJPEG encodes an Adobe-style CMYK JPEG, 8 pixels high, with one solid 8x8 block per
+// colour from left to right. Like Photoshop, it stores the CMYK values inverted.
+// Go cannot encode CMYK JPEGs, but solid blocks only have DC coefficients, so a tiny encoder does.
+func createCMYKJPEG(t *testing.T, blocks [][4]uint8) []byte {
+	t.Helper()
+
+	var out bytes.Buffer
+	segment := func(marker byte, payload ...byte) {
+		out.Write([]byte{0xFF, marker, byte((len(payload) + 2) >> 8), byte(len(payload) + 2)})
+		out.Write(payload)
+	}
+
+	out.Write([]byte{0xFF, 0xD8})                                       // SOI
+	segment(0xEE, 'A', 'd', 'o', 'b', 'e', 0, 100, 0, 0, 0, 0, 0)       // APP14: transform 0, plain CMYK
+	segment(0xDB, append([]byte{0}, bytes.Repeat([]byte{1}, 64)...)...) // DQT: all ones
+	width := 8 * len(blocks)
+	segment(0xC0, 8, 0, 8, byte(width>>8), byte(width), 4, 1, 0x11, 0, 2, 0x11, 0, 3, 0x11, 0, 4, 0x11, 0) // SOF0
+	dcCounts := []byte{0, 1, 5, 1, 1, 1, 1, 1, 1, 0, 0, 0, 0, 0, 0, 0}                                     // Standard luminance DC table
+	segment(0xC4, append(append([]byte{0x00}, dcCounts...), 0, 1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11)...)
+	segment(0xC4, append([]byte{0x10, 1}, append(make([]byte, 15), 0x00)...)...) // AC table: only EOB, code "0"
+	segment(0xDA, 4, 1, 0x00, 2, 0x00, 3, 0x00, 4, 0x00, 0, 63, 0)               // SOS
+
+	// Codes for DC categories 0-11 in the standard table
+	dcCodes := []struct{ code, length uint32 }{
+		{0b00, 2}, {0b010, 3}, {0b011, 3}, {0b100, 3}, {0b101, 3}, {0b110, 3},
+		{0b1110, 4}, {0b11110, 5}, {0b111110, 6}, {0b1111110, 7}, {0b11111110, 8}, {0b111111110, 9},
+	}
+	var acc, nbits uint32
+	writeBits := func(value, length uint32) {
+		for i := int(length) - 1; i >= 0; i-- {
+			acc = acc<<1 | (value>>uint(i))&1
+			nbits++
+			if nbits == 8 {
+				out.WriteByte(byte(acc))
+				if acc == 0xFF {
+					out.WriteByte(0) // Byte stuffing
+				}
+				acc, nbits = 0, 0
+			}
+		}
+	}
+
+	previous := [4]int{}
+	for _, block := range blocks {
+		for c, value := range block {
+			// A solid block's DC coefficient is 8 times its level-shifted sample
+			dc := 8 * (int(255-value) - 128)
+			diff := dc - previous[c]
+			previous[c] = dc
+
+			magnitude, category := diff, uint32(0)
+			if magnitude < 0 {
+				magnitude = -magnitude
+			}
+			for magnitude>>category > 0 {
+				category++
+			}
+			writeBits(dcCodes[category].code, dcCodes[category].length)
+			if category > 0 {
+				extra := diff
+				if diff < 0 {
+					extra = diff + 1<<category - 1
+				}
+				writeBits(uint32(extra), category)
+			}
+			writeBits(0, 1) // EOB: every AC coefficient is zero
+		}
+	}
+	if nbits > 0 {
+		writeBits(0xFF, 8-nbits) // Pad with ones
+	}
+	out.Write([]byte{0xFF, 0xD9}) // EOI
+
+	return out.Bytes()
+}
+
+func TestImageService_ProcessBytes_CMYKDerivativesAreSRGB(t *testing.T) {
+	imageService, err := NewImageService(t.TempDir(), nil, nil)
+	require.NoError(t, err)
+	storage := NewMemoryStorage()
+	imageService.SetStorage(storage)
+
+	// Red (no cyan, full magenta and yellow) beside white
+	upload := createCMYKJPEG(t, [][4]uint8{{0, 255, 255, 0}, {0, 0, 0, 0}})
+	decoded, err := jpeg.Decode(bytes.NewReader(upload))
+	require.NoError(t, err)
+	require.IsType(t, &image.CMYK{}, decoded, "fixture must decode as CMYK")
+
+	photo, err := imageService.ProcessBytes("photoshop.jpg", upload)
+	require.NoError(t, err)
+	assert.Equal(t, 16, photo.Width)
+	assert.Equal(t, 8, photo.Height)
+
+	// The original is stored as uploaded
+	stored, err := storage.Get(storageKeyFromURL(photo.URLOriginal, "originals"))
+	require.NoError(t, err)
+	assert.Equal(t, upload, stored)
+
+	for _, url := range []struct{ url, dir string }{
+		{photo.URLDisplay, "display"},
+		{photo.URLThumbnail, "thumbnails"},
+	} {
+		data, err := storage.Get(storageKeyFromURL(url.url, url.dir))
+		require.NoError(t, err)
+		img, err := vips.NewImageFromBuffer(data)
+		require.NoError(t, err)
+		assert.Equal(t, vips.InterpretationSRGB, img.Interpretation(), url.dir)
+
+		// Inverted colours would turn red into cyan and white into black
+		red, err := img.GetPoint(img.Width()/4, img.Height()/2)
+		require.NoError(t, err)
+		assert.Greater(t, red[0], 200.0, "%s red channel", url.dir)
+		assert.Less(t, red[1], 60.0, "%s green channel", url.dir)
+		assert.Less(t, red[2], 60.0, "%s blue channel", url.dir)
+
+		white, err := img.GetPoint(img.Width()*3/4, img.Height()/2)
+		require.NoError(t, err)
+		for band := range 3 {
+			assert.Greater(t, white[band], 230.0, "%s white band %d", url.dir, band)
+		}
+		img.Close()
+	}
+}
+
+// countingStorage counts the objects written to the storage it wraps.
+type countingStorage struct {
+	Storage
+	puts atomic.Int32
+}
+
+func (c *countingStorage) Put(key string, r io.Reader, size int64) error {
+	c.puts.Add(1)
+	return c.Storage.Put(key, r, size)
+}
+
+func newLazyImageService(t *testing.T) *ImageService {
+	t.Helper()
+
+	fileService, err := NewFileService(t.TempDir())
+	require.NoError(t, err)
+	configService := NewSiteConfigService(fileService)
+	require.NoError(t, configService.Update(&models.SiteConfig{
+		Storage: models.StorageConfig{DerivativeMode: models.DerivativeModeLazy},
+	}))
+
+	imageService, err := NewImageService(t.TempDir(), configService, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	require.NoError(t, err)
+	imageService.SetStorage(NewMemoryStorage())
+	return imageService
+}
+
+func TestImageService_ProcessBytes_LazyDerivatives(t *testing.T) {
+	imageService := newLazyImageService(t)
+
+	photo, err := imageService.ProcessBytes("lazy.jpg", createTestJPEG(t, 800, 600))
+	require.NoError(t, err)
+	assert.True(t, photo.DerivativesPending)
+	assert.Zero(t, photo.FileSizeDisplay)
+	assert.Zero(t, photo.FileSizeThumbnail)
+
+	storage := imageService.Storage()
+	displayKey := storageKeyFromURL(photo.URLDisplay, "display")
+	thumbnailKey := storageKeyFromURL(photo.URLThumbnail, "thumbnails")
+	_, err = storage.Size(storageKeyFromURL(photo.URLOriginal, "originals"))
+	require.NoError(t, err, "original should be stored")
+	_, err = storage.Size(displayKey)
+	assert.ErrorIs(t, err, ErrObjectNotFound)
+	_, err = storage.Size(thumbnailKey)
+	assert.ErrorIs(t, err, ErrObjectNotFound)
+
+	size, err := imageService.GenerateDerivative(&models.Album{}, *photo, "display")
+	require.NoError(t, err)
+	assert.Positive(t, size)
+	stored, err := storage.Size(displayKey)
+	require.NoError(t, err)
+	assert.Equal(t, size, stored)
+
+	size, err = imageService.GenerateDerivative(&models.Album{}, *photo, "thumbnail")
+	require.NoError(t, err)
+	assert.Positive(t, size)
+	_, err = storage.Size(thumbnailKey)
+	require.NoError(t, err)
+
+	_, err = imageService.GenerateDerivative(&models.Album{}, *photo, "original")
+	assert.Error(t, err)
+}
+
+func TestImageService_GenerateDerivative_CoalescesConcurrentRequests(t *testing.T) {
+	imageService := newLazyImageService(t)
+
+	photo, err := imageService.ProcessBytes("busy.jpg", createTestJPEG(t, 800, 600))
+	require.NoError(t, err)
+
+	storage := &countingStorage{Storage: imageService.Storage()}
+	imageService.SetStorage(storage)
+
+	const requests = 20
+	sizes := make([]int64, requests)
+	errs := make([]error, requests)
+	var wg sync.WaitGroup
+	for i := range requests {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			sizes[i], errs[i] = imageService.GenerateDerivative(&models.Album{}, *photo, "display")
+		}()
+	}
+	wg.Wait()
+
+	for i := range requests {
+		require.NoError(t, errs[i])
+		assert.Equal(t, sizes[0], sizes[i])
+	}
+	assert.Equal(t, int32(1), storage.puts.Load(), "display version should be rendered once")
+}
+
+func TestImageService_GenerateDerivative_MissingOriginal(t *testing.T) {
+	imageService := newLazyImageService(t)
+
+	photo := models.Photo{
+		ID:           "gone",
+		URLOriginal:  "/uploads/originals/gone.jpg",
+		URLDisplay:   "/uploads/display/gone_display.webp",
+		URLThumbnail: "/uploads/thumbnails/gone_thumb.webp",
+	}
+	_, err := imageService.GenerateDerivative(&models.Album{}, photo, "display")
+	assert.ErrorIs(t, err, ErrObjectNotFound)
+}
@@ -0,0 +1,145 @@
+package services
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/njoubert/nielsshootsfilm/backend/internal/models"
+)
+
+// Image URL signing defaults.
+const (
+	DefaultImageURLTTL   = time.Hour
+	DefaultImageURLGrace = 15 * time.Minute
+)
+
+// signableImageDirs are the /uploads folders whose files may require a signed URL.
+var signableImageDirs = []string{"originals", "display", "thumbnails", "covers"}
+
+var (
+	// ErrImageURLUnsigned is returned for a request without a signature for a file that needs one.
+	ErrImageURLUnsigned = errors.New("image URL is not signed")
+	// ErrImageURLExpired is returned for a signed URL past its expiry and grace period.
+	ErrImageURLExpired = errors.New("image URL has expired")
+	// ErrImageURLSignature is returned for a signature that does not match its URL.
+	ErrImageURLSignature = errors.New("image URL signature is invalid")
+)
+
+// ImageURLSigner signs the /uploads URLs of photo files in chosen folders, so that the files
+// are only served to pages that got their URLs from the API recently, keeping other sites from
+// embedding them. A signed URL carries ?expires= (Unix seconds) and ?sig=, an HMAC of the path
+// and expiry. Expiries are rounded up to whole TTLs, so every response within one TTL hands out
+// the same URL and browsers can cache the file; URLs are accepted for a grace period after they
+// expire, for pages loaded just before.
+type ImageURLSigner struct {
+	secret []byte
+	ttl    time.Duration
+	grace  time.Duration
+	dirs   []string
+}
+
+// NewImageURLSigner creates a signer for the files in dirs, e.g. "originals" and "display".
+// If secret is empty, a random one is generated, and URLs handed out before a restart stop
+// working after it.
+func NewImageURLSigner(secret string, ttl, grace time.Duration, dirs []string) (*ImageURLSigner, error) {
+	if ttl < time.Second {
+		return nil, fmt.Errorf("image URL TTL must be at least a second, got %s", ttl)
+	}
+	if grace < 0 {
+		return nil, fmt.Errorf("image URL grace period must not be negative, got %s", grace)
+	}
+	if len(dirs) == 0 {
+		return nil, errors.New("no folders to sign image URLs for")
+	}
+	for _, dir := range dirs {
+		if !slices.Contains(signableImageDirs, dir) {
+			return nil, fmt.Errorf("unknown uploads folder %q (allowed: %s)", dir, strings.Join(signableImageDirs, ", "))
+		}
+	}
+
+	key := []byte(secret)
+	if len(key) == 0 {
+		key = make([]byte, 32)
+		if _, err := rand.Read(key); err != nil {
+			return nil, fmt.Errorf("failed to generate image URL secret: %w", err)
+		}
+	}
+
+	return &ImageURLSigner{
+		secret: key,
+		ttl:    ttl,
+		grace:  grace,
+		dirs:   slices.Clone(dirs),
+	}, nil
+}
+
+// Protects reports whether the file stored under key, e.g. "display/<id>_display.webp", is
+// only served with a signed URL.
+func (s *ImageURLSigner) Protects(key string) bool {
+	dir, _, _ := strings.Cut(key, "/")
+	return slices.Contains(s.dirs, dir)
+}
+
+// Sign returns a /uploads URL signed to expire at the end of the TTL after the one now falls
+// in. URLs of files that need no signature, and URLs outside /uploads, are returned unchanged.
+func (s *ImageURLSigner) Sign(rawURL string, now time.Time) string {
+	key, ok := strings.CutPrefix(rawURL, "/uploads/")
+	if !ok || strings.Contains(key, "?") || !s.Protects(key) {
+		return rawURL
+	}
+
+	ttl := int64(s.ttl / time.Second)
+	expires := (now.Unix()/ttl + 2) * ttl
+	query := url.Values{}
+	query.Set("expires", strconv.FormatInt(expires, 10))
+	query.Set("sig", s.signature(key, expires))
+	return rawURL + "?" + query.Encode()
+}
+
+// SignPhoto signs the URLs of a photo's files.
+func (s *ImageURLSigner) SignPhoto(photo *models.Photo, now time.Time) {
+	photo.URLOriginal = s.Sign(photo.URLOriginal, now)
+	photo.URLDisplay = s.Sign(photo.URLDisplay, now)
+	photo.URLThumbnail = s.Sign(photo.URLThumbnail, now)
+	if photo.URLThumbnailPadded != "" {
+		photo.URLThumbnailPadded = s.Sign(photo.URLThumbnailPadded, now)
+	}
+}
+
+// Verify checks the ?expires= and ?sig= of a request for the file stored under key, and
+// returns when the URL stops being accepted, expiry plus grace.
+func (s *ImageURLSigner) Verify(key string, query url.Values, now time.Time) (time.Time, error) {
+	sig, expiresParam := query.Get("sig"), query.Get("expires")
+	if sig == "" || expiresParam == "" {
+		return time.Time{}, ErrImageURLUnsigned
+	}
+	expires, err := strconv.ParseInt(expiresParam, 10, 64)
+	if err != nil {
+		return time.Time{}, ErrImageURLSignature
+	}
+	if !hmac.Equal([]byte(sig), []byte(s.signature(key, expires))) {
+		return time.Time{}, ErrImageURLSignature
+	}
+
+	until := time.Unix(expires, 0).Add(s.grace)
+	if now.After(until) {
+		return time.Time{}, ErrImageURLExpired
+	}
+	return until, nil
+}
+
+// signature returns the hex HMAC-SHA256 of a storage key and its expiry.
+func (s *ImageURLSigner) signature(key string, expires int64) string {
+	mac := hmac.New(sha256.New, s.secret)
+	mac.Write([]byte(key + "\n" + strconv.FormatInt(expires, 10)))
+	return hex.EncodeToString(mac.Sum(nil))
+}
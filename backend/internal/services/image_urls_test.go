@@ -0,0 +1,122 @@
+package services
+
+import (
+	"net/url"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/njoubert/nielsshootsfilm/backend/internal/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// signedQuery splits a signed /uploads URL into its storage key and query.
+func signedQuery(t *testing.T, signed string) (string, url.Values) {
+	t.Helper()
+	path, rawQuery, ok := strings.Cut(signed, "?")
+	require.True(t, ok, "URL should be signed: %s", signed)
+	query, err := url.ParseQuery(rawQuery)
+	require.NoError(t, err)
+	return strings.TrimPrefix(path, "/uploads/"), query
+}
+
+func TestImageURLSigner(t *testing.T) {
+	signer, err := NewImageURLSigner("test-secret", time.Hour, 15*time.Minute, []string{"originals", "display"})
+	require.NoError(t, err)
+	now := time.Date(2026, 3, 14, 10, 20, 0, 0, time.UTC)
+
+	signed := signer.Sign("/uploads/display/abc_display.webp", now)
+	key, query := signedQuery(t, signed)
+	assert.Equal(t, "display/abc_display.webp", key)
+
+	// Expiries are rounded so URLs stay the same, and cacheable, for a whole TTL
+	expires, err := strconv.ParseInt(query.Get("expires"), 10, 64)
+	require.NoError(t, err)
+	assert.Equal(t, time.Date(2026, 3, 14, 12, 0, 0, 0, time.UTC), time.Unix(expires, 0).UTC())
+	assert.Equal(t, signed, signer.Sign("/uploads/display/abc_display.webp", now.Add(30*time.Minute)))
+
+	t.Run("valid", func(t *testing.T) {
+		until, err := signer.Verify(key, query, now)
+		require.NoError(t, err)
+		assert.Equal(t, time.Unix(expires, 0).Add(15*time.Minute), until)
+
+		// Accepted for the grace period after expiry
+		_, err = signer.Verify(key, query, time.Unix(expires, 0).Add(10*time.Minute))
+		assert.NoError(t, err)
+	})
+
+	t.Run("expired", func(t *testing.T) {
+		_, err := signer.Verify(key, query, time.Unix(expires, 0).Add(16*time.Minute))
+		assert.ErrorIs(t, err, ErrImageURLExpired)
+	})
+
+	t.Run("tampered", func(t *testing.T) {
+		// Another file
+		_, err := signer.Verify("originals/abc_original.jpg", query, now)
+		assert.ErrorIs(t, err, ErrImageURLSignature)
+
+		// A later expiry
+		extended := url.Values{"expires": {strconv.FormatInt(expires+3600, 10)}, "sig": query["sig"]}
+		_, err = signer.Verify(key, extended, now)
+		assert.ErrorIs(t, err, ErrImageURLSignature)
+
+		// A changed signature, or one from another secret
+		forged := url.Values{"expires": query["expires"], "sig": {strings.Repeat("0", len(query.Get("sig")))}}
+		_, err = signer.Verify(key, forged, now)
+		assert.ErrorIs(t, err, ErrImageURLSignature)
+
+		other, err := NewImageURLSigner("other-secret", time.Hour, 15*time.Minute, []string{"display"})
+		require.NoError(t, err)
+		_, err = other.Verify(key, query, now)
+		assert.ErrorIs(t, err, ErrImageURLSignature)
+
+		_, err = signer.Verify(key, url.Values{"expires": {"soon"}, "sig": query["sig"]}, now)
+		assert.ErrorIs(t, err, ErrImageURLSignature)
+	})
+
+	t.Run("unsigned", func(t *testing.T) {
+		_, err := signer.Verify(key, url.Values{}, now)
+		assert.ErrorIs(t, err, ErrImageURLUnsigned)
+	})
+
+	t.Run("unprotected files", func(t *testing.T) {
+		assert.True(t, signer.Protects("originals/abc_original.jpg"))
+		assert.False(t, signer.Protects("thumbnails/abc_thumbnail.webp"))
+		assert.Equal(t, "/uploads/thumbnails/abc_thumbnail.webp", signer.Sign("/uploads/thumbnails/abc_thumbnail.webp", now))
+		assert.Equal(t, "https://example.com/display/x.webp", signer.Sign("https://example.com/display/x.webp", now))
+	})
+
+	t.Run("photo", func(t *testing.T) {
+		photo := &models.Photo{
+			URLOriginal:  "/uploads/originals/abc_original.jpg",
+			URLDisplay:   "/uploads/display/abc_display.webp",
+			URLThumbnail: "/uploads/thumbnails/abc_thumbnail.webp",
+		}
+		signer.SignPhoto(photo, now)
+		assert.Contains(t, photo.URLOriginal, "?expires=")
+		assert.Equal(t, signed, photo.URLDisplay)
+		assert.Equal(t, "/uploads/thumbnails/abc_thumbnail.webp", photo.URLThumbnail)
+		assert.Empty(t, photo.URLThumbnailPadded)
+	})
+}
+
+func TestNewImageURLSigner_Invalid(t *testing.T) {
+	_, err := NewImageURLSigner("", time.Hour, 0, nil)
+	assert.Error(t, err)
+	_, err = NewImageURLSigner("", time.Hour, 0, []string{"secrets"})
+	assert.Error(t, err)
+	_, err = NewImageURLSigner("", 0, 0, []string{"display"})
+	assert.Error(t, err)
+	_, err = NewImageURLSigner("", time.Hour, -time.Minute, []string{"display"})
+	assert.Error(t, err)
+
+	// Without a secret, a random one is used
+	signer, err := NewImageURLSigner("", time.Hour, 0, []string{"display"})
+	require.NoError(t, err)
+	other, err := NewImageURLSigner("", time.Hour, 0, []string{"display"})
+	require.NoError(t, err)
+	now := time.Now()
+	assert.NotEqual(t, signer.Sign("/uploads/display/a.webp", now), other.Sign("/uploads/display/a.webp", now))
+}
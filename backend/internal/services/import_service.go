@@ -0,0 +1,161 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/njoubert/nielsshootsfilm/backend/internal/models"
+)
+
+// Folder import errors.
+var (
+	ErrImportDisabled = errors.New("folder import is not configured")
+	ErrInvalidImport  = errors.New("invalid import")
+)
+
+// ImportFileResult reports the outcome of importing one file.
+type ImportFileResult struct {
+	Filename string `json:"filename"`
+	PhotoID  string `json:"photo_id,omitempty"`
+	Error    string `json:"error,omitempty"`
+}
+
+// ImportResult reports the album created by a folder import and what happened to each file.
+type ImportResult struct {
+	Album    *models.Album      `json:"album"`
+	Files    []ImportFileResult `json:"files"`
+	Imported int                `json:"imported"`
+	Failed   int                `json:"failed"`
+}
+
+// ImportService creates albums from folders of images already on the server.
+type ImportService struct {
+	albumService *AlbumService
+	imageService *ImageService
+	allowedRoot  string
+}
+
+// NewImportService creates a new import service. Only folders inside allowedRoot can be imported;
+// an empty allowedRoot disables imports entirely.
+func NewImportService(albumService *AlbumService, imageService *ImageService, allowedRoot string) *ImportService {
+	return &ImportService{
+		albumService: albumService,
+		imageService: imageService,
+		allowedRoot:  allowedRoot,
+	}
+}
+
+// ImportFolder creates an album from the images in dir, in filename order.
+// The album title defaults to the folder name. Files that fail to process are reported, not fatal.
+// Returns an error wrapping ErrInsufficientStorage, before creating the album, while the
+// storage volume is below the free space uploads need.
+func (s *ImportService) ImportFolder(dir, title string) (*ImportResult, error) {
+	folder, err := s.resolve(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	entries, err := os.ReadDir(folder)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read folder: %w", err)
+	}
+
+	filenames := []string{}
+	for _, entry := range entries {
+		if entry.Type().IsRegular() && !strings.HasPrefix(entry.Name(), ".") && s.imageService.AllowsFilename(entry.Name()) {
+			filenames = append(filenames, entry.Name())
+		}
+	}
+	if len(filenames) == 0 {
+		return nil, fmt.Errorf("%w: folder contains no supported images", ErrInvalidImport)
+	}
+	sort.Strings(filenames)
+
+	// Checked before the album is created, so a full volume leaves no empty album behind
+	if err := s.imageService.CheckUploadSpace(); errors.Is(err, ErrInsufficientStorage) {
+		return nil, err
+	}
+
+	album, err := s.albumService.NewAlbum()
+	if err != nil {
+		return nil, err
+	}
+	album.Title = title
+	if album.Title == "" {
+		album.Title = filepath.Base(folder)
+	}
+	if album.Visibility == "" {
+		album.Visibility = "public"
+	}
+	if err := s.albumService.Create(album); err != nil {
+		return nil, err
+	}
+
+	uploads := s.imageService.Uploads(album)
+	result := &ImportResult{Files: make([]ImportFileResult, 0, len(filenames))}
+	for _, filename := range filenames {
+		fileResult := ImportFileResult{Filename: filename}
+
+		photo, err := uploads.ProcessFile(filepath.Join(folder, filename))
+		if err == nil {
+			err = s.albumService.AddPhoto(album.ID, photo)
+		}
+		if err != nil {
+			fileResult.Error = err.Error()
+			result.Failed++
+		} else {
+			fileResult.PhotoID = photo.ID
+			result.Imported++
+		}
+
+		result.Files = append(result.Files, fileResult)
+	}
+
+	result.Album, err = s.albumService.GetByID(album.ID)
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// resolve returns the absolute, symlink-free path of dir after checking it lies inside the allowed root.
+func (s *ImportService) resolve(dir string) (string, error) {
+	if s.allowedRoot == "" {
+		return "", ErrImportDisabled
+	}
+	if dir == "" {
+		return "", fmt.Errorf("%w: path is required", ErrInvalidImport)
+	}
+
+	root, err := filepath.Abs(s.allowedRoot)
+	if err != nil {
+		return "", fmt.Errorf("invalid import root: %w", err)
+	}
+	if root, err = filepath.EvalSymlinks(root); err != nil {
+		return "", fmt.Errorf("invalid import root: %w", err)
+	}
+
+	// Relative paths are taken relative to the import root
+	if !filepath.IsAbs(dir) {
+		dir = filepath.Join(root, dir)
+	}
+	folder, err := filepath.EvalSymlinks(filepath.Clean(dir))
+	if err != nil {
+		return "", fmt.Errorf("%w: folder not found", ErrInvalidImport)
+	}
+
+	rel, err := filepath.Rel(root, folder)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "", fmt.Errorf("%w: path is outside the allowed import root", ErrInvalidImport)
+	}
+
+	info, err := os.Stat(folder)
+	if err != nil || !info.IsDir() {
+		return "", fmt.Errorf("%w: path is not a folder", ErrInvalidImport)
+	}
+	return folder, nil
+}
@@ -0,0 +1,104 @@
+package services
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// setupImportFixture creates an import root with a folder of images and returns the root and the service.
+func setupImportFixture(t *testing.T) (string, *ImportService) {
+	t.Helper()
+
+	root := t.TempDir()
+	folder := filepath.Join(root, "roll-07")
+	require.NoError(t, os.MkdirAll(folder, 0755))
+
+	jpegBytes := createTestJPEG(t, 64, 48)
+	for _, name := range []string{"frame-02.jpg", "frame-01.jpg", "frame-03.JPG", ".hidden.jpg"} {
+		require.NoError(t, os.WriteFile(filepath.Join(folder, name), jpegBytes, 0600))
+	}
+	require.NoError(t, os.WriteFile(filepath.Join(folder, "frame-04.jpg"), []byte("not really a jpeg"), 0600))
+	require.NoError(t, os.WriteFile(filepath.Join(folder, "notes.txt"), []byte("scanned at 3200dpi"), 0600))
+
+	albumService, _ := setupAlbumService(t)
+	imageService, err := NewImageService(t.TempDir(), nil, nil)
+	require.NoError(t, err)
+	imageService.SetStorage(NewMemoryStorage())
+
+	return root, NewImportService(albumService, imageService, root)
+}
+
+func TestImportService_ImportFolder(t *testing.T) {
+	_, importService := setupImportFixture(t)
+
+	result, err := importService.ImportFolder("roll-07", "")
+	require.NoError(t, err)
+
+	// Only image files are considered, in filename order
+	require.Len(t, result.Files, 4)
+	assert.Equal(t, "frame-01.jpg", result.Files[0].Filename)
+	assert.Equal(t, "frame-02.jpg", result.Files[1].Filename)
+	assert.Equal(t, "frame-03.JPG", result.Files[2].Filename)
+	assert.Equal(t, "frame-04.jpg", result.Files[3].Filename)
+	assert.Equal(t, 3, result.Imported)
+	assert.Equal(t, 1, result.Failed)
+	assert.NotEmpty(t, result.Files[3].Error)
+	assert.Empty(t, result.Files[3].PhotoID)
+
+	// The album is named after the folder and holds the photos in order
+	assert.Equal(t, "roll-07", result.Album.Title)
+	assert.Equal(t, "public", result.Album.Visibility)
+	require.Len(t, result.Album.Photos, 3)
+	for i, photo := range result.Album.Photos {
+		assert.Equal(t, result.Files[i].PhotoID, photo.ID)
+		assert.Equal(t, result.Files[i].Filename, photo.FilenameOriginal)
+		assert.Equal(t, i+1, photo.Order)
+	}
+}
+
+func TestImportService_ImportFolder_Title(t *testing.T) {
+	root, importService := setupImportFixture(t)
+
+	// Absolute paths inside the root are accepted too
+	result, err := importService.ImportFolder(filepath.Join(root, "roll-07"), "Summer in Lisbon")
+	require.NoError(t, err)
+	assert.Equal(t, "Summer in Lisbon", result.Album.Title)
+	assert.Equal(t, "summer-in-lisbon", result.Album.Slug)
+}
+
+func TestImportService_ImportFolder_LowStorageSpace(t *testing.T) {
+	_, importService := setupImportFixture(t)
+	importService.imageService.SetStorage(&spaceStorage{MemoryStorage: NewMemoryStorage(), available: 50 << 20})
+	importService.imageService.SetMinUploadSpace(100 << 20)
+
+	// Nothing is imported, and no album is created
+	_, err := importService.ImportFolder("roll-07", "")
+	assert.ErrorIs(t, err, ErrInsufficientStorage)
+	albums, err := importService.albumService.GetAll()
+	require.NoError(t, err)
+	assert.Empty(t, albums)
+}
+
+func TestImportService_ImportFolder_PathGuard(t *testing.T) {
+	root, importService := setupImportFixture(t)
+
+	outside := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(outside, "secret.jpg"), createTestJPEG(t, 8, 8), 0600))
+	require.NoError(t, os.Symlink(outside, filepath.Join(root, "escape")))
+	require.NoError(t, os.MkdirAll(filepath.Join(root, "empty"), 0755))
+
+	for _, path := range []string{"", "..", "roll-07/../..", outside, "escape", "missing", "roll-07/frame-01.jpg", "empty"} {
+		_, err := importService.ImportFolder(path, "")
+		assert.True(t, errors.Is(err, ErrInvalidImport), "path %q should be rejected, got %v", path, err)
+	}
+
+	// Without a configured root, imports are disabled
+	disabled := NewImportService(importService.albumService, importService.imageService, "")
+	_, err := disabled.ImportFolder("roll-07", "")
+	assert.True(t, errors.Is(err, ErrImportDisabled))
+}
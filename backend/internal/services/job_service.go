@@ -0,0 +1,103 @@
+package services
+
+import (
+	"errors"
+	"maps"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/njoubert/nielsshootsfilm/backend/internal/models"
+)
+
+// jobRetention is how long finished jobs remain queryable.
+const jobRetention = 24 * time.Hour
+
+// JobService tracks background jobs in memory so clients can poll their progress.
+// Jobs do not survive a restart.
+type JobService struct {
+	jobs map[string]*models.Job
+	mu   sync.RWMutex
+}
+
+// NewJobService creates a new job service.
+func NewJobService() *JobService {
+	return &JobService{
+		jobs: make(map[string]*models.Job),
+	}
+}
+
+// Create registers a new running job and returns a snapshot of it.
+func (s *JobService) Create(jobType string, total int) models.Job {
+	job := &models.Job{
+		ID:        uuid.New().String(),
+		Type:      jobType,
+		Status:    models.JobStatusRunning,
+		Total:     total,
+		Skipped:   []models.JobItem{},
+		Errors:    []models.JobItem{},
+		CreatedAt: time.Now(),
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.pruneLocked()
+	s.jobs[job.ID] = job
+	return copyJob(job)
+}
+
+// Get returns a snapshot of a job.
+func (s *JobService) Get(id string) (*models.Job, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	job, ok := s.jobs[id]
+	if !ok {
+		return nil, errors.New("job not found")
+	}
+	snapshot := copyJob(job)
+	return &snapshot, nil
+}
+
+// Update applies fn to a job while holding the lock.
+func (s *JobService) Update(id string, fn func(job *models.Job)) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if job, ok := s.jobs[id]; ok {
+		fn(job)
+	}
+}
+
+// Finish marks a job completed, or failed if err is non-nil.
+func (s *JobService) Finish(id string, err error) {
+	s.Update(id, func(job *models.Job) {
+		now := time.Now()
+		job.FinishedAt = &now
+		job.Status = models.JobStatusCompleted
+		if err != nil {
+			job.Status = models.JobStatusFailed
+			job.Error = err.Error()
+		}
+	})
+}
+
+// pruneLocked drops finished jobs older than the retention period. Callers must hold the write lock.
+func (s *JobService) pruneLocked() {
+	cutoff := time.Now().Add(-jobRetention)
+	for id, job := range s.jobs {
+		if job.FinishedAt != nil && job.FinishedAt.Before(cutoff) {
+			delete(s.jobs, id)
+		}
+	}
+}
+
+// copyJob returns a copy of a job that does not share slices with the original.
+func copyJob(job *models.Job) models.Job {
+	snapshot := *job
+	snapshot.Skipped = append([]models.JobItem{}, job.Skipped...)
+	snapshot.Errors = append([]models.JobItem{}, job.Errors...)
+	snapshot.Counts = maps.Clone(job.Counts)
+	return snapshot
+}
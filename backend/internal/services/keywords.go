@@ -0,0 +1,202 @@
+package services
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/xml"
+	"slices"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/njoubert/nielsshootsfilm/backend/internal/models"
+)
+
+// photoshopSignature starts a JPEG APP13 segment holding Photoshop image resources, one of
+// which may be an IPTC record.
+const photoshopSignature = "Photoshop 3.0\x00"
+
+// iptcResourceID is the Photoshop image resource holding IPTC-IIM datasets.
+const iptcResourceID = 0x0404
+
+// IPTC-IIM application record (2) dataset for keywords, repeated once per keyword.
+const (
+	iptcApplicationRecord = 2
+	iptcKeywordsDataset   = 25
+)
+
+// dcNamespace is the Dublin Core namespace of XMP's dc:subject keyword bag.
+const dcNamespace = "http://purl.org/dc/elements/1.1/"
+
+// embeddedKeywords returns the keywords cataloguing tools embed in an image, as written: those
+// of a JPEG's IPTC record, then those of the XMP dc:subject any format may carry. Keywords
+// are not critical, so unreadable metadata is skipped, and files without any give nil.
+func embeddedKeywords(fileBytes []byte) []string {
+	return append(iptcKeywords(fileBytes), xmpKeywords(fileBytes)...)
+}
+
+// keywordTags merges keywords into a photo's tags, normalized and without duplicates, keeping
+// existing tags first. Keywords too long to be a tag are dropped, and none are added past the
+// per-photo tag limit.
+func keywordTags(tags, keywords []string) []string {
+	merged := models.NormalizeTags(tags)
+	for _, keyword := range models.NormalizeTags(keywords) {
+		if len(merged) >= models.MaxPhotoTags {
+			break
+		}
+		if utf8.RuneCountInString(keyword) > models.MaxPhotoTagLength || slices.Contains(merged, keyword) {
+			continue
+		}
+		merged = append(merged, keyword)
+	}
+	return merged
+}
+
+// iptcKeywords returns the keywords of a JPEG's IPTC record, found in the Photoshop
+// resources of its APP13 segment.
+func iptcKeywords(fileBytes []byte) []string {
+	segment := jpegSegment(fileBytes, 0xED, photoshopSignature)
+	if segment == nil {
+		return nil
+	}
+
+	// Image resources: "8BIM", ID, Pascal name padded to even length, size, data padded to even length
+	resources := segment[len(photoshopSignature):]
+	for len(resources) >= 12 && string(resources[:4]) == "8BIM" {
+		id := binary.BigEndian.Uint16(resources[4:6])
+		nameLen := int(resources[6])
+		pos := 6 + nameLen + 1
+		pos += pos % 2
+		if pos+4 > len(resources) {
+			return nil
+		}
+		size := int(binary.BigEndian.Uint32(resources[pos : pos+4]))
+		pos += 4
+		if size < 0 || pos+size > len(resources) {
+			return nil
+		}
+		if id == iptcResourceID {
+			return iptcDatasetKeywords(resources[pos : pos+size])
+		}
+		pos += size + size%2
+		resources = resources[min(pos, len(resources)):]
+	}
+	return nil
+}
+
+// iptcDatasetKeywords returns the keywords among a run of IPTC-IIM datasets.
+func iptcDatasetKeywords(datasets []byte) []string {
+	var keywords []string
+	for len(datasets) >= 5 && datasets[0] == 0x1C {
+		record, dataset := datasets[1], datasets[2]
+		length := int(binary.BigEndian.Uint16(datasets[3:5]))
+		pos := 5
+		// Extended datasets give the byte count of their length instead
+		if length&0x8000 != 0 {
+			count := length & 0x7FFF
+			if count > 4 || pos+count > len(datasets) {
+				return keywords
+			}
+			length = 0
+			for _, b := range datasets[pos : pos+count] {
+				length = length<<8 | int(b)
+			}
+			pos += count
+		}
+		if length < 0 || pos+length > len(datasets) {
+			return keywords
+		}
+		if record == iptcApplicationRecord && dataset == iptcKeywordsDataset {
+			keywords = append(keywords, iptcString(datasets[pos:pos+length]))
+		}
+		datasets = datasets[pos+length:]
+	}
+	return keywords
+}
+
+// iptcString decodes an IPTC value: UTF-8 as most tools now write, else Latin-1 as older
+// ones did.
+func iptcString(value []byte) string {
+	if utf8.Valid(value) {
+		return string(value)
+	}
+	runes := make([]rune, len(value))
+	for i, b := range value {
+		runes[i] = rune(b)
+	}
+	return string(runes)
+}
+
+// xmpKeywords returns the dc:subject keywords of the XMP packet embedded in an image.
+func xmpKeywords(fileBytes []byte) []string {
+	start := bytes.Index(fileBytes, []byte("<x:xmpmeta"))
+	if start < 0 {
+		return nil
+	}
+	end := bytes.Index(fileBytes[start:], []byte("</x:xmpmeta>"))
+	if end < 0 {
+		return nil
+	}
+	packet := fileBytes[start : start+end+len("</x:xmpmeta>")]
+
+	// Collect the text of rdf:li items inside dc:subject, whatever its bag looks like
+	var keywords []string
+	decoder := xml.NewDecoder(bytes.NewReader(packet))
+	depth, subject := 0, 0
+	var item *strings.Builder
+	for {
+		token, err := decoder.Token()
+		if err != nil {
+			return keywords
+		}
+		switch t := token.(type) {
+		case xml.StartElement:
+			depth++
+			if t.Name.Space == dcNamespace && t.Name.Local == "subject" && subject == 0 {
+				subject = depth
+			} else if subject > 0 && t.Name.Local == "li" {
+				item = &strings.Builder{}
+			}
+		case xml.CharData:
+			if item != nil {
+				item.Write(t)
+			}
+		case xml.EndElement:
+			if item != nil && t.Name.Local == "li" {
+				keywords = append(keywords, item.String())
+				item = nil
+			}
+			if depth == subject {
+				subject = 0
+			}
+			depth--
+		}
+	}
+}
+
+// jpegSegment returns the data of the first JPEG APPn segment with the given marker whose data
+// starts with signature, or nil if there is none before the image data.
+func jpegSegment(fileBytes []byte, marker byte, signature string) []byte {
+	if len(fileBytes) < 4 || fileBytes[0] != 0xFF || fileBytes[1] != 0xD8 {
+		return nil
+	}
+	pos := 2
+	for pos+4 <= len(fileBytes) {
+		if fileBytes[pos] != 0xFF {
+			return nil
+		}
+		current := fileBytes[pos+1]
+		if current == 0xDA || current == 0xD9 {
+			return nil
+		}
+		length := int(binary.BigEndian.Uint16(fileBytes[pos+2 : pos+4]))
+		if length < 2 || pos+2+length > len(fileBytes) {
+			return nil
+		}
+		data := fileBytes[pos+4 : pos+2+length]
+		if current == marker && bytes.HasPrefix(data, []byte(signature)) {
+			return data
+		}
+		pos += 2 + length
+	}
+	return nil
+}
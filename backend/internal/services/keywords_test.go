@@ -0,0 +1,144 @@
+package services
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"log/slog"
+	"strings"
+	"testing"
+
+	"github.com/njoubert/nielsshootsfilm/backend/internal/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// createTestJPEGWithKeywords returns a JPEG carrying IPTC keywords in a Photoshop APP13
+// segment and, if xmpSubjects are given, an XMP packet listing them as dc:subject.
+func createTestJPEGWithKeywords(t *testing.T, iptc []string, xmpSubjects []string) []byte {
+	t.Helper()
+
+	// IPTC datasets: 0x1C, record, dataset, 2-byte length, value; a record version first
+	datasets := []byte{0x1C, 2, 0, 0, 2, 0, 4}
+	for _, keyword := range iptc {
+		dataset := []byte{0x1C, 2, 25, 0, 0}
+		binary.BigEndian.PutUint16(dataset[3:], uint16(len(keyword)))
+		datasets = append(append(datasets, dataset...), keyword...)
+	}
+
+	// A resource before the IPTC one, to be skipped: "8BIM", ID, empty name padded, size, data
+	resources := []byte("8BIM\x04\x0C\x00\x00\x00\x00\x00\x01x\x00")
+	resource := []byte("8BIM\x04\x04\x00\x00\x00\x00\x00\x00")
+	binary.BigEndian.PutUint32(resource[8:], uint32(len(datasets)))
+	resources = append(append(resources, resource...), datasets...)
+	if len(datasets)%2 == 1 {
+		resources = append(resources, 0)
+	}
+
+	segments := [][]byte{appSegment(0xED, append([]byte(photoshopSignature), resources...))}
+	if len(xmpSubjects) > 0 {
+		var items strings.Builder
+		for _, subject := range xmpSubjects {
+			fmt.Fprintf(&items, "<rdf:li>%s</rdf:li>", subject)
+		}
+		packet := `<x:xmpmeta xmlns:x="adobe:ns:meta/"><rdf:RDF xmlns:rdf="http://www.w3.org/1999/02/22-rdf-syntax-ns#">` +
+			`<rdf:Description xmlns:dc="http://purl.org/dc/elements/1.1/">` +
+			`<dc:title><rdf:Alt><rdf:li xml:lang="x-default">Not a keyword</rdf:li></rdf:Alt></dc:title>` +
+			`<dc:subject><rdf:Bag>` + items.String() + `</rdf:Bag></dc:subject>` +
+			`</rdf:Description></rdf:RDF></x:xmpmeta>`
+		segments = append(segments, appSegment(0xE1, []byte("http://ns.adobe.com/xap/1.0/\x00"+packet)))
+	}
+
+	// Insert the segments straight after the SOI marker
+	plain := createTestJPEG(t, 320, 200)
+	out := append([]byte{}, plain[:2]...)
+	for _, segment := range segments {
+		out = append(out, segment...)
+	}
+	return append(out, plain[2:]...)
+}
+
+// appSegment returns a JPEG APPn segment with the given marker and data.
+func appSegment(marker byte, data []byte) []byte {
+	segment := []byte{0xFF, marker, 0, 0}
+	binary.BigEndian.PutUint16(segment[2:], uint16(len(data)+2))
+	return append(segment, data...)
+}
+
+func TestEmbeddedKeywords(t *testing.T) {
+	t.Run("IPTC and XMP", func(t *testing.T) {
+		fixture := createTestJPEGWithKeywords(t, []string{"Beach", "Portra 400"}, []string{"beach", "Sunset"})
+		assert.Equal(t, []string{"Beach", "Portra 400", "beach", "Sunset"}, embeddedKeywords(fixture))
+	})
+
+	t.Run("Latin-1 IPTC", func(t *testing.T) {
+		fixture := createTestJPEGWithKeywords(t, []string{"caf\xe9"}, nil)
+		assert.Equal(t, []string{"café"}, embeddedKeywords(fixture))
+	})
+
+	t.Run("no keywords", func(t *testing.T) {
+		assert.Empty(t, embeddedKeywords(createTestJPEG(t, 64, 64)))
+		assert.Empty(t, embeddedKeywords([]byte("not an image")))
+	})
+
+	t.Run("truncated", func(t *testing.T) {
+		fixture := createTestJPEGWithKeywords(t, []string{"beach"}, nil)
+		assert.NotPanics(t, func() {
+			for n := range fixture[:200] {
+				embeddedKeywords(fixture[:n])
+			}
+		})
+	})
+}
+
+func TestKeywordTags(t *testing.T) {
+	long := strings.Repeat("x", models.MaxPhotoTagLength+1)
+	assert.Equal(t, []string{"travel", "beach", "sunset"},
+		keywordTags([]string{"Travel"}, []string{" Beach", "TRAVEL", long, "beach", "sunset", ""}))
+	assert.Nil(t, keywordTags(nil, nil))
+
+	many := make([]string, models.MaxPhotoTags+5)
+	for i := range many {
+		many[i] = fmt.Sprintf("keyword %d", i)
+	}
+	tags := keywordTags([]string{"existing"}, many)
+	assert.Len(t, tags, models.MaxPhotoTags)
+	assert.Equal(t, "existing", tags[0])
+}
+
+func TestImageService_ProcessBytes_KeywordTags(t *testing.T) {
+	fixture := createTestJPEGWithKeywords(t, []string{"Beach", "Portra 400", "beach"}, []string{"Sunset", "PORTRA 400"})
+
+	t.Run("off by default", func(t *testing.T) {
+		imageService, err := NewImageService(t.TempDir(), nil, slog.New(slog.NewTextHandler(io.Discard, nil)))
+		require.NoError(t, err)
+		imageService.SetStorage(NewMemoryStorage())
+
+		photo, err := imageService.ProcessBytes("untagged.jpg", fixture)
+		require.NoError(t, err)
+		assert.Empty(t, photo.Tags)
+	})
+
+	t.Run("on", func(t *testing.T) {
+		fileService, err := NewFileService(t.TempDir())
+		require.NoError(t, err)
+		configService := NewSiteConfigService(fileService)
+		require.NoError(t, configService.Update(&models.SiteConfig{
+			Storage: models.StorageConfig{KeywordTags: models.KeywordTagsOn},
+		}))
+		imageService, err := NewImageService(t.TempDir(), configService, slog.New(slog.NewTextHandler(io.Discard, nil)))
+		require.NoError(t, err)
+		imageService.SetStorage(NewMemoryStorage())
+
+		photo, err := imageService.ProcessBytes("tagged.jpg", fixture)
+		require.NoError(t, err)
+		assert.Equal(t, []string{"beach", "portra 400", "sunset"}, photo.Tags)
+
+		// A repeat upload shares the stored files but is tagged all the same
+		uploads := imageService.Uploads(&models.Album{Photos: []models.Photo{*photo}})
+		repeat, err := uploads.ProcessBytes("again.jpg", fixture)
+		require.NoError(t, err)
+		assert.Equal(t, photo.URLOriginal, repeat.URLOriginal)
+		assert.Equal(t, []string{"beach", "portra 400", "sunset"}, repeat.Tags)
+	})
+}
@@ -0,0 +1,81 @@
+package services
+
+import (
+	"archive/zip"
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/njoubert/nielsshootsfilm/backend/internal/models"
+)
+
+// MultiAlbumManifestName is the name of the manifest file inside a multi-album ZIP.
+const MultiAlbumManifestName = "manifest.json"
+
+// MultiAlbumManifest records what a multi-album ZIP contains and which requested albums were left out.
+type MultiAlbumManifest struct {
+	Quality string               `json:"quality"`
+	Albums  []MultiAlbumIncluded `json:"albums"`
+	Skipped []MultiAlbumSkipped  `json:"skipped"`
+}
+
+// MultiAlbumIncluded is an album included in a multi-album ZIP, under the folder named by its slug.
+type MultiAlbumIncluded struct {
+	Slug   string `json:"slug"`
+	Title  string `json:"title"`
+	Photos int    `json:"photos"` // Photos actually added; missing files are left out
+}
+
+// MultiAlbumSkipped is a requested album left out of a multi-album ZIP, and why.
+type MultiAlbumSkipped struct {
+	Slug   string `json:"slug"`
+	Reason string `json:"reason"`
+}
+
+// StreamMultiAlbumZIP streams a ZIP with one folder per album, holding its downloadable photos
+// at the specified quality level, followed by a manifest listing the included albums and the
+// skipped ones. The ZIP is streamed as it is built, since several albums can be very large.
+func (s *ImageService) StreamMultiAlbumZIP(w http.ResponseWriter, albums []*models.Album, skipped []MultiAlbumSkipped, quality string) error {
+	// Validate quality before any headers are written
+	if _, err := photoStorageKey(&models.Photo{}, quality); err != nil {
+		return err
+	}
+
+	w.Header().Set("Content-Type", "application/zip")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", "albums-"+quality+".zip"))
+
+	zipWriter := zip.NewWriter(w)
+	flusher, _ := w.(http.Flusher)
+	manifest := MultiAlbumManifest{
+		Quality: quality,
+		Albums:  make([]MultiAlbumIncluded, 0, len(albums)),
+		Skipped: skipped,
+	}
+	if manifest.Skipped == nil {
+		manifest.Skipped = []MultiAlbumSkipped{}
+	}
+
+	for _, album := range albums {
+		added, err := s.addPhotosToZIP(zipWriter, flusher, album, quality, album.Photos, album.Slug, false)
+		if err != nil {
+			return err
+		}
+		manifest.Albums = append(manifest.Albums, MultiAlbumIncluded{Slug: album.Slug, Title: album.Title, Photos: added})
+	}
+
+	entry, err := zipWriter.Create(MultiAlbumManifestName)
+	if err != nil {
+		return fmt.Errorf("failed to create ZIP manifest: %w", err)
+	}
+	encoder := json.NewEncoder(entry)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(manifest); err != nil {
+		return fmt.Errorf("failed to write ZIP manifest: %w", err)
+	}
+
+	if err := zipWriter.Close(); err != nil {
+		return fmt.Errorf("failed to finish ZIP: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,48 @@
+package services
+
+import (
+	"cmp"
+	"slices"
+)
+
+// DefaultOverviewLargest is how many of the largest albums an overview lists by default.
+const DefaultOverviewLargest = 5
+
+// Overview is the site's headline numbers for the admin dashboard.
+type Overview struct {
+	Albums       int                 `json:"albums"`
+	Photos       int                 `json:"photos"`
+	TotalBytes   int64               `json:"total_bytes"`
+	ByVisibility map[string]int      `json:"by_visibility"` // Album count per visibility
+	Largest      []AlbumStorageUsage `json:"largest"`       // Largest albums by total bytes, biggest first
+}
+
+// Overview totals albums, photos, and stored bytes across the site and lists the largest
+// albums, at most largest of them. Per-album totals come from the same cache as Report.
+func (s *StorageUsageService) Overview(largest int) (*Overview, error) {
+	albums, err := s.albumService.GetAll()
+	if err != nil {
+		return nil, err
+	}
+	report, err := s.report(albums)
+	if err != nil {
+		return nil, err
+	}
+
+	overview := &Overview{
+		Albums:       len(albums),
+		Photos:       report.Total.Photos,
+		TotalBytes:   report.Total.TotalBytes,
+		ByVisibility: map[string]int{},
+	}
+	for i := range albums {
+		overview.ByVisibility[albums[i].Visibility]++
+	}
+
+	sorted := slices.Clone(report.Albums)
+	slices.SortStableFunc(sorted, func(a, b AlbumStorageUsage) int {
+		return cmp.Compare(b.TotalBytes, a.TotalBytes)
+	})
+	overview.Largest = sorted[:min(largest, len(sorted))]
+	return overview, nil
+}
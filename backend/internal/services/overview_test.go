@@ -0,0 +1,74 @@
+package services
+
+import (
+	"io"
+	"log/slog"
+	"testing"
+
+	"github.com/njoubert/nielsshootsfilm/backend/internal/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStorageUsageService_Overview(t *testing.T) {
+	albumService, _ := setupAlbumService(t)
+	imageService, err := NewImageService(t.TempDir(), nil, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	require.NoError(t, err)
+	imageService.SetStorage(NewMemoryStorage())
+	usageService := NewStorageUsageService(albumService, imageService)
+
+	// Empty sites report zeros rather than nulls
+	overview, err := usageService.Overview(DefaultOverviewLargest)
+	require.NoError(t, err)
+	assert.Zero(t, overview.Albums)
+	assert.Empty(t, overview.ByVisibility)
+	assert.NotNil(t, overview.Largest)
+	assert.Empty(t, overview.Largest)
+
+	// A fixture catalog: two public albums of different sizes, one unlisted, one empty
+	catalog := []struct {
+		album  *models.Album
+		photos []int // Widths of the photos uploaded to it
+	}{
+		{&models.Album{Title: "Small", Visibility: "public"}, []int{160}},
+		{&models.Album{Title: "Large", Visibility: "public"}, []int{640, 480, 320}},
+		{&models.Album{Title: "Medium", Visibility: "unlisted"}, []int{480}},
+		{&models.Album{Title: "Empty", Visibility: "unlisted"}, nil},
+	}
+	var wantPhotos int
+	var wantBytes int64
+	for _, entry := range catalog {
+		require.NoError(t, albumService.Create(entry.album))
+		for _, width := range entry.photos {
+			photo, err := imageService.ProcessBytes("photo.jpg", createTestJPEG(t, width, width*2/3))
+			require.NoError(t, err)
+			require.NoError(t, albumService.AddPhoto(entry.album.ID, photo))
+			wantPhotos++
+			wantBytes += photo.FileSizeOriginal + photo.FileSizeDisplay + photo.FileSizeThumbnail
+		}
+	}
+
+	overview, err = usageService.Overview(2)
+	require.NoError(t, err)
+	assert.Equal(t, 4, overview.Albums)
+	assert.Equal(t, wantPhotos, overview.Photos)
+	assert.Equal(t, wantBytes, overview.TotalBytes, "totals should match the stored file sizes")
+	assert.Equal(t, map[string]int{"public": 2, "unlisted": 2}, overview.ByVisibility)
+
+	// Only the requested number of largest albums are listed, biggest first
+	require.Len(t, overview.Largest, 2)
+	assert.Equal(t, "Large", overview.Largest[0].Title)
+	assert.Equal(t, 3, overview.Largest[0].Photos)
+	assert.Equal(t, "Medium", overview.Largest[1].Title)
+	assert.Greater(t, overview.Largest[0].TotalBytes, overview.Largest[1].TotalBytes)
+
+	// The overview agrees with the full report it is built from
+	report, err := usageService.Report()
+	require.NoError(t, err)
+	assert.Equal(t, report.Total.TotalBytes, overview.TotalBytes)
+
+	overview, err = usageService.Overview(10)
+	require.NoError(t, err)
+	assert.Len(t, overview.Largest, 4)
+	assert.Equal(t, "Empty", overview.Largest[3].Title)
+}
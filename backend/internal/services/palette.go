@@ -0,0 +1,89 @@
+package services
+
+import (
+	"cmp"
+	"fmt"
+	"slices"
+
+	"github.com/davidbyttow/govips/v2/vips"
+)
+
+// paletteSampleEdge is the longest edge photos are shrunk to before their palette is taken.
+const paletteSampleEdge = 64
+
+// PaletteSize is how many colors a photo's palette holds at most.
+const PaletteSize = 5
+
+// paletteBits is how many bits of each channel colors are grouped by, so near-identical shades
+// count as one color.
+const paletteBits = 4
+
+// extractPalette returns the most common colors of an image as #rrggbb, most common first.
+// Pixels are grouped into buckets of similar colors, and each bucket is reported as the
+// average of its pixels.
+func extractPalette(imageBytes []byte) ([]string, error) {
+	img, err := vips.NewThumbnailWithSizeFromBuffer(imageBytes, paletteSampleEdge, paletteSampleEdge, vips.InterestingNone, vips.SizeDown)
+	if err != nil {
+		return nil, fmt.Errorf("failed to shrink image: %w", err)
+	}
+	defer img.Close()
+
+	if err := img.ToColorSpace(vips.InterpretationSRGB); err != nil {
+		return nil, fmt.Errorf("failed to convert to sRGB: %w", err)
+	}
+	if img.BandFormat() != vips.BandFormatUchar {
+		if err := img.Cast(vips.BandFormatUchar); err != nil {
+			return nil, fmt.Errorf("failed to convert to 8 bits: %w", err)
+		}
+	}
+
+	pixels, err := img.ToBytes()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read pixels: %w", err)
+	}
+	bands := img.Bands()
+	count := img.Width() * img.Height()
+	if count == 0 || len(pixels) < count*bands {
+		return nil, fmt.Errorf("unexpected pixel data size %d for %dx%d image", len(pixels), img.Width(), img.Height())
+	}
+
+	type bucket struct {
+		pixels  int
+		r, g, b int
+		key     int
+	}
+	buckets := make(map[int]*bucket)
+	shift := 8 - paletteBits
+	for i := range count {
+		pixel := pixels[i*bands : (i+1)*bands]
+		r, g, b := pixel[0], pixel[0], pixel[0]
+		if bands >= 3 {
+			g, b = pixel[1], pixel[2]
+		}
+		key := int(r>>shift)<<(2*paletteBits) | int(g>>shift)<<paletteBits | int(b>>shift)
+		entry, ok := buckets[key]
+		if !ok {
+			entry = &bucket{key: key}
+			buckets[key] = entry
+		}
+		entry.pixels++
+		entry.r += int(r)
+		entry.g += int(g)
+		entry.b += int(b)
+	}
+
+	ranked := make([]*bucket, 0, len(buckets))
+	for _, entry := range buckets {
+		ranked = append(ranked, entry)
+	}
+	// Ties go to the lower key, so the same image always gives the same palette
+	slices.SortFunc(ranked, func(a, b *bucket) int {
+		return cmp.Or(cmp.Compare(b.pixels, a.pixels), cmp.Compare(a.key, b.key))
+	})
+
+	palette := make([]string, 0, PaletteSize)
+	for _, entry := range ranked[:min(len(ranked), PaletteSize)] {
+		palette = append(palette, fmt.Sprintf("#%02x%02x%02x", entry.r/entry.pixels, entry.g/entry.pixels, entry.b/entry.pixels))
+	}
+	return palette, nil
+}
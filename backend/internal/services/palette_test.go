@@ -0,0 +1,47 @@
+package services
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExtractPalette(t *testing.T) {
+	// Mostly blue, with a red band and a thin white stripe
+	src := image.NewRGBA(image.Rect(0, 0, 100, 100))
+	for y := range 100 {
+		for x := range 100 {
+			c := color.RGBA{0, 0, 255, 255}
+			switch {
+			case y < 30:
+				c = color.RGBA{255, 0, 0, 255}
+			case y < 35:
+				c = color.RGBA{255, 255, 255, 255}
+			}
+			src.Set(x, y, c)
+		}
+	}
+	var buf bytes.Buffer
+	require.NoError(t, png.Encode(&buf, src))
+
+	palette, err := extractPalette(buf.Bytes())
+	require.NoError(t, err)
+	require.GreaterOrEqual(t, len(palette), 3)
+	assert.LessOrEqual(t, len(palette), PaletteSize)
+
+	// Shrinking blends the edges between bands a little, so colors are compared loosely
+	for i, want := range []color.RGBA{{0, 0, 255, 255}, {255, 0, 0, 255}, {255, 255, 255, 255}} {
+		var r, g, b uint8
+		_, err := fmt.Sscanf(palette[i], "#%02x%02x%02x", &r, &g, &b)
+		require.NoError(t, err)
+		for _, diff := range []int{int(r) - int(want.R), int(g) - int(want.G), int(b) - int(want.B)} {
+			assert.LessOrEqual(t, max(diff, -diff), 8, "palette[%d] = %s", i, palette[i])
+		}
+	}
+}
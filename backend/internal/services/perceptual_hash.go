@@ -0,0 +1,115 @@
+package services
+
+import (
+	"fmt"
+	"math/bits"
+	"sort"
+	"strconv"
+
+	"github.com/davidbyttow/govips/v2/vips"
+	"github.com/njoubert/nielsshootsfilm/backend/internal/models"
+)
+
+// DefaultDuplicateThreshold is the Hamming distance at or below which two perceptual hashes
+// are considered near-duplicates when no threshold is given.
+const DefaultDuplicateThreshold = 10
+
+// PerceptualHashBits is the number of bits in a perceptual hash, and so the largest distance.
+const PerceptualHashBits = 64
+
+// perceptualHash computes a 64-bit difference hash (dHash) of an image as 16 hex digits.
+// The image is squashed to 9x8 pixels and each bit records whether a pixel is brighter than
+// its right-hand neighbour, so re-encoded, resized, or lightly edited copies hash alike.
+func perceptualHash(imageBytes []byte) (string, error) {
+	img, err := vips.NewThumbnailWithSizeFromBuffer(imageBytes, 9, 8, vips.InterestingNone, vips.SizeForce)
+	if err != nil {
+		return "", fmt.Errorf("failed to shrink image: %w", err)
+	}
+	defer img.Close()
+
+	if err := img.ToColorSpace(vips.InterpretationSRGB); err != nil {
+		return "", fmt.Errorf("failed to convert to sRGB: %w", err)
+	}
+
+	var hash uint64
+	for y := range 8 {
+		previous := -1.0
+		for x := range 9 {
+			point, err := img.GetPoint(x, y)
+			if err != nil {
+				return "", fmt.Errorf("failed to read pixel: %w", err)
+			}
+			brightness := point[0]
+			if len(point) >= 3 {
+				brightness = 0.299*point[0] + 0.587*point[1] + 0.114*point[2]
+			}
+			if x > 0 {
+				hash <<= 1
+				if previous > brightness {
+					hash |= 1
+				}
+			}
+			previous = brightness
+		}
+	}
+
+	return fmt.Sprintf("%016x", hash), nil
+}
+
+// DuplicateClusters groups photos whose perceptual hashes are within threshold bits of each
+// other. Clusters are linked transitively, so A and C share a cluster when both are near B.
+// Photos without a valid hash, and photos with no near-duplicate, are left out. Photos within
+// a cluster, and clusters by their first photo, keep album order.
+func DuplicateClusters(photos []models.Photo, threshold int) [][]models.Photo {
+	hashes := make([]uint64, len(photos))
+	hashed := make([]bool, len(photos))
+	for i := range photos {
+		if hash, err := strconv.ParseUint(photos[i].PerceptualHash, 16, 64); err == nil {
+			hashes[i], hashed[i] = hash, true
+		}
+	}
+
+	// Union-find over every close pair
+	parent := make([]int, len(photos))
+	for i := range parent {
+		parent[i] = i
+	}
+	var find func(int) int
+	find = func(i int) int {
+		if parent[i] != i {
+			parent[i] = find(parent[i])
+		}
+		return parent[i]
+	}
+	for i := range photos {
+		for j := i + 1; j < len(photos); j++ {
+			if hashed[i] && hashed[j] && bits.OnesCount64(hashes[i]^hashes[j]) <= threshold {
+				if ri, rj := find(i), find(j); ri != rj {
+					parent[max(ri, rj)] = min(ri, rj)
+				}
+			}
+		}
+	}
+
+	members := map[int][]models.Photo{}
+	for i := range photos {
+		if hashed[i] {
+			root := find(i)
+			members[root] = append(members[root], photos[i])
+		}
+	}
+
+	roots := make([]int, 0, len(members))
+	for root, cluster := range members {
+		if len(cluster) > 1 {
+			roots = append(roots, root)
+		}
+	}
+	sort.Ints(roots)
+
+	clusters := make([][]models.Photo, 0, len(roots))
+	for _, root := range roots {
+		clusters = append(clusters, members[root])
+	}
+	return clusters
+}
@@ -0,0 +1,113 @@
+package services
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"math"
+	"testing"
+
+	"github.com/njoubert/nielsshootsfilm/backend/internal/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// createSceneImage draws a fixture with soft light and dark regions whose brightness is
+// shifted by brighten. Mirrored scenes swap left and right.
+func createSceneImage(width, height, brighten int, mirrored bool) *image.RGBA {
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+	for y := range height {
+		for x := range width {
+			u, v := float64(x)/float64(width), float64(y)/float64(height)
+			if mirrored {
+				u = 1 - u
+			}
+			level := 128 + 90*math.Sin(u*7)*math.Cos(v*5) + float64(brighten)
+			gray := uint8(max(0, min(255, level)))
+			img.Set(x, y, color.RGBA{R: gray, G: gray, B: gray / 2, A: 255})
+		}
+	}
+	return img
+}
+
+func encodeJPEG(t *testing.T, img image.Image, quality int) []byte {
+	t.Helper()
+
+	var buf bytes.Buffer
+	require.NoError(t, jpeg.Encode(&buf, img, &jpeg.Options{Quality: quality}))
+	return buf.Bytes()
+}
+
+func TestDuplicateClusters_NearIdenticalUploads(t *testing.T) {
+	imageService, err := NewImageService(t.TempDir(), nil, nil)
+	require.NoError(t, err)
+	imageService.SetStorage(NewMemoryStorage())
+
+	var pngCopy bytes.Buffer
+	require.NoError(t, png.Encode(&pngCopy, createSceneImage(320, 240, 0, false)))
+
+	uploads := []struct {
+		filename string
+		data     []byte
+	}{
+		{"frame-1.jpg", encodeJPEG(t, createSceneImage(320, 240, 0, false), 95)},
+		{"other.jpg", encodeJPEG(t, createSceneImage(320, 240, 0, true), 95)},
+		{"frame-1-small.jpg", encodeJPEG(t, createSceneImage(160, 120, 0, false), 60)},
+		{"frame-1-bright.jpg", encodeJPEG(t, createSceneImage(320, 240, 12, false), 80)},
+		{"frame-1.png", pngCopy.Bytes()},
+		{"plain.jpg", createTestJPEG(t, 320, 240)},
+	}
+
+	photos := make([]models.Photo, 0, len(uploads))
+	for _, upload := range uploads {
+		photo, err := imageService.ProcessBytes(upload.filename, upload.data)
+		require.NoError(t, err)
+		require.Len(t, photo.PerceptualHash, 16)
+		photo.ID = upload.filename
+		photos = append(photos, *photo)
+	}
+
+	clusterIDs := func(threshold int) [][]string {
+		ids := [][]string{}
+		for _, cluster := range DuplicateClusters(photos, threshold) {
+			names := []string{}
+			for _, photo := range cluster {
+				names = append(names, photo.ID)
+			}
+			ids = append(ids, names)
+		}
+		return ids
+	}
+
+	// The altered copies cluster with the original; the mirrored scene and the gradient do not
+	assert.Equal(t, [][]string{
+		{"frame-1.jpg", "frame-1-small.jpg", "frame-1-bright.jpg", "frame-1.png"},
+	}, clusterIDs(DefaultDuplicateThreshold))
+
+	// Everything is within the full hash width of everything else
+	assert.Len(t, clusterIDs(PerceptualHashBits), 1)
+	assert.Len(t, clusterIDs(PerceptualHashBits)[0], len(photos))
+}
+
+func TestDuplicateClusters_Transitive(t *testing.T) {
+	photos := []models.Photo{
+		{ID: "a", PerceptualHash: "0000000000000000"},
+		{ID: "unhashed"},
+		{ID: "b", PerceptualHash: "0000000000000007"}, // 3 bits from a
+		{ID: "far", PerceptualHash: "ffffffffffffffff"},
+		{ID: "c", PerceptualHash: "000000000000003f"}, // 3 bits from b, 6 from a
+		{ID: "bad", PerceptualHash: "not-a-hash"},
+	}
+
+	clusters := DuplicateClusters(photos, 3)
+	require.Len(t, clusters, 1)
+	ids := []string{}
+	for _, photo := range clusters[0] {
+		ids = append(ids, photo.ID)
+	}
+	assert.Equal(t, []string{"a", "b", "c"}, ids)
+
+	assert.Empty(t, DuplicateClusters(photos, 2))
+}
@@ -0,0 +1,145 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/davidbyttow/govips/v2/vips"
+	"github.com/njoubert/nielsshootsfilm/backend/internal/models"
+)
+
+// Print export constants.
+const (
+	PrintDPI     = 300  // Resolution print exports are rendered at
+	printQuality = 95   // JPEG quality for print exports
+	mmPerInch    = 25.4 // For vips resolutions, which are in pixels per millimetre
+)
+
+// ErrUnsupportedPrintSize is returned when a print size is not one of the supported sizes.
+var ErrUnsupportedPrintSize = errors.New("unsupported print size")
+
+// printSizes lists the supported print sizes in inches, short side first.
+var printSizes = map[string][2]int{
+	"4x6":   {4, 6},
+	"5x7":   {5, 7},
+	"8x10":  {8, 10},
+	"8x12":  {8, 12},
+	"11x14": {11, 14},
+	"12x18": {12, 18},
+	"16x20": {16, 20},
+	"20x30": {20, 30},
+}
+
+// PrintSizes returns the names of the supported print sizes, e.g. "8x10".
+func PrintSizes() []string {
+	sizes := make([]string, 0, len(printSizes))
+	for size := range printSizes {
+		sizes = append(sizes, size)
+	}
+	sort.Slice(sizes, func(i, j int) bool {
+		a, b := printSizes[sizes[i]], printSizes[sizes[j]]
+		return a[0]*a[1] < b[0]*b[1]
+	})
+	return sizes
+}
+
+// PrintExport is a photo rendered for printing.
+type PrintExport struct {
+	Data         []byte
+	Filename     string // Download filename, with the print size before the extension
+	Width        int    // Pixel width, the print width in inches times PrintDPI
+	Height       int    // Pixel height
+	EffectiveDPI int    // Resolution the original provides at this size
+	Upscaled     bool   // The original has fewer pixels than the print needs
+}
+
+// RenderPrint renders a photo's original at PrintDPI for a standard print size such as "8x10".
+// The print takes the photo's orientation, and the original is centre-cropped to the print's
+// aspect ratio and then scaled to the exact pixel dimensions, upscaling if it is too small.
+// The original is read as it would be downloaded from the album, so albums that scrub GPS
+// data from downloads print without it, and the JPEG records PrintDPI as its resolution.
+// The print is named like the photo's downloads, with the size added, e.g. "harbour-8x10.jpg".
+func (s *ImageService) RenderPrint(album *models.Album, photo *models.Photo, size string) (*PrintExport, error) {
+	inches, ok := printSizes[strings.ToLower(size)]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s (supported: %s)", ErrUnsupportedPrintSize, size, strings.Join(PrintSizes(), ", "))
+	}
+
+	// Acquire semaphore to limit concurrent VIPS operations
+	s.processSem <- struct{}{}
+	defer func() { <-s.processSem }()
+
+	reader, _, err := s.openDownload(album, photo, "original")
+	if err != nil {
+		return nil, fmt.Errorf("failed to read original: %w", err)
+	}
+	original, err := io.ReadAll(reader)
+	_ = reader.Close()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read original: %w", err)
+	}
+
+	img, err := vips.NewImageFromBuffer(original)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load image: %w", err)
+	}
+	defer img.Close()
+
+	if err := img.AutoRotate(); err != nil {
+		return nil, fmt.Errorf("failed to rotate image: %w", err)
+	}
+
+	// Landscape photos print with the long side horizontal
+	widthInches, heightInches := inches[0], inches[1]
+	if img.Width() > img.Height() {
+		widthInches, heightInches = heightInches, widthInches
+	}
+	targetWidth, targetHeight := widthInches*PrintDPI, heightInches*PrintDPI
+
+	// Centre-crop the original to the print aspect ratio
+	cropWidth, cropHeight := img.Width(), img.Height()
+	if cropWidth*targetHeight > cropHeight*targetWidth {
+		cropWidth = cropHeight * targetWidth / targetHeight
+	} else {
+		cropHeight = cropWidth * targetHeight / targetWidth
+	}
+	left, top := (img.Width()-cropWidth)/2, (img.Height()-cropHeight)/2
+	if err := img.ExtractArea(left, top, cropWidth, cropHeight); err != nil {
+		return nil, fmt.Errorf("failed to crop image: %w", err)
+	}
+
+	// Scale each axis separately so rounding in the crop cannot change the output size
+	hScale := float64(targetWidth) / float64(cropWidth)
+	vScale := float64(targetHeight) / float64(cropHeight)
+	if err := img.ResizeWithVScale(hScale, vScale, vips.KernelLanczos3); err != nil {
+		return nil, fmt.Errorf("failed to resize image: %w", err)
+	}
+
+	// Record the print resolution, which vips keeps in pixels per millimetre
+	printImg, err := img.CopyChangingResolution(PrintDPI/mmPerInch, PrintDPI/mmPerInch)
+	if err != nil {
+		return nil, fmt.Errorf("failed to set print resolution: %w", err)
+	}
+	defer printImg.Close()
+	printImg.SetString("resolution-unit", "in") // Written to JFIF and EXIF as dots per inch
+
+	ep := vips.NewJpegExportParams()
+	ep.Quality = printQuality
+	ep.StripMetadata = false
+	data, _, err := printImg.ExportJpeg(ep)
+	if err != nil {
+		return nil, fmt.Errorf("failed to export jpeg: %w", err)
+	}
+
+	return &PrintExport{
+		Data:         data,
+		Filename:     downloadFilename(s.downloadFilenameTemplate(album), album, photo, "-"+strings.ToLower(size)+".jpg"),
+		Width:        img.Width(),
+		Height:       img.Height(),
+		EffectiveDPI: cropWidth / widthInches,
+		Upscaled:     cropWidth < targetWidth,
+	}, nil
+}
@@ -0,0 +1,159 @@
+package services
+
+import (
+	"bytes"
+	"encoding/binary"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"testing"
+
+	"github.com/njoubert/nielsshootsfilm/backend/internal/models"
+	"github.com/rwcarlsen/goexif/exif"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// createBandedJPEG returns a JPEG whose outer left and right quarters are red and whose middle is blue,
+// so a centre crop can be told apart from a squeeze.
+func createBandedJPEG(t *testing.T, width, height int) []byte {
+	t.Helper()
+
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+	for x := 0; x < width; x++ {
+		c := color.RGBA{B: 255, A: 255}
+		if x < width/4 || x >= width*3/4 {
+			c = color.RGBA{R: 255, A: 255}
+		}
+		for y := 0; y < height; y++ {
+			img.Set(x, y, c)
+		}
+	}
+
+	var buf bytes.Buffer
+	require.NoError(t, jpeg.Encode(&buf, img, &jpeg.Options{Quality: 95}))
+	return buf.Bytes()
+}
+
+func setupPrintPhoto(t *testing.T, original []byte) (*ImageService, *models.Photo) {
+	t.Helper()
+
+	imageService, err := NewImageService(t.TempDir(), nil, nil)
+	require.NoError(t, err)
+	imageService.SetStorage(NewMemoryStorage())
+
+	photo, err := imageService.ProcessBytes("print.jpg", original)
+	require.NoError(t, err)
+	return imageService, photo
+}
+
+func decodePrint(t *testing.T, export *PrintExport) image.Image {
+	t.Helper()
+
+	img, err := jpeg.Decode(bytes.NewReader(export.Data))
+	require.NoError(t, err)
+	assert.Equal(t, export.Width, img.Bounds().Dx())
+	assert.Equal(t, export.Height, img.Bounds().Dy())
+	return img
+}
+
+func TestImageService_RenderPrint_Dimensions(t *testing.T) {
+	tests := []struct {
+		name          string
+		width, height int
+		size          string
+		wantW, wantH  int
+		wantUpscaled  bool
+	}{
+		{"portrait 8x10", 2400, 3600, "8x10", 2400, 3000, false},
+		{"landscape 8x10 is rotated", 3600, 2400, "8x10", 3000, 2400, false},
+		{"landscape 8x12 exact fit", 3600, 2400, "8x12", 3600, 2400, false},
+		{"small original is upscaled", 1200, 800, "4x6", 1800, 1200, true},
+		{"size names are case-insensitive", 2400, 3600, "5X7", 1500, 2100, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			imageService, photo := setupPrintPhoto(t, createTestJPEG(t, tt.width, tt.height))
+
+			export, err := imageService.RenderPrint(&models.Album{}, photo, tt.size)
+			require.NoError(t, err)
+			assert.Equal(t, tt.wantW, export.Width)
+			assert.Equal(t, tt.wantH, export.Height)
+			assert.Equal(t, tt.wantUpscaled, export.Upscaled)
+			decodePrint(t, export)
+		})
+	}
+}
+
+func TestImageService_RenderPrint_CropsToAspect(t *testing.T) {
+	// A 2:1 landscape original printed at 5:4 loses its outer red bands to the centre crop
+	imageService, photo := setupPrintPhoto(t, createBandedJPEG(t, 4000, 2000))
+
+	export, err := imageService.RenderPrint(&models.Album{}, photo, "8x10")
+	require.NoError(t, err)
+	assert.Equal(t, 3000, export.Width)
+	assert.Equal(t, 2400, export.Height)
+	assert.True(t, export.Upscaled, "the 2500px crop is stretched to 3000px")
+	assert.Equal(t, 250, export.EffectiveDPI, "2500 cropped pixels across 10 inches")
+
+	img := decodePrint(t, export)
+
+	// The crop keeps source x 750..3250, so blue covers 300..2700 of the output and red only the edges
+	for _, x := range []int{400, 1500, 2600} {
+		r, _, b, _ := img.At(x, 1200).RGBA()
+		assert.Greater(t, b, r, "x=%d should be blue", x)
+	}
+	for _, x := range []int{50, 2950} {
+		r, _, b, _ := img.At(x, 1200).RGBA()
+		assert.Greater(t, r, b, "x=%d should be red", x)
+	}
+}
+
+func TestImageService_RenderPrint_UnsupportedSize(t *testing.T) {
+	imageService, photo := setupPrintPhoto(t, createTestJPEG(t, 600, 400))
+
+	_, err := imageService.RenderPrint(&models.Album{}, photo, "9x9")
+	assert.ErrorIs(t, err, ErrUnsupportedPrintSize)
+	assert.Contains(t, err.Error(), "8x10")
+}
+
+func TestImageService_RenderPrint_Filename(t *testing.T) {
+	imageService, photo := setupPrintPhoto(t, createTestJPEG(t, 600, 400))
+
+	export, err := imageService.RenderPrint(&models.Album{}, photo, "8X10")
+	require.NoError(t, err)
+	assert.Equal(t, "print-8x10.jpg", export.Filename)
+
+	// Prints follow the album's download filename template like other downloads
+	album := &models.Album{Slug: "coastline", DownloadFilename: "{album}-{index}", Photos: []models.Photo{*photo}}
+	export, err = imageService.RenderPrint(album, photo, "8x10")
+	require.NoError(t, err)
+	assert.Equal(t, "coastline-1-8x10.jpg", export.Filename)
+}
+
+func TestImageService_RenderPrint_ScrubsGPSAndRecordsDPI(t *testing.T) {
+	imageService, photo := setupPrintPhoto(t, createGPSJPEG(t))
+	album := &models.Album{ScrubGPSOnDownload: true, Photos: []models.Photo{*photo}}
+
+	export, err := imageService.RenderPrint(album, photo, "4x6")
+	require.NoError(t, err)
+	assertGPSScrubbed(t, export.Data)
+
+	// The JFIF header records the print resolution in dots per inch
+	at := bytes.Index(export.Data, []byte("JFIF\x00"))
+	require.GreaterOrEqual(t, at, 0)
+	density := export.Data[at+7:]
+	assert.Equal(t, byte(1), density[0], "density units should be dots per inch")
+	assert.Equal(t, PrintDPI, int(binary.BigEndian.Uint16(density[1:])))
+	assert.Equal(t, PrintDPI, int(binary.BigEndian.Uint16(density[3:])))
+
+	// Albums that do not scrub keep the position
+	album.ScrubGPSOnDownload = false
+	export, err = imageService.RenderPrint(album, photo, "4x6")
+	require.NoError(t, err)
+	x, err := exif.Decode(bytes.NewReader(export.Data))
+	require.NoError(t, err)
+	_, err = x.Get(exif.GPSLatitude)
+	assert.NoError(t, err)
+}
@@ -0,0 +1,186 @@
+package services
+
+import (
+	"errors"
+	"log/slog"
+	"slices"
+	"sync"
+
+	"github.com/njoubert/nielsshootsfilm/backend/internal/models"
+)
+
+// RegenerateJobType identifies derivative regeneration jobs.
+const RegenerateJobType = "regenerate_derivatives"
+
+// RegenerateThumbnailsJobType identifies thumbnail regeneration jobs.
+const RegenerateThumbnailsJobType = "regenerate_thumbnails"
+
+// RegenerateDisplaysJobType identifies display version regeneration jobs.
+const RegenerateDisplaysJobType = "regenerate_displays"
+
+// RegenerateService rebuilds display and thumbnail versions from originals in the background.
+type RegenerateService struct {
+	albumService *AlbumService
+	imageService *ImageService
+	jobService   *JobService
+	workers      int
+	logger       *slog.Logger
+}
+
+// NewRegenerateService creates a new regenerate service.
+func NewRegenerateService(albumService *AlbumService, imageService *ImageService, jobService *JobService, logger *slog.Logger) *RegenerateService {
+	return &RegenerateService{
+		albumService: albumService,
+		imageService: imageService,
+		jobService:   jobService,
+		workers:      maxConcurrentVIPSOps,
+		logger:       logger,
+	}
+}
+
+// regenerateTask is one photo to rebuild.
+type regenerateTask struct {
+	album *models.Album
+	photo models.Photo
+}
+
+// regenerateResult is the outcome of rebuilding one photo.
+type regenerateResult struct {
+	albumID string
+	photo   models.Photo
+	err     error
+}
+
+// Start begins regenerating derivatives for one album, or every album if albumID is empty,
+// and returns the job tracking its progress.
+func (s *RegenerateService) Start(albumID string) (models.Job, error) {
+	albums, err := s.albums(albumID)
+	if err != nil {
+		return models.Job{}, err
+	}
+
+	tasks := regenerateTasks(albums)
+	job := s.jobService.Create(RegenerateJobType, len(tasks))
+	go s.run(job.ID, tasks, s.imageService.RegenerateDerivatives)
+	return job, nil
+}
+
+// StartThumbnails begins re-rendering thumbnails after a thumbnail fit change, for one album,
+// or if albumID is empty every album that follows the site's thumbnail fit, and returns the
+// job tracking its progress.
+func (s *RegenerateService) StartThumbnails(albumID string) (models.Job, error) {
+	albums, err := s.albums(albumID)
+	if err != nil {
+		return models.Job{}, err
+	}
+	if albumID == "" {
+		albums = slices.DeleteFunc(albums, func(album models.Album) bool { return album.ThumbnailFit != "" })
+	}
+
+	tasks := regenerateTasks(albums)
+	job := s.jobService.Create(RegenerateThumbnailsJobType, len(tasks))
+	go s.run(job.ID, tasks, s.imageService.RenderThumbnail)
+	return job, nil
+}
+
+// StartDisplays begins re-rendering one album's display versions after its display size
+// changed, and returns the job tracking its progress.
+func (s *RegenerateService) StartDisplays(albumID string) (models.Job, error) {
+	albums, err := s.albums(albumID)
+	if err != nil {
+		return models.Job{}, err
+	}
+
+	tasks := regenerateTasks(albums)
+	job := s.jobService.Create(RegenerateDisplaysJobType, len(tasks))
+	go s.run(job.ID, tasks, s.imageService.RenderDisplay)
+	return job, nil
+}
+
+// albums returns the album with the given ID, or every album if albumID is empty.
+func (s *RegenerateService) albums(albumID string) ([]models.Album, error) {
+	if albumID == "" {
+		return s.albumService.GetAll()
+	}
+	album, err := s.albumService.GetByID(albumID)
+	if err != nil {
+		return nil, err
+	}
+	return []models.Album{*album}, nil
+}
+
+// regenerateTasks returns a task for every photo in the albums.
+func regenerateTasks(albums []models.Album) []regenerateTask {
+	tasks := []regenerateTask{}
+	for i := range albums {
+		for _, photo := range albums[i].Photos {
+			tasks = append(tasks, regenerateTask{album: &albums[i], photo: photo})
+		}
+	}
+	return tasks
+}
+
+// run processes tasks on a pool of workers, rebuilding each photo with render, and records
+// results as they complete. Album writes happen on this goroutine only, so workers never race
+// on albums.json.
+func (s *RegenerateService) run(jobID string, tasks []regenerateTask, render func(*models.Album, models.Photo) (models.Photo, error)) {
+	taskCh := make(chan regenerateTask)
+	resultCh := make(chan regenerateResult)
+
+	var wg sync.WaitGroup
+	for i := 0; i < s.workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for task := range taskCh {
+				photo, err := render(task.album, task.photo)
+				resultCh <- regenerateResult{albumID: task.album.ID, photo: photo, err: err}
+			}
+		}()
+	}
+
+	go func() {
+		for _, task := range tasks {
+			taskCh <- task
+		}
+		close(taskCh)
+		wg.Wait()
+		close(resultCh)
+	}()
+
+	for result := range resultCh {
+		item := models.JobItem{
+			AlbumID:  result.albumID,
+			PhotoID:  result.photo.ID,
+			Filename: result.photo.FilenameOriginal,
+		}
+
+		err := result.err
+		if err == nil {
+			err = s.albumService.UpdateRegeneratedPhoto(result.albumID, result.photo)
+		}
+
+		s.jobService.Update(jobID, func(job *models.Job) {
+			job.Processed++
+			switch {
+			case errors.Is(err, ErrObjectNotFound):
+				item.Reason = "original not found"
+				job.Skipped = append(job.Skipped, item)
+			case err != nil:
+				item.Reason = err.Error()
+				job.Errors = append(job.Errors, item)
+			}
+		})
+
+		if err != nil && s.logger != nil {
+			s.logger.Warn("failed to regenerate derivatives",
+				slog.String("job_id", jobID),
+				slog.String("album_id", result.albumID),
+				slog.String("photo_id", result.photo.ID),
+				slog.String("error", err.Error()),
+			)
+		}
+	}
+
+	s.jobService.Finish(jobID, nil)
+}
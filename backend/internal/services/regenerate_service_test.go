@@ -0,0 +1,195 @@
+package services
+
+import (
+	"io"
+	"log/slog"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/njoubert/nielsshootsfilm/backend/internal/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// waitForJob polls until a job leaves the running state and returns it.
+func waitForJob(t *testing.T, jobService *JobService, id string) *models.Job {
+	t.Helper()
+
+	var job *models.Job
+	require.Eventually(t, func() bool {
+		var err error
+		job, err = jobService.Get(id)
+		require.NoError(t, err)
+		return job.Status != models.JobStatusRunning
+	}, 10*time.Second, 10*time.Millisecond)
+	return job
+}
+
+func TestRegenerateService_Start(t *testing.T) {
+	albumService, _ := setupAlbumService(t)
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+
+	imageService, err := NewImageService(t.TempDir(), nil, logger)
+	require.NoError(t, err)
+	storage := NewMemoryStorage()
+	imageService.SetStorage(storage)
+
+	album := &models.Album{Title: "Roll One", Visibility: "public"}
+	require.NoError(t, albumService.Create(album))
+
+	kept, err := imageService.ProcessBytes("kept.jpg", createTestJPEG(t, 1000, 600))
+	require.NoError(t, err)
+	require.NoError(t, albumService.AddPhoto(album.ID, kept))
+
+	orphan, err := imageService.ProcessBytes("orphan.jpg", createTestJPEG(t, 400, 300))
+	require.NoError(t, err)
+	require.NoError(t, albumService.AddPhoto(album.ID, orphan))
+
+	// Simulate stale derivatives for one photo and a lost original for the other
+	displayKey := strings.TrimPrefix(kept.URLDisplay, "/uploads/")
+	thumbnailKey := strings.TrimPrefix(kept.URLThumbnail, "/uploads/")
+	require.NoError(t, storage.Put(displayKey, strings.NewReader("stale"), 5))
+	require.NoError(t, storage.Delete(thumbnailKey))
+	require.NoError(t, storage.Delete(strings.TrimPrefix(orphan.URLOriginal, "/uploads/")))
+
+	jobService := NewJobService()
+	regenerateService := NewRegenerateService(albumService, imageService, jobService, logger)
+
+	job, err := regenerateService.Start(album.ID)
+	require.NoError(t, err)
+	assert.Equal(t, RegenerateJobType, job.Type)
+	assert.Equal(t, 2, job.Total)
+
+	finished := waitForJob(t, jobService, job.ID)
+	assert.Equal(t, models.JobStatusCompleted, finished.Status)
+	assert.Equal(t, 2, finished.Processed)
+	assert.Empty(t, finished.Errors)
+	require.Len(t, finished.Skipped, 1)
+	assert.Equal(t, orphan.ID, finished.Skipped[0].PhotoID)
+	assert.Equal(t, "orphan.jpg", finished.Skipped[0].Filename)
+	assert.Equal(t, "original not found", finished.Skipped[0].Reason)
+	assert.NotNil(t, finished.FinishedAt)
+
+	// Derivatives were recreated from the original and sizes recorded on the album
+	display, err := storage.Get(displayKey)
+	require.NoError(t, err)
+	assert.NotEqual(t, "stale", string(display))
+	thumbnail, err := storage.Get(thumbnailKey)
+	require.NoError(t, err)
+	assert.NotEmpty(t, thumbnail)
+
+	updated, err := albumService.GetByID(album.ID)
+	require.NoError(t, err)
+	assert.Equal(t, int64(len(display)), updated.Photos[0].FileSizeDisplay)
+	assert.Equal(t, int64(len(thumbnail)), updated.Photos[0].FileSizeThumbnail)
+}
+
+func TestRegenerateService_StartThumbnails(t *testing.T) {
+	albumService, _ := setupAlbumService(t)
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+
+	imageService, err := NewImageService(t.TempDir(), nil, logger)
+	require.NoError(t, err)
+	imageService.SetStorage(NewMemoryStorage())
+
+	following := &models.Album{Title: "Follows Site", Visibility: "public"}
+	require.NoError(t, albumService.Create(following))
+	squared := &models.Album{Title: "Squared", Visibility: "public", ThumbnailFit: models.ThumbnailFitCover}
+	require.NoError(t, albumService.Create(squared))
+	for _, album := range []*models.Album{following, squared} {
+		photo, err := imageService.ProcessBytes("frame.jpg", createTestJPEG(t, 1000, 600))
+		require.NoError(t, err)
+		require.NoError(t, albumService.AddPhoto(album.ID, photo))
+	}
+
+	jobService := NewJobService()
+	regenerateService := NewRegenerateService(albumService, imageService, jobService, logger)
+
+	// One album's thumbnails pick up its own fit
+	job, err := regenerateService.StartThumbnails(squared.ID)
+	require.NoError(t, err)
+	assert.Equal(t, RegenerateThumbnailsJobType, job.Type)
+	assert.Equal(t, 1, job.Total)
+	finished := waitForJob(t, jobService, job.ID)
+	assert.Equal(t, models.JobStatusCompleted, finished.Status)
+	assert.Empty(t, finished.Errors)
+
+	stored, err := albumService.GetByID(squared.ID)
+	require.NoError(t, err)
+	width, height := thumbnailSize(t, imageService, stored.Photos[0])
+	assert.Equal(t, 600, width)
+	assert.Equal(t, 600, height)
+	thumbnail, err := imageService.Storage().Get(strings.TrimPrefix(stored.Photos[0].URLThumbnail, "/uploads/"))
+	require.NoError(t, err)
+	assert.Equal(t, int64(len(thumbnail)), stored.Photos[0].FileSizeThumbnail)
+
+	// A site-wide change skips albums with their own fit
+	job, err = regenerateService.StartThumbnails("")
+	require.NoError(t, err)
+	assert.Equal(t, 1, job.Total)
+	finished = waitForJob(t, jobService, job.ID)
+	assert.Equal(t, models.JobStatusCompleted, finished.Status)
+	assert.Empty(t, finished.Errors)
+}
+
+func TestRegenerateService_StartDisplays(t *testing.T) {
+	albumService, _ := setupAlbumService(t)
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+
+	imageService, err := NewImageService(t.TempDir(), nil, logger)
+	require.NoError(t, err)
+	imageService.SetStorage(NewMemoryStorage())
+
+	album := &models.Album{Title: "Small Screens", Visibility: "public", DisplayMaxEdge: 800}
+	require.NoError(t, albumService.Create(album))
+	photo, err := imageService.ProcessBytes("frame.jpg", createTestJPEG(t, 1000, 600))
+	require.NoError(t, err)
+	require.NoError(t, albumService.AddPhoto(album.ID, photo))
+
+	jobService := NewJobService()
+	regenerateService := NewRegenerateService(albumService, imageService, jobService, logger)
+
+	job, err := regenerateService.StartDisplays(album.ID)
+	require.NoError(t, err)
+	assert.Equal(t, RegenerateDisplaysJobType, job.Type)
+	assert.Equal(t, 1, job.Total)
+	finished := waitForJob(t, jobService, job.ID)
+	assert.Equal(t, models.JobStatusCompleted, finished.Status)
+	assert.Empty(t, finished.Errors)
+
+	stored, err := albumService.GetByID(album.ID)
+	require.NoError(t, err)
+	width, height := displaySize(t, imageService, stored.Photos[0])
+	assert.Equal(t, 800, width)
+	assert.Equal(t, 480, height)
+	display, err := imageService.Storage().Get(strings.TrimPrefix(stored.Photos[0].URLDisplay, "/uploads/"))
+	require.NoError(t, err)
+	assert.Equal(t, int64(len(display)), stored.Photos[0].FileSizeDisplay)
+}
+
+func TestRegenerateService_Start_UnknownAlbum(t *testing.T) {
+	albumService, _ := setupAlbumService(t)
+	imageService, err := NewImageService(t.TempDir(), nil, nil)
+	require.NoError(t, err)
+
+	regenerateService := NewRegenerateService(albumService, imageService, NewJobService(), nil)
+	_, err = regenerateService.Start("missing")
+	assert.EqualError(t, err, "album not found")
+}
+
+func TestJobService_GetReturnsSnapshot(t *testing.T) {
+	jobService := NewJobService()
+	job := jobService.Create("test", 1)
+
+	snapshot, err := jobService.Get(job.ID)
+	require.NoError(t, err)
+	snapshot.Skipped = append(snapshot.Skipped, models.JobItem{PhotoID: "x"})
+
+	fresh, err := jobService.Get(job.ID)
+	require.NoError(t, err)
+	assert.Empty(t, fresh.Skipped, "mutating a snapshot must not change the stored job")
+
+	_, err = jobService.Get("missing")
+	assert.Error(t, err)
+}
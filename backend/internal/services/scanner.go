@@ -0,0 +1,105 @@
+package services
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strings"
+	"time"
+)
+
+// ErrInfected is returned for uploads a malware scanner flags. Errors wrapping it name the
+// signature that matched.
+var ErrInfected = errors.New("file is infected")
+
+// Scanner checks uploaded files for malware before they are stored.
+type Scanner interface {
+	// Scan reads a whole file, returning an error wrapping ErrInfected if it contains
+	// malware, or another error if it could not be scanned.
+	Scan(r io.Reader) error
+}
+
+// DefaultScanTimeout bounds how long one malware scan may take.
+const DefaultScanTimeout = 30 * time.Second
+
+// clamdChunkSize is how much of a file is sent to clamd per INSTREAM chunk.
+const clamdChunkSize = 64 << 10
+
+// ClamAVScanner scans files with a clamd daemon, streaming them over its INSTREAM command.
+type ClamAVScanner struct {
+	network string
+	address string
+	timeout time.Duration
+}
+
+// NewClamAVScanner creates a scanner for the clamd daemon at address: a Unix socket path
+// such as /run/clamav/clamd.ctl, or host:port for TCP. Each scan must finish within timeout.
+func NewClamAVScanner(address string, timeout time.Duration) (*ClamAVScanner, error) {
+	if address == "" {
+		return nil, errors.New("clamd address is required")
+	}
+	network := "tcp"
+	if strings.HasPrefix(address, "/") {
+		network = "unix"
+	}
+	return &ClamAVScanner{network: network, address: address, timeout: timeout}, nil
+}
+
+// Scan streams a file to clamd and reports what it found.
+func (c *ClamAVScanner) Scan(r io.Reader) error {
+	conn, err := net.DialTimeout(c.network, c.address, c.timeout)
+	if err != nil {
+		return fmt.Errorf("failed to connect to clamd: %w", err)
+	}
+	defer func() { _ = conn.Close() }()
+	if err := conn.SetDeadline(time.Now().Add(c.timeout)); err != nil {
+		return fmt.Errorf("failed to set clamd deadline: %w", err)
+	}
+
+	// Null-terminated command, then length-prefixed chunks ending with an empty one
+	if _, err := conn.Write([]byte("zINSTREAM\x00")); err != nil {
+		return fmt.Errorf("failed to send to clamd: %w", err)
+	}
+	buf := make([]byte, 4+clamdChunkSize)
+	for {
+		n, readErr := io.ReadFull(r, buf[4:])
+		if n > 0 {
+			binary.BigEndian.PutUint32(buf, uint32(n)) // #nosec G115 -- n is at most clamdChunkSize
+			if _, err := conn.Write(buf[:4+n]); err != nil {
+				return fmt.Errorf("failed to send to clamd: %w", err)
+			}
+		}
+		if errors.Is(readErr, io.EOF) || errors.Is(readErr, io.ErrUnexpectedEOF) {
+			break
+		}
+		if readErr != nil {
+			return fmt.Errorf("failed to read file for scanning: %w", readErr)
+		}
+	}
+	if _, err := conn.Write([]byte{0, 0, 0, 0}); err != nil {
+		return fmt.Errorf("failed to send to clamd: %w", err)
+	}
+
+	reply, err := bufio.NewReader(conn).ReadBytes(0)
+	if err != nil && !errors.Is(err, io.EOF) {
+		return fmt.Errorf("failed to read clamd reply: %w", err)
+	}
+	return parseClamdReply(string(bytes.TrimRight(reply, "\x00\n")))
+}
+
+// parseClamdReply interprets a clamd INSTREAM reply such as "stream: OK" or
+// "stream: Eicar-Test-Signature FOUND".
+func parseClamdReply(reply string) error {
+	result := strings.TrimPrefix(reply, "stream: ")
+	switch {
+	case result == "OK":
+		return nil
+	case strings.HasSuffix(result, " FOUND"):
+		return fmt.Errorf("%w: %s", ErrInfected, strings.TrimSuffix(result, " FOUND"))
+	}
+	return fmt.Errorf("clamd scan failed: %s", reply)
+}
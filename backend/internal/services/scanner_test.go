@@ -0,0 +1,160 @@
+package services
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// eicar is the industry-standard antivirus test string, which scanners detect as malware.
+const eicar = `X5O!P%@AP[4\PZX54(P^)7CC)7}$EICAR-STANDARD-ANTIVIRUS-TEST-FILE!$H+H*`
+
+// mockScanner flags files containing the EICAR test string, and counts the files it sees.
+type mockScanner struct {
+	scanned atomic.Int32
+	err     error // Returned for every file if set, as if the scanner were down
+}
+
+func (m *mockScanner) Scan(r io.Reader) error {
+	m.scanned.Add(1)
+	if m.err != nil {
+		return m.err
+	}
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return err
+	}
+	if bytes.Contains(data, []byte(eicar)) {
+		return fmt.Errorf("%w: Eicar-Test-Signature", ErrInfected)
+	}
+	return nil
+}
+
+func TestImageService_ScansUploads(t *testing.T) {
+	imageService, err := NewImageService(t.TempDir(), nil, nil)
+	require.NoError(t, err)
+	storage := &countingStorage{Storage: NewMemoryStorage()}
+	imageService.SetStorage(storage)
+	scanner := &mockScanner{}
+	imageService.SetScanner(scanner)
+
+	// Clean photos are stored as usual
+	photo, err := imageService.ProcessBytes("clean.jpg", createTestJPEG(t, 64, 48))
+	require.NoError(t, err)
+	assert.NotEmpty(t, photo.URLOriginal)
+	assert.Equal(t, int32(1), scanner.scanned.Load())
+	stored := storage.puts.Load()
+
+	// A valid image carrying the test string after its end is rejected, and nothing is stored
+	infected := append(createTestJPEG(t, 64, 48), eicar...)
+	_, err = imageService.ProcessBytes("infected.jpg", infected)
+	assert.ErrorIs(t, err, ErrInfected)
+	assert.Equal(t, stored, storage.puts.Load())
+
+	// So is a file that is not an image at all, before its type is checked
+	_, err = imageService.ProcessBytes("eicar.jpg", []byte(eicar))
+	assert.ErrorIs(t, err, ErrInfected)
+
+	// Files that cannot be scanned are rejected too
+	scanner.err = errors.New("connection refused")
+	_, err = imageService.ProcessBytes("clean.jpg", createTestJPEG(t, 64, 48))
+	assert.ErrorContains(t, err, "failed to scan file for malware: connection refused")
+	assert.NotErrorIs(t, err, ErrInfected)
+	assert.Equal(t, stored, storage.puts.Load())
+
+	// Without a scanner nothing is scanned
+	imageService.SetScanner(nil)
+	_, err = imageService.ProcessBytes("clean.jpg", createTestJPEG(t, 64, 48))
+	require.NoError(t, err)
+	assert.Equal(t, int32(4), scanner.scanned.Load())
+}
+
+// fakeClamd answers INSTREAM requests like clamd, flagging streams containing the EICAR test
+// string. It returns the address it listens on.
+func fakeClamd(t *testing.T) string {
+	t.Helper()
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = listener.Close() })
+
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer func() { _ = conn.Close() }()
+				reader := bufio.NewReader(conn)
+				command, err := reader.ReadString(0)
+				if err != nil || command != "zINSTREAM\x00" {
+					_, _ = conn.Write([]byte("UNKNOWN COMMAND\x00"))
+					return
+				}
+				var data bytes.Buffer
+				for {
+					var size uint32
+					if err := binary.Read(reader, binary.BigEndian, &size); err != nil {
+						return
+					}
+					if size == 0 {
+						break
+					}
+					if _, err := io.CopyN(&data, reader, int64(size)); err != nil {
+						return
+					}
+				}
+				reply := "stream: OK\x00"
+				if strings.Contains(data.String(), eicar) {
+					reply = "stream: Eicar-Test-Signature FOUND\x00"
+				}
+				_, _ = conn.Write([]byte(reply))
+			}()
+		}
+	}()
+
+	return listener.Addr().String()
+}
+
+func TestClamAVScanner(t *testing.T) {
+	scanner, err := NewClamAVScanner(fakeClamd(t), time.Second)
+	require.NoError(t, err)
+
+	assert.NoError(t, scanner.Scan(bytes.NewReader(createTestJPEG(t, 64, 48))))
+
+	// Files larger than one chunk are streamed whole, so a signature in a later chunk is found
+	large := append(bytes.Repeat([]byte{0xff}, clamdChunkSize*2+100), eicar...)
+	err = scanner.Scan(bytes.NewReader(large))
+	assert.ErrorIs(t, err, ErrInfected)
+	assert.EqualError(t, err, "file is infected: Eicar-Test-Signature")
+
+	assert.NoError(t, scanner.Scan(bytes.NewReader(nil)))
+
+	// An unreachable daemon is an error, but not an infection
+	unreachable, err := NewClamAVScanner("127.0.0.1:1", 100*time.Millisecond)
+	require.NoError(t, err)
+	err = unreachable.Scan(bytes.NewReader([]byte("photo")))
+	assert.ErrorContains(t, err, "failed to connect to clamd")
+	assert.NotErrorIs(t, err, ErrInfected)
+
+	_, err = NewClamAVScanner("", time.Second)
+	assert.Error(t, err)
+}
+
+func TestParseClamdReply(t *testing.T) {
+	assert.NoError(t, parseClamdReply("stream: OK"))
+	assert.ErrorIs(t, parseClamdReply("stream: Win.Test.EICAR_HDB-1 FOUND"), ErrInfected)
+	assert.EqualError(t, parseClamdReply("INSTREAM size limit exceeded. ERROR"), "clamd scan failed: INSTREAM size limit exceeded. ERROR")
+}
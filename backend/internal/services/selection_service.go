@@ -0,0 +1,154 @@
+package services
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"github.com/google/uuid"
+	"github.com/njoubert/nielsshootsfilm/backend/internal/models"
+)
+
+const selectionsFile = "selections.json"
+
+// SelectionTokenPrefix starts every selection share token.
+const SelectionTokenPrefix = "sel_"
+
+// ErrSelectionNotFound is returned for unknown selection tokens.
+var ErrSelectionNotFound = errors.New("selection not found")
+
+// ErrInvalidSelection is returned, wrapped with the reason, for selections that cannot be made.
+var ErrInvalidSelection = errors.New("invalid selection")
+
+// SelectionService stores the photo selections clients share back from albums.
+type SelectionService struct {
+	fileService *FileService
+	mu          sync.Mutex // Serializes read-modify-write cycles of the file
+}
+
+// NewSelectionService creates a new selection service.
+func NewSelectionService(fileService *FileService) *SelectionService {
+	return &SelectionService{
+		fileService: fileService,
+	}
+}
+
+// Create saves a named selection of an album's photos and gives it a share token. Every
+// photo ID must belong to the album; repeated IDs count once, and the selection keeps the
+// photos in album order.
+func (s *SelectionService) Create(album *models.Album, name string, photoIDs []string) (*models.Selection, error) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return nil, fmt.Errorf("%w: name is required", ErrInvalidSelection)
+	}
+	if utf8.RuneCountInString(name) > models.MaxSelectionNameLength {
+		return nil, fmt.Errorf("%w: name is longer than %d characters", ErrInvalidSelection, models.MaxSelectionNameLength)
+	}
+	if len(photoIDs) == 0 {
+		return nil, fmt.Errorf("%w: at least one photo is required", ErrInvalidSelection)
+	}
+
+	inAlbum := make(map[string]bool, len(album.Photos))
+	for _, photo := range album.Photos {
+		inAlbum[photo.ID] = true
+	}
+	selected := make(map[string]bool, len(photoIDs))
+	for _, id := range photoIDs {
+		if !inAlbum[id] {
+			return nil, fmt.Errorf("%w: photo %s is not in the album", ErrInvalidSelection, id)
+		}
+		selected[id] = true
+	}
+	ordered := make([]string, 0, len(selected))
+	for _, photo := range album.Photos {
+		if selected[photo.ID] {
+			ordered = append(ordered, photo.ID)
+		}
+	}
+
+	b := make([]byte, 24)
+	if _, err := rand.Read(b); err != nil {
+		return nil, fmt.Errorf("failed to generate selection token: %w", err)
+	}
+	selection := models.Selection{
+		ID:        uuid.New().String(),
+		Token:     SelectionTokenPrefix + base64.RawURLEncoding.EncodeToString(b),
+		AlbumID:   album.ID,
+		Name:      name,
+		PhotoIDs:  ordered,
+		CreatedAt: time.Now().UTC(),
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	selections, err := s.list()
+	if err != nil {
+		return nil, err
+	}
+	selections = append(selections, selection)
+	if err := s.fileService.WriteJSON(selectionsFile, &models.SelectionCollection{Selections: selections}); err != nil {
+		return nil, fmt.Errorf("failed to write selections: %w", err)
+	}
+	return &selection, nil
+}
+
+// GetByToken returns the selection with a share token, or ErrSelectionNotFound.
+func (s *SelectionService) GetByToken(token string) (*models.Selection, error) {
+	if !strings.HasPrefix(token, SelectionTokenPrefix) {
+		return nil, ErrSelectionNotFound
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	selections, err := s.list()
+	if err != nil {
+		return nil, err
+	}
+	for i := range selections {
+		if selections[i].Token == token {
+			return &selections[i], nil
+		}
+	}
+	return nil, ErrSelectionNotFound
+}
+
+// ListByAlbum returns an album's selections, oldest first.
+func (s *SelectionService) ListByAlbum(albumID string) ([]models.Selection, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	selections, err := s.list()
+	if err != nil {
+		return nil, err
+	}
+	own := []models.Selection{}
+	for _, selection := range selections {
+		if selection.AlbumID == albumID {
+			own = append(own, selection)
+		}
+	}
+	return own, nil
+}
+
+// list reads every stored selection. Callers must hold mu.
+func (s *SelectionService) list() ([]models.Selection, error) {
+	if !s.fileService.FileExists(selectionsFile) {
+		return []models.Selection{}, nil
+	}
+
+	var collection models.SelectionCollection
+	if err := s.fileService.ReadJSON(selectionsFile, &collection); err != nil {
+		return nil, fmt.Errorf("failed to read selections: %w", err)
+	}
+	if collection.Selections == nil {
+		collection.Selections = []models.Selection{}
+	}
+	return collection.Selections, nil
+}
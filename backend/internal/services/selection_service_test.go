@@ -0,0 +1,78 @@
+package services
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/njoubert/nielsshootsfilm/backend/internal/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSelectionService_CreateAndGet(t *testing.T) {
+	fileService, err := NewFileService(t.TempDir())
+	require.NoError(t, err)
+	service := NewSelectionService(fileService)
+
+	album := &models.Album{ID: "wedding", Photos: []models.Photo{{ID: "p1"}, {ID: "p2"}, {ID: "p3"}}}
+
+	selection, err := service.Create(album, "  Selects  ", []string{"p3", "p1", "p3"})
+	require.NoError(t, err)
+	assert.Equal(t, "Selects", selection.Name)
+	assert.Equal(t, "wedding", selection.AlbumID)
+	assert.Equal(t, []string{"p1", "p3"}, selection.PhotoIDs, "album order, repeats once")
+	assert.True(t, strings.HasPrefix(selection.Token, SelectionTokenPrefix))
+
+	found, err := service.GetByToken(selection.Token)
+	require.NoError(t, err)
+	assert.Equal(t, selection.ID, found.ID)
+	assert.Equal(t, []string{"p1", "p3"}, found.PhotoIDs)
+
+	for _, token := range []string{"", "sel_unknown", selection.Token + "x", strings.TrimPrefix(selection.Token, SelectionTokenPrefix)} {
+		_, err := service.GetByToken(token)
+		assert.ErrorIs(t, err, ErrSelectionNotFound, token)
+	}
+
+	other, err := service.Create(album, "Second round", []string{"p2"})
+	require.NoError(t, err)
+	assert.NotEqual(t, selection.Token, other.Token)
+	_, err = service.Create(&models.Album{ID: "portraits", Photos: []models.Photo{{ID: "q1"}}}, "Mine", []string{"q1"})
+	require.NoError(t, err)
+
+	selections, err := service.ListByAlbum("wedding")
+	require.NoError(t, err)
+	require.Len(t, selections, 2)
+	assert.Equal(t, "Selects", selections[0].Name)
+	assert.Equal(t, "Second round", selections[1].Name)
+
+	selections, err = service.ListByAlbum("missing")
+	require.NoError(t, err)
+	assert.Empty(t, selections)
+}
+
+func TestSelectionService_CreateRejectsInvalid(t *testing.T) {
+	fileService, err := NewFileService(t.TempDir())
+	require.NoError(t, err)
+	service := NewSelectionService(fileService)
+
+	album := &models.Album{ID: "wedding", Photos: []models.Photo{{ID: "p1"}}}
+
+	for name, tc := range map[string]struct {
+		name     string
+		photoIDs []string
+		want     string
+	}{
+		"blank name":         {"  ", []string{"p1"}, "name is required"},
+		"long name":          {strings.Repeat("x", models.MaxSelectionNameLength+1), []string{"p1"}, "longer than"},
+		"no photos":          {"Selects", nil, "at least one photo"},
+		"photo not in album": {"Selects", []string{"p1", "elsewhere"}, "photo elsewhere is not in the album"},
+	} {
+		_, err := service.Create(album, tc.name, tc.photoIDs)
+		assert.ErrorIs(t, err, ErrInvalidSelection, name)
+		assert.ErrorContains(t, err, tc.want, name)
+	}
+
+	selections, err := service.ListByAlbum("wedding")
+	require.NoError(t, err)
+	assert.Empty(t, selections)
+}
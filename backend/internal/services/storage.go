@@ -0,0 +1,253 @@
+package services
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// ErrObjectNotFound is returned when a requested object does not exist in storage.
+var ErrObjectNotFound = errors.New("object not found")
+
+// Storage stores photo files under slash-separated keys such as "originals/<id>.jpg".
+type Storage interface {
+	// Put writes an object, replacing any existing object with the same key.
+	Put(key string, r io.Reader, size int64) error
+	// Get reads an entire object into memory.
+	Get(key string) ([]byte, error)
+	// Delete removes an object. Deleting a missing object is not an error.
+	Delete(key string) error
+	// Stream opens an object for reading. The caller must close the reader.
+	Stream(key string) (io.ReadCloser, error)
+	// Size returns an object's size in bytes.
+	Size(key string) (int64, error)
+}
+
+// ErrInvalidStorageKey is returned for keys that could escape the storage root.
+var ErrInvalidStorageKey = errors.New("invalid storage key")
+
+// validateStorageKey rejects keys that could escape the storage root.
+func validateStorageKey(key string) error {
+	if key == "" || strings.HasPrefix(key, "/") || strings.Contains(key, "\\") {
+		return fmt.Errorf("%w %q", ErrInvalidStorageKey, key)
+	}
+	for _, part := range strings.Split(key, "/") {
+		if part == "" || part == "." || part == ".." {
+			return fmt.Errorf("%w %q", ErrInvalidStorageKey, key)
+		}
+	}
+	return nil
+}
+
+// LocalStorage stores objects as files under a root directory.
+type LocalStorage struct {
+	root string
+}
+
+// NewLocalStorage creates a local-filesystem storage rooted at dir.
+func NewLocalStorage(dir string) (*LocalStorage, error) {
+	// #nosec G301 - 0755 is appropriate for upload directories
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create storage directory %s: %w", dir, err)
+	}
+	return &LocalStorage{root: dir}, nil
+}
+
+// Root returns the directory objects are stored under.
+func (s *LocalStorage) Root() string {
+	return s.root
+}
+
+// path resolves a key to a file path under the root.
+func (s *LocalStorage) path(key string) (string, error) {
+	if err := validateStorageKey(key); err != nil {
+		return "", err
+	}
+	return filepath.Join(s.root, filepath.FromSlash(key)), nil
+}
+
+// Put writes an object atomically via a temp file and rename.
+func (s *LocalStorage) Put(key string, r io.Reader, _ int64) error {
+	filePath, err := s.path(key)
+	if err != nil {
+		return err
+	}
+
+	// #nosec G301 - 0755 is appropriate for upload directories
+	if err := os.MkdirAll(filepath.Dir(filePath), 0755); err != nil {
+		return fmt.Errorf("failed to create directory: %w", err)
+	}
+
+	// A temp file of its own, so concurrent writes of the same key cannot interleave
+	file, err := os.CreateTemp(filepath.Dir(filePath), filepath.Base(filePath)+"-*.tmp")
+	if err != nil {
+		return fmt.Errorf("failed to create file: %w", err)
+	}
+	tmpPath := file.Name()
+
+	if _, err := io.Copy(file, r); err != nil {
+		_ = file.Close()
+		_ = os.Remove(tmpPath)
+		return fmt.Errorf("failed to write file: %w", err)
+	}
+	if err := file.Close(); err != nil {
+		_ = os.Remove(tmpPath)
+		return fmt.Errorf("failed to write file: %w", err)
+	}
+
+	if err := os.Rename(tmpPath, filePath); err != nil {
+		_ = os.Remove(tmpPath)
+		return fmt.Errorf("failed to rename file: %w", err)
+	}
+	return nil
+}
+
+// Get reads an entire object into memory.
+func (s *LocalStorage) Get(key string) ([]byte, error) {
+	reader, err := s.Stream(key)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = reader.Close() }()
+	return io.ReadAll(reader)
+}
+
+// Delete removes an object's file.
+func (s *LocalStorage) Delete(key string) error {
+	filePath, err := s.path(key)
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(filePath); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+// Stream opens an object's file for reading.
+func (s *LocalStorage) Stream(key string) (io.ReadCloser, error) {
+	filePath, err := s.path(key)
+	if err != nil {
+		return nil, err
+	}
+	// #nosec G304 -- path is derived from a validated storage key
+	file, err := os.Open(filePath)
+	if os.IsNotExist(err) {
+		return nil, fmt.Errorf("%s: %w", key, ErrObjectNotFound)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return file, nil
+}
+
+// Size returns the size of an object's file.
+func (s *LocalStorage) Size(key string) (int64, error) {
+	filePath, err := s.path(key)
+	if err != nil {
+		return 0, err
+	}
+	info, err := os.Stat(filePath)
+	if os.IsNotExist(err) {
+		return 0, fmt.Errorf("%s: %w", key, ErrObjectNotFound)
+	}
+	if err != nil {
+		return 0, err
+	}
+	return info.Size(), nil
+}
+
+// MemoryStorage keeps objects in memory. It is intended for tests and ephemeral setups.
+type MemoryStorage struct {
+	objects map[string][]byte
+	mu      sync.RWMutex
+}
+
+// NewMemoryStorage creates an empty in-memory storage.
+func NewMemoryStorage() *MemoryStorage {
+	return &MemoryStorage{objects: make(map[string][]byte)}
+}
+
+// Put stores a copy of the object.
+func (s *MemoryStorage) Put(key string, r io.Reader, _ int64) error {
+	if err := validateStorageKey(key); err != nil {
+		return err
+	}
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.objects[key] = data
+	return nil
+}
+
+// Get returns a copy of the object.
+func (s *MemoryStorage) Get(key string) ([]byte, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	data, ok := s.objects[key]
+	if !ok {
+		return nil, fmt.Errorf("%s: %w", key, ErrObjectNotFound)
+	}
+	return bytes.Clone(data), nil
+}
+
+// Delete removes the object.
+func (s *MemoryStorage) Delete(key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.objects, key)
+	return nil
+}
+
+// Stream returns a reader over a copy of the object.
+func (s *MemoryStorage) Stream(key string) (io.ReadCloser, error) {
+	data, err := s.Get(key)
+	if err != nil {
+		return nil, err
+	}
+	return io.NopCloser(bytes.NewReader(data)), nil
+}
+
+// Size returns the length of the object.
+func (s *MemoryStorage) Size(key string) (int64, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	data, ok := s.objects[key]
+	if !ok {
+		return 0, fmt.Errorf("%s: %w", key, ErrObjectNotFound)
+	}
+	return int64(len(data)), nil
+}
+
+// Keys returns the keys of all stored objects under a prefix, sorted.
+func (s *MemoryStorage) Keys(prefix string) []string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	keys := []string{}
+	for key := range s.objects {
+		if strings.HasPrefix(key, prefix) {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// storageKeyFromURL converts a photo URL like "/uploads/display/x.webp" into the storage key "display/x.webp".
+func storageKeyFromURL(url, dir string) string {
+	return path.Join(dir, path.Base(url))
+}
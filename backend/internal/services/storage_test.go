@@ -0,0 +1,147 @@
+package services
+
+import (
+	"archive/zip"
+	"bytes"
+	"errors"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"io"
+	"log/slog"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/njoubert/nielsshootsfilm/backend/internal/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// testStorageContract exercises the behavior every Storage implementation must provide.
+func testStorageContract(t *testing.T, storage Storage) {
+	t.Helper()
+
+	require.NoError(t, storage.Put("display/a.webp", strings.NewReader("first"), 5))
+	require.NoError(t, storage.Put("display/a.webp", strings.NewReader("second"), 6))
+
+	data, err := storage.Get("display/a.webp")
+	require.NoError(t, err)
+	assert.Equal(t, "second", string(data), "Put should replace existing objects")
+
+	reader, err := storage.Stream("display/a.webp")
+	require.NoError(t, err)
+	streamed, err := io.ReadAll(reader)
+	require.NoError(t, err)
+	require.NoError(t, reader.Close())
+	assert.Equal(t, "second", string(streamed))
+
+	size, err := storage.Size("display/a.webp")
+	require.NoError(t, err)
+	assert.Equal(t, int64(6), size)
+
+	require.NoError(t, storage.Delete("display/a.webp"))
+	_, err = storage.Get("display/a.webp")
+	assert.True(t, errors.Is(err, ErrObjectNotFound))
+	_, err = storage.Stream("display/a.webp")
+	assert.True(t, errors.Is(err, ErrObjectNotFound))
+	_, err = storage.Size("display/a.webp")
+	assert.True(t, errors.Is(err, ErrObjectNotFound))
+	assert.NoError(t, storage.Delete("display/a.webp"), "deleting a missing object is not an error")
+
+	for _, key := range []string{"", "/abs.jpg", "../escape.jpg", "display/../../escape.jpg", "display//a.jpg"} {
+		assert.Error(t, storage.Put(key, strings.NewReader("x"), 1), "key %q should be rejected", key)
+	}
+}
+
+func TestLocalStorage(t *testing.T) {
+	dir := t.TempDir()
+	storage, err := NewLocalStorage(dir)
+	require.NoError(t, err)
+
+	testStorageContract(t, storage)
+
+	// Objects are plain files under the root so they can be served statically
+	require.NoError(t, storage.Put("originals/b.jpg", strings.NewReader("jpeg"), 4))
+	data, err := os.ReadFile(filepath.Join(dir, "originals", "b.jpg"))
+	require.NoError(t, err)
+	assert.Equal(t, "jpeg", string(data))
+
+	// Concurrent writes of one key each land whole, leaving no temp files behind
+	contents := []string{strings.Repeat("a", 1<<20), strings.Repeat("b", 1<<20)}
+	var wg sync.WaitGroup
+	for _, content := range contents {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			assert.NoError(t, storage.Put("originals/c.jpg", strings.NewReader(content), int64(len(content))))
+		}()
+	}
+	wg.Wait()
+	data, err = os.ReadFile(filepath.Join(dir, "originals", "c.jpg"))
+	require.NoError(t, err)
+	assert.Contains(t, contents, string(data))
+	entries, err := os.ReadDir(filepath.Join(dir, "originals"))
+	require.NoError(t, err)
+	assert.Len(t, entries, 2)
+}
+
+func TestMemoryStorage(t *testing.T) {
+	testStorageContract(t, NewMemoryStorage())
+}
+
+// createTestJPEG returns an encoded JPEG with a simple gradient.
+func createTestJPEG(t *testing.T, width, height int) []byte {
+	t.Helper()
+
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+	for x := 0; x < width; x++ {
+		for y := 0; y < height; y++ {
+			img.Set(x, y, color.RGBA{R: uint8(x % 256), G: uint8(y % 256), B: 100, A: 255})
+		}
+	}
+
+	var buf bytes.Buffer
+	require.NoError(t, jpeg.Encode(&buf, img, nil))
+	return buf.Bytes()
+}
+
+func TestImageService_PhotoLifecycle_MemoryStorage(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	imageService, err := NewImageService(t.TempDir(), nil, logger)
+	require.NoError(t, err)
+	storage := NewMemoryStorage()
+	imageService.SetStorage(storage)
+
+	// Upload: original and derivatives are written through the storage interface
+	photo, err := imageService.ProcessBytes("roll1-01.jpg", createTestJPEG(t, 1200, 800))
+	require.NoError(t, err)
+	assert.Equal(t, 1200, photo.Width)
+	assert.Equal(t, 800, photo.Height)
+
+	for _, url := range []string{photo.URLOriginal, photo.URLDisplay, photo.URLThumbnail} {
+		key := strings.TrimPrefix(url, "/uploads/")
+		data, err := storage.Get(key)
+		require.NoError(t, err, "expected %s in storage", key)
+		assert.NotEmpty(t, data)
+	}
+	assert.Len(t, storage.Keys(""), 3)
+
+	// Download: the ZIP is streamed from storage
+	photo.ID = "photo-1"
+	album := &models.Album{Slug: "roll-one", Photos: []models.Photo{*photo, {ID: "missing", FilenameOriginal: "gone.jpg", URLOriginal: "/uploads/originals/gone.jpg", Downloadable: true}}}
+	w := httptest.NewRecorder()
+	require.NoError(t, imageService.StreamAlbumZIP(w, httptest.NewRequest("GET", "/download", nil), album, "original", ZIPOptions{}))
+
+	zipReader, err := zip.NewReader(bytes.NewReader(w.Body.Bytes()), int64(w.Body.Len()))
+	require.NoError(t, err)
+	require.Len(t, zipReader.File, 1, "missing files are skipped")
+	assert.Equal(t, "roll1-01.jpg", zipReader.File[0].Name)
+
+	// Delete: every version is removed from storage
+	require.NoError(t, imageService.DeletePhoto(photo))
+	assert.Empty(t, storage.Keys(""))
+}
@@ -0,0 +1,168 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/njoubert/nielsshootsfilm/backend/internal/models"
+)
+
+// StorageUsage is the number of bytes stored for a set of photos, by quality level.
+type StorageUsage struct {
+	OriginalBytes  int64 `json:"original_bytes"`
+	DisplayBytes   int64 `json:"display_bytes"`
+	ThumbnailBytes int64 `json:"thumbnail_bytes"`
+	CoverBytes     int64 `json:"cover_bytes"`
+	TotalBytes     int64 `json:"total_bytes"`
+	Photos         int   `json:"photos"`
+	MissingFiles   int   `json:"missing_files"` // Files referenced by a photo but absent from storage
+}
+
+// add accumulates other into u.
+func (u *StorageUsage) add(other StorageUsage) {
+	u.OriginalBytes += other.OriginalBytes
+	u.DisplayBytes += other.DisplayBytes
+	u.ThumbnailBytes += other.ThumbnailBytes
+	u.CoverBytes += other.CoverBytes
+	u.TotalBytes += other.TotalBytes
+	u.Photos += other.Photos
+	u.MissingFiles += other.MissingFiles
+}
+
+// AlbumStorageUsage is the storage footprint of one album.
+type AlbumStorageUsage struct {
+	AlbumID string `json:"album_id"`
+	Title   string `json:"title"`
+	Slug    string `json:"slug"`
+	StorageUsage
+}
+
+// StorageUsageReport is the storage footprint of every album and their total.
+type StorageUsageReport struct {
+	Albums []AlbumStorageUsage `json:"albums"`
+	Total  StorageUsage        `json:"total"`
+}
+
+// AlbumStorageUsage sums the sizes of an album's originals, derivatives, and rendered cover.
+// Files shared by repeat uploads of a photo are counted once.
+func (s *ImageService) AlbumStorageUsage(album *models.Album) (StorageUsage, error) {
+	usage := StorageUsage{Photos: len(album.Photos)}
+
+	counted := make(map[string]bool)
+	addObject := func(key string, bytes *int64) error {
+		if counted[key] {
+			return nil
+		}
+		counted[key] = true
+		size, err := s.storage.Size(key)
+		if errors.Is(err, ErrObjectNotFound) {
+			usage.MissingFiles++
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("failed to size %s: %w", key, err)
+		}
+		*bytes += size
+		usage.TotalBytes += size
+		return nil
+	}
+
+	for i := range album.Photos {
+		photo := &album.Photos[i]
+		for _, object := range []struct {
+			url, dir string
+			bytes    *int64
+		}{
+			{photo.URLOriginal, "originals", &usage.OriginalBytes},
+			{photo.URLDisplay, "display", &usage.DisplayBytes},
+			{photo.URLThumbnail, "thumbnails", &usage.ThumbnailBytes},
+			{photo.URLThumbnailPadded, "thumbnails", &usage.ThumbnailBytes},
+		} {
+			if object.url == "" {
+				continue
+			}
+			if err := addObject(storageKeyFromURL(object.url, object.dir), object.bytes); err != nil {
+				return StorageUsage{}, err
+			}
+		}
+	}
+
+	if album.CoverURL != "" {
+		if err := addObject(storageKeyFromURL(album.CoverURL, "covers"), &usage.CoverBytes); err != nil {
+			return StorageUsage{}, err
+		}
+	}
+
+	return usage, nil
+}
+
+// StorageUsageService reports per-album storage usage, caching each album's totals
+// until the album is next saved.
+type StorageUsageService struct {
+	albumService *AlbumService
+	imageService *ImageService
+
+	mu    sync.Mutex
+	cache map[string]cachedStorageUsage
+}
+
+// cachedStorageUsage is an album's usage as of the album's UpdatedAt.
+type cachedStorageUsage struct {
+	updatedAt time.Time
+	usage     StorageUsage
+}
+
+// NewStorageUsageService creates a new storage usage service.
+func NewStorageUsageService(albumService *AlbumService, imageService *ImageService) *StorageUsageService {
+	return &StorageUsageService{
+		albumService: albumService,
+		imageService: imageService,
+		cache:        make(map[string]cachedStorageUsage),
+	}
+}
+
+// Report returns the storage usage of every album. Every photo change goes through
+// AlbumService.Update, which bumps the album's UpdatedAt, so a cached entry is reused
+// only while UpdatedAt is unchanged. Deleted albums drop out of the cache.
+func (s *StorageUsageService) Report() (*StorageUsageReport, error) {
+	albums, err := s.albumService.GetAll()
+	if err != nil {
+		return nil, err
+	}
+	return s.report(albums)
+}
+
+// report computes the storage usage of the given albums, reusing cached totals.
+func (s *StorageUsageService) report(albums []models.Album) (*StorageUsageReport, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	report := &StorageUsageReport{Albums: make([]AlbumStorageUsage, 0, len(albums))}
+	cache := make(map[string]cachedStorageUsage, len(albums))
+	for i := range albums {
+		album := &albums[i]
+
+		entry, ok := s.cache[album.ID]
+		if !ok || !entry.updatedAt.Equal(album.UpdatedAt) {
+			usage, err := s.imageService.AlbumStorageUsage(album)
+			if err != nil {
+				return nil, fmt.Errorf("album %s: %w", album.ID, err)
+			}
+			entry = cachedStorageUsage{updatedAt: album.UpdatedAt, usage: usage}
+		}
+		cache[album.ID] = entry
+
+		report.Albums = append(report.Albums, AlbumStorageUsage{
+			AlbumID:      album.ID,
+			Title:        album.Title,
+			Slug:         album.Slug,
+			StorageUsage: entry.usage,
+		})
+		report.Total.add(entry.usage)
+	}
+	s.cache = cache
+
+	return report, nil
+}
@@ -0,0 +1,104 @@
+package services
+
+import (
+	"io"
+	"log/slog"
+	"testing"
+
+	"github.com/njoubert/nielsshootsfilm/backend/internal/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStorageUsageService_Report(t *testing.T) {
+	albumService, _ := setupAlbumService(t)
+	imageService, err := NewImageService(t.TempDir(), nil, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	require.NoError(t, err)
+	storage := NewMemoryStorage()
+	imageService.SetStorage(storage)
+	usageService := NewStorageUsageService(albumService, imageService)
+
+	objectSize := func(key string) int64 {
+		data, err := storage.Get(key)
+		require.NoError(t, err)
+		return int64(len(data))
+	}
+
+	first := &models.Album{Title: "First", Visibility: "public"}
+	require.NoError(t, albumService.Create(first))
+	second := &models.Album{Title: "Second", Visibility: "public"}
+	require.NoError(t, albumService.Create(second))
+
+	var want StorageUsage
+	var photos []*models.Photo
+	for _, size := range []int{320, 480} {
+		photo, err := imageService.ProcessBytes("photo.jpg", createTestJPEG(t, size, size*2/3))
+		require.NoError(t, err)
+		require.NoError(t, albumService.AddPhoto(first.ID, photo))
+		photos = append(photos, photo)
+
+		want.OriginalBytes += objectSize(storageKeyFromURL(photo.URLOriginal, "originals"))
+		want.DisplayBytes += objectSize(storageKeyFromURL(photo.URLDisplay, "display"))
+		want.ThumbnailBytes += objectSize(storageKeyFromURL(photo.URLThumbnail, "thumbnails"))
+	}
+	want.TotalBytes = want.OriginalBytes + want.DisplayBytes + want.ThumbnailBytes
+	want.Photos = 2
+
+	report, err := usageService.Report()
+	require.NoError(t, err)
+	require.Len(t, report.Albums, 2)
+	assert.Equal(t, first.ID, report.Albums[0].AlbumID)
+	assert.Equal(t, want, report.Albums[0].StorageUsage, "totals should match the stored file sizes")
+	assert.Equal(t, StorageUsage{}, report.Albums[1].StorageUsage)
+	assert.Equal(t, want, report.Total)
+	assert.Positive(t, want.OriginalBytes)
+	assert.Positive(t, want.DisplayBytes)
+	assert.Positive(t, want.ThumbnailBytes)
+
+	// Totals are cached until the album changes
+	removed := photos[1]
+	require.NoError(t, imageService.DeletePhoto(removed))
+	report, err = usageService.Report()
+	require.NoError(t, err)
+	assert.Equal(t, want, report.Total, "storage changes alone should not invalidate the cache")
+
+	// Deleting the photo from the album invalidates it
+	require.NoError(t, albumService.DeletePhoto(first.ID, removed.ID))
+	report, err = usageService.Report()
+	require.NoError(t, err)
+
+	kept := StorageUsage{
+		OriginalBytes:  objectSize(storageKeyFromURL(photos[0].URLOriginal, "originals")),
+		DisplayBytes:   objectSize(storageKeyFromURL(photos[0].URLDisplay, "display")),
+		ThumbnailBytes: objectSize(storageKeyFromURL(photos[0].URLThumbnail, "thumbnails")),
+		Photos:         1,
+	}
+	kept.TotalBytes = kept.OriginalBytes + kept.DisplayBytes + kept.ThumbnailBytes
+	assert.Equal(t, kept, report.Total)
+	assert.Less(t, kept.TotalBytes, want.TotalBytes)
+
+	// Deleted albums drop out of the report
+	require.NoError(t, albumService.Delete(second.ID))
+	report, err = usageService.Report()
+	require.NoError(t, err)
+	require.Len(t, report.Albums, 1)
+	assert.Equal(t, first.ID, report.Albums[0].AlbumID)
+}
+
+func TestImageService_AlbumStorageUsage_MissingFiles(t *testing.T) {
+	imageService, err := NewImageService(t.TempDir(), nil, nil)
+	require.NoError(t, err)
+	storage := NewMemoryStorage()
+	imageService.SetStorage(storage)
+
+	photo, err := imageService.ProcessBytes("photo.jpg", createTestJPEG(t, 320, 240))
+	require.NoError(t, err)
+	require.NoError(t, storage.Delete(storageKeyFromURL(photo.URLOriginal, "originals")))
+
+	usage, err := imageService.AlbumStorageUsage(&models.Album{Photos: []models.Photo{*photo}})
+	require.NoError(t, err)
+	assert.Equal(t, 1, usage.MissingFiles)
+	assert.Zero(t, usage.OriginalBytes)
+	assert.Positive(t, usage.DisplayBytes)
+	assert.Equal(t, usage.DisplayBytes+usage.ThumbnailBytes, usage.TotalBytes)
+}
@@ -0,0 +1,233 @@
+package services
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/njoubert/nielsshootsfilm/backend/internal/models"
+)
+
+// Store backup defaults.
+const (
+	DefaultStoreBackupKeep     = 30        // Snapshots kept in the backup location
+	DefaultStoreBackupInterval = time.Hour // Time between scheduled snapshots
+)
+
+// Snapshot triggers, recorded with each snapshot.
+const (
+	SnapshotTriggerManual    = "manual"
+	SnapshotTriggerScheduled = "scheduled"
+	SnapshotTriggerWrite     = "write"
+	SnapshotTriggerRestore   = "pre-restore" // The live data, saved before a restore replaced it
+)
+
+// storeBackupIndexKey is the object listing the snapshots in the backup location.
+const storeBackupIndexKey = "index.json"
+
+// ErrNothingToBackUp is returned when there is no albums.json to snapshot yet.
+var ErrNothingToBackUp = errors.New("no album data to back up yet")
+
+// StoreBackupService copies albums.json to a second location, such as another disk or a
+// bucket, keeping the most recent snapshots. Unlike the backups FileService keeps next to
+// the data, these survive losing the data directory.
+type StoreBackupService struct {
+	fileService  *FileService
+	albumService *AlbumService // Restores go through it, so they wait for album changes in flight
+	photos       Storage       // Photo storage, checked for the files a restored snapshot references
+	target       Storage
+	keep         int
+	mu           sync.Mutex    // Serializes snapshots and index updates
+	written      chan struct{} // Signalled when albums.json is written
+	now          func() time.Time
+}
+
+// NewStoreBackupService creates a backup service for the albums of albumService, writing
+// snapshots to target and keeping the last keep of them (or DefaultStoreBackupKeep if keep
+// is not positive). photos is the storage holding the albums' photos.
+func NewStoreBackupService(albumService *AlbumService, photos Storage, target Storage, keep int) *StoreBackupService {
+	if keep <= 0 {
+		keep = DefaultStoreBackupKeep
+	}
+	return &StoreBackupService{
+		fileService:  albumService.fileService,
+		albumService: albumService,
+		photos:       photos,
+		target:       target,
+		keep:         keep,
+		written:      make(chan struct{}, 1),
+		now:          time.Now,
+	}
+}
+
+// Snapshot copies albums.json to the backup location now, pruning the oldest snapshots
+// beyond the number kept.
+func (s *StoreBackupService) Snapshot(trigger string) (*models.StoreSnapshot, error) {
+	snapshot, _, err := s.snapshot(trigger, true)
+	return snapshot, err
+}
+
+// SnapshotIfChanged is like Snapshot, but skips the copy when albums.json is unchanged since
+// the latest snapshot. It reports whether a snapshot was taken.
+func (s *StoreBackupService) SnapshotIfChanged(trigger string) (*models.StoreSnapshot, bool, error) {
+	return s.snapshot(trigger, false)
+}
+
+func (s *StoreBackupService) snapshot(trigger string, always bool) (*models.StoreSnapshot, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.snapshotLocked(trigger, always)
+}
+
+// snapshotLocked takes a snapshot. Callers must hold mu.
+func (s *StoreBackupService) snapshotLocked(trigger string, always bool) (*models.StoreSnapshot, bool, error) {
+	data, err := s.fileService.ReadFile(albumsFile)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, false, ErrNothingToBackUp
+	}
+	if err != nil {
+		return nil, false, err
+	}
+	sum := sha256.Sum256(data)
+	checksum := hex.EncodeToString(sum[:])
+
+	index, err := s.readIndex()
+	if err != nil {
+		return nil, false, err
+	}
+	if n := len(index.Snapshots); !always && n > 0 && index.Snapshots[n-1].SHA256 == checksum {
+		return &index.Snapshots[n-1], false, nil
+	}
+
+	createdAt := s.now().UTC()
+	snapshot := models.StoreSnapshot{
+		Key:       "albums-" + createdAt.Format("20060102T150405.000000000Z") + ".json",
+		Size:      int64(len(data)),
+		SHA256:    checksum,
+		Trigger:   trigger,
+		CreatedAt: createdAt,
+	}
+	if err := s.target.Put(snapshot.Key, bytes.NewReader(data), snapshot.Size); err != nil {
+		return nil, false, fmt.Errorf("failed to store snapshot: %w", err)
+	}
+
+	index.Snapshots = append(index.Snapshots, snapshot)
+	var pruned []models.StoreSnapshot
+	if excess := len(index.Snapshots) - s.keep; excess > 0 {
+		pruned = slices.Clone(index.Snapshots[:excess])
+		index.Snapshots = index.Snapshots[excess:]
+	}
+	// The index is written before pruning, so it never lists a deleted snapshot
+	if err := s.writeIndex(index); err != nil {
+		return nil, false, err
+	}
+	for _, old := range pruned {
+		if err := s.target.Delete(old.Key); err != nil {
+			return nil, false, fmt.Errorf("failed to delete old snapshot %s: %w", old.Key, err)
+		}
+	}
+	return &snapshot, true, nil
+}
+
+// List returns the kept snapshots, newest first.
+func (s *StoreBackupService) List() ([]models.StoreSnapshot, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	index, err := s.readIndex()
+	if err != nil {
+		return nil, err
+	}
+	slices.Reverse(index.Snapshots)
+	return index.Snapshots, nil
+}
+
+// NotifyWrite records that a data file was written, for FileService.SetWriteHook. Writes to
+// albums.json schedule a snapshot, taken in the background by Start; writes in quick
+// succession are coalesced.
+func (s *StoreBackupService) NotifyWrite(filename string) {
+	if filename != albumsFile {
+		return
+	}
+	select {
+	case s.written <- struct{}{}:
+	default:
+	}
+}
+
+// Start takes a snapshot every interval in the background, and also after albums.json is
+// written if onWrite is set. Either is skipped when nothing changed since the last snapshot.
+// A non-positive interval disables scheduled snapshots.
+func (s *StoreBackupService) Start(interval time.Duration, onWrite bool, logger *slog.Logger) {
+	var tick <-chan time.Time
+	if interval > 0 {
+		ticker := time.NewTicker(interval)
+		tick = ticker.C
+	}
+	var written <-chan struct{}
+	if onWrite {
+		written = s.written
+	}
+	if tick == nil && written == nil {
+		return
+	}
+
+	go func() {
+		for {
+			trigger := SnapshotTriggerScheduled
+			select {
+			case <-tick:
+			case <-written:
+				trigger = SnapshotTriggerWrite
+			}
+			snapshot, taken, err := s.SnapshotIfChanged(trigger)
+			switch {
+			case errors.Is(err, ErrNothingToBackUp):
+			case err != nil:
+				logger.Error("failed to back up albums", slog.String("trigger", trigger), slog.String("error", err.Error()))
+			case taken:
+				logger.Debug("albums backed up", slog.String("trigger", trigger), slog.String("key", snapshot.Key))
+			}
+		}
+	}()
+}
+
+// readIndex reads the snapshot index, which is empty before the first snapshot. Callers
+// must hold mu.
+func (s *StoreBackupService) readIndex() (*models.StoreSnapshotIndex, error) {
+	index := &models.StoreSnapshotIndex{Snapshots: []models.StoreSnapshot{}}
+	data, err := s.target.Get(storeBackupIndexKey)
+	if errors.Is(err, ErrObjectNotFound) {
+		return index, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read backup index: %w", err)
+	}
+	if err := json.Unmarshal(data, index); err != nil {
+		return nil, fmt.Errorf("failed to parse backup index: %w", err)
+	}
+	if index.Snapshots == nil {
+		index.Snapshots = []models.StoreSnapshot{}
+	}
+	return index, nil
+}
+
+// writeIndex replaces the snapshot index. Callers must hold mu.
+func (s *StoreBackupService) writeIndex(index *models.StoreSnapshotIndex) error {
+	data, err := json.MarshalIndent(index, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal backup index: %w", err)
+	}
+	if err := s.target.Put(storeBackupIndexKey, bytes.NewReader(data), int64(len(data))); err != nil {
+		return fmt.Errorf("failed to write backup index: %w", err)
+	}
+	return nil
+}
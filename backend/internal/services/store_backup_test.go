@@ -0,0 +1,94 @@
+package services
+
+import (
+	"io"
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/njoubert/nielsshootsfilm/backend/internal/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStoreBackupService_SnapshotAndPrune(t *testing.T) {
+	albumService, _ := setupAlbumService(t)
+	fileService := albumService.fileService
+	target := NewMemoryStorage()
+	service := NewStoreBackupService(albumService, NewMemoryStorage(), target, 3)
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	service.now = func() time.Time { return now }
+
+	_, err := service.Snapshot(SnapshotTriggerManual)
+	assert.ErrorIs(t, err, ErrNothingToBackUp)
+
+	album := &models.Album{Title: "Roll 1", Visibility: "public"}
+	require.NoError(t, albumService.Create(album))
+
+	first, err := service.Snapshot(SnapshotTriggerManual)
+	require.NoError(t, err)
+	assert.Equal(t, "albums-20240501T120000.000000000Z.json", first.Key)
+	assert.Equal(t, SnapshotTriggerManual, first.Trigger)
+	stored, err := target.Get(first.Key)
+	require.NoError(t, err)
+	current, err := fileService.ReadFile(albumsFile)
+	require.NoError(t, err)
+	assert.Equal(t, current, stored)
+	assert.Equal(t, int64(len(current)), first.Size)
+
+	// Unchanged data is not snapshotted again unless asked for
+	now = now.Add(time.Minute)
+	latest, taken, err := service.SnapshotIfChanged(SnapshotTriggerScheduled)
+	require.NoError(t, err)
+	assert.False(t, taken)
+	assert.Equal(t, first.Key, latest.Key)
+
+	// Snapshots beyond the three kept are deleted, oldest first
+	var keys []string
+	for i := range 4 {
+		now = now.Add(time.Minute)
+		album.Title = "Roll " + string(rune('2'+i))
+		require.NoError(t, albumService.Update(album.ID, album))
+		snapshot, taken, err := service.SnapshotIfChanged(SnapshotTriggerScheduled)
+		require.NoError(t, err)
+		require.True(t, taken)
+		keys = append(keys, snapshot.Key)
+	}
+
+	snapshots, err := service.List()
+	require.NoError(t, err)
+	require.Len(t, snapshots, 3)
+	assert.Equal(t, keys[3], snapshots[0].Key, "newest first")
+	assert.Equal(t, keys[1], snapshots[2].Key)
+	for _, key := range keys[1:] {
+		_, err := target.Get(key)
+		assert.NoError(t, err, key)
+	}
+	for _, key := range []string{first.Key, keys[0]} {
+		_, err := target.Get(key)
+		assert.ErrorIs(t, err, ErrObjectNotFound, key)
+	}
+}
+
+func TestStoreBackupService_SnapshotOnWrite(t *testing.T) {
+	albumService, _ := setupAlbumService(t)
+	fileService := albumService.fileService
+	target := NewMemoryStorage()
+	service := NewStoreBackupService(albumService, NewMemoryStorage(), target, 5)
+	fileService.SetWriteHook(service.NotifyWrite)
+	service.Start(0, true, slog.New(slog.NewTextHandler(io.Discard, nil)))
+
+	require.NoError(t, albumService.Create(&models.Album{Title: "Roll 1", Visibility: "public"}))
+
+	var snapshots []models.StoreSnapshot
+	require.Eventually(t, func() bool {
+		var err error
+		snapshots, err = service.List()
+		return err == nil && len(snapshots) > 0
+	}, 5*time.Second, 10*time.Millisecond)
+	assert.Equal(t, SnapshotTriggerWrite, snapshots[0].Trigger)
+
+	// Writes to other stores do not trigger snapshots
+	service.NotifyWrite(apiKeysFile)
+	assert.Empty(t, service.written)
+}
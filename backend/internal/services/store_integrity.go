@@ -0,0 +1,215 @@
+package services
+
+import (
+	"fmt"
+	"slices"
+
+	"github.com/google/uuid"
+	"github.com/njoubert/nielsshootsfilm/backend/internal/models"
+)
+
+// Kinds of store integrity issues.
+const (
+	IntegrityMissingID     = "missing_id"     // An album or photo without an ID
+	IntegrityDuplicateID   = "duplicate_id"   // An album ID used earlier in the store, or a photo ID earlier in its album
+	IntegrityMissingSlug   = "missing_slug"   // An album or photo without a slug
+	IntegrityDuplicateSlug = "duplicate_slug" // An album slug used earlier in its namespace, or a photo slug earlier in its album
+	IntegrityMissingFile   = "missing_file"   // A photo file or cover that is not in storage
+	IntegrityOrphanCover   = "orphan_cover"   // A cover photo ID naming no photo of the album
+)
+
+// IntegrityIssue is an inconsistency found in albums.json.
+type IntegrityIssue struct {
+	Type       string `json:"type"`
+	AlbumIndex int    `json:"album_index"` // Position in albums.json, since the album's ID may be the problem
+	AlbumID    string `json:"album_id,omitempty"`
+	PhotoID    string `json:"photo_id,omitempty"`
+	Detail     string `json:"detail"`
+	Fixed      bool   `json:"fixed"`
+}
+
+// StoreIntegrity is the result of checking albums.json for inconsistencies.
+type StoreIntegrity struct {
+	Albums int              `json:"albums"`
+	Photos int              `json:"photos"`
+	Issues []IntegrityIssue `json:"issues"`
+	Fixed  int              `json:"fixed"`
+}
+
+// CheckIntegrity reports what hand edits of albums.json can leave inconsistent: missing and
+// duplicate album and photo IDs and slugs, photo files and covers missing from photos, and
+// cover photo IDs naming no photo. It reads the file as stored, before the slugs and covers
+// reads derive. With fix, missing and duplicate IDs and slugs are regenerated, keeping the
+// first of each duplicate, and the file is saved; the other issues are only reported.
+// Storage is checked after the album lock is released, so slow storage does not hold up
+// album changes.
+func (s *AlbumService) CheckIntegrity(photos Storage, fix bool) (*StoreIntegrity, error) {
+	report, files, err := s.checkStoreIntegrity(fix)
+	if err != nil {
+		return nil, err
+	}
+
+	checked := make(map[string]bool)
+	for _, file := range files {
+		if checked[file.key] {
+			continue
+		}
+		checked[file.key] = true
+		exists, err := storageHas(photos, file.key)
+		if err != nil {
+			return nil, err
+		}
+		if !exists {
+			report.Issues = append(report.Issues, IntegrityIssue{Type: IntegrityMissingFile, AlbumIndex: file.albumIndex,
+				AlbumID: file.albumID, PhotoID: file.photoID, Detail: file.key + " is missing"})
+		}
+	}
+	return report, nil
+}
+
+// integrityFileRef is a file an album in albums.json refers to, to be looked for in storage.
+type integrityFileRef struct {
+	albumFileRef
+	albumIndex int
+	albumID    string
+}
+
+// checkStoreIntegrity checks albums.json for the issues CheckIntegrity reports other than
+// missing files, and with fix saves the fixes. It returns the files the albums refer to, as
+// fixed, for the caller to look for in storage.
+func (s *AlbumService) checkStoreIntegrity(fix bool) (*StoreIntegrity, []integrityFileRef, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	report := &StoreIntegrity{Issues: []IntegrityIssue{}}
+	if !s.fileService.FileExists(albumsFile) {
+		return report, nil, nil
+	}
+	var collection models.AlbumCollection
+	if err := s.fileService.ReadJSON(albumsFile, &collection); err != nil {
+		return nil, nil, fmt.Errorf("failed to read albums: %w", err)
+	}
+	albums := collection.Albums
+
+	record := func(issue IntegrityIssue) {
+		issue.Fixed = fix && issue.Type != IntegrityOrphanCover
+		if issue.Fixed {
+			report.Fixed++
+		}
+		report.Issues = append(report.Issues, issue)
+	}
+
+	albumIDs := make(map[string]int, len(albums))
+	for i := range albums {
+		album := &albums[i]
+		report.Photos += len(album.Photos)
+
+		// IDs first, so the other issues name the album by its fixed ID
+		if first, taken := albumIDs[album.ID]; album.ID == "" || taken {
+			issue := IntegrityIssue{Type: IntegrityMissingID, AlbumIndex: i, Detail: "album has no ID"}
+			if album.ID != "" {
+				issue = IntegrityIssue{Type: IntegrityDuplicateID, AlbumIndex: i, AlbumID: album.ID,
+					Detail: fmt.Sprintf("album ID is also used by the album at index %d", first)}
+			}
+			if fix {
+				album.ID = uuid.New().String()
+				issue.AlbumID = album.ID
+			}
+			record(issue)
+		}
+		albumIDs[album.ID] = i
+
+		if first := slices.IndexFunc(albums[:i], func(other models.Album) bool {
+			return other.Namespace == album.Namespace && other.Slug == album.Slug
+		}); album.Slug == "" || first >= 0 {
+			issue := IntegrityIssue{Type: IntegrityMissingSlug, AlbumIndex: i, AlbumID: album.ID, Detail: "album has no slug"}
+			base := generateSlug(album.Title)
+			if album.Slug != "" {
+				issue.Type = IntegrityDuplicateSlug
+				issue.Detail = fmt.Sprintf("album slug %q is also used by the album at index %d", album.Slug, first)
+				base = album.Slug
+			}
+			if fix {
+				neighbors := albumsInNamespace(slices.Delete(slices.Clone(albums), i, i+1), album.Namespace)
+				album.Slug = generateUniqueSlug(base, neighbors)
+			}
+			record(issue)
+		}
+
+		checkPhotoIntegrity(i, album, fix, record)
+
+		for _, coverID := range coverReferences(album) {
+			if !slices.ContainsFunc(album.Photos, func(photo models.Photo) bool { return photo.ID == coverID }) {
+				record(IntegrityIssue{Type: IntegrityOrphanCover, AlbumIndex: i, AlbumID: album.ID, PhotoID: coverID,
+					Detail: "cover photo is not in the album"})
+			}
+		}
+	}
+	report.Albums = len(albums)
+
+	if report.Fixed > 0 {
+		if err := s.fileService.WriteJSON(albumsFile, &collection); err != nil {
+			return nil, nil, fmt.Errorf("failed to write albums: %w", err)
+		}
+	}
+
+	var files []integrityFileRef
+	for i := range albums {
+		for _, ref := range albumFileRefs(&albums[i]) {
+			files = append(files, integrityFileRef{albumFileRef: ref, albumIndex: i, albumID: albums[i].ID})
+		}
+	}
+	return report, files, nil
+}
+
+// checkPhotoIntegrity records missing and duplicate photo IDs and slugs in an album, and with
+// fix regenerates them.
+func checkPhotoIntegrity(index int, album *models.Album, fix bool, record func(IntegrityIssue)) {
+	ids := make(map[string]bool, len(album.Photos))
+	slugs := make(map[string]bool, len(album.Photos))
+	slugsNeeded := false
+	for j := range album.Photos {
+		photo := &album.Photos[j]
+		if photo.ID == "" || ids[photo.ID] {
+			issue := IntegrityIssue{Type: IntegrityMissingID, AlbumIndex: index, AlbumID: album.ID, Detail: "photo has no ID"}
+			if photo.ID != "" {
+				issue.Type = IntegrityDuplicateID
+				issue.PhotoID = photo.ID
+				issue.Detail = "photo ID is also used by an earlier photo in the album"
+			}
+			if fix {
+				photo.ID = uuid.New().String()
+				issue.PhotoID = photo.ID
+			}
+			record(issue)
+		}
+		ids[photo.ID] = true
+
+		if photo.Slug == "" || slugs[photo.Slug] {
+			issue := IntegrityIssue{Type: IntegrityMissingSlug, AlbumIndex: index, AlbumID: album.ID, PhotoID: photo.ID,
+				Detail: "photo has no slug"}
+			if photo.Slug != "" {
+				issue.Type = IntegrityDuplicateSlug
+				issue.Detail = fmt.Sprintf("photo slug %q is also used by an earlier photo in the album", photo.Slug)
+			}
+			slugsNeeded = true
+			record(issue)
+		} else {
+			slugs[photo.Slug] = true
+		}
+	}
+
+	// Photos that need a slug are given one as on save
+	if fix && slugsNeeded {
+		assignPhotoSlugs(album)
+	}
+}
+
+// coverReferences returns the photo IDs an album names as its covers, each once.
+func coverReferences(album *models.Album) []string {
+	refs := slices.Clone(album.CoverPhotoIDs)
+	if album.CoverPhotoID != "" && !slices.Contains(refs, album.CoverPhotoID) {
+		refs = append(refs, album.CoverPhotoID)
+	}
+	return refs
+}
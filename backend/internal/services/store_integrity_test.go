@@ -0,0 +1,159 @@
+package services
+
+import (
+	"testing"
+
+	"github.com/njoubert/nielsshootsfilm/backend/internal/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// writeInconsistentStore writes an albums.json with every kind of integrity issue, as hand
+// edits might leave it, and stores the files of all photos but one.
+func writeInconsistentStore(t *testing.T, albumService *AlbumService, photos Storage) {
+	t.Helper()
+
+	beach := restoreTestPhoto("p1")
+	beach.Slug = "beach"
+	unnamed := restoreTestPhoto("p2")
+	unnamed.ID = ""
+	copied := restoreTestPhoto("p3")
+	copied.ID = "p1"
+	copied.Slug = "beach"
+	putPhotoFiles(t, photos, beach)
+	putPhotoFiles(t, photos, unnamed)
+
+	collection := models.AlbumCollection{Albums: []models.Album{
+		{ID: "a1", Title: "Summer", Slug: "summer", Visibility: "public",
+			Photos: []models.Photo{beach, unnamed, copied}, CoverPhotoIDs: []string{"p1", "gone"}},
+		{ID: "a1", Title: "Summer Again", Slug: "summer", Visibility: "public"},
+		{Title: "Winter Roll", Visibility: "public"},
+		{ID: "a4", Title: "Summer", Slug: "summer", Namespace: "niels", Visibility: "public"},
+	}}
+	require.NoError(t, albumService.fileService.WriteJSON(albumsFile, &collection))
+}
+
+// issueCounts counts a report's issues by type.
+func issueCounts(report *StoreIntegrity) map[string]int {
+	counts := make(map[string]int)
+	for _, issue := range report.Issues {
+		counts[issue.Type]++
+	}
+	return counts
+}
+
+func TestAlbumService_CheckIntegrity(t *testing.T) {
+	albumService, _ := setupAlbumService(t)
+	photos := NewMemoryStorage()
+	writeInconsistentStore(t, albumService, photos)
+
+	report, err := albumService.CheckIntegrity(photos, false)
+	require.NoError(t, err)
+	assert.Equal(t, 4, report.Albums)
+	assert.Equal(t, 3, report.Photos)
+	assert.Zero(t, report.Fixed)
+	assert.Equal(t, map[string]int{
+		IntegrityMissingID:     2, // The unnamed photo and the Winter Roll album
+		IntegrityDuplicateID:   2, // The copied photo and the second a1 album
+		IntegrityMissingSlug:   2,
+		IntegrityDuplicateSlug: 2, // The copied photo and the second summer album; the niels one is in its own namespace
+		IntegrityOrphanCover:   1,
+		IntegrityMissingFile:   3, // The copied photo's original, display version, and thumbnail
+	}, issueCounts(report))
+	for _, issue := range report.Issues {
+		assert.False(t, issue.Fixed)
+		if issue.Type == IntegrityOrphanCover {
+			assert.Equal(t, "gone", issue.PhotoID)
+			assert.Equal(t, 0, issue.AlbumIndex)
+		}
+	}
+
+	// Without fix the store is unchanged
+	again, err := albumService.CheckIntegrity(photos, false)
+	require.NoError(t, err)
+	assert.Equal(t, report.Issues, again.Issues)
+}
+
+func TestAlbumService_CheckIntegrity_Fix(t *testing.T) {
+	albumService, _ := setupAlbumService(t)
+	photos := NewMemoryStorage()
+	writeInconsistentStore(t, albumService, photos)
+
+	report, err := albumService.CheckIntegrity(photos, true)
+	require.NoError(t, err)
+	assert.Equal(t, 8, report.Fixed)
+	for _, issue := range report.Issues {
+		fixable := issue.Type != IntegrityMissingFile && issue.Type != IntegrityOrphanCover
+		assert.Equal(t, fixable, issue.Fixed, issue.Type)
+	}
+
+	// Only what fixing cannot repair is left
+	after, err := albumService.CheckIntegrity(photos, false)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]int{IntegrityOrphanCover: 1, IntegrityMissingFile: 3}, issueCounts(after))
+
+	// The first of each duplicate keeps its ID and slug
+	albums, err := albumService.GetAll()
+	require.NoError(t, err)
+	require.Len(t, albums, 4)
+	assert.Equal(t, "a1", albums[0].ID)
+	assert.Equal(t, "summer", albums[0].Slug)
+	assert.NotEqual(t, "a1", albums[1].ID)
+	assert.Equal(t, "summer-1", albums[1].Slug)
+	assert.NotEmpty(t, albums[2].ID)
+	assert.Equal(t, "winter-roll", albums[2].Slug)
+	assert.Equal(t, "summer", albums[3].Slug)
+
+	photoIDs := map[string]bool{}
+	photoSlugs := map[string]bool{}
+	for _, photo := range albums[0].Photos {
+		assert.NotEmpty(t, photo.ID)
+		photoIDs[photo.ID] = true
+		photoSlugs[photo.Slug] = true
+	}
+	assert.Len(t, photoIDs, 3)
+	assert.Len(t, photoSlugs, 3)
+	assert.Equal(t, "p1", albums[0].Photos[0].ID)
+	assert.Equal(t, "beach", albums[0].Photos[0].Slug)
+
+	// An empty store has no issues
+	empty, _ := setupAlbumService(t)
+	report, err = empty.CheckIntegrity(photos, true)
+	require.NoError(t, err)
+	assert.Empty(t, report.Issues)
+}
+
+// blockingSizeStorage holds up every Size call until release is closed.
+type blockingSizeStorage struct {
+	Storage
+	started chan struct{}
+	release chan struct{}
+}
+
+func (b *blockingSizeStorage) Size(key string) (int64, error) {
+	select {
+	case b.started <- struct{}{}:
+	default:
+	}
+	<-b.release
+	return b.Storage.Size(key)
+}
+
+func TestAlbumService_CheckIntegrity_StorageOutsideLock(t *testing.T) {
+	albumService, _ := setupAlbumService(t)
+	photos := &blockingSizeStorage{Storage: NewMemoryStorage(), started: make(chan struct{}), release: make(chan struct{})}
+	writeInconsistentStore(t, albumService, photos.Storage)
+
+	done := make(chan error)
+	go func() {
+		_, err := albumService.CheckIntegrity(photos, false)
+		done <- err
+	}()
+	<-photos.started
+
+	// Albums can change while storage is being checked
+	require.NoError(t, albumService.Create(&models.Album{Title: "Autumn", Visibility: "public"}))
+
+	close(photos.release)
+	require.NoError(t, <-done)
+}
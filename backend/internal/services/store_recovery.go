@@ -0,0 +1,132 @@
+package services
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"time"
+
+	"github.com/njoubert/nielsshootsfilm/backend/internal/models"
+)
+
+// StoreRecovery describes a JSON store that could not be parsed at startup and what was done about it.
+type StoreRecovery struct {
+	File    string `json:"file"`
+	Error   string `json:"error"`             // Why the file could not be parsed
+	Backup  string `json:"backup,omitempty"`  // Backup restored in its place; empty if none was usable
+	Corrupt string `json:"corrupt,omitempty"` // Where the unparseable file was set aside, once replaced
+}
+
+// Recovered reports whether a valid backup was restored.
+func (r StoreRecovery) Recovered() bool {
+	return r.Backup != ""
+}
+
+// RecoverStores checks that every JSON store parses, restoring any that does not from its most
+// recent valid backup. It returns what it found for each unparseable store; stores that parse,
+// or do not exist yet, are left alone. A store with no valid backup is left in place, so
+// nothing is lost, and is reported with an empty Backup.
+func RecoverStores(fileService *FileService) ([]StoreRecovery, error) {
+	stores := []struct {
+		filename string
+		v        any
+	}{
+		{albumsFile, &models.AlbumCollection{}},
+		{siteConfigFile, &models.SiteConfig{}},
+		{albumDefaultsFile, &models.AlbumDefaults{}},
+		{albumHistoryFile, &models.AlbumHistory{}},
+		{apiKeysFile, &models.APIKeyCollection{}},
+		{albumViewsFile, &models.AlbumViews{}},
+		{albumSessionsFile, &models.AlbumSessionCollection{}},
+		{selectionsFile, &models.SelectionCollection{}},
+	}
+
+	recoveries := []StoreRecovery{}
+	for _, store := range stores {
+		recovery, err := fileService.Recover(store.filename, store.v)
+		if err != nil {
+			return recoveries, err
+		}
+		if recovery != nil {
+			recoveries = append(recoveries, *recovery)
+		}
+	}
+	return recoveries, nil
+}
+
+// Recover reads a JSON file into v. If the file exists but does not parse, the most recent
+// backup that does is restored in its place and read instead, and the unparseable file is moved
+// into the backup directory with a .corrupt suffix. Returns nil if the file parsed or does not
+// exist, and otherwise what was recovered. Errors are returned only for I/O failures.
+func (fs *FileService) Recover(filename string, v any) (*StoreRecovery, error) {
+	lock := fs.getFileLock(filename)
+	lock.Lock()
+	defer lock.Unlock()
+
+	filePath := filepath.Join(fs.dataDir, filename)
+	// #nosec G304 - File path is from controlled data directory
+	data, err := os.ReadFile(filePath)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read file %s: %w", filename, err)
+	}
+
+	parseErr := decodeFresh(data, v)
+	if parseErr == nil {
+		return nil, nil
+	}
+	recovery := &StoreRecovery{File: filename, Error: parseErr.Error()}
+
+	backups, err := filepath.Glob(filepath.Join(fs.backupDir, filename+".*.bak"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to find backups: %w", err)
+	}
+	// Timestamped names sort oldest first
+	sort.Sort(sort.Reverse(sort.StringSlice(backups)))
+
+	for _, backup := range backups {
+		// #nosec G304 - File path is from controlled backup directory
+		backupData, err := os.ReadFile(backup)
+		if err != nil || decodeFresh(backupData, v) != nil {
+			continue
+		}
+
+		corrupt := filepath.Join(fs.backupDir, fmt.Sprintf("%s.%s.corrupt", filename, time.Now().Format("20060102-150405.000000000")))
+		if err := os.Rename(filePath, corrupt); err != nil {
+			return nil, fmt.Errorf("failed to set aside corrupt %s: %w", filename, err)
+		}
+
+		tmpPath := filePath + ".tmp"
+		// #nosec G306 - 0644 is appropriate for JSON data files
+		if err := os.WriteFile(tmpPath, backupData, 0644); err != nil {
+			return nil, fmt.Errorf("failed to write temporary file: %w", err)
+		}
+		if err := os.Rename(tmpPath, filePath); err != nil {
+			_ = os.Remove(tmpPath)
+			return nil, fmt.Errorf("failed to restore backup: %w", err)
+		}
+
+		recovery.Backup = filepath.Base(backup)
+		recovery.Corrupt = filepath.Base(corrupt)
+		return recovery, nil
+	}
+
+	return recovery, nil
+}
+
+// decodeFresh unmarshals data into v only if it parses completely, so a failed attempt never
+// leaves v half-filled.
+func decodeFresh(data []byte, v any) error {
+	fresh := reflect.New(reflect.TypeOf(v).Elem())
+	if err := json.Unmarshal(data, fresh.Interface()); err != nil {
+		return err
+	}
+	reflect.ValueOf(v).Elem().Set(fresh.Elem())
+	return nil
+}
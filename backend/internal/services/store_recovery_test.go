@@ -0,0 +1,91 @@
+package services
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+
+	"github.com/njoubert/nielsshootsfilm/backend/internal/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRecoverStores_RestoresLatestValidBackup(t *testing.T) {
+	dataDir := t.TempDir()
+	fileService, err := NewFileService(dataDir)
+	require.NoError(t, err)
+	albumService := NewAlbumService(fileService)
+
+	// Each write keeps a backup of the previous version
+	for _, title := range []string{"First", "Second", "Third"} {
+		require.NoError(t, albumService.Create(&models.Album{Title: title, Visibility: "public"}))
+	}
+	backups, err := filepath.Glob(filepath.Join(dataDir, ".backups", albumsFile+".*.bak"))
+	require.NoError(t, err)
+	require.Len(t, backups, 2)
+	sort.Strings(backups)
+
+	// A truncated primary, and a newest backup that is no better
+	require.NoError(t, os.WriteFile(filepath.Join(dataDir, albumsFile), []byte(`{"albums": [{"title": "Fir`), 0644))
+	require.NoError(t, os.WriteFile(backups[1], []byte("not json"), 0644))
+
+	recoveries, err := RecoverStores(fileService)
+	require.NoError(t, err)
+	require.Len(t, recoveries, 1)
+	recovery := recoveries[0]
+	assert.Equal(t, albumsFile, recovery.File)
+	assert.True(t, recovery.Recovered())
+	assert.Equal(t, filepath.Base(backups[0]), recovery.Backup)
+	assert.NotEmpty(t, recovery.Error)
+
+	// The album list is back to the backup's contents
+	albums, err := albumService.GetAll()
+	require.NoError(t, err)
+	require.Len(t, albums, 1)
+	assert.Equal(t, "First", albums[0].Title)
+
+	// The corrupt file is kept for inspection
+	corrupt, err := os.ReadFile(filepath.Join(dataDir, ".backups", recovery.Corrupt))
+	require.NoError(t, err)
+	assert.Equal(t, `{"albums": [{"title": "Fir`, string(corrupt))
+
+	// A second start finds nothing to do
+	recoveries, err = RecoverStores(fileService)
+	require.NoError(t, err)
+	assert.Empty(t, recoveries)
+}
+
+func TestRecoverStores_NoValidBackup(t *testing.T) {
+	dataDir := t.TempDir()
+	fileService, err := NewFileService(dataDir)
+	require.NoError(t, err)
+
+	require.NoError(t, os.WriteFile(filepath.Join(dataDir, albumsFile), []byte("{"), 0644))
+
+	recoveries, err := RecoverStores(fileService)
+	require.NoError(t, err)
+	require.Len(t, recoveries, 1)
+	assert.False(t, recoveries[0].Recovered())
+	assert.Empty(t, recoveries[0].Corrupt)
+
+	// The unreadable file is left alone rather than replaced with nothing
+	data, err := os.ReadFile(filepath.Join(dataDir, albumsFile))
+	require.NoError(t, err)
+	assert.Equal(t, "{", string(data))
+}
+
+func TestRecoverStores_HealthyStores(t *testing.T) {
+	fileService, err := NewFileService(t.TempDir())
+	require.NoError(t, err)
+
+	// Missing stores are fine; they are created on first write
+	recoveries, err := RecoverStores(fileService)
+	require.NoError(t, err)
+	assert.Empty(t, recoveries)
+
+	require.NoError(t, NewAlbumService(fileService).Create(&models.Album{Title: "Fine", Visibility: "public"}))
+	recoveries, err = RecoverStores(fileService)
+	require.NoError(t, err)
+	assert.Empty(t, recoveries)
+}
@@ -0,0 +1,183 @@
+package services
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/njoubert/nielsshootsfilm/backend/internal/models"
+)
+
+// maxReportedMissingFiles caps how many missing files an invalid snapshot's error names.
+const maxReportedMissingFiles = 5
+
+// Restore errors.
+var (
+	ErrSnapshotNotFound = errors.New("snapshot not found")
+	ErrInvalidSnapshot  = errors.New("snapshot cannot be restored")
+)
+
+// StoreRestore describes a completed restore.
+type StoreRestore struct {
+	Restored models.StoreSnapshot `json:"restored"`
+	// The live data as it was before the restore; nil if there was none
+	Safety *models.StoreSnapshot `json:"safety_snapshot"`
+}
+
+// Restore replaces albums.json with the snapshot stored under key. The snapshot must match
+// its recorded checksum, parse, and reference only photo files that exist; otherwise an
+// error wrapping ErrInvalidSnapshot is returned and nothing changes. The live data is
+// snapshotted first, so a restore can itself be undone.
+func (s *StoreBackupService) Restore(key string) (*StoreRestore, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	index, err := s.readIndex()
+	if err != nil {
+		return nil, err
+	}
+	var restored *models.StoreSnapshot
+	for i := range index.Snapshots {
+		if index.Snapshots[i].Key == key {
+			restored = &index.Snapshots[i]
+			break
+		}
+	}
+	if restored == nil {
+		return nil, ErrSnapshotNotFound
+	}
+
+	data, err := s.target.Get(key)
+	if errors.Is(err, ErrObjectNotFound) {
+		return nil, fmt.Errorf("%w: snapshot file is missing", ErrInvalidSnapshot)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read snapshot: %w", err)
+	}
+	sum := sha256.Sum256(data)
+	if hex.EncodeToString(sum[:]) != restored.SHA256 {
+		return nil, fmt.Errorf("%w: snapshot does not match its checksum", ErrInvalidSnapshot)
+	}
+	var collection models.AlbumCollection
+	if err := json.Unmarshal(data, &collection); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidSnapshot, err)
+	}
+	for i := range collection.Albums {
+		if err := collection.Albums[i].Validate(); err != nil {
+			return nil, fmt.Errorf("%w: album %s: %v", ErrInvalidSnapshot, collection.Albums[i].ID, err)
+		}
+	}
+	missing, err := s.missingFiles(collection.Albums)
+	if err != nil {
+		return nil, err
+	}
+	if len(missing) > 0 {
+		named := missing[:min(len(missing), maxReportedMissingFiles)]
+		return nil, fmt.Errorf("%w: %d referenced files are missing, including %s",
+			ErrInvalidSnapshot, len(missing), strings.Join(named, ", "))
+	}
+
+	// The safety snapshot is taken under the album store lock too, so it is exactly the data
+	// the restore replaces. It may prune the one being restored, which is already read.
+	result := &StoreRestore{Restored: *restored}
+	err = s.albumService.replaceAll(data, func() error {
+		safety, _, err := s.snapshotLocked(SnapshotTriggerRestore, true)
+		switch {
+		case errors.Is(err, ErrNothingToBackUp):
+		case err != nil:
+			return fmt.Errorf("failed to snapshot current albums: %w", err)
+		default:
+			result.Safety = safety
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// replaceAll writes data, a whole albums.json, over the stored albums. It holds the store
+// lock, so a change read before the restore cannot write its stale copy back over it.
+// prepare runs under the lock first; an error from it leaves the albums as they were. The
+// data is written as given, so fields this version does not know survive.
+func (s *AlbumService) replaceAll(data []byte, prepare func() error) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := prepare(); err != nil {
+		return err
+	}
+	if err := s.fileService.WriteJSON(albumsFile, json.RawMessage(data)); err != nil {
+		return fmt.Errorf("failed to write albums: %w", err)
+	}
+	return nil
+}
+
+// missingFiles returns the storage keys of the photo files and covers albums reference
+// that do not exist, in album order.
+func (s *StoreBackupService) missingFiles(albums []models.Album) ([]string, error) {
+	missing := []string{}
+	checked := make(map[string]bool)
+	for i := range albums {
+		for _, ref := range albumFileRefs(&albums[i]) {
+			if checked[ref.key] {
+				continue
+			}
+			checked[ref.key] = true
+			exists, err := storageHas(s.photos, ref.key)
+			if err != nil {
+				return nil, err
+			}
+			if !exists {
+				missing = append(missing, ref.key)
+			}
+		}
+	}
+	return missing, nil
+}
+
+// albumFileRef is a file in photo storage an album references.
+type albumFileRef struct {
+	photoID string // Empty for the album's cover
+	key     string
+}
+
+// albumFileRefs lists the storage keys of an album's photo files (originals, display
+// versions, and thumbnails) and its cover, in album order. Derivatives of photos whose
+// derivatives are pending are left out, since they are rendered when first asked for.
+func albumFileRefs(album *models.Album) []albumFileRef {
+	var refs []albumFileRef
+	add := func(photoID, url, dir string) {
+		if url != "" {
+			refs = append(refs, albumFileRef{photoID: photoID, key: storageKeyFromURL(url, dir)})
+		}
+	}
+	for i := range album.Photos {
+		photo := &album.Photos[i]
+		add(photo.ID, photo.URLOriginal, "originals")
+		if photo.DerivativesPending {
+			continue
+		}
+		add(photo.ID, photo.URLDisplay, "display")
+		add(photo.ID, photo.URLThumbnail, "thumbnails")
+		add(photo.ID, photo.URLThumbnailPadded, "thumbnails")
+	}
+	add("", album.CoverURL, "covers")
+	return refs
+}
+
+// storageHas reports whether an object exists in storage.
+func storageHas(storage Storage, key string) (bool, error) {
+	_, err := storage.Size(key)
+	if errors.Is(err, ErrObjectNotFound) {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to check %s: %w", key, err)
+	}
+	return true, nil
+}
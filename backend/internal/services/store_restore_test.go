@@ -0,0 +1,136 @@
+package services
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/njoubert/nielsshootsfilm/backend/internal/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// putPhotoFiles stores placeholder files for every object a photo references.
+func putPhotoFiles(t *testing.T, storage Storage, photo models.Photo) {
+	t.Helper()
+	for _, key := range []string{
+		storageKeyFromURL(photo.URLOriginal, "originals"),
+		storageKeyFromURL(photo.URLDisplay, "display"),
+		storageKeyFromURL(photo.URLThumbnail, "thumbnails"),
+	} {
+		require.NoError(t, storage.Put(key, bytes.NewReader([]byte("x")), 1))
+	}
+}
+
+func restoreTestPhoto(id string) models.Photo {
+	return models.Photo{
+		ID:               id,
+		FilenameOriginal: id + ".jpg",
+		URLOriginal:      "/uploads/originals/" + id + ".jpg",
+		URLDisplay:       "/uploads/display/" + id + ".webp",
+		URLThumbnail:     "/uploads/thumbnails/" + id + ".webp",
+	}
+}
+
+func TestStoreBackupService_Restore(t *testing.T) {
+	albumService, _ := setupAlbumService(t)
+	photos := NewMemoryStorage()
+	service := NewStoreBackupService(albumService, photos, NewMemoryStorage(), 5)
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	service.now = func() time.Time { return now }
+
+	photo := restoreTestPhoto("photo-1")
+	putPhotoFiles(t, photos, photo)
+	album := &models.Album{Title: "Before", Visibility: "public", Photos: []models.Photo{photo}}
+	require.NoError(t, albumService.Create(album))
+	before, err := service.Snapshot(SnapshotTriggerManual)
+	require.NoError(t, err)
+
+	now = now.Add(time.Minute)
+	album.Title = "After"
+	require.NoError(t, albumService.Update(album.ID, album))
+
+	now = now.Add(time.Minute)
+	restore, err := service.Restore(before.Key)
+	require.NoError(t, err)
+	assert.Equal(t, before.Key, restore.Restored.Key)
+	require.NotNil(t, restore.Safety)
+	assert.Equal(t, SnapshotTriggerRestore, restore.Safety.Trigger)
+
+	restored, err := albumService.GetByID(album.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "Before", restored.Title)
+
+	// Restoring the safety snapshot undoes the restore
+	_, err = service.Restore(restore.Safety.Key)
+	require.NoError(t, err)
+	restored, err = albumService.GetByID(album.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "After", restored.Title)
+
+	_, err = service.Restore("albums-unknown.json")
+	assert.ErrorIs(t, err, ErrSnapshotNotFound)
+}
+
+func TestStoreBackupService_Restore_MissingFiles(t *testing.T) {
+	albumService, _ := setupAlbumService(t)
+	photos := NewMemoryStorage()
+	service := NewStoreBackupService(albumService, photos, NewMemoryStorage(), 5)
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	service.now = func() time.Time { return now }
+
+	kept, deleted := restoreTestPhoto("kept"), restoreTestPhoto("deleted")
+	putPhotoFiles(t, photos, kept)
+	putPhotoFiles(t, photos, deleted)
+	album := &models.Album{Title: "Before", Visibility: "public", Photos: []models.Photo{kept, deleted}}
+	require.NoError(t, albumService.Create(album))
+	before, err := service.Snapshot(SnapshotTriggerManual)
+	require.NoError(t, err)
+
+	// The photo is deleted after the snapshot, taking its files with it
+	now = now.Add(time.Minute)
+	album.Title = "After"
+	album.Photos = album.Photos[:1]
+	require.NoError(t, albumService.Update(album.ID, album))
+	require.NoError(t, photos.Delete("originals/deleted.jpg"))
+	require.NoError(t, photos.Delete("display/deleted.webp"))
+
+	_, err = service.Restore(before.Key)
+	require.ErrorIs(t, err, ErrInvalidSnapshot)
+	assert.Contains(t, err.Error(), "2 referenced files are missing")
+	assert.Contains(t, err.Error(), "originals/deleted.jpg")
+
+	// Nothing changed: the live data stands and no safety snapshot was taken
+	current, err := albumService.GetByID(album.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "After", current.Title)
+	snapshots, err := service.List()
+	require.NoError(t, err)
+	assert.Len(t, snapshots, 1)
+}
+
+func TestStoreBackupService_Restore_WaitsForAlbumChanges(t *testing.T) {
+	albumService, _ := setupAlbumService(t)
+	service := NewStoreBackupService(albumService, NewMemoryStorage(), NewMemoryStorage(), 5)
+
+	album := &models.Album{Title: "Before", Visibility: "public"}
+	require.NoError(t, albumService.Create(album))
+	before, err := service.Snapshot(SnapshotTriggerManual)
+	require.NoError(t, err)
+
+	// A change in flight holds the store lock; the restore must not write under it
+	albumService.mu.Lock()
+	done := make(chan error, 1)
+	go func() {
+		_, err := service.Restore(before.Key)
+		done <- err
+	}()
+	select {
+	case err := <-done:
+		albumService.mu.Unlock()
+		t.Fatalf("restore finished while the album store was locked: %v", err)
+	case <-time.After(50 * time.Millisecond):
+	}
+	albumService.mu.Unlock()
+	require.NoError(t, <-done)
+}
@@ -0,0 +1,129 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"math"
+
+	"github.com/davidbyttow/govips/v2/vips"
+)
+
+// ErrNoSubject is returned by a SubjectDetector that finds nothing standing out in an image.
+var ErrNoSubject = errors.New("no subject detected")
+
+// SubjectDetector locates the subject of a photo, such as a face, so square thumbnails can
+// be cropped around it instead of the centre.
+type SubjectDetector interface {
+	// DetectSubject returns the centre of the image's subject as fractions (0-1) of its
+	// width and height, or an error if it cannot find one.
+	DetectSubject(imageBytes []byte) (x, y float64, err error)
+}
+
+// saliencySampleEdge is the longest edge photos are shrunk to before looking for a subject.
+const saliencySampleEdge = 64
+
+// minSubjectContrast is how far, in 0-255 RGB distance, a pixel's colour must be from the
+// image's mean to count as part of a subject, so compression noise is not mistaken for one.
+const minSubjectContrast = 16
+
+// SaliencyDetector finds the subject of a photo as the region whose colours stand out most
+// from the photo's average colour. It needs no model files, and suits the common case of a
+// subject against a plainer background.
+type SaliencyDetector struct{}
+
+// DetectSubject returns the centroid of the pixels furthest from the image's mean colour,
+// weighted by how far they are. Images where nothing stands out clearly, such as flat
+// colours, have no subject.
+func (SaliencyDetector) DetectSubject(imageBytes []byte) (float64, float64, error) {
+	// Loaded as stored, not auto-rotated, to match the frame thumbnails are cropped from
+	img, err := vips.NewImageFromBuffer(imageBytes)
+	if err != nil {
+		return 0, 0, fmt.Errorf("failed to load image: %w", err)
+	}
+	defer img.Close()
+	if err := img.ThumbnailWithSize(saliencySampleEdge, saliencySampleEdge, vips.InterestingNone, vips.SizeDown); err != nil {
+		return 0, 0, fmt.Errorf("failed to shrink image: %w", err)
+	}
+
+	if err := img.ToColorSpace(vips.InterpretationSRGB); err != nil {
+		return 0, 0, fmt.Errorf("failed to convert to sRGB: %w", err)
+	}
+	if img.BandFormat() != vips.BandFormatUchar {
+		if err := img.Cast(vips.BandFormatUchar); err != nil {
+			return 0, 0, fmt.Errorf("failed to convert to 8 bits: %w", err)
+		}
+	}
+
+	pixels, err := img.ToBytes()
+	if err != nil {
+		return 0, 0, fmt.Errorf("failed to read pixels: %w", err)
+	}
+	width, height, bands := img.Width(), img.Height(), img.Bands()
+	count := width * height
+	if count == 0 || len(pixels) < count*bands {
+		return 0, 0, fmt.Errorf("unexpected pixel data size %d for %dx%d image", len(pixels), width, height)
+	}
+	channels := min(bands, 3) // Alpha does not count towards standing out
+
+	var mean [3]float64
+	for i := range count {
+		for c := range channels {
+			mean[c] += float64(pixels[i*bands+c])
+		}
+	}
+	for c := range channels {
+		mean[c] /= float64(count)
+	}
+
+	distances := make([]float64, count)
+	var meanDistance float64
+	for i := range count {
+		var sum float64
+		for c := range channels {
+			d := float64(pixels[i*bands+c]) - mean[c]
+			sum += d * d
+		}
+		distances[i] = math.Sqrt(sum)
+		meanDistance += distances[i]
+	}
+	meanDistance /= float64(count)
+
+	// Only pixels standing out more than average count, favouring the strongest
+	threshold := max(meanDistance, minSubjectContrast)
+	var total, sumX, sumY float64
+	for i, distance := range distances {
+		if distance <= threshold {
+			continue
+		}
+		weight := (distance - threshold) * (distance - threshold)
+		total += weight
+		sumX += weight * (float64(i%width) + 0.5)
+		sumY += weight * (float64(i/width) + 0.5)
+	}
+	if total == 0 {
+		return 0, 0, ErrNoSubject
+	}
+	return sumX / total / float64(width), sumY / total / float64(height), nil
+}
+
+// squareCropOrigin returns the top-left corner of the largest square in a width x height
+// image, centred on the subject if the detector finds one and on the image otherwise.
+func (s *ImageService) squareCropOrigin(imageBytes []byte, width, height int) (int, int) {
+	side := min(width, height)
+	left, top := (width-side)/2, (height-side)/2
+	if s.subjectDetector == nil || width == height {
+		return left, top
+	}
+
+	x, y, err := s.subjectDetector.DetectSubject(imageBytes)
+	if err != nil {
+		if s.logger != nil && !errors.Is(err, ErrNoSubject) {
+			s.logger.Warn("subject detection failed, centre-cropping thumbnail", slog.String("error", err.Error()))
+		}
+		return left, top
+	}
+	left = min(max(int(math.Round(x*float64(width)))-side/2, 0), width-side)
+	top = min(max(int(math.Round(y*float64(height)))-side/2, 0), height-side)
+	return left, top
+}
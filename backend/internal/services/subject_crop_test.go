@@ -0,0 +1,123 @@
+package services
+
+import (
+	"bytes"
+	"errors"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"io"
+	"log/slog"
+	"strings"
+	"testing"
+
+	"github.com/davidbyttow/govips/v2/vips"
+	"github.com/njoubert/nielsshootsfilm/backend/internal/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// failingDetector is a SubjectDetector that never finds anything.
+type failingDetector struct{}
+
+func (failingDetector) DetectSubject([]byte) (float64, float64, error) {
+	return 0, 0, errors.New("detector unavailable")
+}
+
+// createOffCentreSubjectJPEG draws a red disc of the given radius centred at (cx, cy) on a
+// plain grey background. A zero radius leaves the background plain.
+func createOffCentreSubjectJPEG(t *testing.T, width, height, cx, cy, radius int) []byte {
+	t.Helper()
+
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+	for y := range height {
+		for x := range width {
+			c := color.RGBA{R: 128, G: 128, B: 128, A: 255}
+			if radius > 0 && (x-cx)*(x-cx)+(y-cy)*(y-cy) <= radius*radius {
+				c = color.RGBA{R: 220, G: 30, B: 30, A: 255}
+			}
+			img.Set(x, y, c)
+		}
+	}
+	var buf bytes.Buffer
+	require.NoError(t, jpeg.Encode(&buf, img, &jpeg.Options{Quality: 90}))
+	return buf.Bytes()
+}
+
+// redColumns decodes a photo's stored thumbnail and returns its width and the leftmost and
+// rightmost columns containing red pixels, or -1s if there are none.
+func redColumns(t *testing.T, imageService *ImageService, photo models.Photo) (width, first, last int) {
+	t.Helper()
+
+	data, err := imageService.Storage().Get(strings.TrimPrefix(photo.URLThumbnail, "/uploads/"))
+	require.NoError(t, err)
+	img, err := vips.NewImageFromBuffer(data)
+	require.NoError(t, err)
+	defer img.Close()
+	pixels, err := img.ToBytes()
+	require.NoError(t, err)
+
+	width, bands := img.Width(), img.Bands()
+	first, last = -1, -1
+	for i := range img.Width() * img.Height() {
+		r, g := pixels[i*bands], pixels[i*bands+1]
+		if r > 180 && g < 80 {
+			x := i % width
+			if first == -1 || x < first {
+				first = x
+			}
+			last = max(last, x)
+		}
+	}
+	return width, first, last
+}
+
+func TestSaliencyDetector_DetectSubject(t *testing.T) {
+	x, y, err := SaliencyDetector{}.DetectSubject(createOffCentreSubjectJPEG(t, 1200, 600, 1050, 300, 100))
+	require.NoError(t, err)
+	assert.InDelta(t, 1050.0/1200, x, 0.03)
+	assert.InDelta(t, 0.5, y, 0.03)
+
+	// Flat images have nothing to crop around
+	_, _, err = SaliencyDetector{}.DetectSubject(createOffCentreSubjectJPEG(t, 300, 200, 0, 0, 0))
+	assert.ErrorIs(t, err, ErrNoSubject)
+}
+
+func TestImageService_SubjectCrop(t *testing.T) {
+	imageService, err := NewImageService(t.TempDir(), nil, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	require.NoError(t, err)
+	imageService.SetStorage(NewMemoryStorage())
+	squared := &models.Album{ThumbnailFit: models.ThumbnailFitCover}
+
+	// The subject sits at the right edge, outside a centre crop of the middle half
+	photo, err := imageService.ProcessBytes("subject.jpg", createOffCentreSubjectJPEG(t, 1200, 600, 1050, 300, 100))
+	require.NoError(t, err)
+
+	rendered, err := imageService.RenderThumbnail(squared, *photo)
+	require.NoError(t, err)
+	width, first, _ := redColumns(t, imageService, rendered)
+	assert.Equal(t, 600, width)
+	assert.Equal(t, -1, first, "a centre crop should cut the subject off")
+
+	// With a detector the crop shifts right, as far as it can, to take in the whole subject
+	imageService.SetSubjectDetector(SaliencyDetector{})
+	rendered, err = imageService.RenderThumbnail(squared, *photo)
+	require.NoError(t, err)
+	width, first, last := redColumns(t, imageService, rendered)
+	assert.Equal(t, 600, width)
+	assert.InDelta(t, 1050-100-600, first, 8)
+	assert.InDelta(t, 1050+100-600, last, 8)
+
+	// Contain thumbnails are never cropped
+	rendered, err = imageService.RenderThumbnail(&models.Album{ThumbnailFit: models.ThumbnailFitContain}, *photo)
+	require.NoError(t, err)
+	width, _, _ = redColumns(t, imageService, rendered)
+	assert.Equal(t, 800, width)
+
+	// A failing detector falls back to the centre
+	imageService.SetSubjectDetector(failingDetector{})
+	rendered, err = imageService.RenderThumbnail(squared, *photo)
+	require.NoError(t, err)
+	_, first, _ = redColumns(t, imageService, rendered)
+	assert.Equal(t, -1, first)
+}
@@ -0,0 +1,71 @@
+package services
+
+import (
+	"fmt"
+
+	"github.com/davidbyttow/govips/v2/vips"
+	"github.com/njoubert/nielsshootsfilm/backend/internal/models"
+)
+
+// ThumbnailFit returns the thumbnail fit for an album's photos: the album's own override,
+// else the site's portfolio setting, else contain. A nil album gets the site setting.
+func (s *ImageService) ThumbnailFit(album *models.Album) string {
+	if album != nil && album.ThumbnailFit != "" {
+		return album.ThumbnailFit
+	}
+	if s.configService != nil {
+		if config, err := s.configService.Get(); err == nil && config.Portfolio.ThumbnailFit == models.ThumbnailFitCover {
+			return models.ThumbnailFitCover
+		}
+	}
+	return models.ThumbnailFitContain
+}
+
+// generateThumbnail stores the thumbnail of an image under dstKey. With the cover fit the
+// image is cropped to a square first, around its subject if a subject detector is set and
+// finds one, else around its centre; contain keeps its aspect ratio.
+func (s *ImageService) generateThumbnail(imageBytes []byte, dstKey, fit string) (int64, error) {
+	// Load image with vips (multi-frame formats load only their first frame)
+	img, err := vips.NewImageFromBuffer(imageBytes)
+	if err != nil {
+		return 0, fmt.Errorf("failed to load image: %w", err)
+	}
+	defer img.Close()
+
+	if err := convertToSRGB(img); err != nil {
+		return 0, err
+	}
+
+	if fit == models.ThumbnailFitCover {
+		width, height := img.Width(), img.Height()
+		side := min(width, height)
+		left, top := s.squareCropOrigin(imageBytes, width, height)
+		if err := img.ExtractArea(left, top, side, side); err != nil {
+			return 0, fmt.Errorf("failed to crop thumbnail: %w", err)
+		}
+	}
+
+	return s.storeResized(img, dstKey, thumbnailMaxSize, thumbnailQuality, false)
+}
+
+// RenderThumbnail rebuilds a photo's thumbnail from its original with the album's thumbnail
+// fit. Returns a copy of the photo with the new thumbnail file size.
+// Returns an error wrapping ErrObjectNotFound if the original is missing.
+func (s *ImageService) RenderThumbnail(album *models.Album, photo models.Photo) (models.Photo, error) {
+	// Acquire semaphore to limit concurrent VIPS operations
+	s.processSem <- struct{}{}
+	defer func() { <-s.processSem }()
+
+	original, err := s.storage.Get(storageKeyFromURL(photo.URLOriginal, "originals"))
+	if err != nil {
+		return photo, fmt.Errorf("failed to read original: %w", err)
+	}
+
+	thumbnailSize, err := s.generateThumbnail(original, storageKeyFromURL(photo.URLThumbnail, "thumbnails"), s.ThumbnailFit(album))
+	if err != nil {
+		return photo, fmt.Errorf("failed to generate thumbnail: %w", err)
+	}
+
+	photo.FileSizeThumbnail = thumbnailSize
+	return photo, nil
+}
@@ -0,0 +1,72 @@
+package services
+
+import (
+	"io"
+	"log/slog"
+	"strings"
+	"testing"
+
+	"github.com/davidbyttow/govips/v2/vips"
+	"github.com/njoubert/nielsshootsfilm/backend/internal/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// thumbnailSize decodes a photo's stored thumbnail and returns its dimensions.
+func thumbnailSize(t *testing.T, imageService *ImageService, photo models.Photo) (int, int) {
+	t.Helper()
+
+	data, err := imageService.Storage().Get(strings.TrimPrefix(photo.URLThumbnail, "/uploads/"))
+	require.NoError(t, err)
+	img, err := vips.NewImageFromBuffer(data)
+	require.NoError(t, err)
+	defer img.Close()
+	return img.Width(), img.Height()
+}
+
+func TestImageService_ThumbnailFit(t *testing.T) {
+	fileService, err := NewFileService(t.TempDir())
+	require.NoError(t, err)
+	configService := NewSiteConfigService(fileService)
+
+	imageService, err := NewImageService(t.TempDir(), configService, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	require.NoError(t, err)
+	imageService.SetStorage(NewMemoryStorage())
+
+	// Contain is the default and keeps the aspect ratio
+	assert.Equal(t, models.ThumbnailFitContain, imageService.ThumbnailFit(nil))
+	landscape, err := imageService.ProcessBytes("landscape.jpg", createTestJPEG(t, 1600, 1000))
+	require.NoError(t, err)
+	width, height := thumbnailSize(t, imageService, *landscape)
+	assert.Equal(t, 800, width)
+	assert.Equal(t, 500, height)
+
+	// Cover centre-crops to a square
+	config, err := configService.Get()
+	require.NoError(t, err)
+	config.Portfolio.ThumbnailFit = models.ThumbnailFitCover
+	require.NoError(t, configService.Update(config))
+	assert.Equal(t, models.ThumbnailFitCover, imageService.ThumbnailFit(nil))
+
+	portrait, err := imageService.ProcessBytes("portrait.jpg", createTestJPEG(t, 600, 900))
+	require.NoError(t, err)
+	width, height = thumbnailSize(t, imageService, *portrait)
+	assert.Equal(t, 600, width)
+	assert.Equal(t, 600, height)
+
+	// An album's own fit overrides the site's
+	contained := &models.Album{ThumbnailFit: models.ThumbnailFitContain}
+	assert.Equal(t, models.ThumbnailFitContain, imageService.ThumbnailFit(contained))
+	rendered, err := imageService.RenderThumbnail(contained, *portrait)
+	require.NoError(t, err)
+	width, height = thumbnailSize(t, imageService, rendered)
+	assert.Equal(t, 533, width)
+	assert.Equal(t, 800, height)
+
+	rendered, err = imageService.RenderThumbnail(&models.Album{}, *landscape)
+	require.NoError(t, err)
+	width, height = thumbnailSize(t, imageService, rendered)
+	assert.Equal(t, 800, width)
+	assert.Equal(t, 800, height)
+	assert.Positive(t, rendered.FileSizeThumbnail)
+}
@@ -0,0 +1,147 @@
+package services
+
+import (
+	"fmt"
+	"math"
+	"path"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/davidbyttow/govips/v2/vips"
+)
+
+// ThumbnailPaddingBlur fills the padding around a thumbnail with a blurred, enlarged copy
+// of the photo instead of a solid color.
+const ThumbnailPaddingBlur = "blur"
+
+// thumbnailPaddingBlurSigma is how strongly the blurred fill is blurred, in pixels.
+const thumbnailPaddingBlurSigma = 24
+
+// paddingColorPattern matches the #rrggbb colors padded thumbnails can be filled with.
+var paddingColorPattern = regexp.MustCompile(`^#[0-9a-fA-F]{6}$`)
+
+// ThumbnailPadding configures padded thumbnails: every photo fitted, without cropping, into
+// a canvas of one fixed aspect ratio, so portrait and landscape photos fill identical grid cells.
+type ThumbnailPadding struct {
+	Aspect     float64 // Canvas width divided by height
+	Background string  // #rrggbb, or ThumbnailPaddingBlur
+}
+
+// ParseThumbnailPadding parses an aspect ratio written as width:height (e.g. 4:3) and a
+// background of #rrggbb or "blur".
+func ParseThumbnailPadding(aspect, background string) (*ThumbnailPadding, error) {
+	width, height, ok := strings.Cut(aspect, ":")
+	if !ok {
+		return nil, fmt.Errorf("invalid thumbnail aspect %q: want width:height", aspect)
+	}
+	w, errW := strconv.ParseFloat(width, 64)
+	h, errH := strconv.ParseFloat(height, 64)
+	if errW != nil || errH != nil || !(w > 0) || !(h > 0) || math.IsInf(w, 0) || math.IsInf(h, 0) {
+		return nil, fmt.Errorf("invalid thumbnail aspect %q: want positive width:height", aspect)
+	}
+	if background != ThumbnailPaddingBlur && !paddingColorPattern.MatchString(background) {
+		return nil, fmt.Errorf("invalid thumbnail background %q: want #rrggbb or blur", background)
+	}
+	return &ThumbnailPadding{Aspect: w / h, Background: strings.ToLower(background)}, nil
+}
+
+// canvas returns the padded thumbnail's size: the longer side is thumbnailMaxSize.
+func (p *ThumbnailPadding) canvas() (width, height int) {
+	if p.Aspect >= 1 {
+		return thumbnailMaxSize, max(1, int(math.Round(thumbnailMaxSize/p.Aspect)))
+	}
+	return max(1, int(math.Round(thumbnailMaxSize*p.Aspect))), thumbnailMaxSize
+}
+
+// color returns the solid background color.
+func (p *ThumbnailPadding) color() *vips.Color {
+	value, _ := strconv.ParseUint(p.Background[1:], 16, 32)
+	return &vips.Color{R: uint8(value >> 16), G: uint8(value >> 8), B: uint8(value)}
+}
+
+// SetThumbnailPadding has a padded thumbnail stored alongside each photo's regular one,
+// rendered on upload and when derivatives are regenerated. A nil padding turns this off;
+// padded thumbnails already stored are kept.
+func (s *ImageService) SetThumbnailPadding(padding *ThumbnailPadding) {
+	s.thumbnailPadding = padding
+}
+
+// paddedThumbnailKey returns the storage key of the padded version of a thumbnail, next to it.
+func paddedThumbnailKey(thumbnailKey string) string {
+	return strings.TrimSuffix(thumbnailKey, path.Ext(thumbnailKey)) + "_padded.webp"
+}
+
+// generatePaddedThumbnail stores the padded thumbnail of an image under dstKey: the image is
+// shrunk to fit the canvas and centred on the background. Images smaller than the canvas
+// are not enlarged.
+func (s *ImageService) generatePaddedThumbnail(imageBytes []byte, dstKey string, padding *ThumbnailPadding) (int64, error) {
+	canvasWidth, canvasHeight := padding.canvas()
+
+	img, err := vips.NewImageFromBuffer(imageBytes)
+	if err != nil {
+		return 0, fmt.Errorf("failed to load image: %w", err)
+	}
+	defer img.Close()
+
+	if err := convertToSRGB(img); err != nil {
+		return 0, err
+	}
+
+	scale := math.Min(float64(canvasWidth)/float64(img.Width()), float64(canvasHeight)/float64(img.Height()))
+	if scale < 1.0 {
+		if err := img.Resize(scale, vips.KernelLanczos3); err != nil {
+			return 0, fmt.Errorf("failed to resize image: %w", err)
+		}
+	}
+	// Rounding can leave the resized image a pixel over the canvas
+	width, height := min(img.Width(), canvasWidth), min(img.Height(), canvasHeight)
+	if width != img.Width() || height != img.Height() {
+		if err := img.ExtractArea(0, 0, width, height); err != nil {
+			return 0, fmt.Errorf("failed to crop thumbnail: %w", err)
+		}
+	}
+	left, top := (canvasWidth-width)/2, (canvasHeight-height)/2
+
+	if padding.Background != ThumbnailPaddingBlur {
+		if err := img.EmbedBackground(left, top, canvasWidth, canvasHeight, padding.color()); err != nil {
+			return 0, fmt.Errorf("failed to pad thumbnail: %w", err)
+		}
+		return s.storeResized(img, dstKey, thumbnailMaxSize, thumbnailQuality, false)
+	}
+
+	// The fill is the photo cropped to cover the canvas, then blurred
+	fill, err := vips.NewImageFromBuffer(imageBytes)
+	if err != nil {
+		return 0, fmt.Errorf("failed to load image: %w", err)
+	}
+	defer fill.Close()
+
+	if err := convertToSRGB(fill); err != nil {
+		return 0, err
+	}
+	if err := fill.ThumbnailWithSize(canvasWidth, canvasHeight, vips.InterestingCentre, vips.SizeBoth); err != nil {
+		return 0, fmt.Errorf("failed to size thumbnail fill: %w", err)
+	}
+	if err := fill.GaussianBlur(thumbnailPaddingBlurSigma); err != nil {
+		return 0, fmt.Errorf("failed to blur thumbnail fill: %w", err)
+	}
+	if err := fill.Composite(img, vips.BlendModeOver, left, top); err != nil {
+		return 0, fmt.Errorf("failed to pad thumbnail: %w", err)
+	}
+	return s.storeResized(fill, dstKey, thumbnailMaxSize, thumbnailQuality, false)
+}
+
+// renderPaddedThumbnail stores the padded version of the thumbnail at thumbnailKey and
+// returns its URL, or "" if thumbnail padding is off.
+func (s *ImageService) renderPaddedThumbnail(imageBytes []byte, thumbnailKey string) (string, error) {
+	padding := s.thumbnailPadding
+	if padding == nil {
+		return "", nil
+	}
+	key := paddedThumbnailKey(thumbnailKey)
+	if _, err := s.generatePaddedThumbnail(imageBytes, key, padding); err != nil {
+		return "", fmt.Errorf("failed to generate padded thumbnail: %w", err)
+	}
+	return "/uploads/" + key, nil
+}
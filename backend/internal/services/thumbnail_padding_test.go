@@ -0,0 +1,115 @@
+package services
+
+import (
+	"testing"
+
+	"github.com/davidbyttow/govips/v2/vips"
+	"github.com/njoubert/nielsshootsfilm/backend/internal/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseThumbnailPadding(t *testing.T) {
+	padding, err := ParseThumbnailPadding("4:3", "#FFFFFF")
+	require.NoError(t, err)
+	assert.InDelta(t, 4.0/3, padding.Aspect, 1e-9)
+	assert.Equal(t, "#ffffff", padding.Background)
+
+	padding, err = ParseThumbnailPadding("1:1", ThumbnailPaddingBlur)
+	require.NoError(t, err)
+	assert.Equal(t, ThumbnailPaddingBlur, padding.Background)
+
+	for _, aspect := range []string{"", "4", "4:0", "-1:1", "a:b", "Inf:1"} {
+		_, err := ParseThumbnailPadding(aspect, "#000000")
+		assert.Error(t, err, aspect)
+	}
+	for _, background := range []string{"", "white", "#fff", "#12345g"} {
+		_, err := ParseThumbnailPadding("1:1", background)
+		assert.Error(t, err, background)
+	}
+}
+
+func TestImageService_PaddedThumbnails(t *testing.T) {
+	for _, background := range []string{"#202020", ThumbnailPaddingBlur} {
+		t.Run(background, func(t *testing.T) {
+			imageService, err := NewImageService(t.TempDir(), nil, nil)
+			require.NoError(t, err)
+			storage := NewMemoryStorage()
+			imageService.SetStorage(storage)
+			padding, err := ParseThumbnailPadding("4:3", background)
+			require.NoError(t, err)
+			imageService.SetThumbnailPadding(padding)
+
+			// Portrait, landscape, and small inputs all get the same canvas
+			var sizes [][2]int
+			for _, size := range [][2]int{{600, 1200}, {1600, 900}, {120, 90}} {
+				photo, err := imageService.ProcessBytes("photo.jpg", createTestJPEG(t, size[0], size[1]))
+				require.NoError(t, err)
+				require.NotEmpty(t, photo.URLThumbnailPadded)
+				assert.NotEqual(t, photo.URLThumbnail, photo.URLThumbnailPadded)
+
+				data, err := storage.Get(storageKeyFromURL(photo.URLThumbnailPadded, "thumbnails"))
+				require.NoError(t, err)
+				img, err := vips.NewImageFromBuffer(data)
+				require.NoError(t, err)
+				sizes = append(sizes, [2]int{img.Width(), img.Height()})
+				img.Close()
+
+				// The regular thumbnail keeps the photo's own aspect ratio
+				_, err = storage.Get(storageKeyFromURL(photo.URLThumbnail, "thumbnails"))
+				require.NoError(t, err)
+			}
+			for _, size := range sizes {
+				assert.Equal(t, [2]int{800, 600}, size)
+			}
+		})
+	}
+}
+
+func TestImageService_PaddedThumbnails_Lifecycle(t *testing.T) {
+	imageService, err := NewImageService(t.TempDir(), nil, nil)
+	require.NoError(t, err)
+	storage := NewMemoryStorage()
+	imageService.SetStorage(storage)
+
+	// Off by default
+	photo, err := imageService.ProcessBytes("photo.jpg", createTestJPEG(t, 640, 480))
+	require.NoError(t, err)
+	assert.Empty(t, photo.URLThumbnailPadded)
+
+	// Regenerating derivatives adds one once padding is on
+	padding, err := ParseThumbnailPadding("1:1", "#ffffff")
+	require.NoError(t, err)
+	imageService.SetThumbnailPadding(padding)
+	regenerated, err := imageService.RegenerateDerivatives(&models.Album{}, *photo)
+	require.NoError(t, err)
+	require.NotEmpty(t, regenerated.URLThumbnailPadded)
+	paddedKey := storageKeyFromURL(regenerated.URLThumbnailPadded, "thumbnails")
+	_, err = storage.Get(paddedKey)
+	require.NoError(t, err)
+
+	// And deleting the photo removes it
+	require.NoError(t, imageService.DeletePhoto(&regenerated))
+	_, err = storage.Get(paddedKey)
+	assert.ErrorIs(t, err, ErrObjectNotFound)
+}
+
+func TestImageService_PaddedThumbnails_Lazy(t *testing.T) {
+	imageService := newLazyImageService(t)
+	padding, err := ParseThumbnailPadding("1:1", "#ffffff")
+	require.NoError(t, err)
+	imageService.SetThumbnailPadding(padding)
+
+	// The padded thumbnail has its URL from upload, and is rendered with the thumbnail
+	photo, err := imageService.ProcessBytes("lazy.jpg", createTestJPEG(t, 640, 480))
+	require.NoError(t, err)
+	require.NotEmpty(t, photo.URLThumbnailPadded)
+	paddedKey := storageKeyFromURL(photo.URLThumbnailPadded, "thumbnails")
+	_, err = imageService.Storage().Size(paddedKey)
+	assert.ErrorIs(t, err, ErrObjectNotFound)
+
+	_, err = imageService.GenerateDerivative(&models.Album{}, *photo, "thumbnail")
+	require.NoError(t, err)
+	_, err = imageService.Storage().Size(paddedKey)
+	assert.NoError(t, err)
+}
@@ -0,0 +1,123 @@
+package services
+
+import (
+	"fmt"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/njoubert/nielsshootsfilm/backend/internal"
+)
+
+// imageFileTypes maps every image file extension we can process to its MIME type. It is the
+// single source of truth for which uploads are accepted and what clients are told about it.
+var imageFileTypes = map[string]string{
+	".jpg":  "image/jpeg",
+	".jpeg": "image/jpeg",
+	".png":  "image/png",
+	".webp": "image/webp",
+	".gif":  "image/gif",
+	".tif":  "image/tiff",
+	".tiff": "image/tiff",
+	".heic": "image/heic",
+	".heif": "image/heif",
+}
+
+// defaultMaxImageSizeMB is the largest image accepted when the site config does not set one.
+const defaultMaxImageSizeMB = 50
+
+// UploadConfig describes which files uploads accept, so clients can check before sending them.
+type UploadConfig struct {
+	Extensions       []string `json:"extensions"` // Lowercase with a leading dot, e.g. ".jpg"
+	MimeTypes        []string `json:"mime_types"`
+	MaxFileSizeBytes int64    `json:"max_file_size_bytes"`
+}
+
+// IsSupportedImageFilename reports whether a filename has an image extension we can process.
+func IsSupportedImageFilename(filename string) bool {
+	_, ok := imageFileTypes[strings.ToLower(filepath.Ext(filename))]
+	return ok
+}
+
+// ValidateAllowedExtensions checks that every extension in a configured allow list is one we
+// can process, written with a leading dot, e.g. ".jpg".
+func ValidateAllowedExtensions(extensions []string) error {
+	for _, ext := range extensions {
+		if _, ok := imageFileTypes[strings.ToLower(ext)]; !ok {
+			supported := make([]string, 0, len(imageFileTypes))
+			for known := range imageFileTypes {
+				supported = append(supported, known)
+			}
+			slices.Sort(supported)
+			return fmt.Errorf("unsupported file extension %q (supported: %s)", ext, strings.Join(supported, ", "))
+		}
+	}
+	return nil
+}
+
+// UploadConfig returns the file types and size currently accepted for uploads.
+func (s *ImageService) UploadConfig() UploadConfig {
+	allowed := s.allowedFileTypes()
+	config := UploadConfig{
+		Extensions:       make([]string, 0, len(allowed)),
+		MimeTypes:        []string{},
+		MaxFileSizeBytes: min(int64(s.maxImageSizeMB())*1024*1024, internal.MaxUploadFileSize),
+	}
+	for ext, mimeType := range allowed {
+		config.Extensions = append(config.Extensions, ext)
+		if !slices.Contains(config.MimeTypes, mimeType) {
+			config.MimeTypes = append(config.MimeTypes, mimeType)
+		}
+	}
+	slices.Sort(config.Extensions)
+	slices.Sort(config.MimeTypes)
+	return config
+}
+
+// AllowsFilename reports whether uploads currently accept a file with this name's extension.
+func (s *ImageService) AllowsFilename(filename string) bool {
+	_, ok := s.allowedFileTypes()[strings.ToLower(filepath.Ext(filename))]
+	return ok
+}
+
+// allowsMimeType reports whether uploads currently accept files of this detected MIME type.
+func (s *ImageService) allowsMimeType(mimeType string) bool {
+	for _, allowed := range s.allowedFileTypes() {
+		if allowed == mimeType {
+			return true
+		}
+	}
+	return false
+}
+
+// allowedFileTypes returns the image file types accepted for upload: those in the site's
+// storage.allowed_extensions list, or every type we can process if the list is empty.
+func (s *ImageService) allowedFileTypes() map[string]string {
+	if s.configService == nil {
+		return imageFileTypes
+	}
+	config, err := s.configService.Get()
+	if err != nil || len(config.Storage.AllowedExtensions) == 0 {
+		return imageFileTypes
+	}
+
+	allowed := make(map[string]string, len(config.Storage.AllowedExtensions))
+	for _, ext := range config.Storage.AllowedExtensions {
+		ext = strings.ToLower(ext)
+		if mimeType, ok := imageFileTypes[ext]; ok {
+			allowed[ext] = mimeType
+		}
+	}
+	return allowed
+}
+
+// maxImageSizeMB returns the site's configured image size limit, or the default.
+func (s *ImageService) maxImageSizeMB() int {
+	if s.configService != nil {
+		config, err := s.configService.Get()
+		if err == nil && config.Storage.MaxImageSizeMB > 0 {
+			return config.Storage.MaxImageSizeMB
+		}
+	}
+	return defaultMaxImageSizeMB
+}
@@ -0,0 +1,61 @@
+package services
+
+import (
+	"bytes"
+	"image"
+	"image/png"
+	"testing"
+
+	"github.com/njoubert/nielsshootsfilm/backend/internal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestImageService_UploadConfig(t *testing.T) {
+	// Without a site config every supported type is accepted, up to the default size
+	imageService, err := NewImageService(t.TempDir(), nil, nil)
+	require.NoError(t, err)
+	config := imageService.UploadConfig()
+	assert.Equal(t, []string{".gif", ".heic", ".heif", ".jpeg", ".jpg", ".png", ".tif", ".tiff", ".webp"}, config.Extensions)
+	assert.Equal(t, []string{"image/gif", "image/heic", "image/heif", "image/jpeg", "image/png", "image/tiff", "image/webp"}, config.MimeTypes)
+	assert.Equal(t, int64(defaultMaxImageSizeMB<<20), config.MaxFileSizeBytes)
+
+	// A configured allow list and size limit narrow what is reported and what is processed
+	configService := createTestConfigService(t, 80)
+	siteConfig, err := configService.Get()
+	require.NoError(t, err)
+	siteConfig.Storage.MaxImageSizeMB = 20
+	siteConfig.Storage.AllowedExtensions = []string{".JPG", ".jpeg", ".bmp"}
+	require.NoError(t, configService.Update(siteConfig))
+
+	imageService, err = NewImageService(t.TempDir(), configService, nil)
+	require.NoError(t, err)
+	imageService.SetStorage(NewMemoryStorage())
+	config = imageService.UploadConfig()
+	assert.Equal(t, []string{".jpeg", ".jpg"}, config.Extensions)
+	assert.Equal(t, []string{"image/jpeg"}, config.MimeTypes)
+	assert.Equal(t, int64(20<<20), config.MaxFileSizeBytes)
+
+	assert.True(t, imageService.AllowsFilename("frame.JPG"))
+	assert.False(t, imageService.AllowsFilename("frame.png"))
+
+	_, err = imageService.ProcessBytes("frame.jpg", createTestJPEG(t, 64, 48))
+	require.NoError(t, err)
+
+	var pngData bytes.Buffer
+	require.NoError(t, png.Encode(&pngData, image.NewRGBA(image.Rect(0, 0, 64, 48))))
+	_, err = imageService.ProcessBytes("frame.png", pngData.Bytes())
+	assert.EqualError(t, err, "unsupported file type: image/png")
+
+	// The absolute limit caps whatever is configured
+	siteConfig.Storage.MaxImageSizeMB = 500
+	require.NoError(t, configService.Update(siteConfig))
+	assert.Equal(t, int64(internal.MaxUploadFileSize), imageService.UploadConfig().MaxFileSizeBytes)
+}
+
+func TestValidateAllowedExtensions(t *testing.T) {
+	assert.NoError(t, ValidateAllowedExtensions(nil))
+	assert.NoError(t, ValidateAllowedExtensions([]string{".jpg", ".HEIC"}))
+	assert.ErrorContains(t, ValidateAllowedExtensions([]string{".jpg", "png"}), `unsupported file extension "png"`)
+	assert.ErrorContains(t, ValidateAllowedExtensions([]string{".bmp"}), "supported: .gif, .heic")
+}
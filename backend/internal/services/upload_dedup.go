@@ -0,0 +1,91 @@
+package services
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"slices"
+
+	"github.com/njoubert/nielsshootsfilm/backend/internal/models"
+)
+
+// contentHash returns the hex SHA-256 of an uploaded file, recorded as a photo's ContentHash.
+func contentHash(fileBytes []byte) string {
+	sum := sha256.Sum256(fileBytes)
+	return hex.EncodeToString(sum[:])
+}
+
+// photosByContent indexes an album's photos by content hash, for spotting repeat uploads.
+// Photos uploaded before hashes were recorded are left out. A nil album gives nil.
+func photosByContent(album *models.Album) map[string]models.Photo {
+	if album == nil {
+		return nil
+	}
+	byHash := make(map[string]models.Photo, len(album.Photos))
+	for _, photo := range album.Photos {
+		if _, seen := byHash[photo.ContentHash]; photo.ContentHash != "" && !seen {
+			byHash[photo.ContentHash] = photo
+		}
+	}
+	return byHash
+}
+
+// process stores an upload and its derivatives, unless the album already holds a photo with
+// the same content: then the new photo shares that photo's stored files, and nothing is
+// decoded or encoded. Callers must hold the processing semaphore.
+func (u AlbumUploads) process(filename string, fileBytes []byte) (*models.Photo, error) {
+	if existing, ok := u.existing[contentHash(fileBytes)]; ok {
+		photo := sharedPhoto(existing, filename)
+		// EXIF is cheap to read again, and the existing photo's may have been edited since,
+		// e.g. given a capture date on upload
+		if exifData, err := u.service.extractEXIFFromBytes(fileBytes); err == nil {
+			photo.EXIF = exifData
+		}
+		if u.service.keywordTagging() {
+			photo.Tags = keywordTags(photo.Tags, embeddedKeywords(fileBytes))
+		}
+		return photo, nil
+	}
+	return u.service.processImage(filename, fileBytes, u.minEdge)
+}
+
+// Reused reports whether an upload processed by u shares the stored files of a photo already
+// in the album, in which case they already have the album's watermark and thumbnail fit.
+func (u AlbumUploads) Reused(photo *models.Photo) bool {
+	existing, ok := u.existing[photo.ContentHash]
+	return ok && existing.URLOriginal == photo.URLOriginal
+}
+
+// sharedPhoto returns a new photo, named filename, referring to the stored files of an
+// existing photo with the same content. Only what was derived from the file, apart from its
+// EXIF data, is copied; titles, captions, and other details are left for the new photo's own.
+func sharedPhoto(existing models.Photo, filename string) *models.Photo {
+	photo := &models.Photo{
+		FilenameOriginal:   filename,
+		MediaType:          existing.MediaType,
+		IsAnimated:         existing.IsAnimated,
+		URLOriginal:        existing.URLOriginal,
+		URLDisplay:         existing.URLDisplay,
+		URLThumbnail:       existing.URLThumbnail,
+		URLThumbnailPadded: existing.URLThumbnailPadded,
+		Width:              existing.Width,
+		Height:             existing.Height,
+		FileSizeOriginal:   existing.FileSizeOriginal,
+		OriginalDownscaled: existing.OriginalDownscaled,
+		FileSizeDisplay:    existing.FileSizeDisplay,
+		FileSizeThumbnail:  existing.FileSizeThumbnail,
+		DerivativesPending: existing.DerivativesPending,
+		PerceptualHash:     existing.PerceptualHash,
+		Blurhash:           existing.Blurhash,
+		Palette:            slices.Clone(existing.Palette),
+		ContentHash:        existing.ContentHash,
+		Downloadable:       true,
+	}
+	if existing.Exposure != nil {
+		exposure := *existing.Exposure
+		photo.Exposure = &exposure
+	}
+	if existing.FilmStockSource == "exif" {
+		photo.FilmStock, photo.FilmStockSource = existing.FilmStock, existing.FilmStockSource
+	}
+	return photo
+}
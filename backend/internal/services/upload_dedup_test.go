@@ -0,0 +1,94 @@
+package services
+
+import (
+	"sync/atomic"
+	"testing"
+
+	"github.com/njoubert/nielsshootsfilm/backend/internal/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAlbumUploads_RepeatUploadReusesDerivatives(t *testing.T) {
+	albumService, _ := setupAlbumService(t)
+	imageService, err := NewImageService(t.TempDir(), nil, nil)
+	require.NoError(t, err)
+	storage := NewMemoryStorage()
+	imageService.SetStorage(storage)
+	var encodes atomic.Int64
+	imageService.onEncode = func(string) { encodes.Add(1) }
+
+	album := &models.Album{Title: "Contact Sheet", Visibility: "public"}
+	require.NoError(t, albumService.Create(album))
+
+	data := createTestJPEG(t, 640, 480)
+	first, err := imageService.Uploads(album).ProcessBytes("frame-12.jpg", data)
+	require.NoError(t, err)
+	assert.NotEmpty(t, first.ContentHash)
+	assert.Equal(t, int64(2), encodes.Load(), "display and thumbnail are encoded")
+	first.Title = "Frame 12"
+	require.NoError(t, albumService.AddPhoto(album.ID, first))
+
+	// The same file again shares the stored files, with no encoding
+	album, err = albumService.GetByID(album.ID)
+	require.NoError(t, err)
+	uploads := imageService.Uploads(album)
+	encodes.Store(0)
+	repeat, err := uploads.ProcessBytes("frame-12-copy.jpg", data)
+	require.NoError(t, err)
+	assert.Zero(t, encodes.Load())
+	assert.True(t, uploads.Reused(repeat))
+	assert.Equal(t, "frame-12-copy.jpg", repeat.FilenameOriginal)
+	assert.Empty(t, repeat.Title, "only file-derived fields are copied")
+	assert.Equal(t, first.ContentHash, repeat.ContentHash)
+	assert.Equal(t, first.URLOriginal, repeat.URLOriginal)
+	assert.Equal(t, first.URLDisplay, repeat.URLDisplay)
+	assert.Equal(t, first.URLThumbnail, repeat.URLThumbnail)
+	assert.Equal(t, first.FileSizeDisplay, repeat.FileSizeDisplay)
+	assert.Equal(t, first.Width, repeat.Width)
+
+	// Different content, or another album, is processed as usual
+	other, err := uploads.ProcessBytes("frame-13.jpg", createTestJPEG(t, 480, 640))
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), encodes.Load())
+	assert.False(t, uploads.Reused(other))
+
+	elsewhere, err := imageService.Uploads(&models.Album{}).ProcessBytes("frame-12.jpg", data)
+	require.NoError(t, err)
+	assert.NotEqual(t, first.URLOriginal, elsewhere.URLOriginal)
+}
+
+func TestAlbumService_PhotosOwningFiles(t *testing.T) {
+	service, _ := setupAlbumService(t)
+	shared := models.Photo{FilenameOriginal: "a.jpg", URLOriginal: "/uploads/originals/a.jpg", URLDisplay: "/uploads/display/a_display.webp", URLThumbnail: "/uploads/thumbnails/a_thumbnail.webp"}
+	own := models.Photo{FilenameOriginal: "b.jpg", URLOriginal: "/uploads/originals/b.jpg", URLDisplay: "/uploads/display/b_display.webp", URLThumbnail: "/uploads/thumbnails/b_thumbnail.webp"}
+
+	album := &models.Album{Title: "Roll 1", Visibility: "public"}
+	require.NoError(t, service.Create(album))
+	for _, photo := range []models.Photo{shared, shared, own} {
+		require.NoError(t, service.AddPhoto(album.ID, &photo))
+	}
+	album, err := service.GetByID(album.ID)
+	require.NoError(t, err)
+
+	// Deleting one of two photos sharing files keeps the files
+	owning, err := service.PhotosOwningFiles(album.ID, album.Photos[:1])
+	require.NoError(t, err)
+	assert.Empty(t, owning)
+
+	// Deleting both deletes the files once
+	owning, err = service.PhotosOwningFiles(album.ID, album.Photos)
+	require.NoError(t, err)
+	require.Len(t, owning, 2)
+	assert.Equal(t, album.Photos[0].ID, owning[0].ID)
+	assert.Equal(t, album.Photos[2].ID, owning[1].ID)
+
+	// Unless a photo in another album still uses them
+	other := &models.Album{Title: "Roll 2", Visibility: "public"}
+	require.NoError(t, service.Create(other))
+	require.NoError(t, service.AddPhoto(other.ID, &shared))
+	owning, err = service.PhotosOwningFiles(album.ID, album.Photos)
+	require.NoError(t, err)
+	require.Len(t, owning, 1)
+	assert.Equal(t, album.Photos[2].ID, owning[0].ID)
+}
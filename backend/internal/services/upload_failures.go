@@ -0,0 +1,135 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+// DefaultUploadFailureTimeout bounds how long one upload failure notification may take.
+const DefaultUploadFailureTimeout = 5 * time.Second
+
+// maxPendingUploadFailures is how many notifications may be in flight at once. Failures
+// beyond that are dropped rather than queued, so a slow notifier cannot pile up goroutines.
+const maxPendingUploadFailures = 32
+
+// UploadFailure describes an upload that could not be processed or added to its album.
+type UploadFailure struct {
+	AlbumID  string    `json:"album_id"`
+	Filename string    `json:"filename"`
+	Error    string    `json:"error"`
+	Time     time.Time `json:"time"`
+}
+
+// UploadFailureNotifier is told about failed uploads, so operators can alert on them.
+type UploadFailureNotifier interface {
+	// NotifyUploadFailure reports one failure, giving up when ctx is done.
+	NotifyUploadFailure(ctx context.Context, failure UploadFailure) error
+}
+
+// UploadFailureNotifierFunc adapts a function to an UploadFailureNotifier.
+type UploadFailureNotifierFunc func(ctx context.Context, failure UploadFailure) error
+
+// NotifyUploadFailure calls f.
+func (f UploadFailureNotifierFunc) NotifyUploadFailure(ctx context.Context, failure UploadFailure) error {
+	return f(ctx, failure)
+}
+
+// UploadFailureHook passes upload failures to a notifier in the background, so reporting
+// them never slows down or fails the upload request itself.
+type UploadFailureHook struct {
+	notifier UploadFailureNotifier
+	timeout  time.Duration
+	pending  chan struct{} // Holds a token per notification in flight
+	logger   *slog.Logger
+}
+
+// NewUploadFailureHook creates a hook that gives each notification at most timeout. A nil
+// logger logs to slog.Default().
+func NewUploadFailureHook(notifier UploadFailureNotifier, timeout time.Duration, logger *slog.Logger) *UploadFailureHook {
+	if logger == nil {
+		logger = slog.Default()
+	}
+	return &UploadFailureHook{
+		notifier: notifier,
+		timeout:  timeout,
+		pending:  make(chan struct{}, maxPendingUploadFailures),
+		logger:   logger,
+	}
+}
+
+// Notify reports a failed upload without waiting for the notifier. A nil hook does nothing.
+func (h *UploadFailureHook) Notify(albumID, filename string, err error) {
+	if h == nil {
+		return
+	}
+	failure := UploadFailure{AlbumID: albumID, Filename: filename, Error: err.Error(), Time: time.Now().UTC()}
+
+	select {
+	case h.pending <- struct{}{}:
+	default:
+		h.logger.Warn("dropped upload failure notification, too many in flight",
+			slog.String("album_id", albumID),
+			slog.String("filename", filename),
+		)
+		return
+	}
+
+	go func() {
+		defer func() { <-h.pending }()
+		ctx, cancel := context.WithTimeout(context.Background(), h.timeout)
+		defer cancel()
+		if err := h.notifier.NotifyUploadFailure(ctx, failure); err != nil {
+			h.logger.Warn("failed to send upload failure notification",
+				slog.String("album_id", albumID),
+				slog.String("filename", filename),
+				slog.String("error", err.Error()),
+			)
+		}
+	}()
+}
+
+// WebhookUploadFailureNotifier posts each upload failure as JSON to a URL, such as an
+// alerting service's incoming webhook.
+type WebhookUploadFailureNotifier struct {
+	url    string
+	client *http.Client
+}
+
+// NewWebhookUploadFailureNotifier creates a notifier posting to an http or https URL.
+func NewWebhookUploadFailureNotifier(webhookURL string) (*WebhookUploadFailureNotifier, error) {
+	parsed, err := url.Parse(webhookURL)
+	if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+		return nil, errors.New("webhook URL must be an absolute http or https URL")
+	}
+	return &WebhookUploadFailureNotifier{url: webhookURL, client: &http.Client{}}, nil
+}
+
+// NotifyUploadFailure posts the failure, treating any non-2xx response as an error.
+func (n *WebhookUploadFailureNotifier) NotifyUploadFailure(ctx context.Context, failure UploadFailure) error {
+	body, err := json.Marshal(failure)
+	if err != nil {
+		return fmt.Errorf("failed to encode upload failure: %w", err)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, n.url, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to create webhook request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := n.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to call webhook: %w", err)
+	}
+	_ = resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("webhook responded %s", resp.Status)
+	}
+	return nil
+}
@@ -0,0 +1,108 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestUploadFailureHook(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+
+	// Failures reach the notifier with the album, filename, and error
+	received := make(chan UploadFailure, 1)
+	hook := NewUploadFailureHook(UploadFailureNotifierFunc(func(_ context.Context, failure UploadFailure) error {
+		received <- failure
+		return nil
+	}), time.Second, logger)
+	hook.Notify("album-1", "frame.jpg", errors.New("unsupported file type: text/plain"))
+
+	select {
+	case failure := <-received:
+		assert.Equal(t, "album-1", failure.AlbumID)
+		assert.Equal(t, "frame.jpg", failure.Filename)
+		assert.Equal(t, "unsupported file type: text/plain", failure.Error)
+		assert.WithinDuration(t, time.Now(), failure.Time, time.Minute)
+	case <-time.After(5 * time.Second):
+		t.Fatal("notifier was not called")
+	}
+
+	// A notifier that hangs neither blocks the caller nor outlives its timeout
+	timedOut := make(chan error, 1)
+	hook = NewUploadFailureHook(UploadFailureNotifierFunc(func(ctx context.Context, _ UploadFailure) error {
+		<-ctx.Done()
+		timedOut <- ctx.Err()
+		return ctx.Err()
+	}), 50*time.Millisecond, logger)
+	start := time.Now()
+	hook.Notify("album-1", "frame.jpg", errors.New("disk full"))
+	assert.Less(t, time.Since(start), 50*time.Millisecond)
+	select {
+	case err := <-timedOut:
+		assert.ErrorIs(t, err, context.DeadlineExceeded)
+	case <-time.After(5 * time.Second):
+		t.Fatal("notification was not cancelled")
+	}
+
+	// Without a hook nothing happens
+	var none *UploadFailureHook
+	none.Notify("album-1", "frame.jpg", errors.New("disk full"))
+}
+
+func TestUploadFailureHook_DropsWhenBacklogged(t *testing.T) {
+	release := make(chan struct{})
+	defer close(release)
+	calls := make(chan struct{}, maxPendingUploadFailures+1)
+	hook := NewUploadFailureHook(UploadFailureNotifierFunc(func(ctx context.Context, _ UploadFailure) error {
+		calls <- struct{}{}
+		<-release
+		return nil
+	}), time.Minute, nil) // A nil logger logs the drop to the default logger
+
+	for range maxPendingUploadFailures + 1 {
+		hook.Notify("album-1", "frame.jpg", errors.New("disk full"))
+	}
+	for range maxPendingUploadFailures {
+		<-calls
+	}
+	select {
+	case <-calls:
+		t.Fatal("notification beyond the backlog limit should be dropped")
+	case <-time.After(50 * time.Millisecond):
+	}
+}
+
+func TestWebhookUploadFailureNotifier(t *testing.T) {
+	var posted UploadFailure
+	status := http.StatusNoContent
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, http.MethodPost, r.Method)
+		assert.Equal(t, "application/json", r.Header.Get("Content-Type"))
+		assert.NoError(t, json.NewDecoder(r.Body).Decode(&posted))
+		w.WriteHeader(status)
+	}))
+	defer server.Close()
+
+	notifier, err := NewWebhookUploadFailureNotifier(server.URL + "/hooks/uploads")
+	require.NoError(t, err)
+	failure := UploadFailure{AlbumID: "album-1", Filename: "frame.jpg", Error: "file is empty", Time: time.Date(2024, 8, 3, 14, 0, 0, 0, time.UTC)}
+	require.NoError(t, notifier.NotifyUploadFailure(context.Background(), failure))
+	assert.Equal(t, failure, posted)
+
+	status = http.StatusInternalServerError
+	assert.EqualError(t, notifier.NotifyUploadFailure(context.Background(), failure), "webhook responded 500 Internal Server Error")
+
+	for _, invalid := range []string{"", "alerts.example.com/hook", "ftp://alerts.example.com/hook", "https://"} {
+		_, err := NewWebhookUploadFailureNotifier(invalid)
+		assert.Error(t, err, invalid)
+	}
+}
@@ -0,0 +1,40 @@
+package services
+
+import (
+	"errors"
+
+	"github.com/njoubert/nielsshootsfilm/backend/internal/models"
+)
+
+// ErrImageTooSmall is returned for uploads whose longest edge is below the minimum
+// resolution. Errors wrapping it give the image's size and the minimum.
+var ErrImageTooSmall = errors.New("image resolution is too low")
+
+// AlbumUploads processes uploads bound for one album, applying the album's own upload
+// settings where it has them and the site's otherwise.
+type AlbumUploads struct {
+	service  *ImageService
+	minEdge  int                     // Smallest accepted longest edge in pixels; 0 accepts any size
+	existing map[string]models.Photo // The album's photos by content hash; their files are reused for repeat uploads
+}
+
+// Uploads returns the processor for uploads into an album. A nil album gets the site's
+// settings, as do ImageService's own Process methods.
+func (s *ImageService) Uploads(album *models.Album) AlbumUploads {
+	return AlbumUploads{service: s, minEdge: s.MinUploadEdge(album), existing: photosByContent(album)}
+}
+
+// MinUploadEdge returns the shortest longest edge, in pixels, accepted for uploads into an
+// album: the album's own override, else the site's storage.min_upload_edge_px, else 0 for
+// any size. A nil album gets the site setting.
+func (s *ImageService) MinUploadEdge(album *models.Album) int {
+	if album != nil && album.MinUploadEdgePx != nil {
+		return *album.MinUploadEdgePx
+	}
+	if s.configService != nil {
+		if config, err := s.configService.Get(); err == nil {
+			return config.Storage.MinUploadEdgePx
+		}
+	}
+	return 0
+}
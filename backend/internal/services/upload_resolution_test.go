@@ -0,0 +1,63 @@
+package services
+
+import (
+	"io"
+	"log/slog"
+	"testing"
+
+	"github.com/njoubert/nielsshootsfilm/backend/internal/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestImageService_MinUploadEdge(t *testing.T) {
+	fileService, err := NewFileService(t.TempDir())
+	require.NoError(t, err)
+	configService := NewSiteConfigService(fileService)
+
+	imageService, err := NewImageService(t.TempDir(), configService, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	require.NoError(t, err)
+	storage := NewMemoryStorage()
+	imageService.SetStorage(storage)
+
+	// Any size is accepted until a minimum is configured
+	assert.Equal(t, 0, imageService.MinUploadEdge(nil))
+	_, err = imageService.ProcessBytes("tiny.jpg", createTestJPEG(t, 64, 48))
+	require.NoError(t, err)
+
+	config, err := configService.Get()
+	require.NoError(t, err)
+	config.Storage.MinUploadEdgePx = 1000
+	require.NoError(t, configService.Update(config))
+	assert.Equal(t, 1000, imageService.MinUploadEdge(nil))
+
+	// Just under the minimum is rejected before anything is stored
+	stored := len(storage.objects)
+	_, err = imageService.ProcessBytes("small.jpg", createTestJPEG(t, 999, 600))
+	require.ErrorIs(t, err, ErrImageTooSmall)
+	assert.EqualError(t, err, "image resolution is too low: 999x600, but the longest edge must be at least 1000 pixels")
+	assert.Len(t, storage.objects, stored)
+
+	// The long edge counts, whichever way round the image is
+	photo, err := imageService.ProcessBytes("landscape.jpg", createTestJPEG(t, 1000, 600))
+	require.NoError(t, err)
+	assert.Equal(t, 1000, photo.Width)
+	_, err = imageService.ProcessBytes("portrait.jpg", createTestJPEG(t, 600, 1000))
+	require.NoError(t, err)
+
+	// Albums can lower the minimum, e.g. for proofs, or raise it
+	anySize := 0
+	proofs := &models.Album{MinUploadEdgePx: &anySize}
+	assert.Equal(t, 0, imageService.MinUploadEdge(proofs))
+	_, err = imageService.Uploads(proofs).ProcessBytes("proof.jpg", createTestJPEG(t, 320, 240))
+	require.NoError(t, err)
+
+	strict := 1200
+	prints := &models.Album{MinUploadEdgePx: &strict}
+	_, err = imageService.Uploads(prints).ProcessBytes("landscape.jpg", createTestJPEG(t, 1000, 600))
+	assert.ErrorIs(t, err, ErrImageTooSmall)
+
+	// Albums without an override follow the site
+	_, err = imageService.Uploads(&models.Album{}).ProcessBytes("small.jpg", createTestJPEG(t, 999, 600))
+	assert.ErrorIs(t, err, ErrImageTooSmall)
+}
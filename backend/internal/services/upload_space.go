@@ -0,0 +1,57 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"syscall"
+)
+
+// DefaultMinUploadSpace is how much free space the storage volume must have for uploads to be
+// accepted, unless configured otherwise.
+const DefaultMinUploadSpace = 1 << 30 // 1GB
+
+// ErrInsufficientStorage is returned when uploads are turned away for lack of storage space.
+var ErrInsufficientStorage = errors.New("insufficient storage")
+
+// SpaceReporter is implemented by storage backends that can tell how much space is free on
+// the volume they store objects on. Uploads to other backends are never turned away.
+type SpaceReporter interface {
+	// AvailableBytes returns the free space available for new objects.
+	AvailableBytes() (int64, error)
+}
+
+// AvailableBytes returns the free space on the filesystem holding the storage root.
+func (s *LocalStorage) AvailableBytes() (int64, error) {
+	var stat syscall.Statfs_t
+	if err := syscall.Statfs(s.root, &stat); err != nil {
+		return 0, fmt.Errorf("failed to get filesystem stats: %w", err)
+	}
+	// #nosec G115 - disk size conversions are safe for reasonable disk sizes
+	return int64(stat.Bavail) * int64(stat.Bsize), nil
+}
+
+// SetMinUploadSpace sets how much free space the storage volume must have for uploads to be
+// accepted; 0 turns the check off. It defaults to DefaultMinUploadSpace.
+func (s *ImageService) SetMinUploadSpace(bytes int64) {
+	s.minUploadSpace = bytes
+}
+
+// CheckUploadSpace checks, before an upload is read, that the storage volume has at least the
+// configured free space, returning an error wrapping ErrInsufficientStorage if it does not.
+// Each file is still checked against the disk usage limits as it is stored.
+func (s *ImageService) CheckUploadSpace() error {
+	reporter, ok := s.storage.(SpaceReporter)
+	if !ok || s.minUploadSpace <= 0 {
+		return nil
+	}
+
+	available, err := reporter.AvailableBytes()
+	if err != nil {
+		return err
+	}
+	if available < s.minUploadSpace {
+		return fmt.Errorf("%w: %s free on the storage volume, below the %s needed to accept uploads",
+			ErrInsufficientStorage, formatBytes(available), formatBytes(s.minUploadSpace))
+	}
+	return nil
+}
@@ -0,0 +1,55 @@
+package services
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// spaceStorage is an in-memory storage reporting a fixed amount of free space.
+type spaceStorage struct {
+	*MemoryStorage
+	available int64
+	err       error
+}
+
+func (s *spaceStorage) AvailableBytes() (int64, error) {
+	return s.available, s.err
+}
+
+func TestImageService_CheckUploadSpace(t *testing.T) {
+	imageService, err := NewImageService(t.TempDir(), nil, nil)
+	require.NoError(t, err)
+
+	// The local upload directory reports real free space
+	_, ok := imageService.Storage().(SpaceReporter)
+	assert.True(t, ok)
+
+	// Backends that cannot report free space are never turned away
+	imageService.SetStorage(NewMemoryStorage())
+	assert.NoError(t, imageService.CheckUploadSpace())
+
+	storage := &spaceStorage{MemoryStorage: NewMemoryStorage(), available: 200 << 20}
+	imageService.SetStorage(storage)
+	imageService.SetMinUploadSpace(100 << 20)
+	assert.NoError(t, imageService.CheckUploadSpace())
+
+	// Below the threshold uploads are refused
+	storage.available = 50 << 20
+	err = imageService.CheckUploadSpace()
+	require.ErrorIs(t, err, ErrInsufficientStorage)
+	assert.Contains(t, err.Error(), "50.0 MB free on the storage volume, below the 100.0 MB needed")
+
+	// Unless the check is off
+	imageService.SetMinUploadSpace(0)
+	assert.NoError(t, imageService.CheckUploadSpace())
+
+	// Failures to read free space are reported as they are
+	imageService.SetMinUploadSpace(100 << 20)
+	storage.err = errors.New("statfs failed")
+	err = imageService.CheckUploadSpace()
+	require.Error(t, err)
+	assert.NotErrorIs(t, err, ErrInsufficientStorage)
+}
@@ -0,0 +1,227 @@
+package services
+
+import (
+	"fmt"
+	"log/slog"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/njoubert/nielsshootsfilm/backend/internal/models"
+)
+
+const albumViewsFile = "album_views.json"
+
+// DefaultViewFlushInterval is how often counted album views are written to disk.
+const DefaultViewFlushInterval = 30 * time.Second
+
+// viewSessionTTL is how long a visitor session's views are remembered for deduplication.
+const viewSessionTTL = 24 * time.Hour
+
+// RecentlyViewedLimit is how many albums a visitor session's recently viewed history holds.
+const RecentlyViewedLimit = 12
+
+// maxTrackedViews bounds the session views remembered for deduplication between flushes, so
+// a flood of new sessions cannot grow them without limit. Past it, arbitrary views are
+// forgotten to make room, and the sessions they belong to may be counted again.
+const maxTrackedViews = 100_000
+
+// maxTrackedSessions bounds the sessions whose recently viewed history is remembered. Past
+// it, arbitrary histories are forgotten to make room, and those sessions start new ones.
+const maxTrackedSessions = 10_000
+
+// viewKey identifies one visitor session's view of one album.
+type viewKey struct {
+	session string
+	albumID string
+}
+
+// ViewTracker counts the visitor sessions that view each album. Views are counted in
+// memory and written to album_views.json in the background, so recording one never waits
+// on the disk. Repeat views from a session are counted once. It also remembers the albums
+// each session viewed most recently, in memory only, for as long as sessions are tracked.
+type ViewTracker struct {
+	fileService *FileService
+
+	mu      sync.Mutex
+	seen    map[viewKey]time.Time   // When each session first viewed each album
+	pending map[string]int64        // Views counted since the last flush, by album ID
+	recent  map[string]*recentViews // Recently viewed albums, by session
+
+	flushMu sync.Mutex // Serializes flushes, so counts are never written twice
+}
+
+// NewViewTracker creates a new view tracker.
+func NewViewTracker(fileService *FileService) *ViewTracker {
+	return &ViewTracker{
+		fileService: fileService,
+		seen:        make(map[viewKey]time.Time),
+		pending:     make(map[string]int64),
+		recent:      make(map[string]*recentViews),
+	}
+}
+
+// Record counts a view of an album by a visitor session, unless the session has already
+// viewed it. It reports whether the view was counted.
+func (t *ViewTracker) Record(albumID, session string) bool {
+	key := viewKey{session: session, albumID: albumID}
+	now := time.Now()
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.remember(session, albumID, now)
+	first, ok := t.seen[key]
+	if ok && now.Sub(first) < viewSessionTTL {
+		return false
+	}
+	if !ok && len(t.seen) >= maxTrackedViews {
+		forgetOne(t.seen)
+	}
+	t.seen[key] = now
+	t.pending[albumID]++
+	return true
+}
+
+// recentViews is a visitor session's recently viewed history.
+type recentViews struct {
+	albumIDs []string // Most recent first, each album once
+	last     time.Time
+}
+
+// remember moves an album to the front of a session's recently viewed history, dropping the
+// oldest album beyond RecentlyViewedLimit. Callers must hold mu.
+func (t *ViewTracker) remember(session, albumID string, now time.Time) {
+	history, ok := t.recent[session]
+	if !ok {
+		if len(t.recent) >= maxTrackedSessions {
+			forgetOne(t.recent)
+		}
+		history = &recentViews{}
+		t.recent[session] = history
+	}
+	history.last = now
+
+	ids := slices.DeleteFunc(history.albumIDs, func(id string) bool { return id == albumID })
+	ids = slices.Insert(ids, 0, albumID)
+	if len(ids) > RecentlyViewedLimit {
+		ids = ids[:RecentlyViewedLimit]
+	}
+	history.albumIDs = ids
+}
+
+// forgetOne deletes an arbitrary entry from m.
+func forgetOne[K comparable, V any](m map[K]V) {
+	for key := range m {
+		delete(m, key)
+		return
+	}
+}
+
+// RecentlyViewed returns the IDs of the albums a visitor session viewed most recently, most
+// recent first, or nil for an unknown session.
+func (t *ViewTracker) RecentlyViewed(session string) []string {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if history, ok := t.recent[session]; ok {
+		return slices.Clone(history.albumIDs)
+	}
+	return nil
+}
+
+// Views returns how many visitor sessions have viewed an album, including views not yet
+// written to disk.
+func (t *ViewTracker) Views(albumID string) (int64, error) {
+	t.flushMu.Lock()
+	defer t.flushMu.Unlock()
+
+	stored, err := t.load()
+	if err != nil {
+		return 0, err
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return stored.Views[albumID] + t.pending[albumID], nil
+}
+
+// Flush adds the views counted since the last flush to album_views.json and forgets
+// sessions old enough to be counted again, along with the histories of idle sessions.
+// Views that fail to be written are kept for the next flush.
+func (t *ViewTracker) Flush() error {
+	t.flushMu.Lock()
+	defer t.flushMu.Unlock()
+
+	t.mu.Lock()
+	pending := t.pending
+	t.pending = make(map[string]int64)
+	for key, first := range t.seen {
+		if time.Since(first) >= viewSessionTTL {
+			delete(t.seen, key)
+		}
+	}
+	for session, history := range t.recent {
+		if time.Since(history.last) >= viewSessionTTL {
+			delete(t.recent, session)
+		}
+	}
+	t.mu.Unlock()
+
+	if len(pending) == 0 {
+		return nil
+	}
+
+	err := t.write(pending)
+	if err != nil {
+		t.mu.Lock()
+		for albumID, views := range pending {
+			t.pending[albumID] += views
+		}
+		t.mu.Unlock()
+	}
+	return err
+}
+
+// StartFlushing flushes counted views every interval in the background. Call Flush once more
+// on shutdown to save the views counted since the last one.
+func (t *ViewTracker) StartFlushing(interval time.Duration, logger *slog.Logger) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for range ticker.C {
+			if err := t.Flush(); err != nil {
+				logger.Error("failed to save album views", slog.String("error", err.Error()))
+			}
+		}
+	}()
+}
+
+// write adds views to the stored counts. Callers must hold flushMu.
+func (t *ViewTracker) write(views map[string]int64) error {
+	stored, err := t.load()
+	if err != nil {
+		return err
+	}
+	for albumID, count := range views {
+		stored.Views[albumID] += count
+	}
+	// Counts are rewritten every flush, so backups of them would only crowd out others
+	if err := t.fileService.WriteJSONWithoutBackup(albumViewsFile, stored); err != nil {
+		return fmt.Errorf("failed to write album views: %w", err)
+	}
+	return nil
+}
+
+// load reads the stored counts. Callers must hold flushMu.
+func (t *ViewTracker) load() (*models.AlbumViews, error) {
+	stored := &models.AlbumViews{}
+	if t.fileService.FileExists(albumViewsFile) {
+		if err := t.fileService.ReadJSON(albumViewsFile, stored); err != nil {
+			return nil, fmt.Errorf("failed to read album views: %w", err)
+		}
+	}
+	if stored.Views == nil {
+		stored.Views = make(map[string]int64)
+	}
+	return stored, nil
+}
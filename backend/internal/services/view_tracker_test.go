@@ -0,0 +1,139 @@
+package services
+
+import (
+	"fmt"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestViewTracker(t *testing.T) {
+	fileService, err := NewFileService(t.TempDir())
+	require.NoError(t, err)
+	tracker := NewViewTracker(fileService)
+
+	views, err := tracker.Views("album-1")
+	require.NoError(t, err)
+	assert.Zero(t, views)
+
+	// Repeat views within a session count once
+	assert.True(t, tracker.Record("album-1", "session-a"))
+	assert.False(t, tracker.Record("album-1", "session-a"))
+	assert.False(t, tracker.Record("album-1", "session-a"))
+	views, err = tracker.Views("album-1")
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), views)
+
+	// Other sessions and other albums count separately
+	assert.True(t, tracker.Record("album-1", "session-b"))
+	assert.True(t, tracker.Record("album-2", "session-a"))
+	views, err = tracker.Views("album-1")
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), views)
+
+	// Counts survive a flush and a restart, and later views add to them
+	require.NoError(t, tracker.Flush())
+	assert.True(t, fileService.FileExists(albumViewsFile))
+	views, err = tracker.Views("album-1")
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), views, "flushed views should not be counted twice")
+
+	restarted := NewViewTracker(fileService)
+	assert.True(t, restarted.Record("album-1", "session-c"))
+	views, err = restarted.Views("album-1")
+	require.NoError(t, err)
+	assert.Equal(t, int64(3), views)
+	require.NoError(t, restarted.Flush())
+	views, err = restarted.Views("album-2")
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), views)
+}
+
+func TestViewTracker_RecentlyViewed(t *testing.T) {
+	fileService, err := NewFileService(t.TempDir())
+	require.NoError(t, err)
+	tracker := NewViewTracker(fileService)
+
+	assert.Nil(t, tracker.RecentlyViewed("session-a"))
+
+	// Most recent first, and a repeat view moves the album to the front
+	tracker.Record("album-1", "session-a")
+	tracker.Record("album-2", "session-a")
+	tracker.Record("album-3", "session-a")
+	tracker.Record("album-1", "session-a")
+	tracker.Record("album-9", "session-b")
+	assert.Equal(t, []string{"album-1", "album-3", "album-2"}, tracker.RecentlyViewed("session-a"))
+	assert.Equal(t, []string{"album-9"}, tracker.RecentlyViewed("session-b"))
+
+	// The oldest albums fall off beyond the limit
+	for i := range RecentlyViewedLimit + 3 {
+		tracker.Record(fmt.Sprintf("album-%d", 100+i), "session-a")
+	}
+	recent := tracker.RecentlyViewed("session-a")
+	require.Len(t, recent, RecentlyViewedLimit)
+	assert.Equal(t, fmt.Sprintf("album-%d", 100+RecentlyViewedLimit+2), recent[0])
+	assert.NotContains(t, recent, "album-1")
+
+	// Histories outlive flushes while the session is active
+	require.NoError(t, tracker.Flush())
+	assert.Len(t, tracker.RecentlyViewed("session-a"), RecentlyViewedLimit)
+}
+
+func TestViewTracker_Bounded(t *testing.T) {
+	dataDir := t.TempDir()
+	fileService, err := NewFileService(dataDir)
+	require.NoError(t, err)
+	tracker := NewViewTracker(fileService)
+
+	// A flood of new sessions does not grow the tracker past its bounds
+	for i := range maxTrackedViews + 10 {
+		tracker.Record("album-1", fmt.Sprintf("session-%d", i))
+	}
+	assert.Len(t, tracker.seen, maxTrackedViews)
+	assert.Len(t, tracker.recent, maxTrackedSessions)
+	views, err := tracker.Views("album-1")
+	require.NoError(t, err)
+	assert.Equal(t, int64(maxTrackedViews+10), views)
+
+	// Counts are saved without piling up backups
+	require.NoError(t, tracker.Flush())
+	tracker.Record("album-1", "session-new")
+	require.NoError(t, tracker.Flush())
+	backups, err := filepath.Glob(filepath.Join(dataDir, ".backups", albumViewsFile+"*"))
+	require.NoError(t, err)
+	assert.Empty(t, backups)
+}
+
+func TestViewTracker_Concurrent(t *testing.T) {
+	fileService, err := NewFileService(t.TempDir())
+	require.NoError(t, err)
+	tracker := NewViewTracker(fileService)
+
+	// Many sessions viewing at once, each several times, while flushes run
+	const sessions, repeats = 50, 4
+	var wg sync.WaitGroup
+	for i := range sessions {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for range repeats {
+				tracker.Record("album-1", fmt.Sprintf("session-%d", i))
+			}
+			if i%10 == 0 {
+				assert.NoError(t, tracker.Flush())
+			}
+		}()
+	}
+	wg.Wait()
+
+	views, err := tracker.Views("album-1")
+	require.NoError(t, err)
+	assert.Equal(t, int64(sessions), views)
+	require.NoError(t, tracker.Flush())
+	views, err = tracker.Views("album-1")
+	require.NoError(t, err)
+	assert.Equal(t, int64(sessions), views)
+}
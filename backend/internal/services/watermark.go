@@ -0,0 +1,169 @@
+package services
+
+import (
+	"fmt"
+
+	"github.com/davidbyttow/govips/v2/vips"
+	"github.com/njoubert/nielsshootsfilm/backend/internal/models"
+)
+
+// Watermark defaults.
+const (
+	defaultWatermarkOpacity      = 0.5
+	defaultWatermarkScalePercent = 20
+	watermarkMarginPercent       = 3 // Gap between the watermark and the photo edges
+)
+
+// watermarkConfig returns the configured watermark, or false if none is set up.
+func (s *ImageService) watermarkConfig() (models.WatermarkConfig, bool) {
+	if s.configService == nil {
+		return models.WatermarkConfig{}, false
+	}
+	config, err := s.configService.Get()
+	if err != nil || config.Branding.Watermark.ImageKey == "" {
+		return models.WatermarkConfig{}, false
+	}
+
+	watermark := config.Branding.Watermark
+	if watermark.Opacity <= 0 || watermark.Opacity > 1 {
+		watermark.Opacity = defaultWatermarkOpacity
+	}
+	if watermark.ScalePercent <= 0 || watermark.ScalePercent > 100 {
+		watermark.ScalePercent = defaultWatermarkScalePercent
+	}
+	return watermark, true
+}
+
+// applyWatermark stamps the configured watermark onto the bottom-right corner of img.
+// It is a no-op when no watermark is configured.
+func (s *ImageService) applyWatermark(img *vips.ImageRef) error {
+	config, ok := s.watermarkConfig()
+	if !ok {
+		return nil
+	}
+
+	watermarkBytes, err := s.storage.Get(config.ImageKey)
+	if err != nil {
+		return fmt.Errorf("failed to read watermark image: %w", err)
+	}
+
+	watermark, err := vips.NewImageFromBuffer(watermarkBytes)
+	if err != nil {
+		return fmt.Errorf("failed to load watermark image: %w", err)
+	}
+	defer watermark.Close()
+
+	// Scale the watermark relative to the photo so it looks the same at any resolution
+	targetWidth := img.Width() * config.ScalePercent / 100
+	if targetWidth < 1 {
+		targetWidth = 1
+	}
+	if err := watermark.Resize(float64(targetWidth)/float64(watermark.Width()), vips.KernelLanczos3); err != nil {
+		return fmt.Errorf("failed to resize watermark: %w", err)
+	}
+
+	// Fade the watermark by scaling its alpha band
+	if !watermark.HasAlpha() {
+		if err := watermark.AddAlpha(); err != nil {
+			return fmt.Errorf("failed to add watermark alpha: %w", err)
+		}
+	}
+	if err := watermark.Linear([]float64{1, 1, 1, config.Opacity}, []float64{0, 0, 0, 0}); err != nil {
+		return fmt.Errorf("failed to apply watermark opacity: %w", err)
+	}
+	if err := watermark.Cast(vips.BandFormatUchar); err != nil {
+		return fmt.Errorf("failed to convert watermark: %w", err)
+	}
+
+	margin := img.Width() * watermarkMarginPercent / 100
+	x := img.Width() - watermark.Width() - margin
+	y := img.Height() - watermark.Height() - margin
+	if x < 0 {
+		x = 0
+	}
+	if y < 0 {
+		y = 0
+	}
+
+	hadAlpha := img.HasAlpha()
+	if err := img.Composite(watermark, vips.BlendModeOver, x, y); err != nil {
+		return fmt.Errorf("failed to composite watermark: %w", err)
+	}
+
+	// Compositing adds an alpha band; drop it again for opaque photos
+	if !hadAlpha && img.HasAlpha() {
+		if err := img.Flatten(&vips.Color{R: 255, G: 255, B: 255}); err != nil {
+			return fmt.Errorf("failed to flatten watermarked image: %w", err)
+		}
+	}
+	return nil
+}
+
+// RenderDisplay rebuilds a photo's display version from its original at the album's display
+// size, stamped with the watermark if the album is watermarked. Returns a copy of the photo
+// with the new display file size.
+// Returns an error wrapping ErrObjectNotFound if the original is missing.
+func (s *ImageService) RenderDisplay(album *models.Album, photo models.Photo) (models.Photo, error) {
+	// Acquire semaphore to limit concurrent VIPS operations
+	s.processSem <- struct{}{}
+	defer func() { <-s.processSem }()
+
+	original, err := s.storage.Get(storageKeyFromURL(photo.URLOriginal, "originals"))
+	if err != nil {
+		return photo, fmt.Errorf("failed to read original: %w", err)
+	}
+
+	displaySize, err := s.generateDisplayVersion(original, storageKeyFromURL(photo.URLDisplay, "display"), photo.IsAnimated, album.WatermarkEnabled, s.DisplayMaxEdge(album))
+	if err != nil {
+		return photo, fmt.Errorf("failed to generate display version: %w", err)
+	}
+
+	photo.FileSizeDisplay = displaySize
+	return photo, nil
+}
+
+// RenderCover makes sure a watermarked album has a clean cover image and returns its URL.
+// A separate cover is only needed when gallery displays are watermarked but the cover should not be;
+// otherwise the cover photo's display version is used as-is and an empty URL is returned.
+// Any previously rendered cover that no longer applies is deleted.
+func (s *ImageService) RenderCover(album *models.Album) (string, error) {
+	cover := album.CoverPhoto()
+	_, watermarkConfigured := s.watermarkConfig()
+
+	coverURL := ""
+	if cover != nil && album.WatermarkEnabled && !album.WatermarkCover && watermarkConfigured && !cover.IsAnimated {
+		coverURL = "/uploads/covers/" + album.ID + "_" + cover.ID + ".webp"
+	}
+
+	if album.CoverURL != "" && album.CoverURL != coverURL {
+		if err := s.deleteObject(storageKeyFromURL(album.CoverURL, "covers")); err != nil {
+			return album.CoverURL, fmt.Errorf("failed to delete old cover: %w", err)
+		}
+	}
+
+	if coverURL == "" || coverURL == album.CoverURL {
+		return coverURL, nil
+	}
+
+	// Acquire semaphore to limit concurrent VIPS operations
+	s.processSem <- struct{}{}
+	defer func() { <-s.processSem }()
+
+	original, err := s.storage.Get(storageKeyFromURL(cover.URLOriginal, "originals"))
+	if err != nil {
+		return "", fmt.Errorf("failed to read cover original: %w", err)
+	}
+
+	if _, err := s.generateResizedVersion(original, storageKeyFromURL(coverURL, "covers"), s.DisplayMaxEdge(album), displayQuality, false); err != nil {
+		return "", fmt.Errorf("failed to render cover: %w", err)
+	}
+	return coverURL, nil
+}
+
+// DeleteCover removes an album's rendered cover image, if it has one.
+func (s *ImageService) DeleteCover(album *models.Album) error {
+	if album.CoverURL == "" {
+		return nil
+	}
+	return s.deleteObject(storageKeyFromURL(album.CoverURL, "covers"))
+}
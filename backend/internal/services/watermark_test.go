@@ -0,0 +1,149 @@
+package services
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/png"
+	"io"
+	"log/slog"
+	"strings"
+	"testing"
+
+	"github.com/davidbyttow/govips/v2/vips"
+	"github.com/njoubert/nielsshootsfilm/backend/internal/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testWatermarkKey = "branding/watermark.png"
+
+// setupWatermarkedImageService returns an image service backed by memory storage
+// with a solid red watermark configured.
+func setupWatermarkedImageService(t *testing.T) (*ImageService, *MemoryStorage) {
+	t.Helper()
+
+	fileService, err := NewFileService(t.TempDir())
+	require.NoError(t, err)
+	configService := NewSiteConfigService(fileService)
+	require.NoError(t, configService.Update(&models.SiteConfig{
+		Branding: models.BrandingConfig{
+			Watermark: models.WatermarkConfig{ImageKey: testWatermarkKey, Opacity: 1, ScalePercent: 25},
+		},
+	}))
+
+	imageService, err := NewImageService(t.TempDir(), configService, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	require.NoError(t, err)
+	storage := NewMemoryStorage()
+	imageService.SetStorage(storage)
+
+	mark := image.NewNRGBA(image.Rect(0, 0, 100, 50))
+	for x := 0; x < 100; x++ {
+		for y := 0; y < 50; y++ {
+			mark.Set(x, y, color.NRGBA{R: 255, A: 255})
+		}
+	}
+	var buf bytes.Buffer
+	require.NoError(t, png.Encode(&buf, mark))
+	require.NoError(t, storage.Put(testWatermarkKey, bytes.NewReader(buf.Bytes()), int64(buf.Len())))
+
+	return imageService, storage
+}
+
+// decodeStoredImage decodes a stored derivative for pixel comparisons.
+func decodeStoredImage(t *testing.T, storage *MemoryStorage, url string) image.Image {
+	t.Helper()
+
+	data, err := storage.Get(strings.TrimPrefix(url, "/uploads/"))
+	require.NoError(t, err, url)
+	img, err := vips.NewImageFromBuffer(data)
+	require.NoError(t, err)
+	defer img.Close()
+	pngBytes, _, err := img.ExportPng(vips.NewPngExportParams())
+	require.NoError(t, err)
+	decoded, err := png.Decode(bytes.NewReader(pngBytes))
+	require.NoError(t, err)
+	return decoded
+}
+
+// redExcess returns how much redder than the source gradient a region is, on average.
+func redExcess(img image.Image, region image.Rectangle) float64 {
+	var total float64
+	for x := region.Min.X; x < region.Max.X; x++ {
+		for y := region.Min.Y; y < region.Max.Y; y++ {
+			r, g, b, _ := img.At(x, y).RGBA()
+			total += float64(r>>8) - (float64(g>>8)+float64(b>>8))/2
+		}
+	}
+	return total / float64(region.Dx()*region.Dy())
+}
+
+func TestImageService_RenderCover_ExcludesWatermark(t *testing.T) {
+	imageService, storage := setupWatermarkedImageService(t)
+
+	photo, err := imageService.ProcessBytes("roll1-01.jpg", createTestJPEG(t, 1200, 800))
+	require.NoError(t, err)
+	watermarked, err := imageService.RenderDisplay(&models.Album{WatermarkEnabled: true}, *photo)
+	require.NoError(t, err)
+
+	album := &models.Album{
+		ID:               "album-1",
+		CoverPhotoID:     watermarked.ID,
+		Photos:           []models.Photo{watermarked},
+		WatermarkEnabled: true,
+	}
+
+	coverURL, err := imageService.RenderCover(album)
+	require.NoError(t, err)
+	assert.Equal(t, "/uploads/covers/album-1_"+watermarked.ID+".webp", coverURL)
+
+	display := decodeStoredImage(t, storage, watermarked.URLDisplay)
+	cover := decodeStoredImage(t, storage, coverURL)
+	require.Equal(t, display.Bounds(), cover.Bounds())
+
+	// The watermark sits in the bottom-right corner, inset by the margin
+	bounds := display.Bounds()
+	corner := image.Rect(bounds.Dx()-bounds.Dx()/5, bounds.Dy()-bounds.Dy()/10, bounds.Dx()-bounds.Dx()/10, bounds.Dy()-bounds.Dy()/20)
+	assert.Greater(t, redExcess(display, corner)-redExcess(cover, corner), 100.0, "gallery display is stamped, cover is clean")
+
+	// Away from the watermark both renderings match
+	topLeft := image.Rect(0, 0, bounds.Dx()/5, bounds.Dy()/5)
+	assert.InDelta(t, redExcess(display, topLeft), redExcess(cover, topLeft), 5.0)
+
+	// Re-rendering with the current URL is a no-op
+	album.CoverURL = coverURL
+	again, err := imageService.RenderCover(album)
+	require.NoError(t, err)
+	assert.Equal(t, coverURL, again)
+
+	// Opting the cover into the watermark drops the clean rendering
+	album.WatermarkCover = true
+	coverURL, err = imageService.RenderCover(album)
+	require.NoError(t, err)
+	assert.Empty(t, coverURL)
+	assert.Empty(t, storage.Keys("covers/"))
+}
+
+func TestImageService_RenderCover_NoWatermark(t *testing.T) {
+	imageService, storage := setupWatermarkedImageService(t)
+
+	photo, err := imageService.ProcessBytes("roll1-01.jpg", createTestJPEG(t, 400, 300))
+	require.NoError(t, err)
+
+	// Albums without a watermark use the display version directly
+	album := &models.Album{ID: "album-1", Photos: []models.Photo{*photo}}
+	coverURL, err := imageService.RenderCover(album)
+	require.NoError(t, err)
+	assert.Empty(t, coverURL)
+
+	// Unwatermarked displays have no stamp in the corner
+	display := decodeStoredImage(t, storage, photo.URLDisplay)
+	bounds := display.Bounds()
+	corner := image.Rect(bounds.Dx()-bounds.Dx()/5, bounds.Dy()-bounds.Dy()/10, bounds.Dx()-bounds.Dx()/10, bounds.Dy()-bounds.Dy()/20)
+	assert.Less(t, redExcess(display, corner), 100.0)
+
+	// An empty album has no cover to render
+	coverURL, err = imageService.RenderCover(&models.Album{ID: "album-2", WatermarkEnabled: true})
+	require.NoError(t, err)
+	assert.Empty(t, coverURL)
+}
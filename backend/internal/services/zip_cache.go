@@ -0,0 +1,216 @@
+package services
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/njoubert/nielsshootsfilm/backend/internal/models"
+)
+
+// DefaultZIPCacheSize is how many bytes of built album ZIPs the ZIP cache keeps, unless
+// configured otherwise.
+const DefaultZIPCacheSize = 10 << 30 // 10GB
+
+// cachedZIP is a built album ZIP kept in the ZIP cache, with its SHA-256 checksum in hex.
+type cachedZIP struct {
+	path string
+	sum  string
+}
+
+// SetZIPCacheDir keeps built album ZIPs in dir, so downloading an unchanged album again serves
+// the stored archive instead of rebuilding it. An empty dir disables the cache.
+func (s *ImageService) SetZIPCacheDir(dir string) error {
+	if dir != "" {
+		if err := os.MkdirAll(dir, 0o750); err != nil {
+			return fmt.Errorf("failed to create ZIP cache directory: %w", err)
+		}
+	}
+	s.zipCacheDir = dir
+	return nil
+}
+
+// SetZIPCacheSize sets how many bytes of built ZIPs the ZIP cache keeps. Past it, the ZIPs
+// downloaded least recently are removed as new ones are built; 0 keeps every ZIP. It
+// defaults to DefaultZIPCacheSize.
+func (s *ImageService) SetZIPCacheSize(bytes int64) {
+	s.zipCacheSize = max(bytes, 0)
+}
+
+// InvalidateAlbumZIPs removes every cached ZIP of an album, e.g. when it is deleted. Edits
+// need no invalidation, since cached ZIPs are keyed by the album's version.
+func (s *ImageService) InvalidateAlbumZIPs(albumID string) {
+	s.removeCachedZIPs(albumID, "")
+}
+
+// serveCachedAlbumZIP serves a ZIP of the given album photos from the ZIP cache, building it
+// first if this version of the album has not been downloaded at this quality before. The
+// response supports range requests, and its ETag is the checksum so resumed downloads can
+// check with If-Range that the archive has not changed.
+func (s *ImageService) serveCachedAlbumZIP(w http.ResponseWriter, r *http.Request, album *models.Album, quality, filename string, photos []models.Photo, sidecars bool) error {
+	cached, err := s.cachedAlbumZIP(album, quality, photos, sidecars)
+	if err != nil {
+		return err
+	}
+
+	file, err := os.Open(cached.path)
+	if err != nil {
+		return fmt.Errorf("failed to open cached ZIP file: %w", err)
+	}
+	defer func() { _ = file.Close() }()
+
+	w.Header().Set("Content-Type", "application/zip")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+	w.Header().Set("X-Content-SHA256", cached.sum)
+	w.Header().Set("ETag", `"`+cached.sum+`"`)
+	http.ServeContent(w, r, filename, time.Time{}, file)
+	return nil
+}
+
+// cachedAlbumZIP returns the cached ZIP for this version of the album, photos, and quality,
+// building and storing it if needed. Concurrent requests for the same ZIP share one build,
+// and building a new version removes the album's cached ZIPs for older versions.
+func (s *ImageService) cachedAlbumZIP(album *models.Album, quality string, photos []models.Photo, sidecars bool) (cachedZIP, error) {
+	version := strconv.FormatInt(album.UpdatedAt.UnixNano(), 36)
+	key := albumZIPCacheKey(album, version, quality, s.downloadFilenameTemplate(album), photos, sidecars)
+
+	cached, err, _ := s.zipBuilds.Do(key, func() (any, error) {
+		zipPath := filepath.Join(s.zipCacheDir, key+".zip")
+		if _, err := os.Stat(zipPath); err == nil {
+			// The checksum is written before the archive is moved into place
+			if sum, err := os.ReadFile(zipPath + ".sha256"); err == nil {
+				// The modification time records the last download, for eviction
+				now := time.Now()
+				_ = os.Chtimes(zipPath, now, now)
+				return cachedZIP{path: zipPath, sum: string(sum)}, nil
+			}
+		}
+
+		s.removeCachedZIPs(album.ID, version)
+
+		tmpFile, err := os.CreateTemp(s.zipCacheDir, key+"-*.tmp")
+		if err != nil {
+			return cachedZIP{}, fmt.Errorf("failed to create cached ZIP file: %w", err)
+		}
+		defer func() {
+			_ = tmpFile.Close()
+			_ = os.Remove(tmpFile.Name())
+		}()
+
+		hash := sha256.New()
+		if err := s.writeAlbumZIP(io.MultiWriter(tmpFile, hash), album, quality, photos, sidecars); err != nil {
+			return cachedZIP{}, err
+		}
+		if err := tmpFile.Close(); err != nil {
+			return cachedZIP{}, fmt.Errorf("failed to write cached ZIP file: %w", err)
+		}
+
+		sum := hex.EncodeToString(hash.Sum(nil))
+		if err := os.WriteFile(zipPath+".sha256", []byte(sum), 0o600); err != nil {
+			return cachedZIP{}, fmt.Errorf("failed to write cached ZIP checksum: %w", err)
+		}
+		if err := os.Rename(tmpFile.Name(), zipPath); err != nil {
+			return cachedZIP{}, fmt.Errorf("failed to store cached ZIP file: %w", err)
+		}
+		s.evictCachedZIPs(zipPath)
+		return cachedZIP{path: zipPath, sum: sum}, nil
+	})
+	return cached.(cachedZIP), err
+}
+
+// albumZIPCacheKey names the cached ZIP of one album version at one quality. The album ID and
+// version lead, so an album's cached ZIPs can be found by prefix; a hash of the quality, GPS
+// scrubbing, filename template, sidecars, and photo list follows, so split download parts are
+// cached separately and a new site-wide template is not served from older ZIPs.
+func albumZIPCacheKey(album *models.Album, version, quality, template string, photos []models.Photo, sidecars bool) string {
+	hash := sha256.New()
+	_, _ = fmt.Fprintf(hash, "%s\n%t\n", quality, album.ScrubGPSOnDownload)
+	if template != "" {
+		_, _ = fmt.Fprintln(hash, "filename", template)
+	}
+	if sidecars {
+		_, _ = fmt.Fprintln(hash, "sidecars")
+	}
+	for _, photo := range photos {
+		_, _ = fmt.Fprintln(hash, photo.ID, photo.Downloadable)
+	}
+	return album.ID + "_" + version + "_" + hex.EncodeToString(hash.Sum(nil))[:16]
+}
+
+// removeCachedZIPs deletes an album's cached ZIPs, except those of keepVersion if it is set.
+// Files being sent stay readable until their downloads finish.
+func (s *ImageService) removeCachedZIPs(albumID, keepVersion string) {
+	if s.zipCacheDir == "" {
+		return
+	}
+
+	matches, err := filepath.Glob(filepath.Join(s.zipCacheDir, albumID+"_*"))
+	if err != nil {
+		return
+	}
+	for _, match := range matches {
+		if keepVersion != "" && strings.HasPrefix(filepath.Base(match), albumID+"_"+keepVersion+"_") {
+			continue
+		}
+		if err := os.Remove(match); err != nil && !os.IsNotExist(err) {
+			s.logger.Warn("failed to remove cached ZIP file",
+				slog.String("path", match),
+				slog.String("error", err.Error()))
+		}
+	}
+}
+
+// evictCachedZIPs removes the cached ZIPs downloaded least recently, with their checksums,
+// until the cache is within its size budget. keep, the ZIP just built, is never removed, even
+// if it alone is over budget. Files being sent stay readable until their downloads finish.
+func (s *ImageService) evictCachedZIPs(keep string) {
+	if s.zipCacheSize <= 0 {
+		return
+	}
+
+	matches, err := filepath.Glob(filepath.Join(s.zipCacheDir, "*.zip"))
+	if err != nil {
+		return
+	}
+	type cacheEntry struct {
+		path     string
+		size     int64
+		lastUsed time.Time
+	}
+	var entries []cacheEntry
+	var total int64
+	for _, match := range matches {
+		info, err := os.Stat(match)
+		if err != nil {
+			continue
+		}
+		total += info.Size()
+		if match != keep {
+			entries = append(entries, cacheEntry{path: match, size: info.Size(), lastUsed: info.ModTime()})
+		}
+	}
+	slices.SortFunc(entries, func(a, b cacheEntry) int { return a.lastUsed.Compare(b.lastUsed) })
+
+	for _, entry := range entries {
+		if total <= s.zipCacheSize {
+			return
+		}
+		if err := os.Remove(entry.path); err != nil && !os.IsNotExist(err) {
+			s.logger.Warn("failed to evict cached ZIP file",
+				slog.String("path", entry.path),
+				slog.String("error", err.Error()))
+			continue
+		}
+		_ = os.Remove(entry.path + ".sha256")
+		total -= entry.size
+	}
+}
@@ -0,0 +1,153 @@
+package services
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/njoubert/nielsshootsfilm/backend/internal/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// streamCountingStorage counts how many objects are streamed, i.e. read into ZIPs.
+type streamCountingStorage struct {
+	Storage
+	streams atomic.Int32
+}
+
+func (c *streamCountingStorage) Stream(key string) (io.ReadCloser, error) {
+	c.streams.Add(1)
+	return c.Storage.Stream(key)
+}
+
+func TestImageService_CachedAlbumZIP(t *testing.T) {
+	imageService, err := NewImageService(t.TempDir(), nil, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	require.NoError(t, err)
+	storage := &streamCountingStorage{Storage: NewMemoryStorage()}
+	imageService.SetStorage(storage)
+	cacheDir := t.TempDir()
+	require.NoError(t, imageService.SetZIPCacheDir(cacheDir))
+
+	first, err := imageService.ProcessBytes("frame-01.jpg", createTestJPEG(t, 320, 240))
+	require.NoError(t, err)
+	second, err := imageService.ProcessBytes("frame-02.jpg", createTestJPEG(t, 240, 320))
+	require.NoError(t, err)
+	album := &models.Album{
+		ID:        "album-1",
+		Slug:      "contact-sheet",
+		UpdatedAt: time.Date(2024, 8, 2, 12, 0, 0, 0, time.UTC),
+		Photos:    []models.Photo{*first, *second},
+	}
+
+	download := func(header http.Header) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("GET", "/api/albums/contact-sheet/download?quality=original", nil)
+		for name, values := range header {
+			req.Header[name] = values
+		}
+		w := httptest.NewRecorder()
+		require.NoError(t, imageService.StreamAlbumZIP(w, req, album, "original", ZIPOptions{}))
+		return w
+	}
+	cachedFiles := func() int {
+		entries, err := os.ReadDir(cacheDir)
+		require.NoError(t, err)
+		return len(entries)
+	}
+
+	// The first download builds the ZIP and describes it fully
+	w := download(nil)
+	require.Equal(t, http.StatusOK, w.Code)
+	body := w.Body.Bytes()
+	sum := sha256.Sum256(body)
+	assert.Equal(t, strconv.Itoa(len(body)), w.Header().Get("Content-Length"))
+	assert.Equal(t, hex.EncodeToString(sum[:]), w.Header().Get("X-Content-SHA256"))
+	etag := w.Header().Get("ETag")
+	assert.Equal(t, `"`+hex.EncodeToString(sum[:])+`"`, etag)
+	assert.Equal(t, int32(2), storage.streams.Load())
+	assert.Equal(t, 2, cachedFiles()) // The archive and its checksum
+
+	// The second download is served from the cache without reading any photo
+	w = download(nil)
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, body, w.Body.Bytes())
+	assert.Equal(t, int32(2), storage.streams.Load())
+
+	// Interrupted downloads resume with a range, as long as the archive is unchanged
+	w = download(http.Header{"Range": {"bytes=100-199"}, "If-Range": {etag}})
+	require.Equal(t, http.StatusPartialContent, w.Code)
+	assert.Equal(t, body[100:200], w.Body.Bytes())
+
+	// Editing the album invalidates the cached ZIP and removes it once the new one is built
+	album.UpdatedAt = album.UpdatedAt.Add(time.Second)
+	album.Photos = album.Photos[:1]
+	w = download(http.Header{"Range": {"bytes=100-199"}, "If-Range": {etag}})
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.NotEqual(t, body, w.Body.Bytes())
+	assert.NotEqual(t, etag, w.Header().Get("ETag"))
+	assert.Equal(t, int32(3), storage.streams.Load())
+	assert.Equal(t, 2, cachedFiles())
+
+	// Deleting the album removes its cached ZIPs
+	imageService.InvalidateAlbumZIPs(album.ID)
+	assert.Equal(t, 0, cachedFiles())
+}
+
+func TestImageService_CachedAlbumZIP_Eviction(t *testing.T) {
+	imageService, err := NewImageService(t.TempDir(), nil, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	require.NoError(t, err)
+	storage := NewMemoryStorage()
+	imageService.SetStorage(storage)
+	cacheDir := t.TempDir()
+	require.NoError(t, imageService.SetZIPCacheDir(cacheDir))
+	imageService.SetZIPCacheSize(250 << 10) // Room for two of the ZIPs below
+
+	// Each album holds one incompressible 100KB photo
+	albums := map[string]*models.Album{}
+	for _, id := range []string{"a", "b", "c"} {
+		data := make([]byte, 100<<10)
+		_, _ = rand.Read(data)
+		require.NoError(t, storage.Put("originals/"+id+".jpg", bytes.NewReader(data), int64(len(data))))
+		albums[id] = &models.Album{ID: id, Slug: id, UpdatedAt: time.Date(2024, 8, 2, 12, 0, 0, 0, time.UTC),
+			Photos: []models.Photo{{ID: id + "-1", FilenameOriginal: id + ".jpg", URLOriginal: "/uploads/originals/" + id + ".jpg", Downloadable: true}}}
+	}
+	download := func(id string) {
+		w := httptest.NewRecorder()
+		require.NoError(t, imageService.StreamAlbumZIP(w, httptest.NewRequest("GET", "/download", nil), albums[id], "original", ZIPOptions{}))
+		require.Equal(t, http.StatusOK, w.Code)
+	}
+	cachedAlbums := func() []string {
+		matches, err := filepath.Glob(filepath.Join(cacheDir, "*.zip"))
+		require.NoError(t, err)
+		ids := []string{}
+		for _, match := range matches {
+			ids = append(ids, strings.SplitN(filepath.Base(match), "_", 2)[0])
+		}
+		return ids
+	}
+
+	download("a")
+	download("b")
+	assert.Equal(t, []string{"a", "b"}, cachedAlbums())
+
+	// Downloading a again makes b the least recently used, so c's ZIP pushes b's out
+	time.Sleep(10 * time.Millisecond)
+	download("a")
+	download("c")
+	assert.Equal(t, []string{"a", "c"}, cachedAlbums())
+	entries, err := os.ReadDir(cacheDir)
+	require.NoError(t, err)
+	assert.Len(t, entries, 4) // Evicted ZIPs take their checksums with them
+}
@@ -0,0 +1,72 @@
+package services
+
+import (
+	"archive/zip"
+	"encoding/json"
+	"fmt"
+	"path"
+	"time"
+
+	"github.com/njoubert/nielsshootsfilm/backend/internal/models"
+)
+
+// ZIPOptions are the choices an album ZIP download can make beyond the quality level.
+type ZIPOptions struct {
+	Chunked  bool // Stream the ZIP as it is built, without Content-Length, checksum, or caching
+	Sidecars bool // Add a JSON sidecar next to each photo; see PhotoSidecar
+}
+
+// sidecarExtension is appended to a photo's ZIP entry name to name its sidecar.
+const sidecarExtension = ".json"
+
+// PhotoSidecar is the metadata written next to each photo in album ZIPs with sidecars, as
+// <photo filename>.json, for archiving alongside the files.
+type PhotoSidecar struct {
+	Filename  string       `json:"filename"` // The photo's entry in the ZIP, next to this sidecar
+	Title     string       `json:"title,omitempty"`
+	Caption   string       `json:"caption,omitempty"`
+	AltText   string       `json:"alt_text,omitempty"`
+	Tags      []string     `json:"tags"`
+	DateTaken *time.Time   `json:"date_taken,omitempty"` // Capture date, from EXIF or given on upload
+	EXIF      *models.EXIF `json:"exif,omitempty"`
+}
+
+// photoSidecar returns the sidecar of a photo in an album's ZIP. GPS positions are left out
+// if the album scrubs them from downloads.
+func photoSidecar(album *models.Album, photo *models.Photo, filename string) PhotoSidecar {
+	sidecar := PhotoSidecar{
+		Filename: filename,
+		Title:    photo.Title,
+		Caption:  photo.Caption,
+		AltText:  photo.AltText,
+		Tags:     photo.Tags,
+	}
+	if sidecar.Tags == nil {
+		sidecar.Tags = []string{}
+	}
+	if photo.EXIF != nil {
+		exif := *photo.EXIF
+		if album.ScrubGPSOnDownload {
+			exif.Latitude, exif.Longitude = nil, nil
+		}
+		sidecar.EXIF = &exif
+		sidecar.DateTaken = exif.DateTaken
+	}
+	return sidecar
+}
+
+// addSidecarToZIP adds the sidecar of a photo stored in the ZIP as entryName, next to it.
+func addSidecarToZIP(zipWriter *zip.Writer, album *models.Album, photo *models.Photo, entryName string) error {
+	data, err := json.MarshalIndent(photoSidecar(album, photo, path.Base(entryName)), "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode sidecar for %s: %w", entryName, err)
+	}
+	entry, err := zipWriter.CreateHeader(&zip.FileHeader{Name: entryName + sidecarExtension, Method: zip.Deflate})
+	if err != nil {
+		return fmt.Errorf("failed to create ZIP entry for %s sidecar: %w", entryName, err)
+	}
+	if _, err := entry.Write(data); err != nil {
+		return fmt.Errorf("failed to write sidecar to ZIP: %w", err)
+	}
+	return nil
+}
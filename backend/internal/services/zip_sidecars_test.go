@@ -0,0 +1,99 @@
+package services
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/njoubert/nielsshootsfilm/backend/internal/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestImageService_StreamAlbumZIP_Sidecars(t *testing.T) {
+	imageService, err := NewImageService(t.TempDir(), nil, nil)
+	require.NoError(t, err)
+	imageService.SetStorage(NewMemoryStorage())
+
+	taken := time.Date(2023, 6, 21, 19, 45, 0, 0, time.UTC)
+	latitude, longitude := 38.5733, -109.5498
+	described, err := imageService.ProcessBytes("arches.jpg", createTestJPEG(t, 64, 48))
+	require.NoError(t, err)
+	described.ID = "described"
+	described.Title = "Delicate Arch"
+	described.Caption = "Last light"
+	described.Tags = []string{"desert", "utah"}
+	described.EXIF = &models.EXIF{Camera: "Pentax 67", ISO: 160, DateTaken: &taken, Latitude: &latitude, Longitude: &longitude}
+
+	bare, err := imageService.ProcessBytes("bare.jpg", createTestJPEG(t, 48, 64))
+	require.NoError(t, err)
+	bare.ID = "bare"
+
+	album := &models.Album{ID: "album-1", Slug: "utah", Photos: []models.Photo{*described, *bare}}
+
+	readZIP := func(opts ZIPOptions) ([]string, map[string][]byte) {
+		t.Helper()
+		w := httptest.NewRecorder()
+		require.NoError(t, imageService.StreamAlbumZIP(w, httptest.NewRequest("GET", "/download", nil), album, "original", opts))
+		zipReader, err := zip.NewReader(bytes.NewReader(w.Body.Bytes()), int64(w.Body.Len()))
+		require.NoError(t, err)
+		entries := make(map[string][]byte)
+		var names []string
+		for _, file := range zipReader.File {
+			reader, err := file.Open()
+			require.NoError(t, err)
+			data, err := io.ReadAll(reader)
+			require.NoError(t, err)
+			_ = reader.Close()
+			entries[file.Name] = data
+			names = append(names, file.Name)
+		}
+		return names, entries
+	}
+	readSidecar := func(entries map[string][]byte, name string) PhotoSidecar {
+		t.Helper()
+		require.Contains(t, entries, name)
+		var sidecar PhotoSidecar
+		require.NoError(t, json.Unmarshal(entries[name], &sidecar))
+		return sidecar
+	}
+
+	// Without the option, only photos
+	names, _ := readZIP(ZIPOptions{})
+	assert.Equal(t, []string{"arches.jpg", "bare.jpg"}, names)
+
+	// Each photo is followed by its sidecar, named after it
+	names, entries := readZIP(ZIPOptions{Sidecars: true})
+	assert.Equal(t, []string{"arches.jpg", "arches.jpg.json", "bare.jpg", "bare.jpg.json"}, names)
+
+	sidecar := readSidecar(entries, "arches.jpg.json")
+	assert.Equal(t, "arches.jpg", sidecar.Filename)
+	assert.Equal(t, "Delicate Arch", sidecar.Title)
+	assert.Equal(t, "Last light", sidecar.Caption)
+	assert.Equal(t, []string{"desert", "utah"}, sidecar.Tags)
+	require.NotNil(t, sidecar.DateTaken)
+	assert.True(t, taken.Equal(*sidecar.DateTaken))
+	require.NotNil(t, sidecar.EXIF)
+	assert.Equal(t, "Pentax 67", sidecar.EXIF.Camera)
+	assert.Equal(t, 160, sidecar.EXIF.ISO)
+	require.NotNil(t, sidecar.EXIF.Latitude)
+
+	sidecar = readSidecar(entries, "bare.jpg.json")
+	assert.Equal(t, "bare.jpg", sidecar.Filename)
+	assert.Empty(t, sidecar.Title)
+	assert.Equal(t, []string{}, sidecar.Tags)
+	assert.Nil(t, sidecar.EXIF)
+
+	// Albums scrubbing GPS leave positions out of sidecars too
+	album.ScrubGPSOnDownload = true
+	_, entries = readZIP(ZIPOptions{Sidecars: true, Chunked: true})
+	sidecar = readSidecar(entries, "arches.jpg.json")
+	require.NotNil(t, sidecar.EXIF)
+	assert.Nil(t, sidecar.EXIF.Latitude)
+	assert.Nil(t, sidecar.EXIF.Longitude)
+	assert.Equal(t, "Pentax 67", sidecar.EXIF.Camera)
+}
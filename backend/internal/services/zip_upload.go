@@ -0,0 +1,76 @@
+package services
+
+import (
+	"archive/zip"
+	"fmt"
+	"io"
+	"math"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/njoubert/nielsshootsfilm/backend/internal/models"
+)
+
+// ZIPEntry is a file in an uploaded ZIP archive. Entries that are not imported have no
+// File and say why in Reason.
+type ZIPEntry struct {
+	Name   string
+	File   *zip.File
+	Reason string
+}
+
+// ZIPImageEntries returns the files in an uploaded ZIP archive, in archive order.
+// Directories, hidden files, and macOS metadata under __MACOSX/ are skipped silently.
+// Entries with unsafe paths (absolute, with backslashes, or escaping the archive) and files
+// of types uploads do not accept are kept in place with the reason they are rejected.
+func (s *ImageService) ZIPImageEntries(archive *zip.Reader) []ZIPEntry {
+	entries := []ZIPEntry{}
+	for _, file := range archive.File {
+		if strings.Contains(file.Name, `\`) || !filepath.IsLocal(file.Name) {
+			entries = append(entries, ZIPEntry{Name: file.Name, Reason: "unsafe path"})
+			continue
+		}
+		if file.FileInfo().IsDir() {
+			continue
+		}
+		if strings.HasPrefix(file.Name, "__MACOSX/") || strings.HasPrefix(path.Base(file.Name), ".") {
+			continue
+		}
+		if !s.AllowsFilename(file.Name) {
+			entries = append(entries, ZIPEntry{Name: file.Name, Reason: "unsupported file type"})
+			continue
+		}
+		entries = append(entries, ZIPEntry{Name: file.Name, File: file})
+	}
+	return entries
+}
+
+// ProcessZIPEntry processes one image file from an uploaded ZIP archive with the site's
+// upload settings.
+func (s *ImageService) ProcessZIPEntry(file *zip.File) (*models.Photo, error) {
+	return s.Uploads(nil).ProcessZIPEntry(file)
+}
+
+// ProcessZIPEntry processes one image file from an uploaded ZIP archive, named after the
+// entry's base filename. The size recorded in the archive is checked against the upload
+// limits before anything is decompressed.
+func (u AlbumUploads) ProcessZIPEntry(file *zip.File) (*models.Photo, error) {
+	if err := u.service.validateUploadSize(int64(min(file.UncompressedSize64, math.MaxInt64))); err != nil {
+		return nil, err
+	}
+
+	reader, err := file.Open()
+	if err != nil {
+		return nil, fmt.Errorf("failed to open ZIP entry: %w", err)
+	}
+	defer func() { _ = reader.Close() }()
+
+	// Reads past the recorded size fail, so a lying header cannot inflate this
+	fileBytes, err := io.ReadAll(reader)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read ZIP entry: %w", err)
+	}
+
+	return u.ProcessBytes(path.Base(file.Name), fileBytes)
+}
@@ -645,9 +645,10 @@ export class AlbumPhotoPage extends LitElement {
 
   private handleDownload = () => {
     if (!this.currentPhoto) return;
+    // The API checks the album's download settings and names the file.
     const link = document.createElement('a');
-    link.href = this.currentPhoto.url_original;
-    link.download = this.currentPhoto.filename_original;
+    link.href = `/api/albums/${this.albumSlug}/photos/${this.currentPhoto.id}/download`;
+    link.download = '';
     link.click();
   };
 
@@ -50,6 +50,7 @@ export interface Album {
   expiration_date?: string;
   allow_downloads: boolean;
   order: number;
+  pinned?: boolean;
   theme_override?: ThemeMode;
   created_at: string;
   updated_at: string;
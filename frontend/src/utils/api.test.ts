@@ -303,6 +303,38 @@ describe('api utilities', () => {
       expect(result).toHaveLength(1);
       expect(result[0].visibility).toBe('public');
     });
+
+    it('should list pinned albums first, each group by order', async () => {
+      const album = (id: string, order: number, pinned?: boolean) => ({
+        id,
+        slug: id,
+        title: id,
+        visibility: 'public' as const,
+        photos: [],
+        allow_downloads: true,
+        order,
+        pinned,
+        created_at: '2025-10-19T00:00:00Z',
+        updated_at: '2025-10-19T00:00:00Z',
+      });
+
+      global.fetch = vi.fn().mockResolvedValue({
+        ok: true,
+        json: () =>
+          Promise.resolve({
+            albums: [
+              album('desert', 2),
+              album('city', 3, true),
+              album('beach', 1),
+              album('alps', 4, true),
+            ],
+          }),
+      } as Response);
+
+      const result = await fetchPublicAlbums();
+
+      expect(result.map((a) => a.id)).toEqual(['city', 'alps', 'beach', 'desert']);
+    });
   });
 
   describe('album token management', () => {
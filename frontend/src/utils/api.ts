@@ -63,14 +63,14 @@ export async function fetchMainPortfolioAlbum(): Promise<Album | null> {
 }
 
 /**
- * Fetch all public albums (for album listing page).
+ * Fetch all public albums (for album listing page), pinned albums first.
  */
 export async function fetchPublicAlbums(): Promise<Album[]> {
   console.debug('Fetching public albums');
   const data = await fetchAlbumsData();
   return data.albums
     .filter((album) => album.visibility === 'public')
-    .sort((a, b) => a.order - b.order);
+    .sort((a, b) => Number(!!b.pinned) - Number(!!a.pinned) || a.order - b.order);
 }
 
 /**